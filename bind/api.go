@@ -2,30 +2,60 @@
 package bind
 
 import (
+	"errors"
 	"io"
+	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/go-mix/mix/bind/hardware/null"
+	"github.com/go-mix/mix/bind/debug"
 	"github.com/go-mix/mix/bind/opt"
 	"github.com/go-mix/mix/bind/sample"
-	"github.com/go-mix/mix/bind/sox"
 	"github.com/go-mix/mix/bind/spec"
 	"github.com/go-mix/mix/bind/wav"
 )
 
-// Configure begins streaming to the bound out audio interface, via a callback function
-func Configure(s spec.AudioSpec) {
-	sample.ConfigureOutput(s)
-	switch useOutput {
-	case opt.OutputWAV:
-		wav.ConfigureOutput(s)
-	case opt.OutputNull:
-		null.ConfigureOutput(s)
+// Configure the bound out audio interface, and return the spec obtained, which may differ from the spec requested, e.g. SDL
+func Configure(s spec.AudioSpec) (obtained spec.AudioSpec, err error) {
+	obtained = s
+	if driver := outputDriver(useOutput); driver != nil {
+		obtained, err = driver.ConfigureOutput(s)
 	}
+	sample.ConfigureOutput(obtained)
+	if err != nil {
+		debug.Warnf("bind.Configure(%s) failed: %s", useOutput, err)
+	} else {
+		debug.Infof("bind.Configure(%s) obtained %+v", useOutput, obtained)
+	}
+	return
+}
+
+// Start streaming to the bound out audio interface, via the output callback
+func Start() (err error) {
+	if driver, ok := outputDriver(useOutput).(StreamingOutputDriver); ok {
+		err = driver.Start()
+	}
+	return
+}
+
+// OutputLatency of the bound out audio interface, between mixing a sample and hearing it, e.g. its buffer;
+// 0 for an interface that does not play in real time
+func OutputLatency() time.Duration {
+	if driver, ok := outputDriver(useOutput).(StreamingOutputDriver); ok {
+		return driver.OutputLatency()
+	}
+	return 0
+}
+
+// IsStreamingOutput is true if the bound out audio interface pulls samples on its own, e.g. hardware
+func IsStreamingOutput() bool {
+	_, ok := outputDriver(useOutput).(StreamingOutputDriver)
+	return ok
 }
 
+// IsDirectOutput is true if the bound out audio interface is pulled by OutputNext, e.g. to write WAV
 func IsDirectOutput() bool {
-	return useOutput == opt.OutputWAV
+	return useOutput != opt.OutputReader && outputDriver(useOutput) != nil && !IsStreamingOutput()
 }
 
 // SetMixNextOutFunc to stream mix out from mix
@@ -33,45 +63,69 @@ func SetOutputCallback(fn sample.OutNextCallbackFunc) {
 	sample.SetOutputCallback(fn)
 }
 
-// OutputStart requires a known length
+// SetOutputBlockCallback to stream a block of mix out from mix, directly into float32 frames, when the output is AudioF32
+func SetOutputBlockCallback(fn sample.OutNextBlockCallbackFunc) {
+	sample.SetOutputBlockCallback(fn)
+}
+
+// SetOutputMetadata written in the header of a WAV output started after, e.g. its title and artist, or nil for none (the default)
+func SetOutputMetadata(meta *spec.Metadata) {
+	wav.SetOutputMetadata(meta)
+}
+
+// OutputStart with a known length, or 0 to stream an unknown length
 func OutputStart(length time.Duration, out io.Writer) {
-	switch useOutput {
-	case opt.OutputWAV:
-		wav.OutputStart(length, out)
-	case opt.OutputNull:
-		// do nothing
+	if driver := outputDriver(useOutput); driver != nil {
+		driver.OutputStart(length, out)
 	}
 }
 
 // OutputNext using the configured writer.
-func OutputNext(numSamples spec.Tz) {
-	switch useOutput {
-	case opt.OutputWAV:
-		wav.OutputNext(numSamples)
-	case opt.OutputNull:
-		// do nothing
+func OutputNext(numSamples spec.Tz) (err error) {
+	if driver := outputDriver(useOutput); driver != nil {
+		err = driver.OutputNext(numSamples)
+	}
+	return
+}
+
+// OutputClose using the configured writer, flushing it if it is buffered, e.g. to patch the header of a streamed WAV.
+func OutputClose() (err error) {
+	if closer, ok := outputDriver(useOutput).(OutputCloser); ok {
+		err = closer.OutputClose()
 	}
+	return
 }
 
-// LoadWAV into a buffer
-func LoadWAV(file string) ([]sample.Sample, *spec.AudioSpec) {
-	switch useLoader {
-	case opt.InputWAV:
-		return wav.Load(file)
-	case opt.InputSOX:
-		return sox.Load(file)
-	default:
-		return make([]sample.Sample, 0), &spec.AudioSpec{}
+// LoadWAV into a buffer, via the selected loader (despite the name, not only WAV)
+func LoadWAV(file string) ([]sample.Sample, *spec.AudioSpec, error) {
+	if useLoader == opt.InputAuto {
+		return loadAuto(file)
 	}
+	if loader := loaderOf(useLoader); loader != nil {
+		return loader.Load(file)
+	}
+	return make([]sample.Sample, 0), &spec.AudioSpec{}, nil
+}
+
+// LoadBytes of encoded audio data into a buffer, via the selected loader; the name is only used to select a loader by file extension
+func LoadBytes(name string, data []byte) ([]sample.Sample, *spec.AudioSpec, error) {
+	selected := useLoader
+	if selected == opt.InputAuto {
+		var err error
+		if selected, err = loaderByExtension(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if loader := loaderOf(selected); loader != nil {
+		return loader.LoadBytes(data)
+	}
+	return make([]sample.Sample, 0), &spec.AudioSpec{}, nil
 }
 
 // Teardown to close all hardware bindings
 func Teardown() {
-	switch useOutput {
-	case opt.OutputWAV:
-		wav.TeardownOutput()
-	case opt.OutputNull:
-		// do nothing
+	if driver := outputDriver(useOutput); driver != nil {
+		driver.Teardown()
 	}
 }
 
@@ -80,16 +134,32 @@ func UseLoader(opt opt.Input) {
 	useLoader = opt
 }
 
-// UseLoaderString to select the file loading interface by string
+// UseLoaderString to select the file loading interface by the name it is registered under, or "auto"
 func UseLoaderString(loader string) {
-	switch loader {
-	case string(opt.InputWAV):
-		useLoader = opt.InputWAV
-	case string(opt.InputSOX):
-		useLoader = opt.InputSOX
-	default:
+	if loader != string(opt.InputAuto) && loaderOf(opt.Input(loader)) == nil {
 		panic("No such Loader: " + loader)
 	}
+	useLoader = opt.Input(loader)
+}
+
+// UseLoaderChecked is UseLoaderString, but returns an error instead of panicking if there is no such loader, or if it is not
+// available, see CheckedLoader, e.g. sox.ErrSoxNotFound; the loader is only selected if it can be used.
+func UseLoaderChecked(loader string) error {
+	if loader == string(opt.InputAuto) {
+		useLoader = opt.InputAuto
+		return nil
+	}
+	l := loaderOf(opt.Input(loader))
+	if l == nil {
+		return errors.New("No such Loader: " + loader)
+	}
+	if checked, ok := l.(CheckedLoader); ok {
+		if err := checked.Check(); err != nil {
+			return err
+		}
+	}
+	useLoader = opt.Input(loader)
+	return nil
 }
 
 // UseOutput to select the outback interface
@@ -97,16 +167,12 @@ func UseOutput(opt opt.Output) {
 	useOutput = opt
 }
 
-// UseOutputString to select the outback interface by string
+// UseOutputString to select the outback interface by the name it is registered under
 func UseOutputString(output string) {
-	switch output {
-	case string(opt.OutputWAV):
-		useOutput = opt.OutputWAV
-	case string(opt.OutputNull):
-		useOutput = opt.OutputNull
-	default:
+	if outputDriver(opt.Output(output)) == nil {
 		panic("No such Output: " + output)
 	}
+	useOutput = opt.Output(output)
 }
 
 //
@@ -117,3 +183,21 @@ var (
 	useLoader = opt.InputWAV
 	useOutput = opt.OutputNull
 )
+
+// loadAuto selects the loader by file extension
+func loadAuto(file string) ([]sample.Sample, *spec.AudioSpec, error) {
+	name, err := loaderByExtension(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loaderOf(name).Load(file)
+}
+
+// loaderByExtension of a file, the loader registered under its name, e.g. "mp3"
+func loaderByExtension(file string) (opt.Input, error) {
+	ext := strings.ToLower(filepath.Ext(file))
+	if name := opt.Input(strings.TrimPrefix(ext, ".")); name != opt.InputAuto && name != "" && loaderOf(name) != nil {
+		return name, nil
+	}
+	return "", errors.New("No loader for file extension: " + ext)
+}
@@ -2,11 +2,13 @@
 package bind
 
 import (
+	"io/ioutil"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
 )
 
 func TestAPI(t *testing.T) {
@@ -23,6 +25,60 @@ func TestAPI_UseWAVString(t *testing.T) {
 	assert.Equal(t, opt.InputWAV, useLoader)
 }
 
+func TestAPI_UseMP3String(t *testing.T) {
+	UseLoaderString("mp3")
+	assert.Equal(t, opt.InputMP3, useLoader)
+}
+
+func TestAPI_UseFLACString(t *testing.T) {
+	UseLoaderString("flac")
+	assert.Equal(t, opt.InputFLAC, useLoader)
+}
+
+func TestAPI_UseOGGString(t *testing.T) {
+	UseLoaderString("ogg")
+	assert.Equal(t, opt.InputOGG, useLoader)
+}
+
+func TestAPI_UseAutoString(t *testing.T) {
+	UseLoaderString("auto")
+	assert.Equal(t, opt.InputAuto, useLoader)
+}
+
+func TestAPI_LoadAuto(t *testing.T) {
+	UseLoader(opt.InputAuto)
+	defer UseLoader(opt.InputWAV)
+	_, specs, err := LoadWAV("mp3/testdata/Silence44100HzMono.mp3")
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioS16, specs.Format)
+	_, specs, err = LoadWAV("wav/testdata/Signed24bitMono.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioS24, specs.Format)
+	_, specs, err = LoadWAV("flac/testdata/Signed24bitStereo.flac")
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioS24, specs.Format)
+	_, specs, err = LoadWAV("ogg/testdata/Vorbis44100HzMono.ogg")
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioF32, specs.Format)
+	_, _, err = LoadWAV("wav/testdata/Unknown.aiff")
+	assert.EqualError(t, err, "No loader for file extension: .aiff")
+}
+
+func TestAPI_LoadBytes(t *testing.T) {
+	UseLoader(opt.InputAuto)
+	defer UseLoader(opt.InputWAV)
+	data, err := ioutil.ReadFile("wav/testdata/Signed16bitStereo.wav")
+	assert.Nil(t, err)
+	out, specs, err := LoadBytes("kick.wav", data)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, specs.Channels)
+	assert.Equal(t, 4, len(out))
+	_, _, err = LoadBytes("kick.mp3", data)
+	assert.NotNil(t, err)
+	_, _, err = LoadBytes("kick", data)
+	assert.EqualError(t, err, "No loader for file extension: ")
+}
+
 func TestAPI_UseWAVString_Fail(t *testing.T) {
 	defer func() {
 		msg := recover()
@@ -42,6 +98,18 @@ func TestAPI_UseOutputString(t *testing.T) {
 	assert.Equal(t, opt.OutputWAV, useOutput)
 }
 
+func TestAPI_UseOutputString_Raw(t *testing.T) {
+	UseOutputString("raw")
+	assert.Equal(t, opt.OutputRaw, useOutput)
+	assert.True(t, IsDirectOutput())
+}
+
+func TestAPI_UseOutputString_Reader(t *testing.T) {
+	UseOutputString("reader")
+	assert.Equal(t, opt.OutputReader, useOutput)
+	assert.False(t, IsStreamingOutput())
+}
+
 func TestAPI_UseOutputString_Fail(t *testing.T) {
 	defer func() {
 		msg := recover()
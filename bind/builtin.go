@@ -0,0 +1,150 @@
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-mix/mix/bind/flac"
+	"github.com/go-mix/mix/bind/hardware/null"
+	"github.com/go-mix/mix/bind/mp3"
+	"github.com/go-mix/mix/bind/ogg"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/raw"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/sox"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+)
+
+func init() {
+	RegisterOutput(string(opt.OutputWAV), wavOutput{})
+	RegisterOutput(string(opt.OutputRaw), rawOutput{})
+	RegisterOutput(string(opt.OutputReader), readerOutput{})
+	RegisterOutput(string(opt.OutputNull), nullOutput{})
+	RegisterLoader(string(opt.InputWAV), wavLoader{})
+	RegisterLoader(string(opt.InputSOX), soxLoader{funcLoader{sox.Load, sox.LoadBytes}})
+	RegisterLoader(string(opt.InputMP3), funcLoader{mp3.Load, mp3.LoadBytes})
+	RegisterLoader(string(opt.InputFLAC), funcLoader{flac.Load, flac.LoadBytes})
+	RegisterLoader(string(opt.InputOGG), funcLoader{ogg.Load, ogg.LoadBytes})
+}
+
+//
+// Private
+//
+
+// wavOutput of WAV bytes to a writer
+type wavOutput struct{}
+
+func (wavOutput) ConfigureOutput(s spec.AudioSpec) (spec.AudioSpec, error) {
+	return wav.ConfigureOutput(s), nil
+}
+
+func (wavOutput) OutputStart(length time.Duration, w io.Writer) {
+	wav.OutputStart(length, w)
+}
+
+func (wavOutput) OutputNext(numSamples spec.Tz) error {
+	return wav.OutputNext(numSamples)
+}
+
+func (wavOutput) OutputClose() error {
+	return wav.OutputClose()
+}
+
+func (wavOutput) Teardown() {
+	wav.TeardownOutput()
+}
+
+// rawOutput of headerless PCM bytes to a writer
+type rawOutput struct{}
+
+func (rawOutput) ConfigureOutput(s spec.AudioSpec) (spec.AudioSpec, error) {
+	raw.ConfigureOutput(s)
+	return s, nil
+}
+
+func (rawOutput) OutputStart(length time.Duration, w io.Writer) {
+	raw.OutputStart(w)
+}
+
+func (rawOutput) OutputNext(numSamples spec.Tz) error {
+	return raw.OutputNext(numSamples)
+}
+
+func (rawOutput) OutputClose() error {
+	return raw.OutputClose()
+}
+
+func (rawOutput) Teardown() {
+	raw.TeardownOutput()
+}
+
+// readerOutput does nothing, because the mix is pulled via an io.Reader instead
+type readerOutput struct{}
+
+func (readerOutput) ConfigureOutput(s spec.AudioSpec) (spec.AudioSpec, error) { return s, nil }
+func (readerOutput) OutputStart(length time.Duration, w io.Writer)            {}
+func (readerOutput) OutputNext(numSamples spec.Tz) error                      { return nil }
+func (readerOutput) Teardown()                                                {}
+
+// nullOutput pulls samples as fast as possible, and discards them, e.g. for benchmarking
+type nullOutput struct{}
+
+func (nullOutput) ConfigureOutput(s spec.AudioSpec) (spec.AudioSpec, error) {
+	null.ConfigureOutput(s)
+	return s, nil
+}
+
+func (nullOutput) OutputStart(length time.Duration, w io.Writer) {}
+func (nullOutput) OutputNext(numSamples spec.Tz) error           { return nil }
+
+func (nullOutput) Start() error {
+	null.Start()
+	return nil
+}
+
+func (nullOutput) OutputLatency() time.Duration { return 0 }
+func (nullOutput) Teardown()                    {}
+
+// wavLoader of WAV files, which can also stream
+type wavLoader struct{}
+
+func (wavLoader) Load(file string) ([]sample.Sample, *spec.AudioSpec, error) {
+	return wav.Load(file)
+}
+
+func (wavLoader) LoadBytes(data []byte) ([]sample.Sample, *spec.AudioSpec, error) {
+	return wav.LoadBytes(data)
+}
+
+func (wavLoader) OpenStream(file string) (Stream, error) {
+	s, err := wav.OpenStream(file)
+	if err != nil {
+		return nil, err // not a nil *wav.Stream in a non-nil Stream
+	}
+	return s, nil
+}
+
+// funcLoader of a package with Load and LoadBytes functions
+type funcLoader struct {
+	load      func(file string) ([]sample.Sample, *spec.AudioSpec, error)
+	loadBytes func(data []byte) ([]sample.Sample, *spec.AudioSpec, error)
+}
+
+func (l funcLoader) Load(file string) ([]sample.Sample, *spec.AudioSpec, error) {
+	return l.load(file)
+}
+
+func (l funcLoader) LoadBytes(data []byte) ([]sample.Sample, *spec.AudioSpec, error) {
+	return l.loadBytes(data)
+}
+
+// soxLoader of any format that the sox binary can decode, if it is installed
+type soxLoader struct {
+	funcLoader
+}
+
+func (soxLoader) Check() error {
+	return sox.Check()
+}
@@ -0,0 +1,44 @@
+//go:build portaudio
+// +build portaudio
+
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-mix/mix/bind/hardware/portaudio"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func init() {
+	RegisterOutput(string(opt.OutputPortAudio), portaudioOutput{})
+}
+
+//
+// Private
+//
+
+// portaudioOutput to a PortAudio hardware interface, only built with the portaudio tag, because it requires cgo and portaudio-2.0
+type portaudioOutput struct{}
+
+func (portaudioOutput) ConfigureOutput(s spec.AudioSpec) (spec.AudioSpec, error) {
+	return portaudio.ConfigureOutput(s)
+}
+
+func (portaudioOutput) OutputStart(length time.Duration, w io.Writer) {}
+func (portaudioOutput) OutputNext(numSamples spec.Tz) error           { return nil }
+
+func (portaudioOutput) Start() error {
+	return portaudio.Start()
+}
+
+func (portaudioOutput) OutputLatency() time.Duration {
+	return portaudio.OutputLatency()
+}
+
+func (portaudioOutput) Teardown() {
+	portaudio.TeardownOutput()
+}
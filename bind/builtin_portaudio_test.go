@@ -0,0 +1,19 @@
+//go:build portaudio
+// +build portaudio
+
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/opt"
+)
+
+func TestAPI_UseOutputString_PortAudio(t *testing.T) {
+	UseOutputString("portaudio")
+	assert.Equal(t, opt.OutputPortAudio, useOutput)
+	assert.False(t, IsDirectOutput())
+}
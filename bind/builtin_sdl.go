@@ -0,0 +1,45 @@
+//go:build sdl
+// +build sdl
+
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-mix/mix/bind/hardware/sdl"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func init() {
+	RegisterOutput(string(opt.OutputSDL), sdlOutput{})
+}
+
+//
+// Private
+//
+
+// sdlOutput to an SDL2 hardware interface, only built with the sdl tag, because it requires cgo and sdl2
+type sdlOutput struct{}
+
+func (sdlOutput) ConfigureOutput(s spec.AudioSpec) (spec.AudioSpec, error) {
+	return sdl.ConfigureOutput(s)
+}
+
+func (sdlOutput) OutputStart(length time.Duration, w io.Writer) {}
+func (sdlOutput) OutputNext(numSamples spec.Tz) error           { return nil }
+
+func (sdlOutput) Start() error {
+	sdl.Start()
+	return nil
+}
+
+func (sdlOutput) OutputLatency() time.Duration {
+	return sdl.OutputLatency()
+}
+
+func (sdlOutput) Teardown() {
+	sdl.TeardownOutput()
+}
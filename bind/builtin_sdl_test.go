@@ -0,0 +1,19 @@
+//go:build sdl
+// +build sdl
+
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/opt"
+)
+
+func TestAPI_UseOutputString_SDL(t *testing.T) {
+	UseOutputString("sdl")
+	assert.Equal(t, opt.OutputSDL, useOutput)
+	assert.False(t, IsDirectOutput())
+}
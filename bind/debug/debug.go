@@ -1,37 +1,107 @@
-// Package debug for debugging
+// Package debug for debugging, via a pluggable logger that is silent unless one is set
 package debug
 
 import (
-	"log"
+	"fmt"
+	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 )
 
-// Debug ON/OFF (ripples down to all sub-modules)
-func Configure(active bool) {
-	isActive = active
+// Logger receives the messages of the mixer at each level, e.g. an adapter to a structured logger.
+// It may be called from the mix loop, so it must be safe for concurrent use and should not block.
+type Logger interface {
+	Debugf(format string, args ...interface{}) // e.g. mix cycle stats and fire lifecycle
+	Infof(format string, args ...interface{})  // e.g. source load events and output configuration
+	Warnf(format string, args ...interface{})  // e.g. a source that cannot be loaded
+}
+
+// SetLogger to receive all messages of the mixer, or nil to silence them (the default).
+func SetLogger(l Logger) {
+	logger.Store(holder{l})
 }
 
-// Printf only when debug is active
-func Printf(format string, args ...interface{}) {
-	if isActive {
-		logger.Printf(format, args...)
+// Configure debug ON/OFF (ripples down to all sub-modules), as a convenience that sets a logger to stderr, never stdout,
+// which may be the output audio stream.
+func Configure(active bool) {
+	if active {
+		SetLogger(NewLogger(os.Stderr))
+	} else {
+		SetLogger(nil)
 	}
 }
 
-// Active returns current state of debug
+// Active returns true if a logger is set
 func Active() bool {
-	return isActive
+	return current() != nil
+}
+
+// Debugf to the logger, if one is set
+func Debugf(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		l.Debugf(format, args...)
+	}
+}
+
+// Infof to the logger, if one is set
+func Infof(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		l.Infof(format, args...)
+	}
+}
+
+// Warnf to the logger, if one is set
+func Warnf(format string, args ...interface{}) {
+	if l := current(); l != nil {
+		l.Warnf(format, args...)
+	}
+}
+
+// NewLogger writing one line of plain text per message to w, prefixed by its level
+func NewLogger(w io.Writer) Logger {
+	return &textLogger{w: w}
 }
 
 //
 // Private
 //
 
-var (
-	isActive bool
-	logger   *log.Logger
-)
+var logger atomic.Value // of holder
+
+// holder of the logger, because an atomic.Value cannot store nil, nor interfaces of differing concrete types
+type holder struct {
+	Logger
+}
+
+func current() Logger {
+	h, _ := logger.Load().(holder)
+	return h.Logger
+}
+
+type textLogger struct {
+	w     io.Writer
+	mutex sync.Mutex
+}
 
-func init() {
-	logger = log.New(os.Stderr, "", 0)
+func (t *textLogger) Debugf(format string, args ...interface{}) {
+	t.printf("DEBUG", format, args...)
+}
+
+func (t *textLogger) Infof(format string, args ...interface{}) {
+	t.printf("INFO", format, args...)
+}
+
+func (t *textLogger) Warnf(format string, args ...interface{}) {
+	t.printf("WARN", format, args...)
+}
+
+func (t *textLogger) printf(level string, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if len(msg) == 0 || msg[len(msg)-1] != '\n' {
+		msg += "\n"
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	fmt.Fprintf(t.w, "%s %s", level, msg)
 }
@@ -1,2 +1,69 @@
 // Package debug for debugging
 package debug
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(nil)
+	l := &testLogger{}
+	SetLogger(l)
+	assert.True(t, Active())
+	Debugf("cycle %d", 1)
+	Infof("loaded %s", "kick.wav")
+	Warnf("failed %s", "snare.wav")
+	assert.Equal(t, []string{"debug: cycle 1", "info: loaded kick.wav", "warn: failed snare.wav"}, l.lines)
+	SetLogger(nil)
+	assert.False(t, Active())
+	Warnf("silent")
+	assert.Equal(t, 3, len(l.lines))
+}
+
+func TestConfigure(t *testing.T) {
+	defer SetLogger(nil)
+	Configure(true)
+	assert.True(t, Active())
+	Configure(false)
+	assert.False(t, Active())
+}
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf)
+	l.Infof("loaded %s\n", "kick.wav")
+	l.Warnf("failed %s", "snare.wav")
+	assert.Equal(t, "INFO loaded kick.wav\nWARN failed snare.wav\n", buf.String())
+}
+
+//
+// Test Components
+//
+
+type testLogger struct {
+	lines []string
+	mutex sync.Mutex
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {
+	l.add("debug: "+format, args...)
+}
+
+func (l *testLogger) Infof(format string, args ...interface{}) {
+	l.add("info: "+format, args...)
+}
+
+func (l *testLogger) Warnf(format string, args ...interface{}) {
+	l.add("warn: "+format, args...)
+}
+
+func (l *testLogger) add(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,93 @@
+// Package flac is direct FLAC file input, via the pure-Go mewkiz/flac decoder
+package flac
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mewkiz/flac"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// MaxChannels that can be loaded; files with more channels are rejected rather than guessing at a downmix
+const MaxChannels = 2
+
+// Load a FLAC file into memory
+func Load(path string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = errors.New("File not found: " + path)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return decode(bufio.NewReader(file), "FLAC "+path)
+}
+
+// LoadBytes of FLAC data into memory, e.g. from an embedded asset
+func LoadBytes(data []byte) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	return decode(bytes.NewReader(data), "FLAC")
+}
+
+//
+// Private
+//
+
+func decode(in io.Reader, name string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	stream, err := flac.New(in)
+	if err != nil {
+		err = errors.New("Cannot decode " + name + ": " + err.Error())
+		return
+	}
+	channels := int(stream.Info.NChannels)
+	if channels > MaxChannels {
+		err = fmt.Errorf("Unsupported FLAC channel count: %d (maximum is %d)", channels, MaxChannels)
+		return
+	}
+	bitsPerSample := stream.Info.BitsPerSample
+	specs = &spec.AudioSpec{
+		Freq:     float64(stream.Info.SampleRate),
+		Format:   formatOf(bitsPerSample),
+		Channels: channels,
+	}
+	scale := sample.Value(int64(1) << (bitsPerSample - 1))
+	out = make([]sample.Sample, 0, stream.Info.NSamples)
+	for {
+		frame, parseErr := stream.ParseNext()
+		if parseErr == io.EOF {
+			break
+		} else if parseErr != nil {
+			err = errors.New("Cannot decode " + name + ": " + parseErr.Error())
+			return
+		}
+		for i := 0; i < int(frame.BlockSize); i++ {
+			values := make([]sample.Value, channels)
+			for c := range values {
+				values[c] = sample.Value(frame.Subframes[c].Samples[i]) / scale
+			}
+			out = append(out, sample.New(values))
+		}
+	}
+	return
+}
+
+func formatOf(bitsPerSample uint8) spec.AudioFormat {
+	switch {
+	case bitsPerSample <= 8:
+		return spec.AudioS8
+	case bitsPerSample <= 16:
+		return spec.AudioS16
+	case bitsPerSample <= 24:
+		return spec.AudioS24
+	default:
+		return spec.AudioS32
+	}
+}
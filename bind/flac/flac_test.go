@@ -0,0 +1,52 @@
+// Package flac is direct FLAC file input, via the pure-Go mewkiz/flac decoder
+package flac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestLoad(t *testing.T) {
+	expect := []float64{0, 0.5, -0.5, 0.25}
+	for _, tc := range []struct {
+		file     string
+		format   spec.AudioFormat
+		channels int
+	}{
+		{"testdata/Signed16bitMono.flac", spec.AudioS16, 1},
+		{"testdata/Signed16bitStereo.flac", spec.AudioS16, 2},
+		{"testdata/Signed24bitMono.flac", spec.AudioS24, 1},
+		{"testdata/Signed24bitStereo.flac", spec.AudioS24, 2},
+	} {
+		out, specs, err := Load(tc.file)
+		assert.Nil(t, err, tc.file)
+		assert.Equal(t, float64(44100), specs.Freq, tc.file)
+		assert.Equal(t, tc.format, specs.Format, tc.file)
+		assert.Equal(t, tc.channels, specs.Channels, tc.file)
+		assert.Equal(t, len(expect), len(out), tc.file)
+		for i, smp := range out {
+			assert.Equal(t, expect[i], float64(smp.Values[0]), tc.file)
+			if tc.channels == 2 {
+				assert.Equal(t, -expect[i], float64(smp.Values[1]), tc.file)
+			}
+		}
+	}
+}
+
+func TestLoad_TooManyChannels(t *testing.T) {
+	_, _, err := Load("testdata/Signed16bit6Channel.flac")
+	assert.EqualError(t, err, "Unsupported FLAC channel count: 6 (maximum is 2)")
+}
+
+func TestLoad_DecodeError(t *testing.T) {
+	_, _, err := Load("../mp3/testdata/NotAnMP3.mp3")
+	assert.NotNil(t, err)
+}
+
+func TestLoad_FileNotFound(t *testing.T) {
+	_, _, err := Load("testdata/ThisShouldFailBecauseItDoesNotExist.flac")
+	assert.EqualError(t, err, "File not found: testdata/ThisShouldFailBecauseItDoesNotExist.flac")
+}
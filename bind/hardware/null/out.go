@@ -2,15 +2,29 @@
 package null
 
 import (
+	"sync"
+
 	"github.com/go-mix/mix/bind/sample"
 	"github.com/go-mix/mix/bind/spec"
 )
 
 func ConfigureOutput(s spec.AudioSpec) {
-	go func() {
-		for {
-			sample.OutNextBytes()
-		}
-	}()
 	// nothing to do
 }
+
+// Start pulling samples from the output callback as fast as possible; only the first call starts pulling
+func Start() {
+	startOnce.Do(func() {
+		go func() {
+			for {
+				sample.OutNextBytes()
+			}
+		}()
+	})
+}
+
+//
+// Private
+//
+
+var startOnce sync.Once
@@ -0,0 +1,146 @@
+//go:build portaudio
+// +build portaudio
+
+// Package portaudio is for modular binding of mix to a PortAudio hardware interface
+package portaudio
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// UseDeviceIndex to select an output device by its index in the list of all devices, e.g. on a multi-interface machine
+func UseDeviceIndex(index int) {
+	useDeviceIndex = index
+	useDeviceName = ""
+}
+
+// UseDeviceName to select an output device by name, e.g. on a multi-interface machine
+func UseDeviceName(name string) {
+	useDeviceName = name
+	useDeviceIndex = -1
+}
+
+// DeviceNames lists all output devices, in order of their index
+func DeviceNames() (names []string, err error) {
+	if err = portaudio.Initialize(); err != nil {
+		return
+	}
+	defer portaudio.Terminate()
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return
+	}
+	for _, device := range devices {
+		names = append(names, device.Name)
+	}
+	return
+}
+
+// ConfigureOutput opens a stream of float32 frames on the selected device, to be driven by the output callback, closing any stream
+// it opened before; returns the spec obtained, which is always AudioF32, at the sample rate of the stream
+func ConfigureOutput(s spec.AudioSpec) (obtained spec.AudioSpec, err error) {
+	TeardownOutput()
+	if err = portaudio.Initialize(); err != nil {
+		return obtained, fmt.Errorf("Cannot initialize PortAudio: %s", err)
+	}
+	device, err := outputDevice()
+	if err != nil {
+		portaudio.Terminate()
+		return
+	}
+	params := portaudio.HighLatencyParameters(nil, device)
+	params.Output.Channels = s.Channels
+	params.SampleRate = s.Freq
+	outputChannels = s.Channels
+	stream, err = portaudio.OpenStream(params, streamCallback)
+	if err != nil {
+		stream = nil
+		portaudio.Terminate()
+		return obtained, fmt.Errorf("Cannot open PortAudio stream on %s: %s", device.Name, err)
+	}
+	obtained = s
+	obtained.Format = spec.AudioF32
+	if info := stream.Info(); info != nil && info.SampleRate > 0 {
+		obtained.Freq = info.SampleRate
+	}
+	return
+}
+
+// Start the stream, which must have been opened by ConfigureOutput
+func Start() (err error) {
+	if stream == nil {
+		return errors.New("Cannot start PortAudio stream: not configured")
+	}
+	if err = stream.Start(); err != nil {
+		return fmt.Errorf("Cannot start PortAudio stream: %s", err)
+	}
+	return
+}
+
+// OutputLatency of the open stream, between mixing a sample and hearing it, or 0 if no stream is open
+func OutputLatency() time.Duration {
+	if stream == nil {
+		return 0
+	}
+	return stream.Info().OutputLatency
+}
+
+// TeardownOutput closes the stream and terminates the library
+func TeardownOutput() {
+	if stream == nil {
+		return
+	}
+	stream.Stop()
+	stream.Close()
+	stream = nil
+	portaudio.Terminate()
+}
+
+//
+// Private
+//
+
+var (
+	stream         *portaudio.Stream
+	outputChannels int
+	useDeviceIndex = -1
+	useDeviceName  string
+)
+
+func outputDevice() (device *portaudio.DeviceInfo, err error) {
+	if useDeviceIndex < 0 && useDeviceName == "" {
+		device, err = portaudio.DefaultOutputDevice()
+		if err != nil || device == nil {
+			return nil, errors.New("No PortAudio output device available")
+		}
+		return
+	}
+	devices, err := portaudio.Devices()
+	if err != nil {
+		return
+	}
+	for i, d := range devices {
+		if i == useDeviceIndex || (useDeviceName != "" && d.Name == useDeviceName) {
+			if d.MaxOutputChannels == 0 {
+				return nil, fmt.Errorf("PortAudio device %s has no output channels", d.Name)
+			}
+			return d, nil
+		}
+	}
+	if useDeviceName != "" {
+		return nil, fmt.Errorf("No such PortAudio output device: %s", useDeviceName)
+	}
+	return nil, fmt.Errorf("No such PortAudio output device index: %d", useDeviceIndex)
+}
+
+// streamCallback fills an interleaved buffer of float32 frames, directly by the mix if it is configured as AudioF32
+func streamCallback(out []float32) {
+	sample.OutNextBlock(out, outputChannels)
+}
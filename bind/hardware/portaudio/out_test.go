@@ -0,0 +1,36 @@
+//go:build portaudio
+// +build portaudio
+
+// Package portaudio is for modular binding of mix to a PortAudio hardware interface
+package portaudio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+func TestUseDevice(t *testing.T) {
+	UseDeviceIndex(3)
+	assert.Equal(t, 3, useDeviceIndex)
+	assert.Equal(t, "", useDeviceName)
+	UseDeviceName("Built-in Output")
+	assert.Equal(t, -1, useDeviceIndex)
+	assert.Equal(t, "Built-in Output", useDeviceName)
+}
+
+func TestStreamCallback(t *testing.T) {
+	outputChannels = 2
+	sample.SetOutputCallback(func() []sample.Value {
+		return []sample.Value{0.5, -0.5}
+	})
+	out := make([]float32, 8)
+	streamCallback(out)
+	assert.Equal(t, []float32{0.5, -0.5, 0.5, -0.5, 0.5, -0.5, 0.5, -0.5}, out)
+}
+
+func TestStart_NotConfigured(t *testing.T) {
+	assert.EqualError(t, Start(), "Cannot start PortAudio stream: not configured")
+}
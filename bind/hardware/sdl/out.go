@@ -0,0 +1,148 @@
+//go:build sdl
+// +build sdl
+
+// Package sdl is for modular binding of mix to an SDL2 hardware interface
+package sdl
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// ConfigureOutput opens a (paused) SDL audio device, and returns the spec obtained, which may differ from the spec requested
+func ConfigureOutput(s spec.AudioSpec) (obtained spec.AudioSpec, err error) {
+	if err = sdl.InitSubSystem(sdl.INIT_AUDIO); err != nil {
+		return obtained, fmt.Errorf("Cannot initialize SDL audio: %s", err)
+	}
+	desired := sdl.AudioSpec{
+		Freq:     int32(s.Freq),
+		Format:   formatToSDL(s.Format),
+		Channels: uint8(s.Channels),
+		Samples:  bufferSamples,
+	}
+	var have sdl.AudioSpec
+	device, err = sdl.OpenAudioDevice("", false, &desired, &have, sdl.AUDIO_ALLOW_ANY_CHANGE)
+	if err != nil {
+		sdl.QuitSubSystem(sdl.INIT_AUDIO)
+		return obtained, fmt.Errorf("Cannot open SDL audio device: %s", err)
+	}
+	obtained = s
+	obtained.Freq = float64(have.Freq)
+	obtained.Channels = int(have.Channels)
+	if obtained.Format, err = formatFromSDL(have.Format); err != nil {
+		TeardownOutput()
+		return
+	}
+	bufferDur = time.Duration(have.Samples) * time.Second / time.Duration(have.Freq)
+	return
+}
+
+// Start the device, and keep its queue filled via the output callback
+func Start() {
+	stop = make(chan bool)
+	running.Add(1)
+	go feed(stop)
+	sdl.PauseAudioDevice(device, false)
+}
+
+// OutputLatency of the open device, which is kept about two buffers ahead of playback, or 0 if no device is open
+func OutputLatency() time.Duration {
+	if device == 0 {
+		return 0
+	}
+	return 2 * bufferDur
+}
+
+// TeardownOutput closes the device and the SDL audio subsystem, so that the host app can reinitialize later
+func TeardownOutput() {
+	if stop != nil {
+		close(stop)
+		running.Wait()
+		stop = nil
+	}
+	if device != 0 {
+		sdl.CloseAudioDevice(device)
+		device = 0
+		sdl.QuitSubSystem(sdl.INIT_AUDIO)
+	}
+}
+
+//
+// Private
+//
+
+const bufferSamples = 2048
+
+var (
+	device    sdl.AudioDeviceID
+	bufferDur time.Duration
+	stop      chan bool
+	running   sync.WaitGroup
+)
+
+// feed the device queue, keeping about two buffers ahead of playback; one buffer is reused, as SDL copies what is queued
+func feed(stop chan bool) {
+	defer running.Done()
+	var buf []byte
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		buf = buf[:0]
+		for n := 0; n < bufferSamples; n++ {
+			buf = append(buf, sample.OutNextBytes()...)
+		}
+		sdl.QueueAudio(device, buf)
+		for sdl.GetQueuedAudioSize(device) > uint32(len(buf)) {
+			select {
+			case <-stop:
+				return
+			case <-time.After(bufferDur / 4):
+			}
+		}
+	}
+}
+
+func formatToSDL(f spec.AudioFormat) sdl.AudioFormat {
+	switch f {
+	case spec.AudioU8:
+		return sdl.AUDIO_U8
+	case spec.AudioS8:
+		return sdl.AUDIO_S8
+	case spec.AudioU16:
+		return sdl.AUDIO_U16LSB
+	case spec.AudioS16:
+		return sdl.AUDIO_S16LSB
+	case spec.AudioS32:
+		return sdl.AUDIO_S32LSB
+	default: // SDL has no 24-bit or 64-bit format
+		return sdl.AUDIO_F32LSB
+	}
+}
+
+func formatFromSDL(f sdl.AudioFormat) (spec.AudioFormat, error) {
+	switch f {
+	case sdl.AUDIO_U8:
+		return spec.AudioU8, nil
+	case sdl.AUDIO_S8:
+		return spec.AudioS8, nil
+	case sdl.AUDIO_U16LSB:
+		return spec.AudioU16, nil
+	case sdl.AUDIO_S16LSB:
+		return spec.AudioS16, nil
+	case sdl.AUDIO_S32LSB:
+		return spec.AudioS32, nil
+	case sdl.AUDIO_F32LSB:
+		return spec.AudioF32, nil
+	default:
+		return "", fmt.Errorf("Unsupported SDL audio format: %#04x", uint16(f))
+	}
+}
@@ -0,0 +1,29 @@
+//go:build sdl
+// +build sdl
+
+// Package sdl is for modular binding of mix to an SDL2 hardware interface
+package sdl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestFormat(t *testing.T) {
+	for _, f := range []spec.AudioFormat{spec.AudioU8, spec.AudioS8, spec.AudioU16, spec.AudioS16, spec.AudioS32, spec.AudioF32} {
+		actual, err := formatFromSDL(formatToSDL(f))
+		assert.Nil(t, err)
+		assert.Equal(t, f, actual)
+	}
+	actual, err := formatFromSDL(formatToSDL(spec.AudioF64))
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioF32, actual)
+}
+
+func TestFormat_Unsupported(t *testing.T) {
+	_, err := formatFromSDL(0x9010) // big-endian 16-bit
+	assert.EqualError(t, err, "Unsupported SDL audio format: 0x9010")
+}
@@ -0,0 +1,292 @@
+// Package midi is Standard MIDI File input, of the notes timed by the tempo map of the file
+package midi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// Note of a MIDI file, timed since the start of the file
+type Note struct {
+	Track    int
+	Channel  int // 0 to 15
+	Key      int // note number, 0 to 127
+	Velocity int // 1 to 127
+	Begin    time.Duration
+	Length   time.Duration // until the note-off, or 0 if there is none
+}
+
+// Load the notes of a Standard MIDI File (format 0 or 1), in order of their beginning
+func Load(path string) (notes []Note, err error) {
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = errors.New("File not found: " + path)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return decode(bufio.NewReader(file), "MIDI "+path)
+}
+
+// LoadBytes of Standard MIDI File data, e.g. from an embedded asset
+func LoadBytes(data []byte) (notes []Note, err error) {
+	return decode(bytes.NewReader(data), "MIDI")
+}
+
+//
+// Private
+//
+
+// defaultTempo in microseconds per quarter note, 120 BPM, until the file sets one
+const defaultTempo = 500000
+
+// event of a track that matters to the notes, at an absolute tick
+type event struct {
+	tick     uint64
+	track    int
+	status   byte // of a channel message, or 0xFF for a tempo change
+	data1    int
+	data2    int
+	tempo    uint32 // microseconds per quarter note
+	sequence int    // in the file, to keep the order of events at the same tick
+}
+
+// tempoChange at a tick, and the time since the start of the file that it happens
+type tempoChange struct {
+	tick  uint64
+	tempo uint32
+	at    time.Duration
+}
+
+func decode(in io.Reader, name string) (notes []Note, err error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return
+	}
+	r := &reader{data: data}
+	if r.chunkID() != "MThd" {
+		err = errors.New("Cannot decode " + name + ": not a Standard MIDI File")
+		return
+	}
+	header := r.chunk()
+	if len(header) < 6 {
+		err = errors.New("Cannot decode " + name + ": header is too short")
+		return
+	}
+	format := binary.BigEndian.Uint16(header[0:2])
+	trackCount := int(binary.BigEndian.Uint16(header[2:4]))
+	division := binary.BigEndian.Uint16(header[4:6])
+	if format > 1 {
+		err = fmt.Errorf("Unsupported MIDI format: %d (must be 0 or 1)", format)
+		return
+	}
+	var events []event
+	for track := 0; track < trackCount && !r.done(); track++ {
+		if r.chunkID() != "MTrk" {
+			r.chunk() // skip an unknown chunk, as the specification requires
+			track--
+			continue
+		}
+		var trackEvents []event
+		if trackEvents, err = decodeTrack(r.chunk(), track, len(events)); err != nil {
+			err = errors.New("Cannot decode " + name + ": " + err.Error())
+			return
+		}
+		events = append(events, trackEvents...)
+	}
+	if r.err != nil {
+		err = errors.New("Cannot decode " + name + ": " + r.err.Error())
+		return
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].tick == events[j].tick {
+			return events[i].sequence < events[j].sequence
+		}
+		return events[i].tick < events[j].tick
+	})
+	notes = notesOf(events, timerOf(events, division))
+	return
+}
+
+// decodeTrack of a chunk into the events that matter to the notes, at their absolute tick
+func decodeTrack(data []byte, track int, sequence int) (events []event, err error) {
+	r := &reader{data: data}
+	var tick uint64
+	var running byte
+	for !r.done() {
+		tick += uint64(r.varLen())
+		status := r.byte()
+		if status < 0x80 { // running status
+			if running == 0 {
+				return nil, fmt.Errorf("data byte 0x%02X without a status in track %d", status, track)
+			}
+			r.pos--
+			status = running
+		}
+		switch {
+		case status == 0xFF: // meta event
+			kind := r.byte()
+			meta := r.bytes(int(r.varLen()))
+			if kind == 0x51 && len(meta) == 3 { // set tempo
+				events = append(events, event{tick: tick, track: track, status: 0xFF, sequence: sequence + len(events),
+					tempo: uint32(meta[0])<<16 | uint32(meta[1])<<8 | uint32(meta[2])})
+			} else if kind == 0x2F { // end of track
+				return events, r.err
+			}
+		case status == 0xF0 || status == 0xF7: // system exclusive
+			r.bytes(int(r.varLen()))
+			running = 0
+		case status >= 0xF0: // system common or real time, not expected in a file
+			return nil, fmt.Errorf("unexpected status 0x%02X in track %d", status, track)
+		default: // channel message
+			running = status
+			e := event{tick: tick, track: track, status: status, data1: int(r.byte()), sequence: sequence + len(events)}
+			if kind := status & 0xF0; kind != 0xC0 && kind != 0xD0 {
+				e.data2 = int(r.byte())
+			}
+			if kind := status & 0xF0; kind == 0x80 || kind == 0x90 {
+				events = append(events, e)
+			}
+		}
+	}
+	return events, r.err
+}
+
+// timerOf the ticks of a file, from its division and its tempo map, to the time since the start of the file
+func timerOf(events []event, division uint16) func(tick uint64) time.Duration {
+	if division&0x8000 != 0 { // SMPTE frames per second and ticks per frame, regardless of tempo
+		fps := float64(-int8(division >> 8))
+		if fps == 29 {
+			fps = 29.97
+		}
+		tickDur := float64(time.Second) / (fps * float64(division&0xFF))
+		return func(tick uint64) time.Duration {
+			return time.Duration(float64(tick) * tickDur)
+		}
+	}
+	ticksPerQuarter := float64(division)
+	if ticksPerQuarter == 0 {
+		ticksPerQuarter = 1
+	}
+	changes := []tempoChange{{0, defaultTempo, 0}}
+	for _, e := range events {
+		if e.status != 0xFF {
+			continue
+		}
+		last := changes[len(changes)-1]
+		at := last.at + durOfTicks(e.tick-last.tick, last.tempo, ticksPerQuarter)
+		if e.tick == last.tick {
+			changes[len(changes)-1] = tempoChange{e.tick, e.tempo, at}
+		} else {
+			changes = append(changes, tempoChange{e.tick, e.tempo, at})
+		}
+	}
+	return func(tick uint64) time.Duration {
+		i := sort.Search(len(changes), func(i int) bool {
+			return changes[i].tick > tick
+		}) - 1
+		return changes[i].at + durOfTicks(tick-changes[i].tick, changes[i].tempo, ticksPerQuarter)
+	}
+}
+
+func durOfTicks(ticks uint64, tempo uint32, ticksPerQuarter float64) time.Duration {
+	return time.Duration(float64(ticks) * float64(tempo) * float64(time.Microsecond) / ticksPerQuarter)
+}
+
+// notesOf the note-on and note-off events, each note-off ending the earliest open note of its track, channel and key
+func notesOf(events []event, timeOf func(tick uint64) time.Duration) (notes []Note) {
+	open := make(map[[3]int][]int) // indices of the notes that are on, by track, channel and key
+	for _, e := range events {
+		kind := e.status & 0xF0
+		if kind != 0x80 && kind != 0x90 {
+			continue
+		}
+		key := [3]int{e.track, int(e.status & 0x0F), e.data1}
+		at := timeOf(e.tick)
+		if kind == 0x90 && e.data2 > 0 {
+			open[key] = append(open[key], len(notes))
+			notes = append(notes, Note{
+				Track:    e.track,
+				Channel:  key[1],
+				Key:      e.data1,
+				Velocity: e.data2,
+				Begin:    at,
+			})
+		} else if on := open[key]; len(on) > 0 { // note-off, or note-on with zero velocity
+			notes[on[0]].Length = at - notes[on[0]].Begin
+			open[key] = on[1:]
+		}
+	}
+	return
+}
+
+// reader of big-endian chunks and variable-length quantities, which records the first error and then reads zeros
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) done() bool {
+	return r.err != nil || r.pos >= len(r.data)
+}
+
+func (r *reader) byte() byte {
+	if r.pos >= len(r.data) {
+		r.fail()
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *reader) bytes(n int) []byte {
+	if n < 0 || r.pos+n > len(r.data) {
+		r.fail()
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) chunkID() string {
+	return string(r.bytes(4))
+}
+
+func (r *reader) chunk() []byte {
+	length := r.bytes(4)
+	if length == nil {
+		return nil
+	}
+	return r.bytes(int(binary.BigEndian.Uint32(length)))
+}
+
+// varLen quantity of up to 4 bytes, 7 bits each, most significant first
+func (r *reader) varLen() (v uint32) {
+	for i := 0; i < 4; i++ {
+		b := r.byte()
+		v = v<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return
+		}
+	}
+	return
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = errors.New("unexpected end of data")
+	}
+}
@@ -0,0 +1,84 @@
+// Package midi is Standard MIDI File input, of the notes timed by the tempo map of the file
+package midi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBytes(t *testing.T) {
+	notes, err := LoadBytes(testFile(1, 480,
+		testTrack( // the tempo map, in its own track: 120 BPM, then 60 BPM after two beats
+			0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20,
+			0x87, 0x40, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40,
+		),
+		testTrack(
+			0x00, 0x99, 36, 127, // note-on
+			0x83, 0x60, 0x89, 36, 0, // note-off at 480
+			0x83, 0x60, 0x99, 38, 64, // note-on at 960
+			0x83, 0x60, 38, 0, // running status, note-on with zero velocity, at 1440
+			0x83, 0x60, 40, 100, // at 1920, never off
+		),
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, []Note{
+		{Track: 1, Channel: 9, Key: 36, Velocity: 127, Begin: 0, Length: 500 * time.Millisecond},
+		{Track: 1, Channel: 9, Key: 38, Velocity: 64, Begin: time.Second, Length: time.Second},
+		{Track: 1, Channel: 9, Key: 40, Velocity: 100, Begin: 3 * time.Second},
+	}, notes)
+}
+
+func TestLoadBytes_Format0(t *testing.T) {
+	notes, err := LoadBytes(testFile(0, 96,
+		testTrack(
+			0x00, 0xF0, 0x02, 0x7E, 0xF7, // system exclusive
+			0x00, 0xC0, 5, // program change, one data byte
+			0x60, 0x90, 60, 90,
+			0x60, 0x80, 60, 0,
+		),
+	))
+	assert.Nil(t, err)
+	assert.Equal(t, []Note{{Key: 60, Velocity: 90, Begin: 500 * time.Millisecond, Length: 500 * time.Millisecond}}, notes)
+}
+
+func TestLoadBytes_FAIL(t *testing.T) {
+	_, err := LoadBytes([]byte("RIFF"))
+	assert.NotNil(t, err)
+	_, err = LoadBytes(testFile(2, 96))
+	assert.NotNil(t, err)
+	_, err = LoadBytes(testFile(0, 96, testTrack(0x00, 0x90, 60))[:20])
+	assert.NotNil(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	notes, err := Load("testdata/TempoChange.mid")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(notes))
+	assert.Equal(t, 3*time.Second, notes[3].Begin)
+}
+
+func TestLoad_FAIL(t *testing.T) {
+	_, err := Load("testdata/ThisShouldFailBecauseItDoesNotExist.mid")
+	assert.NotNil(t, err)
+}
+
+//
+// Test Components
+//
+
+func testFile(format int, division int, tracks ...[]byte) (data []byte) {
+	data = append(data, 'M', 'T', 'h', 'd', 0, 0, 0, 6)
+	data = append(data, byte(format>>8), byte(format), byte(len(tracks)>>8), byte(len(tracks)), byte(division>>8), byte(division))
+	for _, track := range tracks {
+		data = append(data, track...)
+	}
+	return
+}
+
+func testTrack(events ...byte) (data []byte) {
+	events = append(events, 0x00, 0xFF, 0x2F, 0x00)
+	data = append(data, 'M', 'T', 'r', 'k', 0, 0, byte(len(events)>>8), byte(len(events)))
+	return append(data, events...)
+}
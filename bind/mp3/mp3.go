@@ -0,0 +1,81 @@
+// Package mp3 is direct MP3 file input, via the pure-Go go-mp3 decoder
+package mp3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/hajimehoshi/go-mp3"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Load an MP3 file into memory
+func Load(path string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = errors.New("File not found: " + path)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	out, specs, err = decode(file)
+	if err != nil {
+		err = errors.New("Cannot decode MP3 " + path + ": " + err.Error())
+	}
+	return
+}
+
+// LoadBytes of MP3 data into memory, e.g. from an embedded asset
+func LoadBytes(data []byte) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	out, specs, err = decode(bytes.NewReader(data))
+	if err != nil {
+		err = errors.New("Cannot decode MP3: " + err.Error())
+	}
+	return
+}
+
+//
+// Private
+//
+
+func decode(in io.Reader) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	decoder, err := mp3.NewDecoder(in)
+	if err != nil {
+		return
+	}
+	specs = &spec.AudioSpec{
+		Freq:     float64(decoder.SampleRate()),
+		Format:   spec.AudioS16,
+		Channels: decoderChannels,
+	}
+	buffer := make([]byte, chunkSize*bytesPerSample)
+	for {
+		n, readErr := io.ReadFull(decoder, buffer)
+		for offset := 0; offset+bytesPerSample <= n; offset += bytesPerSample {
+			out = append(out, sample.New([]sample.Value{
+				sample.ValueOfBytesS16LSB(buffer[offset : offset+2]),
+				sample.ValueOfBytesS16LSB(buffer[offset+2 : offset+4]),
+			}))
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		} else if readErr != nil {
+			err = readErr
+			return
+		}
+	}
+	return
+}
+
+// the decoder always outputs 16-bit little-endian stereo, even for a mono MP3
+const (
+	decoderChannels = 2
+	bytesPerSample  = 4
+	chunkSize       = 2048
+)
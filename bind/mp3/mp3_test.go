@@ -0,0 +1,33 @@
+// Package mp3 is direct MP3 file input, via the pure-Go go-mp3 decoder
+package mp3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestLoad(t *testing.T) {
+	out, specs, err := Load("testdata/Silence44100HzMono.mp3")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(44100), specs.Freq)
+	assert.Equal(t, spec.AudioS16, specs.Format)
+	assert.Equal(t, 2, specs.Channels)
+	assert.Equal(t, 8*1152, len(out))
+	for _, smp := range out {
+		assert.Equal(t, 2, len(smp.Values))
+		assert.Equal(t, float64(0), float64(smp.Values[0]))
+	}
+}
+
+func TestLoad_DecodeError(t *testing.T) {
+	_, _, err := Load("testdata/NotAnMP3.mp3")
+	assert.NotNil(t, err)
+}
+
+func TestLoad_FileNotFound(t *testing.T) {
+	_, _, err := Load("testdata/ThisShouldFailBecauseItDoesNotExist.mp3")
+	assert.EqualError(t, err, "File not found: testdata/ThisShouldFailBecauseItDoesNotExist.mp3")
+}
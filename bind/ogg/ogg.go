@@ -0,0 +1,90 @@
+// Package ogg is direct Ogg Vorbis file input, via the pure-Go jfreymuth/oggvorbis decoder
+package ogg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jfreymuth/oggvorbis"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// MaxChannels that can be loaded; files with more channels are rejected rather than guessing at a downmix
+const MaxChannels = 2
+
+// ChunkSize of samples to decode at a time
+const ChunkSize = 2048
+
+// Load an Ogg Vorbis file into memory
+func Load(path string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = errors.New("File not found: " + path)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	return decode(file, "OGG "+path)
+}
+
+// LoadBytes of Ogg Vorbis data into memory, e.g. from an embedded asset
+func LoadBytes(data []byte) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	return decode(bytes.NewReader(data), "OGG")
+}
+
+//
+// Private
+//
+
+func decode(in io.ReadSeeker, name string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	format, err := oggvorbis.GetFormat(in)
+	if err != nil {
+		err = errors.New("Cannot decode " + name + ": " + err.Error())
+		return
+	}
+	if format.Channels > MaxChannels {
+		err = fmt.Errorf("Unsupported OGG channel count: %d (maximum is %d)", format.Channels, MaxChannels)
+		return
+	}
+	if _, err = in.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	reader, err := oggvorbis.NewReader(in)
+	if err != nil {
+		err = errors.New("Cannot decode " + name + ": " + err.Error())
+		return
+	}
+	specs = &spec.AudioSpec{
+		Freq:     float64(reader.SampleRate()),
+		Format:   spec.AudioF32,
+		Channels: reader.Channels(),
+	}
+	buffer := make([]float32, ChunkSize*specs.Channels)
+	for {
+		n, readErr := reader.Read(buffer)
+		for offset := 0; offset+specs.Channels <= n; offset += specs.Channels {
+			values := make([]sample.Value, specs.Channels)
+			for c := range values {
+				values[c] = sample.Value(buffer[offset+c])
+			}
+			out = append(out, sample.New(values))
+		}
+		if readErr == io.EOF {
+			break
+		} else if readErr != nil {
+			err = errors.New("Cannot decode " + name + ": " + readErr.Error())
+			return
+		} else if n == 0 {
+			err = errors.New("Cannot decode " + name + ": " + io.ErrNoProgress.Error())
+			return
+		}
+	}
+	return
+}
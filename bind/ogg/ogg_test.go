@@ -0,0 +1,48 @@
+// Package ogg is direct Ogg Vorbis file input, via the pure-Go jfreymuth/oggvorbis decoder
+package ogg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestLoad(t *testing.T) {
+	out, specs, err := Load("testdata/Vorbis44100HzMono.ogg")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(44100), specs.Freq)
+	assert.Equal(t, spec.AudioF32, specs.Format)
+	assert.Equal(t, 1, specs.Channels)
+	assert.Equal(t, 44100, len(out))
+	// fixture and reference values are from the jfreymuth/oggvorbis test suite (a libvorbis decode)
+	for at, expect := range map[int]float64{
+		0:     0.005767822265625,
+		1000:  0.73016357421875,
+		11025: -0.32611083984375,
+		22050: 0.0,
+		33075: 0.08172607421875,
+		44099: 0.014007568359375,
+	} {
+		assert.InDelta(t, expect, float64(out[at].Values[0]), 0.00002, "at %d", at)
+	}
+	for _, smp := range out {
+		assert.True(t, smp.Values[0] >= -1 && smp.Values[0] <= 1)
+	}
+}
+
+func TestLoad_TooManyChannels(t *testing.T) {
+	_, _, err := Load("testdata/Vorbis6Channel.ogg")
+	assert.EqualError(t, err, "Unsupported OGG channel count: 6 (maximum is 2)")
+}
+
+func TestLoad_DecodeError(t *testing.T) {
+	_, _, err := Load("../mp3/testdata/NotAnMP3.mp3")
+	assert.NotNil(t, err)
+}
+
+func TestLoad_FileNotFound(t *testing.T) {
+	_, _, err := Load("testdata/ThisShouldFailBecauseItDoesNotExist.ogg")
+	assert.EqualError(t, err, "File not found: testdata/ThisShouldFailBecauseItDoesNotExist.ogg")
+}
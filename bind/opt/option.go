@@ -6,8 +6,13 @@ type Input string
 
 // OptLoadWav to use Go-Native WAV file I/O
 const (
-	InputWAV Input = "wav"
-	InputSOX Input = "sox"
+	InputWAV  Input = "wav"
+	InputSOX  Input = "sox"
+	InputMP3  Input = "mp3"
+	InputFLAC Input = "flac"
+	InputOGG  Input = "ogg"
+	// InputAuto to select the loader by file extension, e.g. to mix .wav and .mp3 sources
+	InputAuto Input = "auto"
 )
 
 // OptOutput represents an audio output option
@@ -18,3 +23,15 @@ const OutputNull Output = "null"
 
 // OptOutputWAV to use WAV directly for []byte to stdout
 const OutputWAV Output = "wav"
+
+// OptOutputPortAudio to use a PortAudio hardware interface for real-time playback
+const OutputPortAudio Output = "portaudio"
+
+// OptOutputSDL to use an SDL2 hardware interface for real-time playback
+const OutputSDL Output = "sdl"
+
+// OptOutputReader to pull output via an io.Reader, instead of streaming to an audio interface
+const OutputReader Output = "reader"
+
+// OptOutputRaw to use headerless interleaved PCM directly for []byte to stdout
+const OutputRaw Output = "raw"
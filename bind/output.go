@@ -0,0 +1,83 @@
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+)
+
+// Output of one mix to a writer, offline, which pulls each sample from a callback of its own instead of the one set by
+// SetOutputCallback, e.g. of a mixer other than the one bound to the audio interface. It encodes WAV if the selected output
+// is WAV, else raw PCM; with an output that does not write, e.g. hardware, it pulls each sample and discards it.
+type Output struct {
+	next   sample.OutNextCallbackFunc
+	kind   opt.Output
+	spec   spec.AudioSpec
+	writer io.Writer
+	wav    *wav.Writer
+	meta   *spec.Metadata // written in the header of a WAV
+	frame  []byte         // reused to encode each sample
+}
+
+// NewOutput pulling each sample from a callback, in the kind of the output selected now
+func NewOutput(next sample.OutNextCallbackFunc) *Output {
+	return &Output{next: next, kind: useOutput}
+}
+
+// Configure the output, and return the spec obtained, e.g. the nearest format that WAV can encode
+func (o *Output) Configure(s spec.AudioSpec) (obtained spec.AudioSpec) {
+	obtained = s
+	if o.kind == opt.OutputWAV {
+		obtained.Format = wav.OutputFormat(s.Format)
+	}
+	o.spec = obtained
+	return
+}
+
+// SetMetadata written in the header of a WAV output started after, or nil for none (the default)
+func (o *Output) SetMetadata(meta *spec.Metadata) {
+	o.meta = meta
+}
+
+// Start writing with a known length, or 0 to stream an unknown length
+func (o *Output) Start(length time.Duration, out io.Writer) {
+	o.writer, o.wav = nil, nil
+	switch o.kind {
+	case opt.OutputWAV:
+		o.wav = wav.NewWriterWithMeta(out, wav.FormatFromSpec(&o.spec), length, o.meta)
+		o.writer = o.wav
+	case opt.OutputRaw:
+		o.writer = out
+	}
+}
+
+// Next samples, pulled from the callback and written, if there is a writer
+func (o *Output) Next(numSamples spec.Tz) (err error) {
+	for n := spec.Tz(0); n < numSamples; n++ {
+		smp := o.next()
+		if o.writer == nil {
+			continue
+		}
+		o.frame = sample.EncodeTo(o.frame[:0], o.spec.Format, smp)
+		if _, err = o.writer.Write(o.frame); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Close the output, flushing the writer if it is buffered, e.g. to patch the header of a streamed WAV
+func (o *Output) Close() (err error) {
+	if o.wav != nil {
+		err = o.wav.Close()
+	} else if flusher, ok := o.writer.(interface{ Flush() error }); ok {
+		err = flusher.Flush()
+	}
+	o.writer, o.wav = nil, nil
+	return
+}
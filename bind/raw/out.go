@@ -0,0 +1,55 @@
+// Package raw is direct headerless PCM output, e.g. for gstreamer's fdsrc or opusenc --raw
+package raw
+
+import (
+	"io"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func ConfigureOutput(s spec.AudioSpec) {
+	outputSpec = &s
+}
+
+// OutputStart records the writer; raw output has no header, so the length is not needed.
+func OutputStart(out io.Writer) {
+	writer = out
+}
+
+// OutputNext interleaved samples, encoded in the configured format, or pull and discard them if the output has not been started,
+// or has been closed
+func OutputNext(numSamples spec.Tz) (err error) {
+	for n := spec.Tz(0); n < numSamples; n++ {
+		if writer == nil {
+			sample.OutNextBytes()
+			continue
+		}
+		if _, err = writer.Write(sample.OutNextBytes()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// OutputClose flushes the writer, if it is buffered, e.g. a *bufio.Writer
+func OutputClose() (err error) {
+	if flusher, ok := writer.(interface{ Flush() error }); ok {
+		err = flusher.Flush()
+	}
+	writer = nil
+	return
+}
+
+func TeardownOutput() {
+	writer = nil
+}
+
+//
+// Private
+//
+
+var (
+	writer     io.Writer
+	outputSpec *spec.AudioSpec
+)
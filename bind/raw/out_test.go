@@ -0,0 +1,55 @@
+// Package raw is direct headerless PCM output, e.g. for gstreamer's fdsrc or opusenc --raw
+package raw
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestOutputNext(t *testing.T) {
+	for _, tc := range []struct {
+		format spec.AudioFormat
+		width  int
+	}{
+		{spec.AudioS16, 2},
+		{spec.AudioF32, 4},
+		{spec.AudioF64, 8},
+	} {
+		s := spec.AudioSpec{Freq: 44100, Format: tc.format, Channels: 2}
+		sample.ConfigureOutput(s)
+		sample.SetOutputCallback(func() []sample.Value {
+			return []sample.Value{0.5, -0.5}
+		})
+		ConfigureOutput(s)
+		var buf bytes.Buffer
+		OutputStart(&buf)
+		assert.Nil(t, OutputNext(10))
+		assert.Equal(t, 10*2*tc.width, buf.Len())
+		frame := sample.OutNextBytes() // one interleaved sample of all channels
+		assert.Equal(t, append(frame, frame...), buf.Bytes()[:2*len(frame)])
+		TeardownOutput()
+	}
+}
+
+func TestOutputClose(t *testing.T) {
+	s := spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 1}
+	sample.ConfigureOutput(s)
+	sample.SetOutputCallback(func() []sample.Value {
+		return []sample.Value{0.5}
+	})
+	ConfigureOutput(s)
+	var buf bytes.Buffer
+	OutputStart(bufio.NewWriter(&buf))
+	assert.Nil(t, OutputNext(10))
+	assert.Equal(t, 0, buf.Len()) // still buffered
+	assert.Nil(t, OutputClose())
+	assert.Equal(t, 10*2, buf.Len())
+	assert.Nil(t, OutputNext(10)) // closed, so pulled and discarded
+	assert.Equal(t, 10*2, buf.Len())
+}
@@ -0,0 +1,121 @@
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// OutputDriver of an audio interface, selected by the name it is registered under, see RegisterOutput. The mix is pulled
+// from the output callback, see sample.OutNextBytes, either by the driver on its own, if it is a StreamingOutputDriver,
+// or else a # of samples at a time by OutputNext.
+type OutputDriver interface {
+	ConfigureOutput(s spec.AudioSpec) (obtained spec.AudioSpec, err error) // obtained may differ from the spec requested
+	OutputStart(length time.Duration, w io.Writer)                         // with a known length, or 0 to stream an unknown length
+	OutputNext(numSamples spec.Tz) error                                   // pull and write samples, or discard them if not started
+	Teardown()                                                             // close the interface
+}
+
+// StreamingOutputDriver pulls samples from the output callback on its own, once started, e.g. hardware
+type StreamingOutputDriver interface {
+	OutputDriver
+	Start() error
+	OutputLatency() time.Duration // between mixing a sample and hearing it, e.g. its buffer
+}
+
+// OutputCloser is an OutputDriver that flushes its writer when the output is closed, e.g. to patch the header of a streamed WAV
+type OutputCloser interface {
+	OutputClose() error
+}
+
+// Loader of encoded audio, selected by the name it is registered under, see RegisterLoader
+type Loader interface {
+	Load(file string) ([]sample.Sample, *spec.AudioSpec, error)
+	LoadBytes(data []byte) ([]sample.Sample, *spec.AudioSpec, error)
+}
+
+// StreamLoader is a Loader that can also decode a file a block at a time, see OpenStream
+type StreamLoader interface {
+	Loader
+	OpenStream(file string) (Stream, error)
+}
+
+// CheckedLoader is a Loader that may not be available, e.g. it runs a binary that is not installed, see UseLoaderChecked
+type CheckedLoader interface {
+	Loader
+	Check() error
+}
+
+// RegisterOutput driver under a name, for UseOutputString, e.g. by a third-party binding in its init();
+// a name that is already registered, even a built-in, is replaced.
+func RegisterOutput(name string, driver OutputDriver) {
+	if driver == nil {
+		panic("Output driver is nil: " + name)
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	outputs[opt.Output(name)] = driver
+}
+
+// RegisterLoader under a name, for UseLoaderString, e.g. by a third-party binding in its init(); a name that is already registered,
+// even a built-in, is replaced. With the auto loader, a file is loaded by the loader registered under its extension, e.g. "aiff".
+func RegisterLoader(name string, loader Loader) {
+	if loader == nil {
+		panic("Loader is nil: " + name)
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	loaders[opt.Input(name)] = loader
+}
+
+// Outputs registered, in order of their name
+func Outputs() (names []string) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	for name := range outputs {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return
+}
+
+// Loaders registered, in order of their name
+func Loaders() (names []string) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	for name := range loaders {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return
+}
+
+//
+// Private
+//
+
+var (
+	outputs       = make(map[opt.Output]OutputDriver)
+	loaders       = make(map[opt.Input]Loader)
+	registryMutex = &sync.RWMutex{}
+)
+
+// outputDriver registered under a name, or nil
+func outputDriver(name opt.Output) OutputDriver {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return outputs[name]
+}
+
+// loaderOf a name, or nil
+func loaderOf(name opt.Input) Loader {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	return loaders[name]
+}
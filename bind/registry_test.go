@@ -0,0 +1,161 @@
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestRegisterOutput(t *testing.T) {
+	driver := &testOutput{}
+	RegisterOutput("test", driver)
+	assert.Contains(t, Outputs(), "test")
+	assert.Contains(t, Outputs(), string(opt.OutputWAV)) // a built-in is a registry entry too
+	UseOutputString("test")
+	defer UseOutput(opt.OutputNull)
+	assert.True(t, IsDirectOutput())
+	assert.False(t, IsStreamingOutput())
+	sample.SetOutputCallback(func() []sample.Value { return []sample.Value{0.5} })
+	obtained, err := Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioF64, Channels: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioF32, obtained.Format)
+	var out bytes.Buffer
+	OutputStart(time.Second, &out)
+	assert.Nil(t, OutputNext(3))
+	assert.Nil(t, OutputClose()) // not an OutputCloser
+	Teardown()
+	assert.Equal(t, time.Second, driver.length)
+	assert.Equal(t, 3*4, out.Len())
+	assert.True(t, driver.torn)
+}
+
+func TestRegisterOutput_Streaming(t *testing.T) {
+	driver := &testStreamingOutput{}
+	RegisterOutput("test-streaming", driver)
+	UseOutputString("test-streaming")
+	defer UseOutput(opt.OutputNull)
+	assert.True(t, IsStreamingOutput())
+	assert.False(t, IsDirectOutput())
+	assert.Nil(t, Start())
+	assert.True(t, driver.started)
+	assert.Equal(t, 20*time.Millisecond, OutputLatency())
+}
+
+func TestRegisterOutput_Nil(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterOutput("nil", nil)
+	})
+}
+
+func TestRegisterLoader(t *testing.T) {
+	RegisterLoader("snd", testLoader{})
+	assert.Contains(t, Loaders(), "snd")
+	UseLoaderString("snd")
+	defer UseLoader(opt.InputWAV)
+	out, specs, err := LoadWAV("kick.snd")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(out))
+	assert.Equal(t, 1, specs.Channels)
+	UseLoader(opt.InputAuto)
+	_, _, err = LoadWAV("kick.snd") // by the extension
+	assert.Nil(t, err)
+	_, _, err = LoadBytes("kick.snd", []byte{})
+	assert.Nil(t, err)
+	_, err = OpenStream("kick.snd")
+	assert.EqualError(t, err, "Cannot stream with loader: snd")
+}
+
+func TestUseLoaderChecked(t *testing.T) {
+	RegisterLoader("snd", testLoader{})
+	RegisterLoader("unavailable", testCheckedLoader{errors.New("not installed")})
+	defer UseLoader(opt.InputWAV)
+	assert.Nil(t, UseLoaderChecked("snd"))
+	assert.EqualError(t, UseLoaderChecked("unavailable"), "not installed")
+	assert.EqualError(t, UseLoaderChecked("nonexistent"), "No such Loader: nonexistent")
+	assert.Equal(t, opt.Input("snd"), useLoader) // unchanged by a failure
+	assert.Nil(t, UseLoaderChecked(string(opt.InputAuto)))
+}
+
+//
+// Private
+//
+
+// testOutput writes each sample pulled as F32
+type testOutput struct {
+	length time.Duration
+	writer io.Writer
+	torn   bool
+}
+
+func (o *testOutput) ConfigureOutput(s spec.AudioSpec) (spec.AudioSpec, error) {
+	s.Format = spec.AudioF32
+	return s, nil
+}
+
+func (o *testOutput) OutputStart(length time.Duration, w io.Writer) {
+	o.length, o.writer = length, w
+}
+
+func (o *testOutput) OutputNext(numSamples spec.Tz) error {
+	for n := spec.Tz(0); n < numSamples; n++ {
+		if _, err := o.writer.Write(sample.OutNextBytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *testOutput) Teardown() {
+	o.torn = true
+}
+
+type testStreamingOutput struct {
+	testOutput
+	started bool
+}
+
+func (o *testStreamingOutput) Start() error {
+	o.started = true
+	return nil
+}
+
+func (o *testStreamingOutput) OutputLatency() time.Duration {
+	return 20 * time.Millisecond
+}
+
+// testLoader of one sample of silence, for any file
+type testLoader struct{}
+
+func (testLoader) Load(file string) ([]sample.Sample, *spec.AudioSpec, error) {
+	return []sample.Sample{sample.New([]sample.Value{0})}, &spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1}, nil
+}
+
+func (l testLoader) LoadBytes(data []byte) ([]sample.Sample, *spec.AudioSpec, error) {
+	return l.Load("")
+}
+
+// testCheckedLoader that is not available
+type testCheckedLoader struct {
+	err error
+}
+
+func (l testCheckedLoader) Load(file string) ([]sample.Sample, *spec.AudioSpec, error) {
+	return nil, nil, l.err
+}
+
+func (l testCheckedLoader) LoadBytes(data []byte) ([]sample.Sample, *spec.AudioSpec, error) {
+	return nil, nil, l.err
+}
+
+func (l testCheckedLoader) Check() error {
+	return l.err
+}
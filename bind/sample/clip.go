@@ -0,0 +1,50 @@
+// Package sample models an audio sample
+package sample
+
+import "math"
+
+// ClipMode of the safety stage applied to each sample just before its conversion to the output format.
+// Whatever the mode, the integer formats saturate at full scale rather than wrap around.
+type ClipMode uint
+
+const (
+	// ClipHard at -1 and +1, and the default.
+	ClipHard ClipMode = iota
+	// ClipSoft by a tanh curve above the knee, which approaches -1 and +1 without reaching them, with less harsh distortion.
+	ClipSoft
+	// ClipNone passes the float formats through, even beyond full scale.
+	ClipNone
+)
+
+// ClipSoftKnee is the level above which ClipSoft bends the curve; below it, a sample is unchanged.
+const ClipSoftKnee = 0.8
+
+// Clip a sample of all channels in place, and return whether any channel was beyond full scale (before it was clipped).
+func Clip(mode ClipMode, values []Value) (clipped bool) {
+	for c, v := range values {
+		if v > 1 || v < -1 {
+			clipped = true
+		}
+		switch mode {
+		case ClipHard:
+			values[c] = Value(math.Max(-1, math.Min(1, float64(v))))
+		case ClipSoft:
+			values[c] = v.softClip()
+		}
+	}
+	return
+}
+
+//
+// Private
+//
+
+// softClip above the knee, by a tanh curve whose slope is 1 at the knee, so it is continuous with the unchanged level below it
+func (this Value) softClip() Value {
+	abs := math.Abs(float64(this))
+	if abs <= ClipSoftKnee {
+		return this
+	}
+	out := ClipSoftKnee + (1-ClipSoftKnee)*math.Tanh((abs-ClipSoftKnee)/(1-ClipSoftKnee))
+	return Value(math.Copysign(out, float64(this)))
+}
@@ -0,0 +1,30 @@
+// Package sample models an audio sample
+package sample
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClip(t *testing.T) {
+	values := []Value{0.5, 1.5, -3}
+	assert.True(t, Clip(ClipHard, values))
+	assert.Equal(t, []Value{0.5, 1, -1}, values)
+	assert.False(t, Clip(ClipHard, []Value{1, -1}))
+}
+
+func TestClip_Soft(t *testing.T) {
+	values := []Value{0.5, 0.9, 1.5, -3}
+	assert.True(t, Clip(ClipSoft, values))
+	assert.Equal(t, Value(0.5), values[0]) // below the knee
+	assert.True(t, values[1] > ClipSoftKnee && values[1] < 0.9)
+	assert.True(t, values[2] > values[1] && values[2] < 1)
+	assert.True(t, values[3] < -values[2] && values[3] > -1)
+}
+
+func TestClip_None(t *testing.T) {
+	values := []Value{1.5, -3}
+	assert.True(t, Clip(ClipNone, values))
+	assert.Equal(t, []Value{1.5, -3}, values)
+}
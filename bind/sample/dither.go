@@ -0,0 +1,64 @@
+// Package sample models an audio sample
+package sample
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// DitherMode of the noise added to each sample just before its conversion to an integer output format, which decorrelates the
+// quantization error from the signal, e.g. of a fade-out or a reverb tail rendered to 16-bit, at the cost of a little noise.
+type DitherMode uint
+
+const (
+	// DitherNone truncates each sample to the integer format, and the default.
+	DitherNone DitherMode = iota
+	// DitherTPDF adds noise of a triangular distribution, of ±1 LSB, then rounds; recommended for 16-bit renders.
+	DitherTPDF
+	// DitherShaped is DitherTPDF with first-order noise shaping, which feeds the quantization error back to push its noise
+	// toward high frequencies, where it is less audible.
+	DitherShaped
+)
+
+// Dither of each sample of all channels, with the noise of each channel independent, and the error of each fed back by
+// DitherShaped; it is deterministic given its seed.
+type Dither struct {
+	mode   DitherMode
+	random *rand.Rand
+	errors []float64
+}
+
+// NewDither of a mode for a # of channels, with its noise drawn from a seed
+func NewDither(mode DitherMode, channels int, seed int64) *Dither {
+	return &Dither{
+		mode:   mode,
+		random: rand.New(rand.NewSource(seed)),
+		errors: make([]float64, channels),
+	}
+}
+
+// Apply the dither to a sample of all channels in place, quantizing each to the integer format, such that its conversion is exact;
+// it does nothing for a float format, or DitherNone. Apply it after any limiter or clipper.
+func (d *Dither) Apply(format spec.AudioFormat, values []Value) {
+	if d == nil || d.mode == DitherNone || format.IsFloat() || format.Bits() == 0 {
+		return
+	}
+	scale := math.Ldexp(1, format.Bits()-1) // full scale, e.g. 0x8000 for 16-bit, as converted by ToInt16
+	for c, v := range values {
+		if math.IsNaN(float64(v)) {
+			continue
+		}
+		in := float64(v)
+		if d.mode == DitherShaped && c < len(d.errors) {
+			in -= d.errors[c]
+		}
+		noise := d.random.Float64() - d.random.Float64()
+		out := math.Max(-scale, math.Min(scale-1, math.Round(in*scale+noise))) / scale
+		if d.mode == DitherShaped && c < len(d.errors) {
+			d.errors[c] = out - in
+		}
+		values[c] = Value(out)
+	}
+}
@@ -0,0 +1,70 @@
+// Package sample models an audio sample
+package sample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestDither(t *testing.T) {
+	values := []Value{0.3, 0.3}
+	NewDither(DitherNone, 2, 1).Apply(spec.AudioS16, values)
+	assert.Equal(t, []Value{0.3, 0.3}, values)
+	NewDither(DitherTPDF, 2, 1).Apply(spec.AudioF32, values)
+	assert.Equal(t, []Value{0.3, 0.3}, values)
+	var nilDither *Dither
+	nilDither.Apply(spec.AudioS16, values)
+	assert.Equal(t, []Value{0.3, 0.3}, values)
+}
+
+func TestDither_TPDF(t *testing.T) {
+	a, b, other := NewDither(DitherTPDF, 2, 7), NewDither(DitherTPDF, 2, 7), NewDither(DitherTPDF, 2, 8)
+	differ, channelsDiffer := false, false
+	for n := 0; n < 100; n++ {
+		va, vb, vo := []Value{0.3, 0.3}, []Value{0.3, 0.3}, []Value{0.3, 0.3}
+		a.Apply(spec.AudioS16, va)
+		b.Apply(spec.AudioS16, vb)
+		other.Apply(spec.AudioS16, vo)
+		assert.Equal(t, va, vb) // deterministic given the seed
+		differ = differ || va[0] != vo[0]
+		channelsDiffer = channelsDiffer || va[0] != va[1]
+		for _, v := range va {
+			lsb := float64(v) * 0x8000
+			assert.Equal(t, math.Round(lsb), lsb) // quantized, so the conversion is exact
+			assert.InDelta(t, 0.3*0x8000, lsb, 1.5)
+			assert.Equal(t, int16(lsb), v.ToInt16())
+		}
+	}
+	assert.True(t, differ)
+	assert.True(t, channelsDiffer) // the noise of each channel is independent
+}
+
+// a level below 1 LSB, which truncation would silence, is kept on average by the dither
+func TestDither_BelowLSB(t *testing.T) {
+	for _, mode := range []DitherMode{DitherTPDF, DitherShaped} {
+		d := NewDither(mode, 1, 1)
+		in := Value(0.3) / 0x8000
+		assert.Equal(t, int16(0), in.ToInt16())
+		var sum float64
+		for n := 0; n < 10000; n++ {
+			v := []Value{in}
+			d.Apply(spec.AudioS16, v)
+			sum += float64(v[0].ToInt16())
+		}
+		assert.InDelta(t, 0.3, sum/10000, 0.05)
+	}
+}
+
+func TestDither_FullScale(t *testing.T) {
+	d := NewDither(DitherShaped, 2, 1)
+	for n := 0; n < 100; n++ {
+		values := []Value{1, -1}
+		d.Apply(spec.AudioS16, values)
+		assert.True(t, values[0] <= Value(0x7FFF)/0x8000)
+		assert.True(t, values[1] >= -1)
+	}
+}
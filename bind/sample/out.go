@@ -2,55 +2,126 @@
 package sample
 
 import (
+	"math"
+	"sync"
+
 	"github.com/go-mix/mix/bind/spec"
 )
 
 // OutNextCallbackFunc to stream mix out from mix
 type OutNextCallbackFunc func() []Value
 
+// OutNextBlockCallbackFunc to stream a block of mix out from mix, directly into a destination of interleaved float32 frames
+type OutNextBlockCallbackFunc func(dst []float32, frames spec.Tz)
+
 func ConfigureOutput(s spec.AudioSpec) {
+	outMutex.Lock()
+	defer outMutex.Unlock()
 	outSpec = &s
 }
 
 // SetOutNextCallback to set streaming callback function
 func SetOutputCallback(fn OutNextCallbackFunc) {
+	outMutex.Lock()
+	defer outMutex.Unlock()
 	outNextCallback = fn
 }
 
+// SetOutputBlockCallback to set the streaming callback of a block of float32 frames, used instead of the callback of each sample
+// when the output is configured as AudioF32, see OutNextBlock; nil to always use the callback of each sample.
+func SetOutputBlockCallback(fn OutNextBlockCallbackFunc) {
+	outMutex.Lock()
+	defer outMutex.Unlock()
+	outNextBlockCallback = fn
+}
+
+// OutNextBlock to mix the next block of frames for all channels into dst, interleaved float32, e.g. the buffer of a driver.
+// If the output is configured as AudioF32 and a block callback is set, it fills dst directly, else it pulls each sample.
+func OutNextBlock(dst []float32, channels int) {
+	frames := spec.Tz(len(dst) / channels)
+	outMutex.RLock()
+	fn, blockFn := outNextCallback, outNextBlockCallback
+	if outSpec == nil || outSpec.Format != spec.AudioF32 {
+		blockFn = nil
+	}
+	outMutex.RUnlock()
+	if blockFn != nil {
+		blockFn(dst, frames)
+		return
+	}
+	for i := 0; i+channels <= len(dst); i += channels {
+		smp := fn()
+		for c := 0; c < channels; c++ {
+			dst[i+c] = float32(smp[c])
+		}
+	}
+}
+
 // OutNext to mix the next sample for all channels, in []float64
 func OutNext() []Value {
-	return outNextCallback()
+	outMutex.RLock()
+	fn := outNextCallback
+	outMutex.RUnlock()
+	return fn()
 }
 
-// OutNextBytes to mix the next sample for all channels, in bytes
+// OutNextBytes to mix the next sample for all channels, in bytes, in a buffer that is reused by the next call;
+// it is only safe to call from the one goroutine that drives the output, which must write or copy the bytes before the next call.
 func OutNextBytes() (out []byte) {
-	in := outNextCallback()
-	for ch := 0; ch < outSpec.Channels; ch++ {
-		switch outSpec.Format {
+	outMutex.RLock()
+	format, fn := outSpec.Format, outNextCallback
+	outMutex.RUnlock()
+	outBytes = EncodeTo(outBytes[:0], format, fn())
+	return outBytes
+}
+
+// Encode a sample of all channels as interleaved bytes in a specific format
+func Encode(format spec.AudioFormat, in []Value) (out []byte) {
+	return EncodeTo(nil, format, in)
+}
+
+// EncodeTo appends a sample of all channels as interleaved bytes in a specific format, e.g. to a reused buffer
+// such that encoding in the mix loop makes no allocations once the buffer has grown to the size of a sample.
+func EncodeTo(out []byte, format spec.AudioFormat, in []Value) []byte {
+	for ch := 0; ch < len(in); ch++ {
+		switch format {
 		case spec.AudioU8:
 			out = append(out, in[ch].ToByteU8())
 		case spec.AudioS8:
 			out = append(out, in[ch].ToByteS8())
 		case spec.AudioS16:
-			out = append(out, in[ch].ToBytesS16LSB()...)
+			v := uint16(in[ch].ToInt16())
+			out = append(out, byte(v), byte(v>>8))
 		case spec.AudioU16:
-			out = append(out, in[ch].ToBytesU16LSB()...)
+			v := in[ch].ToUint16()
+			out = append(out, byte(v), byte(v>>8))
+		case spec.AudioS24:
+			v := in[ch].ToInt24()
+			out = append(out, byte(v), byte(v>>8), byte(v>>16))
 		case spec.AudioS32:
-			out = append(out, in[ch].ToBytesS32LSB()...)
+			out = appendUint32LSB(out, uint32(in[ch].ToInt32()))
 		case spec.AudioF32:
-			out = append(out, in[ch].ToBytesF32LSB()...)
+			out = appendUint32LSB(out, math.Float32bits(float32(in[ch])))
 		case spec.AudioF64:
-			out = append(out, in[ch].ToBytesF64LSB()...)
+			v := math.Float64bits(float64(in[ch]))
+			out = appendUint32LSB(appendUint32LSB(out, uint32(v)), uint32(v>>32))
 		}
 	}
-	return
+	return out
 }
 
 //
 // Private
 //
 
+func appendUint32LSB(out []byte, v uint32) []byte {
+	return append(out, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
 var (
-	outSpec         *spec.AudioSpec
-	outNextCallback OutNextCallbackFunc
+	outBytes             []byte // reused by OutNextBytes
+	outSpec              *spec.AudioSpec
+	outNextCallback      OutNextCallbackFunc
+	outNextBlockCallback OutNextBlockCallbackFunc
+	outMutex             = &sync.RWMutex{} // the output may pull samples from its own goroutine
 )
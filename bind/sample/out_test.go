@@ -3,6 +3,10 @@ package sample
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
 )
 
 func TestOut_useWAV(t *testing.T) {
@@ -28,3 +32,46 @@ func TestOut_outCallbackFunc(t *testing.T) {
 func TestOut_outNextBytes(t *testing.T) {
 	// TODO
 }
+
+func TestOutNextBlock(t *testing.T) {
+	next := 0
+	SetOutputCallback(func() []Value {
+		next++
+		return []Value{Value(next) / 4, -Value(next) / 4}
+	})
+	defer SetOutputCallback(nil)
+	blocks := 0
+	SetOutputBlockCallback(func(dst []float32, frames spec.Tz) {
+		blocks++
+		assert.Equal(t, spec.Tz(3), frames)
+	})
+	defer SetOutputBlockCallback(nil)
+	ConfigureOutput(spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 2})
+	dst := make([]float32, 6)
+	OutNextBlock(dst, 2) // not F32, so each sample is pulled
+	assert.Equal(t, []float32{0.25, -0.25, 0.5, -0.5, 0.75, -0.75}, dst)
+	assert.Equal(t, 0, blocks)
+	ConfigureOutput(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2})
+	OutNextBlock(dst, 2)
+	assert.Equal(t, 1, blocks)
+	assert.Equal(t, 3, next)
+}
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, []byte{0x00, 0x40, 0x00, 0xC0}, Encode(spec.AudioS16, []Value{0.5, -0.5}))
+	assert.Equal(t, 8, len(Encode(spec.AudioF32, []Value{0.5, -0.5})))
+	assert.Equal(t, 16, len(Encode(spec.AudioF64, []Value{0.5, -0.5})))
+	for _, format := range spec.Formats {
+		assert.Equal(t, 2*format.Bits()/8, len(Encode(format, []Value{0.5, -0.5})))
+	}
+	assert.Equal(t, append(Value(0.25).ToBytesF64LSB(), Value(-1).ToBytesF64LSB()...), Encode(spec.AudioF64, []Value{0.25, -1}))
+	assert.Equal(t, append(Value(0.25).ToBytesS32LSB(), Value(-1).ToBytesS32LSB()...), Encode(spec.AudioS32, []Value{0.25, -1}))
+}
+
+func TestEncodeTo_NoAllocs(t *testing.T) {
+	buf := make([]byte, 0, 16)
+	in := []Value{0.5, -0.5}
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		buf = EncodeTo(buf[:0], spec.AudioF64, in)
+	}))
+}
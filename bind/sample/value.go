@@ -32,6 +32,12 @@ func (this Value) ToBytesS16LSB() (out []byte) {
 	return
 }
 
+func (this Value) ToBytesS24LSB() (out []byte) {
+	v := this.ToInt24()
+	out = []byte{byte(v), byte(v >> 8), byte(v >> 16)}
+	return
+}
+
 func (this Value) ToBytesS32LSB() (out []byte) {
 	out = make([]byte, 4)
 	binary.LittleEndian.PutUint32(out, uint32(this.ToInt32()))
@@ -45,33 +51,37 @@ func (this Value) ToBytesF32LSB() (out []byte) {
 }
 
 func (this Value) ToBytesF64LSB() (out []byte) {
-	out = make([]byte, 4)
+	out = make([]byte, 8)
 	binary.LittleEndian.PutUint64(out, math.Float64bits(float64(this)))
 	return
 }
 
 func (this Value) ToUint8() uint8 {
-	return uint8(0x80 * (this + 1))
+	return uint8(this.clip(0x80) + 0x80)
 }
 
 func (this Value) ToInt8() int8 {
-	return int8(0x80 * this)
+	return int8(this.clip(0x80))
 }
 
 func (this Value) ToUint16() uint16 {
-	return uint16(0x8000 * (this + 1))
+	return uint16(this.clip(0x8000) + 0x8000)
 }
 
 func (this Value) ToInt16() int16 {
-	return int16(0x8000 * this)
+	return int16(this.clip(0x8000))
+}
+
+func (this Value) ToInt24() int32 {
+	return int32(this.clip(0x800000))
 }
 
 func (this Value) ToInt32() int32 {
-	return int32(0x80000000 * this)
+	return int32(this.clip(0x80000000))
 }
 
 func ValueOfByteU8(sample byte) Value {
-	return Value(int8(sample))/Value(0x7F) - Value(1)
+	return Value(sample)/Value(0x80) - Value(1)
 }
 
 func ValueOfByteS8(sample byte) Value {
@@ -94,6 +104,11 @@ func ValueOfBytesS16LSB(sample []byte) Value {
 //	return Value(int16(binary.BigEndian.Uint16(sample))) / Value(0x7FFF)
 //}
 
+func ValueOfBytesS24LSB(sample []byte) Value {
+	// shift into the high 24 bits of an int32 to extend the sign
+	return Value(int32(uint32(sample[0])<<8|uint32(sample[1])<<16|uint32(sample[2])<<24)>>8) / Value(0x7FFFFF)
+}
+
 func ValueOfBytesS32LSB(sample []byte) Value {
 	return Value(int32(binary.LittleEndian.Uint32(sample))) / Value(0x7FFFFFFF)
 }
@@ -117,3 +132,16 @@ func ValueOfBytesF64LSB(sample []byte) Value {
 //func ValueOfBytesF64MSB(sample []byte) Value {
 //	return Value(math.Float64frombits(binary.BigEndian.Uint64(sample)))
 //}
+
+//
+// Private
+//
+
+// clip to the range of a signed integer of the given full scale, e.g. 0x8000 for 16-bit, to saturate rather than wrap around on overflow;
+// NaN is silence, since its conversion to an integer is undefined
+func (this Value) clip(scale float64) float64 {
+	if math.IsNaN(float64(this)) {
+		return 0
+	}
+	return math.Max(-scale, math.Min(scale-1, float64(this)*scale))
+}
@@ -2,11 +2,16 @@
 package sample
 
 import (
+	"math"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestValueFromByteU8(t *testing.T) {
-	//TODO: Test
+	assert.Equal(t, Value(0), ValueOfByteU8(0x80))
+	assert.Equal(t, Value(-1), ValueOfByteU8(0x00))
+	assert.Equal(t, Value(0.5), ValueOfByteU8(0xC0))
 }
 
 func TestValueFromByteS8(t *testing.T) {
@@ -29,6 +34,12 @@ func TestValueFromBytesS16MSB(t *testing.T) {
 	//TODO: Test
 }
 
+func TestValueFromBytesS24LSB(t *testing.T) {
+	assert.Equal(t, Value(0), ValueOfBytesS24LSB([]byte{0x00, 0x00, 0x00}))
+	assert.Equal(t, Value(1), ValueOfBytesS24LSB([]byte{0xFF, 0xFF, 0x7F}))
+	assert.Equal(t, Value(-1), ValueOfBytesS24LSB([]byte{0x01, 0x00, 0x80}))
+}
+
 func TestValueFromBytesS32LSB(t *testing.T) {
 	//TODO: Test
 }
@@ -69,6 +80,11 @@ func TestValueToBytesS16LSB(t *testing.T) {
 	// TODO
 }
 
+func TestValueToBytesS24LSB(t *testing.T) {
+	assert.Equal(t, []byte{0x00, 0x00, 0x40}, Value(0.5).ToBytesS24LSB())
+	assert.Equal(t, []byte{0x00, 0x00, 0xC0}, Value(-0.5).ToBytesS24LSB())
+}
+
 func TestValueToBytesS32LSB(t *testing.T) {
 	// TODO
 }
@@ -94,7 +110,19 @@ func TestValueToUint16(t *testing.T) {
 }
 
 func TestValueToInt16(t *testing.T) {
-	// TODO
+	assert.Equal(t, int16(0), Value(0).ToInt16())
+	assert.Equal(t, int16(0x4000), Value(0.5).ToInt16())
+	assert.Equal(t, int16(0x7FFF), Value(1).ToInt16())
+	assert.Equal(t, int16(0x7FFF), Value(1.5).ToInt16()) // saturates, never wraps
+	assert.Equal(t, int16(-0x8000), Value(-1.5).ToInt16())
+	assert.Equal(t, int16(0), Value(math.NaN()).ToInt16())
+}
+
+func TestValueToInt24(t *testing.T) {
+	assert.Equal(t, int32(0x400000), Value(0.5).ToInt24())
+	assert.Equal(t, int32(0x7FFFFF), Value(2).ToInt24())
+	assert.Equal(t, int32(-0x800000), Value(-2).ToInt24())
+	assert.Equal(t, []byte{0xFF, 0xFF, 0x7F}, Value(2).ToBytesS24LSB())
 }
 
 func TestValueToInt32(t *testing.T) {
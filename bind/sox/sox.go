@@ -1,41 +1,178 @@
-// Package sox is for file I/O via go-sox package
+// Package sox is file input via the sox command-line tool, which decodes nearly any audio format
 package sox
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os/exec"
+	"strings"
+	"sync"
+
 	"github.com/go-mix/mix/bind/sample"
 	"github.com/go-mix/mix/bind/spec"
-	sox "github.com/krig/go-sox"
 )
 
+// ErrSoxNotFound if the sox binary is not installed, see SetCommand
+var ErrSoxNotFound = errors.New("Sox binary not found (install sox, or see sox.SetCommand)")
+
+// ChunkSize of values to buffer from the output of sox at a time
 const ChunkSize = 2048
 
-// Load sound file into memory
-func Load(path string) (out []sample.Sample, specs *spec.AudioSpec) {
-	file := sox.OpenRead(path)
-	if file == nil {
-		panic("Sox can't open file: " + path)
+// Options of one load
+type Options struct {
+	// ExtraArgs of sox, before the input, e.g. to decode a headerless file: -t raw -r 44100 -c 2 -e signed -b 16
+	ExtraArgs []string
+}
+
+// SetCommand to run sox, a name in the PATH or a path; the default is "sox"
+func SetCommand(command string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	soxCommand = command
+}
+
+// SetExtraArgs of sox for every load by Load or LoadBytes, see Options
+func SetExtraArgs(args []string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	extraArgs = append([]string(nil), args...)
+}
+
+// Check that the sox binary is installed, else ErrSoxNotFound
+func Check() error {
+	if _, err := exec.LookPath(command()); err != nil {
+		return ErrSoxNotFound
+	}
+	return nil
+}
+
+// Load a sound file into memory, with the extra args, see SetExtraArgs
+func Load(path string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	return LoadWith(context.Background(), path, defaultOptions())
+}
+
+// LoadBytes of sound data into memory, e.g. from an embedded asset, with the extra args, see SetExtraArgs; sox cannot detect
+// the type of some data from its content alone, e.g. an MP3 without an ID3 tag, so the extra args may need to give it, e.g. -t mp3
+func LoadBytes(data []byte) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	return LoadBytesWith(context.Background(), data, defaultOptions())
+}
+
+// LoadWith options, a sound file into memory, decoding the output of sox as it streams; if the context is done first,
+// sox is killed and the error of the context is returned.
+func LoadWith(ctx context.Context, path string, opts Options) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	return run(ctx, path, nil, opts)
+}
+
+// LoadBytesWith options, sound data into memory, see LoadBytes and LoadWith
+func LoadBytesWith(ctx context.Context, data []byte, opts Options) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	return run(ctx, "-", bytes.NewReader(data), opts)
+}
+
+//
+// Private
+//
+
+var (
+	soxCommand = "sox"
+	extraArgs  []string
+	mutex      = &sync.Mutex{}
+)
+
+const (
+	auMagic      = 0x2e736e64 // ".snd"
+	auHeaderSize = 24
+	auFloat32    = 6 // encoding of 32-bit IEEE floating point
+)
+
+func command() string {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return soxCommand
+}
+
+func defaultOptions() Options {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return Options{ExtraArgs: extraArgs}
+}
+
+// run sox to convert an input to Sun AU of 32-bit floats on its stdout, which streams with its header first, and decode it
+func run(ctx context.Context, input string, stdin io.Reader, opts Options) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	name := "Sox " + input
+	path, err := exec.LookPath(command())
+	if err != nil {
+		return nil, nil, ErrSoxNotFound
+	}
+	args := append(append([]string(nil), opts.ExtraArgs...), input, "-t", "au", "-e", "floating-point", "-b", "32", "-")
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	reader := bufio.NewReaderSize(stdout, ChunkSize*4)
+	out, specs, err = decode(reader, name)
+	if err != nil {
+		io.Copy(ioutil.Discard, reader) // until sox exits, e.g. it failed to open the input, and its error explains why
+	}
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	} else if waitErr != nil {
+		return nil, nil, fmt.Errorf("Cannot decode %s: %s: %s", name, waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return
+}
+
+// decode Sun AU of 32-bit floats, big-endian, a frame at a time
+func decode(in io.Reader, name string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	var header [auHeaderSize]byte
+	if _, err = io.ReadFull(in, header[:]); err != nil {
+		err = errors.New("Cannot decode " + name + ": no header")
+		return
+	}
+	offset, encoding := binary.BigEndian.Uint32(header[4:]), binary.BigEndian.Uint32(header[12:])
+	if binary.BigEndian.Uint32(header[0:]) != auMagic || encoding != auFloat32 || offset < auHeaderSize {
+		err = errors.New("Cannot decode " + name + ": unexpected header")
+		return
 	}
-	defer file.Release()
-	info := file.Signal()
 	specs = &spec.AudioSpec{
-		Freq:     info.Rate(),
-		Format:   spec.AudioS32,
-		Channels: int(info.Channels()),
+		Freq:     float64(binary.BigEndian.Uint32(header[16:])),
+		Format:   spec.AudioF32,
+		Channels: int(binary.BigEndian.Uint32(header[20:])),
+	}
+	if specs.Channels < 1 {
+		err = errors.New("Cannot decode " + name + ": no channels")
+		return
 	}
-	buffer := make([]sox.Sample, ChunkSize*specs.Channels)
+	if _, err = io.CopyN(ioutil.Discard, in, int64(offset-auHeaderSize)); err != nil { // the annotation
+		err = errors.New("Cannot decode " + name + ": " + err.Error())
+		return
+	}
+	frame := make([]byte, 4*specs.Channels)
 	for {
-		size := file.Read(buffer, uint(len(buffer)))
-		if size == 0 || size == sox.EOF {
-			break
+		if _, err = io.ReadFull(in, frame); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return out, specs, nil // a partial frame at the end is dropped
+		} else if err != nil {
+			err = errors.New("Cannot decode " + name + ": " + err.Error())
+			return
 		}
-		outBuffer := make([]sample.Value, size)
-		for offset := 0; offset < int(size); offset += specs.Channels {
-			values := outBuffer[offset : offset+specs.Channels]
-			for c := 0; c < specs.Channels; c++ {
-				values[c] = sample.Value(sox.SampleToFloat64(buffer[offset+c]))
-			}
-			out = append(out, sample.New(values))
+		values := make([]sample.Value, specs.Channels)
+		for c := range values {
+			values[c] = sample.Value(math.Float32frombits(binary.BigEndian.Uint32(frame[4*c:])))
 		}
+		out = append(out, sample.New(values))
 	}
-	return
 }
@@ -0,0 +1,113 @@
+// Package sox is file input via the sox command-line tool, which decodes nearly any audio format
+package sox
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestLoad(t *testing.T) {
+	dir, cleanup := testSox(t, `echo "$@" > "$(dirname "$0")/args"; cat "$(dirname "$0")/out.au"`)
+	defer cleanup()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "out.au"), testAU(22050, 2, []float32{0.5, -0.5, 1, -1, 0.25}), 0644))
+	SetExtraArgs([]string{"-t", "raw", "-r", "22050", "-c", "2", "-e", "signed", "-b", "16"})
+	defer SetExtraArgs(nil)
+	out, specs, err := Load("in.raw")
+	assert.Nil(t, err)
+	assert.Equal(t, &spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 2}, specs)
+	assert.Equal(t, []sample.Sample{
+		sample.New([]sample.Value{0.5, -0.5}),
+		sample.New([]sample.Value{1, -1}),
+	}, out) // a partial frame at the end is dropped
+	args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+	assert.Nil(t, err)
+	assert.Equal(t, "-t raw -r 22050 -c 2 -e signed -b 16 in.raw -t au -e floating-point -b 32 -", strings.TrimSpace(string(args)))
+}
+
+func TestLoadBytes(t *testing.T) {
+	_, cleanup := testSox(t, `cat`) // stdin to stdout, as if it were already AU
+	defer cleanup()
+	out, specs, err := LoadBytesWith(context.Background(), testAU(44100, 1, []float32{0.125, 0.25}), Options{ExtraArgs: []string{"-t", "au"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, specs.Channels)
+	assert.Equal(t, []sample.Sample{sample.New([]sample.Value{0.125}), sample.New([]sample.Value{0.25})}, out)
+}
+
+func TestLoad_NotFound(t *testing.T) {
+	SetCommand("/nonexistent/sox")
+	defer SetCommand("sox")
+	assert.Equal(t, ErrSoxNotFound, Check())
+	_, _, err := Load("in.wav")
+	assert.Equal(t, ErrSoxNotFound, err)
+}
+
+func TestLoad_Stderr(t *testing.T) {
+	_, cleanup := testSox(t, `echo "sox FAIL formats: can't open input file 'in.wav'" >&2; exit 2`)
+	defer cleanup()
+	assert.Nil(t, Check())
+	_, _, err := Load("in.wav")
+	assert.EqualError(t, err, "Cannot decode Sox in.wav: exit status 2: sox FAIL formats: can't open input file 'in.wav'")
+}
+
+func TestLoadWith_Cancel(t *testing.T) {
+	_, cleanup := testSox(t, `exec sleep 10`)
+	defer cleanup()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	_, _, err := LoadWith(ctx, "in.wav", Options{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(begin) < 5*time.Second, "sox was not killed")
+}
+
+func TestDecode(t *testing.T) {
+	_, _, err := decode(bytes.NewReader([]byte("RIFF....WAVEfmt ")), "Sox test")
+	assert.EqualError(t, err, "Cannot decode Sox test: no header")
+	header := testAU(44100, 1, nil)
+	binary.BigEndian.PutUint32(header[12:], 3) // 16-bit linear PCM
+	_, _, err = decode(bytes.NewReader(header), "Sox test")
+	assert.EqualError(t, err, "Cannot decode Sox test: unexpected header")
+}
+
+//
+// Test Components
+//
+
+// testSox replaces the sox binary with a shell script, in a temporary directory
+func testSox(t *testing.T, script string) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "mix-sox")
+	assert.Nil(t, err)
+	command := filepath.Join(dir, "sox")
+	assert.Nil(t, ioutil.WriteFile(command, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	SetCommand(command)
+	return dir, func() {
+		SetCommand("sox")
+		os.RemoveAll(dir)
+	}
+}
+
+// testAU of 32-bit floats, with an annotation, as sox writes it to a pipe, of unknown size
+func testAU(freq uint32, channels uint32, values []float32) []byte {
+	var buf bytes.Buffer
+	for _, v := range []uint32{auMagic, 32, math.MaxUint32, auFloat32, freq, channels} {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	buf.WriteString("sox\x00\x00\x00\x00\x00")
+	for _, v := range values {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	return buf.Bytes()
+}
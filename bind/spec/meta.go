@@ -0,0 +1,103 @@
+// Package spec specifies valid audio formats
+package spec
+
+// SourceMeta embedded in an audio source, e.g. the loop points and cue markers of a sampler WAV, in Tz of its sample frames
+type SourceMeta struct {
+	Loops []Loop    // in the order they are embedded; the first is the sustain loop of a sampler
+	Cues  []Cue     // in order of their position
+	Tags  *Metadata // descriptive, e.g. of the LIST/INFO and bext chunks of a WAV, or nil if it has none
+}
+
+// Loop between two Tz of a source, from its begin up to but not including its end
+type Loop struct {
+	BeginTz Tz
+	EndTz   Tz
+}
+
+// Cue marker at a Tz of a source, e.g. the beginning of a slice
+type Cue struct {
+	ID    uint32
+	Tz    Tz
+	Label string // or empty if the cue has none
+}
+
+// Metadata that describes audio, e.g. for an archive, of the LIST/INFO chunk of a WAV and its Broadcast Wave (bext) chunk;
+// an empty field is absent
+type Metadata struct {
+	// of a LIST/INFO chunk
+	Title     string            // INAM
+	Artist    string            // IART
+	Comment   string            // ICMT
+	Copyright string            // ICOP
+	Date      string            // ICRD, of creation, e.g. 2026-10-16
+	Genre     string            // IGNR
+	Software  string            // ISFT
+	Info      map[string]string // any other, by the four-character ID of its chunk, e.g. IENG for the engineer
+	// of a bext chunk, of EBU Tech 3285
+	Description         string // at most 256 bytes
+	Originator          string // at most 32 bytes
+	OriginatorReference string // at most 32 bytes
+	OriginationDate     string // yyyy-mm-dd
+	OriginationTime     string // hh:mm:ss
+	TimeReference       uint64 // of the first sample, in samples since midnight, at the frequency of the file
+	CodingHistory       string
+}
+
+// HasInfo if any field of a LIST/INFO chunk is set
+func (m *Metadata) HasInfo() bool {
+	return m != nil && (m.Title != "" || m.Artist != "" || m.Comment != "" || m.Copyright != "" || m.Date != "" ||
+		m.Genre != "" || m.Software != "" || len(m.Info) > 0)
+}
+
+// HasBext if any field of a bext chunk is set
+func (m *Metadata) HasBext() bool {
+	return m != nil && (m.Description != "" || m.Originator != "" || m.OriginatorReference != "" || m.OriginationDate != "" ||
+		m.OriginationTime != "" || m.TimeReference != 0 || m.CodingHistory != "")
+}
+
+// Scale the meta of a source from one frequency to another, e.g. after it is resampled, or nil if there is none
+func (m *SourceMeta) Scale(from float64, to float64) *SourceMeta {
+	if m == nil {
+		return nil
+	}
+	ratio := to / from
+	scaled := &SourceMeta{Tags: m.Tags}
+	for _, l := range m.Loops {
+		scaled.Loops = append(scaled.Loops, Loop{scaleTz(l.BeginTz, ratio), scaleTz(l.EndTz, ratio)})
+	}
+	for _, c := range m.Cues {
+		scaled.Cues = append(scaled.Cues, Cue{c.ID, scaleTz(c.Tz, ratio), c.Label})
+	}
+	return scaled
+}
+
+// Offset the meta of a source to begin at a Tz of it, e.g. after its leading silence is trimmed, or nil if there is none;
+// a point before it is at 0
+func (m *SourceMeta) Offset(from Tz) *SourceMeta {
+	if m == nil || from == 0 {
+		return m
+	}
+	offset := &SourceMeta{Tags: m.Tags}
+	for _, l := range m.Loops {
+		offset.Loops = append(offset.Loops, Loop{offsetTz(l.BeginTz, from), offsetTz(l.EndTz, from)})
+	}
+	for _, c := range m.Cues {
+		offset.Cues = append(offset.Cues, Cue{c.ID, offsetTz(c.Tz, from), c.Label})
+	}
+	return offset
+}
+
+//
+// Private
+//
+
+func offsetTz(tz Tz, from Tz) Tz {
+	if tz < from {
+		return 0
+	}
+	return tz - from
+}
+
+func scaleTz(tz Tz, ratio float64) Tz {
+	return Tz(float64(tz)*ratio + 0.5)
+}
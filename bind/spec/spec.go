@@ -2,6 +2,9 @@
 package spec
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"time"
 )
 
@@ -14,27 +17,72 @@ type AudioSpec struct {
 	Format   AudioFormat
 	Channels int
 	Length   time.Duration
+	Meta     *SourceMeta // embedded in a loaded source, e.g. WAV loop points and cues, or nil
 }
 
-// Validate these specs
-func (spec *AudioSpec) Validate() {
-	if spec.Freq == 0 {
-		panic("Must specify Frequency")
-	}
-	if spec.Freq < 0 {
-		panic("Must specify a mixing frequency greater than zero.")
+// MaxChannels of any audio I/O, e.g. 8 for 7.1 surround; mono, stereo, quad and anything in between are supported
+const MaxChannels = 8
+
+// Validate these specs, or return an error describing the first problem found
+func (spec *AudioSpec) Validate() error {
+	if spec.Freq <= 0 || math.IsNaN(spec.Freq) || math.IsInf(spec.Freq, 0) {
+		return fmt.Errorf("Unsupported frequency: %v (must be greater than zero)", spec.Freq)
 	}
 	if spec.Format == "" {
-		panic("Must specify Format")
+		return errors.New("Must specify Format")
+	}
+	if !spec.Format.IsValid() {
+		return fmt.Errorf("Unknown format: %s (must be one of %v)", spec.Format, Formats)
+	}
+	if spec.Channels < 1 || spec.Channels > MaxChannels {
+		return fmt.Errorf("Unsupported channel count: %d (must be 1 to %d)", spec.Channels, MaxChannels)
 	}
-	if spec.Channels == 0 {
-		panic("Must specify Channels")
+	return nil
+}
+
+// MustValidate these specs, or panic with the error from Validate
+func (spec *AudioSpec) MustValidate() {
+	if err := spec.Validate(); err != nil {
+		panic(err.Error())
 	}
 }
 
-// AudioFormat represents the bit allocation for a single sample of audio
+// AudioFormat represents the bit allocation for a single sample of audio.
+// Every format can be loaded from, encoded to and rendered as a WAV file, except that WAV has no signed 8-bit or
+// unsigned 16-bit encoding, so a WAV output of S8 obtains U8, and of U16 obtains S16.
 type AudioFormat string
 
+// Formats supported for audio I/O
+var Formats = []AudioFormat{AudioU8, AudioS8, AudioU16, AudioS16, AudioS24, AudioS32, AudioF32, AudioF64}
+
+// IsValid is true if the format is one of the supported Formats
+func (f AudioFormat) IsValid() bool {
+	return f.Bits() > 0
+}
+
+// IsFloat is true if the format is floating-point
+func (f AudioFormat) IsFloat() bool {
+	return f == AudioF32 || f == AudioF64
+}
+
+// Bits per sample (per channel) of the format, or 0 if it is not supported
+func (f AudioFormat) Bits() int {
+	switch f {
+	case AudioU8, AudioS8:
+		return 8
+	case AudioU16, AudioS16:
+		return 16
+	case AudioS24:
+		return 24
+	case AudioS32, AudioF32:
+		return 32
+	case AudioF64:
+		return 64
+	default:
+		return 0
+	}
+}
+
 // AudioU8 is unsigned-integer 8-bit sample (per channel)
 const AudioU8 AudioFormat = "U8"
 
@@ -47,6 +95,9 @@ const AudioU16 AudioFormat = "U16"
 // AudioS16 is signed-integer 16-bit sample (per channel)
 const AudioS16 AudioFormat = "S16"
 
+// AudioS24 is signed-integer 24-bit sample (per channel)
+const AudioS24 AudioFormat = "S24"
+
 // AudioS32 is signed-integer 32-bit sample (per channel)
 const AudioS32 AudioFormat = "S32"
 
@@ -1,2 +1,73 @@
 // Package spec specifies valid audio formats
 package spec
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	for _, channels := range []int{1, 2, 4, MaxChannels} {
+		s := AudioSpec{Freq: 44100, Format: AudioF32, Channels: channels}
+		assert.Nil(t, s.Validate())
+	}
+	for _, channels := range []int{0, -1, MaxChannels + 1} {
+		s := AudioSpec{Freq: 44100, Format: AudioF32, Channels: channels}
+		assert.EqualError(t, s.Validate(), fmt.Sprintf("Unsupported channel count: %d (must be 1 to 8)", channels))
+	}
+	for _, format := range Formats {
+		s := AudioSpec{Freq: 44100, Format: format, Channels: 2}
+		assert.Nil(t, s.Validate())
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	s := AudioSpec{Freq: 0, Format: AudioS16, Channels: 2}
+	assert.EqualError(t, s.Validate(), "Unsupported frequency: 0 (must be greater than zero)")
+	s = AudioSpec{Freq: 44100, Channels: 2}
+	assert.EqualError(t, s.Validate(), "Must specify Format")
+	s = AudioSpec{Freq: 44100, Format: "S12", Channels: 2}
+	assert.EqualError(t, s.Validate(), "Unknown format: S12 (must be one of [U8 S8 U16 S16 S24 S32 F32 F64])")
+}
+
+func TestMustValidate(t *testing.T) {
+	s := AudioSpec{Freq: 44100, Format: AudioS16, Channels: 0}
+	assert.PanicsWithValue(t, "Unsupported channel count: 0 (must be 1 to 8)", s.MustValidate)
+}
+
+func TestAudioFormat_Bits(t *testing.T) {
+	assert.Equal(t, 8, AudioU8.Bits())
+	assert.Equal(t, 8, AudioS8.Bits())
+	assert.Equal(t, 24, AudioS24.Bits())
+	assert.Equal(t, 64, AudioF64.Bits())
+	assert.Equal(t, 0, AudioFormat("S12").Bits())
+	assert.True(t, AudioF32.IsFloat())
+	assert.False(t, AudioS32.IsFloat())
+}
+
+func TestSourceMeta_Scale(t *testing.T) {
+	meta := &SourceMeta{
+		Loops: []Loop{{BeginTz: 1000, EndTz: 2000}},
+		Cues:  []Cue{{ID: 1, Tz: 441, Label: "one"}},
+	}
+	assert.Equal(t, &SourceMeta{
+		Loops: []Loop{{BeginTz: 2000, EndTz: 4000}},
+		Cues:  []Cue{{ID: 1, Tz: 882, Label: "one"}},
+	}, meta.Scale(22050, 44100))
+	assert.Nil(t, (*SourceMeta)(nil).Scale(22050, 44100))
+}
+
+func TestSourceMeta_Offset(t *testing.T) {
+	meta := &SourceMeta{
+		Loops: []Loop{{BeginTz: 100, EndTz: 300}},
+		Cues:  []Cue{{ID: 1, Tz: 20, Label: "pickup"}, {ID: 2, Tz: 150}},
+	}
+	assert.Equal(t, &SourceMeta{
+		Loops: []Loop{{BeginTz: 50, EndTz: 250}},
+		Cues:  []Cue{{ID: 1, Tz: 0, Label: "pickup"}, {ID: 2, Tz: 100}},
+	}, meta.Offset(50))
+	assert.Equal(t, meta, meta.Offset(0))
+	assert.Nil(t, (*SourceMeta)(nil).Offset(50))
+}
@@ -0,0 +1,34 @@
+// Package bind is for modular binding of mix to audio interface
+package bind
+
+import (
+	"errors"
+
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Stream of audio decoded a block at a time from an open file, e.g. to play a long file without loading it whole
+type Stream interface {
+	Spec() *spec.AudioSpec                    // of the audio in the file
+	Frames() int64                            // of audio in the file
+	SeekFrame(frame int64) error              // to move the decode cursor to
+	Read(frames int) ([]sample.Sample, error) // up to a # of frames from the decode cursor, fewer at the end, then io.EOF
+	Close() error                             // the file
+}
+
+// OpenStream of a file, via the selected loader, or an error if the loader cannot stream, e.g. any but WAV for now
+func OpenStream(file string) (Stream, error) {
+	loader := useLoader
+	if loader == opt.InputAuto {
+		var err error
+		if loader, err = loaderByExtension(file); err != nil {
+			return nil, err
+		}
+	}
+	if streamer, ok := loaderOf(loader).(StreamLoader); ok {
+		return streamer.OpenStream(file)
+	}
+	return nil, errors.New("Cannot stream with loader: " + string(loader))
+}
@@ -0,0 +1,82 @@
+// Package wav is direct WAV filo I/O
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// chunk of a RIFF file, located by the offset and size of its data, excluding the pad byte that follows an odd size
+type chunk struct {
+	id     string
+	offset int64
+	size   uint32
+}
+
+// riffHeaderSize of "RIFF", the size of the rest of the file, and "WAVE"
+const riffHeaderSize = 12
+
+// chunkHeaderSize of the ID and the size of each chunk
+const chunkHeaderSize = 8
+
+// readChunks of a RIFF WAVE file, in order, whatever their ID, so that the caller can skip those it does not know,
+// e.g. the LIST, bext and JUNK chunks written by a DAW. The chunks end at the size in the RIFF header, unless it is a placeholder
+// of a stream, e.g. 0 or 0xFFFFFFFF, or the file ends first. The data chunk runs to the end of the file if its size is a placeholder,
+// e.g. of a WAV streamed to a pipe, or the file ends first, e.g. a recording that was cut off; any other chunk that is cut short is an
+// error, with the byte offset of its header.
+func readChunks(r io.ReaderAt) (chunks []chunk, err error) {
+	header := make([]byte, riffHeaderSize)
+	if n, _ := r.ReadAt(header, 0); n < riffHeaderSize || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, errors.New("Not a RIFF WAVE file")
+	}
+	end := int64(-1) // until the end of the file
+	if size := binary.LittleEndian.Uint32(header[4:8]); size >= 4 && size != 0xFFFFFFFF {
+		end = chunkHeaderSize + int64(size)
+	}
+	for offset := int64(riffHeaderSize); end < 0 || offset < end; {
+		n, _ := r.ReadAt(header[:chunkHeaderSize], offset)
+		if n == 0 { // at the end of the file, even if the RIFF header claims more
+			return
+		} else if n < chunkHeaderSize {
+			return nil, fmt.Errorf("Truncated chunk header at byte %d", offset)
+		}
+		ch := chunk{
+			id:     string(header[0:4]),
+			offset: offset + chunkHeaderSize,
+			size:   binary.LittleEndian.Uint32(header[4:8]),
+		}
+		if ch.size > 0 {
+			if n, _ := r.ReadAt(header[:1], ch.offset+int64(ch.size)-1); n < 1 || (ch.id == "data" && ch.size == streamPlaceholderSize) {
+				if ch.id != "data" {
+					return nil, fmt.Errorf("Truncated %q chunk at byte %d: %d bytes declared", ch.id, offset, ch.size)
+				}
+				ch.size = uint32(sizeFrom(r, ch.offset, int64(ch.size)))
+			}
+		}
+		chunks = append(chunks, ch)
+		offset = ch.offset + int64(ch.size) + int64(ch.size%2)
+	}
+	return
+}
+
+// sizeFrom an offset to the end of the file, at most a limit, found by bisecting the last byte that can be read
+func sizeFrom(r io.ReaderAt, offset int64, limit int64) int64 {
+	b := make([]byte, 1)
+	lo, hi := int64(0), limit // lo bytes can be read, and no more than hi
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if n, _ := r.ReadAt(b, offset+mid-1); n == 1 {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// section of the file that is the data of the chunk
+func (ch chunk) section(r io.ReaderAt) *io.SectionReader {
+	return io.NewSectionReader(r, ch.offset, int64(ch.size))
+}
@@ -20,30 +20,11 @@ type Format struct {
 }
 
 func FormatFromSpec(s *spec.AudioSpec) Format {
-	format := Format{}
-	switch s.Format {
-	case spec.AudioU8:
-		format.SampleFormat = AudioFormatLinearPCM
-		format.BitsPerSample = 8
-	case spec.AudioS8:
-		format.SampleFormat = AudioFormatLinearPCM
-		format.BitsPerSample = 8
-	case spec.AudioU16:
-		format.SampleFormat = AudioFormatLinearPCM
-		format.BitsPerSample = 16
-	case spec.AudioS16:
-		format.SampleFormat = AudioFormatLinearPCM
-		format.BitsPerSample = 16
-	case spec.AudioS32:
-		format.SampleFormat = AudioFormatLinearPCM
-		format.BitsPerSample = 32
-	case spec.AudioF32:
+	format := Format{SampleFormat: AudioFormatLinearPCM}
+	if s.Format.IsFloat() {
 		format.SampleFormat = AudioFormatIEEEFloat
-		format.BitsPerSample = 32
-	case spec.AudioF64:
-		format.SampleFormat = AudioFormatIEEEFloat
-		format.BitsPerSample = 64
 	}
+	format.BitsPerSample = uint16(s.Format.Bits())
 	format.NumChannels = uint16(s.Channels)
 	format.SampleRate = uint32(s.Freq)
 	if format.ByteRate == 0 {
@@ -64,6 +45,17 @@ type Data struct {
 type SampleFormat uint16
 
 const (
-	AudioFormatLinearPCM SampleFormat = 0x0001
-	AudioFormatIEEEFloat SampleFormat = 0x0003
+	AudioFormatLinearPCM  SampleFormat = 0x0001
+	AudioFormatIEEEFloat  SampleFormat = 0x0003
+	AudioFormatExtensible SampleFormat = 0xFFFE // the real format code is at the head of the SubFormat GUID
 )
+
+// formatSize of the Format, at the head of every fmt chunk
+const formatSize = 16
+
+// formatExtensibleSize of an extensible fmt chunk: the Format, then the extension size, valid bits per sample,
+// channel mask, and SubFormat GUID
+const formatExtensibleSize = 40
+
+// subFormatGUIDTail that follows the format code in the SubFormat GUID of an extensible fmt chunk, for PCM and IEEE float
+var subFormatGUIDTail = []byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71}
@@ -0,0 +1,226 @@
+// Package wav is direct WAV filo I/O
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Metadata of a WAV file, of its LIST/INFO and bext chunks, see LoadWithMeta and NewWriterWithMeta
+type Metadata = spec.Metadata
+
+// The descriptive chunks are parsed as leniently as those of a sampler: a field that is cut short is ignored. As they are written,
+// a field that is too long for its chunk is truncated, and one that cannot be encoded is skipped, each with a warning, so that the
+// file is always valid.
+
+// infoMaxSize of the text of each field of a LIST/INFO chunk, beyond which it is truncated as it is written
+const infoMaxSize = 1 << 16
+
+// bextSize of a bext chunk, before its coding history: description, originator, originator reference, origination date and time,
+// time reference, version, UMID, loudness, and reserved
+const bextSize = 602
+
+// bextField of text in a bext chunk, at a byte offset, of a fixed size, padded with NUL
+type bextField struct {
+	name   string
+	offset int
+	size   int
+}
+
+var (
+	bextDescription         = bextField{"Description", 0, 256}
+	bextOriginator          = bextField{"Originator", 256, 32}
+	bextOriginatorReference = bextField{"OriginatorReference", 288, 32}
+	bextOriginationDate     = bextField{"OriginationDate", 320, 10}
+	bextOriginationTime     = bextField{"OriginationTime", 330, 8}
+)
+
+// bext offsets of the time reference, a little-endian uint64 of its low and high words, and of the version, which is written as 1
+const (
+	bextTimeReferenceOffset = 338
+	bextVersionOffset       = 346
+	bextVersion             = 1
+)
+
+// infoField of the Metadata, by its ID in a LIST/INFO chunk
+type infoField struct {
+	id   string
+	text *string
+}
+
+// infoFields of the Metadata, in the order they are written
+func infoFields(meta *Metadata) []infoField {
+	return []infoField{
+		{"INAM", &meta.Title},
+		{"IART", &meta.Artist},
+		{"ICMT", &meta.Comment},
+		{"ICOP", &meta.Copyright},
+		{"ICRD", &meta.Date},
+		{"IGNR", &meta.Genre},
+		{"ISFT", &meta.Software},
+	}
+}
+
+// parseInfo of the data of a LIST/INFO chunk into the metadata
+func parseInfo(data []byte, meta *Metadata) {
+	if len(data) < 4 || string(data[0:4]) != "INFO" {
+		return
+	}
+	fields := infoFields(meta)
+	for at := 4; at+8 <= len(data); {
+		id := string(data[at : at+4])
+		size := int(binary.LittleEndian.Uint32(data[at+4 : at+8]))
+		at += 8
+		if at+size > len(data) {
+			return
+		}
+		text := string(cutNUL(data[at : at+size]))
+		at += size + size%2
+		if text == "" {
+			continue
+		}
+		known := false
+		for _, f := range fields {
+			if f.id == id {
+				*f.text, known = text, true
+			}
+		}
+		if !known {
+			if meta.Info == nil {
+				meta.Info = make(map[string]string)
+			}
+			meta.Info[id] = text
+		}
+	}
+}
+
+// parseBext of the data of a bext chunk into the metadata
+func parseBext(data []byte, meta *Metadata) {
+	if len(data) < bextVersionOffset {
+		return
+	}
+	meta.Description = bextDescription.read(data)
+	meta.Originator = bextOriginator.read(data)
+	meta.OriginatorReference = bextOriginatorReference.read(data)
+	meta.OriginationDate = bextOriginationDate.read(data)
+	meta.OriginationTime = bextOriginationTime.read(data)
+	meta.TimeReference = binary.LittleEndian.Uint64(data[bextTimeReferenceOffset:bextVersionOffset])
+	if len(data) > bextSize {
+		meta.CodingHistory = string(cutNUL(data[bextSize:]))
+	}
+}
+
+// encodeInfo of the metadata as the data of a LIST/INFO chunk, or nil if it has none
+func encodeInfo(meta *Metadata) []byte {
+	if !meta.HasInfo() {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString("INFO")
+	write := func(id string, text string) {
+		text = truncateText("LIST/INFO "+id, text, infoMaxSize-1)
+		if text == "" {
+			return
+		}
+		buf.WriteString(id)
+		binary.Write(&buf, binary.LittleEndian, uint32(len(text)+1))
+		buf.WriteString(text)
+		buf.WriteByte(0)
+		if (len(text)+1)%2 == 1 {
+			buf.WriteByte(0)
+		}
+	}
+	known := make(map[string]bool)
+	for _, f := range infoFields(meta) {
+		write(f.id, *f.text)
+		known[f.id] = true
+	}
+	ids := make([]string, 0, len(meta.Info))
+	for id := range meta.Info {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		switch {
+		case known[id]:
+			debug.Warnf("wav metadata LIST/INFO %s skipped: set its field of the Metadata instead", id)
+		case !isChunkID(id):
+			debug.Warnf("wav metadata LIST/INFO %q skipped: an ID must be four printable ASCII characters", id)
+		default:
+			write(id, meta.Info[id])
+		}
+	}
+	if buf.Len() == 4 {
+		return nil // every field was skipped
+	}
+	return buf.Bytes()
+}
+
+// encodeBext of the metadata as the data of a bext chunk, or nil if it has none
+func encodeBext(meta *Metadata) []byte {
+	if !meta.HasBext() {
+		return nil
+	}
+	data := make([]byte, bextSize, bextSize+len(meta.CodingHistory))
+	bextDescription.write(data, meta.Description)
+	bextOriginator.write(data, meta.Originator)
+	bextOriginatorReference.write(data, meta.OriginatorReference)
+	bextOriginationDate.write(data, meta.OriginationDate)
+	bextOriginationTime.write(data, meta.OriginationTime)
+	binary.LittleEndian.PutUint64(data[bextTimeReferenceOffset:bextVersionOffset], meta.TimeReference)
+	binary.LittleEndian.PutUint16(data[bextVersionOffset:bextVersionOffset+2], bextVersion)
+	return append(data, truncateText("bext CodingHistory", meta.CodingHistory, infoMaxSize)...)
+}
+
+// read the field from the data of a bext chunk
+func (f bextField) read(data []byte) string {
+	return string(cutNUL(data[f.offset : f.offset+f.size]))
+}
+
+// write text into the field of the data of a bext chunk, truncated to its size
+func (f bextField) write(data []byte, text string) {
+	copy(data[f.offset:f.offset+f.size], truncateText("bext "+f.name, text, f.size))
+}
+
+// truncateText of a field to at most a # of bytes, and before any NUL, on a boundary of UTF-8, warning if it is truncated
+func truncateText(field string, text string, size int) string {
+	if at := strings.IndexByte(text, 0); at >= 0 {
+		debug.Warnf("wav metadata %s truncated at a NUL character, at byte %d", field, at)
+		text = text[:at]
+	}
+	if len(text) <= size {
+		return text
+	}
+	debug.Warnf("wav metadata %s truncated from %d bytes to %d", field, len(text), size)
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size]
+}
+
+// cutNUL and everything after it, of text padded with NUL
+func cutNUL(text []byte) []byte {
+	if at := bytes.IndexByte(text, 0); at >= 0 {
+		return text[:at]
+	}
+	return text
+}
+
+// isChunkID of four printable ASCII characters
+func isChunkID(id string) bool {
+	if len(id) != 4 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x20 || id[i] > 0x7E {
+			return false
+		}
+	}
+	return true
+}
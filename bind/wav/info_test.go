@@ -0,0 +1,105 @@
+// Package wav is direct WAV filo I/O
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestNewWriterWithMeta(t *testing.T) {
+	meta := &Metadata{
+		Title:           "Take 3",
+		Artist:          "Ensemble",
+		Software:        "mix",
+		Info:            map[string]string{"IENG": "Jo"},
+		Description:     "Room tone",
+		Originator:      "Field recorder",
+		OriginationDate: "2026-10-16",
+		OriginationTime: "09:30:00",
+		TimeReference:   1 << 33,
+		CodingHistory:   "A=PCM,F=8000,W=16,M=mono\r\n", // odd, so the chunk is padded
+	}
+	s := spec.AudioSpec{Freq: 8000, Format: spec.AudioS16, Channels: 1}
+	// a known length
+	var buf bytes.Buffer
+	w := NewWriterWithMeta(&buf, FormatFromSpec(&s), 10*time.Millisecond, meta)
+	w.Write(make([]byte, 80*2))
+	assert.Nil(t, w.Close())
+	testInfoAssertSizes(t, buf.Bytes(), 80*2)
+	out, specs, err := LoadBytes(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, 80, len(out))
+	assert.Equal(t, meta, specs.Meta.Tags)
+	// a seekable stream, whose sizes are patched on close
+	outfile, err := ioutil.TempFile("", "mix-wav-meta")
+	assert.Nil(t, err)
+	defer os.Remove(outfile.Name())
+	w = NewWriterWithMeta(outfile, FormatFromSpec(&s), 0, meta)
+	w.Write(make([]byte, 100*2))
+	assert.Nil(t, w.Close())
+	outfile.Close()
+	data, err := ioutil.ReadFile(outfile.Name())
+	assert.Nil(t, err)
+	testInfoAssertSizes(t, data, 100*2)
+	out, _, loaded, err := LoadWithMeta(outfile.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, 100, len(out))
+	assert.Equal(t, meta, loaded)
+}
+
+func TestNewWriterWithMeta_Truncate(t *testing.T) {
+	meta := &Metadata{
+		Title:       "Tit\x00le",
+		Info:        map[string]string{"IENGINEER": "skipped", "INAM": "skipped", "ISRC": "kept"},
+		Description: strings.Repeat("é", 200), // 400 bytes
+		Originator:  strings.Repeat("o", 40),
+	}
+	s := spec.AudioSpec{Freq: 8000, Format: spec.AudioS16, Channels: 1}
+	var buf bytes.Buffer
+	w := NewWriterWithMeta(&buf, FormatFromSpec(&s), 10*time.Millisecond, meta)
+	w.Write(make([]byte, 80*2))
+	assert.Nil(t, w.Close())
+	_, specs, err := LoadBytes(buf.Bytes())
+	assert.Nil(t, err)
+	tags := specs.Meta.Tags
+	assert.Equal(t, "Tit", tags.Title)
+	assert.Equal(t, map[string]string{"ISRC": "kept"}, tags.Info)
+	assert.Equal(t, strings.Repeat("é", 128), tags.Description) // on a boundary of UTF-8
+	assert.Equal(t, strings.Repeat("o", 32), tags.Originator)
+}
+
+func TestNewWriterWithMeta_None(t *testing.T) {
+	s := spec.AudioSpec{Freq: 8000, Format: spec.AudioS16, Channels: 1}
+	var plain, empty bytes.Buffer
+	NewWriter(&plain, FormatFromSpec(&s), 10*time.Millisecond)
+	NewWriterWithMeta(&empty, FormatFromSpec(&s), 10*time.Millisecond, &Metadata{})
+	assert.Equal(t, plain.Bytes(), empty.Bytes())
+	assert.Equal(t, 44, empty.Len())
+}
+
+//
+// Private
+//
+
+// testInfoAssertSizes in the RIFF header and the data chunk of a WAV, for the # of bytes of its samples
+func testInfoAssertSizes(t *testing.T, data []byte, dataSize int) {
+	assert.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[riffSizeOffset:]))
+	chunks, err := readChunks(bytes.NewReader(data))
+	assert.Nil(t, err)
+	var ids []string
+	for _, ch := range chunks {
+		ids = append(ids, ch.id)
+	}
+	assert.Equal(t, []string{"fmt ", "bext", "LIST", "data"}, ids)
+	assert.Equal(t, uint32(dataSize), chunks[3].size)
+	assert.Equal(t, int64(len(data)-dataSize), chunks[3].offset)
+}
@@ -0,0 +1,94 @@
+// Package wav is direct WAV filo I/O
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// The metadata chunks of a sampler WAV are parsed leniently: an entry that is cut short, or a loop that ends before it begins,
+// is ignored, so that the audio of a file with broken metadata still loads.
+
+// smplHeaderSize of a sampler chunk, before its loops: manufacturer, product, sample period, MIDI unity note, MIDI pitch fraction,
+// SMPTE format, SMPTE offset, # of loops, and the size of the sampler data that follows the loops
+const smplHeaderSize = 36
+
+// smplLoopSize of each loop in a sampler chunk: cue point ID, type, start, end (inclusive), fraction, and play count
+const smplLoopSize = 24
+
+// cuePointSize of each cue point in a cue chunk: ID, position, data chunk ID, chunk start, block start, and sample offset
+const cuePointSize = 24
+
+// parseSmpl chunk data into the loops of the meta
+func parseSmpl(data []byte, meta *spec.SourceMeta) {
+	if len(data) < smplHeaderSize {
+		return
+	}
+	numLoops := int(binary.LittleEndian.Uint32(data[28:32]))
+	for i := 0; i < numLoops; i++ {
+		at := smplHeaderSize + i*smplLoopSize
+		if at+smplLoopSize > len(data) {
+			return
+		}
+		start := binary.LittleEndian.Uint32(data[at+8 : at+12])
+		end := binary.LittleEndian.Uint32(data[at+12 : at+16])
+		if end < start {
+			continue
+		}
+		meta.Loops = append(meta.Loops, spec.Loop{BeginTz: spec.Tz(start), EndTz: spec.Tz(end) + 1})
+	}
+}
+
+// parseCue chunk data into the cues of the meta, in order of their position
+func parseCue(data []byte, meta *spec.SourceMeta) {
+	if len(data) < 4 {
+		return
+	}
+	numCues := int(binary.LittleEndian.Uint32(data[0:4]))
+	for i := 0; i < numCues; i++ {
+		at := 4 + i*cuePointSize
+		if at+cuePointSize > len(data) {
+			break
+		}
+		meta.Cues = append(meta.Cues, spec.Cue{
+			ID: binary.LittleEndian.Uint32(data[at : at+4]),
+			Tz: spec.Tz(binary.LittleEndian.Uint32(data[at+20 : at+24])),
+		})
+	}
+	sort.SliceStable(meta.Cues, func(i, j int) bool {
+		return meta.Cues[i].Tz < meta.Cues[j].Tz
+	})
+}
+
+// parseLabels of the cues, from the data of an associated data list chunk, by cue ID
+func parseLabels(data []byte, labels map[uint32]string) {
+	if len(data) < 4 || string(data[0:4]) != "adtl" {
+		return
+	}
+	for at := 4; at+8 <= len(data); {
+		id := string(data[at : at+4])
+		size := int(binary.LittleEndian.Uint32(data[at+4 : at+8]))
+		at += 8
+		if at+size > len(data) {
+			return
+		}
+		if id == "labl" && size >= 4 {
+			text := data[at+4 : at+size]
+			if end := bytes.IndexByte(text, 0); end >= 0 {
+				text = text[:end]
+			}
+			labels[binary.LittleEndian.Uint32(data[at:at+4])] = string(text)
+		}
+		at += size + size%2
+	}
+}
+
+// labelCues of the meta by their ID
+func labelCues(meta *spec.SourceMeta, labels map[uint32]string) {
+	for i := range meta.Cues {
+		meta.Cues[i].Label = labels[meta.Cues[i].ID]
+	}
+}
@@ -3,8 +3,10 @@ package wav
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"io"
 
 	riff "github.com/youpy/go-riff"
 
@@ -16,14 +18,15 @@ import (
 type Reader struct {
 	Format      *Format
 	AudioFormat spec.AudioFormat
+	Meta        *spec.SourceMeta // loop points and cues of a sampler WAV, and its LIST/INFO and bext metadata, or nil if it has none
 	*Data
 	// private
-	riffReader *riff.Reader
-	riffChunk  *riff.RIFFChunk
+	file   riff.RIFFReader
+	chunks []chunk
 }
 
 func NewReader(file riff.RIFFReader) (reader *Reader, err error) {
-	reader = &Reader{riffReader: riff.NewReader(file)}
+	reader = &Reader{file: file}
 	format, audioFormat, err := reader.openAndParse()
 	if err != nil {
 		return
@@ -57,7 +60,7 @@ func (r *Reader) ReadSamples(params ...uint32) (out []sample.Sample, err error)
 	numSamples = n / blockAlign
 	r.Data.pos += uint32(numSamples * blockAlign)
 
-	for offset := 0; offset < len(buffer)-numChannels-bytesPerSample; offset += blockAlign {
+	for offset := 0; offset < numSamples*blockAlign; offset += blockAlign {
 		values := make([]sample.Value, numChannels)
 		for c := 0; c < int(numChannels); c++ {
 			offsetCh := offset + c*bytesPerSample
@@ -83,7 +86,12 @@ func (r *Reader) readSamplesIntoBuffer(p []byte) (n int, err error) {
 		r.Data = data
 	}
 
-	return r.Data.Read(p)
+	// read full blocks, else a short read would split a sample across buffers
+	n, err = io.ReadFull(r.Data, p)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return
 }
 
 func (r *Reader) sampleFromBytes(audio spec.AudioFormat, bytes []byte) sample.Value {
@@ -97,6 +105,8 @@ func (r *Reader) sampleFromBytes(audio spec.AudioFormat, bytes []byte) sample.Va
 		return sample.ValueOfBytesU16LSB(bytes)
 	case spec.AudioS16:
 		return sample.ValueOfBytesS16LSB(bytes)
+	case spec.AudioS24:
+		return sample.ValueOfBytesS24LSB(bytes)
 	case spec.AudioS32:
 		return sample.ValueOfBytesS32LSB(bytes)
 	case spec.AudioF32:
@@ -109,65 +119,48 @@ func (r *Reader) sampleFromBytes(audio spec.AudioFormat, bytes []byte) sample.Va
 }
 
 func (r *Reader) openAndParse() (format *Format, audio spec.AudioFormat, err error) {
-	var riffChunk *riff.RIFFChunk
-
-	format = new(Format)
-
-	if r.riffChunk == nil {
-		riffChunk, err = r.riffReader.Read()
-		if err != nil {
+	if r.chunks == nil {
+		if r.chunks, err = readChunks(r.file); err != nil {
 			return
 		}
-
-		r.riffChunk = riffChunk
-	} else {
-		riffChunk = r.riffChunk
 	}
 
-	for _, ch := range riffChunk.Chunks {
-		var data []byte
-		switch string(ch.ChunkID[:]) {
-		case "fmt ":
-			err = binary.Read(ch, binary.LittleEndian, format)
-			if err != nil {
+	meta := &spec.SourceMeta{}
+	tags := &Metadata{}
+	labels := make(map[uint32]string)
+	for _, ch := range r.chunks {
+		switch ch.id {
+		case "fmt ", "smpl", "cue ", "LIST", "bext":
+			data := make([]byte, ch.size)
+			if _, err = io.ReadFull(ch.section(r.file), data); err != nil {
 				return
 			}
-			switch SampleFormat(format.SampleFormat) {
-			case AudioFormatLinearPCM: // Linear PCM
-				switch format.BitsPerSample {
-				case 8:
-					audio = spec.AudioS8
-				case 16:
-					audio = spec.AudioS16
-				default:
-					panic(fmt.Sprintf("Unhandled Linear PCM bitrate: %+v", format.BitsPerSample))
-				}
-			case AudioFormatIEEEFloat: // IEEE Float
-				switch format.BitsPerSample {
-				case 32:
-					audio = spec.AudioF32
-				case 64:
-					audio = spec.AudioF64
-				default:
-					panic(fmt.Sprintf("Unhandled IEEE Float bitrate: %+v", format.BitsPerSample))
+			switch ch.id {
+			case "fmt ":
+				if format, audio, err = parseFormat(data, ch.offset-chunkHeaderSize); err != nil {
+					return
 				}
-			default:
-				panic("Unhandled format")
-			}
-		case "fact":
-			data = make([]byte, ch.ChunkSize)
-			err = binary.Read(ch, binary.LittleEndian, data)
-			if err != nil {
-				return
-			}
-		case "PEAK":
-			data = make([]byte, ch.ChunkSize)
-			err = binary.Read(ch, binary.LittleEndian, data)
-			if err != nil {
-				return
+			case "smpl":
+				parseSmpl(data, meta)
+			case "cue ":
+				parseCue(data, meta)
+			case "LIST":
+				parseLabels(data, labels)
+				parseInfo(data, tags)
+			case "bext":
+				parseBext(data, tags)
 			}
+		default:
+			// skip any other chunk, e.g. fact, PEAK or JUNK
 		}
 	}
+	if tags.HasInfo() || tags.HasBext() {
+		meta.Tags = tags
+	}
+	if len(meta.Loops) > 0 || len(meta.Cues) > 0 || meta.Tags != nil {
+		labelCues(meta, labels)
+		r.Meta = meta
+	}
 
 	if format == nil && err == nil {
 		err = errors.New("Format chunk is not found")
@@ -175,23 +168,64 @@ func (r *Reader) openAndParse() (format *Format, audio spec.AudioFormat, err err
 	return
 }
 
-func (r *Reader) readData() (data *Data, err error) {
-	var riffChunk *riff.RIFFChunk
+// parseFormat of the data of a fmt chunk at a byte offset, including an extensible format, whose real format code is at the head
+// of its SubFormat GUID
+func parseFormat(data []byte, offset int64) (format *Format, audio spec.AudioFormat, err error) {
+	if len(data) < formatSize {
+		return nil, "", fmt.Errorf("Truncated fmt chunk at byte %d: %d bytes", offset, len(data))
+	}
+	format = new(Format)
+	if err = binary.Read(bytes.NewReader(data[:formatSize]), binary.LittleEndian, format); err != nil {
+		return
+	}
+	if format.NumChannels == 0 {
+		return nil, "", fmt.Errorf("No channels in fmt chunk at byte %d", offset)
+	}
+	if format.SampleFormat == AudioFormatExtensible {
+		if len(data) < formatExtensibleSize || !bytes.Equal(data[26:40], subFormatGUIDTail) {
+			return nil, "", fmt.Errorf("Malformed extensible fmt chunk at byte %d", offset)
+		}
+		format.SampleFormat = SampleFormat(binary.LittleEndian.Uint16(data[24:26]))
+	}
+	switch format.SampleFormat {
+	case AudioFormatLinearPCM: // Linear PCM
+		switch format.BitsPerSample {
+		case 8:
+			audio = spec.AudioU8 // 8-bit WAV is always unsigned
+		case 16:
+			audio = spec.AudioS16
+		case 24:
+			audio = spec.AudioS24
+		case 32:
+			audio = spec.AudioS32
+		default:
+			err = fmt.Errorf("Unhandled Linear PCM bitrate: %+v", format.BitsPerSample)
+		}
+	case AudioFormatIEEEFloat: // IEEE Float
+		switch format.BitsPerSample {
+		case 32:
+			audio = spec.AudioF32
+		case 64:
+			audio = spec.AudioF64
+		default:
+			err = fmt.Errorf("Unhandled IEEE Float bitrate: %+v", format.BitsPerSample)
+		}
+	default:
+		err = fmt.Errorf("Unsupported WAV format code: %#04x", uint16(format.SampleFormat))
+	}
+	return
+}
 
-	if r.riffChunk == nil {
-		riffChunk, err = r.riffReader.Read()
-		if err != nil {
+func (r *Reader) readData() (data *Data, err error) {
+	if r.chunks == nil {
+		if r.chunks, err = readChunks(r.file); err != nil {
 			return
 		}
-
-		r.riffChunk = riffChunk
-	} else {
-		riffChunk = r.riffChunk
 	}
 
-	for _, ch := range riffChunk.Chunks {
-		if string(ch.ChunkID[:]) == "data" {
-			data = &Data{bufio.NewReader(ch), ch.ChunkSize, 0}
+	for _, ch := range r.chunks {
+		if ch.id == "data" {
+			data = &Data{bufio.NewReader(ch.section(r.file)), ch.size, 0}
 			return
 		}
 	}
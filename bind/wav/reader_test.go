@@ -2,8 +2,10 @@
 package wav
 
 import (
-	//	"github.com/stretchr/testify/assert"
+	"bytes"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestReaderOpen(t *testing.T) {
@@ -30,6 +32,27 @@ func TestReaderOpenAndParse(t *testing.T) {
 	// TODO
 }
 
+func TestReaderOpenAndParse_Malformed(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("this is not a riff header")))
+	assert.NotNil(t, err)
+}
+
 func TestReaderReadData(t *testing.T) {
 	// TODO
 }
+
+func TestReaderOpenAndParse_TruncatedHeader(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("RIFF\x24\x00\x00\x00WAVEfmt ")))
+	assert.EqualError(t, err, "Truncated chunk header at byte 12")
+}
+
+func TestParseFormat_Errors(t *testing.T) {
+	_, _, err := parseFormat(make([]byte, 14), 12)
+	assert.EqualError(t, err, "Truncated fmt chunk at byte 12: 14 bytes")
+	extensible := []byte{0xFE, 0xFF, 1, 0, 0x44, 0xAC, 0, 0, 0x88, 0x58, 1, 0, 2, 0, 16, 0}
+	_, _, err = parseFormat(extensible, 12)
+	assert.EqualError(t, err, "Malformed extensible fmt chunk at byte 12")
+	noChannels := []byte{1, 0, 0, 0, 0x44, 0xAC, 0, 0, 0x88, 0x58, 1, 0, 2, 0, 16, 0}
+	_, _, err = parseFormat(noChannels, 12)
+	assert.EqualError(t, err, "No channels in fmt chunk at byte 12")
+}
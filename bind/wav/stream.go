@@ -0,0 +1,96 @@
+// Package wav is direct WAV filo I/O
+package wav
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// OpenStream of a WAV file, to decode it a block at a time from the open file, e.g. to play a long file without loading it whole
+func OpenStream(path string) (*Stream, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	s := &Stream{file: file, reader: reader}
+	for _, ch := range reader.chunks {
+		if ch.id == "data" {
+			s.data = ch
+			break
+		}
+	}
+	if s.data.id != "data" {
+		file.Close()
+		return nil, errors.New("Data chunk is not found")
+	}
+	s.blockAlign = int64(reader.Format.NumChannels) * int64(reader.Format.BitsPerSample/8)
+	s.spec = &spec.AudioSpec{
+		Freq:     float64(reader.Format.SampleRate),
+		Format:   reader.AudioFormat,
+		Channels: int(reader.Format.NumChannels),
+		Meta:     reader.Meta,
+	}
+	if err = s.SeekFrame(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Stream of the decoded frames of a WAV file; it is not safe for concurrent use.
+type Stream struct {
+	file       *os.File
+	reader     *Reader
+	data       chunk
+	blockAlign int64
+	spec       *spec.AudioSpec
+}
+
+// Spec of the audio in the file
+func (s *Stream) Spec() *spec.AudioSpec {
+	return s.spec
+}
+
+// Frames of audio in the file
+func (s *Stream) Frames() int64 {
+	return int64(s.data.size) / s.blockAlign
+}
+
+// SeekFrame moves the decode cursor to a frame
+func (s *Stream) SeekFrame(frame int64) error {
+	if frame < 0 || frame > s.Frames() {
+		return fmt.Errorf("Cannot seek to frame %d of %d", frame, s.Frames())
+	}
+	at := frame * s.blockAlign
+	section := io.NewSectionReader(s.file, s.data.offset+at, int64(s.data.size)-at)
+	s.reader.Data = &Data{bufio.NewReader(section), s.data.size, uint32(at)}
+	return nil
+}
+
+// Read up to a # of frames from the decode cursor, fewer at the end of the file, and then io.EOF
+func (s *Stream) Read(frames int) ([]sample.Sample, error) {
+	remaining := int64(s.data.size-s.reader.Data.pos) / s.blockAlign
+	if remaining <= 0 {
+		return nil, io.EOF
+	}
+	if int64(frames) > remaining {
+		frames = int(remaining)
+	}
+	return s.reader.ReadSamples(uint32(frames))
+}
+
+// Close the file
+func (s *Stream) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,57 @@
+// Package wav is direct WAV filo I/O
+package wav
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestOpenStream(t *testing.T) {
+	expect, _, err := Load("testdata/SustainLoop.wav")
+	assert.Nil(t, err)
+	s, err := OpenStream("testdata/SustainLoop.wav")
+	assert.Nil(t, err)
+	defer s.Close()
+	assert.Equal(t, int64(4410), s.Frames())
+	assert.Equal(t, 1, s.Spec().Channels)
+	assert.Equal(t, &spec.SourceMeta{Loops: []spec.Loop{{BeginTz: 1000, EndTz: 2000}}}, s.Spec().Meta)
+	out, err := s.Read(1000)
+	assert.Nil(t, err)
+	assert.Equal(t, expect[:1000], out)
+	assert.Nil(t, s.SeekFrame(4000))
+	out, err = s.Read(1000)
+	assert.Nil(t, err)
+	assert.Equal(t, expect[4000:], out)
+	_, err = s.Read(1000)
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, s.SeekFrame(10))
+	out, err = s.Read(2)
+	assert.Nil(t, err)
+	assert.Equal(t, expect[10:12], out)
+}
+
+func TestOpenStream_Stereo(t *testing.T) {
+	expect, _, err := Load("testdata/Extensible24bitStereo.wav")
+	assert.Nil(t, err)
+	s, err := OpenStream("testdata/Extensible24bitStereo.wav")
+	assert.Nil(t, err)
+	defer s.Close()
+	assert.Nil(t, s.SeekFrame(1))
+	out, _ := s.Read(10)
+	assert.Equal(t, expect[1:], out)
+}
+
+func TestOpenStream_FAIL(t *testing.T) {
+	_, err := OpenStream("testdata/ThisShouldFailBecauseItDoesNotExist.wav")
+	assert.NotNil(t, err)
+	_, err = OpenStream("testdata/TruncatedList.wav")
+	assert.NotNil(t, err)
+	s, err := OpenStream("testdata/Signed16bitMono.wav")
+	assert.Nil(t, err)
+	defer s.Close()
+	assert.EqualError(t, s.SeekFrame(5), "Cannot seek to frame 5 of 4")
+}
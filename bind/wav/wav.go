@@ -2,38 +2,69 @@
 package wav
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"os"
 
+	riff "github.com/youpy/go-riff"
+
 	"github.com/go-mix/mix/bind/sample"
 	"github.com/go-mix/mix/bind/spec"
 )
 
 // Load a WAV file into memory
-func Load(path string) (out []sample.Sample, specs *spec.AudioSpec) {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		panic("File not found: " + path)
+func Load(path string) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = errors.New("File not found: " + path)
+		return
 	}
-	file, _ := os.Open(path)
-	reader, err := NewReader(file)
+	file, err := os.Open(path)
 	if err != nil {
-		panic(err)
+		return
+	}
+	defer file.Close()
+	return decode(file)
+}
+
+// LoadWithMeta is Load, also returning the metadata of its LIST/INFO and bext chunks, or nil if it has none; it is also in the
+// Tags of the Meta of the spec, whichever way the file is loaded
+func LoadWithMeta(path string) (out []sample.Sample, specs *spec.AudioSpec, meta *Metadata, err error) {
+	if out, specs, err = Load(path); err == nil && specs.Meta != nil {
+		meta = specs.Meta.Tags
+	}
+	return
+}
+
+// LoadBytes of WAV data into memory, e.g. from an embedded asset
+func LoadBytes(data []byte) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	return decode(bytes.NewReader(data))
+}
+
+//
+// Private
+//
+
+func decode(in riff.RIFFReader) (out []sample.Sample, specs *spec.AudioSpec, err error) {
+	reader, err := NewReader(in)
+	if err != nil {
+		return
 	}
 	specs = &spec.AudioSpec{
 		Freq:     float64(reader.Format.SampleRate),
 		Format:   reader.AudioFormat,
 		Channels: int(reader.Format.NumChannels),
+		Meta:     reader.Meta,
 	}
 	for {
-		samples, err := reader.ReadSamples()
-		if err == io.EOF {
+		samples, readErr := reader.ReadSamples()
+		if readErr == io.EOF {
 			break
+		} else if readErr != nil {
+			err = readErr
+			return
 		}
 		out = append(out, samples...)
 	}
 	return
 }
-
-//
-// Private
-//
@@ -3,8 +3,102 @@ package wav
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
 )
 
 func TestLoad(t *testing.T) {
-	// TODO
+	out, specs, err := Load("../../lib/source/testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, specs.Channels)
+	assert.True(t, len(out) > 0)
+}
+
+func TestLoad_BitDepths(t *testing.T) {
+	expect := []float64{0, 0.5, -0.5, 0.25}
+	for _, tc := range []struct {
+		file     string
+		format   spec.AudioFormat
+		channels int
+	}{
+		{"testdata/Unsigned8bitMono.wav", spec.AudioU8, 1},
+		{"testdata/Unsigned8bitStereo.wav", spec.AudioU8, 2},
+		{"testdata/Signed16bitMono.wav", spec.AudioS16, 1},
+		{"testdata/Signed16bitStereo.wav", spec.AudioS16, 2},
+		{"testdata/Signed24bitMono.wav", spec.AudioS24, 1},
+		{"testdata/Signed24bitStereo.wav", spec.AudioS24, 2},
+		{"testdata/Signed32bitMono.wav", spec.AudioS32, 1},
+		{"testdata/Signed32bitStereo.wav", spec.AudioS32, 2},
+		{"testdata/DAWChunksSigned16bitMono.wav", spec.AudioS16, 1},
+		{"testdata/Extensible24bitStereo.wav", spec.AudioS24, 2},
+		{"testdata/ExtensibleFloat32bitMono.wav", spec.AudioF32, 1},
+	} {
+		out, specs, err := Load(tc.file)
+		assert.Nil(t, err, tc.file)
+		assert.Equal(t, tc.format, specs.Format, tc.file)
+		assert.Equal(t, tc.channels, specs.Channels, tc.file)
+		assert.Equal(t, len(expect), len(out), tc.file)
+		for i, smp := range out {
+			assert.InDelta(t, expect[i], float64(smp.Values[0]), 1e-3, tc.file)
+			if tc.channels == 2 {
+				assert.InDelta(t, -expect[i], float64(smp.Values[1]), 1e-3, tc.file)
+			}
+		}
+	}
+}
+
+func TestLoad_SustainLoop(t *testing.T) {
+	out, specs, err := Load("testdata/SustainLoop.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, 4410, len(out))
+	assert.Equal(t, &spec.SourceMeta{Loops: []spec.Loop{{BeginTz: 1000, EndTz: 2000}}}, specs.Meta)
+}
+
+func TestLoad_CueMarkers(t *testing.T) {
+	out, specs, err := Load("testdata/CueMarkers.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, 4410, len(out))
+	assert.Equal(t, &spec.SourceMeta{Cues: []spec.Cue{
+		{ID: 1, Tz: 0, Label: "one"},
+		{ID: 2, Tz: 1470, Label: "two"},
+		{ID: 3, Tz: 2940, Label: "three"},
+	}}, specs.Meta)
+}
+
+func TestLoad_NoMeta(t *testing.T) {
+	_, specs, err := Load("testdata/Signed16bitMono.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, specs.Meta)
+}
+
+func TestLoad_OddSizeChunk(t *testing.T) {
+	out, specs, err := Load("testdata/OddSizeUnsigned8bitMono.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioU8, specs.Format)
+	assert.Equal(t, 3, len(out)) // not the pad byte
+	assert.InDelta(t, -0.5, float64(out[2].Values[0]), 1e-2)
+}
+
+func TestLoad_Truncated(t *testing.T) {
+	_, _, err := Load("testdata/TruncatedList.wav")
+	assert.EqualError(t, err, `Truncated "LIST" chunk at byte 36: 64 bytes declared`)
+}
+
+func TestLoad_TruncatedData(t *testing.T) {
+	out, _, err := Load("testdata/Truncated.wav") // 8 bytes of data declared, but the file ends 5 bytes in
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(out)) // the whole frames, not the odd byte
+	assert.InDelta(t, 0.5, float64(out[1].Values[0]), 1e-3)
+}
+
+func TestLoad_UnsupportedFormat(t *testing.T) {
+	_, _, err := Load("testdata/ADPCM4bitMono.wav")
+	assert.EqualError(t, err, "Unsupported WAV format code: 0x0002")
+}
+
+func TestLoad_FileNotFound(t *testing.T) {
+	_, _, err := Load("testdata/ThisShouldFailBecauseItDoesNotExist.wav")
+	assert.EqualError(t, err, "File not found: testdata/ThisShouldFailBecauseItDoesNotExist.wav")
 }
@@ -4,6 +4,7 @@ package wav
 import (
 	"encoding/binary"
 	"io"
+	"math"
 
 	riff "github.com/youpy/go-riff"
 
@@ -13,12 +14,45 @@ import (
 	"github.com/go-mix/mix/bind/spec"
 )
 
-func ConfigureOutput(s spec.AudioSpec) {
-	outputSpec = &s
+// ConfigureOutput and return the spec obtained, which substitutes the nearest format that WAV can encode,
+// because 8-bit WAV is always unsigned and 16-bit WAV is always signed, e.g. S8 obtains U8
+func ConfigureOutput(s spec.AudioSpec) (obtained spec.AudioSpec) {
+	obtained = s
+	obtained.Format = OutputFormat(s.Format)
+	outputSpec = &obtained
+	return
 }
 
+// OutputFormat that WAV encodes for the requested format
+func OutputFormat(format spec.AudioFormat) spec.AudioFormat {
+	switch format {
+	case spec.AudioS8:
+		return spec.AudioU8
+	case spec.AudioU16:
+		return spec.AudioS16
+	default:
+		return format
+	}
+}
+
+// OutputStart with a known length, or 0 to stream with placeholder sizes in the header
 func OutputStart(length time.Duration, out io.Writer) {
-	writer = NewWriter(out, FormatFromSpec(outputSpec), length)
+	writer = NewWriterWithMeta(out, FormatFromSpec(outputSpec), length, outputMeta)
+}
+
+// SetOutputMetadata written in the header of each output started after, or nil for none (the default)
+func SetOutputMetadata(meta *Metadata) {
+	outputMeta = meta
+}
+
+// OutputClose patches the header sizes of a streamed output, if the writer is an io.WriteSeeker
+func OutputClose() (err error) {
+	if writer == nil {
+		return
+	}
+	err = writer.Close()
+	writer = nil
+	return
 }
 
 func TeardownOutput() {
@@ -28,24 +62,103 @@ func TeardownOutput() {
 type Writer struct {
 	io.Writer
 	Format *Format
+	// private
+	out            io.Writer
+	isStreaming    bool
+	dataWritten    uint32
+	headerSize     uint32 // of the RIFF data before the samples: "WAVE", and each chunk, up to the header of the data chunk
+	dataSizeOffset int64
 }
 
+// NewWriter with a known length, or 0 to stream with placeholder sizes in the header, which pipe consumers (e.g. aplay or ffmpeg) accept
 func NewWriter(w io.Writer, format Format, length time.Duration) (writer *Writer) {
-	dataSize := uint32(float64(length/time.Second)*float64(format.SampleRate)) * uint32(format.BlockAlign)
-	riffSize := 4 + 8 + 16 + 8 + dataSize
+	return NewWriterWithMeta(w, format, length, nil)
+}
+
+// NewWriterWithMeta is NewWriter, with a LIST/INFO chunk, and a bext chunk, of the fields of metadata that are set, if any, before
+// the samples; a field that is too long is truncated, and one that cannot be encoded is skipped, with a warning
+func NewWriterWithMeta(w io.Writer, format Format, length time.Duration, meta *Metadata) (writer *Writer) {
+	info, bext := encodeInfo(meta), encodeBext(meta)
+	var metaSize uint32 // of the chunks of metadata, between the fmt chunk and the data chunk
+	if info != nil {
+		metaSize += chunkHeaderSize + uint32(len(info)+len(info)%2)
+	}
+	if bext != nil {
+		metaSize += chunkHeaderSize + uint32(len(bext)+len(bext)%2)
+	}
+	headerSize := 4 + 8 + 16 + metaSize + 8
+	dataSize := uint32(math.Round(length.Seconds()*float64(format.SampleRate))) * uint32(format.BlockAlign) // to the nearest frame, as the mixer counts them
+	riffSize := headerSize + dataSize
+	if length == 0 {
+		dataSize = streamPlaceholderSize
+		riffSize = streamPlaceholderSize
+	}
 	riffWriter := riff.NewWriter(w, []byte("WAVE"), riffSize)
 
-	writer = &Writer{riffWriter, &format}
+	writer = &Writer{
+		Writer:         riffWriter,
+		Format:         &format,
+		out:            w,
+		isStreaming:    length == 0,
+		headerSize:     headerSize,
+		dataSizeOffset: dataSizeOffset + int64(metaSize),
+	}
 	riffWriter.WriteChunk([]byte("fmt "), 16, func(w io.Writer) {
 		binary.Write(w, binary.LittleEndian, format)
 	})
+	if bext != nil {
+		writeChunkPadded(riffWriter, "bext", bext)
+	}
+	if info != nil {
+		writeChunkPadded(riffWriter, "LIST", info)
+	}
 	riffWriter.WriteChunk([]byte("data"), dataSize, func(w io.Writer) {})
 
 	return writer
 }
 
+// Write sample data, counting the bytes written for the header sizes of a streamed output
+func (w *Writer) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	w.dataWritten += uint32(n)
+	return
+}
+
+// Close the output, first flushing the writer if it is buffered, e.g. a *bufio.Writer; then, if streamed, seek back to patch
+// the header sizes, else leave the placeholder sizes for pipe consumers
+func (w *Writer) Close() (err error) {
+	if flusher, ok := w.out.(interface{ Flush() error }); ok {
+		if err = flusher.Flush(); err != nil {
+			return
+		}
+	}
+	seeker, ok := w.out.(io.WriteSeeker)
+	if !w.isStreaming || !ok {
+		return
+	}
+	if _, err = seeker.Seek(riffSizeOffset, io.SeekStart); err != nil {
+		return
+	}
+	if err = binary.Write(seeker, binary.LittleEndian, w.headerSize+w.dataWritten); err != nil {
+		return
+	}
+	if _, err = seeker.Seek(w.dataSizeOffset, io.SeekStart); err != nil {
+		return
+	}
+	if err = binary.Write(seeker, binary.LittleEndian, w.dataWritten); err != nil {
+		return
+	}
+	_, err = seeker.Seek(0, io.SeekEnd)
+	return
+}
+
+// OutputNext samples, or pull and discard them if the output has not been started, or has been closed
 func OutputNext(numSamples spec.Tz) (err error) {
 	for n := spec.Tz(0); n < numSamples; n++ {
+		if writer == nil {
+			sample.OutNextBytes()
+			continue
+		}
 		writer.Write(sample.OutNextBytes())
 	}
 	return
@@ -55,7 +168,24 @@ func OutputNext(numSamples spec.Tz) (err error) {
 // Private
 //
 
+const (
+	streamPlaceholderSize = 0xFFFFFFFF
+	riffSizeOffset        = 4           // after "RIFF"
+	dataSizeOffset        = 12 + 24 + 4 // after the RIFF header, the fmt chunk, and "data", without any chunk of metadata
+)
+
 var (
 	writer     *Writer
 	outputSpec *spec.AudioSpec
+	outputMeta *Metadata
 )
+
+// writeChunkPadded of data, with a pad byte after an odd size, which the size of the chunk excludes
+func writeChunkPadded(riffWriter *riff.Writer, id string, data []byte) {
+	riffWriter.WriteChunk([]byte(id), uint32(len(data)), func(w io.Writer) {
+		w.Write(data)
+		if len(data)%2 == 1 {
+			w.Write([]byte{0})
+		}
+	})
+}
@@ -2,21 +2,93 @@
 package wav
 
 import (
-	//"io/ioutil"
-	//"os"
-	//"log"
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
 	"testing"
-	//"github.com/stretchr/testify/assert"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
 )
 
 func TestConfigureOutput(t *testing.T) {
-	// TODO
+	assert.Equal(t, spec.AudioU8, ConfigureOutput(spec.AudioSpec{Freq: 8000, Format: spec.AudioS8, Channels: 1}).Format)
+	assert.Equal(t, spec.AudioS16, ConfigureOutput(spec.AudioSpec{Freq: 8000, Format: spec.AudioU16, Channels: 1}).Format)
+	assert.Equal(t, spec.AudioS24, ConfigureOutput(spec.AudioSpec{Freq: 8000, Format: spec.AudioS24, Channels: 1}).Format)
 }
 
 func TestTeardownOutput(t *testing.T) {
 	// TODO
 }
 
+func TestOutput_RoundTrip(t *testing.T) {
+	for _, format := range spec.Formats {
+		s := ConfigureOutput(spec.AudioSpec{Freq: 8000, Format: format, Channels: 2})
+		sample.ConfigureOutput(s)
+		sample.SetOutputCallback(func() []sample.Value {
+			return []sample.Value{0.5, -1.5} // out of range must be clamped, not wrapped
+		})
+		outfile, err := ioutil.TempFile("", "mix-wav-writer")
+		assert.Nil(t, err)
+		OutputStart(1500*time.Millisecond, outfile)
+		OutputNext(12000)
+		outfile.Close()
+		out, specs, err := Load(outfile.Name())
+		os.Remove(outfile.Name())
+		assert.Nil(t, err)
+		assert.Equal(t, OutputFormat(format), specs.Format)
+		assert.Equal(t, 12000, len(out))
+		assert.InDelta(t, 0.5, float64(out[0].Values[0]), 1e-2) // within the precision of 8-bit
+		assert.True(t, out[0].Values[1] <= -0.99)
+	}
+}
+
+func TestOutput_Streaming(t *testing.T) {
+	s := spec.AudioSpec{Freq: 8000, Format: spec.AudioS16, Channels: 1}
+	sample.ConfigureOutput(s)
+	sample.SetOutputCallback(func() []sample.Value {
+		return []sample.Value{0.5}
+	})
+	ConfigureOutput(s)
+	// a pipe consumer gets placeholder sizes
+	var buf bytes.Buffer
+	OutputStart(0, &buf)
+	OutputNext(100)
+	assert.Nil(t, OutputClose())
+	assert.Equal(t, []byte{0xFF, 0xFF, 0xFF, 0xFF}, buf.Bytes()[riffSizeOffset:riffSizeOffset+4])
+	assert.Equal(t, []byte{0xFF, 0xFF, 0xFF, 0xFF}, buf.Bytes()[dataSizeOffset:dataSizeOffset+4])
+	out, specs, err := LoadBytes(buf.Bytes()) // and can be loaded back, its data running to the end
+	assert.Nil(t, err)
+	assert.Equal(t, float64(8000), specs.Freq)
+	assert.Equal(t, 100, len(out))
+	// a seekable file gets its sizes patched on close
+	outfile, err := ioutil.TempFile("", "mix-wav-stream")
+	assert.Nil(t, err)
+	defer os.Remove(outfile.Name())
+	OutputStart(0, outfile)
+	OutputNext(100)
+	assert.Nil(t, OutputClose())
+	outfile.Close()
+	out, _, err = Load(outfile.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, 100, len(out))
+	// a buffered writer is flushed on close
+	buf.Reset()
+	OutputStart(100*time.Millisecond, bufio.NewWriter(&buf))
+	OutputNext(800)
+	assert.Nil(t, OutputClose())
+	out, _, err = LoadBytes(buf.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, 800, len(out))
+	written := buf.Len()
+	OutputNext(100) // closed, so pulled and discarded
+	assert.Equal(t, written, buf.Len())
+}
+
 func TestWrite(t *testing.T) {
 	//outfile, err := ioutil.TempFile("/tmp", "outfile")
 	//if err != nil {
@@ -63,3 +135,31 @@ func TestWrite(t *testing.T) {
 	//assert.Equal(t, reader.Format.BitsPerSample, bitsPerSample)
 
 }
+
+func TestOutput_Channels(t *testing.T) {
+	for _, channels := range []int{1, 4} {
+		s := spec.AudioSpec{Freq: 8000, Format: spec.AudioS16, Channels: channels}
+		sample.ConfigureOutput(s)
+		sample.SetOutputCallback(func() []sample.Value {
+			values := make([]sample.Value, channels)
+			for c := range values {
+				values[c] = sample.Value(c) / 8
+			}
+			return values
+		})
+		ConfigureOutput(s)
+		var buf bytes.Buffer
+		OutputStart(10*time.Millisecond, &buf)
+		OutputNext(80)
+		out, specs, err := LoadBytes(buf.Bytes())
+		assert.Nil(t, err)
+		assert.Equal(t, channels, specs.Channels)
+		assert.Equal(t, 80, len(out))
+		for c := 0; c < channels; c++ {
+			assert.InDelta(t, float64(c)/8, float64(out[79].Values[c]), 1e-3)
+		}
+		format := FormatFromSpec(&s)
+		assert.Equal(t, uint16(channels), format.NumChannels)
+		assert.Equal(t, uint16(channels*2), format.BlockAlign)
+	}
+}
@@ -3,10 +3,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"math/rand"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"gopkg.in/pkg/profile.v1"
@@ -18,6 +20,7 @@ import (
 
 var (
 	loader, out string
+	format      string
 	profileMode string
 	sampleHz    = float64(48000)
 	specs       = spec.AudioSpec{
@@ -25,8 +28,7 @@ var (
 		Format:   spec.AudioF32,
 		Channels: 2,
 	}
-	bpm     = 120
-	step    = time.Minute / time.Duration(bpm*4)
+	bpm     = float64(120)
 	loops   = 8
 	prefix  = "sound/808/"
 	kick1   = "kick1.wav"
@@ -58,10 +60,12 @@ var (
 
 func main() {
 	// command-line arguments
-	flag.StringVar(&out, "out", "null", "playback binding [null] _OR_ [wav] for direct stdout (e.g. >file or |aplay)")
+	flag.StringVar(&out, "out", "null", "playback binding [null, portaudio, sdl (built with the tag of its name)] _OR_ [wav, raw] for direct stdout (e.g. >file or |aplay)")
 	flag.StringVar(&profileMode, "profile", "", "enable profiling [cpu, mem, block]")
-	flag.StringVar(&loader, "loader", "wav", "input loading interface [wav, sox]")
+	flag.StringVar(&loader, "loader", "wav", "input loading interface [wav, sox, mp3, flac, ogg, auto]")
+	flag.StringVar(&format, "format", string(spec.AudioF32), "output sample format [U8, S16, S24, S32, F32, F64]")
 	flag.Parse()
+	specs.Format = spec.AudioFormat(format)
 
 	// CPU/Memory/Block profiling
 	if len(profileMode) > 0 {
@@ -80,38 +84,46 @@ func main() {
 
 	// configure mix
 	bind.UseOutputString(out)
-	bind.UseLoaderString(loader)
+	if err := bind.UseLoaderChecked(loader); err != nil {
+		fmt.Fprintf(os.Stderr, "Mix: cannot use %v loader: %s\n", loader, err)
+		os.Exit(1)
+	}
 	defer mix.Teardown()
-	mix.Configure(specs)
+	if err := mix.Configure(specs); err != nil {
+		fmt.Fprintf(os.Stderr, "Mix: cannot configure %v output: %s\n", out, err)
+		os.Exit(1)
+	}
 	mix.SetSoundsPath(prefix)
 
 	// setup the music
-	t := 1 * time.Second // buffer before music
-	for n := 0; n < loops; n++ {
-		for s := 0; s < len(pattern); s++ {
-			mix.SetFire(
-				pattern[s], t+time.Duration(s)*step, 0, 1.0, rand.Float64()*2-1)
-		}
-		t += time.Duration(len(pattern)) * step
+	mix.SetTempo(bpm, 4)
+	mix.SetStepOffset(1 * time.Second) // buffer before music
+
+	// each hit anywhere from left to right, the same every time
+	mix.SetHumanize(mix.HumanizeSpec{PanJitter: 1})
+	steps := loops * len(pattern)
+	for s := 0; s < steps; s++ {
+		mix.SetFireAtStep(pattern[s%len(pattern)], s, 0, 1.0, 0)
 	}
-	t += 5 * time.Second // buffer after music
+	t := 1*time.Second + time.Duration(steps)*mix.StepDuration() + 5*time.Second // buffer after music
 
 	//
 	if bind.IsDirectOutput() {
 		out := os.Stdout
+		signal.Ignore(syscall.SIGPIPE) // if the consumer, e.g. aplay, dies, report the broken pipe instead of exiting
 		mix.Debug(true)
 		mix.OutputStart(t, out)
 		for p := time.Duration(0); p <= t; p += t / 4 {
 			mix.OutputContinueTo(p)
 		}
-		mix.OutputClose()
+		if err := mix.OutputClose(); err != nil {
+			fmt.Fprintf(os.Stderr, "Mix: %s\n", err)
+		}
 	} else {
 		mix.Debug(true)
 		mix.StartAt(time.Now().Add(1 * time.Second))
 		fmt.Printf("Mix: 808 Example - pid:%v playback:%v spec:%v\n", os.Getpid(), out, specs)
-		for mix.FireCount() > 0 {
-			time.Sleep(1 * time.Second)
-		}
+		mix.WaitDone(context.Background())
 	}
 
 }
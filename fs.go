@@ -0,0 +1,12 @@
+package mix
+
+import (
+	"io/fs"
+
+	"github.com/go-mix/mix/lib/mix"
+)
+
+// SetSoundsFS to load sources from a file system, e.g. an embed.FS, as an alternative to SetSoundsPath on the OS
+func SetSoundsFS(fsys fs.FS) {
+	mix.SetSoundsFS(fsys)
+}
@@ -1,10 +1,14 @@
 module github.com/go-mix/mix
 
-go 1.11
+go 1.16
 
 require (
-	github.com/krig/go-sox v0.0.0-20180617124112-7d2f8ae31981
+	github.com/gordonklaus/portaudio v0.0.0-20180817120803-00e7307ccd93
+	github.com/hajimehoshi/go-mp3 v0.2.1
+	github.com/jfreymuth/oggvorbis v1.0.1
+	github.com/mewkiz/flac v1.0.5
 	github.com/stretchr/testify v1.4.0
+	github.com/veandco/go-sdl2 v0.3.3
 	github.com/youpy/go-riff v0.0.0-20131220112943-557d78c11efb
 	gopkg.in/pkg/profile.v1 v1.3.0
 )
@@ -0,0 +1,120 @@
+// Package analyze measures rendered audio, e.g. for the integration tests of an output binding
+package analyze
+
+import (
+	"math"
+)
+
+// Channel of interleaved samples, e.g. as rendered by mix.Render, with a # of channels; channel 0 is the left
+func Channel(samples []float64, channels int, channel int) []float64 {
+	if channels < 1 || channel < 0 || channel >= channels {
+		return nil
+	}
+	out := make([]float64, 0, len(samples)/channels)
+	for i := channel; i < len(samples); i += channels {
+		out = append(out, samples[i])
+	}
+	return out
+}
+
+// MeasureRMS of each channel of interleaved samples, linear from 0 to 1 for a full scale square wave
+func MeasureRMS(samples []float64, channels int) []float64 {
+	if channels < 1 {
+		return nil
+	}
+	rms := make([]float64, channels)
+	frames := len(samples) / channels
+	if frames == 0 {
+		return rms
+	}
+	for i := 0; i < frames*channels; i++ {
+		rms[i%channels] += samples[i] * samples[i]
+	}
+	for c := range rms {
+		rms[c] = math.Sqrt(rms[c] / float64(frames))
+	}
+	return rms
+}
+
+// MeasurePeak of each channel of interleaved samples, linear from 0 to 1 at full scale
+func MeasurePeak(samples []float64, channels int) []float64 {
+	if channels < 1 {
+		return nil
+	}
+	peak := make([]float64, channels)
+	for i, v := range samples {
+		peak[i%channels] = math.Max(peak[i%channels], math.Abs(v))
+	}
+	return peak
+}
+
+// DetectDominantFreq in Hz of the samples of one channel at a frequency, see Channel, by the strongest bin of their spectrum
+// (windowed, and interpolated between bins), or 0 for silence. The resolution is finer than 1Hz for a tenth of a second at 44100Hz.
+func DetectDominantFreq(samples []float64, freq float64) float64 {
+	if len(samples) < 2 || freq <= 0 {
+		return 0
+	}
+	n := 1
+	for n < len(samples) {
+		n <<= 1
+	}
+	spectrum := make([]complex128, n)
+	for i, v := range samples {
+		hann := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(len(samples)-1))
+		spectrum[i] = complex(v*hann, 0)
+	}
+	fft(spectrum)
+	magnitude := func(bin int) float64 {
+		re, im := real(spectrum[bin]), imag(spectrum[bin])
+		return math.Sqrt(re*re + im*im)
+	}
+	best, bestMagnitude := 0, 0.0
+	for bin := 1; bin < n/2; bin++ { // not DC
+		if m := magnitude(bin); m > bestMagnitude {
+			best, bestMagnitude = bin, m
+		}
+	}
+	if best == 0 {
+		return 0
+	}
+	offset := 0.0
+	if best > 1 && best < n/2-1 {
+		before, after := magnitude(best-1), magnitude(best+1)
+		if d := before - 2*bestMagnitude + after; d != 0 {
+			offset = 0.5 * (before - after) / d // parabolic interpolation of the peak between bins
+		}
+	}
+	return (float64(best) + offset) * freq / float64(n)
+}
+
+//
+// Private
+//
+
+// fft in place, of a # of values that is a power of two, by the iterative radix-2 Cooley-Tukey algorithm
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		angle := -2 * math.Pi / float64(size)
+		step := complex(math.Cos(angle), math.Sin(angle))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
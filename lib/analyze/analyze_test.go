@@ -0,0 +1,62 @@
+// Package analyze measures rendered audio, e.g. for the integration tests of an output binding
+package analyze
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannel(t *testing.T) {
+	samples := []float64{1, -1, 2, -2, 3, -3}
+	assert.Equal(t, []float64{1, 2, 3}, Channel(samples, 2, 0))
+	assert.Equal(t, []float64{-1, -2, -3}, Channel(samples, 2, 1))
+	assert.Nil(t, Channel(samples, 2, 2))
+}
+
+func TestMeasureRMS(t *testing.T) {
+	samples := testSine(1000, 44100, 4410, 0.5)
+	stereo := make([]float64, 0, 2*len(samples))
+	for _, v := range samples {
+		stereo = append(stereo, v, 0)
+	}
+	rms := MeasureRMS(stereo, 2)
+	assert.InDelta(t, 0.5/math.Sqrt2, rms[0], 0.001)
+	assert.Equal(t, float64(0), rms[1])
+	assert.Equal(t, []float64{1}, MeasureRMS([]float64{1, -1, 1, -1}, 1))
+	assert.Equal(t, []float64{0, 0}, MeasureRMS(nil, 2))
+}
+
+func TestMeasurePeak(t *testing.T) {
+	assert.Equal(t, []float64{0.5, 0.75}, MeasurePeak([]float64{0.5, -0.25, -0.5, -0.75}, 2))
+}
+
+func TestDetectDominantFreq(t *testing.T) {
+	assert.InDelta(t, float64(1000), DetectDominantFreq(testSine(1000, 44100, 4410, 0.5), 44100), 1)
+	assert.InDelta(t, float64(60), DetectDominantFreq(testSine(60, 48000, 48000, 1), 48000), 1)
+	assert.InDelta(t, float64(12345), DetectDominantFreq(testSine(12345, 44100, 8000, 0.1), 44100), 2)
+	assert.Equal(t, float64(0), DetectDominantFreq(make([]float64, 1000), 44100))
+	assert.Equal(t, float64(0), DetectDominantFreq(nil, 44100))
+}
+
+func TestFFT(t *testing.T) {
+	x := []complex128{1, 0, 0, 0, 0, 0, 0, 0}
+	fft(x)
+	for _, v := range x {
+		assert.InDelta(t, 1.0, cmplx.Abs(v), 1e-12) // an impulse has a flat spectrum
+	}
+}
+
+//
+// Test Components
+//
+
+func testSine(freq float64, sampleRate float64, n int, amplitude float64) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = amplitude * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+	}
+	return out
+}
@@ -0,0 +1,170 @@
+// Package effect processes audio inserted on the master output or a bus of the mix
+package effect
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// NewDelay echoes the audio after a delay time, feeding back a proportion (0 to 1) of each echo into the next,
+// mixed with the dry audio by the wet proportion (0 to 1).
+func NewDelay(delayTime time.Duration, feedback float64, wet float64) *Delay {
+	d := &Delay{}
+	d.delayTime = delayMaxOf(delayTime)
+	d.feedback = delayClamp(feedback)
+	d.wet = delayClamp(wet)
+	return d
+}
+
+// Delay is an echo effect. Its parameters can be changed from any goroutine while it is processing;
+// a new delay time crossfades from the old one over DelayCrossfadeDur, instead of sweeping the pitch.
+type Delay struct {
+	/* target */
+	delayTime time.Duration
+	feedback  float64
+	wet       float64
+	mutex     sync.Mutex
+	/* processing */
+	freq       float64   // of the mix, when the buffer was allocated
+	channels   int       // of the buffer
+	buffer     []float64 // ring of interleaved samples, DelayMaxDur long
+	frames     int       // in the ring
+	writeFrame int
+	nowFrames  int     // the current delay, in frames
+	nextFrames int     // the delay being crossfaded to, in frames
+	fade       float64 // progress of the crossfade from 0 to 1, or 0 if not fading
+}
+
+// DelayMaxDur is the longest delay time; longer delay times are clamped.
+const DelayMaxDur = 4 * time.Second
+
+// DelayCrossfadeDur is the time to crossfade from an old delay time to a new one.
+const DelayCrossfadeDur = 20 * time.Millisecond
+
+// SetDelayTime between the audio and its echo
+func (d *Delay) SetDelayTime(delayTime time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.delayTime = delayMaxOf(delayTime)
+}
+
+// DelayTime between the audio and its echo
+func (d *Delay) DelayTime() time.Duration {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.delayTime
+}
+
+// SyncToStep of a sequence, e.g. a 16th note at the current tempo, such that each echo lands on a step.
+func (d *Delay) SyncToStep(step time.Duration) {
+	d.SetDelayTime(step)
+}
+
+// SetFeedback proportion of each echo fed into the next, from 0 to 1
+func (d *Delay) SetFeedback(feedback float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.feedback = delayClamp(feedback)
+}
+
+// Feedback proportion of each echo fed into the next
+func (d *Delay) Feedback() float64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.feedback
+}
+
+// SetWet proportion of the echo mixed with the dry audio, from 0 to 1
+func (d *Delay) SetWet(wet float64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.wet = delayClamp(wet)
+}
+
+// Wet proportion of the echo mixed with the dry audio
+func (d *Delay) Wet() float64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.wet
+}
+
+// Process interleaved samples in place; until the mix is configured, audio passes through unaffected.
+func (d *Delay) Process(in []float64, channels int) {
+	d.ProcessAt(in, channels, Freq())
+}
+
+// ProcessAt a sample rate in Hz, interleaved samples in place, e.g. of a mixer other than the default
+func (d *Delay) ProcessAt(in []float64, channels int, freq float64) {
+	if freq <= 0 || channels <= 0 {
+		return
+	}
+	d.mutex.Lock()
+	delayTime, feedback, wet := d.delayTime, d.feedback, d.wet
+	d.mutex.Unlock()
+	targetFrames := int(delayTime.Seconds() * freq)
+	if freq != d.freq || channels != d.channels {
+		d.allocate(freq, channels)
+		d.nowFrames = targetFrames
+	}
+	fadeStep := 1 / (DelayCrossfadeDur.Seconds() * freq)
+	for frame := 0; frame+channels <= len(in); frame += channels {
+		if d.fade == 0 && targetFrames != d.nowFrames {
+			d.nextFrames = targetFrames
+			d.fade = fadeStep
+		}
+		for c := 0; c < channels; c++ {
+			echo := d.read(d.nowFrames, c)
+			if d.fade > 0 {
+				echo = echo*(1-d.fade) + d.read(d.nextFrames, c)*d.fade
+			}
+			dry := in[frame+c]
+			d.buffer[d.writeFrame*channels+c] = dry + echo*feedback
+			in[frame+c] = dry*(1-wet) + echo*wet
+		}
+		d.writeFrame = (d.writeFrame + 1) % d.frames
+		if d.fade > 0 {
+			d.fade += fadeStep
+			if d.fade >= 1 {
+				d.nowFrames = d.nextFrames
+				d.fade = 0
+			}
+		}
+	}
+}
+
+//
+// Private
+//
+
+// allocate the ring buffer, DelayMaxDur long, which also clears any echo in progress
+func (d *Delay) allocate(freq float64, channels int) {
+	d.freq = freq
+	d.channels = channels
+	d.frames = int(math.Ceil(DelayMaxDur.Seconds()*freq)) + 1
+	d.buffer = make([]float64, d.frames*channels)
+	d.writeFrame = 0
+	d.fade = 0
+}
+
+// read a channel of the frame that was written a delay # of frames ago
+func (d *Delay) read(delayFrames int, channel int) float64 {
+	if delayFrames <= 0 {
+		return 0
+	}
+	frame := (d.writeFrame - delayFrames + d.frames) % d.frames
+	return d.buffer[frame*d.channels+channel]
+}
+
+func delayMaxOf(delayTime time.Duration) time.Duration {
+	if delayTime < 0 {
+		return 0
+	} else if delayTime > DelayMaxDur {
+		return DelayMaxDur
+	}
+	return delayTime
+}
+
+func delayClamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,58 @@
+// Package effect processes audio inserted on the master output or a bus of the mix
+package effect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	testEffectSetup()
+	d := NewDelay(10*time.Millisecond, 0.5, 0.5)
+	in := make([]float64, 2*2000)
+	in[0] = 1 // impulse on the left channel only
+	d.Process(in, 2)
+	assert.Equal(t, 0.5, in[0])      // dry
+	assert.Equal(t, 0.5, in[441*2])  // first echo
+	assert.Equal(t, 0.25, in[882*2]) // second echo, fed back
+	for n := 0; n < 2000; n++ {
+		assert.Equal(t, float64(0), in[n*2+1]) // nothing smears into the right channel
+	}
+}
+
+func TestDelay_Clamp(t *testing.T) {
+	d := NewDelay(time.Minute, 1.5, -1)
+	assert.Equal(t, DelayMaxDur, d.DelayTime())
+	assert.Equal(t, float64(1), d.Feedback())
+	assert.Equal(t, float64(0), d.Wet())
+	d.SetFeedback(2)
+	d.SetWet(0.25)
+	assert.Equal(t, float64(1), d.Feedback())
+	assert.Equal(t, 0.25, d.Wet())
+}
+
+func TestDelay_SyncToStep(t *testing.T) {
+	d := NewDelay(0, 0, 1)
+	d.SyncToStep(time.Minute / time.Duration(120*4))
+	assert.Equal(t, 125*time.Millisecond, d.DelayTime())
+}
+
+func TestDelay_SetDelayTime(t *testing.T) {
+	testEffectSetup()
+	d := NewDelay(10*time.Millisecond, 0, 1)
+	d.Process(make([]float64, 1), 1)
+	d.SetDelayTime(20 * time.Millisecond)
+	in := make([]float64, 441)
+	for n := range in {
+		in[n] = 1
+	}
+	d.Process(in, 1)
+	assert.Equal(t, 441, d.nowFrames)
+	assert.Equal(t, 882, d.nextFrames)
+	assert.True(t, d.fade > 0) // still crossfading, 20ms takes 882 frames
+	d.Process(make([]float64, 882), 1)
+	assert.Equal(t, 882, d.nowFrames)
+	assert.Equal(t, float64(0), d.fade)
+}
@@ -0,0 +1,44 @@
+// Package effect processes audio inserted on the master output or a bus of the mix
+package effect
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Effect processes interleaved samples of audio in place, e.g. one sample of all channels at a time from the mix loop.
+// Any state must be kept per channel. Process is only ever called from the mix loop, but an Effect whose
+// parameters can be changed from another goroutine must guard them itself.
+type Effect interface {
+	Process(in []float64, channels int)
+}
+
+// ProcessAt a sample rate in Hz, interleaved samples in place with an effect, e.g. of a mixer other than the default.
+// An effect that implements ProcessAt is given the rate; any other effect must take it from Freq.
+func ProcessAt(e Effect, in []float64, channels int, freq float64) {
+	if at, ok := e.(interface {
+		ProcessAt(in []float64, channels int, freq float64)
+	}); ok {
+		at.ProcessAt(in, channels, freq)
+		return
+	}
+	e.Process(in, channels)
+}
+
+// Configure the sample rate of the mix, which the effects are processing.
+func Configure(s spec.AudioSpec) {
+	atomic.StoreUint64(&masterFreqBits, math.Float64bits(s.Freq))
+}
+
+// Freq of the mix in Hz, or 0 if not yet configured.
+func Freq() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&masterFreqBits))
+}
+
+//
+// Private
+//
+
+var masterFreqBits uint64 // accessed atomically
@@ -0,0 +1,158 @@
+// Package effect processes audio inserted on the master output or a bus of the mix
+package effect
+
+import (
+	"math"
+	"sync"
+)
+
+// NewLowPass biquad filter, attenuating above the cutoff frequency in Hz, with resonance Q (0.7071 is flat)
+func NewLowPass(cutoff float64, q float64) *Filter {
+	return newFilter(filterLowPass, cutoff, q)
+}
+
+// NewHighPass biquad filter, attenuating below the cutoff frequency in Hz, with resonance Q (0.7071 is flat)
+func NewHighPass(cutoff float64, q float64) *Filter {
+	return newFilter(filterHighPass, cutoff, q)
+}
+
+// Filter is a biquad low-pass or high-pass filter. Its parameters can be changed from any goroutine while it is processing,
+// and glide to their new values over FilterSmoothDur to avoid clicks.
+type Filter struct {
+	kind filterKind
+	/* target */
+	cutoff float64
+	q      float64
+	mutex  sync.Mutex
+	/* processing */
+	freq       float64 // of the mix, when the coefficients were computed
+	nowCutoff  float64
+	nowQ       float64
+	b0, b1, b2 float64
+	a1, a2     float64
+	x1, x2     []float64 // per channel
+	y1, y2     []float64 // per channel
+}
+
+// FilterSmoothDur is the time constant in seconds of the glide toward a new cutoff or Q
+const FilterSmoothDur = 0.01
+
+// SetCutoff frequency in Hz
+func (f *Filter) SetCutoff(cutoff float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.cutoff = math.Max(filterMinCutoff, cutoff)
+}
+
+// Cutoff frequency in Hz
+func (f *Filter) Cutoff() float64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.cutoff
+}
+
+// SetQ resonance, where 0.7071 is flat and higher values peak at the cutoff
+func (f *Filter) SetQ(q float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.q = math.Max(filterMinQ, q)
+}
+
+// Q resonance
+func (f *Filter) Q() float64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.q
+}
+
+// Process interleaved samples in place; until the mix is configured, audio passes through unfiltered.
+func (f *Filter) Process(in []float64, channels int) {
+	f.ProcessAt(in, channels, Freq())
+}
+
+// ProcessAt a sample rate in Hz, interleaved samples in place, e.g. of a mixer other than the default
+func (f *Filter) ProcessAt(in []float64, channels int, freq float64) {
+	if freq <= 0 || channels <= 0 {
+		return
+	}
+	f.mutex.Lock()
+	cutoff, q := f.cutoff, f.q
+	f.mutex.Unlock()
+	if len(f.x1) != channels {
+		f.x1, f.x2 = make([]float64, channels), make([]float64, channels)
+		f.y1, f.y2 = make([]float64, channels), make([]float64, channels)
+	}
+	if freq != f.freq {
+		// first time processing, or the mix was reconfigured: jump straight to the target
+		f.freq = freq
+		f.nowCutoff, f.nowQ = cutoff, q
+		f.compute()
+	}
+	smooth := 1 - math.Exp(-1/(FilterSmoothDur*freq))
+	for frame := 0; frame+channels <= len(in); frame += channels {
+		if f.nowCutoff != cutoff || f.nowQ != q {
+			f.nowCutoff = filterGlide(f.nowCutoff, cutoff, smooth)
+			f.nowQ = filterGlide(f.nowQ, q, smooth)
+			f.compute()
+		}
+		for c := 0; c < channels; c++ {
+			x := in[frame+c]
+			y := f.b0*x + f.b1*f.x1[c] + f.b2*f.x2[c] - f.a1*f.y1[c] - f.a2*f.y2[c]
+			f.x2[c], f.x1[c] = f.x1[c], x
+			f.y2[c], f.y1[c] = f.y1[c], y
+			in[frame+c] = y
+		}
+	}
+}
+
+//
+// Private
+//
+
+type filterKind uint
+
+const (
+	filterLowPass filterKind = iota
+	filterHighPass
+)
+
+const (
+	filterMinCutoff = 1
+	filterMinQ      = 0.1
+	filterMaxCutoff = 0.49 // ratio of the mix frequency, just below Nyquist
+)
+
+func newFilter(kind filterKind, cutoff float64, q float64) *Filter {
+	return &Filter{
+		kind:   kind,
+		cutoff: math.Max(filterMinCutoff, cutoff),
+		q:      math.Max(filterMinQ, q),
+	}
+}
+
+// compute the normalized biquad coefficients from the current cutoff and Q, per the RBJ Audio EQ Cookbook
+func (f *Filter) compute() {
+	w0 := 2 * math.Pi * math.Min(f.nowCutoff, f.freq*filterMaxCutoff) / f.freq
+	cos, alpha := math.Cos(w0), math.Sin(w0)/(2*f.nowQ)
+	a0 := 1 + alpha
+	switch f.kind {
+	case filterLowPass:
+		f.b0 = (1 - cos) / 2 / a0
+		f.b1 = (1 - cos) / a0
+	case filterHighPass:
+		f.b0 = (1 + cos) / 2 / a0
+		f.b1 = -(1 + cos) / a0
+	}
+	f.b2 = f.b0
+	f.a1 = -2 * cos / a0
+	f.a2 = (1 - alpha) / a0
+}
+
+// filterGlide one step from a value toward a target, in proportion, arriving once it is close enough to be inaudible
+func filterGlide(from float64, to float64, smooth float64) float64 {
+	next := from * math.Pow(to/from, smooth)
+	if math.Abs(next-to) < to*1e-4 {
+		return to
+	}
+	return next
+}
@@ -0,0 +1,96 @@
+// Package effect processes audio inserted on the master output or a bus of the mix
+package effect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestConfigure(t *testing.T) {
+	testEffectSetup()
+	assert.Equal(t, float64(44100), Freq())
+}
+
+func TestLowPass(t *testing.T) {
+	testEffectSetup()
+	lp := NewLowPass(1000, 0.7071)
+	assert.InDelta(t, 1, testFilterGain(lp, 100), 0.05)
+	lp = NewLowPass(1000, 0.7071)
+	assert.InDelta(t, 0.7071, testFilterGain(lp, 1000), 0.05)
+	lp = NewLowPass(1000, 0.7071)
+	assert.True(t, testFilterGain(lp, 10000) < 0.05)
+}
+
+func TestHighPass(t *testing.T) {
+	testEffectSetup()
+	hp := NewHighPass(1000, 0.7071)
+	assert.True(t, testFilterGain(hp, 100) < 0.05)
+	hp = NewHighPass(1000, 0.7071)
+	assert.InDelta(t, 1, testFilterGain(hp, 10000), 0.05)
+}
+
+func TestFilter_PerChannel(t *testing.T) {
+	testEffectSetup()
+	lp := NewLowPass(1000, 0.7071)
+	in := make([]float64, 2*1000)
+	for n := 0; n < 1000; n++ {
+		in[n*2] = math.Sin(2 * math.Pi * 100 * float64(n) / 44100)
+	}
+	lp.Process(in, 2)
+	for n := 0; n < 1000; n++ {
+		assert.Equal(t, float64(0), in[n*2+1]) // nothing from the left channel smears into the right
+	}
+}
+
+func TestFilter_SetCutoff(t *testing.T) {
+	testEffectSetup()
+	lp := NewLowPass(1000, 0.7071)
+	lp.Process(make([]float64, 1), 1)
+	lp.SetCutoff(5000)
+	lp.SetQ(2)
+	assert.Equal(t, float64(5000), lp.Cutoff())
+	assert.Equal(t, float64(2), lp.Q())
+	lp.Process(make([]float64, 1), 1)
+	assert.True(t, lp.nowCutoff > 1000)
+	assert.True(t, lp.nowCutoff < 5000) // glides toward the new cutoff, instead of jumping
+	lp.Process(make([]float64, 44100), 1)
+	assert.Equal(t, float64(5000), lp.nowCutoff)
+	assert.Equal(t, float64(2), lp.nowQ)
+}
+
+func TestFilter_Unconfigured(t *testing.T) {
+	Configure(spec.AudioSpec{})
+	lp := NewLowPass(1000, 0.7071)
+	in := []float64{1, 0.5, -1}
+	lp.Process(in, 1)
+	assert.Equal(t, []float64{1, 0.5, -1}, in)
+}
+
+//
+// Private
+//
+
+func testEffectSetup() {
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 2,
+	})
+}
+
+// testFilterGain of a filter for a sine wave at a frequency, measured by the peak after it has settled
+func testFilterGain(f *Filter, hz float64) (peak float64) {
+	in := make([]float64, 44100/2)
+	for n := range in {
+		in[n] = math.Sin(2 * math.Pi * hz * float64(n) / 44100)
+	}
+	f.Process(in, 1)
+	for _, v := range in[len(in)/2:] {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return
+}
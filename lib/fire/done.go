@@ -0,0 +1,96 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"sync"
+)
+
+// DoneReason a Fire finished playing
+type DoneReason uint
+
+const (
+	NotDone      DoneReason = iota // the Fire has not finished
+	DoneSustain                    // its sustain expired
+	DoneEnd                        // it played to the natural end of its source or region, or of its repeats
+	DoneCanceled                   // it was canceled, e.g. cleared from the mixer, or torn down with it
+	DoneChoked                     // another Fire of its choke group started playing, see SetChoke
+)
+
+// OnDone registers a hook to call when the Fire is done, e.g. to trigger another Fire when this one finishes.
+// Each hook is called exactly once, on a goroutine that is never the mix loop, in the order the Fires finish;
+// a hook registered on a Fire that is already done is called immediately, before OnDone returns.
+// The reason the Fire finished is its DoneReason.
+func (f *Fire) OnDone(fn func(f *Fire)) {
+	f.mutex.Lock()
+	if f.doneReason == NotDone {
+		f.doneHooks = append(f.doneHooks, fn)
+		f.mutex.Unlock()
+		return
+	}
+	f.mutex.Unlock()
+	fn(f)
+}
+
+// DoneReason the Fire first finished playing, or NotDone; it is cleared if the Fire is reset to play again, e.g. by a rewind,
+// unless the Fire was canceled, which is never reset.
+func (f *Fire) DoneReason() DoneReason {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.doneReason
+}
+
+//
+// Private
+//
+
+type doneCall struct {
+	fire  *Fire
+	hooks []func(f *Fire)
+}
+
+var (
+	doneCalls  []doneCall
+	doneMutex  sync.Mutex
+	doneSignal = make(chan struct{}, 1)
+	doneOnce   sync.Once
+)
+
+// finish the Fire, in a state of done or canceled, and queue its hooks; the caller must hold the mutex of the Fire.
+// Only the first finish has a reason, so that a Fire that is done and then canceled still reports how it finished.
+func (f *Fire) finish(state StateEnum, reason DoneReason) {
+	f.state = state
+	if f.doneReason != NotDone {
+		return
+	}
+	f.doneReason = reason
+	if len(f.doneHooks) == 0 {
+		return
+	}
+	hooks := f.doneHooks
+	f.doneHooks = nil
+	doneOnce.Do(func() {
+		go doneLoop()
+	})
+	doneMutex.Lock()
+	doneCalls = append(doneCalls, doneCall{f, hooks})
+	doneMutex.Unlock()
+	select {
+	case doneSignal <- struct{}{}:
+	default: // already signaled
+	}
+}
+
+// doneLoop calls the queued hooks each time it is signaled, on one goroutine, so that the mix loop never waits on a hook
+func doneLoop() {
+	for range doneSignal {
+		doneMutex.Lock()
+		calls := doneCalls
+		doneCalls = nil
+		doneMutex.Unlock()
+		for _, call := range calls {
+			for _, fn := range call.hooks {
+				fn(call.fire)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestOnDone_Sustain(t *testing.T) {
+	f := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	done := testOnDone(f)
+	for at := spec.Tz(1000); at <= 2000; at++ {
+		f.At(at)
+	}
+	assert.Equal(t, f, testDoneWait(t, done))
+	assert.Equal(t, DoneSustain, f.DoneReason())
+	f.Cancel()
+	assert.Equal(t, DoneSustain, f.DoneReason()) // of the first finish
+	testDoneNever(t, done)
+}
+
+func TestOnDone_End(t *testing.T) {
+	f := New("nonexistent.wav", spec.Tz(10), 0, 1, 0)
+	done := testOnDone(f)
+	f.At(10)
+	f.At(11) // nothing to play
+	testDoneWait(t, done)
+	assert.Equal(t, DoneEnd, f.DoneReason())
+}
+
+func TestOnDone_Cancel(t *testing.T) {
+	f := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	assert.Equal(t, NotDone, f.DoneReason())
+	first, second := testOnDone(f), testOnDone(f)
+	f.Cancel()
+	f.Cancel()
+	testDoneWait(t, first)
+	testDoneWait(t, second)
+	assert.Equal(t, DoneCanceled, f.DoneReason())
+	testDoneNever(t, first)
+}
+
+func TestOnDone_AlreadyDone(t *testing.T) {
+	f := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	f.Cancel()
+	called := false
+	f.OnDone(func(*Fire) {
+		called = true
+	})
+	assert.True(t, called)
+}
+
+func TestOnDone_AfterReset(t *testing.T) {
+	f := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	for at := spec.Tz(1000); at <= 2000; at++ {
+		f.At(at)
+	}
+	assert.Equal(t, DoneSustain, f.DoneReason())
+	f.Reset()
+	assert.Equal(t, NotDone, f.DoneReason())
+	done := testOnDone(f)
+	testDoneNever(t, done) // not the stale reason of the previous run
+	for at := spec.Tz(1000); at <= 2000; at++ {
+		f.At(at)
+	}
+	assert.Equal(t, f, testDoneWait(t, done))
+	assert.Equal(t, DoneSustain, f.DoneReason())
+}
+
+//
+// Test Components
+//
+
+func testOnDone(f *Fire) chan *Fire {
+	done := make(chan *Fire, 2)
+	f.OnDone(func(f *Fire) {
+		done <- f
+	})
+	return done
+}
+
+func testDoneWait(t *testing.T, done chan *Fire) *Fire {
+	select {
+	case f := <-done:
+		return f
+	case <-time.After(time.Second):
+		t.Fatal("hook was not called")
+		return nil
+	}
+}
+
+func testDoneNever(t *testing.T, done chan *Fire) {
+	select {
+	case <-done:
+		t.Fatal("hook was called more than once")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,57 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"sort"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// EnvelopePoint is a target value at an offset in Tz from the beginning of a Fire.
+type EnvelopePoint struct {
+	OffsetTz spec.Tz
+	Value    float64
+}
+
+// Envelope is a series of points, linearly interpolated, sorted by offset.
+type Envelope []EnvelopePoint
+
+// NewEnvelope from a series of points in any order.
+func NewEnvelope(points []EnvelopePoint) Envelope {
+	env := make(Envelope, len(points))
+	copy(env, points)
+	sort.SliceStable(env, func(i, j int) bool {
+		return env[i].OffsetTz < env[j].OffsetTz
+	})
+	return env
+}
+
+// At an offset in Tz from the beginning of the Fire, return the interpolated value.
+func (env Envelope) At(at spec.Tz) float64 {
+	n := sort.Search(len(env), func(i int) bool {
+		return env[i].OffsetTz > at
+	})
+	if n == 0 {
+		return env[0].Value
+	}
+	if n == len(env) {
+		return env[n-1].Value
+	}
+	from, to := env[n-1], env[n]
+	pos := float64(at-from.OffsetTz) / float64(to.OffsetTz-from.OffsetTz)
+	return from.Value + pos*(to.Value-from.Value)
+}
+
+// shifted earlier by a # of Tz, beginning at the value it had then, or nil if it has no points
+func (env Envelope) shifted(by spec.Tz) Envelope {
+	if len(env) == 0 {
+		return nil
+	}
+	out := Envelope{{0, env.At(by)}}
+	for _, p := range env {
+		if p.OffsetTz > by {
+			out = append(out, EnvelopePoint{p.OffsetTz - by, p.Value})
+		}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestNewEnvelope(t *testing.T) {
+	env := NewEnvelope([]EnvelopePoint{{100, 1}, {0, 0}})
+	assert.Equal(t, Envelope{{0, 0}, {100, 1}}, env)
+}
+
+func TestEnvelopeAt(t *testing.T) {
+	env := NewEnvelope([]EnvelopePoint{{10, 0}, {110, 1}, {210, -1}})
+	assert.Equal(t, float64(0), env.At(0))
+	assert.Equal(t, float64(0), env.At(10))
+	assert.Equal(t, float64(0.5), env.At(60))
+	assert.Equal(t, float64(1), env.At(110))
+	assert.Equal(t, float64(0), env.At(160))
+	assert.Equal(t, float64(-1), env.At(210))
+	assert.Equal(t, float64(-1), env.At(spec.Tz(5000)))
+}
@@ -2,23 +2,52 @@
 package fire
 
 import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
 	"github.com/go-mix/mix/bind/spec"
 
+	"github.com/go-mix/mix/lib/level"
 	"github.com/go-mix/mix/lib/source"
 )
 
+// Configure the master frequency, to convert the Tz of a Fire to time.
+func Configure(s spec.AudioSpec) {
+	atomic.StoreUint64(&masterFreqBits, math.Float64bits(s.Freq))
+}
+
+// Configure the master frequency of this Fire, to convert its Tz to time, instead of the one configured for the package,
+// e.g. for a fire of a mixer other than the default; it must be set before the Fire is scheduled.
+func (f *Fire) Configure(s spec.AudioSpec) {
+	f.masterFreq = s.Freq
+}
+
+// SetCache of the sources of this Fire, to know their natural length, instead of the default cache of the source package,
+// e.g. for a fire of a mixer other than the default; it must be set before the Fire is scheduled.
+func (f *Fire) SetCache(c *source.Cache) {
+	f.cache = c
+}
+
 // New Fire to represent a single audio source playing at a specific time in the future.
 func New(source string, beginTz spec.Tz, endTz spec.Tz, volume float64, pan float64) *Fire {
 	// debug.Printf("NewFire(%v, %v, %v, %v, %v)\n", source, beginTz, endTz, volume, pan)
 	s := &Fire{
 		/* setup */
-		Source:  source,
-		Volume:  volume,
-		Pan:     pan,
-		BeginTz: beginTz,
-		EndTz:   endTz,
+		Source:    source,
+		Volume:    volume,
+		Pan:       pan,
+		nowVolume: volume,
+		nowPan:    pan,
+		Rate:      1,
+		timeScale: 1,
+		BeginTz:   beginTz,
+		EndTz:     endTz,
 		/* playback */
-		state: fireStateReady,
+		state: StateReady,
 	}
 	return s
 }
@@ -26,49 +55,429 @@ func New(source string, beginTz spec.Tz, endTz spec.Tz, volume float64, pan floa
 // Fire represents a single audio source playing at a specific time in the future.
 type Fire struct {
 	/* setup */
-	BeginTz spec.Tz
-	EndTz   spec.Tz
-	Source  string
-	Volume  float64 // 0 to 1
-	Pan     float64 // -1 to +1
+	BeginTz   spec.Tz
+	BeginFrac float64 // of a sample after the BeginTz, that the Fire really begins, from 0 to 1; honored by interpolation
+	EndTz     spec.Tz
+	Source    string  // key of the source, or the name of a multi-sample source, see Resolve
+	Bus       string  // name of the mix bus, or empty for the default master bus
+	Volume    float64 // 0 to 1
+	Pan       float64 // -1 to +1
+	Rate      float64 // playback rate, e.g. 2 is one octave up and 0.5 is one octave down
+	/* region */
+	OffsetTz spec.Tz // into the source, where playback starts
+	LengthTz spec.Tz // of the source to play, or 0 to play to its natural end
+	/* loop */
+	IntervalTz spec.Tz // re-trigger every interval, or 0 to play once
+	Repeat     int     // total # of times to trigger a loop, or -1 to repeat until canceled
+	XFadeTz    spec.Tz // of the seam of a crossfade loop, see SetLoopXFade
+	/* sustain loop */
+	SustainLoopBeginTz spec.Tz // of the source, where playback wraps back to while the Fire is sustained
+	SustainLoopEndTz   spec.Tz // of the source, where playback wraps from, or 0 to play through
+	xfadeLoop          bool
+	/* automation */
+	volumeEnvelope Envelope
+	panEnvelope    Envelope
+	/* fades */
+	attackTz  spec.Tz
+	releaseTz spec.Tz
+	fadesSet  bool // else a sustain that truncates the source gets a release of TruncateReleaseDur
+	/* choke */
+	chokeGroup  string
+	choked      bool
+	chokeTz     spec.Tz // of mix playback, when it was choked
+	chokeFadeTz spec.Tz
+	/* humanize */
+	humanize  *HumanizeSpec // or nil for that of the mixer
+	humanized bool
+	/* tone */
+	toneLowDB  float64
+	toneHighDB float64
+	toneFilter *ToneFilter // resolved as it goes live, or nil if it has no tone
 	/* playback */
-	nowTz spec.Tz
-	state fireStateEnum
+	nowTz         spec.Tz
+	atTz          spec.Tz // of mix playback, as of the last At or Seek
+	nowVolume     float64 // ramps toward the Volume while playing, to avoid zipper noise
+	nowPan        float64 // ramps toward the Pan while playing
+	state         StateEnum
+	xfadeHeadTz   spec.Tz // of the current iteration of a crossfade loop
+	xfadeTailTz   spec.Tz // of the previous iteration, while it overlaps the current one
+	xfadeCrossing bool
+	/* done */
+	doneReason DoneReason
+	doneHooks  []func(f *Fire)
+	variant    string     // the key of the source resolved from a multi-sample source, or empty
+	mutex      sync.Mutex // guards playback, loop & automation, between the mix loop and any other goroutine
+	meter      level.Meter
+	masterFreq float64       // or 0 for the master frequency configured for the package
+	cache      *source.Cache // of its sources, or nil for the default cache
+	timeScale  float64       // of the mix timeline, see SetTimeScale
 }
 
 // At the series of Tz it's playing for, return the series of Tz corresponding to source audio.
 func (f *Fire) At(at spec.Tz) (t spec.Tz) {
-	//	debug.Printf("*Fire[%s].At(%v vs %v)\n", f.Source, at, f.BeginTz)
-	switch f.state {
-	case fireStateReady:
-		if at >= f.BeginTz {
-			f.state = fireStatePlay
-			f.nowTz++
+	t, _ = f.PlayAt(at)
+	return
+}
+
+// PlayAt is At, and also returns whether the Fire sounds at that Tz of mix playback: from its very first sample, at its BeginTz,
+// which plays the source at Tz 0 (plus any offset), until it is done.
+func (f *Fire) PlayAt(at spec.Tz) (t spec.Tz, playing bool) {
+	t, playing, _ = f.Advance(at)
+	return
+}
+
+// Advance is PlayAt, and also returns the state the Fire transitioned to, if it started playing (StatePlay)
+// or finished (StateDone) at that Tz of mix playback, else zero.
+func (f *Fire) Advance(at spec.Tz) (t spec.Tz, playing bool, transition StateEnum) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	before := f.state
+	t, playing = f.playAt(at)
+	if f.state != before && (f.state == StatePlay || f.state == StateDone) {
+		transition = f.state
+	}
+	return
+}
+
+// SetChoke group of the Fire: when another Fire of the same group starts playing, this one is choked, see Choke,
+// e.g. a closed hi-hat cuts off an open one; or empty for none, the default.
+func (f *Fire) SetChoke(group string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.chokeGroup = group
+}
+
+// ChokeGroup of the Fire, or empty for none
+func (f *Fire) ChokeGroup() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.chokeGroup
+}
+
+// Choke the Fire at a Tz of mix playback, fading it out over a # of Tz, after which it is done with DoneChoked.
+// Only a Fire that is playing can be choked, and only once: a second choke does not restart the fade.
+func (f *Fire) Choke(at spec.Tz, fadeTz spec.Tz) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.state != StatePlay || f.choked {
+		return
+	}
+	if fadeTz < 1 {
+		fadeTz = 1
+	}
+	f.choked = true
+	f.chokeTz = at
+	f.chokeFadeTz = fadeTz
+}
+
+// SetLoop to re-trigger the Fire every interval Tz, for a total # of repeats, or -1 to repeat until canceled.
+// Each repeat cuts off the previous one, like a re-triggered sampler voice.
+func (f *Fire) SetLoop(intervalTz spec.Tz, repeat int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.IntervalTz = intervalTz
+	f.Repeat = repeat
+}
+
+// SetLoopXFade to play the source over and over until canceled, overlapping the tail of each iteration with the head of the next
+// for a # of Tz, crossfaded by equal-power gains, so that there is no click at the seam, e.g. a backing track. Unlike SetLoop, the
+// iterations overlap; the crossfade is clamped to half the length of the source. Must be set before the Fire begins playing.
+func (f *Fire) SetLoopXFade(xfadeTz spec.Tz) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.xfadeLoop = true
+	f.XFadeTz = xfadeTz
+	f.EndTz = 0
+}
+
+// LoopXFade of a crossfade loop, as of the last Tz of mix playback, see SetLoopXFade: while the tail of the previous iteration
+// overlaps the head of the current one, the Tz of the tail, and the equal-power gains of the head and the tail; else ok is false.
+// Called by the mix loop once per sample, for a second read of the source.
+func (f *Fire) LoopXFade() (tailTz spec.Tz, headGain float64, tailGain float64, ok bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if !f.xfadeCrossing {
+		return 0, 1, 0, false
+	}
+	_, xfadeTz := f.xfadeLength()
+	angle := math.Pi / 2 * float64(f.xfadeHeadTz) / float64(xfadeTz)
+	return f.xfadeTailTz, math.Sin(angle), math.Cos(angle), true
+}
+
+// LoopsForever is true if the Fire loops until canceled, so never ends
+func (f *Fire) LoopsForever() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.loopsForever()
+}
+
+// Cancel the Fire, such that it will never play (again), even if the playhead is rewound.
+func (f *Fire) Cancel() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.finish(StateCancel, DoneCanceled)
+	debug.Debugf("fire(%s) canceled", f.Source)
+}
+
+// IsCanceled the Fire?
+func (f *Fire) IsCanceled() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.state == StateCancel
+}
+
+// SetRegion of the source to play, from an offset, for a length or 0 to its natural end. Must be set before the Fire begins playing.
+// The Fire ends early if the region extends past the end of the source, or its sustain is longer than the region.
+func (f *Fire) SetRegion(offsetTz spec.Tz, lengthTz spec.Tz) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.OffsetTz = offsetTz
+	f.LengthTz = lengthTz
+	if f.EndTz != 0 && f.IntervalTz == 0 {
+		if natural := f.naturalLength(); f.EndTz-f.BeginTz > natural {
+			f.EndTz = f.BeginTz + natural
 		}
-	case fireStatePlay:
-		t = f.nowTz
-		f.nowTz++
-		if f.EndTz != 0 {
-			if at >= f.EndTz {
-				f.state = fireStateDone
-			}
-		} else {
-			f.EndTz = f.BeginTz + f.sourceLength()
+	}
+}
+
+// SetEnd of the Fire, the Tz where its sustain expires, e.g. as resolved by the tempo when it goes live; ignored if it plays to its
+// natural end, or loops. Must be set before the Fire begins playing.
+func (f *Fire) SetEnd(endTz spec.Tz) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.EndTz == 0 || f.IntervalTz != 0 || endTz <= f.BeginTz {
+		return
+	}
+	f.EndTz = endTz
+}
+
+// SetSustainLoop between two Tz of the source, like a classic sampler: playback wraps from the end of the loop back to its begin,
+// for as long as the Fire is sustained. The loop is ignored if the Fire has no sustain, or loops by interval, or the loop
+// is empty or begins before the region. Must be set before the Fire begins playing.
+func (f *Fire) SetSustainLoop(beginTz spec.Tz, endTz spec.Tz) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.SustainLoopBeginTz = beginTz
+	f.SustainLoopEndTz = endTz
+}
+
+// Resolve the Source of the Fire to the key of a variant, e.g. a layer of a multi-sample source picked as the Fire goes live.
+func (f *Fire) Resolve(variant string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.variant = variant
+}
+
+// Resolved key of the source that the Fire plays: the variant its Source was resolved to, else its Source.
+func (f *Fire) Resolved() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.resolved()
+}
+
+// SetTimeScale of the mix timeline the Fire is scheduled on, e.g. 5 to play it five times faster: each time of the Fire, e.g. its
+// begin, sustain or loop interval, is of the timeline, and its source plays at its rate times the scale. Set by the mixer before
+// it is scheduled, see mix.SetTimeScale; the Tz it begins and ends are not changed.
+func (f *Fire) SetTimeScale(scale float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.timeScale = scale
+}
+
+// PlayRate of the source, its rate times the time scale of the mix timeline, called by the mix loop
+func (f *Fire) PlayRate() float64 {
+	return f.Rate * f.timeScale
+}
+
+// SetRate of playback, e.g. 2 is one octave up at double speed. Must be set before the Fire begins playing.
+func (f *Fire) SetRate(rate float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if rate <= 0 {
+		rate = 1
+	}
+	f.Rate = rate
+}
+
+// SetVolume from 0 to 1, taking effect on the next sample mixed; while playing, it ramps over SmoothDur.
+// It is safe to call from any goroutine, e.g. a mixer fader in a UI, while the mixer runs. A volume envelope takes precedence.
+func (f *Fire) SetVolume(v float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Volume = math.Max(0, math.Min(1, v))
+	if f.state != StatePlay {
+		f.nowVolume = f.Volume
+	}
+}
+
+// ScaleVolume by a factor, e.g. 0.5, clamped from 0 to 1 like SetVolume; it is safe to call from any goroutine while the mixer runs.
+func (f *Fire) ScaleVolume(factor float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Volume = math.Max(0, math.Min(1, f.Volume*factor))
+	if f.state != StatePlay {
+		f.nowVolume = f.Volume
+	}
+}
+
+// SetPan from -1 to +1, taking effect on the next sample mixed; while playing, it ramps over SmoothDur.
+// It is safe to call from any goroutine while the mixer runs. A pan envelope takes precedence.
+func (f *Fire) SetPan(p float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Pan = math.Max(-1, math.Min(1, p))
+	if f.state != StatePlay {
+		f.nowPan = f.Pan
+	}
+}
+
+// SmoothDur for a change of volume across its whole range (or half the range of pan) while the Fire is playing
+const SmoothDur = 5 * time.Millisecond
+
+// SetFades to ramp the volume linearly up over the first attack, and down over the last release, of the audible window
+// of the Fire (or of each repeat, if it loops). Zero for either is an instant edge; until SetFades is called, a Fire whose
+// sustain truncates its source gets a release of TruncateReleaseDur, to avoid a click where the audio is cut off.
+func (f *Fire) SetFades(attack time.Duration, release time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.attackTz = f.tzOf(attack)
+	f.releaseTz = f.tzOf(release)
+	f.fadesSet = true
+}
+
+// TruncateReleaseDur of the default release of a Fire whose sustain cuts off its source before the natural end
+const TruncateReleaseDur = 2 * time.Millisecond
+
+// FadeAt an offset in Tz from the beginning of the Fire (or of the current repeat), the gain from 0 to 1 of its attack and release,
+// called by the mix loop once per sample.
+func (f *Fire) FadeAt(at spec.Tz) float64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	choke := f.chokeGain()
+	if f.xfadeLoop || (f.fadesSet && f.attackTz == 0 && f.releaseTz == 0) {
+		return choke
+	}
+	at = f.sinceBegin(at)
+	window := f.sustainTz()
+	if f.IntervalTz > 0 && f.IntervalTz < window {
+		window = f.IntervalTz
+	}
+	attackTz, releaseTz := f.attackTz, f.releaseTz
+	if !f.fadesSet {
+		if f.EndTz == 0 || (window >= f.naturalLength() && !f.sustainLooping()) {
+			return choke
 		}
-	case fireStateDone:
-		// garbage collection
+		releaseTz = f.tzOf(TruncateReleaseDur)
 	}
-	return
+	gain := 1.0
+	if attackTz > 0 && at < attackTz {
+		gain = float64(at) / float64(attackTz)
+	}
+	if releaseTz > 0 && window-at < releaseTz {
+		gain = math.Min(gain, math.Max(0, float64(window-at)/float64(releaseTz)))
+	}
+	return gain * choke
+}
+
+// SetVolumeEnvelope to automate the volume (0 to 1) over the sustain of the Fire; nil to use the fixed Volume.
+func (f *Fire) SetVolumeEnvelope(points []EnvelopePoint) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(points) == 0 {
+		f.volumeEnvelope = nil
+		return
+	}
+	f.volumeEnvelope = NewEnvelope(points)
+}
+
+// SetPanEnvelope to automate the pan (-1 to +1) over the sustain of the Fire; nil to use the fixed Pan.
+func (f *Fire) SetPanEnvelope(points []EnvelopePoint) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(points) == 0 {
+		f.panEnvelope = nil
+		return
+	}
+	f.panEnvelope = NewEnvelope(points)
+}
+
+// VolumeAt an offset in Tz from the beginning of the Fire, called by the mix loop once per sample.
+func (f *Fire) VolumeAt(at spec.Tz) float64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.volumeEnvelope == nil {
+		f.nowVolume = f.rampToward(f.nowVolume, f.Volume)
+		return f.nowVolume
+	}
+	return f.volumeEnvelope.At(f.sinceBegin(at))
+}
+
+// PanAt an offset in Tz from the beginning of the Fire, called by the mix loop once per sample.
+func (f *Fire) PanAt(at spec.Tz) float64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.panEnvelope == nil {
+		f.nowPan = f.rampToward(f.nowPan, f.Pan)
+		return f.nowPan
+	}
+	return f.panEnvelope.At(f.sinceBegin(at))
 }
 
 // IsAlive the Fire?
 func (f *Fire) IsAlive() bool {
-	return f.state < fireStateDone
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.state < StateDone
 }
 
 // IsPlaying the Fire?
 func (f *Fire) IsPlaying() bool {
-	return f.state == fireStatePlay
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.state == StatePlay
+}
+
+// Reset the Fire to its ready state, e.g. when the mixer playhead is rewound.
+func (f *Fire) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.reset()
+}
+
+// Seek the Fire to a specific Tz of mix playback, e.g. when the mixer playhead jumps.
+func (f *Fire) Seek(at spec.Tz) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.reset()
+	if f.state == StateCancel {
+		return
+	}
+	f.atTz = at
+	if f.xfadeLoop {
+		if at > f.BeginTz {
+			f.state = StatePlay
+		}
+		return
+	}
+	if f.IntervalTz > 0 {
+		if f.Repeat >= 0 && at >= f.BeginTz+f.IntervalTz*spec.Tz(f.Repeat) {
+			f.finish(StateDone, DoneEnd)
+		} else if at > f.BeginTz {
+			f.state = StatePlay
+		}
+		return
+	}
+	if f.EndTz == 0 {
+		if length := f.naturalLength(); length > 0 {
+			f.EndTz = f.BeginTz + length
+		}
+	}
+	if f.EndTz != 0 && at >= f.EndTz {
+		f.finish(StateDone, f.endReason())
+	} else if at > f.BeginTz {
+		f.state = StatePlay
+		f.nowTz = at - f.BeginTz
+	}
 }
 
 // Teardown the Fire and release its memory
@@ -76,19 +485,379 @@ func (f *Fire) Teardown() {
 	// TODO: confirm that all memory of this object is released when its pointer is deleted from the *Mixer.fires slice, else make sure it does get released somehow
 }
 
+// Level of this Fire's contribution to the mix over the last mix cycle, safe to read from any goroutine.
+func (f *Fire) Level() level.Level {
+	return f.meter.Level()
+}
+
+// Meter of this Fire's contribution to the mix, which only the mix loop should add to.
+func (f *Fire) Meter() *level.Meter {
+	return &f.meter
+}
+
+// BeginAt the time since the start of mix playback that the Fire begins.
+func (f *Fire) BeginAt() time.Duration {
+	return f.durOf(f.BeginTz)
+}
+
+// Sustain of the Fire, or of each repeat if it loops; the natural length of its source, if it was set without a sustain.
+func (f *Fire) Sustain() time.Duration {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.durOf(f.sustainTz())
+}
+
+// Remaining time until the Fire finishes playing: all of it if it is ready, none if it is done,
+// or -1 if it loops until canceled.
+func (f *Fire) Remaining() time.Duration {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch f.state {
+	case StateReady:
+		if f.loopsForever() {
+			return -1
+		} else if f.IntervalTz > 0 {
+			return f.durOf(f.IntervalTz * spec.Tz(f.Repeat))
+		}
+		return f.durOf(f.sustainTz())
+	case StatePlay:
+		if f.loopsForever() {
+			return -1
+		}
+		endTz := f.BeginTz + f.sustainTz()
+		if f.IntervalTz > 0 {
+			endTz = f.BeginTz + f.IntervalTz*spec.Tz(f.Repeat)
+		}
+		if endTz <= f.atTz {
+			return 0
+		}
+		return f.durOf(endTz - f.atTz)
+	default:
+		return 0
+	}
+}
+
+// End of the Fire, in Tz of mix playback: its begin plus its sustain, or plus all of its repeats if it loops. It is not known
+// if the Fire loops until canceled, nor if its source has no length yet, e.g. a multi-sample source until a variant is resolved.
+func (f *Fire) End() (endTz spec.Tz, known bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.end(f.resolved())
+}
+
+// EndAs a variant of its source, see End, e.g. to know how long a multi-sample source may play before a variant is resolved.
+func (f *Fire) EndAs(variant string) (endTz spec.Tz, known bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.end(variant)
+}
+
+// EffectiveSustain of the Fire, or of each repeat if it loops: its sustain, or if it was set with a sustain of 0, the natural length
+// of its source (or its region) at its rate, i.e. it plays the full source. Returns an error if that is not yet known, because the
+// source is not loaded, or it is a multi-sample source whose variant is not yet resolved.
+func (f *Fire) EffectiveSustain() (time.Duration, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	sustainTz := f.sustainTz()
+	if sustainTz <= 0 {
+		return 0, fmt.Errorf("Length of source %s is not known until it is loaded", f.resolved())
+	}
+	return f.durOf(sustainTz), nil
+}
+
+// State of the Fire
+func (f *Fire) State() StateEnum {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.state
+}
+
+// StateEnum of a Fire, from ready, to playing, to done (or canceled)
+type StateEnum uint
+
+const (
+	StateReady StateEnum = 1
+	StatePlay  StateEnum = 2
+	// it is assumed that all alive states are < StateDone
+	StateDone   StateEnum = 6
+	StateCancel StateEnum = 7
+)
+
 //
 // Private
 //
 
-type fireStateEnum uint
+var masterFreqBits uint64 // accessed atomically
 
-const (
-	fireStateReady fireStateEnum = 1
-	fireStatePlay  fireStateEnum = 2
-	// it is assumed that all alive states are < SOURCE_FINISHED
-	fireStateDone fireStateEnum = 6
-)
+// freq of the master, of the Fire if it is configured, else of the package
+func (f *Fire) freq() float64 {
+	if f.masterFreq > 0 {
+		return f.masterFreq
+	}
+	return math.Float64frombits(atomic.LoadUint64(&masterFreqBits))
+}
 
-func (f *Fire) sourceLength() spec.Tz {
-	return source.GetLength(f.Source)
+// durOf a # of Tz of mix playback, in time of the mix timeline, to the nearest nanosecond
+func (f *Fire) durOf(tz spec.Tz) time.Duration {
+	return durAt(f.freq()/f.timeScale, tz)
+}
+
+// tzOf a duration of the mix timeline, in Tz of mix playback
+func (f *Fire) tzOf(d time.Duration) spec.Tz {
+	return tzAt(f.freq()/f.timeScale, d)
+}
+
+// sourceDurOf a # of Tz of the source, at the master frequency, to the nearest nanosecond
+func (f *Fire) sourceDurOf(tz spec.Tz) time.Duration {
+	return durAt(f.freq(), tz)
+}
+
+// sourceTzOf a duration of the source, in Tz at the master frequency
+func (f *Fire) sourceTzOf(d time.Duration) spec.Tz {
+	return tzAt(f.freq(), d)
+}
+
+// rampToward a target by one sample's worth of SmoothDur, or straight to it if the master frequency is not configured
+func (f *Fire) rampToward(from float64, to float64) float64 {
+	freq := f.freq()
+	if freq <= 0 {
+		return to
+	}
+	step := 1 / (freq * SmoothDur.Seconds())
+	if from < to {
+		return math.Min(to, from+step)
+	}
+	return math.Max(to, from-step)
+}
+
+// durAt a frequency, of a # of Tz, to the nearest nanosecond
+func durAt(freq float64, tz spec.Tz) time.Duration {
+	if freq <= 0 {
+		return 0
+	}
+	return time.Duration(math.Round(float64(tz) * float64(time.Second) / freq))
+}
+
+// tzAt a frequency, of a duration
+func tzAt(freq float64, d time.Duration) spec.Tz {
+	return spec.Tz(math.Round(d.Seconds() * freq))
+}
+
+// sustainTz of the Fire, or of each repeat if it loops
+func (f *Fire) sustainTz() spec.Tz {
+	return f.sustainTzAs(f.resolved())
+}
+
+// sustainTzAs a variant of its source, see sustainTz
+func (f *Fire) sustainTzAs(src string) spec.Tz {
+	if f.EndTz != 0 {
+		return f.EndTz - f.BeginTz
+	}
+	return f.naturalLengthOf(src)
+}
+
+// end of the Fire as a variant of its source, see End; the caller must hold the mutex
+func (f *Fire) end(src string) (endTz spec.Tz, known bool) {
+	if f.xfadeLoop {
+		return 0, false
+	}
+	if f.IntervalTz > 0 {
+		if f.Repeat < 0 {
+			return 0, false
+		}
+		return f.BeginTz + f.IntervalTz*spec.Tz(f.Repeat), true
+	}
+	sustainTz := f.sustainTzAs(src)
+	if sustainTz <= 0 {
+		return 0, false
+	}
+	return f.BeginTz + sustainTz, true
+}
+
+// playAt a Tz of mix playback, see PlayAt; the caller must hold the mutex
+func (f *Fire) playAt(at spec.Tz) (t spec.Tz, playing bool) {
+	//	debug.Printf("*Fire[%s].At(%v vs %v)\n", f.Source, at, f.BeginTz)
+	f.atTz = at
+	if f.state == StatePlay && f.choked && at-f.chokeTz >= f.chokeFadeTz {
+		f.finish(StateDone, DoneChoked)
+		debug.Debugf("fire(%s) choked at %dz", f.Source, at)
+		return
+	}
+	switch f.state {
+	case StateReady:
+		if at >= f.BeginTz {
+			// a fire that begins late, e.g. triggered live, starts immediately, skipping the samples it missed
+			f.state = StatePlay
+			if f.xfadeLoop {
+				return f.xfadeLoopAt(at)
+			}
+			t = f.sustainLoopTz(at - f.BeginTz)
+			f.nowTz = t + 1
+			playing = true
+			debug.Debugf("fire(%s) play at %dz", f.Source, at)
+		}
+	case StatePlay:
+		if f.xfadeLoop {
+			return f.xfadeLoopAt(at)
+		}
+		if f.IntervalTz > 0 {
+			return f.loopAt(at)
+		}
+		t = f.sustainLoopTz(f.nowTz)
+		f.nowTz = t + 1
+		playing = true
+		if f.EndTz != 0 {
+			if at >= f.EndTz {
+				f.finish(StateDone, f.endReason())
+				debug.Debugf("fire(%s) done at %dz", f.Source, at)
+			}
+		} else if length := f.naturalLength(); length > 0 {
+			f.EndTz = f.BeginTz + length
+		} else {
+			f.finish(StateDone, DoneEnd) // nothing to play, e.g. a region beyond the end of the source
+			playing = false
+			debug.Debugf("fire(%s) done at %dz, with nothing to play", f.Source, at)
+		}
+	case StateDone, StateCancel:
+		// garbage collection
+	}
+	return
+}
+
+// chokeGain from 1 to 0 over the fade of a choke, as of the last Tz of mix playback, or 1 if the Fire is not choked
+func (f *Fire) chokeGain() float64 {
+	if !f.choked {
+		return 1
+	}
+	return math.Max(0, 1-float64(f.atTz-f.chokeTz)/float64(f.chokeFadeTz))
+}
+
+func (f *Fire) reset() {
+	if f.state == StateCancel {
+		return
+	}
+	f.choked = false
+	f.doneReason = NotDone // such that a hook registered after a reset waits for the Fire to finish again
+	f.nowTz = 0
+	f.atTz = 0
+	f.xfadeCrossing = false
+	f.state = StateReady
+}
+
+func (f *Fire) resolved() string {
+	if f.variant != "" {
+		return f.variant
+	}
+	return f.Source
+}
+
+// loopAt computes the Tz within the current repeat of a looping Fire, from the Tz of mix playback
+func (f *Fire) loopAt(at spec.Tz) (t spec.Tz, playing bool) {
+	elapsed := at - f.BeginTz
+	if f.Repeat >= 0 && elapsed/f.IntervalTz >= spec.Tz(f.Repeat) {
+		f.finish(StateDone, DoneEnd)
+		debug.Debugf("fire(%s) done at %dz, after %d repeats", f.Source, at, f.Repeat)
+		return
+	}
+	t = elapsed % f.IntervalTz
+	length := f.naturalLength()
+	if f.EndTz != 0 {
+		length = f.EndTz - f.BeginTz
+	}
+	if t >= length {
+		return 0, false // silent until the next repeat
+	}
+	return t, true
+}
+
+// xfadeLoopAt computes the Tz within the current iteration of a crossfade loop, from the Tz of mix playback, and while the tail of
+// the previous iteration overlaps it, the Tz within that one too, see LoopXFade. Each iteration begins a crossfade before the end
+// of the one before it.
+func (f *Fire) xfadeLoopAt(at spec.Tz) (t spec.Tz, playing bool) {
+	length, xfadeTz := f.xfadeLength()
+	if length == 0 {
+		f.finish(StateDone, DoneEnd) // nothing to play, e.g. the source is not loaded
+		debug.Debugf("fire(%s) done at %dz, with nothing to loop", f.Source, at)
+		return
+	}
+	period := length - xfadeTz
+	elapsed := at - f.BeginTz
+	t = elapsed % period
+	f.xfadeHeadTz = t
+	f.xfadeCrossing = elapsed >= period && t < xfadeTz
+	if f.xfadeCrossing {
+		f.xfadeTailTz = t + period
+	}
+	return t, true
+}
+
+// xfadeLength of each iteration of a crossfade loop, the natural length of the source, and its crossfade, at most half of that
+func (f *Fire) xfadeLength() (length spec.Tz, xfadeTz spec.Tz) {
+	length = f.naturalLength()
+	xfadeTz = f.XFadeTz
+	if xfadeTz > length/2 {
+		xfadeTz = length / 2
+	}
+	return
+}
+
+// loopsForever is true if the Fire loops until canceled, by interval or crossfade
+func (f *Fire) loopsForever() bool {
+	return f.xfadeLoop || (f.IntervalTz > 0 && f.Repeat < 0)
+}
+
+// endReason of a Fire that reached its EndTz: its sustain expired, unless the EndTz is its natural end
+func (f *Fire) endReason() DoneReason {
+	if f.EndTz-f.BeginTz == f.naturalLength() {
+		return DoneEnd
+	}
+	return DoneSustain
+}
+
+// sustainLooping is true if the Fire wraps around its sustain loop while it plays
+func (f *Fire) sustainLooping() bool {
+	return f.EndTz != 0 && f.IntervalTz == 0 && f.SustainLoopEndTz > f.SustainLoopBeginTz && f.SustainLoopBeginTz >= f.OffsetTz
+}
+
+// sustainLoopTz of playback, wrapped back into the sustain loop once it passes the end, else as is
+func (f *Fire) sustainLoopTz(t spec.Tz) spec.Tz {
+	if !f.sustainLooping() {
+		return t
+	}
+	endTz := spec.Tz(float64(f.SustainLoopEndTz-f.OffsetTz) / f.PlayRate())
+	lengthTz := spec.Tz(float64(f.SustainLoopEndTz-f.SustainLoopBeginTz) / f.PlayRate())
+	if t < endTz || lengthTz == 0 {
+		return t
+	}
+	return endTz - lengthTz + (t-endTz)%lengthTz
+}
+
+// sinceBegin of the Fire, of a Tz of its playback, for its fades and envelopes: as is, unless it wraps around a sustain loop
+func (f *Fire) sinceBegin(t spec.Tz) spec.Tz {
+	if f.sustainLooping() && f.atTz >= f.BeginTz {
+		return f.atTz - f.BeginTz
+	}
+	return t
+}
+
+// naturalLength is the length of the source (or its region) in Tz of mix playback, at the play rate of this Fire
+func (f *Fire) naturalLength() spec.Tz {
+	return f.naturalLengthOf(f.resolved())
+}
+
+// naturalLengthOf a variant of its source, see naturalLength
+func (f *Fire) naturalLengthOf(src string) spec.Tz {
+	cache := f.cache
+	if cache == nil {
+		cache = source.DefaultCache()
+	}
+	length := cache.GetLength(src)
+	if f.OffsetTz >= length {
+		return 0
+	}
+	length -= f.OffsetTz
+	if f.LengthTz > 0 && f.LengthTz < length {
+		length = f.LengthTz
+	}
+	return spec.Tz(float64(length) / f.PlayRate())
 }
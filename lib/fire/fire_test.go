@@ -2,11 +2,14 @@
 package fire
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
 )
 
 func TestBase(t *testing.T) {
@@ -20,11 +23,11 @@ func TestBase(t *testing.T) {
 	// before start:
 	assert.Equal(t, spec.Tz(0), fire.At(bgnTz-2))
 	assert.Equal(t, spec.Tz(0), fire.At(bgnTz-1))
-	assert.Equal(t, fireStateReady, fire.state)
+	assert.Equal(t, StateReady, fire.state)
 	assert.Equal(t, true, fire.IsAlive())
 	// start:
 	assert.Equal(t, spec.Tz(0), fire.At(bgnTz))
-	assert.Equal(t, fireStatePlay, fire.state)
+	assert.Equal(t, StatePlay, fire.state)
 	assert.Equal(t, true, fire.IsAlive())
 	// after start / before end:
 	for n := spec.Tz(1); n < testLengthTz; n++ {
@@ -32,18 +35,211 @@ func TestBase(t *testing.T) {
 	}
 	// end:
 	assert.Equal(t, testLengthTz, fire.At(endTz))
-	assert.Equal(t, fireStateDone, fire.state)
+	assert.Equal(t, StateDone, fire.state)
 	assert.Equal(t, false, fire.IsAlive())
 	// after end:
 	assert.Equal(t, spec.Tz(0), fire.At(endTz+1))
 }
 
+func TestIntrospection(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	f := New("sound.wav", 500, 750, 0.5, -0.5)
+	assert.Equal(t, 500*time.Millisecond, f.BeginAt())
+	assert.Equal(t, 250*time.Millisecond, f.Sustain())
+	assert.Equal(t, StateReady, f.State())
+	assert.Equal(t, 250*time.Millisecond, f.Remaining())
+	f.At(500)
+	f.At(600)
+	assert.Equal(t, StatePlay, f.State())
+	assert.Equal(t, 150*time.Millisecond, f.Remaining())
+	f.At(750)
+	assert.Equal(t, StateDone, f.State())
+	assert.Equal(t, time.Duration(0), f.Remaining())
+}
+
+func TestConfigure_Fire(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	f := New("sound.wav", 500, 750, 0.5, -0.5)
+	f.Configure(spec.AudioSpec{Freq: 2000, Format: spec.AudioF32, Channels: 1})
+	assert.Equal(t, 250*time.Millisecond, f.BeginAt())
+	assert.Equal(t, 125*time.Millisecond, f.Sustain())
+	assert.Equal(t, 500*time.Millisecond, New("sound.wav", 500, 750, 0.5, -0.5).BeginAt())
+}
+
+func TestRemaining_Loop(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	f := New("sound.wav", 0, 50, 1, 0)
+	f.SetLoop(100, 4)
+	assert.Equal(t, 400*time.Millisecond, f.Remaining())
+	assert.Equal(t, 50*time.Millisecond, f.Sustain())
+	f.At(0)
+	f.At(150)
+	assert.Equal(t, 250*time.Millisecond, f.Remaining())
+	f.Seek(380)
+	assert.Equal(t, 20*time.Millisecond, f.Remaining())
+	forever := New("sound.wav", 0, 50, 1, 0)
+	forever.SetLoop(100, -1)
+	assert.Equal(t, time.Duration(-1), forever.Remaining())
+}
+
+func TestEnd(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	endTz, known := New("sound.wav", 20, 70, 1, 0).End()
+	assert.True(t, known)
+	assert.Equal(t, spec.Tz(70), endTz)
+	loop := New("sound.wav", 20, 70, 1, 0)
+	loop.SetLoop(100, 4)
+	endTz, known = loop.End()
+	assert.True(t, known)
+	assert.Equal(t, spec.Tz(420), endTz)
+	loop.SetLoop(100, -1)
+	_, known = loop.End()
+	assert.False(t, known)
+	_, known = New("not-loaded.wav", 20, 0, 1, 0).End()
+	assert.False(t, known)
+}
+
+func TestSetEnd(t *testing.T) {
+	f := New("sound.wav", 20, 70, 1, 0)
+	f.SetEnd(90)
+	assert.Equal(t, spec.Tz(90), f.EndTz)
+	f.SetEnd(10) // before it begins
+	assert.Equal(t, spec.Tz(90), f.EndTz)
+	natural := New("sound.wav", 20, 0, 1, 0)
+	natural.SetEnd(90)
+	assert.Equal(t, spec.Tz(0), natural.EndTz)
+}
+
+func TestEffectiveSustain(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	sustain, err := New("sound.wav", 20, 70, 1, 0).EffectiveSustain()
+	assert.Nil(t, err)
+	assert.Equal(t, 50*time.Millisecond, sustain)
+	_, err = New("not-loaded.wav", 20, 0, 1, 0).EffectiveSustain()
+	assert.NotNil(t, err)
+}
+
+func TestSetCache(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	cache := source.NewCache()
+	cache.Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	defer cache.Teardown()
+	src := source.ToneKey(source.WaveSine, 100, 250*time.Millisecond) // only in this cache
+	assert.Nil(t, cache.Prepare(src))
+	f := New(src, 20, 0, 1, 0)
+	_, err := f.EffectiveSustain()
+	assert.NotNil(t, err) // not in the default cache
+	f.SetCache(cache)
+	sustain, err := f.EffectiveSustain()
+	assert.Nil(t, err)
+	assert.Equal(t, 250*time.Millisecond, sustain)
+}
+
+func TestSetVolume(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	f := New("sound.wav", 0, 100, 1, 0)
+	f.SetVolume(0.5)
+	assert.Equal(t, 0.5, f.VolumeAt(0)) // immediately, before playing
+	f.SetVolume(2)
+	assert.Equal(t, float64(1), f.Volume)
+	f.At(0)
+	f.SetVolume(0)
+	assert.Equal(t, 0.8, f.VolumeAt(1)) // ramps over 5 samples at 1000Hz, while playing
+	assert.InDelta(t, 0.6, f.VolumeAt(2), 1e-9)
+	for n := spec.Tz(3); n < 6; n++ {
+		f.VolumeAt(n)
+	}
+	assert.Equal(t, float64(0), f.VolumeAt(6))
+}
+
+func TestSetPan(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	f := New("sound.wav", 0, 100, 1, 0)
+	f.SetPan(-3)
+	assert.Equal(t, float64(-1), f.PanAt(0))
+	f.At(0)
+	f.SetPan(1)
+	assert.Equal(t, -0.8, f.PanAt(1))
+	for n := spec.Tz(2); n < 11; n++ {
+		f.PanAt(n)
+	}
+	assert.Equal(t, float64(1), f.PanAt(11))
+}
+
+func TestSetFades(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	f := New("sound.wav", 0, 100, 1, 0)
+	f.SetFades(10*time.Millisecond, 20*time.Millisecond)
+	assert.Equal(t, float64(0), f.FadeAt(0))
+	assert.Equal(t, 0.5, f.FadeAt(5))
+	assert.Equal(t, float64(1), f.FadeAt(50))
+	assert.Equal(t, 0.5, f.FadeAt(90))
+	assert.Equal(t, float64(0), f.FadeAt(100))
+	f.SetFades(0, 0)
+	assert.Equal(t, float64(1), f.FadeAt(0))
+	assert.Equal(t, float64(1), f.FadeAt(99))
+}
+
+func TestSetFades_Loop(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1})
+	f := New("sound.wav", 0, 100, 1, 0)
+	f.SetLoop(40, -1)
+	f.SetFades(0, 10*time.Millisecond)
+	assert.Equal(t, float64(1), f.FadeAt(20))
+	assert.Equal(t, 0.5, f.FadeAt(35)) // released before each repeat cuts off the last
+}
+
 func TestNewFire(t *testing.T) {
 	// TODO
 }
 
 func TestAt(t *testing.T) {
-	// TODO
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	assert.Equal(t, spec.Tz(0), fire.At(999))
+	assert.Equal(t, StateReady, fire.state)
+	assert.Equal(t, spec.Tz(0), fire.At(1000))
+	assert.Equal(t, StatePlay, fire.state)
+	assert.Equal(t, spec.Tz(1), fire.At(1001))
+}
+
+func TestPlayAt(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(1002), 1, 0)
+	at, playing := fire.PlayAt(999)
+	assert.False(t, playing)
+	at, playing = fire.PlayAt(1000)
+	assert.Equal(t, spec.Tz(0), at) // the first sample of the source
+	assert.True(t, playing)
+	at, playing = fire.PlayAt(1001)
+	assert.Equal(t, spec.Tz(1), at)
+	assert.True(t, playing)
+	_, playing = fire.PlayAt(1002)
+	assert.True(t, playing)
+	assert.Equal(t, StateDone, fire.state)
+	_, playing = fire.PlayAt(1003)
+	assert.False(t, playing)
+}
+
+func TestAt_Late(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	// first played 300 samples late, it starts immediately, skipping the missed samples
+	assert.Equal(t, spec.Tz(300), fire.At(1300))
+	assert.Equal(t, StatePlay, fire.state)
+	assert.Equal(t, spec.Tz(301), fire.At(1301))
+}
+
+func TestAdvance(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(1002), 1, 0)
+	_, _, transition := fire.Advance(999)
+	assert.Equal(t, StateEnum(0), transition)
+	_, playing, transition := fire.Advance(1000)
+	assert.True(t, playing)
+	assert.Equal(t, StatePlay, transition)
+	_, _, transition = fire.Advance(1001)
+	assert.Equal(t, StateEnum(0), transition)
+	_, _, transition = fire.Advance(1002)
+	assert.Equal(t, StateDone, transition)
+	_, _, transition = fire.Advance(1003)
+	assert.Equal(t, StateEnum(0), transition)
 }
 
 func TestState(t *testing.T) {
@@ -58,6 +254,193 @@ func TestIsPlaying(t *testing.T) {
 	// TODO
 }
 
+func TestSeek(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	// seek into the middle:
+	fire.Seek(1500)
+	assert.Equal(t, StatePlay, fire.state)
+	assert.Equal(t, spec.Tz(500), fire.At(1500))
+	assert.Equal(t, spec.Tz(501), fire.At(1501))
+	// seek past the end:
+	fire.Seek(2000)
+	assert.Equal(t, false, fire.IsAlive())
+	// seek backwards before the beginning:
+	fire.Seek(10)
+	assert.Equal(t, StateReady, fire.state)
+	assert.Equal(t, spec.Tz(0), fire.At(10))
+}
+
+func TestResolve(t *testing.T) {
+	f := New("kick", 0, 0, 1, 0)
+	assert.Equal(t, "kick", f.Resolved())
+	f.Resolve("kick_hard.wav")
+	assert.Equal(t, "kick_hard.wav", f.Resolved())
+	assert.Equal(t, "kick", f.Source)
+}
+
+func TestSetRate(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	assert.Equal(t, float64(1), fire.Rate)
+	fire.SetRate(0.5)
+	assert.Equal(t, float64(0.5), fire.Rate)
+	fire.SetRate(0)
+	assert.Equal(t, float64(1), fire.Rate)
+}
+
+func TestSetSustainLoop(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(5000), 1, 0)
+	fire.SetSustainLoop(100, 200)
+	for at := spec.Tz(1000); at < 1200; at++ {
+		assert.Equal(t, at-1000, fire.At(at))
+	}
+	assert.Equal(t, spec.Tz(100), fire.At(1200)) // wraps from the end of the loop back to its begin
+	assert.Equal(t, spec.Tz(101), fire.At(1201))
+	// begun late, well past the end of the loop
+	fire = New("sound.wav", spec.Tz(1000), spec.Tz(5000), 1, 0)
+	fire.SetSustainLoop(100, 200)
+	assert.Equal(t, spec.Tz(150), fire.At(1450))
+	// ignored without a sustain
+	fire = New("sound.wav", spec.Tz(1000), 0, 1, 0)
+	fire.SetSustainLoop(100, 200)
+	assert.Equal(t, spec.Tz(450), fire.At(1450))
+}
+
+func TestSetLoop(t *testing.T) {
+	bgnTz := spec.Tz(1000)
+	fire := New("sound.wav", bgnTz, bgnTz+50, 1, 0)
+	fire.SetLoop(100, 3)
+	assert.Equal(t, spec.Tz(0), fire.At(bgnTz))
+	assert.Equal(t, spec.Tz(10), fire.At(bgnTz+10))
+	// between repeats, after sustain:
+	assert.Equal(t, spec.Tz(0), fire.At(bgnTz+60))
+	// second and third repeat:
+	assert.Equal(t, spec.Tz(10), fire.At(bgnTz+110))
+	assert.Equal(t, spec.Tz(49), fire.At(bgnTz+249))
+	assert.Equal(t, true, fire.IsAlive())
+	// after the last repeat:
+	assert.Equal(t, spec.Tz(0), fire.At(bgnTz+300))
+	assert.Equal(t, false, fire.IsAlive())
+	// seek back into the second repeat:
+	fire.Seek(bgnTz + 120)
+	assert.Equal(t, spec.Tz(20), fire.At(bgnTz+120))
+}
+
+func TestSetLoopXFade(t *testing.T) {
+	s := spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1}
+	Configure(s)
+	source.DefaultCache().Configure(s)
+	src := source.ToneKey(source.WaveSine, 100, 100*time.Millisecond) // 100 Tz
+	assert.Nil(t, source.Prepare(src))
+	defer source.Evict(src)
+	fire := New(src, 1000, 0, 1, 0)
+	fire.SetLoopXFade(20)
+	assert.True(t, fire.LoopsForever())
+	assert.Equal(t, time.Duration(-1), fire.Remaining())
+	_, known := fire.End()
+	assert.False(t, known)
+	assert.Equal(t, spec.Tz(79), fire.At(1079))
+	_, _, _, ok := fire.LoopXFade()
+	assert.False(t, ok)
+	assert.Equal(t, spec.Tz(5), fire.At(1085)) // the next iteration begins 20 Tz before the end of this one
+	tailTz, headGain, tailGain, ok := fire.LoopXFade()
+	assert.True(t, ok)
+	assert.Equal(t, spec.Tz(85), tailTz)
+	assert.InDelta(t, 1, headGain*headGain+tailGain*tailGain, 1e-9) // equal-power
+	assert.InDelta(t, math.Sin(math.Pi/8), headGain, 1e-9)
+	assert.Equal(t, spec.Tz(20), fire.At(1100))
+	_, _, _, ok = fire.LoopXFade()
+	assert.False(t, ok)
+	assert.Equal(t, spec.Tz(10), fire.At(1000+80*1000+10)) // until canceled
+	assert.True(t, fire.IsAlive())
+	fire.Cancel()
+	assert.False(t, fire.IsAlive())
+	// a crossfade longer than half the source is clamped
+	fire = New(src, 0, 0, 1, 0)
+	fire.SetLoopXFade(80)
+	assert.Equal(t, spec.Tz(0), fire.At(50))
+	tailTz, _, _, ok = fire.LoopXFade()
+	assert.True(t, ok)
+	assert.Equal(t, spec.Tz(50), tailTz)
+}
+
+func TestCancel(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	fire.SetLoop(100, -1)
+	fire.Seek(1500)
+	assert.Equal(t, true, fire.IsAlive())
+	fire.Cancel()
+	assert.Equal(t, false, fire.IsAlive())
+	assert.Equal(t, true, fire.IsCanceled())
+	fire.Seek(0)
+	assert.Equal(t, false, fire.IsAlive())
+}
+
+func TestSetChoke(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	assert.Equal(t, "", fire.ChokeGroup())
+	fire.SetChoke("hat")
+	assert.Equal(t, "hat", fire.ChokeGroup())
+}
+
+func TestChoke(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	fire.SetFades(0, 0)
+	fire.Choke(1000, 10) // not yet playing
+	fire.PlayAt(1000)
+	assert.Equal(t, float64(1), fire.FadeAt(0))
+	fire.Choke(1010, 10)
+	fire.Choke(1012, 100) // the first choke is kept
+	fire.PlayAt(1010)
+	assert.Equal(t, float64(1), fire.FadeAt(10))
+	fire.PlayAt(1015)
+	assert.Equal(t, 0.5, fire.FadeAt(15))
+	_, playing := fire.PlayAt(1019)
+	assert.True(t, playing)
+	_, playing, transition := fire.Advance(1020)
+	assert.False(t, playing)
+	assert.Equal(t, StateDone, transition)
+	assert.Equal(t, DoneChoked, fire.DoneReason())
+}
+
+func TestChoke_Seek(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0)
+	fire.SetFades(0, 0)
+	fire.PlayAt(1000)
+	fire.Choke(1000, 10)
+	fire.Seek(1500)
+	_, playing := fire.PlayAt(1500)
+	assert.True(t, playing)
+	assert.Equal(t, float64(1), fire.FadeAt(500))
+}
+
+func TestScaleVolume(t *testing.T) {
+	f := New("sound.wav", 0, 100, 0.8, 0)
+	f.ScaleVolume(0.5)
+	assert.Equal(t, 0.4, f.Volume)
+	assert.Equal(t, 0.4, f.VolumeAt(0))
+	f.ScaleVolume(4)
+	assert.Equal(t, float64(1), f.Volume)
+	f.ScaleVolume(-1)
+	assert.Equal(t, float64(0), f.Volume)
+}
+
+func TestSetVolumeEnvelope(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 0.8, 0)
+	assert.Equal(t, float64(0.8), fire.VolumeAt(500))
+	fire.SetVolumeEnvelope([]EnvelopePoint{{0, 0}, {1000, 1}})
+	assert.Equal(t, float64(0.5), fire.VolumeAt(500))
+	fire.SetVolumeEnvelope(nil)
+	assert.Equal(t, float64(0.8), fire.VolumeAt(500))
+}
+
+func TestSetPanEnvelope(t *testing.T) {
+	fire := New("sound.wav", spec.Tz(1000), spec.Tz(2000), 1, 0.2)
+	assert.Equal(t, float64(0.2), fire.PanAt(500))
+	fire.SetPanEnvelope([]EnvelopePoint{{0, -1}, {1000, 1}})
+	assert.Equal(t, float64(0), fire.PanAt(500))
+	assert.Equal(t, float64(1), fire.PanAt(1500))
+}
+
 func TestSetState(t *testing.T) {
 	// TODO
 }
@@ -0,0 +1,72 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// HumanizeSpec of the random variation of each hit, e.g. of a drum pattern, so that it does not sound like a machine; each jitter
+// is the most it varies either way, drawn uniformly, and zero for none.
+type HumanizeSpec struct {
+	VolumeJitter float64       `json:"volumeJitter,omitempty"` // of the volume, up or down, e.g. 0.1; the volume is clamped to 0 to 1
+	PanJitter    float64       `json:"panJitter,omitempty"`    // of the pan, left or right; the pan is clamped to -1 to +1
+	TimingJitter time.Duration `json:"timingJitter,omitempty"` // of the begin, earlier or later, e.g. 10ms; it never begins before zero
+}
+
+// IsZero is true if the humanize varies nothing
+func (h HumanizeSpec) IsZero() bool {
+	return h.VolumeJitter == 0 && h.PanJitter == 0 && h.TimingJitter == 0
+}
+
+// SetHumanize of the Fire, instead of that of its mixer, e.g. a zero HumanizeSpec to play one hit exactly as set; it must be set
+// before the Fire goes live, shortly before it begins.
+func (f *Fire) SetHumanize(h HumanizeSpec) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.humanize = &h
+}
+
+// Humanize of the Fire, and ok if it was set, else it varies by that of its mixer
+func (f *Fire) Humanize() (h HumanizeSpec, ok bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.humanize == nil {
+		return HumanizeSpec{}, false
+	}
+	return *f.humanize, true
+}
+
+// ApplyHumanize of the Fire, if it was set, else of the mixer, varying its volume, pan and begin (and its end, by as much) by
+// jitters drawn from a random source, but never to begin before a Tz, or zero, unless it already began before it. Called by the
+// mixer as the Fire goes live; only the first call varies it, e.g. it replays as it was after a seek back.
+func (f *Fire) ApplyHumanize(h HumanizeSpec, random *rand.Rand, minTz spec.Tz) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.humanized {
+		return
+	}
+	f.humanized = true
+	if f.humanize != nil {
+		h = *f.humanize
+	}
+	if h.IsZero() {
+		return
+	}
+	volume, pan, timing := 2*random.Float64()-1, 2*random.Float64()-1, 2*random.Float64()-1 // always all three, so the draws are reproducible
+	f.Volume = math.Max(0, math.Min(1, f.Volume+volume*h.VolumeJitter))
+	f.nowVolume = f.Volume
+	f.Pan = math.Max(-1, math.Min(1, f.Pan+pan*h.PanJitter))
+	f.nowPan = f.Pan
+	if minTz > f.BeginTz {
+		minTz = f.BeginTz
+	}
+	beginTz := spec.Tz(math.Max(float64(minTz), float64(f.BeginTz)+math.Round(timing*float64(f.tzOf(h.TimingJitter)))))
+	if f.EndTz != 0 {
+		f.EndTz = f.EndTz - f.BeginTz + beginTz
+	}
+	f.BeginTz = beginTz
+}
@@ -0,0 +1,55 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestApplyHumanize(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	h := HumanizeSpec{VolumeJitter: 0.5, PanJitter: 0.5, TimingJitter: 10 * time.Millisecond}
+	for n := 0; n < 100; n++ {
+		f := New("sound.wav", spec.Tz(100), spec.Tz(1100), 1, -1)
+		f.Configure(spec.AudioSpec{Freq: 44100})
+		f.ApplyHumanize(h, random, 0)
+		assert.True(t, f.Volume >= 0.5 && f.Volume <= 1)
+		assert.True(t, f.Pan >= -1 && f.Pan <= -0.5)
+		assert.True(t, f.BeginTz >= 0 && f.BeginTz <= 100+441)
+		assert.Equal(t, spec.Tz(1000), f.EndTz-f.BeginTz)
+	}
+}
+
+func TestApplyHumanize_Once(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	f := New("sound.wav", spec.Tz(44100), 0, 0.5, 0)
+	f.Configure(spec.AudioSpec{Freq: 44100})
+	f.ApplyHumanize(HumanizeSpec{VolumeJitter: 0.2, TimingJitter: time.Second}, random, 44000)
+	volume, begin := f.Volume, f.BeginTz
+	assert.NotEqual(t, 0.5, volume)
+	assert.True(t, begin >= 44000)
+	assert.Equal(t, spec.Tz(0), f.EndTz)
+	f.ApplyHumanize(HumanizeSpec{VolumeJitter: 0.2, TimingJitter: time.Second}, random, 0)
+	assert.Equal(t, volume, f.Volume)
+	assert.Equal(t, begin, f.BeginTz)
+}
+
+func TestSetHumanize(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	f := New("sound.wav", spec.Tz(100), 0, 0.5, 0)
+	_, ok := f.Humanize()
+	assert.False(t, ok)
+	f.SetHumanize(HumanizeSpec{}) // exactly as set, whatever the mixer
+	h, ok := f.Humanize()
+	assert.True(t, ok)
+	assert.True(t, h.IsZero())
+	f.ApplyHumanize(HumanizeSpec{VolumeJitter: 0.5, PanJitter: 0.5}, random, 0)
+	assert.Equal(t, 0.5, f.Volume)
+	assert.Equal(t, 0.0, f.Pan)
+	assert.Equal(t, spec.Tz(100), f.BeginTz)
+}
@@ -0,0 +1,181 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"sort"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// NewQueue of Fires, empty.
+func NewQueue() *Queue {
+	return &Queue{sources: make(map[string]int)}
+}
+
+// Queue of Fires in order of their beginning, a min-heap by BeginTz, such that the mixer can schedule tens of thousands
+// of Fires and only touch the few that begin soon. Fires that begin at the same Tz keep the order they were pushed.
+// A Queue is not safe for concurrent use; the mixer guards it. Once it has grown, it makes no allocations to push or pop.
+type Queue struct {
+	items   queueHeap
+	sources map[string]int // # of queued Fires of each source
+	seq     uint64
+}
+
+// Push a Fire onto the Queue, in O(log n)
+func (q *Queue) Push(f *Fire) {
+	q.seq++
+	q.items = append(q.items, queueItem{f, q.seq})
+	q.items.up(len(q.items) - 1)
+	q.sources[f.Source]++
+}
+
+// Peek at the Fire that begins soonest, or nil if the Queue is empty
+func (q *Queue) Peek() *Fire {
+	if len(q.items) == 0 {
+		return nil
+	}
+	return q.items[0].fire
+}
+
+// Pop the Fire that begins soonest, in O(log n), or nil if the Queue is empty
+func (q *Queue) Pop() *Fire {
+	if len(q.items) == 0 {
+		return nil
+	}
+	f := q.items[0].fire
+	last := len(q.items) - 1
+	q.items[0] = q.items[last]
+	q.items[last] = queueItem{} // release the Fire to the garbage collector
+	q.items = q.items[:last]
+	q.items.down(0)
+	q.forget(f)
+	return f
+}
+
+// PopBefore a Tz, all the Fires that begin before it, in order of their beginning
+func (q *Queue) PopBefore(tz spec.Tz) (fires []*Fire) {
+	for len(q.items) > 0 && q.items[0].fire.BeginTz < tz {
+		fires = append(fires, q.Pop())
+	}
+	return
+}
+
+// Len of the Queue
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+// HasSource is true if any queued Fire plays the source
+func (q *Queue) HasSource(source string) bool {
+	return q.sources[source] > 0
+}
+
+// EachSource of the queued Fires, once per source
+func (q *Queue) EachSource(fn func(source string)) {
+	for source := range q.sources {
+		fn(source)
+	}
+}
+
+// Each Fire in the Queue, in no particular order
+func (q *Queue) Each(fn func(f *Fire)) {
+	for _, item := range q.items {
+		fn(item.fire)
+	}
+}
+
+// Fires in the Queue, a copy in order of their beginning
+func (q *Queue) Fires() []*Fire {
+	items := make(queueHeap, len(q.items))
+	copy(items, q.items)
+	sort.Sort(items)
+	fires := make([]*Fire, len(items))
+	for i, item := range items {
+		fires[i] = item.fire
+	}
+	return fires
+}
+
+// Remove the Fires that match, in O(n), returning the # removed
+func (q *Queue) Remove(match func(f *Fire) bool) (removed int) {
+	keep := q.items[:0]
+	for _, item := range q.items {
+		if match(item.fire) {
+			q.forget(item.fire)
+			removed++
+		} else {
+			keep = append(keep, item)
+		}
+	}
+	for i := len(keep); i < len(q.items); i++ {
+		q.items[i] = queueItem{} // release the removed Fires to the garbage collector
+	}
+	q.items = keep
+	for i := len(q.items)/2 - 1; i >= 0; i-- {
+		q.items.down(i)
+	}
+	return
+}
+
+//
+// Private
+//
+
+type queueItem struct {
+	fire *Fire
+	seq  uint64 // of pushing, to break a tie in BeginTz
+}
+
+// queueHeap is a binary min-heap, which sorts like sort.Interface; it does not use container/heap, which would box each item
+type queueHeap []queueItem
+
+func (h queueHeap) Len() int {
+	return len(h)
+}
+
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].fire.BeginTz == h[j].fire.BeginTz {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].fire.BeginTz < h[j].fire.BeginTz
+}
+
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+// up from the item at index i, toward the root, until it is not less than its parent
+func (h queueHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.Less(i, parent) {
+			return
+		}
+		h.Swap(i, parent)
+		i = parent
+	}
+}
+
+// down from the item at index i, toward the leaves, until neither child is less than it
+func (h queueHeap) down(i int) {
+	for {
+		least, left, right := i, 2*i+1, 2*i+2
+		if left < len(h) && h.Less(left, least) {
+			least = left
+		}
+		if right < len(h) && h.Less(right, least) {
+			least = right
+		}
+		if least == i {
+			return
+		}
+		h.Swap(i, least)
+		i = least
+	}
+}
+
+func (q *Queue) forget(f *Fire) {
+	if q.sources[f.Source]--; q.sources[f.Source] <= 0 {
+		delete(q.sources, f.Source)
+	}
+}
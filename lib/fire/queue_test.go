@@ -0,0 +1,62 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestQueue(t *testing.T) {
+	q := NewQueue()
+	assert.Nil(t, q.Peek())
+	assert.Nil(t, q.Pop())
+	late := New("kick.wav", 300, 0, 1, 0)
+	first := New("kick.wav", 100, 0, 1, 0)
+	second := New("snare.wav", 100, 0, 1, 0) // begins with the first, but pushed after it
+	early := New("hat.wav", 50, 0, 1, 0)
+	for _, f := range []*Fire{late, first, second, early} {
+		q.Push(f)
+	}
+	assert.Equal(t, 4, q.Len())
+	assert.Equal(t, early, q.Peek())
+	assert.Equal(t, []*Fire{early, first, second, late}, q.Fires())
+	assert.Equal(t, []*Fire{early, first, second}, q.PopBefore(300))
+	assert.Equal(t, 1, q.Len())
+	assert.True(t, q.HasSource("kick.wav"))
+	assert.False(t, q.HasSource("snare.wav"))
+	var sources []string
+	q.EachSource(func(source string) {
+		sources = append(sources, source)
+	})
+	assert.Equal(t, []string{"kick.wav"}, sources)
+	count := 0
+	q.Each(func(f *Fire) {
+		count++
+	})
+	assert.Equal(t, q.Len(), count)
+	assert.Equal(t, late, q.Pop())
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestQueue_Remove(t *testing.T) {
+	q := NewQueue()
+	for n := 0; n < 100; n++ {
+		q.Push(New("kick.wav", spec.Tz(1000-n), 0, 1, 0))
+	}
+	removed := q.Remove(func(f *Fire) bool {
+		return f.BeginTz%2 == 0
+	})
+	assert.Equal(t, 50, removed)
+	assert.Equal(t, 50, q.Len())
+	prev := spec.Tz(0)
+	for q.Len() > 0 {
+		f := q.Pop()
+		assert.Equal(t, spec.Tz(1), f.BeginTz%2)
+		assert.True(t, f.BeginTz > prev)
+		prev = f.BeginTz
+	}
+	assert.False(t, q.HasSource("kick.wav"))
+}
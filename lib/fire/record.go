@@ -0,0 +1,333 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Record of the setup of a Fire, e.g. to save a schedule and restore it later, losslessly;
+// each time is a duration of the mix timeline, or of the source for its region and sustain loop, at the master frequency,
+// and encodes to JSON as an integer # of nanoseconds.
+type Record struct {
+	Source         string         `json:"source"`
+	Bus            string         `json:"bus,omitempty"`
+	Begin          time.Duration  `json:"begin"`                 // since the start of mix playback, including any fraction of a sample
+	SubSample      bool           `json:"subSample,omitempty"`   // if it begins at a fraction of a sample, see BeginFrac
+	Sustain        time.Duration  `json:"sustain"`               // or 0 for the natural length of the source
+	Volume         float64        `json:"volume"`                // 0 to 1
+	Pan            float64        `json:"pan"`                   // -1 to +1
+	Rate           float64        `json:"rate"`                  // of playback, or 0 for 1
+	Offset         time.Duration  `json:"offset,omitempty"`      // of the region of the source
+	Length         time.Duration  `json:"length,omitempty"`      // of the region of the source, or 0 to its natural end
+	Interval       time.Duration  `json:"interval,omitempty"`    // of a loop, or 0 to play once
+	Repeat         int            `json:"repeat,omitempty"`      // of a loop, or -1 to repeat until canceled
+	LoopXFade      *time.Duration `json:"loopXFade,omitempty"`   // of the source, of the seam of a crossfade loop, or nil for none
+	SustainLoop    *RecordLoop    `json:"sustainLoop,omitempty"` // of the source, or nil to play through
+	Fades          *RecordFades   `json:"fades,omitempty"`       // or nil if SetFades was never called
+	VolumeEnvelope []RecordPoint  `json:"volumeEnvelope,omitempty"`
+	PanEnvelope    []RecordPoint  `json:"panEnvelope,omitempty"`
+	Tone           *RecordTone    `json:"tone,omitempty"`      // or nil if it has no tone
+	Choke          string         `json:"choke,omitempty"`     // group, or empty for none
+	Humanize       *HumanizeSpec  `json:"humanize,omitempty"`  // of its own, or nil for that of the mixer
+	Humanized      bool           `json:"humanized,omitempty"` // already, such that it is not varied again
+	TempoSustain   *RecordSteps   `json:"tempoSustain,omitempty"`
+	After          *RecordAfter   `json:"after,omitempty"` // or nil if it does not wait on a previous fire
+}
+
+// RecordAfter of a Fire that waits to begin a gap after a previous Fire ends, by the index of that one among the Records saved
+// with it; its begin is not yet known, and is recorded as 0
+type RecordAfter struct {
+	Fire int           `json:"fire"`
+	Gap  time.Duration `json:"gap"` // negative to overlap the end of the previous Fire
+}
+
+// RecordTone of a Fire, as set by SetTone, in dB
+type RecordTone struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// RecordSteps of the sustain of a Fire that follows the tempo, resolved by the mixer as the Fire goes live, not by the Fire itself
+type RecordSteps struct {
+	Step  float64 `json:"step"`
+	Steps float64 `json:"steps"`
+}
+
+// RecordFades of a Fire, as set by SetFades
+type RecordFades struct {
+	Attack  time.Duration `json:"attack"`
+	Release time.Duration `json:"release"`
+}
+
+// RecordLoop of a Fire, as set by SetSustainLoop
+type RecordLoop struct {
+	Begin time.Duration `json:"begin"`
+	End   time.Duration `json:"end"`
+}
+
+// RecordPoint of an envelope, at an offset from the beginning of the Fire
+type RecordPoint struct {
+	Offset time.Duration `json:"offset"`
+	Value  float64       `json:"value"`
+}
+
+// Record the setup of the Fire, but not its playback
+func (f *Fire) Record() Record {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	r := Record{
+		Source:         f.Source,
+		Bus:            f.Bus,
+		Begin:          f.beginDur(),
+		SubSample:      f.BeginFrac != 0,
+		Volume:         f.Volume,
+		Pan:            f.Pan,
+		Rate:           f.Rate,
+		Offset:         f.sourceDurOf(f.OffsetTz),
+		Length:         f.sourceDurOf(f.LengthTz),
+		Interval:       f.durOf(f.IntervalTz),
+		Repeat:         f.Repeat,
+		VolumeEnvelope: f.recordPointsOf(f.volumeEnvelope),
+		PanEnvelope:    f.recordPointsOf(f.panEnvelope),
+		Choke:          f.chokeGroup,
+		Humanized:      f.humanized,
+	}
+	if f.EndTz != 0 {
+		r.Sustain = f.durOf(f.EndTz - f.BeginTz)
+	}
+	if f.SustainLoopEndTz != 0 {
+		r.SustainLoop = &RecordLoop{f.sourceDurOf(f.SustainLoopBeginTz), f.sourceDurOf(f.SustainLoopEndTz)}
+	}
+	if f.fadesSet {
+		r.Fades = &RecordFades{f.durOf(f.attackTz), f.durOf(f.releaseTz)}
+	}
+	if f.xfadeLoop {
+		xfade := f.sourceDurOf(f.XFadeTz)
+		r.LoopXFade = &xfade
+	}
+	if f.toneLowDB != 0 || f.toneHighDB != 0 {
+		r.Tone = &RecordTone{f.toneLowDB, f.toneHighDB}
+	}
+	if f.humanize != nil {
+		h := *f.humanize
+		r.Humanize = &h
+	}
+	return r
+}
+
+// RecordFrom a Tz of mix playback, the rest of a Fire that is playing, e.g. to save it and resume it later from where it was: the
+// Record of its setup as if it began then, at the position it reached in its source, with what is left of its sustain, fades and
+// envelopes. A Fire that loops by interval or crossfade, or is inside its sustain loop, is two Records: what is left of the current
+// iteration, and the loop from where it next begins; the first seam of a crossfade loop is faded out, not crossfaded. A Fire that is
+// choked has nothing left to record, and a Fire that has not begun playing is recorded as is.
+func (f *Fire) RecordFrom(at spec.Tz) []Record {
+	r := f.Record()
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.state != StatePlay || at <= f.BeginTz {
+		return []Record{r}
+	}
+	if f.choked {
+		return nil
+	}
+	elapsed := at - f.BeginTz
+	switch {
+	case f.xfadeLoop:
+		return f.recordXFadeFrom(r, at, elapsed)
+	case f.IntervalTz > 0:
+		return f.recordLoopFrom(r, at, elapsed)
+	}
+	return f.recordSustainFrom(r, at, elapsed)
+}
+
+// Restore the setup of the Fire from a Record, except its source and the times it begins and ends, with which it was created,
+// and its sustain in steps, which the mixer resolves. Must be called before the Fire begins playing.
+func (f *Fire) Restore(r Record) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.Bus = r.Bus
+	f.Rate = r.Rate
+	if f.Rate <= 0 {
+		f.Rate = 1
+	}
+	f.OffsetTz = f.sourceTzOf(r.Offset)
+	f.LengthTz = f.sourceTzOf(r.Length)
+	f.IntervalTz = f.tzOf(r.Interval)
+	f.Repeat = r.Repeat
+	if r.SustainLoop != nil {
+		f.SustainLoopBeginTz = f.sourceTzOf(r.SustainLoop.Begin)
+		f.SustainLoopEndTz = f.sourceTzOf(r.SustainLoop.End)
+	}
+	if r.Fades != nil {
+		f.attackTz = f.tzOf(r.Fades.Attack)
+		f.releaseTz = f.tzOf(r.Fades.Release)
+		f.fadesSet = true
+	}
+	f.volumeEnvelope = f.envelopeOf(r.VolumeEnvelope)
+	f.panEnvelope = f.envelopeOf(r.PanEnvelope)
+	if r.LoopXFade != nil {
+		f.xfadeLoop = true
+		f.XFadeTz = f.sourceTzOf(*r.LoopXFade)
+		f.EndTz = 0
+	}
+	if r.Tone != nil {
+		f.toneLowDB = r.Tone.Low
+		f.toneHighDB = r.Tone.High
+	}
+	f.chokeGroup = r.Choke
+	if r.Humanize != nil {
+		h := *r.Humanize
+		f.humanize = &h
+	}
+	f.humanized = r.Humanized
+}
+
+//
+// Private
+//
+
+// recordSustainFrom a Tz of mix playback, a Fire that plays once, elapsed since it began, see RecordFrom;
+// the caller must hold the mutex
+func (f *Fire) recordSustainFrom(r Record, at spec.Tz, elapsed spec.Tz) []Record {
+	if f.EndTz != 0 && f.EndTz <= at {
+		return nil
+	}
+	t := f.sustainLoopTz(elapsed)
+	srcTz := f.OffsetTz + f.sourceAdvance(t)
+	rest := f.restAt(r, at, srcTz, elapsed)
+	rest.SustainLoop = r.SustainLoop
+	if f.EndTz != 0 {
+		rest.Sustain = f.durOf(f.EndTz - at)
+	}
+	if !f.sustainLooping() || srcTz < f.SustainLoopBeginTz {
+		return []Record{rest}
+	}
+	untilEnd := spec.Tz(float64(f.SustainLoopEndTz-srcTz) / f.PlayRate()) // of the sustain loop, where it wraps
+	if untilEnd >= f.EndTz-at {
+		rest.SustainLoop = nil
+		return []Record{rest}
+	}
+	loop := f.restAt(r, at+untilEnd, f.SustainLoopBeginTz, elapsed+untilEnd)
+	loop.SustainLoop = r.SustainLoop
+	loop.Sustain = f.durOf(f.EndTz - at - untilEnd)
+	if untilEnd <= 1 {
+		return []Record{loop}
+	}
+	rest.SustainLoop = nil
+	rest.Sustain = f.durOf(untilEnd - 1) // it sounds at its end too
+	rest.Fades = &RecordFades{}          // none at the seam
+	if r.Fades != nil {
+		rest.Fades.Attack = f.durOf(f.attackTz - minTz(f.attackTz, elapsed))
+	}
+	return []Record{rest, loop}
+}
+
+// recordLoopFrom a Tz of mix playback, a Fire that loops by interval, elapsed since it began, see RecordFrom;
+// the caller must hold the mutex
+func (f *Fire) recordLoopFrom(r Record, at spec.Tz, elapsed spec.Tz) (records []Record) {
+	done := elapsed / f.IntervalTz
+	within := elapsed % f.IntervalTz
+	length := f.IntervalTz // of each repeat, which cuts off the one before it
+	if sustain := f.sustainTz(); sustain < length {
+		length = sustain
+	}
+	if within+1 < length {
+		rest := f.restAt(r, at, f.OffsetTz+f.sourceAdvance(within), within)
+		rest.Sustain = f.durOf(length - within - 1) // it sounds at its end too
+		if r.Fades == nil && f.EndTz == 0 {
+			rest.Fades = &RecordFades{} // cut off as it was, without the release of a truncated sustain
+		}
+		records = append(records, rest)
+	}
+	if f.Repeat < 0 || spec.Tz(f.Repeat) > done+1 {
+		loop := r
+		loop.Begin = f.durOf(f.BeginTz + (done+1)*f.IntervalTz)
+		loop.SubSample = false
+		if f.Repeat > 0 {
+			loop.Repeat = f.Repeat - int(done+1)
+		}
+		records = append(records, loop)
+	}
+	return
+}
+
+// recordXFadeFrom a Tz of mix playback, a Fire that loops by crossfade, elapsed since it began, see RecordFrom;
+// the caller must hold the mutex
+func (f *Fire) recordXFadeFrom(r Record, at spec.Tz, elapsed spec.Tz) []Record {
+	length, xfadeTz := f.xfadeLength()
+	if length == 0 {
+		return []Record{r}
+	}
+	period := length - xfadeTz
+	t := elapsed % period
+	rest := f.restAt(r, at, f.OffsetTz+f.sourceAdvance(t), t)
+	rest.Fades = &RecordFades{0, f.durOf(xfadeTz)}
+	loop := r
+	loop.Begin = f.durOf(f.BeginTz + (elapsed/period+1)*period)
+	loop.SubSample = false
+	return []Record{rest, loop}
+}
+
+// restAt of a Record, as if the Fire began at a Tz of mix playback, at a Tz of its source, and a Tz of its fades and envelopes, to
+// play once, for its natural length unless the caller sets its sustain; the caller must hold the mutex
+func (f *Fire) restAt(r Record, at spec.Tz, srcTz spec.Tz, envTz spec.Tz) Record {
+	r.Source = f.resolved()
+	r.Begin = f.durOf(at)
+	r.SubSample = false
+	r.Sustain = 0
+	r.Offset = f.sourceDurOf(srcTz)
+	if f.LengthTz > 0 {
+		r.Length = f.sourceDurOf(f.LengthTz - minTz(f.LengthTz, srcTz-f.OffsetTz))
+	}
+	r.Interval, r.Repeat, r.LoopXFade, r.SustainLoop = 0, 0, nil, nil
+	if r.Fades != nil {
+		r.Fades = &RecordFades{f.durOf(f.attackTz - minTz(f.attackTz, envTz)), f.durOf(f.releaseTz)}
+	}
+	r.VolumeEnvelope = f.recordPointsOf(f.volumeEnvelope.shifted(envTz))
+	r.PanEnvelope = f.recordPointsOf(f.panEnvelope.shifted(envTz))
+	r.Humanized = true
+	return r
+}
+
+// sourceAdvance of the source, in Tz at the master frequency, over a # of Tz of its playback
+func (f *Fire) sourceAdvance(t spec.Tz) spec.Tz {
+	return spec.Tz(float64(t) * f.PlayRate())
+}
+
+// minTz of two Tz
+func minTz(a spec.Tz, b spec.Tz) spec.Tz {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// beginDur of the Fire on the mix timeline, including the fraction of a sample after its BeginTz, to the nearest nanosecond
+func (f *Fire) beginDur() time.Duration {
+	freq := f.freq() / f.timeScale
+	if freq <= 0 {
+		return 0
+	}
+	return time.Duration(math.Round((float64(f.BeginTz) + f.BeginFrac) * float64(time.Second) / freq))
+}
+
+func (f *Fire) recordPointsOf(env Envelope) (points []RecordPoint) {
+	for _, p := range env {
+		points = append(points, RecordPoint{f.durOf(p.OffsetTz), p.Value})
+	}
+	return
+}
+
+func (f *Fire) envelopeOf(points []RecordPoint) Envelope {
+	if len(points) == 0 {
+		return nil
+	}
+	env := make([]EnvelopePoint, len(points))
+	for i, p := range points {
+		env[i] = EnvelopePoint{f.tzOf(p.Offset), p.Value}
+	}
+	return NewEnvelope(env)
+}
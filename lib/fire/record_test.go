@@ -0,0 +1,111 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestRecord(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2})
+	f := New("sound.wav", 44100, 66150, 0.8, -0.25)
+	f.Bus = "drums"
+	f.SetRate(1.5)
+	f.SetLoop(11025, 4)
+	f.SetRegion(441, 22050)
+	f.SetFades(20*time.Millisecond, 10*time.Millisecond)
+	f.SetSustainLoop(4410, 8820)
+	f.SetVolumeEnvelope([]EnvelopePoint{{0, 0}, {4410, 1}})
+	f.SetPanEnvelope([]EnvelopePoint{{0, -1}})
+	r := f.Record()
+	assert.Equal(t, time.Second, r.Begin)
+	assert.Equal(t, 500*time.Millisecond, r.Sustain)
+	assert.Equal(t, 10*time.Millisecond, r.Offset)
+	assert.Equal(t, &RecordFades{20 * time.Millisecond, 10 * time.Millisecond}, r.Fades)
+	assert.Equal(t, &RecordLoop{100 * time.Millisecond, 200 * time.Millisecond}, r.SustainLoop)
+	assert.Equal(t, []RecordPoint{{0, 0}, {100 * time.Millisecond, 1}}, r.VolumeEnvelope)
+	restored := New(r.Source, f.tzOf(r.Begin), f.tzOf(r.Begin+r.Sustain), r.Volume, r.Pan)
+	restored.Restore(r)
+	assert.Equal(t, r, restored.Record())
+	assert.Equal(t, f.FadeAt(10), restored.FadeAt(10))
+}
+
+func TestRecord_Setup(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2})
+	f := New("sound.wav", 44100, 0, 0.8, -0.25)
+	f.BeginFrac = 0.5
+	f.SetLoopXFade(882)
+	f.SetTone(-6, 4)
+	f.SetChoke("hats")
+	f.SetHumanize(HumanizeSpec{VolumeJitter: 0.1, TimingJitter: 5 * time.Millisecond})
+	r := f.Record()
+	assert.Equal(t, time.Second+11338*time.Nanosecond, r.Begin) // and half a sample
+	assert.True(t, r.SubSample)
+	xfade := 20 * time.Millisecond
+	assert.Equal(t, &xfade, r.LoopXFade)
+	assert.Equal(t, &RecordTone{-6, 4}, r.Tone)
+	assert.Equal(t, "hats", r.Choke)
+	assert.Equal(t, &HumanizeSpec{VolumeJitter: 0.1, TimingJitter: 5 * time.Millisecond}, r.Humanize)
+	assert.False(t, r.Humanized)
+	restored := New(r.Source, 44100, 0, r.Volume, r.Pan)
+	restored.BeginFrac = 0.5
+	restored.Restore(r)
+	assert.Equal(t, r, restored.Record())
+	assert.True(t, restored.LoopsForever())
+}
+
+func TestRecord_Default(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2})
+	r := New("sound.wav", 0, 0, 1, 0).Record()
+	assert.Equal(t, time.Duration(0), r.Sustain)
+	assert.Nil(t, r.Fades)
+	assert.Nil(t, r.SustainLoop)
+	assert.Nil(t, r.VolumeEnvelope)
+	restored := New(r.Source, 0, 0, 1, 0)
+	restored.Restore(Record{}) // e.g. from an earlier version, without a rate
+	assert.Equal(t, float64(1), restored.Rate)
+}
+
+func TestRecord_TimeScale(t *testing.T) {
+	Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2})
+	f := New("sound.wav", 8820, 13230, 1, 0)
+	f.SetTimeScale(5)
+	f.SetRate(1.5)
+	f.SetLoop(2205, 4)
+	f.SetRegion(441, 22050)
+	assert.Equal(t, 7.5, f.PlayRate())
+	r := f.Record()
+	assert.Equal(t, time.Second, r.Begin) // of the timeline, five times faster
+	assert.Equal(t, 500*time.Millisecond, r.Sustain)
+	assert.Equal(t, 250*time.Millisecond, r.Interval)
+	assert.Equal(t, 10*time.Millisecond, r.Offset) // of the source, as is
+	assert.Equal(t, 1.5, r.Rate)
+}
+
+func TestRecordFrom_XFade(t *testing.T) {
+	s := spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1}
+	Configure(s)
+	source.DefaultCache().Configure(s)
+	src := source.ToneKey(source.WaveSine, 100, 100*time.Millisecond) // 100 Tz
+	assert.Nil(t, source.Prepare(src))
+	defer source.Evict(src)
+	f := New(src, 1000, 0, 1, 0)
+	f.SetLoopXFade(20)
+	assert.Equal(t, []Record{f.Record()}, f.RecordFrom(900)) // not yet playing
+	f.At(1000)
+	f.At(1090) // 10 Tz into the second iteration, which began at 1080
+	records := f.RecordFrom(1090)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, 1090*time.Millisecond, records[0].Begin)
+	assert.Equal(t, 10*time.Millisecond, records[0].Offset)
+	assert.Nil(t, records[0].LoopXFade)
+	assert.Equal(t, &RecordFades{0, 20 * time.Millisecond}, records[0].Fades)
+	assert.Equal(t, 1160*time.Millisecond, records[1].Begin) // where the third iteration begins
+	xfade := 20 * time.Millisecond
+	assert.Equal(t, &xfade, records[1].LoopXFade)
+}
@@ -0,0 +1,131 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"math"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+// ToneLowFreq and ToneHighFreq in Hz, the corners of the low and high shelves of the tone of a Fire, see SetTone
+const (
+	ToneLowFreq  = 200.0
+	ToneHighFreq = 4000.0
+)
+
+// SetTone of the Fire, boosting or cutting its lows below ToneLowFreq and its highs above ToneHighFreq by a gain in dB each, e.g.
+// +4 highs to brighten a snare hit, or -6 highs to dull a ghost note; zero for both (the default) leaves it unfiltered, at no cost.
+// It must be set before the Fire goes live, shortly before it begins, when its filter is resolved at the frequency of the mix.
+func (f *Fire) SetTone(lowDB float64, highDB float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.toneLowDB = lowDB
+	f.toneHighDB = highDB
+}
+
+// Tone of the Fire, the gains in dB of its lows and highs, see SetTone
+func (f *Fire) Tone() (lowDB float64, highDB float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.toneLowDB, f.toneHighDB
+}
+
+// ResolveTone of the Fire, computing the coefficients of its filter at a frequency in Hz for a # of channels, each from silence,
+// or none if it has no tone. Called by the mixer as the Fire goes live, holding the lock of the mix loop, which owns the filter.
+func (f *Fire) ResolveTone(freq float64, channels int) {
+	f.mutex.Lock()
+	lowDB, highDB := f.toneLowDB, f.toneHighDB
+	f.mutex.Unlock()
+	if (lowDB == 0 && highDB == 0) || freq <= 0 || channels <= 0 {
+		f.toneFilter = nil
+		return
+	}
+	t := &ToneFilter{state: make([][2]toneState, channels)}
+	if lowDB != 0 {
+		t.low = newToneShelf(false, lowDB, ToneLowFreq, freq)
+	}
+	if highDB != 0 {
+		t.high = newToneShelf(true, highDB, ToneHighFreq, freq)
+	}
+	f.toneFilter = t
+}
+
+// ToneFilter of the Fire, as resolved, or nil if it has no tone; only for the mix loop, see ResolveTone
+func (f *Fire) ToneFilter() *ToneFilter {
+	return f.toneFilter
+}
+
+// ToneFilter of a Fire, a low shelf and a high shelf in series, with the state of each channel
+type ToneFilter struct {
+	low   *toneShelf // or nil if flat
+	high  *toneShelf // or nil if flat
+	state [][2]toneState
+}
+
+// Process a sample of all channels in place, by both shelves
+func (t *ToneFilter) Process(smp []sample.Value) {
+	for c := range smp {
+		if c >= len(t.state) {
+			return
+		}
+		x := float64(smp[c])
+		if t.low != nil {
+			x = t.low.process(&t.state[c][0], x)
+		}
+		if t.high != nil {
+			x = t.high.process(&t.state[c][1], x)
+		}
+		smp[c] = sample.Value(x)
+	}
+}
+
+//
+// Private
+//
+
+// toneMaxCorner ratio of the frequency of the mix, just below Nyquist
+const toneMaxCorner = 0.49
+
+// toneShelf coefficients of a biquad shelving filter, normalized, in direct form I
+type toneShelf struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+}
+
+// toneState of a biquad of one channel
+type toneState struct {
+	x1, x2 float64
+	y1, y2 float64
+}
+
+// newToneShelf low or high, of a gain in dB at a corner in Hz, at a frequency of the mix, with a slope of 1, per the RBJ Audio EQ Cookbook
+func newToneShelf(high bool, gainDB float64, corner float64, freq float64) *toneShelf {
+	a := math.Pow(10, gainDB/40)
+	w0 := 2 * math.Pi * math.Min(corner, freq*toneMaxCorner) / freq
+	cos := math.Cos(w0)
+	alpha2 := 2 * math.Sqrt(a) * math.Sin(w0) / math.Sqrt2 // 2√A·α
+	var b0, b1, b2, a0, a1, a2 float64
+	if high {
+		b0 = a * ((a + 1) + (a-1)*cos + alpha2)
+		b1 = -2 * a * ((a - 1) + (a+1)*cos)
+		b2 = a * ((a + 1) + (a-1)*cos - alpha2)
+		a0 = (a + 1) - (a-1)*cos + alpha2
+		a1 = 2 * ((a - 1) - (a+1)*cos)
+		a2 = (a + 1) - (a-1)*cos - alpha2
+	} else {
+		b0 = a * ((a + 1) - (a-1)*cos + alpha2)
+		b1 = 2 * a * ((a - 1) - (a+1)*cos)
+		b2 = a * ((a + 1) - (a-1)*cos - alpha2)
+		a0 = (a + 1) + (a-1)*cos + alpha2
+		a1 = -2 * ((a - 1) + (a+1)*cos)
+		a2 = (a + 1) + (a-1)*cos - alpha2
+	}
+	return &toneShelf{b0 / a0, b1 / a0, b2 / a0, a1 / a0, a2 / a0}
+}
+
+func (s *toneShelf) process(st *toneState, x float64) float64 {
+	y := s.b0*x + s.b1*st.x1 + s.b2*st.x2 - s.a1*st.y1 - s.a2*st.y2
+	st.x2, st.x1 = st.x1, x
+	st.y2, st.y1 = st.y1, y
+	return y
+}
@@ -0,0 +1,74 @@
+// Package fire model an audio source playing at a specific time
+package fire
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+func TestSetTone(t *testing.T) {
+	f := New("sound.wav", 0, 0, 1, 0)
+	lowDB, highDB := f.Tone()
+	assert.Equal(t, 0.0, lowDB)
+	assert.Equal(t, 0.0, highDB)
+	f.ResolveTone(44100, 2)
+	assert.Nil(t, f.ToneFilter()) // no cost
+	f.SetTone(6, -6)
+	lowDB, highDB = f.Tone()
+	assert.Equal(t, 6.0, lowDB)
+	assert.Equal(t, -6.0, highDB)
+	f.ResolveTone(44100, 2)
+	assert.NotNil(t, f.ToneFilter())
+	f.SetTone(0, 0)
+	f.ResolveTone(44100, 2)
+	assert.Nil(t, f.ToneFilter())
+}
+
+func TestToneFilter(t *testing.T) {
+	for _, tc := range []struct {
+		lowDB, highDB float64
+		freq          float64
+		gainDB        float64
+	}{
+		{6, 0, 50, 6},
+		{6, 0, 10000, 0},
+		{-12, 0, 30, -12},
+		{0, 6, 15000, 6},
+		{0, -6, 12000, -6},
+		{0, -6, 100, 0},
+		{6, -6, 1000, 0},
+	} {
+		f := New("sound.wav", 0, 0, 1, 0)
+		f.SetTone(tc.lowDB, tc.highDB)
+		f.ResolveTone(44100, 2)
+		assert.InDelta(t, tc.gainDB, testToneGainDB(f.ToneFilter(), tc.freq, 44100), 0.6, "%+v", tc)
+	}
+}
+
+//
+// Private
+//
+
+// testToneGainDB of a filter for a sine at a frequency, in the left channel, the right channel silent
+func testToneGainDB(tone *ToneFilter, freq float64, rate float64) float64 {
+	var in, out float64
+	smp := make([]sample.Value, 2)
+	frames := int(rate / 2)
+	for n := 0; n < frames; n++ {
+		x := math.Sin(2 * math.Pi * freq * float64(n) / rate)
+		smp[0], smp[1] = sample.Value(x), 0
+		tone.Process(smp)
+		if n >= frames/2 { // after it settles
+			in += x * x
+			out += float64(smp[0] * smp[0])
+			if smp[1] != 0 {
+				return math.NaN()
+			}
+		}
+	}
+	return 10 * math.Log10(out/in)
+}
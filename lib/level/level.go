@@ -0,0 +1,108 @@
+// Package level meters the peak and RMS level of audio, per channel
+package level
+
+import (
+	"math"
+	"sync"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+// Level of each channel over the last mix cycle, linear from 0 to 1 (or above, if clipping)
+type Level struct {
+	Peak []float64
+	RMS  []float64
+}
+
+// DBFS of a linear level, e.g. 1 is 0 dBFS and 0.5 is about -6 dBFS; silence is -Inf
+func DBFS(v float64) float64 {
+	return 20 * math.Log10(v)
+}
+
+// Meter accumulates samples in the mix loop, and publishes their level at the end of each mix cycle.
+// Add, Publish and Reset must only be called from the mix loop; Level is safe to call from any goroutine.
+// Once it has metered a sample, a Meter makes no allocations in the mix loop.
+type Meter struct {
+	peak       []float64
+	sumSquares []float64
+	count      int
+	published  Level      // reused by each Publish, and copied by Level
+	mutex      sync.Mutex // guards the published level
+}
+
+// Add the values of one sample to the meter, and return true if any channel is clipping, i.e. at or above 1
+func (m *Meter) Add(values []sample.Value) (clip bool) {
+	if len(m.peak) != len(values) {
+		m.allocate(len(values))
+	}
+	for c, v := range values {
+		abs := math.Abs(float64(v))
+		if abs > m.peak[c] {
+			m.peak[c] = abs
+		}
+		if abs >= 1 {
+			clip = true
+		}
+		m.sumSquares[c] += abs * abs
+	}
+	m.count++
+	return
+}
+
+// Publish the level of all samples added since the last Publish, and start accumulating anew
+func (m *Meter) Publish() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for c := range m.peak {
+		m.published.Peak[c] = m.peak[c]
+		m.published.RMS[c] = 0
+		if m.count > 0 {
+			m.published.RMS[c] = math.Sqrt(m.sumSquares[c] / float64(m.count))
+		}
+		m.peak[c] = 0
+		m.sumSquares[c] = 0
+	}
+	m.count = 0
+}
+
+// Reset the meter to silence, e.g. when its audio has stopped
+func (m *Meter) Reset() {
+	for c := range m.peak {
+		m.peak[c] = 0
+		m.sumSquares[c] = 0
+	}
+	m.count = 0
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for c := range m.published.Peak {
+		m.published.Peak[c] = 0
+		m.published.RMS[c] = 0
+	}
+}
+
+// Level last published by the meter, or empty if nothing has been published yet
+func (m *Meter) Level() Level {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return Level{
+		Peak: append([]float64(nil), m.published.Peak...),
+		RMS:  append([]float64(nil), m.published.RMS...),
+	}
+}
+
+//
+// Private
+//
+
+// allocate the meter for a # of channels, which empties the published level until the next Publish
+func (m *Meter) allocate(channels int) {
+	m.peak = make([]float64, channels)
+	m.sumSquares = make([]float64, channels)
+	m.count = 0
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.published = Level{
+		Peak: make([]float64, channels),
+		RMS:  make([]float64, channels),
+	}
+}
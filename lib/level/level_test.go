@@ -0,0 +1,49 @@
+// Package level meters the peak and RMS level of audio, per channel
+package level
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+func TestDBFS(t *testing.T) {
+	assert.Equal(t, float64(0), DBFS(1))
+	assert.InDelta(t, -6.0206, DBFS(0.5), 0.0001)
+	assert.True(t, math.IsInf(DBFS(0), -1))
+}
+
+func TestMeter(t *testing.T) {
+	m := &Meter{}
+	assert.Equal(t, 0, len(m.Level().Peak))
+	assert.False(t, m.Add([]sample.Value{0.5, -0.25}))
+	assert.False(t, m.Add([]sample.Value{-0.5, 0}))
+	m.Publish()
+	lvl := m.Level()
+	assert.Equal(t, []float64{0.5, 0.25}, lvl.Peak)
+	assert.InDelta(t, 0.5, lvl.RMS[0], 0.0001)
+	assert.InDelta(t, math.Sqrt(0.0625/2), lvl.RMS[1], 0.0001)
+	m.Publish()
+	assert.Equal(t, []float64{0, 0}, m.Level().Peak)
+}
+
+func TestMeter_Clip(t *testing.T) {
+	m := &Meter{}
+	assert.False(t, m.Add([]sample.Value{0.99}))
+	assert.True(t, m.Add([]sample.Value{1}))
+	assert.True(t, m.Add([]sample.Value{-1.5}))
+}
+
+func TestMeter_Reset(t *testing.T) {
+	m := &Meter{}
+	m.Add([]sample.Value{0.5})
+	m.Publish()
+	m.Add([]sample.Value{0.75})
+	m.Reset()
+	assert.Equal(t, []float64{0}, m.Level().Peak)
+	m.Publish()
+	assert.Equal(t, []float64{0}, m.Level().Peak)
+}
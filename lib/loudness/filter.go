@@ -0,0 +1,116 @@
+// Package loudness measures the loudness of audio by ITU-R BS.1770 and EBU R128, e.g. to publish renders at a consistent loudness
+package loudness
+
+import (
+	"math"
+)
+
+//
+// Private
+//
+
+// biquad filter, in direct form I
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	x1, x2     float64
+	y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting of BS.1770 at a frequency: a high shelf of about +4dB, modeling the head, then a high-pass (RLB) filter. The coefficients
+// are derived from the analog prototypes of the filters by the bilinear transform, so they match the standard at 48kHz and at any other frequency.
+func kWeighting(freq float64) [2]biquad {
+	shelf := func() biquad {
+		const f0, gain, q = 1681.974450955533, 3.999843853973347, 0.7071752369554196
+		k := math.Tan(math.Pi * f0 / freq)
+		vh := math.Pow(10, gain/20)
+		vb := math.Pow(vh, 0.4996667741545416)
+		a0 := 1 + k/q + k*k
+		return biquad{
+			b0: (vh + vb*k/q + k*k) / a0,
+			b1: 2 * (k*k - vh) / a0,
+			b2: (vh - vb*k/q + k*k) / a0,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}
+	highPass := func() biquad {
+		const f0, q = 38.13547087602444, 0.5003270373238773
+		k := math.Tan(math.Pi * f0 / freq)
+		a0 := 1 + k/q + k*k
+		return biquad{
+			b0: 1,
+			b1: -2,
+			b2: 1,
+			a1: 2 * (k*k - 1) / a0,
+			a2: (1 - k/q + k*k) / a0,
+		}
+	}
+	return [2]biquad{shelf(), highPass()}
+}
+
+// truePeak of one channel, by oversampling with a polyphase windowed-sinc interpolator, as by BS.1770 Annex 2
+type truePeak struct {
+	factor  int
+	taps    [][]float64 // of each phase
+	history []float64   // of the last input samples, a ring
+	at      int
+}
+
+// truePeakTaps of each phase of the interpolator
+const truePeakTaps = 12
+
+// newTruePeak oversampled 4x below 96kHz, 2x below 192kHz, else not at all
+func newTruePeak(freq float64) *truePeak {
+	factor := 4
+	if freq >= 192000 {
+		factor = 1
+	} else if freq >= 96000 {
+		factor = 2
+	}
+	tp := &truePeak{factor: factor, taps: make([][]float64, factor), history: make([]float64, truePeakTaps)}
+	n := truePeakTaps * factor
+	center := float64(n-1) / 2
+	for p := 0; p < factor; p++ {
+		tp.taps[p] = make([]float64, truePeakTaps)
+		for j := range tp.taps[p] {
+			i := p + j*factor
+			x := (float64(i) - center) / float64(factor)
+			window := 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(n))
+			tp.taps[p][j] = sinc(x) * window
+		}
+	}
+	return tp
+}
+
+// add a sample, returning the absolute peak of it and the samples interpolated before it
+func (tp *truePeak) add(x float64) (peak float64) {
+	tp.history[tp.at] = x
+	peak = math.Abs(x)
+	if tp.factor == 1 {
+		return
+	}
+	for _, taps := range tp.taps {
+		y := 0.0
+		for j, h := range taps {
+			y += h * tp.history[(tp.at-j+truePeakTaps)%truePeakTaps]
+		}
+		peak = math.Max(peak, math.Abs(y))
+	}
+	tp.at = (tp.at + 1) % truePeakTaps
+	return
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
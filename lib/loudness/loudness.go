@@ -0,0 +1,195 @@
+// Package loudness measures the loudness of audio by ITU-R BS.1770 and EBU R128, e.g. to publish renders at a consistent loudness
+package loudness
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+// Report of the loudness of audio
+type Report struct {
+	Integrated float64 // in LUFS, gated over the whole audio, or -Inf for silence
+	Range      float64 // in LU, the loudness range (LRA) of the short-term loudness, by EBU Tech 3342
+	TruePeak   float64 // in dBTP, the peak of the audio oversampled to find the peaks between samples, or -Inf for silence
+}
+
+// Meter of the loudness of audio, one sample of all channels at a time; it keeps a small value for each 100ms, so it can meter hours.
+type Meter struct {
+	freq      float64
+	weights   []float64   // of each channel
+	filters   [][2]biquad // K-weighting of each channel
+	peaks     []*truePeak
+	stepTz    int       // # of frames in 100ms
+	atTz      int       // # of frames into the current step
+	stepSum   float64   // of the K-weighted squares of the current step, summed by channel weight
+	steps     []float64 // sums of the last 3s of steps, a ring
+	stepCount int
+	blocks    []float64 // mean square of each 400ms block, at each step
+	shortTerm []float64 // mean square of each 3s window, at each step
+	peak      float64   // true peak of all channels, linear
+}
+
+// New meter of audio at a frequency in Hz with a # of channels. Each channel is weighted as by BS.1770: with 5 or 6 channels, the order
+// is L, R, C, (LFE,) Ls, Rs, whose surround channels are 1.5dB louder, and whose LFE is not measured; any other channel has the same weight.
+func New(freq float64, channels int) *Meter {
+	m := &Meter{
+		freq:    freq,
+		weights: channelWeights(channels),
+		filters: make([][2]biquad, channels),
+		peaks:   make([]*truePeak, channels),
+		stepTz:  int(math.Round(freq * stepDur)),
+		steps:   make([]float64, shortTermSteps),
+	}
+	if m.stepTz < 1 {
+		m.stepTz = 1
+	}
+	for c := range m.filters {
+		m.filters[c] = kWeighting(freq)
+		m.peaks[c] = newTruePeak(freq)
+	}
+	return m
+}
+
+// Add one sample of all channels to the meter
+func (m *Meter) Add(values []sample.Value) {
+	for c, v := range values {
+		if c >= len(m.weights) {
+			break
+		}
+		x := float64(v)
+		m.peak = math.Max(m.peak, m.peaks[c].add(x))
+		if m.weights[c] == 0 {
+			continue
+		}
+		y := m.filters[c][1].process(m.filters[c][0].process(x))
+		m.stepSum += m.weights[c] * y * y
+	}
+	m.atTz++
+	if m.atTz < m.stepTz {
+		return
+	}
+	m.steps[m.stepCount%shortTermSteps] = m.stepSum
+	m.stepCount++
+	m.stepSum = 0
+	m.atTz = 0
+	if m.stepCount >= blockSteps {
+		m.blocks = append(m.blocks, m.meanSquare(blockSteps))
+	}
+	if m.stepCount >= shortTermSteps {
+		m.shortTerm = append(m.shortTerm, m.meanSquare(shortTermSteps))
+	}
+}
+
+// Report of the loudness of all the audio added so far
+func (m *Meter) Report() Report {
+	return Report{
+		Integrated: m.integrated(),
+		Range:      m.loudnessRange(),
+		TruePeak:   20 * math.Log10(m.peak),
+	}
+}
+
+// Measure the loudness of interleaved samples at a frequency in Hz with a # of channels, see New
+func Measure(samples []float64, freq float64, channels int) Report {
+	m := New(freq, channels)
+	frame := make([]sample.Value, channels)
+	for i := 0; i+channels <= len(samples); i += channels {
+		for c := range frame {
+			frame[c] = sample.Value(samples[i+c])
+		}
+		m.Add(frame)
+	}
+	return m.Report()
+}
+
+// LUFS of a mean square, summed by channel weight
+func LUFS(meanSquare float64) float64 {
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+//
+// Private
+//
+
+const (
+	stepDur          = 0.1 // seconds between blocks, i.e. an overlap of 75% of a block
+	blockSteps       = 4   // 400ms gating blocks of the integrated loudness
+	shortTermSteps   = 30  // 3s windows of the short-term loudness
+	absoluteGate     = -70 // LUFS
+	relativeGate     = -10 // LU below the loudness of the blocks above the absolute gate
+	rangeGate        = -20 // LU below, for the loudness range
+	rangeLowPercent  = 0.10
+	rangeHighPercent = 0.95
+)
+
+// channelWeights by BS.1770, for a # of channels
+func channelWeights(channels int) []float64 {
+	weights := make([]float64, channels)
+	for c := range weights {
+		weights[c] = 1
+	}
+	switch channels {
+	case 5: // L, R, C, Ls, Rs
+		weights[3], weights[4] = 1.41, 1.41
+	case 6: // L, R, C, LFE, Ls, Rs
+		weights[3], weights[4], weights[5] = 0, 1.41, 1.41
+	}
+	return weights
+}
+
+// meanSquare of the last # of steps
+func (m *Meter) meanSquare(steps int) (sum float64) {
+	for s := 1; s <= steps; s++ {
+		sum += m.steps[(m.stepCount-s)%shortTermSteps]
+	}
+	return sum / float64(steps*m.stepTz)
+}
+
+// integrated loudness of the blocks, gated absolutely and then relative to the loudness of the blocks above the absolute gate
+func (m *Meter) integrated() float64 {
+	gated := gate(m.blocks, absoluteGate)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+	return LUFS(mean(gate(gated, LUFS(mean(gated))+relativeGate)))
+}
+
+// loudnessRange of the short-term loudness, between its low and high percentiles, after gating as by EBU Tech 3342
+func (m *Meter) loudnessRange() float64 {
+	gated := gate(m.shortTerm, absoluteGate)
+	if len(gated) == 0 {
+		return 0
+	}
+	gated = gate(gated, LUFS(mean(gated))+rangeGate)
+	loudness := make([]float64, len(gated))
+	for i, ms := range gated {
+		loudness[i] = LUFS(ms)
+	}
+	sort.Float64s(loudness)
+	return percentile(loudness, rangeHighPercent) - percentile(loudness, rangeLowPercent)
+}
+
+// gate mean squares whose loudness is above a threshold in LUFS
+func gate(meanSquares []float64, threshold float64) (gated []float64) {
+	for _, ms := range meanSquares {
+		if LUFS(ms) > threshold {
+			gated = append(gated, ms)
+		}
+	}
+	return
+}
+
+func mean(values []float64) (sum float64) {
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// percentile of sorted values, the nearest rank
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Round(p * float64(len(sorted)-1)))
+	return sorted[i]
+}
@@ -0,0 +1,90 @@
+// Package loudness measures the loudness of audio by ITU-R BS.1770 and EBU R128, e.g. to publish renders at a consistent loudness
+package loudness
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a 1kHz sine at -23dBFS in both channels is -23 LUFS, by EBU Tech 3341, at any frequency
+func TestMeasure(t *testing.T) {
+	for _, freq := range []float64{44100, 48000, 96000} {
+		report := Measure(testSine(1000, freq, 2, 5, -23), freq, 2)
+		assert.InDelta(t, -23.0, report.Integrated, 0.1)
+		assert.InDelta(t, -23.0, report.TruePeak, 0.1)
+		assert.InDelta(t, 0.0, report.Range, 0.1)
+	}
+}
+
+// a full scale sine in one channel is -3.01 LUFS
+func TestMeasure_Mono(t *testing.T) {
+	assert.InDelta(t, -3.01, Measure(testSine(997, 48000, 1, 5, 0), 48000, 1).Integrated, 0.05)
+}
+
+// the LFE is not measured, and the surround channels are 1.5dB louder
+func TestMeasure_Surround(t *testing.T) {
+	mono := testSine(1000, 48000, 1, 2, -20)
+	surround := make([]float64, 0, len(mono)*6)
+	for _, v := range mono {
+		surround = append(surround, 0, 0, 0, v, 0, 0)
+	}
+	assert.True(t, math.IsInf(Measure(surround, 48000, 6).Integrated, -1))
+	surround = surround[:0]
+	for _, v := range mono {
+		surround = append(surround, 0, 0, 0, 0, v, 0)
+	}
+	assert.InDelta(t, Measure(mono, 48000, 1).Integrated+1.5, Measure(surround, 48000, 6).Integrated, 0.05)
+}
+
+func TestMeasure_Silence(t *testing.T) {
+	report := Measure(make([]float64, 48000*2*2), 48000, 2)
+	assert.True(t, math.IsInf(report.Integrated, -1))
+	assert.True(t, math.IsInf(report.TruePeak, -1))
+	assert.Equal(t, float64(0), report.Range)
+}
+
+// 10s at -20dBFS then 10s at -30dBFS has a loudness range of 10 LU, as by EBU Tech 3342
+func TestMeasure_Range(t *testing.T) {
+	samples := append(testSine(1000, 44100, 2, 10, -20), testSine(1000, 44100, 2, 10, -30)...)
+	assert.InDelta(t, 10.0, Measure(samples, 44100, 2).Range, 0.1)
+}
+
+// the gating ignores a long silence, which would otherwise lower the integrated loudness
+func TestMeasure_Gate(t *testing.T) {
+	samples := append(testSine(1000, 48000, 2, 10, -23), make([]float64, 48000*2*10)...)
+	assert.InDelta(t, -23.0, Measure(samples, 48000, 2).Integrated, 0.1)
+}
+
+// a sine at a quarter of the frequency, whose samples straddle its peaks, has a true peak 3dB above its sample peak
+func TestMeasure_TruePeak(t *testing.T) {
+	samples := make([]float64, 48000)
+	for i := range samples {
+		samples[i] = 0.5 * math.Sin(math.Pi/2*float64(i)+math.Pi/4)
+	}
+	assert.InDelta(t, 20*math.Log10(0.5), Measure(samples, 48000, 1).TruePeak, 0.2)
+}
+
+func TestLUFS(t *testing.T) {
+	assert.InDelta(t, -0.691, LUFS(1), 1e-9)
+	assert.True(t, math.IsInf(LUFS(0), -1))
+}
+
+//
+// Test Components
+//
+
+// testSine interleaved in a # of channels, for a # of seconds, with a peak in dBFS
+func testSine(hz float64, freq float64, channels int, seconds float64, dbfs float64) []float64 {
+	amplitude := math.Pow(10, dbfs/20)
+	n := int(freq * seconds)
+	out := make([]float64, 0, n*channels)
+	for i := 0; i < n; i++ {
+		v := amplitude * math.Sin(2*math.Pi*hz*float64(i)/freq)
+		for c := 0; c < channels; c++ {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+// MixAlgorithm combines the sum of all buses, after the master volume, into the output from -1 to +1
+type MixAlgorithm uint
+
+const (
+	// MixLogarithmic is Paul Vögler's loudness normalization by logarithmic dynamic range compression, and the default:
+	// below the threshold, the sum is scaled by the inverse golden ratio, and above it, compressed logarithmically.
+	MixLogarithmic MixAlgorithm = iota
+	// MixHardClip is plain summation, clipped at -1 and +1.
+	MixHardClip
+	// MixLimiter is plain summation, with a lookahead limiter holding the peaks under the ceiling.
+	// The output is delayed by the lookahead.
+	MixLimiter
+)
+
+// ClipMode of the safety stage after compression, see SetClipMode
+type ClipMode = sample.ClipMode
+
+const (
+	ClipHard = sample.ClipHard // at -1 and +1, and the default
+	ClipSoft = sample.ClipSoft // by a tanh curve above sample.ClipSoftKnee
+	ClipNone = sample.ClipNone // float formats pass through beyond full scale; integer formats saturate
+)
+
+// DitherMode of the conversion to an integer output format, see SetDither
+type DitherMode = sample.DitherMode
+
+const (
+	DitherNone   = sample.DitherNone   // truncate, and the default
+	DitherTPDF   = sample.DitherTPDF   // triangular noise of ±1 LSB, recommended for 16-bit renders
+	DitherShaped = sample.DitherShaped // TPDF with first-order noise shaping
+)
+
+// MixParams tune the mix algorithms, and can be adjusted while mixing.
+type MixParams struct {
+	Threshold float64       // MixLogarithmic: level above which compression begins, from 0 to 1
+	Ceiling   float64       // MixLimiter: peak output level, from 0 to 1
+	Lookahead time.Duration // MixLimiter: time to see a peak coming, which delays the output
+	Release   time.Duration // MixLimiter: time constant to recover gain after a peak
+}
+
+// DefaultMixParams preserve the original behavior of the mixer
+func DefaultMixParams() MixParams {
+	return MixParams{
+		Threshold: 1,
+		Ceiling:   1,
+		Lookahead: 5 * time.Millisecond,
+		Release:   50 * time.Millisecond,
+	}
+}
+
+// SetMixAlgorithm to combine the sum of all buses into the output; the default is MixLogarithmic.
+func (m *Mixer) SetMixAlgorithm(alg MixAlgorithm) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixAlgorithm = alg
+	m.mixLimiterReset()
+}
+
+// GetMixAlgorithm that combines the sum of all buses into the output
+func (m *Mixer) GetMixAlgorithm() MixAlgorithm {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixAlgorithm
+}
+
+// SetMixParams to tune the mix algorithms; zero values are replaced by the defaults.
+func (m *Mixer) SetMixParams(p MixParams) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	d := DefaultMixParams()
+	if p.Threshold <= 0 {
+		p.Threshold = d.Threshold
+	}
+	if p.Ceiling <= 0 {
+		p.Ceiling = d.Ceiling
+	}
+	if p.Lookahead <= 0 {
+		p.Lookahead = d.Lookahead
+	}
+	if p.Release <= 0 {
+		p.Release = d.Release
+	}
+	p.Threshold = math.Min(1, p.Threshold)
+	p.Ceiling = math.Min(1, p.Ceiling)
+	m.mixParams = p
+}
+
+// GetMixParams that tune the mix algorithms
+func (m *Mixer) GetMixParams() MixParams {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixParams
+}
+
+// SetHeadroom in dB, by which the master is scaled down before compression, e.g. 6 for half the level, leaving room for stacks
+// of loud fires; the default is 0, and a negative headroom is 0.
+func (m *Mixer) SetHeadroom(db float64) {
+	db = math.Max(0, db)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixHeadroom = db
+	m.headroomGain = math.Pow(10, -db/20)
+}
+
+// GetHeadroom in dB, see SetHeadroom
+func (m *Mixer) GetHeadroom() float64 {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixHeadroom
+}
+
+// SetClipMode of the safety stage after compression, just before the conversion to the output format; the default is ClipHard.
+// Each output sample beyond full scale is counted in the stats, whatever the mode, see LoopStats.
+func (m *Mixer) SetClipMode(mode ClipMode) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixClipMode = mode
+}
+
+// GetClipMode of the safety stage, see SetClipMode
+func (m *Mixer) GetClipMode() ClipMode {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixClipMode
+}
+
+// SetDither of each output sample after the safety stage, as it is converted to an integer output format; the default is DitherNone.
+// The noise of each channel is independent, and drawn from the random seed, so a render is reproducible, see SetRandomSeed.
+func (m *Mixer) SetDither(mode DitherMode) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixDitherMode = mode
+	m.mixResetDither()
+}
+
+// GetDither mode of the conversion to an integer output format, see SetDither
+func (m *Mixer) GetDither() DitherMode {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixDitherMode
+}
+
+//
+// Private
+//
+
+// mixResetDither from the random seed, for the master channels, or none; the caller must hold the mixMutex
+func (m *Mixer) mixResetDither() {
+	m.mixDither = nil
+	if m.mixDitherMode != DitherNone && m.masterSpec != nil {
+		m.mixDither = sample.NewDither(m.mixDitherMode, m.masterSpec.Channels, m.mixRandomSeed)
+	}
+}
+
+// mixApplyAlgorithm to one sample of all channels, into out; the caller must hold the mixMutex
+func (m *Mixer) mixApplyAlgorithm(smp []sample.Value, out []sample.Value) {
+	switch m.mixAlgorithm {
+	case MixHardClip:
+		for c := range smp {
+			out[c] = sample.Value(math.Max(-1, math.Min(1, float64(smp[c]))))
+		}
+	case MixLimiter:
+		m.mixLimit(smp, out)
+	default:
+		for c := range smp {
+			out[c] = mixLogarithmicRangeCompression(smp[c], m.mixParams.Threshold)
+		}
+	}
+}
+
+// mixLimit one sample of all channels, delayed by the lookahead, with the gain needed for the loudest peak within the lookahead
+func (m *Mixer) mixLimit(smp []sample.Value, out []sample.Value) {
+	frames := int(m.mixParams.Lookahead.Seconds() * m.masterFreq)
+	if frames < 1 {
+		frames = 1
+	}
+	if len(m.limiterDelay) == 0 || frames != m.limiterFrames || m.masterFreq != m.limiterFreq || len(m.limiterDelay[0]) != len(smp) {
+		m.mixLimiterAllocate(frames, len(smp))
+	}
+	for c := range smp {
+		out[c] = m.limiterDelay[m.limiterAt][c]
+		m.limiterDelay[m.limiterAt][c] = smp[c]
+	}
+	m.limiterAt = (m.limiterAt + 1) % m.limiterFrames
+	peak := m.mixParams.Ceiling
+	for _, frame := range m.limiterDelay {
+		for _, v := range frame {
+			peak = math.Max(peak, math.Abs(float64(v)))
+		}
+	}
+	need := m.mixParams.Ceiling / peak
+	if need < m.limiterGain {
+		m.limiterGain = need
+	} else {
+		m.limiterGain += (need - m.limiterGain) * (1 - math.Exp(-1/(m.mixParams.Release.Seconds()*m.masterFreq)))
+	}
+	for c := range out {
+		limited := float64(out[c]) * m.limiterGain
+		out[c] = sample.Value(math.Max(-m.mixParams.Ceiling, math.Min(m.mixParams.Ceiling, limited)))
+	}
+}
+
+func (m *Mixer) mixLimiterAllocate(frames int, channels int) {
+	m.limiterDelay = make([][]sample.Value, frames)
+	for i := range m.limiterDelay {
+		m.limiterDelay[i] = make([]sample.Value, channels)
+	}
+	m.limiterFrames = frames
+	m.limiterFreq = m.masterFreq
+	m.limiterAt = 0
+	m.limiterGain = 1
+}
+
+func (m *Mixer) mixLimiterReset() {
+	m.limiterDelay = nil
+	m.limiterFrames = 0
+}
+
+// mixLogarithmicRangeCompression of a value, scaled such that the default threshold of 1 is the original curve
+func mixLogarithmicRangeCompression(i sample.Value, threshold float64) sample.Value {
+	t := sample.Value(threshold)
+	if i < -t {
+		return t * sample.Value(-math.Log(-float64(i/t)-0.85)/14-0.75)
+	} else if i > t {
+		return t * sample.Value(math.Log(float64(i/t)-0.85)/14+0.75)
+	} else {
+		return sample.Value(i / 1.61803398875)
+	}
+}
@@ -0,0 +1,154 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetMixAlgorithm(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, MixLogarithmic, GetMixAlgorithm())
+	SetMixAlgorithm(MixLimiter)
+	assert.Equal(t, MixLimiter, GetMixAlgorithm())
+	Teardown()
+	assert.Equal(t, MixLogarithmic, GetMixAlgorithm())
+}
+
+func TestSetMixParams(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, DefaultMixParams(), GetMixParams())
+	SetMixParams(MixParams{Threshold: 0.5, Ceiling: 2})
+	p := GetMixParams()
+	assert.Equal(t, 0.5, p.Threshold)
+	assert.Equal(t, float64(1), p.Ceiling)
+	assert.Equal(t, DefaultMixParams().Lookahead, p.Lookahead)
+	assert.Equal(t, DefaultMixParams().Release, p.Release)
+}
+
+func TestMixAlgorithm_RMS(t *testing.T) {
+	logarithmic, logarithmicPeak := testMixAlgorithmRMS(MixLogarithmic, DefaultMixParams())
+	hardClip, hardClipPeak := testMixAlgorithmRMS(MixHardClip, DefaultMixParams())
+	limiter, limiterPeak := testMixAlgorithmRMS(MixLimiter, DefaultMixParams())
+	assert.True(t, hardClip > limiter)
+	assert.True(t, limiter > logarithmic)
+	assert.Equal(t, float64(1), hardClipPeak)
+	assert.True(t, limiterPeak <= 1)
+	assert.True(t, logarithmicPeak < 1)
+	lowThreshold, _ := testMixAlgorithmRMS(MixLogarithmic, MixParams{Threshold: 0.5})
+	assert.True(t, lowThreshold < logarithmic)
+	lowCeiling, lowCeilingPeak := testMixAlgorithmRMS(MixLimiter, MixParams{Ceiling: 0.5})
+	assert.True(t, lowCeiling < limiter)
+	assert.True(t, lowCeilingPeak <= 0.5)
+}
+
+func TestSetHeadroom(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, float64(0), GetHeadroom())
+	_, peak := testMixStackPeak(ClipNone, 0)
+	assert.True(t, peak > 1) // a pathological stack overdrives even the logarithmic compression
+	_, peak = testMixStackPeak(ClipNone, 12)
+	assert.True(t, peak < 1)
+	SetHeadroom(-6)
+	assert.Equal(t, float64(0), GetHeadroom())
+	SetHeadroom(12)
+	Teardown()
+	assert.Equal(t, float64(0), GetHeadroom())
+}
+
+func TestSetClipMode(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, ClipHard, GetClipMode())
+	stats, peak := testMixStackPeak(ClipHard, 0)
+	assert.Equal(t, float64(1), peak)
+	assert.True(t, stats.Clipped > 0)
+	assert.Equal(t, stats.Clipped, stats.LastClipped)
+	soft, peak := testMixStackPeak(ClipSoft, 0)
+	assert.True(t, peak > sample.ClipSoftKnee && peak < 1)
+	assert.Equal(t, stats.Clipped, soft.Clipped) // counted whatever the mode
+	_, peak = testMixStackPeak(ClipNone, 0)
+	assert.True(t, peak > 1)
+	none, _ := testMixStackPeak(ClipNone, 12)
+	assert.Equal(t, int64(0), none.Clipped)
+	Teardown()
+	assert.Equal(t, ClipHard, GetClipMode())
+}
+
+func TestSetDither(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, DitherNone, GetDither())
+	truncated := testMixDitherRender(DitherNone)
+	dithered := testMixDitherRender(DitherTPDF)
+	assert.Equal(t, dithered, testMixDitherRender(DitherTPDF)) // reproducible, from the random seed
+	assert.NotEqual(t, truncated, dithered)
+	for _, v := range dithered {
+		assert.Equal(t, math.Round(v*0x8000), v*0x8000)
+	}
+	SetRandomSeed(2)
+	assert.NotEqual(t, dithered, testMixDitherRender(DitherTPDF))
+	assert.Equal(t, DitherTPDF, GetDither())
+	Teardown()
+	assert.Equal(t, DitherNone, GetDither())
+}
+
+func TestMixLogarithmicRangeCompression(t *testing.T) {
+	assert.Equal(t, sample.Value(0.5/1.61803398875), mixLogarithmicRangeCompression(0.5, 1))
+	assert.Equal(t, sample.Value(math.Log(2-0.85)/14+0.75), mixLogarithmicRangeCompression(2, 1))
+	assert.Equal(t, sample.Value(-math.Log(2-0.85)/14-0.75), mixLogarithmicRangeCompression(-2, 1))
+	assert.Equal(t, sample.Value(0.5*(math.Log(2-0.85)/14+0.75)), mixLogarithmicRangeCompression(1, 0.5))
+}
+
+//
+// Private
+//
+
+// testMixAlgorithmRMS of two overlapping tones, in phase, which sum to a peak of 1.6
+func testMixAlgorithmRMS(alg MixAlgorithm, params MixParams) (rms float64, peak float64) {
+	testMixSetup()
+	SetMixAlgorithm(alg)
+	SetMixParams(params)
+	SetFireTone(441, 100*time.Millisecond, 500*time.Millisecond, 0.8, 0)
+	SetFireTone(441, 100*time.Millisecond, 500*time.Millisecond, 0.8, 0)
+	out, _ := Render(time.Second)
+	var sumSquares float64
+	for _, v := range out {
+		sumSquares += v * v
+		peak = math.Max(peak, math.Abs(v))
+	}
+	rms = math.Sqrt(sumSquares / float64(len(out)))
+	return
+}
+
+// testMixDitherRender of a quiet tone to S16, with a dither mode
+func testMixDitherRender(mode DitherMode) []float64 {
+	seed := mixDefault.mixRandomSeed
+	Teardown()
+	Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 1})
+	SetRandomSeed(seed)
+	SetDither(mode)
+	SetFireTone(441, 0, 100*time.Millisecond, 0.001, 0)
+	out, _ := Render(100 * time.Millisecond)
+	return out
+}
+
+// testMixStackPeak of a stack of fifty loud tones, in phase, with a clip mode and headroom, and the stats of the one mix cycle
+func testMixStackPeak(mode ClipMode, headroom float64) (stats LoopStats, peak float64) {
+	testMixSetup()
+	SetClipMode(mode)
+	SetHeadroom(headroom)
+	SetCycleDuration(50 * time.Millisecond)
+	for i := 0; i < 50; i++ {
+		SetFireTone(100, 0, 40*time.Millisecond, 1, 0)
+	}
+	out, _ := Render(60 * time.Millisecond)
+	for _, v := range out {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return Stats(), peak
+}
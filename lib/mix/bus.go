@@ -0,0 +1,217 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/lib/effect"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// Bus is a group of fires, summed together and then mixed into the master with its own volume, pan, mute and solo.
+// Fires that are not set on a bus play on the default master bus.
+type Bus struct {
+	mixer   *Mixer
+	name    string
+	index   int // in the list of buses
+	volume  float64
+	pan     float64
+	muted   bool
+	soloed  bool
+	gain    float64 // ramps toward the volume (or zero if silenced) to avoid clicks
+	duck    float64 // reduction in dB by the buses that trigger its ducking, see SetDucking
+	sum     []sample.Value
+	effects []effect.Effect
+}
+
+// NewBus with a name, at full volume and center pan; if a bus already has the name, that bus is returned.
+func (m *Mixer) NewBus(name string) *Bus {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if b, ok := m.mixBuses[name]; ok {
+		return b
+	}
+	b := &Bus{mixer: m, name: name, index: len(m.mixBusList), volume: 1, gain: 1}
+	m.mixBuses[name] = b
+	m.mixBusList = append(m.mixBusList, b)
+	return b
+}
+
+// SetFireOnBus is SetFire, played on a bus; a nil bus is the default master bus.
+func (m *Mixer) SetFireOnBus(bus *Bus, source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFireOnBus(%s) failed: %s", source, err)
+		return nil
+	}
+	if bus != nil {
+		f.Bus = bus.name
+	}
+	m.mixSchedule(f)
+	return f
+}
+
+// Name of the bus
+func (b *Bus) Name() string {
+	return b.name
+}
+
+// SetVolume of the bus from 0 to 1, applied to the sum of its fires.
+func (b *Bus) SetVolume(v float64) {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.volume = math.Max(0, v)
+}
+
+// GetVolume of the bus
+func (b *Bus) GetVolume() float64 {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	return b.volume
+}
+
+// SetPan of the bus from -1 (left) to +1 (right), balancing the sum of its fires.
+func (b *Bus) SetPan(p float64) {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.pan = math.Max(-1, math.Min(1, p))
+}
+
+// GetPan of the bus
+func (b *Bus) GetPan() float64 {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	return b.pan
+}
+
+// Mute the bus, ramping it to silence to avoid clicks.
+func (b *Bus) Mute() {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.muted = true
+}
+
+// Unmute the bus
+func (b *Bus) Unmute() {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.muted = false
+}
+
+// IsMuted bus?
+func (b *Bus) IsMuted() bool {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	return b.muted
+}
+
+// Solo the bus; while any bus is soloed, all buses that are not soloed are silenced.
+func (b *Bus) Solo() {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.soloed = true
+}
+
+// Unsolo the bus
+func (b *Bus) Unsolo() {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.soloed = false
+}
+
+// IsSoloed bus?
+func (b *Bus) IsSoloed() bool {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	return b.soloed
+}
+
+//
+// Private
+//
+
+func (m *Mixer) mixClearBuses() {
+	m.mixMasterBus = &Bus{mixer: m, volume: 1, gain: 1}
+	m.mixBuses = map[string]*Bus{"": m.mixMasterBus}
+	m.mixBusList = []*Bus{m.mixMasterBus}
+	m.mixDuckers = nil
+}
+
+// mixBusOf a fire, or the default master bus
+func (m *Mixer) mixBusOf(f *fire.Fire) *Bus {
+	if b, ok := m.mixBuses[f.Bus]; ok {
+		return b
+	}
+	return m.mixMasterBus
+}
+
+// mixSumBuses into one sample for the master, with the gain, ducking and pan of each bus, and reset each bus sum for the next sample
+func (m *Mixer) mixSumBuses(smp []sample.Value) {
+	channels := len(smp)
+	for c := range smp {
+		smp[c] = 0
+	}
+	anySoloed := false
+	for _, b := range m.mixBusList {
+		if b.soloed {
+			anySoloed = true
+			break
+		}
+	}
+	m.mixDuck()
+	for _, b := range m.mixBusList {
+		b.rampGain(anySoloed)
+		if len(b.sum) != channels {
+			b.sum = make([]sample.Value, channels)
+			continue
+		}
+		m.mixProcessEffects(b.effects, b.sum)
+		gain := b.gain
+		if b.duck > 0 {
+			gain *= math.Pow(10, -b.duck/20)
+		}
+		for c := 0; c < channels; c++ {
+			smp[c] += b.sum[c] * sample.Value(gain*busPanGain(m.mixPanLaw, c, channels, b.pan))
+			b.sum[c] = 0
+		}
+	}
+}
+
+// add a fire sample to the sum of the bus
+func (b *Bus) add(values []sample.Value) {
+	if len(b.sum) != len(values) {
+		b.sum = make([]sample.Value, len(values))
+	}
+	for c := range values {
+		b.sum[c] += values[c]
+	}
+}
+
+func (b *Bus) rampGain(anySoloed bool) {
+	target := b.volume
+	if b.muted || (anySoloed && !b.soloed) {
+		target = 0
+	}
+	if b.gain < target {
+		b.gain = math.Min(target, b.gain+b.mixer.masterGainStep)
+	} else if b.gain > target {
+		b.gain = math.Max(target, b.gain-b.mixer.masterGainStep)
+	}
+}
+
+// busPanGain of a channel by a pan law, from the first (left) to the last (right) channel; by the linear law, it balances
+// each channel, even with more than two channels; a mono bus is not panned
+func busPanGain(law PanLaw, channel int, channels int, pan float64) float64 {
+	if law != PanLinear {
+		return source.PanGain(law, channel, channels, pan)
+	}
+	if pan == 0 || channels < 2 {
+		return 1
+	}
+	position := float64(channel)/float64(channels-1)*2 - 1 // -1 for the first channel, +1 for the last
+	return math.Min(1, 1+pan*position)
+}
@@ -0,0 +1,118 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestNewBus(t *testing.T) {
+	testMixSetup()
+	hats := NewBus("hats")
+	assert.Equal(t, "hats", hats.Name())
+	assert.Equal(t, hats, NewBus("hats"))
+	assert.Equal(t, float64(1), hats.GetVolume())
+	assert.Equal(t, float64(0), hats.GetPan())
+	hats.SetVolume(0.5)
+	hats.SetPan(-2)
+	assert.Equal(t, 0.5, hats.GetVolume())
+	assert.Equal(t, float64(-1), hats.GetPan())
+	Teardown()
+	assert.NotEqual(t, hats, NewBus("hats"))
+}
+
+func TestSetFireOnBus(t *testing.T) {
+	testMixSetup()
+	perc := NewBus("perc")
+	f := SetFireOnBus(perc, "../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 0, 1.0, 0)
+	assert.Equal(t, "perc", f.Bus)
+	assert.Equal(t, "", SetFireOnBus(nil, "../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 0, 1.0, 0).Bus)
+	assert.Nil(t, SetFireOnBus(perc, "../source/testdata/ThisShouldFailBecauseItDoesNotExist.wav", 0, 0, 1.0, 0))
+}
+
+func TestBus_Volume(t *testing.T) {
+	testMixSetup()
+	whole := testBusPeak(nil)
+	testMixSetup()
+	perc := NewBus("perc")
+	perc.SetVolume(0.5)
+	assert.InDelta(t, whole/2, testBusPeak(perc), 0.01)
+}
+
+func TestBus_Mute(t *testing.T) {
+	testMixSetup()
+	hats := NewBus("hats")
+	hats.Mute()
+	assert.True(t, hats.IsMuted())
+	assert.Equal(t, float64(0), testBusPeak(hats))
+	hats.Unmute()
+	assert.False(t, hats.IsMuted())
+}
+
+func TestBus_Solo(t *testing.T) {
+	testMixSetup()
+	snare := NewBus("snare")
+	kick := NewBus("kick")
+	snare.Solo()
+	assert.True(t, snare.IsSoloed())
+	assert.Equal(t, float64(0), testBusPeak(kick))
+	assert.Equal(t, float64(0), testBusPeak(nil)) // the default master bus is silenced too
+	assert.True(t, testBusPeak(snare) > 0)
+	snare.Unsolo()
+	assert.False(t, snare.IsSoloed())
+	assert.True(t, testBusPeak(kick) > 0)
+}
+
+func TestBus_Pan(t *testing.T) {
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 2,
+	})
+	left := NewBus("left")
+	left.SetPan(-1)
+	SetFireOnBus(left, "../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 0, 1.0, 0)
+	var peak [2]sample.Value
+	for n := 0; n < 44100; n++ {
+		smp := NextSample()
+		for c := range peak {
+			if smp[c].Abs() > peak[c] {
+				peak[c] = smp[c].Abs()
+			}
+		}
+	}
+	assert.True(t, peak[0] > 0)
+	assert.Equal(t, sample.Value(0), peak[1])
+}
+
+func TestBusPanGain(t *testing.T) {
+	assert.Equal(t, float64(1), busPanGain(PanLinear, 0, 1, -1))
+	assert.Equal(t, float64(1), busPanGain(PanLinear, 0, 2, -1))
+	assert.Equal(t, float64(0), busPanGain(PanLinear, 1, 2, -1))
+	assert.Equal(t, 0.5, busPanGain(PanLinear, 0, 2, 0.5))
+	assert.Equal(t, float64(1), busPanGain(PanLinear, 1, 2, 0.5))
+}
+
+//
+// Private
+//
+
+// testBusPeak of the mix output while a fire plays on a bus, after the gain of the bus has ramped
+func testBusPeak(bus *Bus) (peak float64) {
+	for n := 0; n < 44100/10; n++ {
+		NextSample()
+	}
+	SetFireOnBus(bus, "../source/testdata/Signed16bitLittleEndian44100HzMono.wav", GetNowAt()+100*time.Millisecond, 0, 1.0, 0)
+	for n := 0; n < 44100; n++ {
+		if v := float64(NextSample()[0].Abs()); v > peak {
+			peak = v
+		}
+	}
+	return
+}
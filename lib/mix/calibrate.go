@@ -0,0 +1,38 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// Calibration signal, synthesized without any file, e.g. to bring up an output binding or to check its channel mapping
+type Calibration uint
+
+const (
+	ToneLeft1k  Calibration = iota // a 1kHz sine panned hard left, on the left channel only by any pan law but PanLinear
+	ToneRight1k                    // a 1kHz sine panned hard right, on the right channel only by any pan law but PanLinear
+	PinkNoise                      // on every channel
+	Sweep                          // a logarithmic sine sweep from 20Hz to 20kHz, or the highest frequency of the output, on every channel
+)
+
+// CalibrationVolume of a calibration signal, i.e. -6dBFS before the mix algorithm
+const CalibrationVolume = 0.5
+
+// Calibrate by playing a calibration signal now for a duration, through the normal path of fires, so it is heard after any output latency.
+// The left and right channels are panned hard to a side, by the pan law, e.g. PanBalance, see SetPanLaw. See package analyze to measure the output.
+func (m *Mixer) Calibrate(signal Calibration, d time.Duration) *fire.Fire {
+	switch signal {
+	case ToneLeft1k:
+		return m.FireNow(source.ToneKey(source.WaveSine, 1000, d), d, CalibrationVolume, -1)
+	case ToneRight1k:
+		return m.FireNow(source.ToneKey(source.WaveSine, 1000, d), d, CalibrationVolume, 1)
+	case PinkNoise:
+		return m.FireNow(source.ToneKey(source.WavePink, 0, d), d, CalibrationVolume, 0)
+	case Sweep:
+		return m.FireNow(source.ToneKey(source.WaveSweep, 20000, d), d, CalibrationVolume, 0)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/analyze"
+)
+
+func TestCalibrate_Tone(t *testing.T) {
+	left := testCalibrate(ToneLeft1k)
+	assert.InDelta(t, float64(1000), analyze.DetectDominantFreq(analyze.Channel(left, 2, 0), 44100), 1)
+	assert.Equal(t, float64(0), analyze.MeasurePeak(left, 2)[1])
+	right := testCalibrate(ToneRight1k)
+	assert.Equal(t, float64(0), analyze.MeasurePeak(right, 2)[0])
+	assert.InDelta(t, float64(1000), analyze.DetectDominantFreq(analyze.Channel(right, 2, 1), 44100), 1)
+}
+
+func TestCalibrate_PinkNoise(t *testing.T) {
+	rms := analyze.MeasureRMS(testCalibrate(PinkNoise), 2)
+	assert.True(t, rms[0] > 0.01)
+	assert.Equal(t, rms[0], rms[1])
+}
+
+func TestCalibrate_Sweep(t *testing.T) {
+	left := analyze.Channel(testCalibrate(Sweep), 2, 0)
+	assert.True(t, analyze.DetectDominantFreq(left[:4410], 44100) < 100)
+	assert.True(t, analyze.DetectDominantFreq(left[len(left)-4410:], 44100) > 5000)
+}
+
+//
+// Test Components
+//
+
+// testCalibrate renders a second of a calibration signal in stereo
+func testCalibrate(signal Calibration) []float64 {
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 2,
+	})
+	SetPanLaw(PanBalance)
+	Calibrate(signal, time.Second)
+	out, _ := Render(time.Second)
+	return out
+}
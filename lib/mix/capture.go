@@ -0,0 +1,92 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// CapturedFire is a fire that was set during a capture, e.g. a pad tapped live, for ReplayCapture.
+type CapturedFire struct {
+	Source  string        // as it was set
+	Begin   time.Duration // the mixer time it was set to begin, since the start of the capture
+	Sustain time.Duration // or 0 for the natural length of the source
+	Volume  float64
+	Pan     float64
+	Step    int           // nearest to the mixer time it was set to begin, by the tempo map, e.g. to quantize with SetFireAtStep
+	Length  time.Duration // of the whole capture, from StartCapture to StopCapture, the interval of each repeat in ReplayCapture
+}
+
+// StartCapture of every fire set from now on, until StopCapture, discarding any capture in progress.
+func (m *Mixer) StartCapture() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixCapturing = true
+	m.mixCaptureStart = m.mixSchedDurOf(m.nowTz)
+	m.mixCaptured = nil
+}
+
+// StopCapture and return the fires that were set since StartCapture, in the order they were set; each has the time it was intended
+// to begin, by the mixer clock, regardless of when it was set by the wall clock.
+func (m *Mixer) StopCapture() (events []CapturedFire) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if !m.mixCapturing {
+		return nil
+	}
+	length := m.mixSchedDurOf(m.nowTz) - m.mixCaptureStart
+	events = m.mixCaptured
+	for i := range events {
+		events[i].Length = length
+	}
+	m.mixCapturing = false
+	m.mixCaptured = nil
+	return
+}
+
+// ReplayCapture of fires, each set like SetFire at an offset plus the time it began in the capture, and again after the length of the
+// capture for a total # of repeats. The fires of a replay are not themselves captured, so a looper can overdub a replay.
+func (m *Mixer) ReplayCapture(events []CapturedFire, atOffset time.Duration, repeat int) {
+	var fires []*fire.Fire
+	for r := 0; r < repeat; r++ {
+		for _, e := range events {
+			f, err := m.mixNewFire(e.Source, atOffset+time.Duration(r)*e.Length+e.Begin, e.Sustain, e.Volume, e.Pan)
+			if err != nil {
+				debug.Warnf("mix.ReplayCapture(%s) failed: %s", e.Source, err)
+				continue
+			}
+			fires = append(fires, f)
+		}
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixPushFires(fires)
+}
+
+//
+// Private
+//
+
+// mixCaptureFires if a capture is in progress; the caller must hold the mixMutex
+func (m *Mixer) mixCaptureFires(fires []*fire.Fire) {
+	if !m.mixCapturing {
+		return
+	}
+	for _, f := range fires {
+		begin := m.mixSchedDurOf(f.BeginTz)
+		var sustain time.Duration
+		if f.EndTz != 0 {
+			sustain = m.mixSchedDurOf(f.EndTz - f.BeginTz)
+		}
+		m.mixCaptured = append(m.mixCaptured, CapturedFire{
+			Source:  m.mixSourceName(f.Source),
+			Begin:   begin - m.mixCaptureStart,
+			Sustain: sustain,
+			Volume:  f.Volume,
+			Pan:     f.Pan,
+			Step:    m.mixStepNearest(begin),
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestStartCapture(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetFire(src, 0, 0, 1.0, 0) // before the capture
+	testMixFor(500 * time.Millisecond)
+	StartCapture()
+	SetFire(src, mixDefault.mixDurOf(mixDefault.nowTz)+130*time.Millisecond, 50*time.Millisecond, 0.5, -0.5)
+	testMixFor(250 * time.Millisecond)
+	SetFire(src, mixDefault.mixDurOf(mixDefault.nowTz), 0, 0.8, 0.5)
+	testMixFor(250 * time.Millisecond)
+	events := StopCapture()
+	assert.Equal(t, []CapturedFire{
+		{Source: src, Begin: 130 * time.Millisecond, Sustain: 50 * time.Millisecond, Volume: 0.5, Pan: -0.5, Step: 5, Length: time.Second / 2},
+		{Source: src, Begin: 250 * time.Millisecond, Volume: 0.8, Pan: 0.5, Step: 6, Length: time.Second / 2},
+	}, events)
+	assert.Nil(t, StopCapture())
+}
+
+func TestReplayCapture(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	events := []CapturedFire{
+		{Source: src, Begin: 100 * time.Millisecond, Volume: 0.5, Length: time.Second},
+		{Source: src, Begin: 500 * time.Millisecond, Sustain: 50 * time.Millisecond, Volume: 1, Pan: 1, Length: time.Second},
+	}
+	StartCapture()
+	ReplayCapture(events, 10*time.Second, 2)
+	assert.Equal(t, 0, len(StopCapture())) // a replay is not captured
+	fires := Fires()
+	assert.Equal(t, 4, len(fires))
+	assert.Equal(t, 10100*time.Millisecond, fires[0].BeginAt())
+	assert.Equal(t, 10500*time.Millisecond, fires[1].BeginAt())
+	assert.Equal(t, 50*time.Millisecond, fires[1].Sustain())
+	assert.Equal(t, float64(1), fires[1].Pan)
+	assert.Equal(t, 11100*time.Millisecond, fires[2].BeginAt())
+	assert.Equal(t, 11500*time.Millisecond, fires[3].BeginAt())
+}
+
+//
+// Test Components
+//
+
+// testMixFor a duration of samples
+func testMixFor(d time.Duration) {
+	for end := mixDefault.nowTz + mixDefault.mixTzOf(d); mixDefault.nowTz < end; {
+		NextSample()
+	}
+}
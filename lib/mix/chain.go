@@ -0,0 +1,210 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"sort"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// SetFireAfter is SetFire, beginning a gap of time after a previous fire ends, e.g. a line of dialogue 300ms after the one before it,
+// without knowing how long that one is. The begin is resolved once the end of the previous fire is known: at once, if its source is
+// loaded, else when it goes live, e.g. a multi-sample source picks a variant, or at the latest when it is done. A chain of fires is
+// resolved in order. A negative gap overlaps the end of the previous fire. If the previous fire is canceled, so is this one, and so on
+// down the chain. Returns nil if there is no previous fire, e.g. SetFire failed to load its source, or it loops until canceled, so
+// never ends, or the source of this fire cannot be loaded; each is only logged as a warning, so a chain after a failed fire is nil.
+func (m *Mixer) SetFireAfter(prev *fire.Fire, gap time.Duration, source string, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	if prev == nil {
+		debug.Warnf("mix.SetFireAfter(%s) failed: no previous fire", source)
+		return nil
+	}
+	if prev.LoopsForever() {
+		debug.Warnf("mix.SetFireAfter(%s) failed: previous fire loops until canceled", source)
+		return nil
+	}
+	f, err := m.mixNewFire(source, 0, sustain, volume, pan) // the begin is not yet known
+	if err != nil {
+		debug.Warnf("mix.SetFireAfter(%s) failed: %s", source, err)
+		return nil
+	}
+	m.mixChainAfter(prev, f, gap)
+	return f
+}
+
+//
+// Private
+//
+
+// mixChained fire, waiting to begin a gap after a previous fire ends
+type mixChained struct {
+	fire  *fire.Fire
+	gapTz int64 // negative to overlap the end of the previous fire
+}
+
+// mixChainAfter a previous fire, a fire that begins a gap after it ends, created to begin at 0, see SetFireAfter
+func (m *Mixer) mixChainAfter(prev *fire.Fire, f *fire.Fire, gap time.Duration) {
+	m.mixMutex.Lock()
+	gapTz := int64(m.mixSchedTzOf(gap))
+	if gap < 0 {
+		gapTz = -int64(m.mixSchedTzOf(-gap))
+	}
+	m.mixChainWaiting[f] = prev
+	m.mixChains[prev] = append(m.mixChains[prev], mixChained{f, gapTz})
+	m.mixMutex.Unlock()
+	prev.OnDone(func(p *fire.Fire) { // may be called at once, if the previous fire is already done
+		if p.DoneReason() == fire.DoneCanceled && f.IsAlive() {
+			f.Cancel()
+		}
+		m.mixMutex.Lock()
+		defer m.mixMutex.Unlock()
+		m.mixResolveChains(p, true)
+	})
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixResolveChains(prev, false)
+}
+
+// mixRecordChains of the fires waiting on a previous fire, appended to the records of the fires, each of the fire it records or nil,
+// with times since a mix time, e.g. zero. The records are sorted by their beginning, and each waiting fire is appended after the
+// one it waits on, by its index among the records, see fire.RecordAfter. The first of a chain that waits on a fire that is not
+// recorded, e.g. one that is playing, is recorded to begin when that one is predicted to end, or now if that is not known.
+// The caller must hold the mixMutex
+func (m *Mixer) mixRecordChains(records []fire.Record, fires []*fire.Fire, since time.Duration) []fire.Record {
+	index := make(map[*fire.Fire]bool, len(fires))
+	for _, f := range fires {
+		if f != nil {
+			index[f] = true
+		}
+	}
+	for f, prev := range m.mixChainWaiting {
+		if _, waiting := m.mixChainWaiting[prev]; waiting || index[prev] || f.IsCanceled() {
+			continue
+		}
+		beginTz := int64(m.mixEarliestTz())
+		if endTz, known := m.mixFireEndTz(prev); known {
+			beginTz = int64(endTz)
+		}
+		for _, c := range m.mixChains[prev] {
+			if c.fire == f {
+				beginTz += c.gapTz
+			}
+		}
+		if beginTz < 0 {
+			beginTz = 0
+		}
+		record := m.mixRecordOf(f)
+		record.Begin = m.mixSchedDurOf(spec.Tz(beginTz)) - since
+		records = append(records, record)
+		fires = append(fires, f)
+	}
+	order := make([]int, len(records))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return records[order[i]].Begin < records[order[j]].Begin
+	})
+	sorted := make([]fire.Record, 0, len(records))
+	sortedFires := make([]*fire.Fire, 0, len(fires))
+	for _, i := range order {
+		sorted = append(sorted, records[i])
+		sortedFires = append(sortedFires, fires[i])
+	}
+	for i := 0; i < len(sortedFires); i++ {
+		if sortedFires[i] == nil {
+			continue
+		}
+		for _, c := range m.mixChains[sortedFires[i]] {
+			if c.fire.IsCanceled() {
+				continue
+			}
+			record := m.mixRecordOf(c.fire)
+			record.Begin = 0 // not yet known
+			record.After = &fire.RecordAfter{Fire: i, Gap: m.mixGapDurOf(c.gapTz)}
+			sorted = append(sorted, record)
+			sortedFires = append(sortedFires, c.fire)
+		}
+	}
+	return sorted
+}
+
+// mixScheduleChains of fires restored from their records, each of the record at its index, or nil if it was not restored:
+// each that waited on a previous fire waits on it again, after the rest are scheduled, or is dropped if that one was not restored,
+// as SetFireAfter fails without a previous fire
+func (m *Mixer) mixScheduleChains(records []fire.Record, fires []*fire.Fire) {
+	var unchained []*fire.Fire
+	for i, f := range fires {
+		if after := records[i].After; after != nil && (after.Fire < 0 || after.Fire >= i || fires[after.Fire] == nil) {
+			fires[i] = nil
+		} else if f != nil && after == nil {
+			unchained = append(unchained, f)
+		}
+	}
+	m.mixSchedule(unchained...)
+	for i, f := range fires {
+		if after := records[i].After; f != nil && after != nil {
+			m.mixChainAfter(fires[after.Fire], f, after.Gap)
+		}
+	}
+}
+
+// mixGapDurOf a gap in Tz, negative to overlap, in time of the schedule
+func (m *Mixer) mixGapDurOf(gapTz int64) time.Duration {
+	if gapTz < 0 {
+		return -m.mixSchedDurOf(spec.Tz(-gapTz))
+	}
+	return m.mixSchedDurOf(spec.Tz(gapTz))
+}
+
+// mixResolveChains of the fires waiting on a previous fire, if its end is known, or if it is done, in which case they begin as soon
+// as they can, or are canceled with it; each one is scheduled, and in turn resolves the fires waiting on it. The caller must hold the mixMutex.
+func (m *Mixer) mixResolveChains(prev *fire.Fire, done bool) {
+	chained := m.mixChains[prev]
+	if len(chained) == 0 {
+		return
+	}
+	if _, waiting := m.mixChainWaiting[prev]; waiting {
+		return // its own begin is not yet known
+	}
+	endTz, known := prev.End()
+	if !known && !done {
+		return
+	}
+	if !known {
+		endTz = m.mixEarliestTz()
+	}
+	canceled := prev.DoneReason() == fire.DoneCanceled
+	delete(m.mixChains, prev)
+	for _, c := range chained {
+		delete(m.mixChainWaiting, c.fire)
+		if canceled && c.fire.IsAlive() {
+			c.fire.Cancel()
+		}
+		if c.fire.IsCanceled() {
+			continue
+		}
+		beginTz := int64(endTz) + c.gapTz
+		if beginTz < 0 {
+			beginTz = 0
+		}
+		c.fire.BeginTz = spec.Tz(beginTz)
+		if c.fire.EndTz != 0 {
+			c.fire.EndTz += c.fire.BeginTz
+		}
+		m.mixCaptureFires([]*fire.Fire{c.fire})
+		m.mixPushFires([]*fire.Fire{c.fire})
+		m.mixResolveChains(c.fire, false)
+	}
+}
+
+// mixCancelChains of the fires waiting on a previous fire, and forget all chains; the caller must hold the mixMutex
+func (m *Mixer) mixCancelChains() {
+	for f := range m.mixChainWaiting {
+		f.Cancel()
+	}
+	m.mixChains = make(map[*fire.Fire][]mixChained)
+	m.mixChainWaiting = make(map[*fire.Fire]*fire.Fire)
+}
@@ -0,0 +1,122 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetFireAfter(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	first := SetFire(src, 50*time.Millisecond, 0, 1.0, 0)
+	second := SetFireAfter(first, 300*time.Millisecond, src, 0, 1.0, 0)
+	third := SetFireAfter(second, -20*time.Millisecond, src, 50*time.Millisecond, 1.0, 0)
+	assert.Equal(t, 450*time.Millisecond, second.BeginAt())
+	assert.Equal(t, 530*time.Millisecond, third.BeginAt())
+	assert.Equal(t, 50*time.Millisecond, third.Sustain())
+	assert.Nil(t, SetFireAfter(nil, 0, src, 0, 1.0, 0))
+	forever := SetFireLoop(src, 0, time.Second, -1, 0, 1.0, 0)
+	assert.Nil(t, SetFireAfter(forever, 0, src, 0, 1.0, 0))
+	out, err := Render(600 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), out[mixDefault.mixTzOf(400*time.Millisecond)])
+	assert.NotEqual(t, float64(0), out[mixDefault.mixTzOf(460*time.Millisecond)])
+}
+
+func TestSetFireAfter_MultiSource(t *testing.T) {
+	testMixSetup()
+	testMultiSource()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	kick := SetFire("kick", 10*time.Millisecond, 0, 0.9, 0)
+	second := SetFireAfter(kick, 100*time.Millisecond, src, 0, 1.0, 0)
+	third := SetFireAfter(second, 0, src, 0, 1.0, 0)
+	assert.Equal(t, kick, mixDefault.mixChainWaiting[second]) // the variant, so the length, is not yet known
+	assert.Equal(t, second, mixDefault.mixChainWaiting[third])
+	Render(20 * time.Millisecond)
+	assert.Empty(t, mixDefault.mixChainWaiting)
+	assert.Equal(t, 160*time.Millisecond, second.BeginAt()) // after a 50ms variant
+	assert.Equal(t, 260*time.Millisecond, third.BeginAt())
+}
+
+func TestSetFireAfter_Cancel(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	first := SetFire(src, time.Second, 0, 1.0, 0)
+	second := SetFireAfter(first, 0, src, 0, 1.0, 0)
+	third := SetFireAfter(second, 0, src, 0, 1.0, 0)
+	done := make(chan *fire.Fire, 1)
+	third.OnDone(func(f *fire.Fire) {
+		done <- f
+	})
+	first.Cancel()
+	select {
+	case f := <-done:
+		assert.Equal(t, fire.DoneCanceled, f.DoneReason())
+		assert.True(t, second.IsCanceled())
+	case <-time.After(time.Second):
+		t.Fatal("chained fire was not canceled")
+	}
+}
+
+func TestSetFireAfter_Teardown(t *testing.T) {
+	testMixSetup()
+	testMultiSource()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	waiting := SetFireAfter(SetFire("kick", time.Second, 0, 0.9, 0), 0, src, 0, 1.0, 0)
+	Teardown()
+	assert.True(t, waiting.IsCanceled())
+	assert.Empty(t, mixDefault.mixChainWaiting)
+}
+
+func TestSetFireAfter_Record(t *testing.T) {
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	for _, tc := range []struct {
+		name    string
+		save    func(m *Mixer, w io.Writer) error
+		restore func(m *Mixer, r io.Reader) error
+	}{
+		{"schedule", (*Mixer).ExportSchedule, func(m *Mixer, r io.Reader) error {
+			m.RegisterMultiSource("kick", []Layer{{Sources: []string{testMultiTone(60)}, MinVelocity: 0, MaxVelocity: 1}})
+			return m.ImportSchedule(r, 0)
+		}},
+		{"session", (*Mixer).SaveSession, (*Mixer).LoadSession},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer a.Teardown()
+			a.RegisterMultiSource("kick", []Layer{{Sources: []string{testMultiTone(60)}, MinVelocity: 0, MaxVelocity: 1}})
+			kick := a.SetFire("kick", 10*time.Millisecond, 0, 0.9, 0)
+			second := a.SetFireAfter(kick, 100*time.Millisecond, src, 0, 1.0, 0)
+			a.SetFireAfter(second, -20*time.Millisecond, src, 50*time.Millisecond, 1.0, 0)
+			assert.Equal(t, 2, len(a.mixChainWaiting)) // the variant, so the length, is not yet known
+			var saved bytes.Buffer
+			assert.Nil(t, tc.save(a, &saved))
+			b, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer b.Teardown()
+			assert.Nil(t, tc.restore(b, bytes.NewReader(saved.Bytes())))
+			assert.Equal(t, 2, len(b.mixChainWaiting))
+			assert.Equal(t, a.ScheduleEnd(), b.ScheduleEnd())
+			var resaved bytes.Buffer
+			assert.Nil(t, tc.save(b, &resaved))
+			assert.JSONEq(t, saved.String(), resaved.String())
+			b.OutputContinueTo(20 * time.Millisecond)
+			assert.Empty(t, b.mixChainWaiting)
+			fires := b.Fires()
+			assert.Equal(t, 3, len(fires))
+			assert.Equal(t, 160*time.Millisecond, fires[1].BeginAt()) // after a 50ms variant
+			assert.Equal(t, 240*time.Millisecond, fires[2].BeginAt())
+			assert.Equal(t, 50*time.Millisecond, fires[2].Sustain())
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// DefaultChokeFade of a fire that is choked by another fire of its choke group, see SetChokeFade
+const DefaultChokeFade = 5 * time.Millisecond
+
+// SetChokeGroup of sources: when a fire of any of them starts playing, every other fire of the group that is playing
+// fades out over the choke fade, e.g. a closed hi-hat cuts off an open one. A fire's own group, see fire.SetChoke,
+// takes precedence over the group of its source. A source is in at most one group; with no sources, the group is removed.
+func (m *Mixer) SetChokeGroup(group string, sources ...string) {
+	keys := make([]string, len(sources))
+	for i, src := range sources {
+		keys[i] = m.mixSourceKey(src)
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	for key, g := range m.mixChokeGroups {
+		if g == group {
+			delete(m.mixChokeGroups, key)
+		}
+	}
+	for _, key := range keys {
+		m.mixChokeGroups[key] = group
+	}
+}
+
+// SetChokeFade of a fire that is choked by another fire of its choke group, from the exact sample the other fire starts playing;
+// the default is DefaultChokeFade, short enough to sound like a cut, without a click.
+func (m *Mixer) SetChokeFade(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixChokeFade = d
+}
+
+// GetChokeFade of a fire that is choked, see SetChokeFade
+func (m *Mixer) GetChokeFade() time.Duration {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixChokeFade
+}
+
+//
+// Private
+//
+
+// mixChokeGroupOf a fire, its own, else of its source, or empty for none; the caller must hold the mixMutex
+func (m *Mixer) mixChokeGroupOf(f *fire.Fire) string {
+	if group := f.ChokeGroup(); group != "" {
+		return group
+	}
+	return m.mixChokeGroups[f.Source]
+}
+
+// mixChoke the other live fires of the choke group of a fire that starts playing now; the caller must hold the mixMutex
+func (m *Mixer) mixChoke(f *fire.Fire) {
+	group := m.mixChokeGroupOf(f)
+	if group == "" {
+		return
+	}
+	fadeTz := m.mixTzOf(m.mixChokeFade)
+	for _, other := range m.mixLiveFires {
+		if other != f && m.mixChokeGroupOf(other) == group {
+			other.Choke(m.nowTz, fadeTz)
+		}
+	}
+}
+
+// mixChokeFrames of a block, at most, such that a fire of a choke group that starts playing during the block does so at its last frame,
+// so that the other fires of its group are choked at that exact sample; the caller must hold the mixMutex
+func (m *Mixer) mixChokeFrames(frames int) int {
+	for _, f := range m.mixLiveFires {
+		if f.State() != fire.StateReady || m.mixChokeGroupOf(f) == "" {
+			continue
+		}
+		beginTz := f.BeginTz
+		if beginTz < m.nowTz {
+			beginTz = m.nowTz // a fire that begins late starts immediately
+		}
+		if n := int(beginTz-m.nowTz) + 1; n < frames {
+			frames = n
+		}
+	}
+	return frames
+}
@@ -0,0 +1,136 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetChokeFade(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, DefaultChokeFade, GetChokeFade())
+	SetChokeFade(20 * time.Millisecond)
+	assert.Equal(t, 20*time.Millisecond, GetChokeFade())
+	SetChokeFade(-time.Second)
+	assert.Equal(t, time.Duration(0), GetChokeFade())
+	Teardown()
+	assert.Equal(t, DefaultChokeFade, GetChokeFade())
+}
+
+func TestSetChokeGroup(t *testing.T) {
+	testMixSetup()
+	open, closed := testChokeHats()
+	o := SetFire(open, 0, 0, 1, 0)
+	c := SetFire(closed, 100*time.Millisecond, 0, 0, 0) // silent, so that the output is only of the open hat
+	testChokeAt(t, o, c.BeginTz)
+}
+
+func TestSetChokeGroup_Remove(t *testing.T) {
+	testMixSetup()
+	open, closed := testChokeHats()
+	SetChokeGroup("hat")
+	o := SetFire(open, 0, 0, 1, 0)
+	SetFire(closed, 100*time.Millisecond, 0, 0, 0)
+	testMixFor(200 * time.Millisecond)
+	assert.True(t, o.IsPlaying())
+}
+
+func TestSetChokeGroup_OtherGroup(t *testing.T) {
+	testMixSetup()
+	open, closed := testChokeHats()
+	SetChokeGroup("hat", open)
+	SetChokeGroup("other", closed)
+	o := SetFire(open, 0, 0, 1, 0)
+	SetFire(closed, 100*time.Millisecond, 0, 0, 0)
+	testMixFor(200 * time.Millisecond)
+	assert.True(t, o.IsPlaying())
+}
+
+// the choke is at the exact sample, even while many live fires are mixed ahead in blocks
+func TestSetChokeGroup_Parallel(t *testing.T) {
+	defer func(fires int) { mixDefault.mixParallelFires = fires }(mixDefault.mixParallelFires)
+	parallel := testChokeManyFires(t)
+	assert.True(t, mixDefault.mixChunkCount > 1)
+	mixDefault.mixParallelFires = math.MaxInt32
+	serial := testChokeManyFires(t)
+	assert.Equal(t, len(serial), len(parallel))
+	for i := range serial {
+		assert.InDelta(t, float64(serial[i]), float64(parallel[i]), 1e-9)
+	}
+}
+
+func TestSetChokeGroup_Live(t *testing.T) {
+	testMixSetup()
+	open, closed := testChokeHats()
+	o := SetFire(open, 0, 0, 1, 0)
+	testMixFor(100 * time.Millisecond)
+	c := FireNow(closed, 0, 0, 0)
+	testChokeAt(t, o, c.BeginTz)
+}
+
+func TestSetChoke_Fire(t *testing.T) {
+	testMixSetup()
+	a := SetFireTone(440, 0, 500*time.Millisecond, 1, 0)
+	b := SetFireTone(660, 100*time.Millisecond, 100*time.Millisecond, 0, 0)
+	a.SetChoke("lead")
+	b.SetChoke("lead")
+	testChokeAt(t, a, b.BeginTz)
+}
+
+//
+// Test Components
+//
+
+// testChokeHats are an open and a closed hi-hat, in a choke group
+func testChokeHats() (open string, closed string) {
+	open = source.ToneKey(source.WaveSine, 441, 500*time.Millisecond)
+	closed = source.ToneKey(source.WaveSine, 882, 50*time.Millisecond)
+	SetChokeGroup("hat", open, closed)
+	return
+}
+
+// testChokeManyFires of which an open hat is choked, not on the boundary of a block, and the output of its fade
+func testChokeManyFires(t *testing.T) (out []sample.Value) {
+	testMixSetup()
+	open, closed := testChokeHats()
+	pad := source.ToneKey(source.WaveSine, 220, 500*time.Millisecond)
+	for n := 0; n < 40; n++ {
+		SetFire(pad, 0, 0, 0, 0)
+	}
+	o := SetFire(open, 0, 0, 1, 0)
+	c := SetFire(closed, 100*time.Millisecond+3*time.Millisecond/2, 0, 0, 0)
+	for mixDefault.nowTz < c.BeginTz {
+		NextSample()
+	}
+	for mixDefault.nowTz <= c.BeginTz+mixDefault.mixTzOf(DefaultChokeFade) {
+		out = append(out, NextSample()[0])
+	}
+	assert.Equal(t, sample.Value(0), out[len(out)-1])
+	assert.Equal(t, fire.DoneChoked, o.DoneReason())
+	return
+}
+
+// testChokeAt that a fire sounds until it is choked at a Tz, then fades out over exactly the choke fade, to silence;
+// the output is checked rather than the state of the fire, which may be mixed ahead in a block
+func testChokeAt(t *testing.T, f *fire.Fire, chokeTz spec.Tz) {
+	fadeTz := mixDefault.mixTzOf(DefaultChokeFade)
+	for mixDefault.nowTz < chokeTz {
+		NextSample()
+	}
+	assert.NotEqual(t, float64(0), float64(NextSample()[0]))
+	for mixDefault.nowTz < chokeTz+fadeTz-1 {
+		NextSample()
+	}
+	assert.NotEqual(t, float64(0), float64(NextSample()[0]))
+	assert.Equal(t, float64(0), float64(NextSample()[0]))
+	assert.False(t, f.IsAlive())
+	assert.Equal(t, fire.DoneChoked, f.DoneReason())
+}
@@ -0,0 +1,114 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Clock of the mixer, which StartAt, Pause, Resume and SeekTo are relative to, e.g. a FakeClock for deterministic tests
+type Clock interface {
+	Now() time.Time
+}
+
+// SetClock of the mixer, or nil for the real clock (the default). With any other clock, the mix never runs ahead of it:
+// past the time of the clock since StartAt, NextSample returns silence without advancing, so that a test can AdvanceBy
+// the clock to mix exactly that window, however fast an output pulls samples.
+func (m *Mixer) SetClock(c Clock) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixClock = c
+}
+
+// Now of the mixer clock
+func (m *Mixer) Now() time.Time {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixNow()
+}
+
+// AdvanceBy a duration the mixer clock, which must be able to Advance, e.g. a FakeClock, and synchronously mix that window,
+// with its mix cycles, fire events and callbacks, pushing the samples to the configured output, e.g. a WAV writer.
+// Returns an error if the clock cannot be advanced, e.g. the real clock.
+func (m *Mixer) AdvanceBy(d time.Duration) error {
+	m.mixMutex.Lock()
+	clock, ok := m.mixClock.(interface {
+		Advance(d time.Duration)
+	})
+	if !ok {
+		m.mixMutex.Unlock()
+		return errors.New("Cannot advance the mixer clock (must be set to one that can, e.g. a FakeClock)")
+	}
+	clock.Advance(d)
+	var deltaTz spec.Tz
+	if toTz := m.mixClockTz(); m.transport == transportPlay && toTz > m.nowTz {
+		deltaTz = toTz - m.nowTz
+	}
+	m.mixMutex.Unlock() // the output pulls each sample via NextSample
+	if deltaTz > 0 {
+		m.mixOutputNext(deltaTz)
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	for m.transport == transportPlay && m.nowTz < m.mixClockTz() { // e.g. the null output, which pulls nothing
+		m.mixNextSample()
+	}
+	return nil
+}
+
+// FakeClock that only moves when it is advanced, e.g. by AdvanceBy; it is safe for concurrent use.
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewFakeClock at a time
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now of the fake clock
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance the fake clock by a duration
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+//
+// Private
+//
+
+// mixNow of the mixer clock; the caller must hold the mixMutex
+func (m *Mixer) mixNow() time.Time {
+	if m.mixClock == nil {
+		return time.Now()
+	}
+	return m.mixClock.Now()
+}
+
+// mixClockTz since the start, of the mixer clock, or 0 before the start; the caller must hold the mixMutex
+func (m *Mixer) mixClockTz() spec.Tz {
+	since := m.mixNow().Sub(m.startAtTime)
+	if since <= 0 {
+		return 0
+	}
+	return m.mixTzOf(since)
+}
+
+// mixAheadOfClock is true if the mix has reached a clock that is not real; the caller must hold the mixMutex
+func (m *Mixer) mixAheadOfClock() bool {
+	if m.mixClock != nil && m.mixPreRollLeftTz > 0 {
+		return m.mixPreRollAheadOfClock()
+	}
+	return m.mixClock != nil && m.nowTz >= m.mixClockTz()
+}
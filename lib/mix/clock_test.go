@@ -0,0 +1,170 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+func TestClock_LongSession(t *testing.T) {
+	testMixSetup()
+	f := SetFireTone(441, 40*time.Minute, 0, 1.0, 0)
+	assert.Equal(t, spec.Tz(40*60*44100), f.BeginTz)
+	assert.Equal(t, spec.Tz(40*60*44100), mixDefault.mixTzOf(40*time.Minute))
+	assert.Equal(t, 40*time.Minute, mixDefault.mixDurOf(40*60*44100))
+	SeekTo(40 * time.Minute)
+	assert.Equal(t, 40*time.Minute, GetNowAt())
+}
+
+func TestClock_SoakJitteredPulls(t *testing.T) {
+	m := testLookaheadMixer(t, spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	defer m.Teardown()
+	m.SetLookahead(2)
+	jitter := rand.New(rand.NewSource(1))
+	block := make([]float32, 32)
+	var consumed spec.Tz
+	for pull := 0; pull < 1000000; pull++ {
+		frames := 1 + jitter.Intn(len(block)) // the buffer of an output callback, of 1 to 32 frames
+		testLookaheadWait(m, frames)          // in real time, an output never pulls faster than the mix
+		if frames == 1 {
+			m.NextSample()
+		} else {
+			m.NextBlock(block, spec.Tz(frames))
+		}
+		consumed += spec.Tz(frames)
+		if off := m.GetNowAt() - m.mixDurOf(consumed); off < -m.mixDurOf(spec.Tz(frames)) || off > m.mixDurOf(spec.Tz(frames)) {
+			t.Fatalf("pull %d: clock at %v, %v off the %d frames consumed", pull, m.GetNowAt(), off, consumed)
+		}
+		if pull%1000 == 0 {
+			assert.Equal(t, m.mixDurOf(m.GetNowFrames()), m.GetNowAt()) // both of what is heard
+		}
+	}
+	assert.Equal(t, int64(0), m.Stats().OutUnderruns)
+	assert.Equal(t, consumed, m.GetNowFrames()) // not the mix ahead of the output
+	assert.Equal(t, m.mixDurOf(m.GetNowFrames()), m.GetNowAt())
+	exact := time.Duration(float64(consumed) / 44100 * float64(time.Second))
+	assert.InDelta(t, float64(exact), float64(m.GetNowAt()), float64(time.Microsecond)) // zero cumulative drift
+}
+
+func TestMixTzOf(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, spec.Tz(441), mixDefault.mixTzOf(10*time.Millisecond))
+	assert.Equal(t, spec.Tz(44100), mixDefault.mixTzOf(time.Second))
+	for tz := spec.Tz(0); tz < 10000; tz++ {
+		assert.Equal(t, tz, mixDefault.mixTzOf(mixDefault.mixDurOf(tz)))
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	testMixSetup()
+	clock := NewFakeClock(time.Unix(1000, 0))
+	SetClock(clock)
+	assert.Equal(t, time.Unix(1000, 0), Now())
+	StartAt(Now())
+	for i := 0; i < 100; i++ {
+		NextSample() // cannot run ahead of the clock
+	}
+	assert.Equal(t, time.Duration(0), GetNowAt())
+	clock.Advance(10 * time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		NextSample()
+	}
+	assert.Equal(t, 10*time.Millisecond, GetNowAt())
+	SetClock(nil)
+	NextSample()
+	assert.Equal(t, mixDefault.mixDurOf(442), GetNowAt())
+}
+
+func TestAdvanceBy(t *testing.T) {
+	testMixSetup()
+	assert.NotNil(t, AdvanceBy(time.Second)) // the real clock
+	SetClock(NewFakeClock(time.Unix(1000, 0)))
+	StartAt(Now())
+	f := SetFireTone(441, 50*time.Millisecond, 50*time.Millisecond, 1, 0)
+	done := make(chan fire.DoneReason, 1)
+	f.OnDone(func(f *fire.Fire) {
+		done <- f.DoneReason()
+	})
+	assert.Nil(t, AdvanceBy(100*time.Millisecond))
+	assert.Equal(t, 100*time.Millisecond, GetNowAt())
+	assert.True(t, f.IsPlaying())
+	assert.Nil(t, AdvanceBy(time.Second))
+	assert.Equal(t, 1100*time.Millisecond, GetNowAt())
+	assert.NotEqual(t, fire.NotDone, <-done)
+	Pause()
+	assert.Nil(t, AdvanceBy(time.Second))
+	assert.Equal(t, 1100*time.Millisecond, GetNowAt())
+	Resume()
+	assert.Nil(t, AdvanceBy(time.Second))
+	assert.Equal(t, 2100*time.Millisecond, GetNowAt())
+}
+
+func TestAdvanceBy_WAV(t *testing.T) {
+	testMixSetup()
+	bind.UseOutput(opt.OutputWAV)
+	defer bind.UseOutput(opt.OutputNull)
+	_, err := bind.Configure(*Spec())
+	assert.Nil(t, err)
+	bind.SetOutputCallback(NextSample)
+	var out bytes.Buffer
+	OutputStart(0, &out)
+	header := out.Len()
+	SetClock(NewFakeClock(time.Unix(1000, 0)))
+	StartAt(Now())
+	assert.Nil(t, AdvanceBy(100*time.Millisecond))
+	assert.Equal(t, 4410*4, out.Len()-header) // of 32-bit float mono samples
+	assert.Equal(t, 100*time.Millisecond, GetNowAt())
+}
+
+func TestGetNowFrames(t *testing.T) {
+	testMixSetup()
+	clock := NewFakeClock(time.Unix(1000, 0))
+	SetClock(clock)
+	StartAt(Now())
+	assert.Equal(t, spec.Tz(0), GetNowFrames())
+	done := make(chan spec.Tz)
+	go func() { // from another goroutine, while the mix loop holds the mixer
+		for GetNowFrames() < 4410 {
+			time.Sleep(time.Millisecond)
+		}
+		done <- GetNowFrames()
+	}()
+	assert.Nil(t, AdvanceBy(100*time.Millisecond))
+	select {
+	case f := <-done:
+		assert.Equal(t, spec.Tz(4410), f)
+	case <-time.After(time.Second):
+		t.Fatal("frames were not published")
+	}
+	assert.Equal(t, time.Unix(1000, 0).Add(100*time.Millisecond), AtFrame(GetNowFrames()))
+	SeekTo(time.Second)
+	assert.Equal(t, spec.Tz(44100), GetNowFrames())
+}
+
+func TestFramesToDuration(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, spec.Tz(0), DurationToFrames(-time.Second))
+	assert.Equal(t, spec.Tz(440), DurationToFrames(10*time.Millisecond-time.Nanosecond)) // floor
+	assert.Equal(t, spec.Tz(441), DurationToFrames(10*time.Millisecond))
+	assert.Equal(t, 22676*time.Nanosecond, FramesToDuration(1)) // 22675.7ns, rounded up
+	assert.Equal(t, 40*time.Minute, FramesToDuration(40*60*44100))
+	for f := spec.Tz(0); f < 100000; f++ {
+		assert.Equal(t, f, DurationToFrames(FramesToDuration(f)))
+	}
+	for f := spec.Tz(0); f < 1000; f++ { // a frequency that is not whole
+		d := durationOf(f, 44099.5)
+		assert.Equal(t, f, framesOf(d, 44099.5))
+		if f > 0 {
+			assert.Equal(t, f-1, framesOf(d-1, 44099.5))
+		}
+	}
+}
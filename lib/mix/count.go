@@ -0,0 +1,145 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// FireCount returns the current total ready fires + live fires.
+// Like the other counters, it is maintained by the mix loop, so it is cheap to poll from any goroutine, e.g. a UI at 60Hz.
+func (m *Mixer) FireCount() int {
+	return m.FireCountReady() + m.FireCountLive()
+}
+
+// FireCountReady returns the # of fires scheduled to begin in the future.
+func (m *Mixer) FireCountReady() int {
+	return int(atomic.LoadInt64(&m.mixCountReady))
+}
+
+// FireCountLive returns the # of fires that have begun and not yet been collected by the mix cycle;
+// a fire that is done still counts, until the next mix cycle.
+func (m *Mixer) FireCountLive() int {
+	return int(atomic.LoadInt64(&m.mixCountLive))
+}
+
+// NextFireAt returns the begin time of the earliest ready fire, since the start of mix playback, or false if there is none.
+func (m *Mixer) NextFireAt() (time.Duration, bool) {
+	next := atomic.LoadInt64(&m.mixNextFireAt)
+	return time.Duration(next), next >= 0
+}
+
+// ScheduleEnd returns the time since the start of mix playback that the last of the ready and live fires ends, by its sustain, or the
+// natural length of its source if it has none, e.g. to render exactly to the end. A multi-sample source not yet resolved to a variant
+// counts as its longest variant, and a fire chained after another counts from the end of that one. A fire that loops until canceled
+// never ends, so it is not counted, nor is a fire whose source is not loaded. Returns 0 if there are no fires.
+func (m *Mixer) ScheduleEnd() time.Duration {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	var lastTz spec.Tz
+	count := func(f *fire.Fire) {
+		if endTz, known := m.mixFireEndTz(f); known && endTz > lastTz {
+			lastTz = endTz
+		}
+	}
+	m.mixReadyFires.Each(count)
+	for _, f := range m.mixLiveFires {
+		if f.IsAlive() {
+			count(f)
+		}
+	}
+	for f := range m.mixChainWaiting {
+		count(f)
+	}
+	return m.mixSchedDurOf(lastTz)
+}
+
+// WaitDone blocks until there are no more fires, i.e. FireCount is zero, or else until the context is done, and returns its error,
+// e.g. instead of polling FireCount. Like FireCount, it follows the mix loop, so a fire that is done is counted until the next mix cycle.
+func (m *Mixer) WaitDone(ctx context.Context) error {
+	m.mixMutex.Lock()
+	idle := m.mixIdle
+	m.mixMutex.Unlock()
+	if idle == nil {
+		return nil
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//
+// Private
+//
+
+// mixFireEndTz of a fire, see fire.End, or if its multi-sample source is not yet resolved, the end of its longest variant,
+// and if it is chained after another fire, from the end of that one; the caller must hold the mixMutex
+func (m *Mixer) mixFireEndTz(f *fire.Fire) (endTz spec.Tz, known bool) {
+	if f.IsCanceled() {
+		return 0, false
+	}
+	endTz, known = f.End()
+	if m.mixMultiSource(f.Source) != nil && f.Resolved() == f.Source {
+		m.mixEachVariant(f.Source, func(key string) {
+			if variantEndTz, variantKnown := f.EndAs(key); variantKnown && (!known || variantEndTz > endTz) {
+				endTz, known = variantEndTz, true
+			}
+		})
+	}
+	prev, waiting := m.mixChainWaiting[f]
+	if !known || !waiting {
+		return
+	}
+	prevEndTz, prevKnown := m.mixFireEndTz(prev) // while it waits, its begin is 0, so its end is relative
+	if !prevKnown {
+		return 0, false
+	}
+	for _, c := range m.mixChains[prev] {
+		if beginTz := int64(prevEndTz) + c.gapTz; c.fire == f && beginTz > 0 {
+			endTz += spec.Tz(beginTz)
+		}
+	}
+	return
+}
+
+// mixCountFires after any change to the fires, and once per sample because some live fires may not yet have begun;
+// it only touches the live fires, and the soonest of the ready fires. The caller must hold the mixMutex
+func (m *Mixer) mixCountFires() {
+	ready, live, nextTz := int64(m.mixReadyFires.Len()), int64(0), spec.Tz(math.MaxUint64)
+	if f := m.mixReadyFires.Peek(); f != nil {
+		nextTz = f.BeginTz
+	}
+	for _, f := range m.mixLiveFires {
+		if f.IsCanceled() {
+			continue
+		} else if f.BeginTz <= m.nowTz {
+			live++
+		} else {
+			ready++
+			if f.BeginTz < nextTz {
+				nextTz = f.BeginTz
+			}
+		}
+	}
+	atomic.StoreInt64(&m.mixCountReady, ready)
+	atomic.StoreInt64(&m.mixCountLive, live)
+	if ready+live > 0 && m.mixIdle == nil {
+		m.mixIdle = make(chan struct{})
+	} else if ready+live == 0 && m.mixIdle != nil {
+		close(m.mixIdle) // for WaitDone
+		m.mixIdle = nil
+	}
+	if nextTz == spec.Tz(math.MaxUint64) {
+		atomic.StoreInt64(&m.mixNextFireAt, -1)
+	} else {
+		atomic.StoreInt64(&m.mixNextFireAt, int64(m.mixSchedDurOf(nextTz)))
+	}
+}
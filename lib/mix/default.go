@@ -0,0 +1,850 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/effect"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/level"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// SetMixAlgorithm on the default mixer, see Mixer.SetMixAlgorithm
+func SetMixAlgorithm(alg MixAlgorithm) {
+	mixDefault.SetMixAlgorithm(alg)
+}
+
+// GetMixAlgorithm on the default mixer, see Mixer.GetMixAlgorithm
+func GetMixAlgorithm() MixAlgorithm {
+	return mixDefault.GetMixAlgorithm()
+}
+
+// SetMixParams on the default mixer, see Mixer.SetMixParams
+func SetMixParams(p MixParams) {
+	mixDefault.SetMixParams(p)
+}
+
+// GetMixParams on the default mixer, see Mixer.GetMixParams
+func GetMixParams() MixParams {
+	return mixDefault.GetMixParams()
+}
+
+// SetHeadroom on the default mixer, see Mixer.SetHeadroom
+func SetHeadroom(db float64) {
+	mixDefault.SetHeadroom(db)
+}
+
+// GetHeadroom on the default mixer, see Mixer.GetHeadroom
+func GetHeadroom() float64 {
+	return mixDefault.GetHeadroom()
+}
+
+// SetClipMode on the default mixer, see Mixer.SetClipMode
+func SetClipMode(mode ClipMode) {
+	mixDefault.SetClipMode(mode)
+}
+
+// GetClipMode on the default mixer, see Mixer.GetClipMode
+func GetClipMode() ClipMode {
+	return mixDefault.GetClipMode()
+}
+
+// SetDither on the default mixer, see Mixer.SetDither
+func SetDither(mode DitherMode) {
+	mixDefault.SetDither(mode)
+}
+
+// GetDither on the default mixer, see Mixer.GetDither
+func GetDither() DitherMode {
+	return mixDefault.GetDither()
+}
+
+// SetPanLaw on the default mixer, see Mixer.SetPanLaw
+func SetPanLaw(law PanLaw) {
+	mixDefault.SetPanLaw(law)
+}
+
+// GetPanLaw on the default mixer, see Mixer.GetPanLaw
+func GetPanLaw() PanLaw {
+	return mixDefault.GetPanLaw()
+}
+
+// MeasureLoudness on the default mixer, see Mixer.MeasureLoudness
+func MeasureLoudness(length time.Duration) (LoudnessReport, error) {
+	return mixDefault.MeasureLoudness(length)
+}
+
+// RenderNormalized on the default mixer, see Mixer.RenderNormalized
+func RenderNormalized(length time.Duration, targetLUFS float64, w io.Writer) (LoudnessReport, error) {
+	return mixDefault.RenderNormalized(length, targetLUFS, w)
+}
+
+// Calibrate on the default mixer, see Mixer.Calibrate
+func Calibrate(signal Calibration, d time.Duration) *fire.Fire {
+	return mixDefault.Calibrate(signal, d)
+}
+
+// SetChokeGroup on the default mixer, see Mixer.SetChokeGroup
+func SetChokeGroup(group string, sources ...string) {
+	mixDefault.SetChokeGroup(group, sources...)
+}
+
+// SetChokeFade on the default mixer, see Mixer.SetChokeFade
+func SetChokeFade(d time.Duration) {
+	mixDefault.SetChokeFade(d)
+}
+
+// SetTimeScale on the default mixer, see Mixer.SetTimeScale
+func SetTimeScale(scale float64) {
+	mixDefault.SetTimeScale(scale)
+}
+
+// GetTimeScale of the default mixer, see Mixer.GetTimeScale
+func GetTimeScale() float64 {
+	return mixDefault.GetTimeScale()
+}
+
+// SetHumanize on the default mixer, see Mixer.SetHumanize
+func SetHumanize(h HumanizeSpec) {
+	mixDefault.SetHumanize(h)
+}
+
+// GetHumanize of the default mixer, see Mixer.GetHumanize
+func GetHumanize() HumanizeSpec {
+	return mixDefault.GetHumanize()
+}
+
+// GetChokeFade on the default mixer, see Mixer.GetChokeFade
+func GetChokeFade() time.Duration {
+	return mixDefault.GetChokeFade()
+}
+
+// NewBus on the default mixer, see Mixer.NewBus
+func NewBus(name string) *Bus {
+	return mixDefault.NewBus(name)
+}
+
+// SetDucking on the default mixer, see Mixer.SetDucking
+func SetDucking(triggerBus string, targetBus string, depthDB float64, attack time.Duration, release time.Duration) {
+	mixDefault.SetDucking(triggerBus, targetBus, depthDB, attack, release)
+}
+
+// GetDucking on the default mixer, see Mixer.GetDucking
+func GetDucking(bus string) float64 {
+	return mixDefault.GetDucking(bus)
+}
+
+// SetFireOnBus on the default mixer, see Mixer.SetFireOnBus
+func SetFireOnBus(bus *Bus, source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireOnBus(bus, source, begin, sustain, volume, pan)
+}
+
+// StartCapture on the default mixer, see Mixer.StartCapture
+func StartCapture() {
+	mixDefault.StartCapture()
+}
+
+// StopCapture on the default mixer, see Mixer.StopCapture
+func StopCapture() (events []CapturedFire) {
+	return mixDefault.StopCapture()
+}
+
+// ReplayCapture on the default mixer, see Mixer.ReplayCapture
+func ReplayCapture(events []CapturedFire, atOffset time.Duration, repeat int) {
+	mixDefault.ReplayCapture(events, atOffset, repeat)
+}
+
+// SetClock on the default mixer, see Mixer.SetClock
+func SetClock(c Clock) {
+	mixDefault.SetClock(c)
+}
+
+// Now on the default mixer, see Mixer.Now
+func Now() time.Time {
+	return mixDefault.Now()
+}
+
+// AdvanceBy on the default mixer, see Mixer.AdvanceBy
+func AdvanceBy(d time.Duration) error {
+	return mixDefault.AdvanceBy(d)
+}
+
+// FireCount on the default mixer, see Mixer.FireCount
+func FireCount() int {
+	return mixDefault.FireCount()
+}
+
+// FireCountReady on the default mixer, see Mixer.FireCountReady
+func FireCountReady() int {
+	return mixDefault.FireCountReady()
+}
+
+// FireCountLive on the default mixer, see Mixer.FireCountLive
+func FireCountLive() int {
+	return mixDefault.FireCountLive()
+}
+
+// WaitDone on the default mixer, see Mixer.WaitDone
+func WaitDone(ctx context.Context) error {
+	return mixDefault.WaitDone(ctx)
+}
+
+// NextFireAt on the default mixer, see Mixer.NextFireAt
+func NextFireAt() (time.Duration, bool) {
+	return mixDefault.NextFireAt()
+}
+
+// ScheduleEnd on the default mixer, see Mixer.ScheduleEnd
+func ScheduleEnd() time.Duration {
+	return mixDefault.ScheduleEnd()
+}
+
+// AddMasterEffect on the default mixer, see Mixer.AddMasterEffect
+func AddMasterEffect(e effect.Effect) {
+	mixDefault.AddMasterEffect(e)
+}
+
+// RemoveMasterEffect on the default mixer, see Mixer.RemoveMasterEffect
+func RemoveMasterEffect(e effect.Effect) {
+	mixDefault.RemoveMasterEffect(e)
+}
+
+// FireEvents on the default mixer, see Mixer.FireEvents
+func FireEvents() <-chan FireEvent {
+	return mixDefault.FireEvents()
+}
+
+// GetOutputLevel on the default mixer, see Mixer.GetOutputLevel
+func GetOutputLevel() level.Level {
+	return mixDefault.GetOutputLevel()
+}
+
+// GetClipCount on the default mixer, see Mixer.GetClipCount
+func GetClipCount() uint64 {
+	return mixDefault.GetClipCount()
+}
+
+// SetOutputLatency on the default mixer, see Mixer.SetOutputLatency
+func SetOutputLatency(d time.Duration) {
+	mixDefault.SetOutputLatency(d)
+}
+
+// GetOutputLatency on the default mixer, see Mixer.GetOutputLatency
+func GetOutputLatency() time.Duration {
+	return mixDefault.GetOutputLatency()
+}
+
+// SetLookahead on the default mixer, see Mixer.SetLookahead
+func SetLookahead(cycles int) {
+	mixDefault.SetLookahead(cycles)
+}
+
+// GetLookahead of the default mixer, see Mixer.GetLookahead
+func GetLookahead() int {
+	return mixDefault.GetLookahead()
+}
+
+// FireNow on the default mixer, see Mixer.FireNow
+func FireNow(source string, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.FireNow(source, sustain, volume, pan)
+}
+
+// LoadMIDI on the default mixer, see Mixer.LoadMIDI
+func LoadMIDI(path string, mapping map[int]string, defaultSustain time.Duration) (skipped int, err error) {
+	return mixDefault.LoadMIDI(path, mapping, defaultSustain)
+}
+
+// NextBlock on the default mixer, see Mixer.NextBlock
+func NextBlock(dst []float32, frames spec.Tz) {
+	mixDefault.NextBlock(dst, frames)
+}
+
+// NextSample on the default mixer, see Mixer.NextSample
+func NextSample() []sample.Value {
+	return mixDefault.NextSample()
+}
+
+// Configure on the default mixer, see Mixer.Configure
+func Configure(s spec.AudioSpec) {
+	mixDefault.Configure(s)
+}
+
+// ConfigureObtained on the default mixer, see Mixer.ConfigureObtained
+func ConfigureObtained(requested spec.AudioSpec, obtained spec.AudioSpec) {
+	mixDefault.ConfigureObtained(requested, obtained)
+}
+
+// ObtainedSpec on the default mixer, see Mixer.ObtainedSpec
+func ObtainedSpec() spec.AudioSpec {
+	return mixDefault.ObtainedSpec()
+}
+
+// RequestedSpec on the default mixer, see Mixer.RequestedSpec
+func RequestedSpec() spec.AudioSpec {
+	return mixDefault.RequestedSpec()
+}
+
+// Spec on the default mixer, see Mixer.Spec
+func Spec() *spec.AudioSpec {
+	return mixDefault.Spec()
+}
+
+// Teardown on the default mixer, see Mixer.Teardown
+func Teardown() {
+	mixDefault.Teardown()
+}
+
+// TeardownWith on the default mixer, see Mixer.TeardownWith
+func TeardownWith(opts TeardownOptions) error {
+	return mixDefault.TeardownWith(opts)
+}
+
+// SetFire on the default mixer, see Mixer.SetFire; nil if the source cannot be loaded, see SetFireErr
+func SetFire(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFire(source, begin, sustain, volume, pan)
+}
+
+// SetFireErr on the default mixer, see Mixer.SetFireErr
+func SetFireErr(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) (*fire.Fire, error) {
+	return mixDefault.SetFireErr(source, begin, sustain, volume, pan)
+}
+
+// SetFires on the default mixer, see Mixer.SetFires
+func SetFires(batch []FireSpec) (fires []*fire.Fire, errs []error) {
+	return mixDefault.SetFires(batch)
+}
+
+// SetFireRate on the default mixer, see Mixer.SetFireRate
+func SetFireRate(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64, rate float64) *fire.Fire {
+	return mixDefault.SetFireRate(source, begin, sustain, volume, pan, rate)
+}
+
+// SetFireRegion on the default mixer, see Mixer.SetFireRegion
+func SetFireRegion(source string, begin time.Duration, sustain time.Duration, offset time.Duration, length time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireRegion(source, begin, sustain, offset, length, volume, pan)
+}
+
+// SetFireLoop on the default mixer, see Mixer.SetFireLoop
+func SetFireLoop(source string, begin time.Duration, interval time.Duration, repeat int, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireLoop(source, begin, interval, repeat, sustain, volume, pan)
+}
+
+// SetFireLoopXFade on the default mixer, see Mixer.SetFireLoopXFade
+func SetFireLoopXFade(source string, begin time.Duration, xfade time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireLoopXFade(source, begin, xfade, volume, pan)
+}
+
+// SetFireSustainLoop on the default mixer, see Mixer.SetFireSustainLoop
+func SetFireSustainLoop(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireSustainLoop(source, begin, sustain, volume, pan)
+}
+
+// SetFireAfter on the default mixer, see Mixer.SetFireAfter; nil if the previous fire is nil, or the source cannot be loaded
+func SetFireAfter(prev *fire.Fire, gap time.Duration, source string, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireAfter(prev, gap, source, sustain, volume, pan)
+}
+
+// NewFireGroup of the default mixer, see Mixer.NewFireGroup
+func NewFireGroup() *FireGroup {
+	return mixDefault.NewFireGroup()
+}
+
+// EnvelopePoint on the default mixer, see Mixer.EnvelopePoint
+func EnvelopePoint(offset time.Duration, value float64) fire.EnvelopePoint {
+	return mixDefault.EnvelopePoint(offset, value)
+}
+
+// Fires on the default mixer, see Mixer.Fires
+func Fires() []*fire.Fire {
+	return mixDefault.Fires()
+}
+
+// StartAt on the default mixer, see Mixer.StartAt
+func StartAt(t time.Time) {
+	mixDefault.StartAt(t)
+}
+
+// Pause on the default mixer, see Mixer.Pause
+func Pause() {
+	mixDefault.Pause()
+}
+
+// Resume on the default mixer, see Mixer.Resume
+func Resume() {
+	mixDefault.Resume()
+}
+
+// Stop on the default mixer, see Mixer.Stop
+func Stop() {
+	mixDefault.Stop()
+}
+
+// SeekTo on the default mixer, see Mixer.SeekTo
+func SeekTo(d time.Duration) {
+	mixDefault.SeekTo(d)
+}
+
+// SetReplayLimit on the default mixer, see Mixer.SetReplayLimit
+func SetReplayLimit(fires int) {
+	mixDefault.SetReplayLimit(fires)
+}
+
+// GetReplayLimit on the default mixer, see Mixer.GetReplayLimit
+func GetReplayLimit() int {
+	return mixDefault.GetReplayLimit()
+}
+
+// IsPlaying on the default mixer, see Mixer.IsPlaying
+func IsPlaying() bool {
+	return mixDefault.IsPlaying()
+}
+
+// GetStartTime on the default mixer, see Mixer.GetStartTime
+func GetStartTime() time.Time {
+	return mixDefault.GetStartTime()
+}
+
+// SetMasterVolume on the default mixer, see Mixer.SetMasterVolume
+func SetMasterVolume(v float64) {
+	mixDefault.SetMasterVolume(v)
+}
+
+// GetMasterVolume on the default mixer, see Mixer.GetMasterVolume
+func GetMasterVolume() float64 {
+	return mixDefault.GetMasterVolume()
+}
+
+// Mute on the default mixer, see Mixer.Mute
+func Mute() {
+	mixDefault.Mute()
+}
+
+// Unmute on the default mixer, see Mixer.Unmute
+func Unmute() {
+	mixDefault.Unmute()
+}
+
+// IsMuted on the default mixer, see Mixer.IsMuted
+func IsMuted() bool {
+	return mixDefault.IsMuted()
+}
+
+// GetNowAt on the default mixer, see Mixer.GetNowAt
+func GetNowAt() time.Duration {
+	return mixDefault.GetNowAt()
+}
+
+// SetTickCallback on the default mixer, see Mixer.SetTickCallback
+func SetTickCallback(ppqn int, fn func(tick int64, at time.Duration)) {
+	mixDefault.SetTickCallback(ppqn, fn)
+}
+
+// GetNowFrames on the default mixer, see Mixer.GetNowFrames
+func GetNowFrames() spec.Tz {
+	return mixDefault.GetNowFrames()
+}
+
+// DurationToFrames on the default mixer, see Mixer.DurationToFrames
+func DurationToFrames(d time.Duration) spec.Tz {
+	return mixDefault.DurationToFrames(d)
+}
+
+// FramesToDuration on the default mixer, see Mixer.FramesToDuration
+func FramesToDuration(f spec.Tz) time.Duration {
+	return mixDefault.FramesToDuration(f)
+}
+
+// AtFrame on the default mixer, see Mixer.AtFrame
+func AtFrame(f spec.Tz) time.Time {
+	return mixDefault.AtFrame(f)
+}
+
+// ClearAllFires on the default mixer, see Mixer.ClearAllFires
+func ClearAllFires() {
+	mixDefault.ClearAllFires()
+}
+
+// ClearFiresAfter on the default mixer, see Mixer.ClearFiresAfter
+func ClearFiresAfter(t time.Duration) int {
+	return mixDefault.ClearFiresAfter(t)
+}
+
+// ClearFiresBySource on the default mixer, see Mixer.ClearFiresBySource
+func ClearFiresBySource(src string) int {
+	return mixDefault.ClearFiresBySource(src)
+}
+
+// SetSoundsPath on the default mixer, see Mixer.SetSoundsPath
+func SetSoundsPath(prefix string) {
+	mixDefault.SetSoundsPath(prefix)
+}
+
+// Prepare on the default mixer, see Mixer.Prepare
+func Prepare(sources ...string) error {
+	return mixDefault.Prepare(sources...)
+}
+
+// PrepareCtx on the default mixer, see Mixer.PrepareCtx
+func PrepareCtx(ctx context.Context, sources ...string) error {
+	return mixDefault.PrepareCtx(ctx, sources...)
+}
+
+// EvictSource on the default mixer, see Mixer.EvictSource
+func EvictSource(src string) {
+	mixDefault.EvictSource(src)
+}
+
+// SourceCacheSize on the default mixer, see Mixer.SourceCacheSize
+func SourceCacheSize() int64 {
+	return mixDefault.SourceCacheSize()
+}
+
+// SetSourceCacheLimit on the default mixer, see Mixer.SetSourceCacheLimit
+func SetSourceCacheLimit(size int64) {
+	mixDefault.SetSourceCacheLimit(size)
+}
+
+// SourceCacheStats on the default mixer, see Mixer.SourceCacheStats
+func SourceCacheStats() source.CacheStats {
+	return mixDefault.SourceCacheStats()
+}
+
+// SetSourceStreaming on the default mixer, see Mixer.SetSourceStreaming
+func SetSourceStreaming(name string, on bool) {
+	mixDefault.SetSourceStreaming(name, on)
+}
+
+// SetAutoNormalize on the default mixer, see Mixer.SetAutoNormalize
+func SetAutoNormalize(target float64) {
+	mixDefault.SetAutoNormalize(target)
+}
+
+// SetAutoNormalizeRMS on the default mixer, see Mixer.SetAutoNormalizeRMS
+func SetAutoNormalizeRMS(target float64) {
+	mixDefault.SetAutoNormalizeRMS(target)
+}
+
+// StopAutoNormalize on the default mixer, see Mixer.StopAutoNormalize
+func StopAutoNormalize() {
+	mixDefault.StopAutoNormalize()
+}
+
+// SetAutoTrim on the default mixer, see Mixer.SetAutoTrim
+func SetAutoTrim(thresholdDB float64) {
+	mixDefault.SetAutoTrim(thresholdDB)
+}
+
+// SetAutoTrimTrailing on the default mixer, see Mixer.SetAutoTrimTrailing
+func SetAutoTrimTrailing(on bool) {
+	mixDefault.SetAutoTrimTrailing(on)
+}
+
+// StopAutoTrim on the default mixer, see Mixer.StopAutoTrim
+func StopAutoTrim() {
+	mixDefault.StopAutoTrim()
+}
+
+// SetSourceTrim on the default mixer, see Mixer.SetSourceTrim
+func SetSourceTrim(name string, offset time.Duration) {
+	mixDefault.SetSourceTrim(name, offset)
+}
+
+// SetSourceGain on the default mixer, see Mixer.SetSourceGain
+func SetSourceGain(name string, db float64) {
+	mixDefault.SetSourceGain(name, db)
+}
+
+// GetSourceGain on the default mixer, see Mixer.GetSourceGain
+func GetSourceGain(name string) float64 {
+	return mixDefault.GetSourceGain(name)
+}
+
+// SetResampleQuality on the default mixer, see Mixer.SetResampleQuality
+func SetResampleQuality(q source.ResampleQuality) {
+	mixDefault.SetResampleQuality(q)
+}
+
+// SetDownmixMatrix on the default mixer, see Mixer.SetDownmixMatrix
+func SetDownmixMatrix(srcChannels int, matrix [][]float64) error {
+	return mixDefault.SetDownmixMatrix(srcChannels, matrix)
+}
+
+// SetCycleDuration on the default mixer, see Mixer.SetCycleDuration
+func SetCycleDuration(d time.Duration) {
+	mixDefault.SetCycleDuration(d)
+}
+
+// GetCycleDurationTz on the default mixer, see Mixer.GetCycleDurationTz
+func GetCycleDurationTz() spec.Tz {
+	return mixDefault.GetCycleDurationTz()
+}
+
+// OutputContinueTo on the default mixer, see Mixer.OutputContinueTo
+func OutputContinueTo(t time.Duration) {
+	mixDefault.OutputContinueTo(t)
+}
+
+// SetWorkers on the default mixer, see Mixer.SetWorkers
+func SetWorkers(n int) {
+	mixDefault.SetWorkers(n)
+}
+
+// RenderRange on the default mixer, see Mixer.RenderRange
+func RenderRange(from time.Duration, to time.Duration, w io.Writer) error {
+	return mixDefault.RenderRange(from, to, w)
+}
+
+// RenderCtx on the default mixer, see Mixer.RenderCtx
+func RenderCtx(ctx context.Context, length time.Duration, w io.Writer) error {
+	return mixDefault.RenderCtx(ctx, length, w)
+}
+
+// SetSubSamplePrecision on the default mixer, see Mixer.SetSubSamplePrecision
+func SetSubSamplePrecision(on bool) {
+	mixDefault.SetSubSamplePrecision(on)
+}
+
+// GetSubSamplePrecision on the default mixer, see Mixer.GetSubSamplePrecision
+func GetSubSamplePrecision() bool {
+	return mixDefault.GetSubSamplePrecision()
+}
+
+// RegisterMultiSource on the default mixer, see Mixer.RegisterMultiSource
+func RegisterMultiSource(name string, layers []Layer) error {
+	return mixDefault.RegisterMultiSource(name, layers)
+}
+
+// SetRandomSeed on the default mixer, see Mixer.SetRandomSeed
+func SetRandomSeed(seed int64) {
+	mixDefault.SetRandomSeed(seed)
+}
+
+// Render on the default mixer, see Mixer.Render
+func Render(length time.Duration) ([]float64, error) {
+	return mixDefault.Render(length)
+}
+
+// RenderTo on the default mixer, see Mixer.RenderTo
+func RenderTo(w io.Writer, length time.Duration) error {
+	return mixDefault.RenderTo(w, length)
+}
+
+// SetScheduleLowWater on the default mixer, see Mixer.SetScheduleLowWater
+func SetScheduleLowWater(d time.Duration, fn func(horizon time.Duration)) {
+	mixDefault.SetScheduleLowWater(d, fn)
+}
+
+// ExportSchedule on the default mixer, see Mixer.ExportSchedule
+func ExportSchedule(w io.Writer) error {
+	return mixDefault.ExportSchedule(w)
+}
+
+// ImportSchedule on the default mixer, see Mixer.ImportSchedule
+func ImportSchedule(r io.Reader, offset time.Duration) error {
+	return mixDefault.ImportSchedule(r, offset)
+}
+
+// ReloadSource on the default mixer, see Mixer.ReloadSource
+func ReloadSource(name string) error {
+	return mixDefault.ReloadSource(name)
+}
+
+// WatchSources on the default mixer, see Mixer.WatchSources
+func WatchSources(on bool) {
+	mixDefault.WatchSources(on)
+}
+
+// ValidateSchedule on the default mixer, see Mixer.ValidateSchedule
+func ValidateSchedule() []error {
+	return mixDefault.ValidateSchedule()
+}
+
+// SaveSession of the default mixer, see Mixer.SaveSession
+func SaveSession(w io.Writer) error {
+	return mixDefault.SaveSession(w)
+}
+
+// LoadSession on the default mixer, see Mixer.LoadSession
+func LoadSession(r io.Reader) error {
+	return mixDefault.LoadSession(r)
+}
+
+// RestoreSession on the default mixer, see Mixer.RestoreSession
+func RestoreSession(s *Session) error {
+	return mixDefault.RestoreSession(s)
+}
+
+// TeeOutput of the default mixer, see Mixer.TeeOutput
+func TeeOutput(w io.Writer, format spec.AudioFormat) (*Tee, error) {
+	return mixDefault.TeeOutput(w, format)
+}
+
+// Sources on the default mixer, see Mixer.Sources
+func Sources() []SourceInfo {
+	return mixDefault.Sources()
+}
+
+// GetSourceDuration on the default mixer, see Mixer.GetSourceDuration
+func GetSourceDuration(name string) (time.Duration, error) {
+	return mixDefault.GetSourceDuration(name)
+}
+
+// Stats on the default mixer, see Mixer.Stats
+func Stats() LoopStats {
+	return mixDefault.Stats()
+}
+
+// ResetStats on the default mixer, see Mixer.ResetStats
+func ResetStats() {
+	mixDefault.ResetStats()
+}
+
+// SetOverrunWarning on the default mixer, see Mixer.SetOverrunWarning
+func SetOverrunWarning(fn func(stats LoopStats)) {
+	mixDefault.SetOverrunWarning(fn)
+}
+
+// OnError on the default mixer, see Mixer.OnError
+func OnError(fn func(err error, stack []byte)) {
+	mixDefault.OnError(fn)
+}
+
+// Err of the default mixer, see Mixer.Err
+func Err() error {
+	return mixDefault.Err()
+}
+
+// SetPreRoll on the default mixer, see Mixer.SetPreRoll
+func SetPreRoll(bars int, clickSource string) {
+	mixDefault.SetPreRoll(bars, clickSource)
+}
+
+// SetPreRollDuration on the default mixer, see Mixer.SetPreRollDuration
+func SetPreRollDuration(d time.Duration, clickSource string) {
+	mixDefault.SetPreRollDuration(d, clickSource)
+}
+
+// SetPreRollOutput on the default mixer, see Mixer.SetPreRollOutput
+func SetPreRollOutput(include bool) {
+	mixDefault.SetPreRollOutput(include)
+}
+
+// SetTempo on the default mixer, see Mixer.SetTempo
+func SetTempo(bpm float64, stepsPerBeat int) {
+	mixDefault.SetTempo(bpm, stepsPerBeat)
+}
+
+// AddTempoChange on the default mixer, see Mixer.AddTempoChange
+func AddTempoChange(atStep int, bpm float64) {
+	mixDefault.AddTempoChange(atStep, bpm)
+}
+
+// ClearTempoChanges on the default mixer, see Mixer.ClearTempoChanges
+func ClearTempoChanges() {
+	mixDefault.ClearTempoChanges()
+}
+
+// SetSwing on the default mixer, see Mixer.SetSwing
+func SetSwing(amount float64) {
+	mixDefault.SetSwing(amount)
+}
+
+// SetGroove on the default mixer, see Mixer.SetGroove
+func SetGroove(offsets []time.Duration) {
+	mixDefault.SetGroove(offsets)
+}
+
+// SetStepOffset on the default mixer, see Mixer.SetStepOffset
+func SetStepOffset(offset time.Duration) {
+	mixDefault.SetStepOffset(offset)
+}
+
+// StepDuration on the default mixer, see Mixer.StepDuration
+func StepDuration() time.Duration {
+	return mixDefault.StepDuration()
+}
+
+// StepAt on the default mixer, see Mixer.StepAt
+func StepAt(t time.Duration) int {
+	return mixDefault.StepAt(t)
+}
+
+// SetFireAtStep on the default mixer, see Mixer.SetFireAtStep
+func SetFireAtStep(source string, step int, sustainSteps float64, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireAtStep(source, step, sustainSteps, volume, pan)
+}
+
+// SetFireAtBeat on the default mixer, see Mixer.SetFireAtBeat
+func SetFireAtBeat(source string, beat float64, sustainBeats float64, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireAtBeat(source, beat, sustainBeats, volume, pan)
+}
+
+// SetSustainFollowsTempo on the default mixer, see Mixer.SetSustainFollowsTempo
+func SetSustainFollowsTempo(on bool) {
+	mixDefault.SetSustainFollowsTempo(on)
+}
+
+// GetSustainFollowsTempo of the default mixer, see Mixer.GetSustainFollowsTempo
+func GetSustainFollowsTempo() bool {
+	return mixDefault.GetSustainFollowsTempo()
+}
+
+// SetFireTone on the default mixer, see Mixer.SetFireTone
+func SetFireTone(freq float64, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireTone(freq, begin, sustain, volume, pan)
+}
+
+// SetFireWaveform on the default mixer, see Mixer.SetFireWaveform
+func SetFireWaveform(wave source.Waveform, freq float64, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mixDefault.SetFireWaveform(wave, freq, begin, sustain, volume, pan)
+}
+
+// NewReader on the default mixer, see Mixer.NewReader
+func NewReader(format spec.AudioFormat) *Reader {
+	return mixDefault.NewReader(format)
+}
+
+// OutputStartAuto on the default mixer, see Mixer.OutputStartAuto
+func OutputStartAuto(out io.Writer, extraTail time.Duration) time.Duration {
+	return mixDefault.OutputStartAuto(out, extraTail)
+}
+
+// OutputStart on the default mixer, see Mixer.OutputStart
+func OutputStart(length time.Duration, out io.Writer) {
+	mixDefault.OutputStart(length, out)
+}
+
+// SetOutputMetadata on the default mixer, see Mixer.SetOutputMetadata
+func SetOutputMetadata(meta Metadata) {
+	mixDefault.SetOutputMetadata(meta)
+}
+
+// GetOutputMetadata of the default mixer, see Mixer.GetOutputMetadata
+func GetOutputMetadata() Metadata {
+	return mixDefault.GetOutputMetadata()
+}
+
+// OutputClose on the default mixer, see Mixer.OutputClose
+func OutputClose() error {
+	return mixDefault.OutputClose()
+}
+
+// SetOutputPipe on the default mixer, see Mixer.SetOutputPipe
+func SetOutputPipe(on bool) {
+	mixDefault.SetOutputPipe(on)
+}
+
+// GetOutputPipe of the default mixer, see Mixer.GetOutputPipe
+func GetOutputPipe() bool {
+	return mixDefault.GetOutputPipe()
+}
+
+// OnOutputError on the default mixer, see Mixer.OnOutputError
+func OnOutputError(fn func(err error)) {
+	mixDefault.OnOutputError(fn)
+}
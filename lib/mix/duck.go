@@ -0,0 +1,104 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// SetDucking of a target bus by a trigger bus, the classic sidechain pump: whenever the trigger bus sounds, the target bus
+// is ducked by a depth in dB, ramping down over the attack and back up over the release, smoothly from sample to sample;
+// the release begins once the trigger has been silent for a few milliseconds, so that it does not flutter at each zero crossing.
+// The trigger is the sum of the fires on its bus before its own volume, pan and effects, so a muted bus still ducks, e.g.
+// a ghost kick. Buses are named as by NewBus, and created if need be; the empty name is the default master bus.
+// Setting the ducking of the same pair of buses again replaces it, and a depth of 0 removes it. When more than one trigger
+// ducks the same target, the deepest reduction of them at each sample wins, so that two triggers at once do not duck it twice.
+func (m *Mixer) SetDucking(triggerBus string, targetBus string, depthDB float64, attack time.Duration, release time.Duration) {
+	trigger := m.NewBus(triggerBus)
+	target := m.NewBus(targetBus)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	keep := m.mixDuckers[:0]
+	for _, d := range m.mixDuckers {
+		if d.trigger != trigger || d.target != target {
+			keep = append(keep, d)
+		}
+	}
+	m.mixDuckers = keep
+	if depthDB <= 0 {
+		target.duck = 0
+		return
+	}
+	m.mixDuckers = append(m.mixDuckers, &mixDucker{
+		trigger: trigger,
+		target:  target,
+		depth:   depthDB,
+		attack:  attack,
+		release: release,
+	})
+}
+
+// GetDucking of a bus, its reduction in dB by the buses that trigger its ducking as of the last sample mixed, or 0 if it is not ducked
+func (m *Mixer) GetDucking(bus string) float64 {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if b, ok := m.mixBuses[bus]; ok {
+		return b.duck
+	}
+	return 0
+}
+
+//
+// Private
+//
+
+const (
+	mixDuckThreshold = 0.001                // of the peak of the sum of a trigger bus, above which it sounds, i.e. -60dBFS
+	mixDuckHold      = 5 * time.Millisecond // after a trigger falls silent, before the release
+)
+
+// mixDucker of a target bus by a trigger bus, see SetDucking
+type mixDucker struct {
+	trigger   *Bus
+	target    *Bus
+	depth     float64 // in dB
+	attack    time.Duration
+	release   time.Duration
+	heldTz    spec.Tz // # of samples left to hold, since the trigger last sounded
+	reduction float64 // in dB, ramps toward the depth while the trigger sounds, else toward 0
+}
+
+// mixDuck each target bus by its triggers, from the sum of each trigger bus before it is mixed; the caller must hold the mixMutex
+func (m *Mixer) mixDuck() {
+	if len(m.mixDuckers) == 0 {
+		return
+	}
+	for _, d := range m.mixDuckers {
+		d.target.duck = 0
+	}
+	for _, d := range m.mixDuckers {
+		if busSounds(d.trigger.sum) {
+			d.heldTz = m.mixTzOf(mixDuckHold)
+		}
+		if d.heldTz > 0 {
+			d.heldTz--
+			d.reduction = math.Min(d.depth, d.reduction+d.depth/math.Max(1, float64(m.mixTzOf(d.attack))))
+		} else {
+			d.reduction = math.Max(0, d.reduction-d.depth/math.Max(1, float64(m.mixTzOf(d.release))))
+		}
+		d.target.duck = math.Max(d.target.duck, d.reduction)
+	}
+}
+
+// busSounds if the peak of its sum is above the threshold of ducking
+func busSounds(sum []sample.Value) bool {
+	for _, v := range sum {
+		if math.Abs(float64(v)) > mixDuckThreshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetDucking(t *testing.T) {
+	testMixSetup()
+	SetMixAlgorithm(MixHardClip)
+	testDuckPads()
+	NewBus("kick").Mute() // a ghost kick ducks, but is not heard
+	SetFireOnBus(NewBus("kick"), source.ToneKey(source.WaveSine, 100, 100*time.Millisecond), 100*time.Millisecond, 0, 1, 0)
+	SetDucking("kick", "pads", 12, time.Millisecond, 10*time.Millisecond)
+	assert.InDelta(t, 0.5, testDuckPeak(50*time.Millisecond, 100*time.Millisecond), 0.01)
+	assert.InDelta(t, 0.5*math.Pow(10, -12.0/20), testDuckPeak(110*time.Millisecond, 190*time.Millisecond), 0.01)
+	assert.InDelta(t, float64(12), GetDucking("pads"), 0.01)
+	assert.InDelta(t, 0.5, testDuckPeak(250*time.Millisecond, 300*time.Millisecond), 0.01)
+	assert.Equal(t, float64(0), GetDucking("pads"))
+	assert.Equal(t, float64(0), GetDucking("kick"))
+}
+
+// the deepest of the duckers of a bus wins, rather than their sum
+func TestSetDucking_Combine(t *testing.T) {
+	testMixSetup()
+	testDuckPads()
+	SetFireOnBus(NewBus("kick"), source.ToneKey(source.WaveSine, 100, 100*time.Millisecond), 0, 0, 1, 0)
+	SetFireOnBus(NewBus("snare"), source.ToneKey(source.WaveSine, 200, 100*time.Millisecond), 0, 0, 1, 0)
+	SetDucking("kick", "pads", 12, 0, 0)
+	SetDucking("snare", "pads", 6, 0, 0)
+	testMixFor(50 * time.Millisecond)
+	assert.Equal(t, float64(12), GetDucking("pads"))
+	SetDucking("kick", "pads", 0, 0, 0)
+	NextSample()
+	assert.Equal(t, float64(6), GetDucking("pads"))
+	Teardown()
+	assert.Equal(t, 0, len(mixDefault.mixDuckers))
+}
+
+//
+// Test Components
+//
+
+// testDuckPads plays a steady tone on the pads bus, at half volume
+func testDuckPads() {
+	SetFireOnBus(NewBus("pads"), source.ToneKey(source.WaveSine, 441, time.Second), 0, 0, 0.5, 0)
+}
+
+// testDuckPeak of the output from one time to another
+func testDuckPeak(from time.Duration, to time.Duration) (peak float64) {
+	for mixDefault.nowTz < mixDefault.mixTzOf(from) {
+		NextSample()
+	}
+	for mixDefault.nowTz < mixDefault.mixTzOf(to) {
+		peak = math.Max(peak, math.Abs(float64(NextSample()[0])))
+	}
+	return
+}
@@ -0,0 +1,68 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/lib/effect"
+)
+
+// AddMasterEffect to the end of the chain of effects on the master output, applied after all buses are summed and before dynamic range compression.
+func (m *Mixer) AddMasterEffect(e effect.Effect) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.masterEffects = append(m.masterEffects, e)
+}
+
+// RemoveMasterEffect from the chain of effects on the master output.
+func (m *Mixer) RemoveMasterEffect(e effect.Effect) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.masterEffects = mixWithoutEffect(m.masterEffects, e)
+}
+
+// AddEffect to the end of the chain of effects on the bus, applied to the sum of its fires before the bus volume and pan.
+func (b *Bus) AddEffect(e effect.Effect) {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.effects = append(b.effects, e)
+}
+
+// RemoveEffect from the chain of effects on the bus.
+func (b *Bus) RemoveEffect(e effect.Effect) {
+	b.mixer.mixMutex.Lock()
+	defer b.mixer.mixMutex.Unlock()
+	b.effects = mixWithoutEffect(b.effects, e)
+}
+
+//
+// Private
+//
+
+// mixProcessEffects on one sample of all channels, in place
+func (m *Mixer) mixProcessEffects(effects []effect.Effect, smp []sample.Value) {
+	if len(effects) == 0 {
+		return
+	}
+	if len(m.effectBuffer) != len(smp) {
+		m.effectBuffer = make([]float64, len(smp))
+	}
+	for c, v := range smp {
+		m.effectBuffer[c] = float64(v)
+	}
+	for _, e := range effects {
+		effect.ProcessAt(e, m.effectBuffer, len(smp), m.masterFreq)
+	}
+	for c, v := range m.effectBuffer {
+		smp[c] = sample.Value(v)
+	}
+}
+
+func mixWithoutEffect(effects []effect.Effect, remove effect.Effect) []effect.Effect {
+	keep := make([]effect.Effect, 0, len(effects))
+	for _, e := range effects {
+		if e != remove {
+			keep = append(keep, e)
+		}
+	}
+	return keep
+}
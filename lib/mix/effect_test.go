@@ -0,0 +1,50 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/effect"
+)
+
+func TestAddMasterEffect(t *testing.T) {
+	testMixSetup()
+	whole := testBusPeak(nil)
+	testMixSetup()
+	half := &testGainEffect{gain: 0.5}
+	AddMasterEffect(half)
+	assert.InDelta(t, whole/2, testBusPeak(nil), 0.001)
+	assert.True(t, half.calls > 0)
+	RemoveMasterEffect(half)
+	assert.Equal(t, 0, len(mixDefault.masterEffects))
+}
+
+func TestBus_AddEffect(t *testing.T) {
+	testMixSetup()
+	hats := NewBus("hats")
+	silence := &testGainEffect{gain: 0}
+	hats.AddEffect(silence)
+	hats.AddEffect(effect.NewHighPass(1000, 0.7071))
+	assert.Equal(t, float64(0), testBusPeak(hats))
+	hats.RemoveEffect(silence)
+	assert.Equal(t, 1, len(hats.effects))
+	assert.True(t, testBusPeak(hats) > 0)
+}
+
+//
+// Private
+//
+
+type testGainEffect struct {
+	gain  float64
+	calls int
+}
+
+func (e *testGainEffect) Process(in []float64, channels int) {
+	e.calls++
+	for i := range in {
+		in[i] *= e.gain
+	}
+}
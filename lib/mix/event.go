@@ -0,0 +1,67 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// FireEventBufferSize is the # of events buffered for the subscriber; when it is full, the oldest event is dropped.
+const FireEventBufferSize = 256
+
+// FireEvent is emitted from the mix loop when a fire starts or finishes playing.
+type FireEvent struct {
+	Fire  *fire.Fire
+	State fire.StateEnum // fire.StatePlay when it starts, or fire.StateDone when it finishes
+	At    time.Duration  // mixer time of the transition, not the wall-clock time it was received
+}
+
+// FireEvents returns the channel of events as fires start and finish playing. Every call returns the same channel.
+// The mix loop never blocks on delivery: if the channel is full, the oldest event is dropped.
+func (m *Mixer) FireEvents() <-chan FireEvent {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.mixFireEvents == nil {
+		m.mixFireEvents = make(chan FireEvent, FireEventBufferSize)
+	}
+	return m.mixFireEvents
+}
+
+//
+// Private
+//
+
+// mixFireAt advances a live fire to a mix time, returning the Tz of the fire and whether it sounds, as fire.PlayAt, and the state
+// it transitioned to if it started or finished playing, else zero, see mixFireTransition
+func (m *Mixer) mixFireAt(f *fire.Fire, at spec.Tz) (t spec.Tz, playing bool, transition fire.StateEnum) {
+	return f.Advance(at)
+}
+
+// mixFireTransition of a live fire at the current Tz: a fire that starts playing chokes its group, and an event is emitted
+func (m *Mixer) mixFireTransition(f *fire.Fire, state fire.StateEnum) {
+	if state == fire.StatePlay {
+		m.mixChoke(f)
+	}
+	m.mixEmitFireEvent(f, state)
+}
+
+// mixEmitFireEvent without blocking, dropping the oldest event if the buffer is full
+func (m *Mixer) mixEmitFireEvent(f *fire.Fire, state fire.StateEnum) {
+	if m.mixFireEvents == nil {
+		return
+	}
+	event := FireEvent{Fire: f, State: state, At: m.mixSchedDurOf(m.nowTz)}
+	for {
+		select {
+		case m.mixFireEvents <- event:
+			return
+		default:
+			select {
+			case <-m.mixFireEvents:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestFireEvents(t *testing.T) {
+	testMixSetup()
+	events := FireEvents()
+	testDrainFireEvents(events)
+	f := SetFire("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 10*time.Millisecond, 20*time.Millisecond, 1.0, 0)
+	for n := 0; n < 4410; n++ {
+		NextSample()
+	}
+	assert.Equal(t, 2, len(events))
+	start := <-events
+	assert.Equal(t, f, start.Fire)
+	assert.Equal(t, fire.StatePlay, start.State)
+	assert.InDelta(t, float64(10*time.Millisecond), float64(start.At), float64(mixDefault.mixDurOf(1)))
+	done := <-events
+	assert.Equal(t, f, done.Fire)
+	assert.Equal(t, fire.StateDone, done.State)
+	assert.InDelta(t, float64(30*time.Millisecond), float64(done.At), float64(mixDefault.mixDurOf(1)))
+}
+
+func TestFireEvents_DropOldest(t *testing.T) {
+	testMixSetup()
+	events := FireEvents()
+	testDrainFireEvents(events)
+	f := fire.New("test", 0, 0, 1.0, 0)
+	for n := 0; n < FireEventBufferSize+10; n++ {
+		mixDefault.nowTz = spec.Tz(n)
+		mixDefault.mixEmitFireEvent(f, fire.StatePlay)
+	}
+	assert.Equal(t, FireEventBufferSize, len(events))
+	assert.Equal(t, mixDefault.mixDurOf(10), (<-events).At)
+	testDrainFireEvents(events)
+}
+
+func TestOnDone_Chain(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	riser := SetFire(src, 0, 50*time.Millisecond, 1.0, 0)
+	drops := make(chan *fire.Fire, 1)
+	riser.OnDone(func(f *fire.Fire) {
+		drops <- FireNow(src, 0, 1.0, 0)
+	})
+	testMixFor(100 * time.Millisecond)
+	select {
+	case drop := <-drops:
+		assert.Equal(t, fire.DoneSustain, riser.DoneReason())
+		assert.True(t, drop.BeginTz >= mixDefault.mixTzOf(50*time.Millisecond))
+	case <-time.After(time.Second):
+		t.Fatal("hook was not called")
+	}
+}
+
+func TestOnDone_Teardown(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	ready, live := SetFire(src, 10*time.Second, 0, 1.0, 0), SetFire(src, 0, 0, 1.0, 0)
+	testMixFor(10 * time.Millisecond)
+	done := make(chan *fire.Fire, 2)
+	ready.OnDone(func(f *fire.Fire) {
+		done <- f
+	})
+	live.OnDone(func(f *fire.Fire) {
+		done <- f
+	})
+	Teardown()
+	for i := 0; i < 2; i++ {
+		select {
+		case f := <-done:
+			assert.Equal(t, fire.DoneCanceled, f.DoneReason())
+		case <-time.After(time.Second):
+			t.Fatal("hook was not called")
+		}
+	}
+}
+
+//
+// Private
+//
+
+func testDrainFireEvents(events <-chan FireEvent) {
+	for len(events) > 0 {
+		<-events
+	}
+}
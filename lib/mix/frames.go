@@ -0,0 +1,100 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// GetNowFrames of the mix position, the # of frames since the start that the output has taken, which counts up by one for each;
+// with a lookahead, it is of what is heard, not of the mix ahead of it, the same position as GetNowAt, see SetLookahead. Unlike
+// GetNowAt, it is never rounded, and it is read without waiting for the mix loop, from any goroutine.
+func (m *Mixer) GetNowFrames() spec.Tz {
+	return spec.Tz(atomic.LoadInt64(&m.mixNowFrames))
+}
+
+// DurationToFrames of a time of the schedule since the start, to the frame that contains it, i.e. floor(d × frequency ÷ time scale),
+// which is also the frame that a fire set at d begins on, see SetTimeScale. A negative duration is frame 0. Read without waiting for
+// the mix loop, from any goroutine.
+func (m *Mixer) DurationToFrames(d time.Duration) spec.Tz {
+	return framesOf(d, m.mixSchedFreq())
+}
+
+// FramesToDuration in time of the schedule since the start, of the beginning of a frame, rounded up to the next whole nanosecond,
+// i.e. ceil(f × time scale / frequency), such that DurationToFrames(FramesToDuration(f)) is always f again. Read without waiting
+// for the mix loop.
+func (m *Mixer) FramesToDuration(f spec.Tz) time.Duration {
+	return durationOf(f, m.mixSchedFreq())
+}
+
+// AtFrame of the mix, the time by the mixer clock at which it is predicted to be heard, from the start time, i.e. when the output
+// takes it, like GetNowFrames, not when it is mixed ahead of that, see SetLookahead, e.g. for a
+// video frame or a lighting cue to be scheduled by another system, aligned to the audio clock; the prediction is only
+// good while playing, as a pause or a seek moves the start time. Read without waiting for the mix loop, from any goroutine.
+func (m *Mixer) AtFrame(f spec.Tz) time.Time {
+	startAt, _ := m.mixStartAt.Load().(time.Time)
+	return startAt.Add(durationOf(f, m.mixFreq())) // frames play in real time, whatever the time scale
+}
+
+//
+// Private
+//
+
+// mixPublishClock for DurationToFrames, FramesToDuration and AtFrame to read without the mixMutex, and the position heard for
+// GetNowAt and GetNowFrames; as it plays, each frame heard is published by the mix loop, or with a lookahead, by the output that
+// takes it. The caller must hold the mixMutex
+func (m *Mixer) mixPublishClock() {
+	m.mixPublishHeard()
+	m.mixPublishFreq()
+	m.mixStartAt.Store(m.startAtTime)
+}
+
+// mixPublishFreq of the master, and of the schedule at the time scale; the caller must hold the mixMutex
+func (m *Mixer) mixPublishFreq() {
+	atomic.StoreUint64(&m.mixFreqBits, math.Float64bits(m.masterFreq))
+	atomic.StoreUint64(&m.mixSchedFreqBits, math.Float64bits(m.masterFreq/m.mixTimeScale))
+}
+
+// mixFreq of the master, as published, or 0 before the mixer is configured
+func (m *Mixer) mixFreq() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&m.mixFreqBits))
+}
+
+// mixSchedFreq of the schedule, the master frequency divided by the time scale, as published, or 0 before the mixer is configured
+func (m *Mixer) mixSchedFreq() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&m.mixSchedFreqBits))
+}
+
+// framesOf a duration at a frequency, floor(d × freq), in integers for a whole frequency, so that it never rounds down
+// a frame that is exactly on time
+func framesOf(d time.Duration, freq float64) spec.Tz {
+	if d <= 0 || freq <= 0 {
+		return 0
+	}
+	if whole := int64(freq); float64(whole) == freq {
+		return spec.Tz(int64(d)/int64(time.Second)*whole + int64(d)%int64(time.Second)*whole/int64(time.Second))
+	}
+	return spec.Tz(math.Floor(d.Seconds() * freq))
+}
+
+// durationOf a frame at a frequency, ceil(f / freq) in nanoseconds, the inverse of framesOf
+func durationOf(f spec.Tz, freq float64) time.Duration {
+	if freq <= 0 {
+		return 0
+	}
+	if whole := int64(freq); float64(whole) == freq {
+		tz := int64(f)
+		return time.Duration(tz/whole*int64(time.Second) + (tz%whole*int64(time.Second)+whole-1)/whole)
+	}
+	d := time.Duration(math.Ceil(float64(f) * float64(time.Second) / freq))
+	for framesOf(d, freq) < f { // the float rounded down
+		d++
+	}
+	for d > 0 && framesOf(d-1, freq) >= f { // or up
+		d--
+	}
+	return d
+}
@@ -0,0 +1,13 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"io/fs"
+
+	"github.com/go-mix/mix/lib/source"
+)
+
+// SetSoundsFS to load sources from a file system, e.g. an embed.FS, as an alternative to the OS; the sounds path prefix still applies.
+func SetSoundsFS(fsys fs.FS) {
+	source.SetFS(fsys)
+}
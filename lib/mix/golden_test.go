@@ -0,0 +1,105 @@
+// Package mix combines sources into an output audio stream
+package mix_test
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/mix"
+	"github.com/go-mix/mix/lib/testutil"
+)
+
+// Golden tests of the output of the mixer, sample by sample; in package mix_test, as testutil imports mix.
+// After a deliberate change to the mixing algorithm, regenerate them with `go test ./lib/mix/ -run TestGolden -update`.
+
+func TestGolden_SingleFire(t *testing.T) {
+	testGolden(t, "single-fire", func(m *mix.Mixer) error {
+		_, err := m.SetFireErr("golden-sine", 10*time.Millisecond, 0, 1.0, 0)
+		return err
+	})
+}
+
+func TestGolden_Overlapping(t *testing.T) {
+	testGolden(t, "overlapping", func(m *mix.Mixer) error {
+		if _, err := m.SetFireErr("golden-sine", 10*time.Millisecond, 0, 1.0, 0); err != nil {
+			return err
+		}
+		_, err := m.SetFireErr("golden-square", 30*time.Millisecond, 0, 1.0, 0) // summed above the threshold of the compression
+		return err
+	})
+}
+
+func TestGolden_PanExtremes(t *testing.T) {
+	testGolden(t, "pan-extremes", func(m *mix.Mixer) error {
+		m.SetPanLaw(mix.PanBalance) // to silence the other side, which the default law does not
+		if _, err := m.SetFireErr("golden-sine", 0, 0, 1.0, -1); err != nil {
+			return err
+		}
+		_, err := m.SetFireErr("golden-sine", 50*time.Millisecond, 0, 1.0, 1)
+		return err
+	})
+}
+
+func TestGolden_SustainTruncation(t *testing.T) {
+	testGolden(t, "sustain-truncation", func(m *mix.Mixer) error {
+		_, err := m.SetFireErr("golden-sine", 10*time.Millisecond, 30*time.Millisecond, 1.0, 0)
+		return err
+	})
+}
+
+func TestGolden_MidCycle(t *testing.T) {
+	testGoldenSetup(t)
+	m, err := mix.New(testGoldenSpec)
+	assert.Nil(t, err)
+	defer m.Teardown()
+	m.SetCycleDuration(20 * time.Millisecond)
+	out, err := m.Render(30 * time.Millisecond)
+	assert.Nil(t, err)
+	_, err = m.SetFireErr("golden-sine", 47*time.Millisecond, 0, 1.0, 0.5) // after the mix began, between cycle boundaries
+	assert.Nil(t, err)
+	rest, err := m.Render(120 * time.Millisecond)
+	assert.Nil(t, err)
+	testutil.Golden(t, testGoldenPath("mid-cycle"), testGoldenSpec, append(out, rest...), testGoldenTolerance)
+}
+
+//
+// Private
+//
+
+// testGoldenSpec of the output of every golden, short and at a low frequency to keep the goldens small
+var testGoldenSpec = spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 2}
+
+// testGoldenTolerance of the precision of a golden in F32
+var testGoldenTolerance = testutil.Tolerance{MaxDiff: 1e-6}
+
+// testGolden renders a schedule for 150ms and compares it to a golden
+func testGolden(t *testing.T, name string, schedule func(m *mix.Mixer) error) {
+	testGoldenSetup(t)
+	out, err := testutil.Render(testGoldenSpec, 150*time.Millisecond, schedule)
+	assert.Nil(t, err)
+	assert.Equal(t, int(math.Round(0.15*testGoldenSpec.Freq))*testGoldenSpec.Channels, len(out)) // to the nearest frame
+	testutil.Golden(t, testGoldenPath(name), testGoldenSpec, out, testGoldenTolerance)
+}
+
+// testGoldenSetup registers the in-memory sources of the goldens: 100ms of a sine and of a square wave, mono at 22.05kHz
+func testGoldenSetup(t *testing.T) {
+	s := spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 1}
+	frames := int(0.1 * s.Freq)
+	sine, square := make([]float64, frames), make([]float64, frames)
+	for n := range sine {
+		sine[n] = 0.8 * math.Sin(2*math.Pi*441*float64(n)/s.Freq)
+		square[n] = 0.9 * math.Copysign(1, math.Sin(2*math.Pi*147*float64(n)/s.Freq))
+	}
+	assert.Nil(t, testutil.RegisterSource("golden-sine", s, sine))
+	assert.Nil(t, testutil.RegisterSource("golden-square", s, square))
+}
+
+// testGoldenPath of a golden by name
+func testGoldenPath(name string) string {
+	return filepath.Join("testdata", "golden", name+".wav")
+}
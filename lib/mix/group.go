@@ -0,0 +1,171 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// FireGroup of fires, e.g. a musical phrase, to shift, scale or cancel as a unit, see NewFireGroup. It only tracks the membership
+// of its fires: each operation applies to the members that have not yet gone live, atomically with respect to the mix loop, such
+// that a partially shifted phrase is never heard, and counts the members it skipped because they already had. A group is safe for
+// concurrent use; groups do not nest.
+type FireGroup struct {
+	mixer *Mixer
+	fires []*fire.Fire // guarded by the mixMutex of the mixer
+}
+
+// NewFireGroup of the mixer, empty
+func (m *Mixer) NewFireGroup() *FireGroup {
+	return &FireGroup{mixer: m}
+}
+
+// SetFire is Mixer.SetFire, of a member of the group, which joins it before it is scheduled; nil, and nothing joins, if the source
+// cannot be loaded
+func (g *FireGroup) SetFire(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	m := g.mixer
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.FireGroup.SetFire(%s) failed: %s", source, err)
+		return nil
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	g.fires = append(g.fires, f)
+	fires := []*fire.Fire{f}
+	m.mixCaptureFires(fires)
+	m.mixPushFires(fires)
+	return f
+}
+
+// Add fires to the group, e.g. set by SetFireAtStep; nil fires, and members, are ignored
+func (g *FireGroup) Add(fires ...*fire.Fire) {
+	g.mixer.mixMutex.Lock()
+	defer g.mixer.mixMutex.Unlock()
+	member := g.members()
+	for _, f := range fires {
+		if f != nil && !member[f] {
+			g.fires = append(g.fires, f)
+			member[f] = true
+		}
+	}
+}
+
+// Len of the group, the # of its members that are still alive, i.e. not done nor canceled
+func (g *FireGroup) Len() int {
+	g.mixer.mixMutex.Lock()
+	defer g.mixer.mixMutex.Unlock()
+	g.prune()
+	return len(g.fires)
+}
+
+// ShiftBy a duration the begin, and end, of the members that have not yet gone live, later, or earlier if negative, but never
+// before time zero. Returns the # shifted, and the # skipped because they already went live, or are waiting on a previous fire,
+// see SetFireAfter. Fires already chained after a member keep their begin.
+func (g *FireGroup) ShiftBy(d time.Duration) (shifted int, skipped int) {
+	m := g.mixer
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	shiftTz := int64(m.mixSchedTzOf(d))
+	if d < 0 {
+		shiftTz = -int64(m.mixSchedTzOf(-d))
+	}
+	ready, skipped := g.removeReady()
+	for _, f := range ready {
+		beginTz := int64(f.BeginTz) + shiftTz
+		if beginTz < 0 {
+			beginTz = 0
+		}
+		if f.EndTz != 0 {
+			f.EndTz = spec.Tz(beginTz) + f.EndTz - f.BeginTz // keeps its sustain
+		}
+		f.BeginTz = spec.Tz(beginTz)
+		m.mixReadyFires.Push(f)
+	}
+	m.mixRecountHorizon()
+	m.mixCountFires()
+	return len(ready), skipped
+}
+
+// ScaleVolume of the members that have not yet gone live by a factor, e.g. 0.5, clamped from 0 to 1 like fire.SetVolume.
+// Returns the # scaled, and the # skipped because they already went live, or are waiting on a previous fire, see SetFireAfter.
+func (g *FireGroup) ScaleVolume(factor float64) (scaled int, skipped int) {
+	m := g.mixer
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	member := g.members()
+	m.mixReadyFires.Each(func(f *fire.Fire) {
+		if member[f] {
+			f.ScaleVolume(factor)
+			scaled++
+		}
+	})
+	return scaled, len(g.fires) - scaled
+}
+
+// Cancel the members that have not yet gone live, such that they never play, and remove them from the group.
+// Returns the # canceled, and the # skipped because they already went live, or are waiting on a previous fire, see SetFireAfter.
+func (g *FireGroup) Cancel() (canceled int, skipped int) {
+	m := g.mixer
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	ready, skipped := g.removeReady()
+	for _, f := range ready {
+		f.Cancel()
+	}
+	g.prune()
+	m.mixRecountHorizon()
+	m.mixCountFires()
+	return len(ready), skipped
+}
+
+//
+// Private
+//
+
+// removeReady members from the ready queue, returning them in the order they joined the group, and the # of the other members
+// that are still alive; the caller must hold the mixMutex
+func (g *FireGroup) removeReady() (ready []*fire.Fire, others int) {
+	member := g.members()
+	g.mixer.mixReadyFires.Remove(func(f *fire.Fire) bool {
+		if member[f] {
+			member[f] = false // removed
+			return true
+		}
+		return false
+	})
+	for _, f := range g.fires {
+		if !member[f] {
+			ready = append(ready, f)
+		}
+	}
+	return ready, len(g.fires) - len(ready)
+}
+
+// members of the group that are still alive, after pruning the others; the caller must hold the mixMutex
+func (g *FireGroup) members() map[*fire.Fire]bool {
+	g.prune()
+	member := make(map[*fire.Fire]bool, len(g.fires))
+	for _, f := range g.fires {
+		member[f] = true
+	}
+	return member
+}
+
+// prune the members that are done or canceled, so that the group only holds the fires it may still affect;
+// the caller must hold the mixMutex
+func (g *FireGroup) prune() {
+	alive := g.fires[:0]
+	for _, f := range g.fires {
+		if f.IsAlive() {
+			alive = append(alive, f)
+		}
+	}
+	for i := len(alive); i < len(g.fires); i++ {
+		g.fires[i] = nil // release the fire to the garbage collector
+	}
+	g.fires = alive
+}
@@ -0,0 +1,72 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestFireGroup(t *testing.T) {
+	testMixSetup()
+	SetCycleDuration(10 * time.Millisecond)
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	g := NewFireGroup()
+	var phrase []*fire.Fire
+	for n := 0; n < 4; n++ {
+		phrase = append(phrase, g.SetFire(src, time.Second+time.Duration(n)*100*time.Millisecond, 50*time.Millisecond, 0.8, 0))
+	}
+	step := SetFireAtStep(src, 12, 1, 0.8, 0) // 1.5 seconds
+	g.Add(step, nil, phrase[0])
+	assert.Equal(t, 5, g.Len())
+	other := SetFire(src, time.Second, 0, 1.0, 0)
+	shifted, skipped := g.ShiftBy(500 * time.Millisecond)
+	assert.Equal(t, 5, shifted)
+	assert.Equal(t, 0, skipped)
+	assert.Equal(t, 1500*time.Millisecond, phrase[0].BeginAt())
+	assert.Equal(t, 50*time.Millisecond, phrase[0].Sustain())
+	assert.Equal(t, 2*time.Second, step.BeginAt())
+	assert.Equal(t, time.Second, other.BeginAt()) // not a member
+	scaled, skipped := g.ScaleVolume(0.5)
+	assert.Equal(t, 5, scaled)
+	assert.Equal(t, 0, skipped)
+	assert.Equal(t, 0.4, phrase[3].Volume)
+	assert.Equal(t, 1.0, other.Volume)
+	for n := 0; n < 44100*155/100; n++ { // until the first fire of the phrase has begun
+		NextSample()
+	}
+	assert.True(t, phrase[0].IsPlaying())
+	shifted, skipped = g.ShiftBy(-100 * time.Millisecond)
+	assert.Equal(t, 4, shifted)
+	assert.Equal(t, 1, skipped) // already live
+	assert.Equal(t, 1500*time.Millisecond, phrase[0].BeginAt())
+	assert.Equal(t, 1500*time.Millisecond, phrase[1].BeginAt())
+	canceled, skipped := g.Cancel()
+	assert.Equal(t, 4, canceled)
+	assert.Equal(t, 1, skipped)
+	assert.True(t, phrase[3].IsCanceled())
+	assert.True(t, step.IsCanceled())
+	assert.False(t, phrase[0].IsCanceled())
+	assert.Equal(t, 1, g.Len())
+	assert.Equal(t, 1, FireCount()) // the first of the phrase, the other having ended
+}
+
+func TestFireGroup_ShiftBeforeZero(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	g := NewFireGroup()
+	f := g.SetFire(src, 200*time.Millisecond, 50*time.Millisecond, 1.0, 0)
+	natural := g.SetFire(src, 300*time.Millisecond, 0, 1.0, 0)
+	assert.Nil(t, g.SetFire("ThisDoesNotExist.wav", 0, 0, 1.0, 0))
+	shifted, skipped := g.ShiftBy(-250 * time.Millisecond)
+	assert.Equal(t, 2, shifted)
+	assert.Equal(t, 0, skipped)
+	assert.Equal(t, time.Duration(0), f.BeginAt()) // never before time zero
+	assert.Equal(t, 50*time.Millisecond, f.Sustain())
+	assert.Equal(t, 50*time.Millisecond, natural.BeginAt())
+	assert.Equal(t, 100*time.Millisecond, natural.Sustain())
+}
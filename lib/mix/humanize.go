@@ -0,0 +1,42 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math/rand"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// HumanizeSpec of the random variation of the volume, pan and timing of each fire, see SetHumanize
+type HumanizeSpec = fire.HumanizeSpec
+
+// SetHumanize of every fire, e.g. VolumeJitter 0.1, PanJitter 0.2 and TimingJitter 10ms for a drum pattern that does not sound like
+// a machine; a zero HumanizeSpec (the default) for none. Each fire is varied as it goes live, in order of playback, by jitters drawn
+// from the random seed, so a render is reproducible, see SetRandomSeed. A fire's own humanize, see fire.SetHumanize, takes precedence.
+// The volume is clamped to 0 to 1, the pan to -1 to +1, and a fire never begins before zero, nor before the mix time as it goes live.
+func (m *Mixer) SetHumanize(h HumanizeSpec) {
+	if h.VolumeJitter < 0 || h.PanJitter < 0 || h.TimingJitter < 0 {
+		debug.Warnf("mix.SetHumanize(%+v) ignored: jitters must not be negative", h)
+		return
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixHumanize = h
+}
+
+// GetHumanize of every fire, see SetHumanize
+func (m *Mixer) GetHumanize() HumanizeSpec {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixHumanize
+}
+
+//
+// Private
+//
+
+// mixResetHumanize of the fires, from the random seed; the caller must hold the mixMutex
+func (m *Mixer) mixResetHumanize() {
+	m.mixHumanizeRandom = rand.New(rand.NewSource(m.mixRandomSeed))
+}
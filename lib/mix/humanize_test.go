@@ -0,0 +1,66 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+func TestSetHumanize(t *testing.T) {
+	type hit struct {
+		begin, end  int64
+		volume, pan float64
+	}
+	render := func() (hits []hit) {
+		testMixSetup()
+		SetRandomSeed(7)
+		SetHumanize(HumanizeSpec{VolumeJitter: 0.2, PanJitter: 0.5, TimingJitter: 10 * time.Millisecond})
+		var fires []*fire.Fire
+		for n := 0; n < 8; n++ {
+			fires = append(fires, SetFireTone(441, time.Duration(n)*100*time.Millisecond, 50*time.Millisecond, 0.9, 0))
+		}
+		for n := 0; n < 44100; n++ {
+			NextSample()
+		}
+		for _, f := range fires {
+			hits = append(hits, hit{int64(f.BeginTz), int64(f.EndTz), f.Volume, f.Pan})
+		}
+		return
+	}
+	first := render()
+	assert.Equal(t, first, render()) // reproducible
+	varied := 0
+	for n, h := range first {
+		nominal := int64(n * 4410)
+		assert.True(t, h.begin >= 0 && h.begin >= nominal-441 && h.begin <= nominal+441)
+		assert.Equal(t, int64(2205), h.end-h.begin)
+		assert.True(t, h.volume >= 0.7 && h.volume <= 1)
+		assert.True(t, h.pan >= -0.5 && h.pan <= 0.5)
+		if (n == 0 || h.begin != nominal) && h.volume != 0.9 && h.pan != 0 { // the first may be clamped to zero
+			varied++
+		}
+	}
+	assert.Equal(t, len(first), varied)
+	assert.Equal(t, 10*time.Millisecond, GetHumanize().TimingJitter)
+	Teardown()
+	assert.True(t, GetHumanize().IsZero())
+}
+
+func TestSetHumanize_Fire(t *testing.T) {
+	testMixSetup()
+	SetHumanize(HumanizeSpec{VolumeJitter: 0.5, TimingJitter: 50 * time.Millisecond})
+	exact := SetFireTone(441, time.Second, 50*time.Millisecond, 0.5, 0)
+	exact.SetHumanize(HumanizeSpec{})           // overrides the mixer, after it was set
+	SetHumanize(HumanizeSpec{VolumeJitter: -1}) // ignored
+	for n := 0; n < 2*44100; n++ {
+		NextSample()
+	}
+	assert.Equal(t, spec.Tz(44100), exact.BeginTz)
+	assert.Equal(t, 0.5, exact.Volume)
+	assert.Equal(t, 0.5, GetHumanize().VolumeJitter)
+}
@@ -0,0 +1,36 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"sync/atomic"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/lib/level"
+)
+
+// GetOutputLevel of each channel over the last mix cycle, after the master volume but before dynamic range compression.
+// Safe to call from any goroutine, e.g. to draw VU meters; see level.DBFS to convert to decibels.
+func (m *Mixer) GetOutputLevel() level.Level {
+	return m.masterMeter.Level()
+}
+
+// GetClipCount is the # of samples since Teardown that reached 1.0 or above in any channel, overdriving the compressor.
+func (m *Mixer) GetClipCount() uint64 {
+	return atomic.LoadUint64(&m.masterClipCount)
+}
+
+//
+// Private
+//
+
+// mixMeterOutput of one sample, before compression, counting it if it clips
+func (m *Mixer) mixMeterOutput(values []sample.Value) {
+	if m.masterMeter.Add(values) {
+		atomic.AddUint64(&m.masterClipCount, 1)
+	}
+}
+
+func (m *Mixer) mixResetMeter() {
+	m.masterMeter.Reset()
+	atomic.StoreUint64(&m.masterClipCount, 0)
+}
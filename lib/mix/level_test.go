@@ -0,0 +1,41 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOutputLevel(t *testing.T) {
+	testMixSetup()
+	SetCycleDuration(time.Second)
+	f := SetFireLoop("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 500*time.Millisecond, -1, 0, 0.4, 0)
+	once := SetFire("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 0, 0.4, 0)
+	for n := 0; n < 44100+2; n++ {
+		NextSample()
+	}
+	out := GetOutputLevel()
+	assert.Equal(t, 1, len(out.Peak))
+	assert.True(t, out.Peak[0] > 0)
+	assert.True(t, out.RMS[0] > 0)
+	assert.True(t, out.RMS[0] <= out.Peak[0])
+	assert.True(t, f.Level().Peak[0] > 0)
+	assert.Equal(t, []float64{0}, once.Level().Peak) // finished playing before the end of the mix cycle
+	assert.Equal(t, uint64(0), GetClipCount())
+}
+
+func TestGetClipCount(t *testing.T) {
+	testMixSetup()
+	SetMasterVolume(1)
+	for n := 0; n < 4; n++ {
+		SetFire("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 0, 1.0, 0)
+	}
+	for n := 0; n < 44100+2; n++ {
+		NextSample()
+	}
+	assert.True(t, GetClipCount() > 0)
+	Teardown()
+	assert.Equal(t, uint64(0), GetClipCount())
+}
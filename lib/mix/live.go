@@ -0,0 +1,63 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// SetOutputLatency reported by the bound out audio interface, between mixing a sample and hearing it, e.g. its buffer.
+// The mixer cannot play a fire sooner than now, so the latency is not subtracted from anything; it is there to compare
+// the mix position with what is heard, e.g. GetNowAt() - GetOutputLatency() is what is coming out of the speakers.
+func (m *Mixer) SetOutputLatency(d time.Duration) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if d < 0 {
+		d = 0
+	}
+	m.mixOutputLatency = d
+}
+
+// GetOutputLatency reported by the bound out audio interface, or 0 if it has none, e.g. a WAV file, plus the lookahead, see SetLookahead
+func (m *Mixer) GetOutputLatency() time.Duration {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixOutputLatency + m.mixLookaheadDur()
+}
+
+// FireNow to play a source at the earliest sample the mix loop can still include, e.g. triggered live from a MIDI controller,
+// with sustain time.Duration, volume from 0 to 1, and pan from -1 to +1. It is heard GetOutputLatency() later.
+// A fire scheduled by SetFire to begin in the past is not skipped, but starts immediately, skipping the samples it missed;
+// FireNow misses none.
+func (m *Mixer) FireNow(source string, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	f, err := m.mixNewFire(source, 0, sustain, volume, pan) // the source may take a while to load, so the begin is not yet known
+	if err != nil {
+		debug.Warnf("mix.FireNow(%s) failed: %s", source, err)
+		return nil
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	f.BeginTz = m.mixEarliestTz()
+	if f.EndTz != 0 {
+		f.EndTz += f.BeginTz
+	}
+	m.mixCaptureFires([]*fire.Fire{f})
+	m.mixPushFires([]*fire.Fire{f})
+	return f
+}
+
+//
+// Private
+//
+
+// mixEarliestTz that a fire can begin and still be mixed from its first sample, after any block that has been mixed ahead;
+// the caller must hold the mixMutex
+func (m *Mixer) mixEarliestTz() spec.Tz {
+	if m.mixBlockHas(m.nowTz) {
+		return m.mixBlockEndTz
+	}
+	return m.nowTz
+}
@@ -0,0 +1,49 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetOutputLatency(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, time.Duration(0), GetOutputLatency())
+	SetOutputLatency(93 * time.Millisecond)
+	assert.Equal(t, 93*time.Millisecond, GetOutputLatency())
+	SetOutputLatency(-time.Second)
+	assert.Equal(t, time.Duration(0), GetOutputLatency())
+}
+
+func TestFireNow(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	testMixFor(300 * time.Millisecond)
+	f := FireNow(src, 50*time.Millisecond, 1.0, 0)
+	assert.Equal(t, mixDefault.nowTz, f.BeginTz)
+	assert.Equal(t, 50*time.Millisecond, f.Sustain())
+	assert.Equal(t, 1, len(mixDefault.mixLiveFires)) // without waiting for the next mix cycle
+	NextSample()
+	assert.Equal(t, fire.StatePlay, f.State())
+	assert.NotEqual(t, float64(0), float64(NextSample()[0]))
+	assert.Nil(t, FireNow("nonexistent.wav", 0, 1.0, 0))
+}
+
+func TestSetFire_Late(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	testMixFor(300 * time.Millisecond)
+	f := SetFire(src, GetNowAt()-10*time.Millisecond, 0, 1.0, 0)
+	NextSample()
+	assert.Equal(t, fire.StatePlay, f.State())
+	assert.Equal(t, 1, FireCountLive())
+	testMixFor(80 * time.Millisecond) // the 10ms it missed are skipped
+	assert.True(t, f.IsAlive())
+	testMixFor(20 * time.Millisecond)
+	assert.False(t, f.IsAlive())
+}
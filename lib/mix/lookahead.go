@@ -0,0 +1,314 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// SetLookahead of the output, in mix cycles, e.g. 2 to mix two cycles ahead of the audio interface, such that a cycle that runs
+// long, under load or garbage collection, does not glitch the output. The mix loop then runs on a goroutine of its own, filling a
+// buffer that the output callback, NextSample or NextBlock, only copies from; the lookahead adds to GetOutputLatency, so keep the
+// cycles short, see SetCycleDuration, e.g. 2 cycles of 10ms add 20ms. If the buffer is still exhausted, the output plays silence
+// for the missing frames, counted in Stats, rather than repeating stale audio. GetNowAt and GetNowFrames follow the frames the output
+// takes, not the mix ahead of them; a seek moves them at once, and they hold while the frames mixed before the seek play out. 0 (the
+// default) mixes in the output callback. Changing it drops what was mixed ahead, so set it once, after Configure and before Start. Only
+// for an output in real time: an offline output, e.g. WAV, pulls faster than that, so it would underrun.
+func (m *Mixer) SetLookahead(cycles int) {
+	if cycles < 0 {
+		debug.Warnf("mix.SetLookahead(%d) ignored: cycles must not be negative", cycles)
+		return
+	}
+	m.mixLookaheadMutex.Lock()
+	defer m.mixLookaheadMutex.Unlock()
+	m.mixMutex.Lock()
+	if m.masterSpec == nil {
+		m.mixMutex.Unlock()
+		debug.Warnf("mix.SetLookahead(%d) ignored: must configure the mixer first", cycles)
+		return
+	}
+	m.mixAheadCycles = cycles
+	m.mixPublishHeard()
+	channels, capacity := len(m.mixOutBuffer), cycles*int(m.masterCycleDurTz)
+	m.mixMutex.Unlock()
+	m.mixStopLookahead()
+	if cycles > 0 {
+		m.mixLookahead = newMixLookahead(cycles, channels, capacity)
+		go m.mixLookaheadLoop(m.mixLookahead)
+	}
+}
+
+// GetLookahead of the output, in mix cycles, see SetLookahead
+func (m *Mixer) GetLookahead() int {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixAheadCycles
+}
+
+//
+// Private
+//
+
+// mixLookaheadChunkFrames mixed ahead at once, holding the mixMutex, so that fires can be set in between
+const mixLookaheadChunkFrames = 256
+
+// mixLookahead buffer of the output, mixed ahead by its loop and read by the output callback
+type mixLookahead struct {
+	cycles int
+	wake   chan struct{} // signaled as the output reads from the buffer
+	quit   chan struct{}
+	done   chan struct{}
+	chunk  []sample.Value // mixed by the loop, before it is pushed
+	heard  []mixHeard     // of each frame of the chunk
+	// mutex guards the buffer, held by the loop only to push a chunk, never while it mixes
+	mutex    sync.Mutex
+	ring     []sample.Value // of interleaved frames
+	at       []mixHeard     // of each frame of the ring, published as the output takes it
+	channels int
+	capacity int // frames
+	read     int // frame
+	count    int // frames
+	out      []sample.Value
+}
+
+func newMixLookahead(cycles int, channels int, capacity int) *mixLookahead {
+	l := &mixLookahead{
+		cycles:   cycles,
+		wake:     make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+		ring:     make([]sample.Value, capacity*channels),
+		at:       make([]mixHeard, capacity),
+		channels: channels,
+		capacity: capacity,
+		out:      make([]sample.Value, channels),
+	}
+	l.wake <- struct{}{} // fill it right away
+	return l
+}
+
+// mixLookaheadNext sample of the output from the lookahead, silent if it is exhausted, or false if there is no lookahead
+func (m *Mixer) mixLookaheadNext() ([]sample.Value, bool) {
+	m.mixLookaheadMutex.Lock()
+	defer m.mixLookaheadMutex.Unlock()
+	l := m.mixLookahead
+	if l == nil {
+		return nil, false
+	}
+	l.mutex.Lock()
+	if l.count == 0 {
+		for c := range l.out {
+			l.out[c] = 0
+		}
+		atomic.AddInt64(&m.mixStatOutUnderruns, 1)
+	} else {
+		copy(l.out, l.ring[l.read*l.channels:(l.read+1)*l.channels])
+		m.mixHear(l.at[l.read])
+		l.take(1)
+	}
+	l.mutex.Unlock()
+	l.signal()
+	return l.out, true
+}
+
+// mixLookaheadNextBlock of frames of the output from the lookahead, into interleaved float32 of all channels, silent for the frames
+// it is missing, or false if there is no lookahead
+func (m *Mixer) mixLookaheadNextBlock(dst []float32, frames spec.Tz) bool {
+	m.mixLookaheadMutex.Lock()
+	defer m.mixLookaheadMutex.Unlock()
+	l := m.mixLookahead
+	if l == nil {
+		return false
+	}
+	l.mutex.Lock()
+	f, heard := 0, mixHeard{}
+	for ; f < int(frames) && l.count > 0; f++ {
+		frame := l.ring[l.read*l.channels : (l.read+1)*l.channels]
+		for c, v := range frame {
+			dst[f*l.channels+c] = float32(v)
+		}
+		heard = l.at[l.read]
+		l.take(1)
+	}
+	if f > 0 {
+		m.mixHear(heard) // of the last frame taken
+	}
+	for i := f * l.channels; i < int(frames)*l.channels; i++ {
+		dst[i] = 0
+	}
+	l.mutex.Unlock()
+	if missing := int64(frames) - int64(f); missing > 0 {
+		atomic.AddInt64(&m.mixStatOutUnderruns, missing)
+	}
+	l.signal()
+	return true
+}
+
+// mixStopLookahead and wait for its loop to end; the caller must hold the mixLookaheadMutex, but not the mixMutex
+func (m *Mixer) mixStopLookahead() {
+	if m.mixLookahead == nil {
+		return
+	}
+	close(m.mixLookahead.quit)
+	<-m.mixLookahead.done
+	m.mixLookahead = nil
+}
+
+// mixLookaheadLoop fills the lookahead each time the output reads from it, until it is stopped
+func (m *Mixer) mixLookaheadLoop(l *mixLookahead) {
+	defer close(l.done)
+	for {
+		select {
+		case <-l.quit:
+			return
+		case <-l.wake:
+		}
+		for m.mixLookaheadFill(l) {
+			select {
+			case <-l.quit:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// mixLookaheadFill mixes the next chunk of the output into the lookahead, or returns false if it is full
+func (m *Mixer) mixLookaheadFill(l *mixLookahead) bool {
+	if !m.mixLookaheadChunk(l) {
+		return false
+	}
+	l.push(l.chunk, l.heard)
+	return true
+}
+
+// mixLookaheadChunk of the output, mixed holding the mixMutex, or returns false if the lookahead is full, or the mix loop fails,
+// after which it mixes silence, see OnError
+func (m *Mixer) mixLookaheadChunk(l *mixLookahead) (ok bool) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			m.mixFail(r, mixPanicStack())
+			ok = false
+		}
+	}()
+	channels := len(m.mixOutBuffer)
+	frames := l.room(channels, l.cycles*int(m.masterCycleDurTz))
+	if frames > mixLookaheadChunkFrames {
+		frames = mixLookaheadChunkFrames
+	}
+	if frames == 0 {
+		return false
+	}
+	l.chunk, l.heard = l.chunk[:0], l.heard[:0]
+	for n := 0; n < frames; n++ {
+		l.chunk = append(l.chunk, m.mixOutputSample()...)
+		l.heard = append(l.heard, m.mixHeardNow())
+	}
+	return true
+}
+
+// room in the lookahead, in frames, after resizing it to the channels and capacity of the mixer, e.g. after SetCycleDuration,
+// keeping the frames it has if the channels are the same
+func (l *mixLookahead) room(channels int, capacity int) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if channels != l.channels || capacity != l.capacity {
+		ring, at := make([]sample.Value, capacity*channels), make([]mixHeard, capacity)
+		count := 0
+		if channels == l.channels {
+			for ; count < l.count && count < capacity; count++ {
+				copy(ring[count*channels:], l.ring[l.read*channels:(l.read+1)*channels])
+				at[count] = l.at[l.read]
+				l.take(1)
+			}
+		}
+		l.ring, l.at, l.channels, l.capacity, l.read, l.count = ring, at, channels, capacity, 0, count
+		l.out = make([]sample.Value, channels)
+	}
+	return l.capacity - l.count
+}
+
+// push interleaved frames into the lookahead, which has room for them, with the position of each
+func (l *mixLookahead) push(frames []sample.Value, heard []mixHeard) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i, f := 0, 0; i < len(frames); i, f = i+l.channels, f+1 {
+		at := (l.read + l.count) % l.capacity
+		copy(l.ring[at*l.channels:(at+1)*l.channels], frames[i:i+l.channels])
+		l.at[at] = heard[f]
+		l.count++
+	}
+}
+
+// take a # of frames from the lookahead; the caller must hold its mutex
+func (l *mixLookahead) take(frames int) {
+	l.read = (l.read + frames) % l.capacity
+	l.count -= frames
+}
+
+// frames in the lookahead
+func (l *mixLookahead) frames() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.count
+}
+
+// signal the loop to fill the lookahead, unless it already is
+func (l *mixLookahead) signal() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// mixLookaheadDur of the lookahead, added to the output latency; the caller must hold the mixMutex
+func (m *Mixer) mixLookaheadDur() time.Duration {
+	return time.Duration(m.mixAheadCycles) * m.mixDurOf(m.masterCycleDurTz)
+}
+
+// mixHeard position of a frame of the output, of a generation that each jump of the playhead moves on from, e.g. a seek
+type mixHeard struct {
+	tz        spec.Tz // since the start
+	preRollTz spec.Tz // left of the pre-roll, or 0 if it is not playing
+	gen       int64
+}
+
+// mixHeardNow, the position after the frame last mixed; the caller must hold the mixMutex
+func (m *Mixer) mixHeardNow() mixHeard {
+	return mixHeard{m.nowTz, m.mixPreRollLeftTz, m.mixHeard.gen}
+}
+
+// mixPublishHeard position, of a new generation, after the playhead jumps, e.g. a seek, such that GetNowAt and GetNowFrames move at
+// once, and not back for the frames mixed ahead before the jump; the caller must hold the mixMutex
+func (m *Mixer) mixPublishHeard() {
+	h := m.mixHeardNow()
+	m.mixHeardMutex.Lock()
+	defer m.mixHeardMutex.Unlock()
+	m.mixHeard = mixHeard{h.tz, h.preRollTz, h.gen + 1}
+	atomic.StoreInt64(&m.mixNowFrames, int64(h.tz))
+}
+
+// mixHear a frame that the output took from the lookahead, for GetNowAt and GetNowFrames, unless it was mixed before the playhead
+// last jumped
+func (m *Mixer) mixHear(h mixHeard) {
+	m.mixHeardMutex.Lock()
+	defer m.mixHeardMutex.Unlock()
+	if h.gen == m.mixHeard.gen {
+		m.mixHeard.tz, m.mixHeard.preRollTz = h.tz, h.preRollTz
+		atomic.StoreInt64(&m.mixNowFrames, int64(h.tz))
+	}
+}
+
+// mixHeardLast, the position of the frame the output took last from the lookahead
+func (m *Mixer) mixHeardLast() mixHeard {
+	m.mixHeardMutex.Lock()
+	defer m.mixHeardMutex.Unlock()
+	return m.mixHeard
+}
@@ -0,0 +1,126 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetLookahead(t *testing.T) {
+	s := spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2}
+	want := testLookaheadMixer(t, s)
+	defer want.Teardown()
+	m := testLookaheadMixer(t, s)
+	defer m.Teardown()
+	m.SetOutputLatency(5 * time.Millisecond)
+	m.SetLookahead(2)
+	assert.Equal(t, 2, m.GetLookahead())
+	assert.Equal(t, 25*time.Millisecond, m.GetOutputLatency())
+	block := make([]float32, 100*2)
+	for n := 0; n < 2000; n++ {
+		testLookaheadWait(m, 100)
+		if n%20 == 0 {
+			m.NextBlock(block, 100)
+			for i := 0; i < 100; i++ {
+				smp := want.NextSample()
+				assert.Equal(t, float32(smp[0]), block[2*i])
+				assert.Equal(t, float32(smp[1]), block[2*i+1])
+			}
+			continue
+		}
+		assert.Equal(t, want.NextSample(), m.NextSample())
+	}
+	assert.Equal(t, int64(0), m.Stats().OutUnderruns)
+	m.SetLookahead(0)
+	assert.Equal(t, 5*time.Millisecond, m.GetOutputLatency())
+	assert.Nil(t, m.mixLookahead) // mixed in the callback again
+}
+
+func TestSetLookahead_Underrun(t *testing.T) {
+	m := testLookaheadMixer(t, spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	defer m.Teardown()
+	m.SetLookahead(1)
+	testLookaheadWait(m, 441)
+	m.mixMutex.Lock() // a mix cycle that runs long
+	for n := 0; n < 441; n++ {
+		m.NextSample()
+	}
+	assert.Equal(t, int64(0), m.Stats().OutUnderruns)
+	for n := 0; n < 100; n++ {
+		assert.Equal(t, 0.0, float64(m.NextSample()[0]))
+	}
+	block := make([]float32, 50)
+	for i := range block {
+		block[i] = 1
+	}
+	m.NextBlock(block, 50)
+	assert.Equal(t, make([]float32, 50), block) // silence, not stale audio
+	assert.Equal(t, int64(150), m.Stats().OutUnderruns)
+	m.mixMutex.Unlock()
+	testLookaheadWait(m, 441)
+	assert.NotEqual(t, 0.0, float64(m.NextSample()[0]))
+	m.ResetStats()
+	assert.Equal(t, int64(0), m.Stats().OutUnderruns)
+}
+
+func TestSetLookahead_GetNowAt(t *testing.T) {
+	m := testLookaheadMixer(t, spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	defer m.Teardown()
+	m.SetLookahead(2)
+	testLookaheadWait(m, 882)
+	assert.Equal(t, time.Duration(0), m.GetNowAt()) // mixed ahead, but not yet played
+	for n := 0; n < 441; n++ {
+		m.NextSample()
+	}
+	assert.Equal(t, 10*time.Millisecond, m.GetNowAt())
+	m.SeekTo(time.Second)
+	assert.Equal(t, time.Second, m.GetNowAt())
+	for n := 0; n < 441; n++ { // mixed before the seek
+		m.NextSample()
+		assert.Equal(t, time.Second, m.GetNowAt())
+	}
+	testLookaheadWait(m, 882)
+	for n := 0; n < 882; n++ {
+		m.NextSample()
+	}
+	assert.Equal(t, time.Second+20*time.Millisecond, m.GetNowAt())
+}
+
+func TestSetLookahead_Teardown(t *testing.T) {
+	m := testLookaheadMixer(t, spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	m.SetLookahead(2)
+	m.Teardown()
+	assert.Equal(t, 0, m.GetLookahead())
+	assert.Nil(t, m.mixLookahead)
+	m.SetLookahead(-1)
+	assert.Equal(t, 0, m.GetLookahead())
+}
+
+//
+// Private
+//
+
+// testLookaheadMixer with 10ms cycles and a tone from zero
+func testLookaheadMixer(t *testing.T, s spec.AudioSpec) *Mixer {
+	m, err := New(s)
+	assert.Nil(t, err)
+	m.SetCycleDuration(10 * time.Millisecond)
+	m.SetFireTone(441, 0, time.Second, 1.0, -0.5)
+	m.SetFireTone(882, 3*time.Millisecond, 20*time.Millisecond, 0.5, 0.5)
+	return m
+}
+
+// testLookaheadWait until the lookahead has at least a # of frames
+func testLookaheadWait(m *Mixer, frames int) {
+	m.mixLookaheadMutex.Lock()
+	l := m.mixLookahead
+	m.mixLookaheadMutex.Unlock()
+	for l.frames() < frames {
+		runtime.Gosched()
+	}
+}
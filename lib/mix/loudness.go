@@ -0,0 +1,64 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"context"
+	"io"
+	"math"
+	"time"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/lib/loudness"
+)
+
+// LoudnessReport of the integrated loudness in LUFS, the loudness range in LU, and the true peak in dBTP of a render, see MeasureLoudness
+type LoudnessReport = loudness.Report
+
+// MeasureLoudness of a render for a length of time from the current playhead, as Render, by ITU-R BS.1770 and EBU R128,
+// at the configured frequency and channels; afterward, the playhead is moved back to where it was, e.g. to render for real.
+func (m *Mixer) MeasureLoudness(length time.Duration) (LoudnessReport, error) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if err := m.mixRenderable(); err != nil {
+		return LoudnessReport{}, err
+	}
+	return m.mixMeasureLoudness(length), nil
+}
+
+// RenderNormalized to a writer, as RenderTo, in two passes: the loudness of the render is measured, see MeasureLoudness, and then
+// it is rendered with the gain that brings its integrated loudness to a target in LUFS, e.g. -14 for streaming platforms. The gain
+// is applied at the master, after the mix algorithm and before the clip stage, so the loudness lands on the target, unless a boost
+// clips; silence is not boosted. The report is of the normalized render, e.g. to check its true peak.
+func (m *Mixer) RenderNormalized(length time.Duration, targetLUFS float64, w io.Writer) (LoudnessReport, error) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if err := m.mixRenderable(); err != nil {
+		return LoudnessReport{}, err
+	}
+	measured := m.mixMeasureLoudness(length)
+	if !math.IsInf(measured.Integrated, 0) {
+		m.mixNormalizeGain = math.Pow(10, (targetLUFS-measured.Integrated)/20)
+	}
+	meter := loudness.New(m.masterFreq, m.masterSpec.Channels)
+	err := m.mixRenderCtx(context.Background(), length, w, meter.Add)
+	m.mixNormalizeGain = 1
+	return meter.Report(), err
+}
+
+//
+// Private
+//
+
+// mixMeasureLoudness of a render from the playhead, and move the playhead back; the caller must hold the mixMutex
+func (m *Mixer) mixMeasureLoudness(length time.Duration) LoudnessReport {
+	from := m.mixSchedDurOf(m.nowTz)
+	release := m.mixHoldReplay()
+	defer release()
+	meter := loudness.New(m.masterFreq, m.masterSpec.Channels)
+	m.mixRender(m.mixRenderFrames(length), func(smp []sample.Value) error {
+		meter.Add(smp)
+		return nil
+	})
+	m.mixSeekTo(from)
+	return meter.Report()
+}
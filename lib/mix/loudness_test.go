@@ -0,0 +1,73 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/loudness"
+)
+
+func TestMeasureLoudness(t *testing.T) {
+	testLoudnessSetup(-23)
+	report, err := MeasureLoudness(2 * time.Second)
+	assert.Nil(t, err)
+	assert.InDelta(t, -23.0, report.Integrated, 0.1)
+	assert.InDelta(t, -23.0, report.TruePeak, 0.1)
+	assert.Equal(t, spec.Tz(0), mixDefault.nowTz) // moved back
+	out, err := Render(2 * time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, report, loudness.Measure(out, 48000, 2)) // of the same render
+}
+
+func TestRenderNormalized(t *testing.T) {
+	testLoudnessSetup(-23)
+	var buf bytes.Buffer
+	report, err := RenderNormalized(2*time.Second, -14, &buf)
+	assert.Nil(t, err)
+	assert.InDelta(t, -14.0, report.Integrated, 0.1)
+	assert.InDelta(t, -14.0, report.TruePeak, 0.1)
+	assert.Equal(t, 2*48000*2*4, buf.Len()) // stereo F32
+	assert.Equal(t, float64(1), mixDefault.mixNormalizeGain)
+}
+
+func TestRenderNormalized_Silence(t *testing.T) {
+	testLoudnessSetup(math.Inf(-1))
+	var buf bytes.Buffer
+	report, err := RenderNormalized(time.Second, -14, &buf)
+	assert.Nil(t, err)
+	assert.True(t, math.IsInf(report.Integrated, -1))
+}
+
+func TestMeasureLoudness_NotConfigured(t *testing.T) {
+	testMixSetup()
+	mixDefault.masterFreq = 0 // simulates never having set a mix frequency
+	defer testMixSetup()
+	_, err := MeasureLoudness(time.Second)
+	assert.EqualError(t, err, "Must configure the mixer before rendering")
+	_, err = RenderNormalized(time.Second, -14, &bytes.Buffer{})
+	assert.EqualError(t, err, "Must configure the mixer before rendering")
+}
+
+//
+// Test Components
+//
+
+// testLoudnessSetup of a 1kHz sine in stereo, with a peak in dBFS, which is also its loudness in LUFS
+func testLoudnessSetup(dbfs float64) {
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     48000,
+		Format:   spec.AudioF32,
+		Channels: 2,
+	})
+	SetMixAlgorithm(MixHardClip)
+	if !math.IsInf(dbfs, -1) {
+		SetFireTone(1000, 0, 2*time.Second, math.Pow(10, dbfs/20), 0)
+	}
+}
@@ -0,0 +1,34 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Metadata that describes audio, of the LIST/INFO and Broadcast Wave (bext) chunks of a WAV, see SetOutputMetadata and SourceInfo
+type Metadata = spec.Metadata
+
+// SetOutputMetadata written in the header of each WAV output after, by OutputStart or RenderRange, e.g. the title, artist and
+// software of a render, as a LIST/INFO chunk, and as a bext chunk if any of its fields are set, e.g. the description, originator and
+// origination date and time for an archive; the sizes in the RIFF header account for them. A field that is too long for its chunk is
+// truncated, and one that cannot be encoded, e.g. of an ID that is not four characters, is skipped, each with a warning, rather than
+// writing an invalid file. An empty Metadata (the default) writes none.
+func (m *Mixer) SetOutputMetadata(meta Metadata) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if !meta.HasInfo() && !meta.HasBext() {
+		m.mixOutputMeta = nil
+		return
+	}
+	m.mixOutputMeta = &meta
+}
+
+// GetOutputMetadata written in the header of each WAV output, see SetOutputMetadata
+func (m *Mixer) GetOutputMetadata() Metadata {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.mixOutputMeta == nil {
+		return Metadata{}
+	}
+	return *m.mixOutputMeta
+}
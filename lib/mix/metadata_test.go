@@ -0,0 +1,81 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetOutputMetadata(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	meta := Metadata{Title: "Demo", Software: "mix", Description: "Bounce of the demo", OriginationDate: "2026-10-16"}
+	SetOutputMetadata(meta)
+	assert.Equal(t, meta, GetOutputMetadata())
+	_, err := bind.Configure(*Spec())
+	assert.Nil(t, err)
+	bind.SetOutputCallback(NextSample)
+	SetFire(source.ToneKey(source.WaveSine, 441, 100*time.Millisecond), 0, 0, 1.0, 0)
+	var out bytes.Buffer
+	OutputStart(100*time.Millisecond, &out)
+	StartAt(time.Now())
+	OutputContinueTo(100 * time.Millisecond)
+	samples, specs, err := wav.LoadBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, 4410, len(samples))
+	assert.Equal(t, &meta, specs.Meta.Tags)
+	out.Reset()
+	assert.Nil(t, RenderRange(0, 50*time.Millisecond, &out))
+	samples, specs, err = wav.LoadBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, 2205, len(samples))
+	assert.Equal(t, &meta, specs.Meta.Tags)
+	Teardown()
+	assert.Equal(t, Metadata{}, GetOutputMetadata())
+}
+
+func TestSetOutputMetadata_Mixer(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 1})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	m.SetOutputMetadata(Metadata{Artist: "Ensemble", Info: map[string]string{"IENG": "Jo"}})
+	var out bytes.Buffer
+	m.OutputStart(10*time.Millisecond, &out)
+	m.OutputContinueTo(10 * time.Millisecond)
+	_, specs, err := wav.LoadBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "Ensemble", specs.Meta.Tags.Artist)
+	assert.Equal(t, "Jo", specs.Meta.Tags.Info["IENG"])
+	m.SetOutputMetadata(Metadata{}) // none
+	out.Reset()
+	m.OutputStart(10*time.Millisecond, &out)
+	m.OutputContinueTo(20 * time.Millisecond)
+	_, specs, err = wav.LoadBytes(out.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, specs.Meta)
+}
+
+func TestSources_Tags(t *testing.T) {
+	testMixSetup()
+	var buf bytes.Buffer
+	s := spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 1}
+	w := wav.NewWriterWithMeta(&buf, wav.FormatFromSpec(&s), 10*time.Millisecond, &Metadata{Description: "Room tone", Originator: "Recorder"})
+	w.Write(make([]byte, 441*2))
+	assert.Nil(t, source.Register("archived.wav", buf.Bytes()))
+	assert.Nil(t, Prepare("archived.wav"))
+	defer EvictSource("archived.wav")
+	info := testSourceInfo("archived.wav")
+	assert.Equal(t, "Room tone", info.Tags.Description)
+	assert.Equal(t, "Recorder", info.Tags.Originator)
+}
@@ -0,0 +1,39 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/bind/midi"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// LoadMIDI from a Standard MIDI File (format 0 or 1), to set a fire for each note whose number is mapped to a source,
+// beginning at its time by the tempo map of the file since the start of mix playback, with its velocity as volume (vel/127),
+// sustained until its note-off, else for the default sustain. Returns the # of notes skipped for having no mapping,
+// or an error, having set none of the fires, if the file cannot be read or any of the sources cannot be loaded.
+func (m *Mixer) LoadMIDI(path string, mapping map[int]string, defaultSustain time.Duration) (skipped int, err error) {
+	notes, err := midi.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	fires := make([]*fire.Fire, 0, len(notes))
+	for _, note := range notes {
+		src, ok := mapping[note.Key]
+		if !ok {
+			skipped++
+			continue
+		}
+		sustain := note.Length
+		if sustain == 0 {
+			sustain = defaultSustain
+		}
+		f, err := m.mixNewFire(src, note.Begin, sustain, float64(note.Velocity)/127, 0)
+		if err != nil {
+			return 0, err
+		}
+		fires = append(fires, f)
+	}
+	m.mixSchedule(fires...)
+	return skipped, nil
+}
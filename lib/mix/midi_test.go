@@ -0,0 +1,39 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestLoadMIDI(t *testing.T) {
+	testMixSetup()
+	kick := source.ToneKey(source.WaveSine, 55, 100*time.Millisecond)
+	snare := source.ToneKey(source.WaveNoise, 0, 100*time.Millisecond)
+	skipped, err := LoadMIDI("../../bind/midi/testdata/TempoChange.mid", map[int]string{36: kick, 38: snare, 42: kick}, 250*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, skipped) // note 40 has no mapping
+	fires := Fires()
+	assert.Equal(t, 3, len(fires))
+	assert.Equal(t, time.Duration(0), fires[0].BeginAt())
+	assert.Equal(t, 500*time.Millisecond, fires[0].Sustain())
+	assert.Equal(t, float64(1), fires[0].Volume)
+	assert.Equal(t, time.Second, fires[1].BeginAt()) // the tempo changes from 120 to 60 BPM at this beat
+	assert.Equal(t, time.Second, fires[1].Sustain())
+	assert.InDelta(t, 64.0/127, fires[1].Volume, 1e-9)
+	assert.Equal(t, 3*time.Second, fires[2].BeginAt())
+	assert.Equal(t, 250*time.Millisecond, fires[2].Sustain()) // no note-off
+}
+
+func TestLoadMIDI_FAIL(t *testing.T) {
+	testMixSetup()
+	_, err := LoadMIDI("ThisDoesNotExist.mid", nil, 0)
+	assert.NotNil(t, err)
+	_, err = LoadMIDI("../../bind/midi/testdata/TempoChange.mid", map[int]string{36: "ThisDoesNotExist.wav"}, 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, FireCount())
+}
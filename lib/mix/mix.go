@@ -2,8 +2,12 @@
 package mix
 
 import (
+	"context"
 	"io"
 	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-mix/mix/bind/spec"
@@ -11,218 +15,1362 @@ import (
 	"github.com/go-mix/mix/bind"
 	"github.com/go-mix/mix/bind/debug"
 	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/lib/effect"
 	"github.com/go-mix/mix/lib/fire"
 	"github.com/go-mix/mix/lib/source"
 )
 
-// NextSample returns the next sample mixed in all channels
-func NextSample() []sample.Value {
-	smp := make([]sample.Value, masterSpec.Channels)
-	var fireSample []sample.Value
-	for _, fire := range mixLiveFires {
-		if fireTz := fire.At(nowTz); fireTz > 0 {
-			fireSample = mixSourceAt(fire.Source, fire.Volume, fire.Pan, fireTz)
-			for c := 0; c < masterSpec.Channels; c++ {
-				smp[c] += fireSample[c]
+// NextSample returns the next sample mixed in all channels, in a buffer that is reused by the next call, such that the mix loop
+// makes no allocations in its steady state; copy the values to keep them. It is silent while paused, ahead of a clock set by SetClock,
+// or during a teardown, or after a failure, see OnError. With a lookahead, it reads the sample mixed ahead, see SetLookahead.
+func (m *Mixer) NextSample() (smp []sample.Value) {
+	if ahead, ok := m.mixLookaheadNext(); ok {
+		return ahead
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			m.mixFail(r, mixPanicStack())
+			smp = m.mixSilence()
+		}
+	}()
+	return m.mixOutputSample()
+}
+
+// NextBlock mixes the next # of frames into dst, interleaved float32 of all channels, e.g. the buffer of an F32 output
+// callback, in one hold of the mix lock and with no intermediate buffers; it is the same audio as that many calls of NextSample.
+// dst must be at least frames × channels long. With a lookahead, it copies the frames mixed ahead, see SetLookahead.
+func (m *Mixer) NextBlock(dst []float32, frames spec.Tz) {
+	if m.mixLookaheadNextBlock(dst, frames) {
+		return
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	channels := len(m.mixOutBuffer)
+	defer func() {
+		if r := recover(); r != nil {
+			m.mixFail(r, mixPanicStack())
+			for i := range dst[:int(frames)*channels] {
+				dst[i] = 0
 			}
 		}
+	}()
+	for f, i := spec.Tz(0), 0; f < frames; f, i = f+1, i+channels {
+		smp := m.mixOutputSample()
+		for c := 0; c < channels; c++ {
+			dst[i+c] = float32(smp[c])
+		}
 	}
-	//	debug.Printf("*Mixer.nextSample %+v\n", sample)
-	nowTz++
-	out := make([]sample.Value, masterSpec.Channels)
-	for c := 0; c < masterSpec.Channels; c++ {
-		out[c] = mixLogarithmicRangeCompression(smp[c])
+}
+
+// Configure the mixer frequency, format, channels & sample rate, as its output obtains them, see ObtainedSpec.
+func (m *Mixer) Configure(s spec.AudioSpec) {
+	s.MustValidate()
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	obtained := s
+	if m.output != nil {
+		obtained = m.output.Configure(s)
 	}
-	if nowTz > nextCycleTz {
-		mixCycle()
+	m.mixConfigure(s, obtained)
+}
+
+// ConfigureObtained is Configure, with the spec obtained of the audio interface for the spec requested of it, which may differ,
+// e.g. a device that only plays at 44.1kHz or in S16, see bind.Configure. The mix, and the resampling of its sources, is at the
+// spec obtained, such that a fire of one second still plays for one second of output.
+func (m *Mixer) ConfigureObtained(requested spec.AudioSpec, obtained spec.AudioSpec) {
+	obtained.MustValidate()
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixConfigure(requested, obtained)
+}
+
+// ObtainedSpec of the output, at which the mixer runs, e.g. to display the real frequency, or zero before it is configured
+func (m *Mixer) ObtainedSpec() spec.AudioSpec {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.masterSpec == nil {
+		return spec.AudioSpec{}
 	}
-	return out
+	return *m.masterSpec
 }
 
-// Configure the mixer frequency, format, channels & sample rate.
-func Configure(s spec.AudioSpec) {
-	masterSpec = &s
-	masterFreq = float64(s.Freq)
-	masterTzDur = time.Second / time.Duration(masterFreq)
-	masterCycleDurTz = spec.Tz(masterFreq)
-	source.Configure(s)
+// RequestedSpec of the output, by Configure, which it may not have obtained, see ObtainedSpec
+func (m *Mixer) RequestedSpec() spec.AudioSpec {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixRequestedSpec
 }
 
 // Spec spec returns the current audio specification.
-func Spec() *spec.AudioSpec {
-	return masterSpec
+func (m *Mixer) Spec() *spec.AudioSpec {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.masterSpec
 }
 
-// Teardown everything and release all memory.
-func Teardown() {
-	ClearAllFires()
-	outputToDur = time.Duration(0)
-	nextCycleTz = 0
-	nowTz = 0
+// DefaultTeardownTimeout of a drain, unless TeardownOptions has another
+const DefaultTeardownTimeout = 5 * time.Second
+
+// TeardownOptions of TeardownWith
+type TeardownOptions struct {
+	Drain   bool          // if true, the live fires play to their end, else they are cut
+	Timeout time.Duration // at most, of the drain, after which the live fires are cut; 0 is DefaultTeardownTimeout
 }
 
-// SetFire to represent a single audio source playing at a specific time in the future (in time.Duration from play start), with sustain time.Duration, volume from 0 to 1, and pan from -1 to +1
-func SetFire(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
-	mixPrepareSource(mixSourcePrefix + source)
-	beginTz := spec.Tz(begin.Nanoseconds() / masterTzDur.Nanoseconds())
-	var endTz spec.Tz
-	if sustain != 0 {
-		endTz = beginTz + spec.Tz(sustain.Nanoseconds()/masterTzDur.Nanoseconds())
+// Teardown everything and release all memory, cutting any live audio; see TeardownWith.
+func (m *Mixer) Teardown() {
+	if err := m.TeardownWith(TeardownOptions{}); err != nil {
+		debug.Warnf("mix.Teardown failed to close the output: %s", err)
+	}
+}
+
+// TeardownWith options, gracefully: stop accepting new fires, cut or drain the live audio, wait for any output in flight,
+// e.g. OutputContinueTo on another goroutine, then flush and close the output writer, patching the header of a streamed WAV
+// if the writer is an io.WriteSeeker, and close any tee likewise, and only then reset everything and release the buffers. An output
+// callback during the teardown returns silence. Calling it again, before the mixer is configured or another fire is set, does nothing.
+// Returns an error if the output, or a tee, could not be closed.
+func (m *Mixer) TeardownWith(opts TeardownOptions) (err error) {
+	m.mixMutex.Lock()
+	if m.teardown != teardownNone {
+		m.mixMutex.Unlock()
+		return nil
+	}
+	m.teardown = teardownDraining // no new fires
+	m.mixMutex.Unlock()
+	if opts.Drain {
+		m.mixDrain(opts.Timeout)
+	}
+	m.mixMutex.Lock()
+	m.teardown = teardownCutting // the output callback returns silence
+	m.mixMutex.Unlock()
+	m.mixLookaheadMutex.Lock() // of what was mixed ahead, too
+	m.mixStopLookahead()
+	m.mixLookaheadMutex.Unlock()
+	m.outputMutex.Lock() // waits for the output in flight
+	if m.outputStarted {
+		err = m.mixOutputClose()
+	}
+	m.outputMutex.Unlock()
+	if teeErr := m.mixCloseTees(); err == nil {
+		err = teeErr
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixCancelAllFires()
+	m.mixClearAllFires()
+	m.mixClearBuses()
+	m.mixLowWaterFn = nil
+	m.mixRunLowWater(false)
+	m.mixStopWorkers()
+	m.mixTickFn = nil
+	m.mixRunTicks(false)
+	m.mixWatchSources(false)
+	m.mixResetTempo()
+	m.mixPreRollBars, m.mixPreRollDur, m.mixPreRollClick, m.mixPreRollOutput, m.mixPreRollLeftTz = 0, 0, "", false, 0
+	m.mixSubSample = false
+	m.mixReplayLimit = 0
+	m.mixPanLaw = PanLinear
+	m.mixChokeGroups = make(map[string]string)
+	m.mixChokeFade = DefaultChokeFade
+	m.mixRandomSeed = DefaultRandomSeed
+	m.mixResetPicks()
+	m.mixHumanize = HumanizeSpec{}
+	m.mixResetHumanize()
+	m.mixTimeScale = 1
+	m.mixCapturing = false
+	m.mixCaptured = nil
+	m.mixOutputLatency = 0
+	m.mixAheadCycles = 0
+	m.mixOutputMeta = nil
+	m.mixOutputPipe = false
+	m.masterEffects = nil
+	m.mixAlgorithm = MixLogarithmic
+	m.mixParams = DefaultMixParams()
+	m.mixLimiterReset()
+	m.mixHeadroom = 0
+	m.headroomGain = 1
+	m.mixClipMode = sample.ClipHard
+	m.mixDitherMode = sample.DitherNone
+	m.mixDither = nil
+	m.transport = transportPlay
+	m.masterVolume = 1
+	m.masterGain = 1
+	m.masterMuted = false
+	m.mixResetMeter()
+	m.mixOverrunFn = nil
+	m.mixRunOverrun(false)
+	m.mixErr = nil
+	m.mixClock = nil
+	if m != mixDefault {
+		m.cache.Teardown() // the default cache is kept, for the package functions of the source package
+	}
+	m.outputToDur = time.Duration(0)
+	m.nextCycleTz = 0
+	m.nowTz = 0
+	m.mixPublishClock()
+	m.mixResetStats()
+	m.mixReleaseBuffers()
+	m.teardown = teardownDone
+	return
+}
+
+// SetFire to represent a single audio source playing at a specific time in the future (in time.Duration from play start), with sustain time.Duration, volume from 0 to 1, and pan from -1 to +1.
+// The fire begins on the exact sample floor(begin × frequency), wherever that falls in the mix cycle, playing the first sample of its source;
+// see SetSubSamplePrecision to honor the fraction of a sample too. A sustain of 0 plays the full source, to its natural end,
+// see fire.EffectiveSustain and ScheduleEnd. Returns nil, having scheduled nothing, if the source cannot be loaded, which is
+// only logged as a warning; see SetFireErr for the error. A nil fire is safe to pass on, e.g. SetFireAfter returns nil after it,
+// and FireGroup.Add ignores it.
+func (m *Mixer) SetFire(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	f, err := m.SetFireErr(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFire(%s) failed: %s", source, err)
+	}
+	return f
+}
+
+// SetFireErr is SetFire, but returns an error (and schedules nothing) if the source cannot be loaded.
+func (m *Mixer) SetFireErr(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) (*fire.Fire, error) {
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		return nil, err
+	}
+	m.mixSchedule(f)
+	return f, nil
+}
+
+// FireSpec of one fire in a batch, with the parameters of SetFire, see SetFires
+type FireSpec struct {
+	Source  string
+	Begin   time.Duration
+	Sustain time.Duration
+	Volume  float64
+	Pan     float64
+}
+
+// SetFires of a batch, e.g. a whole song, as SetFire of each, but much faster: each distinct source is resolved and loaded once,
+// and all of the fires are scheduled in one critical section, so the mix loop waits once for the batch rather than for each fire.
+// Returns the fire of each entry in order, or nil for an entry whose source cannot be loaded, which does not abort the rest;
+// errs is nil if every entry was set, else it has the error of each entry that failed, at the same index.
+func (m *Mixer) SetFires(batch []FireSpec) (fires []*fire.Fire, errs []error) {
+	keys := make(map[string]string)
+	failed := make(map[string]error)
+	m.mixMutex.Lock()
+	subSample := m.mixSubSample
+	m.mixMutex.Unlock()
+	fires = make([]*fire.Fire, len(batch))
+	scheduled := make([]*fire.Fire, 0, len(batch))
+	for i, entry := range batch {
+		key, ok := keys[entry.Source]
+		if !ok {
+			key = m.mixSourceKey(entry.Source)
+			keys[entry.Source] = key
+			if err := m.mixPrepareSource(key); err != nil {
+				failed[key] = err
+			}
+		}
+		if err := failed[key]; err != nil {
+			if errs == nil {
+				errs = make([]error, len(batch))
+			}
+			errs[i] = err
+			continue
+		}
+		fires[i] = m.mixFireOf(key, entry.Begin, entry.Sustain, entry.Volume, entry.Pan, subSample)
+		scheduled = append(scheduled, fires[i])
+	}
+	m.mixSchedule(scheduled...)
+	return
+}
+
+// SetFireRate is SetFire, with a playback rate, e.g. 2 is one octave up at double speed and 0.5 is one octave down.
+func (m *Mixer) SetFireRate(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64, rate float64) *fire.Fire {
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFireRate(%s) failed: %s", source, err)
+		return nil
+	}
+	f.SetRate(rate)
+	m.mixSchedule(f)
+	return f
+}
+
+// SetFireRegion is SetFire, playing a region of the source from an offset, for a length or 0 to its natural end.
+// A region that extends past the end of the source ends early, as does a sustain longer than the region.
+func (m *Mixer) SetFireRegion(source string, begin time.Duration, sustain time.Duration, offset time.Duration, length time.Duration, volume float64, pan float64) *fire.Fire {
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFireRegion(%s) failed: %s", source, err)
+		return nil
+	}
+	f.SetRegion(m.mixTzOf(offset), m.mixTzOf(length))
+	m.mixSchedule(f)
+	return f
+}
+
+// SetFireLoop is SetFire, re-triggered every interval for a total # of repeats, or -1 to repeat until ClearAllFires or the fire is canceled.
+func (m *Mixer) SetFireLoop(source string, begin time.Duration, interval time.Duration, repeat int, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFireLoop(%s) failed: %s", source, err)
+		return nil
+	}
+	f.SetLoop(m.mixSchedTzOf(interval), repeat)
+	m.mixSchedule(f)
+	return f
+}
+
+// SetFireLoopXFade is SetFire looping the source until the fire is canceled, e.g. a backing track: as it nears the end, the tail
+// overlaps the head of the next iteration, crossfaded for a duration by equal-power gains, so there is no click at the seam.
+// The crossfade is clamped to half the length of the source.
+func (m *Mixer) SetFireLoopXFade(source string, begin time.Duration, xfade time.Duration, volume float64, pan float64) *fire.Fire {
+	f, err := m.mixNewFire(source, begin, 0, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFireLoopXFade(%s) failed: %s", source, err)
+		return nil
 	}
-	f := fire.New(mixSourcePrefix+source, beginTz, endTz, volume, pan)
-	mixReadyFires = append(mixReadyFires, f)
+	f.SetLoopXFade(m.mixTzOf(xfade))
+	m.mixSchedule(f)
 	return f
 }
 
-// FireCount returns the current total ready fires + live fires.
-func FireCount() int {
-	return len(mixLiveFires) + len(mixReadyFires)
+// SetFireSustainLoop is SetFire, looping between the sustain loop points embedded in the source, e.g. the smpl chunk of a sampler WAV,
+// for the duration of the sustain, instead of ending early with the source; without a sustain or a loop, it plays like SetFire.
+func (m *Mixer) SetFireSustainLoop(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFireSustainLoop(%s) failed: %s", source, err)
+		return nil
+	}
+	if s := m.mixGetSource(f.Source); s != nil && s.Meta() != nil && len(s.Meta().Loops) > 0 {
+		loop := s.Meta().Loops[0]
+		f.SetSustainLoop(loop.BeginTz, loop.EndTz)
+	}
+	m.mixSchedule(f)
+	return f
+}
+
+// EnvelopePoint at an offset time.Duration from the beginning of a fire, for SetVolumeEnvelope or SetPanEnvelope.
+func (m *Mixer) EnvelopePoint(offset time.Duration, value float64) fire.EnvelopePoint {
+	return fire.EnvelopePoint{
+		OffsetTz: m.mixTzOf(offset),
+		Value:    value,
+	}
+}
+
+// Fires returns a snapshot of the ready and live fires, in order of their beginning, e.g. to render the upcoming schedule.
+// The slice is a copy, safe to keep while the mixer runs; a fire that is canceled still appears until the next mix cycle.
+func (m *Mixer) Fires() []*fire.Fire {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	fires := make([]*fire.Fire, 0, m.mixReadyFires.Len()+len(m.mixLiveFires))
+	fires = append(fires, m.mixLiveFires...)
+	fires = append(fires, m.mixReadyFires.Fires()...)
+	sort.SliceStable(fires, func(i, j int) bool {
+		return fires[i].BeginTz < fires[j].BeginTz
+	})
+	return fires
+}
+
+// StartAt to specify what time, by the mixer clock, to begin mixing.
+func (m *Mixer) StartAt(t time.Time) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.startAtTime = t
+	m.transport = transportPlay
+	m.mixBeginPreRoll()
+	m.mixPublishClock()
 }
 
-// StartAt to specify what time to begin mixing.
-func StartAt(t time.Time) {
-	startAtTime = t
+// Pause the mixer clock, such that no further fires go live until Resume.
+func (m *Mixer) Pause() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.transport != transportPlay {
+		return
+	}
+	m.transport = transportPause
+	m.pausedAtTime = m.mixNow()
+}
+
+// Resume playback from the paused position, shifting the epoch by the duration spent paused.
+func (m *Mixer) Resume() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	switch m.transport {
+	case transportPause:
+		m.startAtTime = m.startAtTime.Add(m.mixNow().Sub(m.pausedAtTime))
+	case transportStop:
+		m.startAtTime = m.mixNow()
+	}
+	m.transport = transportPlay
+	m.mixPublishClock()
+}
+
+// Stop playback and reset the playhead to zero, keeping loaded sources in cache. The fires that are done play again only if they
+// were kept, see SetReplayLimit.
+func (m *Mixer) Stop() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.transport = transportStop
+	for _, f := range m.mixLiveFires {
+		f.Reset()
+		m.mixReadyFires.Push(f)
+	}
+	for _, f := range m.mixDoneFires {
+		f.Reset()
+		m.mixReadyFires.Push(f)
+	}
+	m.mixLiveFires = make([]*fire.Fire, 0)
+	m.mixDoneFires = make([]*fire.Fire, 0)
+	m.mixDropBlock()
+	m.outputToDur = time.Duration(0)
+	m.nextCycleTz = 0
+	m.nowTz = 0
+	m.mixPublishClock()
+	m.mixTickSchedule(true)
+	m.mixCountFires()
+}
+
+// SeekTo moves the playhead to a specific time.Duration-since-epoch, starting any fire that spans it mid-sample. Seeking back
+// before the fires that are done plays them again only if they were kept, see SetReplayLimit.
+func (m *Mixer) SeekTo(d time.Duration) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixSeekTo(d)
+}
+
+// mixSeekTo a time, see SeekTo; the caller must hold the mixMutex
+func (m *Mixer) mixSeekTo(d time.Duration) {
+	m.mixPreRollLeftTz = 0
+	seekTz := m.mixSchedTzOf(d)
+	fires := make([]*fire.Fire, 0, m.mixReadyFires.Len()+len(m.mixLiveFires)+len(m.mixDoneFires))
+	fires = append(fires, m.mixReadyFires.Fires()...)
+	fires = append(fires, m.mixLiveFires...)
+	fires = append(fires, m.mixDoneFires...)
+	m.mixClearAllFires()
+	for _, f := range fires {
+		if f.BeginTz <= seekTz {
+			m.mixPrepareSource(f.Source) // need the source length to know if the fire is entirely in the past
+		}
+		f.Seek(seekTz)
+		if !f.IsAlive() {
+			m.mixRetire(f)
+		} else if f.IsPlaying() {
+			m.mixGoLive(f, seekTz)
+			m.mixLiveFires = append(m.mixLiveFires, f)
+		} else {
+			m.mixReadyFires.Push(f)
+		}
+	}
+	if m.transport == transportPlay {
+		m.startAtTime = m.mixNow().Add(-d)
+	}
+	m.outputToDur = d
+	m.nextCycleTz = seekTz
+	m.nowTz = seekTz
+	m.mixTrimDoneFires()
+	m.mixPublishClock()
+	m.mixTickSchedule(true)
+	m.mixRecountHorizon()
+	m.mixCountFires()
+}
+
+// IsPlaying returns true unless the mixer is paused or stopped.
+func (m *Mixer) IsPlaying() bool {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.transport == transportPlay
 }
 
 // GetStartTime returns the time mixing began.
-func GetStartTime() time.Time {
-	return startAtTime
+func (m *Mixer) GetStartTime() time.Time {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.startAtTime
+}
+
+// SetMasterVolume from 0 to 1, applied to the sum of all fires before dynamic range compression.
+func (m *Mixer) SetMasterVolume(v float64) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.masterVolume = math.Max(0, math.Min(1, v))
+}
+
+// GetMasterVolume returns the master volume from 0 to 1.
+func (m *Mixer) GetMasterVolume() float64 {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.masterVolume
+}
+
+// Mute the output without stopping the clock, such that scheduled fires still expire on time.
+func (m *Mixer) Mute() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.masterMuted = true
+}
+
+// Unmute the output.
+func (m *Mixer) Unmute() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.masterMuted = false
+}
+
+// IsMuted returns true if the output is muted.
+func (m *Mixer) IsMuted() bool {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.masterMuted
 }
 
-// GetNowAt returns current mix position
-func GetNowAt() time.Duration {
-	return time.Duration(nowTz) * masterTzDur
+// GetNowAt returns current mix position, negative during a pre-roll, see SetPreRoll. It counts only the frames that the output
+// callback has taken: with a lookahead, it is of the last frame the output played, not of the mix ahead of it, see SetLookahead.
+func (m *Mixer) GetNowAt() time.Duration {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	at := m.mixHeardNow()
+	if m.mixAheadCycles > 0 {
+		at = m.mixHeardLast()
+	}
+	if at.preRollTz > 0 {
+		return -m.mixDurOf(at.preRollTz)
+	}
+	return m.mixSchedDurOf(at.tz)
 }
 
 // ClearAllFires to remove all ready & live fires.
-func ClearAllFires() {
-	mixReadyFires = make([]*fire.Fire, 0)
-	mixLiveFires = make([]*fire.Fire, 0)
+func (m *Mixer) ClearAllFires() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixCancelAllFires()
+	m.mixClearAllFires()
+}
+
+// ClearFiresAfter removes all fires that begin at or after a time.Duration-since-start, without disturbing any fire that has already begun. Returns the # of fires removed.
+func (m *Mixer) ClearFiresAfter(t time.Duration) int {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	afterTz := m.mixSchedTzOf(t)
+	return m.mixClearFires(func(f *fire.Fire) bool {
+		return f.BeginTz >= afterTz
+	})
+}
+
+// ClearFiresBySource removes all fires of a source, resolved like SetFire, that have not yet begun. Returns the # of fires removed.
+func (m *Mixer) ClearFiresBySource(src string) int {
+	src = m.mixSourceKey(src)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixClearFires(func(f *fire.Fire) bool {
+		return f.Source == src
+	})
 }
 
 // SetSoundsPath to set the sound path prefix.
-func SetSoundsPath(prefix string) {
-	mixSourcePrefix = prefix
+func (m *Mixer) SetSoundsPath(prefix string) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixSourcePrefix = prefix
+}
+
+// Prepare sources ahead of time, resolved like SetFire, and keep them in memory until evicted; returns the first error.
+// The sources are loaded concurrently, see SetWorkers.
+func (m *Mixer) Prepare(sources ...string) error {
+	return m.PrepareCtx(context.Background(), sources...)
+}
+
+// PrepareCtx is Prepare until the context is done; then no other source begins to load, and the error of the context is returned.
+func (m *Mixer) PrepareCtx(ctx context.Context, sources ...string) error {
+	keys := make([]string, len(sources))
+	for i, src := range sources {
+		keys[i] = m.mixSourceKey(src)
+	}
+	return m.cache.PreloadAllCtx(ctx, keys)
+}
+
+// EvictSource from memory, resolved like SetFire; it will be loaded again if it is fired.
+func (m *Mixer) EvictSource(src string) {
+	m.cache.Evict(m.mixSourceKey(src))
+}
+
+// SourceCacheSize in bytes of all sources in memory.
+func (m *Mixer) SourceCacheSize() int64 {
+	return m.cache.Size()
+}
+
+// SetSourceCacheLimit in bytes of all sources in memory, or 0 for no limit (the default). Past the limit, the sources least recently
+// fired are evicted, even if they were prepared, except those of fires that are scheduled or playing; a source evicted is loaded again
+// if it is fired.
+func (m *Mixer) SetSourceCacheLimit(size int64) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.cache.SetCacheLimit(size)
+	keep := make(map[string]bool)
+	m.mixReadyFires.EachSource(func(src string) {
+		m.mixKeepSources(keep, src)
+	})
+	for _, f := range m.mixLiveFires {
+		keep[f.Resolved()] = true
+	}
+	m.cache.Trim(keep)
+}
+
+// SourceCacheStats of hits, misses and evictions of sources in memory, e.g. for monitoring.
+func (m *Mixer) SourceCacheStats() source.CacheStats {
+	return m.cache.GetCacheStats()
+}
+
+// SetSourceStreaming of a source, resolved like SetFire, to decode it from its file a little ahead of where it plays, instead of
+// loading it whole into memory, e.g. a backing track; it is reloaded if it is in memory. Only a WAV file can stream. A streamed source
+// follows a single playhead, so it should be fired once at a time; a sample it cannot decode in time plays as silence, see StreamUnderruns.
+// It applies only in this mixer.
+func (m *Mixer) SetSourceStreaming(name string, on bool) {
+	key := m.mixSourceKey(name)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.cache.SetStreaming(key, on)
+}
+
+// SetStreamingThreshold of the size of a source file in bytes, above which it streams as if by SetSourceStreaming, or 0 never to (the default);
+// it applies to sources as they are loaded.
+func SetStreamingThreshold(size int64) {
+	source.SetStreamingThreshold(size)
+}
+
+// StreamUnderruns counts the samples of streamed sources that played as silence, because they had not yet been decoded.
+func StreamUnderruns() int64 {
+	return source.StreamUnderruns()
+}
+
+// SetResampleQuality of sources converted to the mix frequency when they are loaded, trading load time for quality;
+// any source in memory of the mixer that was resampled is reloaded at the new quality; any other mixer is not affected.
+func (m *Mixer) SetResampleQuality(q source.ResampleQuality) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.cache.SetResampleQuality(q)
+}
+
+// GetResampleQuality of sources of the mixer, see SetResampleQuality.
+func (m *Mixer) GetResampleQuality() source.ResampleQuality {
+	return m.cache.GetResampleQuality()
+}
+
+// SetDownmixMatrix of sources with a # of channels onto fewer output channels: one row for each output channel, of the weight
+// of each source channel. It is applied once, as a source is loaded, and every source in memory of the mixer of that # is reloaded;
+// any other mixer is not affected. There are defaults of quad and 5.1 to stereo; a source with more channels than the output and
+// no matrix fails to load.
+func (m *Mixer) SetDownmixMatrix(srcChannels int, matrix [][]float64) error {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.cache.SetDownmixMatrix(srcChannels, matrix)
+}
+
+// GetDownmixMatrix of sources of the mixer with a # of channels onto a # of output channels, or nil if there is none.
+func (m *Mixer) GetDownmixMatrix(srcChannels int, outChannels int) [][]float64 {
+	return m.cache.GetDownmixMatrix(srcChannels, outChannels)
+}
+
+// SetAutoNormalize the peak of each source as it is loaded to a target level in dBFS, e.g. -3, so that samples recorded at different
+// levels enter the mix at a comparable level; the samples are scaled once, in memory, and every source in memory of the mixer is
+// reloaded; any other mixer is not affected. A streamed source or a synthesized tone is not normalized. The gain applied to each
+// source is in its SourceInfo.
+func (m *Mixer) SetAutoNormalize(target float64) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.cache.SetNormalize(source.NormalizePeak, target)
+}
+
+// SetAutoNormalizeRMS is SetAutoNormalize by the RMS level of each source instead of its peak, e.g. for sustained sounds.
+func (m *Mixer) SetAutoNormalizeRMS(target float64) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.cache.SetNormalize(source.NormalizeRMS, target)
+}
+
+// StopAutoNormalize of sources, reloading every source in memory at its recorded level (the default).
+func (m *Mixer) StopAutoNormalize() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.cache.SetNormalize(source.NormalizeOff, 0)
+}
+
+// SetSourceGain of a source, resolved like SetFire, a static trim in dB after any normalization, or 0 for none (the default);
+// it takes effect at once, including on the fires of it that are already scheduled or playing, but only in this mixer.
+func (m *Mixer) SetSourceGain(name string, db float64) {
+	key := m.mixSourceKey(name)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.cache.SetGain(key, db)
+}
+
+// GetSourceGain of a source, resolved like SetFire, see SetSourceGain.
+func (m *Mixer) GetSourceGain(name string) float64 {
+	return m.cache.GetGain(m.mixSourceKey(name))
+}
+
+// RegisterSource of encoded audio data under a name, which SetFire will resolve before the sounds path.
+func RegisterSource(name string, data []byte) error {
+	return source.Register(name, data)
+}
+
+// SourceProvider of the samples of a source, e.g. a synthesizer that generates its audio in the mix loop, see RegisterSourceProvider
+type SourceProvider = source.Provider
+
+// ProviderStats of the calls to a source provider by the mix loop, see LoopStats
+type ProviderStats = source.ProviderStats
+
+// RegisterSourceProvider of the samples of a source under a name, which SetFire will resolve before the sounds path, or nil to remove it.
+// Each fire of the name plays the frames that the provider generates, from its beginning, as it is mixed; see SourceProvider for the
+// real-time constraints on it. The time of each call is measured, and a provider that is too slow for the mix loop is warned of and
+// marked in Stats, instead of glitching silently.
+func RegisterSourceProvider(name string, p SourceProvider) error {
+	return source.RegisterProvider(name, p)
+}
+
+// SetSubSamplePrecision of the begin of each fire set after it: if on, the fraction of a sample after floor(begin × frequency) is honored
+// too, by interpolating the source between its samples, e.g. so that copies of a sample layered with tiny offsets do not flam. The fraction
+// delays the whole fire, so that its phase is consistent, and it is interpolated from silence before the first sample of its source.
+// Interpolation softens the highest frequencies slightly, so it is off by default.
+func (m *Mixer) SetSubSamplePrecision(on bool) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixSubSample = on
+}
+
+// GetSubSamplePrecision of the begin of fires, see SetSubSamplePrecision.
+func (m *Mixer) GetSubSamplePrecision() bool {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixSubSample
 }
 
 // GetCycleDurationTz sets the duration of a mix cycle.
-func SetCycleDuration(d time.Duration) {
-	if masterFreq == 0 {
+func (m *Mixer) SetCycleDuration(d time.Duration) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.masterFreq == 0 {
 		panic("Must specify mixing frequency before setting cycle duration!")
 	}
-	masterCycleDurTz = spec.Tz((d / time.Second) * time.Duration(masterFreq))
+	m.masterCycleDurTz = m.mixTzOf(d)
 }
 
 // GetCycleDurationTz returns the duration of a mix cycle.
-func GetCycleDurationTz() spec.Tz {
-	return masterCycleDurTz
+func (m *Mixer) GetCycleDurationTz() spec.Tz {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.masterCycleDurTz
 }
 
-// OutputStart requires a known length
-func OutputStart(length time.Duration, out io.Writer) {
+// OutputStart with a known length, or 0 to stream an unknown length; the length is of the mix from time zero, which is lengthened
+// by the pre-roll if it is included, see SetPreRollOutput
+func (m *Mixer) OutputStart(length time.Duration, out io.Writer) {
+	m.mixMutex.Lock()
+	if !m.mixPreRollOutput {
+		m.mixSkipPreRoll()
+	} else if m.mixPreRollLeftTz == 0 {
+		m.mixBeginPreRoll()
+		m.mixPublishClock()
+	}
+	if length > 0 {
+		length = m.mixDurOf(m.mixSchedTzOf(length) + m.mixPreRollLeftTz) // of exactly the frames that OutputContinueTo outputs
+	}
+	meta, pipe := m.mixOutputMeta, m.mixOutputPipe
+	frameSize := 0
+	if m.masterSpec != nil {
+		frameSize = m.masterSpec.Channels * m.masterSpec.Format.Bits() / 8
+	}
+	m.mixMutex.Unlock()
+	m.outputMutex.Lock()
+	defer m.outputMutex.Unlock()
+	m.mixCloseOutWriter()
+	if out != nil && frameSize > 0 {
+		m.outputWriter = m.mixNewOutWriter(out, frameSize, pipe)
+		out = m.outputWriter.writerOf()
+		defer m.outputWriter.wait() // for the header
+	}
+	m.outputStarted = true
+	if m.output != nil {
+		m.output.SetMetadata(meta)
+		m.output.Start(length, out)
+		return
+	}
+	bind.SetOutputMetadata(meta)
 	bind.OutputStart(length, out)
 }
 
+// OutputStartAuto with the exact length of the schedule, to the end of the last fire, see ScheduleEnd, plus a tail, e.g. for the decay
+// of an effect on the last fire, so the header of a WAV is right without guessing how long to pad it. The sources of all the fires are
+// loaded first, so that their natural lengths are known. Returns the end, to mix and output to by OutputContinueTo.
+func (m *Mixer) OutputStartAuto(out io.Writer, extraTail time.Duration) time.Duration {
+	m.mixMutex.Lock()
+	sources := make(map[string]bool)
+	m.mixReadyFires.EachSource(func(src string) {
+		sources[src] = true
+	})
+	for _, f := range m.mixLiveFires {
+		sources[f.Source] = true
+	}
+	for f := range m.mixChainWaiting {
+		sources[f.Source] = true
+	}
+	m.mixMutex.Unlock()
+	for src := range sources {
+		if err := m.mixPrepareSource(src); err != nil {
+			debug.Warnf("mix.OutputStartAuto(%s) failed to load source: %s", src, err)
+		}
+	}
+	end := m.ScheduleEnd() + extraTail
+	m.mixMutex.Lock()
+	length := end - m.outputToDur // from where the output continues
+	m.mixMutex.Unlock()
+	if length < 0 {
+		length = 0
+	}
+	m.OutputStart(length, out)
+	return end
+}
+
 // OutputContinueTo to  mix and output as []byte via stdout, up to a specified duration-since-start
-func OutputContinueTo(t time.Duration) {
-	deltaDur := t - outputToDur
-	deltaTz := spec.Tz(masterFreq * float64((deltaDur)/time.Second))
-	debug.Printf("mix.OutputContinueTo(%+v) deltaDur:%+v nowTz:%+v deltaTz:%+v begin...", t, deltaDur, nowTz, deltaTz)
-	bind.OutputNext(deltaTz)
-	outputToDur = t
-	debug.Printf("mix.OutputContinueTo(%+v) ...done! nowTz:%+v outputToDur:%+v", t, nowTz, outputToDur)
+func (m *Mixer) OutputContinueTo(t time.Duration) {
+	m.mixMutex.Lock()
+	if !m.mixPreRollOutput {
+		m.mixSkipPreRoll()
+	}
+	deltaDur := t - m.outputToDur
+	deltaTz := m.mixSchedTzOf(t) - m.mixSchedTzOf(m.outputToDur) + m.mixPreRollLeftTz // the pre-roll is output first
+	debug.Debugf("mix.OutputContinueTo(%+v) deltaDur:%+v nowTz:%+v deltaTz:%+v begin...", t, deltaDur, m.nowTz, deltaTz)
+	m.mixMutex.Unlock() // the output pulls each sample via NextSample
+	m.mixOutputNext(deltaTz)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.outputToDur = t
+	debug.Debugf("mix.OutputContinueTo(%+v) ...done! nowTz:%+v outputToDur:%+v", t, m.nowTz, m.outputToDur)
 }
 
-// OutputBegin to output WAV closer as []byte via stdout
-func OutputClose() {
-	// nothing to do
+// OutputClose to finish the output, e.g. to patch the header of a streamed WAV if the writer is an io.WriteSeeker; returns an error
+// if the writer failed, see OnOutputError
+func (m *Mixer) OutputClose() error {
+	m.outputMutex.Lock()
+	defer m.outputMutex.Unlock()
+	return m.mixOutputClose()
 }
 
 //
 // Private
 //
 
-var (
-	outputToDur      time.Duration
-	startAtTime      time.Time
-	nowTz            spec.Tz
-	nextCycleTz      spec.Tz
-	masterCycleDurTz spec.Tz
-	masterTzDur      time.Duration
-	// TODO: implement mixFreq float64
-	mixSourcePrefix string
-	mixReadyFires   []*fire.Fire
-	mixLiveFires    []*fire.Fire
-	masterSpec      *spec.AudioSpec
-	masterFreq      float64
+// mixConfigure for the spec obtained of the output, warning if it is not the spec requested; the caller must hold the mixMutex
+func (m *Mixer) mixConfigure(requested spec.AudioSpec, obtained spec.AudioSpec) {
+	if requested != obtained {
+		debug.Warnf("mix.Configure obtained %vHz %s %d channels of the output, not the %vHz %s %d channels requested",
+			obtained.Freq, obtained.Format, obtained.Channels, requested.Freq, requested.Format, requested.Channels)
+	}
+	s := obtained
+	m.mixRequestedSpec = requested
+	m.masterSpec = &s
+	m.teardown = teardownNone
+	m.masterFreq = float64(s.Freq)
+	m.masterCycleDurTz = spec.Tz(m.masterFreq)
+	m.masterGainStep = 1 / (m.masterFreq * masterGainRampDur.Seconds())
+	m.mixFireBuffer = make([]sample.Value, s.Channels)
+	m.mixFireScratch = make([]sample.Value, s.Channels)
+	m.mixFireTail = make([]sample.Value, s.Channels)
+	m.mixSumBuffer = make([]sample.Value, s.Channels)
+	m.mixOutBuffer = make([]sample.Value, s.Channels)
+	m.mixResetDither()
+	m.cache.Configure(s)
+	m.mixPublishClock()
+	m.mixTickSchedule(true)
+	if m == mixDefault { // else, the rate of its effects and fires is only of this mixer
+		effect.Configure(s)
+		fire.Configure(s)
+	}
+}
+
+// mixOutputNext # of samples, which the output pulls via NextSample; the caller must not hold the mixMutex
+func (m *Mixer) mixOutputNext(numSamples spec.Tz) {
+	m.outputMutex.Lock()
+	defer m.outputMutex.Unlock()
+	if m.output != nil {
+		m.output.Next(numSamples)
+	} else {
+		bind.OutputNext(numSamples)
+	}
+	if w := m.outputWriter; w != nil && w.pipe {
+		w.push(false)
+	} else if w != nil {
+		w.wait() // all that was mixed is written, offline
+	}
+}
+
+// mixOutputClose flushes and closes the output writer; the caller must hold the outputMutex
+func (m *Mixer) mixOutputClose() (err error) {
+	m.outputStarted = false
+	if m.output != nil {
+		err = m.output.Close()
+	} else {
+		err = bind.OutputClose()
+	}
+	if writerErr := m.mixCloseOutWriter(); writerErr != nil {
+		err = writerErr // of the failure, rather than what the encoder made of it
+	}
+	return
+}
+
+// mixCloseOutWriter of the output, if any, returning an error of its failure; the caller must hold the outputMutex
+func (m *Mixer) mixCloseOutWriter() (err error) {
+	if m.outputWriter != nil {
+		err = m.outputWriter.close()
+		m.outputWriter = nil
+	}
+	return
+}
+
+const masterGainRampDur = 5 * time.Millisecond
+
+type transportEnum uint
+
+const (
+	transportPlay transportEnum = iota
+	transportPause
+	transportStop
 )
 
-func init() {
-	startAtTime = time.Now().Add(0xFFFF * time.Hour) // this gets reset by Start() or StartAt()
+type teardownEnum uint
+
+const (
+	teardownNone     teardownEnum = iota
+	teardownDraining              // no new fires, while the live fires play to their end
+	teardownCutting               // no new fires, and the output is silent, until the reset
+	teardownDone                  // until the mixer is configured or another fire is set
+)
+
+// mixDrain the live fires to their end, for at most a timeout, after removing the fires that have not yet begun. A direct
+// output that has been started, e.g. a WAV writer, is pulled here; the audio interface of the default mixer pulls on its own,
+// so it is waited for. Else, nothing would hear the rest of the live fires, so there is nothing to wait for.
+// The caller must not hold the mixMutex
+func (m *Mixer) mixDrain(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultTeardownTimeout
+	}
+	m.mixMutex.Lock()
+	m.mixClearFires(func(f *fire.Fire) bool { return true })
+	limitTz, stepTz := m.mixTzOf(timeout), m.mixTzOf(drainStepDur)
+	m.mixMutex.Unlock()
+	m.outputMutex.Lock()
+	started := m.outputStarted
+	m.outputMutex.Unlock()
+	switch {
+	case started && (m.output != nil || bind.IsDirectOutput()):
+		for drainedTz := spec.Tz(0); drainedTz < limitTz && !m.mixIsDrained(); drainedTz += stepTz {
+			m.mixOutputNext(stepTz)
+		}
+	case m == mixDefault && bind.IsStreamingOutput():
+		for deadline := time.Now().Add(timeout); time.Now().Before(deadline) && !m.mixIsDrained(); {
+			time.Sleep(drainStepDur)
+		}
+	}
+}
+
+// mixIsDrained of all live fires, i.e. none is alive; the caller must not hold the mixMutex
+func (m *Mixer) mixIsDrained() bool {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.mixErr != nil {
+		return true // silent until the teardown
+	}
+	for _, f := range m.mixLiveFires {
+		if f.IsAlive() {
+			return false
+		}
+	}
+	return true
+}
+
+const drainStepDur = 10 * time.Millisecond
+
+// mixReleaseBuffers of the mix loop, keeping only the buffers of the master channels, for any output callback after the teardown,
+// which may still be encoding the last sample; the caller must hold the mixMutex
+func (m *Mixer) mixReleaseBuffers() {
+	m.mixChunks = nil
+	m.effectBuffer = nil
+	m.mixKeepSource = make(map[string]bool)
+}
+
+// mixOutputSample is the next sample of the output, tee'd: silent while paused, ahead of the clock, during a teardown, or failed, else
+// mixed; the caller must hold the mixMutex
+func (m *Mixer) mixOutputSample() []sample.Value {
+	if m.transport != transportPlay || m.teardown == teardownCutting || m.mixErr != nil || m.mixAheadOfClock() {
+		smp := m.mixSilence()
+		m.mixTeeSample(smp)
+		return smp
+	}
+	smp := m.mixNextSample()
+	m.mixTeeSample(smp)
+	return smp
+}
+
+// mixNextSample of all live fires, summed by bus, with effects, master gain and headroom, compression, and clipping; the caller must hold the mixMutex
+func (m *Mixer) mixNextSample() []sample.Value {
+	if m.mixPreRollLeftTz > 0 {
+		return m.mixNextPreRoll()
+	}
+	began := time.Now()
+	if !m.mixBlockHas(m.nowTz) && len(m.mixLiveFires) > m.mixParallelFires {
+		m.mixRenderBlock()
+	}
+	if m.mixBlockHas(m.nowTz) {
+		m.mixAddBlockFrame(int(m.nowTz - m.mixBlockBeginTz))
+	} else {
+		for _, fire := range m.mixLiveFires {
+			fireTz, playing, transition := m.mixFireAt(fire, m.nowTz)
+			if transition != 0 {
+				m.mixFireTransition(fire, transition)
+			}
+			if playing {
+				src, volume, pan := m.mixGetSource(fire.Resolved()), fire.VolumeAt(fireTz)*fire.FadeAt(fireTz), fire.PanAt(fireTz)
+				mixSourceAt(m.mixFireBuffer, m.mixFireScratch, src, volume, pan, m.mixPanLaw, fire.PlayRate(), fire.OffsetTz, fireTz, fire.BeginFrac)
+				if fire.XFadeTz > 0 {
+					mixLoopXFadeAt(m.mixFireBuffer, m.mixFireTail, m.mixFireScratch, src, fire, volume, pan, m.mixPanLaw)
+				}
+				if tone := fire.ToneFilter(); tone != nil {
+					tone.Process(m.mixFireBuffer)
+				}
+				fire.Meter().Add(m.mixFireBuffer)
+				m.mixBusOf(fire).add(m.mixFireBuffer)
+			}
+		}
+	}
+	m.mixCountFires()
+	smp := m.mixSumBuffer
+	m.mixSumBuses(smp)
+	m.mixProcessEffects(m.masterEffects, smp)
+	//	debug.Printf("*Mixer.nextSample %+v\n", sample)
+	m.mixCheckTicks()
+	m.nowTz++
+	if m.mixAheadCycles == 0 { // else it is published as the output takes the frame from the lookahead
+		atomic.StoreInt64(&m.mixNowFrames, int64(m.nowTz))
+	}
+	m.mixCheckLowWater()
+	m.mixRampMasterGain()
+	for c := 0; c < m.masterSpec.Channels; c++ {
+		smp[c] *= sample.Value(m.masterGain * m.headroomGain)
+	}
+	m.mixMeterOutput(smp)
+	m.mixApplyAlgorithm(smp, m.mixOutBuffer)
+	if m.mixNormalizeGain != 1 {
+		for c := range m.mixOutBuffer {
+			m.mixOutBuffer[c] *= sample.Value(m.mixNormalizeGain)
+		}
+	}
+	if sample.Clip(m.mixClipMode, m.mixOutBuffer) {
+		m.mixStatCycleClipped++
+	}
+	m.mixDither.Apply(m.masterSpec.Format, m.mixOutBuffer)
+	if m.nowTz > m.nextCycleTz {
+		m.mixStatCycle()
+		m.mixCycle()
+	}
+	m.mixStatSample(began)
+	return m.mixOutBuffer
+}
+
+// mixTzOf a duration, to the nearest sample at the master frequency.
+// Always convert between time and samples with the exact frequency, never a whole # of nanoseconds per sample,
+// else the rounding error accumulates into audible drift over a long session, e.g. 3ms per minute at 44100Hz.
+func (m *Mixer) mixTzOf(d time.Duration) spec.Tz {
+	return spec.Tz(math.Round(d.Seconds() * m.masterFreq))
 }
 
-func mixSourceAt(src string, volume float64, pan float64, at spec.Tz) []sample.Value {
-	s := mixGetSource(src)
+// mixBeginTzOf a fire at a time of the schedule, the exact sample floor(d × frequency ÷ time scale), and the fraction of a sample
+// after it; for a whole frequency, it is computed in integers, so that it never rounds down a sample that is exactly on time
+func (m *Mixer) mixBeginTzOf(d time.Duration) (tz spec.Tz, frac float64) {
+	if d <= 0 {
+		return 0, 0
+	}
+	schedFreq := m.masterFreq / m.mixTimeScale
+	if freq := int64(schedFreq); float64(freq) == schedFreq {
+		rem := int64(d) % int64(time.Second) * freq
+		return spec.Tz(int64(d)/int64(time.Second)*freq + rem/int64(time.Second)), float64(rem%int64(time.Second)) / float64(time.Second)
+	}
+	exact := d.Seconds() * schedFreq
+	return spec.Tz(math.Floor(exact)), exact - math.Floor(exact)
+}
+
+// mixDurOf a # of samples at the master frequency, to the nearest nanosecond
+func (m *Mixer) mixDurOf(tz spec.Tz) time.Duration {
+	return time.Duration(math.Round(float64(tz) * float64(time.Second) / m.masterFreq))
+}
+
+// mixSourceAt a Tz of fire playback into out, at a rate, from an offset into the source, delayed by a fraction of a sample,
+// panned by a pan law; the source itself is never copied, and scratch is only used to interpolate between its samples
+func mixSourceAt(out []sample.Value, scratch []sample.Value, s *source.Source, volume float64, pan float64, law PanLaw, rate float64, offset spec.Tz, at spec.Tz, frac float64) {
 	if s == nil {
-		return make([]sample.Value, masterSpec.Channels)
+		for c := range out {
+			out[c] = 0
+		}
+		return
 	}
-	// if at != 0 {
-	// 	debug.Printf("About to source.SampleAt %v in %v\n", at, s.URL)
-	// }
-	return s.SampleAt(at, volume, pan)
+	// the source is read centered, and panned by the pan law after, so that its volume holds at any pan
+	if rate != 1 || frac != 0 {
+		pos := float64(offset) + (float64(at)-frac)*rate
+		if pos < 0 { // before the first sample, interpolated from silence
+			s.SampleAtInto(out, 0, volume*(1+pos), 0)
+		} else {
+			s.SampleAtFracInto(out, scratch, pos, volume, 0)
+		}
+	} else {
+		s.SampleAtInto(out, offset+at, volume, 0)
+	}
+	mixPan(out, law, pan)
 }
 
-func mixPrepareSource(src string) {
-	source.Prepare(src)
+// mixLoopXFadeAt adds the tail of the previous iteration of a crossfade loop, while it overlaps the head that is already in out,
+// each at its equal-power gain, see fire.LoopXFade; tail is a buffer of the master channels, for the second read of the source
+func mixLoopXFadeAt(out []sample.Value, tail []sample.Value, scratch []sample.Value, s *source.Source, f *fire.Fire, volume float64, pan float64, law PanLaw) {
+	tailTz, headGain, tailGain, ok := f.LoopXFade()
+	if !ok {
+		return
+	}
+	mixSourceAt(tail, scratch, s, volume*tailGain, pan, law, f.PlayRate(), f.OffsetTz, tailTz, f.BeginFrac)
+	for c := range out {
+		out[c] = out[c]*sample.Value(headGain) + tail[c]
+	}
 }
 
-func mixGetSource(src string) *source.Source {
-	return source.Get(src)
+// mixNewFire for a source, resolved and loaded if necessary, but not yet scheduled
+func (m *Mixer) mixNewFire(src string, begin time.Duration, sustain time.Duration, volume float64, pan float64) (*fire.Fire, error) {
+	src = m.mixSourceKey(src)
+	if err := m.mixPrepareSource(src); err != nil {
+		return nil, err
+	}
+	m.mixMutex.Lock()
+	subSample := m.mixSubSample
+	m.mixMutex.Unlock()
+	return m.mixFireOf(src, begin, sustain, volume, pan, subSample), nil
 }
 
-// TODO:
-// Make a new empty map[string]*Source, e.g. keepSource
-// While iterating over the ready & active fires (see issues #11 and #18; implemented as of pull #29) copy any used *Source to the new keepSource
-// Replace the mixSource with keepSource
-func mixCycle() {
-	var f *fire.Fire
-	// for garbage collection of unused sources:
-	keepSource := make(map[string]bool)
-	// if a fire is near-to-playback, move it to the live fire queue
-	keepReadyFires := make([]*fire.Fire, 0)
-	for _, f = range mixReadyFires {
-		keepSource[f.Source] = true
-		if f.BeginTz < nowTz+masterCycleDurTz*2 { // for now, double a mix cycle is consider near-playback
-			mixLiveFires = append(mixLiveFires, f)
+// mixFireOf a source that is already resolved and prepared, beginning at a fraction of a sample if subSample
+func (m *Mixer) mixFireOf(key string, begin time.Duration, sustain time.Duration, volume float64, pan float64, subSample bool) *fire.Fire {
+	beginTz, frac := m.mixBeginTzOf(begin)
+	var endTz spec.Tz
+	if sustain != 0 {
+		endTz = beginTz + m.mixSchedTzOf(sustain)
+	}
+	f := fire.New(key, beginTz, endTz, volume, pan)
+	if subSample {
+		f.BeginFrac = frac
+	}
+	if m.masterSpec != nil {
+		f.Configure(*m.masterSpec) // at the frequency of this mixer, whatever the default
+	}
+	f.SetCache(m.cache) // to know the natural length of its source, loaded by this mixer
+	f.SetTimeScale(m.mixTimeScale)
+	return f
+}
+
+// mixSchedule fires, once they are fully configured, and capture them if a capture is in progress
+func (m *Mixer) mixSchedule(fires ...*fire.Fire) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixCaptureFires(fires)
+	m.mixPushFires(fires)
+}
+
+// mixPushFires onto the ready queue, or straight to the live fires if they begin before the next mix cycle would move them,
+// e.g. triggered live; the caller must hold the mixMutex
+func (m *Mixer) mixPushFires(fires []*fire.Fire) {
+	switch {
+	case m.teardown == teardownDraining, m.teardown == teardownCutting, m.mixErr != nil:
+		for _, f := range fires {
+			f.Cancel() // no new fires during a teardown, or after a failure
+		}
+		return
+	case m.teardown == teardownDone:
+		m.teardown = teardownNone
+	}
+	for _, f := range fires {
+		if f.BeginTz <= m.nextCycleTz {
+			m.mixGoLive(f, f.BeginTz)
+			m.mixLiveFires = append(m.mixLiveFires, f)
 		} else {
-			keepReadyFires = append(keepReadyFires, f)
+			m.mixReadyFires.Push(f)
+		}
+		if f.BeginTz > m.mixHorizonTz {
+			m.mixHorizonTz = f.BeginTz
 		}
 	}
-	mixReadyFires = keepReadyFires
-	// keep only active fires
-	keepLiveFires := make([]*fire.Fire, 0)
-	for _, f = range mixLiveFires {
+	m.mixCountFires()
+}
+
+func (m *Mixer) mixClearAllFires() {
+	m.mixReadyFires = fire.NewQueue()
+	m.mixLiveFires = make([]*fire.Fire, 0)
+	m.mixDoneFires = make([]*fire.Fire, 0)
+	m.mixDropBlock()
+	m.mixHorizonTz = 0
+	m.mixCountFires()
+}
+
+// mixCancelAllFires that are ready or live, before they are cleared, so that each one is done with a reason
+func (m *Mixer) mixCancelAllFires() {
+	m.mixCancelChains()
+	m.mixReadyFires.Each(func(f *fire.Fire) {
+		f.Cancel()
+	})
+	for _, f := range m.mixLiveFires {
 		if f.IsAlive() {
-			keepSource[f.Source] = true
+			f.Cancel()
+		}
+	}
+}
+
+// mixClearFires removes and cancels the ready fires that match, and cancels the live fires that match but have not yet begun,
+// nor been mixed ahead in a block.
+func (m *Mixer) mixClearFires(match func(f *fire.Fire) bool) (removed int) {
+	removed = m.mixReadyFires.Remove(func(f *fire.Fire) bool {
+		if match(f) {
+			f.Cancel()
+			return true
+		}
+		return false
+	})
+	renderedTz := m.mixRenderedTz()
+	for _, f := range m.mixLiveFires {
+		if f.BeginTz > renderedTz && f.IsAlive() && match(f) {
+			f.Cancel() // the next mix cycle will drop it
+			removed++
+		}
+	}
+	m.mixRecountHorizon()
+	m.mixCountFires()
+	return
+}
+
+// mixSourceName of a source key, as it would be set on a fire, without the sounds path prefix; the caller must hold the mixMutex
+func (m *Mixer) mixSourceName(key string) string {
+	if source.IsRegistered(key) || source.IsProvided(key) || source.IsTone(key) || m.mixMultiSource(key) != nil {
+		return key
+	}
+	return strings.TrimPrefix(key, m.mixSourcePrefix)
+}
+
+// mixSourceKey resolves a registered, provided or synthesized source by name, else a path under the sounds path prefix
+func (m *Mixer) mixSourceKey(src string) string {
+	if source.IsRegistered(src) || source.IsProvided(src) || source.IsTone(src) || m.mixMultiSource(src) != nil {
+		return src
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixSourcePrefix + src
+}
+
+// mixPrepareSource, or all of the variants of a multi-sample source
+func (m *Mixer) mixPrepareSource(src string) (err error) {
+	if multi := m.mixMultiSource(src); multi != nil {
+		for _, l := range multi.layers {
+			for _, key := range l.keys {
+				if prepareErr := m.cache.Prepare(key); err == nil {
+					err = prepareErr
+				}
+			}
+		}
+		return
+	}
+	return m.cache.Prepare(src)
+}
+
+func (m *Mixer) mixGetSource(src string) *source.Source {
+	return m.cache.Get(src)
+}
+
+// mixGoLive a fire, resolving its source and the begin of the fires chained after it, and prefetching it if it is streamed, from where the fire will play at a Tz,
+// at or after it begins; the caller must hold the mixMutex
+func (m *Mixer) mixGoLive(f *fire.Fire, at spec.Tz) {
+	if key, ok := source.Replaced(f.Resolved()); ok { // it played the audio before a reload, so replays the new one, see ReloadSource
+		if key == f.Source {
+			key = ""
+		}
+		f.Resolve(key)
+	}
+	m.mixResolveSustain(f) // before it is humanized, which moves its begin and end together
+	f.ApplyHumanize(m.mixHumanize, m.mixHumanizeRandom, m.nowTz)
+	f.ResolveTone(m.masterFreq, len(m.mixOutBuffer))
+	m.mixResolve(f)
+	m.mixResolveChains(f, false)
+	if s := m.mixGetSource(f.Resolved()); s != nil && s.IsStreaming() {
+		s.Prefetch(f.OffsetTz + spec.Tz(float64(at-f.BeginTz)*f.PlayRate()))
+	}
+}
+
+// mixCycle moves the fires that begin soon from the ready queue to the live fires, and collects the live fires that are done,
+// with the sources that no fire will play. It only touches the fires that begin soon, however many are scheduled.
+func (m *Mixer) mixCycle() {
+	// if a fire is near-to-playback, move it to the live fires; for now, double a mix cycle is consider near-playback,
+	// plus the timing jitter, early enough to humanize it earlier
+	nearTz := m.nowTz + m.masterCycleDurTz*2 + m.mixSchedTzOf(m.mixHumanize.TimingJitter)
+	for f := m.mixReadyFires.Peek(); f != nil && f.BeginTz < nearTz; f = m.mixReadyFires.Peek() {
+		m.mixReadyFires.Pop()
+		if f.IsCanceled() {
+			delete(m.mixSustains, f)
+			continue
+		}
+		m.mixPrepareSource(f.Source) // may have been pruned if this fire is being replayed
+		m.mixGoLive(f, f.BeginTz)
+		m.mixLiveFires = append(m.mixLiveFires, f)
+	}
+	// for garbage collection of unused sources:
+	keepSource := m.mixKeepSource
+	for src := range keepSource {
+		delete(keepSource, src)
+	}
+	m.mixReadyFires.EachSource(func(src string) {
+		m.mixKeepSources(keepSource, src)
+	})
+	if m.mixPreRollClick != "" {
+		keepSource[m.mixPreRollClick] = true
+	}
+	// keep only active fires, filtered in place
+	keepLiveFires := m.mixLiveFires[:0]
+	for _, f := range m.mixLiveFires {
+		if f.IsAlive() {
+			keepSource[f.Resolved()] = true
 			keepLiveFires = append(keepLiveFires, f)
+			f.Meter().Publish()
 		} else {
-			f.Teardown()
+			f.Meter().Reset()
+			if !f.IsCanceled() {
+				m.mixRetire(f) // kept only if Stop may replay it, see SetReplayLimit
+			}
 		}
 	}
-	mixLiveFires = keepLiveFires
-	source.Prune(keepSource)
-	nextCycleTz = nowTz + masterCycleDurTz
-	if debug.Active() && source.Count() > 0 {
-		debug.Printf("mix [%dz] fire-ready:%d fire-active:%d sources:%d\n", nowTz, len(mixReadyFires), len(mixLiveFires), source.Count())
+	for i := len(keepLiveFires); i < len(m.mixLiveFires); i++ {
+		m.mixLiveFires[i] = nil // release the collected fires to the garbage collector
+	}
+	m.mixLiveFires = keepLiveFires
+	m.mixTrimDoneFires()
+	m.masterMeter.Publish()
+	sourceCount := m.cache.Count()
+	m.cache.Prune(keepSource)
+	m.cache.Trim(keepSource)
+	m.nextCycleTz = m.nowTz + m.masterCycleDurTz
+	m.mixCountFires()
+	if debug.Active() && sourceCount > 0 {
+		debug.Debugf("mix [%dz] fire-ready:%d fire-active:%d sources:%d pruned:%d", m.nowTz, m.mixReadyFires.Len(), len(m.mixLiveFires), m.cache.Count(), sourceCount-m.cache.Count())
 	}
 }
 
-func mixLogarithmicRangeCompression(i sample.Value) sample.Value {
-	if i < -1 {
-		return sample.Value(-math.Log(-float64(i)-0.85)/14 - 0.75)
-	} else if i > 1 {
-		return sample.Value(math.Log(float64(i)-0.85)/14 + 0.75)
-	} else {
-		return sample.Value(i / 1.61803398875)
+func (m *Mixer) mixRampMasterGain() {
+	target := m.masterVolume
+	if m.masterMuted {
+		target = 0
+	}
+	if m.masterGain < target {
+		m.masterGain = math.Min(target, m.masterGain+m.masterGainStep)
+	} else if m.masterGain > target {
+		m.masterGain = math.Max(target, m.masterGain-m.masterGainStep)
 	}
 }
@@ -2,11 +2,25 @@
 package mix
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/sample"
 	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+	"github.com/go-mix/mix/lib/effect"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
 	"time"
 )
 
@@ -53,8 +67,499 @@ func TestGetStartTime(t *testing.T) {
 	// TODO: Test Mixer GetStartTime
 }
 
+func TestPauseResume(t *testing.T) {
+	testMixSetup()
+	StartAt(time.Now())
+	NextSample()
+	NextSample()
+	assert.True(t, IsPlaying())
+	Pause()
+	assert.False(t, IsPlaying())
+	pausedAt := GetNowAt()
+	startedAt := GetStartTime()
+	NextSample()
+	assert.Equal(t, pausedAt, GetNowAt())
+	Resume()
+	assert.True(t, IsPlaying())
+	assert.True(t, GetStartTime().After(startedAt))
+	NextSample()
+	assert.Equal(t, pausedAt+mixDefault.mixDurOf(1), GetNowAt())
+}
+
+func TestStop(t *testing.T) {
+	testMixSetup()
+	StartAt(time.Now())
+	SetFire("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 0, 1.0, 0)
+	for n := 0; n < 100; n++ {
+		NextSample()
+	}
+	assert.Equal(t, 1, len(mixDefault.mixLiveFires))
+	Stop()
+	assert.False(t, IsPlaying())
+	assert.Equal(t, time.Duration(0), GetNowAt())
+	assert.Equal(t, 0, len(mixDefault.mixLiveFires))
+	assert.Equal(t, 1, FireCount())
+	Resume()
+	assert.True(t, IsPlaying())
+}
+
 func TestSetFire(t *testing.T) {
-	// TODO: Test Mixer SetFire
+	testMixSetup()
+	SetMixAlgorithm(MixHardClip) // plain summation, to compare with the source
+	src := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	f := SetFire(src, 10*time.Millisecond, 0, 1.0, 0)
+	assert.Equal(t, spec.Tz(441), f.BeginTz)
+	out, err := Render(20 * time.Millisecond)
+	assert.Nil(t, err)
+	s := mixDefault.mixGetSource(src)
+	assert.Equal(t, float64(0), out[440])
+	for n := 0; n < 10; n++ { // from the first sample of the source
+		assert.Equal(t, float64(s.SampleAt(spec.Tz(n), 1, 0)[0]), out[441+n])
+	}
+}
+
+func TestSetFire_SourceFails(t *testing.T) {
+	testMixSetup()
+	missing := "../source/testdata/ThisShouldFailBecauseItDoesNotExist.wav"
+	f := SetFire(missing, 0, 0, 1.0, 0)
+	assert.Nil(t, f)
+	_, err := SetFireErr(missing, 0, 0, 1.0, 0)
+	assert.NotNil(t, err)
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	assert.Nil(t, SetFireAfter(f, 0, src, 0, 1.0, 0)) // the chain after a failed fire is nil too
+	g := NewFireGroup()
+	assert.Nil(t, g.SetFire(missing, 0, 0, 1.0, 0))
+	g.Add(f)
+	assert.Equal(t, 0, g.Len())
+	assert.Equal(t, 0, FireCount())
+}
+
+func TestSetFire_BeginFloor(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	assert.Equal(t, spec.Tz(0), SetFire(src, 13606*time.Nanosecond, 0, 1.0, 0).BeginTz) // 0.6 of a sample
+	assert.Equal(t, spec.Tz(44100*3), SetFire(src, 3*time.Second, 0, 1.0, 0).BeginTz)
+	assert.Equal(t, spec.Tz(4410), SetFire(src, 100*time.Millisecond, 0, 1.0, 0).BeginTz) // never rounded down if exactly on time
+	assert.Equal(t, float64(0), SetFire(src, 13606*time.Nanosecond, 0, 1.0, 0).BeginFrac) // unless sub-sample precision is on
+}
+
+func TestSetFire_PhaseCancel(t *testing.T) {
+	src := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	for _, precise := range []bool{false, true} {
+		testMixSetup()
+		SetSubSamplePrecision(precise)
+		begin := 10*time.Millisecond + 13606*time.Nanosecond
+		SetFire(src, begin, 0, 1.0, 0)
+		SetFire(src, begin, 0, -1.0, 0) // inverted
+		out, err := Render(200 * time.Millisecond)
+		assert.Nil(t, err)
+		for n, v := range out {
+			if v != 0 {
+				t.Fatalf("precise:%v not digital silence at sample %d: %v", precise, n, v)
+			}
+		}
+	}
+}
+
+func TestSetSubSamplePrecision(t *testing.T) {
+	testMixSetup()
+	defer SetSubSamplePrecision(false)
+	assert.False(t, GetSubSamplePrecision())
+	SetSubSamplePrecision(true)
+	assert.True(t, GetSubSamplePrecision())
+	SetMixAlgorithm(MixHardClip) // plain summation, to compare with the source
+	src := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	f := SetFire(src, 10*time.Millisecond+11338*time.Nanosecond, 0, 1.0, 0) // and half a sample
+	assert.Equal(t, spec.Tz(441), f.BeginTz)
+	assert.InDelta(t, 0.5, f.BeginFrac, 0.001)
+	out, err := Render(20 * time.Millisecond)
+	assert.Nil(t, err)
+	s := mixDefault.mixGetSource(src)
+	value := func(n int) float64 {
+		return float64(s.SampleAt(spec.Tz(n), 1, 0)[0])
+	}
+	assert.Equal(t, float64(0), out[440])
+	assert.InDelta(t, (1-f.BeginFrac)*value(0), out[441], 1e-6) // from silence
+	for n := 1; n < 10; n++ {
+		assert.InDelta(t, value(n-1)+(1-f.BeginFrac)*(value(n)-value(n-1)), out[441+n], 1e-6)
+	}
+	Teardown()
+	assert.False(t, GetSubSamplePrecision())
+}
+
+// run with `go test -race` to detect any unguarded access
+func TestSetFire_Concurrent(t *testing.T) {
+	testMixSetup()
+	src := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	stop := make(chan bool)
+	mixing := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				close(mixing)
+				return
+			default:
+				NextSample()
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				SetFire(src, time.Duration(n)*time.Millisecond, 0, 1.0, 0) // soon, to go live and expire
+				SetFire(src, time.Hour, 0, 1.0, 0)                         // far future, to remain ready
+				FireCount()
+				GetNowAt()
+				Spec()
+				if n%25 == 0 {
+					SetMasterVolume(float64(g) / 8)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(stop)
+	<-mixing
+	assert.Equal(t, 8*100, ClearFiresAfter(time.Hour))
+}
+
+func TestSeekTo(t *testing.T) {
+	testMixSetup()
+	SetReplayLimit(-1)
+	src := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	past := SetFire(src, 0, 100*time.Millisecond, 1.0, 0)
+	span := SetFire(src, 1*time.Second, 2*time.Second, 1.0, 0)
+	future := SetFire(src, 5*time.Second, 0, 1.0, 0)
+	SeekTo(2 * time.Second)
+	assert.Equal(t, 2*time.Second, GetNowAt().Round(time.Millisecond))
+	assert.Equal(t, []*fire.Fire{past}, mixDefault.mixDoneFires)
+	assert.Equal(t, []*fire.Fire{span}, mixDefault.mixLiveFires)
+	assert.Equal(t, []*fire.Fire{future}, mixDefault.mixReadyFires.Fires())
+	// seeking backwards makes fires already played able to play again
+	SeekTo(0)
+	assert.Equal(t, 0, len(mixDefault.mixDoneFires))
+	assert.Equal(t, 3, FireCount())
+}
+
+func TestFireCountReadyLive(t *testing.T) {
+	testMixSetup()
+	_, ok := NextFireAt()
+	assert.False(t, ok)
+	SetFireTone(441, 2*time.Second, 100*time.Millisecond, 1.0, 0)
+	SetFireTone(441, 10*time.Millisecond, 100*time.Millisecond, 1.0, 0)
+	assert.Equal(t, 2, FireCountReady())
+	assert.Equal(t, 0, FireCountLive())
+	next, ok := NextFireAt()
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Millisecond, next)
+	for n := 0; n < 882; n++ {
+		NextSample()
+	}
+	assert.Equal(t, 1, FireCountReady())
+	assert.Equal(t, 1, FireCountLive())
+	assert.Equal(t, 2, FireCount())
+	next, _ = NextFireAt()
+	assert.Equal(t, 2*time.Second, next)
+	SeekTo(3 * time.Second)
+	assert.Equal(t, 0, FireCount())
+	_, ok = NextFireAt()
+	assert.False(t, ok)
+}
+
+func TestWaitDone(t *testing.T) {
+	testMixSetup()
+	assert.Nil(t, WaitDone(context.Background())) // no fires
+	SetCycleDuration(10 * time.Millisecond)
+	SetFireTone(441, 0, 50*time.Millisecond, 1.0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, WaitDone(ctx)) // nothing is pulling the mix
+	done := make(chan error)
+	go func() {
+		done <- WaitDone(context.Background())
+	}()
+	Render(100 * time.Millisecond)
+	assert.Nil(t, <-done)
+	assert.Equal(t, 0, FireCount())
+}
+
+func TestScheduleEnd(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, time.Duration(0), ScheduleEnd())
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetFire(src, 0, 0, 1.0, 0) // to its natural end
+	assert.Equal(t, 100*time.Millisecond, ScheduleEnd())
+	SetFire(src, 200*time.Millisecond, 50*time.Millisecond, 1.0, 0)
+	assert.Equal(t, 250*time.Millisecond, ScheduleEnd())
+	SetFireLoop(src, 0, 100*time.Millisecond, 3, 0, 1.0, 0)
+	SetFireLoop(src, 0, 100*time.Millisecond, -1, 0, 1.0, 0) // never ends
+	assert.Equal(t, 300*time.Millisecond, ScheduleEnd())
+	testMultiSource()
+	kick := SetFire("kick", 400*time.Millisecond, 0, 0.9, 0)
+	assert.Equal(t, 450*time.Millisecond, ScheduleEnd()) // the longest variant
+	SetFireAfter(kick, 100*time.Millisecond, src, 0, 1.0, 0)
+	assert.Equal(t, 650*time.Millisecond, ScheduleEnd())
+	out, err := Render(ScheduleEnd())
+	assert.Nil(t, err)
+	assert.NotEqual(t, float64(0), out[len(out)-10])
+}
+
+func TestFires(t *testing.T) {
+	testMixSetup()
+	later := SetFireTone(441, 2*time.Second, 100*time.Millisecond, 1.0, 0)
+	sooner := SetFireTone(441, 0, 100*time.Millisecond, 1.0, 0)
+	for n := 0; n < 441; n++ {
+		NextSample()
+	}
+	fires := Fires()
+	assert.Equal(t, []*fire.Fire{sooner, later}, fires)
+	assert.Equal(t, fire.StatePlay, fires[0].State())
+	assert.Equal(t, mixDefault.mixDurOf(4410-440), fires[0].Remaining()) // as of the last sample mixed
+	assert.Equal(t, 2*time.Second, fires[1].BeginAt())
+	assert.Equal(t, 100*time.Millisecond, fires[1].Sustain())
+	fires[0] = nil // a copy, not the live slice
+	assert.Equal(t, sooner, Fires()[0])
+}
+
+func TestSetFireRate(t *testing.T) {
+	testMixSetup()
+	src := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	SetReplayLimit(-1) // to see which is done
+	normal := SetFire(src, 0, 0, 1.0, 0)
+	double := SetFireRate(src, 0, 0, 1.0, 0, 2.0)
+	assert.Equal(t, float64(2), double.Rate)
+	length := source.GetLength(src)
+	SeekTo(mixDefault.mixDurOf(length/2 + 10))
+	assert.Equal(t, []*fire.Fire{double}, mixDefault.mixDoneFires)
+	assert.Equal(t, []*fire.Fire{normal}, mixDefault.mixLiveFires)
+}
+
+func TestSetFireRegion(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetFire(src, 0, 0, 1.0, 0)
+	whole, _ := Render(100 * time.Millisecond)
+	testMixSetup()
+	f := SetFireRegion(src, 0, 0, 50*time.Millisecond, 20*time.Millisecond, 1.0, 0)
+	assert.Equal(t, 20*time.Millisecond, f.Sustain())
+	region, _ := Render(100 * time.Millisecond)
+	for n := 0; n < 883; n++ { // from the first sample of the region
+		assert.Equal(t, whole[2205+n], region[n])
+	}
+	for n := 883; n < len(region); n++ {
+		assert.Equal(t, float64(0), region[n])
+	}
+	assert.Equal(t, fire.StateDone, f.State())
+}
+
+func TestSetFireRegion_PastEnd(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	f := SetFireRegion(src, 0, time.Second, 80*time.Millisecond, time.Second, 1.0, 0)
+	assert.Equal(t, 20*time.Millisecond, f.Sustain()) // ends early, at the end of the source
+	beyond := SetFireRegion(src, 0, 0, 200*time.Millisecond, 0, 1.0, 0)
+	assert.Equal(t, time.Duration(0), beyond.Sustain())
+	out, _ := Render(200 * time.Millisecond)
+	for n := 883; n < len(out); n++ {
+		assert.Equal(t, float64(0), out[n])
+	}
+	assert.Equal(t, fire.StateDone, f.State())
+	assert.Equal(t, fire.StateDone, beyond.State())
+}
+
+func TestSetFire_TruncateRelease(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSquare, 441, 100*time.Millisecond)
+	f := SetFire(src, 0, 20*time.Millisecond, 1.0, 0)
+	out, _ := Render(50 * time.Millisecond)
+	assert.Equal(t, float64(0), f.FadeAt(882)) // released to silence at the end of the sustain, which cuts off the source
+	assert.InDelta(t, 0, out[882], 0.05)
+	assert.Less(t, math.Abs(out[882]), math.Abs(out[800]))
+	whole := SetFire(src, 0, 100*time.Millisecond, 1.0, 0)
+	assert.Equal(t, float64(1), whole.FadeAt(4409)) // plays to its natural end
+}
+
+func TestSetFireSustainLoop(t *testing.T) {
+	testMixSetup()
+	f := SetFireSustainLoop("../../bind/wav/testdata/SustainLoop.wav", 0, 300*time.Millisecond, 1.0, 0)
+	assert.Equal(t, spec.Tz(1000), f.SustainLoopBeginTz)
+	assert.Equal(t, spec.Tz(2000), f.SustainLoopEndTz)
+	out, _ := Render(400 * time.Millisecond)
+	for n := 3000; n < 13000; n++ { // well past the 100ms source, it repeats the 1000 samples of the loop
+		assert.Equal(t, out[n-1000], out[n])
+	}
+	assert.True(t, math.Abs(out[12025]) > 0.3)
+	for n := 13231; n < len(out); n++ { // released at the end of the sustain
+		assert.Equal(t, float64(0), out[n])
+	}
+	assert.Equal(t, fire.StateDone, f.State())
+	plain := SetFireSustainLoop("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, time.Second, 1.0, 0)
+	assert.Equal(t, spec.Tz(0), plain.SustainLoopEndTz) // without a loop, it plays like SetFire
+}
+
+func TestSetFireLoop(t *testing.T) {
+	testMixSetup()
+	loop := SetFireLoop("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 100*time.Millisecond, -1, 50*time.Millisecond, 1.0, 0)
+	assert.Equal(t, 1, FireCount())
+	SeekTo(10 * time.Second)
+	assert.Equal(t, []*fire.Fire{loop}, mixDefault.mixLiveFires)
+	loop.Cancel()
+	mixDefault.mixCycle()
+	assert.Equal(t, 0, FireCount())
+	assert.Equal(t, 0, len(mixDefault.mixDoneFires))
+}
+
+func TestSetFireLoopXFade(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	loop := SetFireLoopXFade(src, 0, 20*time.Millisecond, 1.0, 0)
+	assert.True(t, loop.LoopsForever())
+	assert.Nil(t, SetFireAfter(loop, 0, src, 0, 1.0, 0))
+	out, err := Render(time.Second)
+	assert.Nil(t, err)
+	for n := 882; n < 3528; n++ { // each iteration begins 80ms after the one before, and after its 20ms crossfade, plays alone
+		assert.Equal(t, out[n], out[3528*5+n])
+	}
+	for n := 3528; n < 3528+882; n++ { // the tail of the previous iteration crossfades into the head of the next
+		assert.Equal(t, out[n], out[3528*5+n])
+	}
+	peak := 0.0
+	for n := 3528; n < 3528+882; n++ {
+		peak = math.Max(peak, math.Abs(out[n]))
+	}
+	assert.True(t, peak > 0.3, "silent crossfade")
+	loop.Cancel()
+	out, _ = Render(10 * time.Millisecond)
+	assert.Equal(t, float64(0), out[1])
+}
+
+func TestSetFires(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	f := SetFire(src, 100*time.Millisecond, 50*time.Millisecond, 0.5, 0)
+	one, err := Render(time.Second)
+	assert.Nil(t, err)
+	f.Cancel()
+	testMixSetup()
+	fires, errs := SetFires([]FireSpec{
+		{Source: src, Begin: 100 * time.Millisecond, Sustain: 50 * time.Millisecond, Volume: 0.5},
+		{Source: "nonexistent.wav", Begin: 200 * time.Millisecond, Volume: 1}, // fails without aborting the batch
+		{Source: src, Begin: time.Hour, Volume: 1},
+	})
+	assert.Equal(t, 3, len(fires))
+	assert.Equal(t, 3, len(errs))
+	assert.NotNil(t, fires[0])
+	assert.Nil(t, errs[0])
+	assert.Nil(t, fires[1])
+	assert.NotNil(t, errs[1])
+	assert.Equal(t, spec.Tz(44100*3600), fires[2].BeginTz)
+	assert.Equal(t, 2, FireCount())
+	out, err := Render(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, one, out)
+	fires, errs = SetFires([]FireSpec{{Source: src, Begin: 2 * time.Hour, Volume: 1}})
+	assert.Nil(t, errs)
+	assert.Equal(t, 1, len(fires))
+}
+
+func TestRegisterSourceProvider(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetFire(src, 0, 0, 1.0, 0)
+	tone, err := Render(200 * time.Millisecond)
+	assert.Nil(t, err)
+	testMixSetup()
+	SetSoundsPath("/sounds/") // resolved before the sounds path
+	defer SetSoundsPath("")
+	assert.Nil(t, source.Prepare(src))
+	assert.Nil(t, RegisterSourceProvider("sine", source.Get(src))) // a source is a provider too
+	defer RegisterSourceProvider("sine", nil)
+	SetFire("sine", 0, 0, 1.0, 0)
+	out, err := Render(200 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, tone, out)
+	stats := Stats().Providers["sine"]
+	assert.Equal(t, int64(4410), stats.Calls) // one per frame, until the end of the source
+	assert.True(t, stats.Time > 0)
+}
+
+func TestSetFireLoopXFade_Clamp(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetFireLoopXFade(src, 0, 80*time.Millisecond, 1.0, 0) // clamped to 50ms, half the source
+	out, _ := Render(time.Second)
+	for n := 2205; n < 4410; n++ {
+		assert.Equal(t, out[n], out[2205*8+n])
+	}
+}
+
+func TestClearFiresAfter(t *testing.T) {
+	testMixSetup()
+	src := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	ringing := SetFire(src, 1500*time.Millisecond, 0, 1.0, 0)
+	soon := SetFire(src, 2100*time.Millisecond, 0, 1.0, 0)
+	SetFire(src, 5*time.Second, 0, 1.0, 0)
+	SetFire(src, 6*time.Second, 0, 1.0, 0)
+	SeekTo(2 * time.Second)
+	mixDefault.mixCycle()
+	assert.Equal(t, []*fire.Fire{ringing, soon}, mixDefault.mixLiveFires)
+	assert.Equal(t, 3, ClearFiresAfter(2*time.Second))
+	assert.Equal(t, 1, FireCount())
+	assert.False(t, ringing.IsCanceled())
+	assert.True(t, soon.IsCanceled())
+	assert.Equal(t, 0, ClearFiresAfter(2*time.Second))
+}
+
+func TestClearFiresBySource(t *testing.T) {
+	testMixSetup()
+	kick := "../source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	hihat := "../source/testdata/Float32bitLittleEndian48000HzEstéreo.wav"
+	SetFire(kick, 1*time.Second, 0, 1.0, 0)
+	SetFire(hihat, 1*time.Second, 0, 1.0, 0)
+	SetFire(hihat, 2*time.Second, 0, 1.0, 0)
+	assert.Equal(t, 2, ClearFiresBySource(hihat))
+	assert.Equal(t, 1, FireCount())
+	assert.Equal(t, kick, mixDefault.mixReadyFires.Peek().Source)
+	assert.Equal(t, 0, ClearFiresBySource(hihat))
+}
+
+func TestSetMasterVolume(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, float64(1), GetMasterVolume())
+	SetMasterVolume(1.5)
+	assert.Equal(t, float64(1), GetMasterVolume())
+	SetMasterVolume(-1)
+	assert.Equal(t, float64(0), GetMasterVolume())
+	SetMasterVolume(0.5)
+	assert.Equal(t, float64(0.5), GetMasterVolume())
+	for n := 0; n < 441; n++ { // 10ms at 44100Hz, longer than the ramp
+		NextSample()
+	}
+	assert.Equal(t, float64(0.5), mixDefault.masterGain)
+}
+
+func TestMute(t *testing.T) {
+	testMixSetup()
+	Mute()
+	assert.True(t, IsMuted())
+	for n := 0; n < 441; n++ {
+		NextSample()
+	}
+	assert.Equal(t, float64(0), mixDefault.masterGain)
+	assert.Equal(t, mixDefault.mixDurOf(441), GetNowAt()) // clock keeps running while muted
+	Unmute()
+	assert.False(t, IsMuted())
+	NextSample()
+	assert.True(t, mixDefault.masterGain > 0)
+}
+
+func TestEnvelopePoint(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, fire.EnvelopePoint{OffsetTz: 22050, Value: 0.5}, EnvelopePoint(500*time.Millisecond, 0.5))
 }
 
 func TestSetSoundsPath(t *testing.T) {
@@ -66,7 +571,114 @@ func TestNextOut(t *testing.T) {
 }
 
 func TestTeardown(t *testing.T) {
-	// TODO: Test Mixer Teardown
+	testMixSetup()
+	f := SetFireTone(441, 0, time.Second, 1, 0)
+	Render(10 * time.Millisecond)
+	Teardown()
+	assert.Equal(t, fire.DoneCanceled, f.DoneReason())
+	assert.Equal(t, 0, FireCount())
+	assert.Equal(t, []sample.Value{0}, NextSample()) // the output buffer is kept, for a late output callback
+	SetMasterVolume(0.5)
+	Teardown() // again, does nothing
+	assert.Equal(t, 0.5, GetMasterVolume())
+	SetFireTone(441, 0, time.Second, 1, 0)
+	Teardown() // after another fire is set
+	assert.Equal(t, float64(1), GetMasterVolume())
+}
+
+func TestTeardown_NoNewFires(t *testing.T) {
+	testMixSetup()
+	SetFireTone(441, 0, time.Second, 1, 0)
+	for i := 0; i < 10; i++ {
+		NextSample() // past the zero crossing at the start of the sine
+	}
+	mixDefault.teardown = teardownDraining
+	f := SetFireTone(441, 0, time.Second, 1, 0)
+	assert.True(t, f.IsCanceled())
+	assert.Equal(t, 1, FireCount())
+	assert.NotEqual(t, sample.Value(0), NextSample()[0]) // the live fire plays on while draining
+	mixDefault.teardown = teardownCutting
+	assert.Equal(t, sample.Value(0), NextSample()[0]) // the output callback returns silence
+	mixDefault.teardown = teardownNone
+	assert.NotEqual(t, sample.Value(0), NextSample()[0])
+}
+
+func TestTeardownWith(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    TeardownOptions
+		fireDur time.Duration
+		atLeast spec.Tz
+		atMost  spec.Tz
+	}{
+		{"cut", TeardownOptions{}, 100 * time.Millisecond, 882, 882},
+		{"drain", TeardownOptions{Drain: true}, 100 * time.Millisecond, 4410, 4410 + 441},
+		{"timeout", TeardownOptions{Drain: true, Timeout: 50 * time.Millisecond}, 10 * time.Second, 882 + 2205, 882 + 2205},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testMixSetup()
+			defer testMixOutput(opt.OutputWAV)()
+			m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			var out bytes.Buffer
+			m.OutputStart(0, &out)
+			header := out.Len()
+			m.StartAt(time.Now())
+			m.SetFireTone(441, 0, tc.fireDur, 1, 0)
+			later := m.SetFireTone(441, time.Second, 0, 1, 0) // not yet begun, so never drained
+			m.OutputContinueTo(20 * time.Millisecond)
+			assert.Nil(t, m.TeardownWith(tc.opts))
+			frames := spec.Tz((out.Len() - header) / 4)
+			assert.True(t, frames >= tc.atLeast && frames <= tc.atMost, "%d frames", frames)
+			assert.True(t, later.IsCanceled())
+			assert.Equal(t, 0, m.FireCount())
+			assert.Nil(t, m.TeardownWith(tc.opts)) // again, does nothing
+			assert.Equal(t, header+int(frames)*4, out.Len())
+		})
+	}
+}
+
+func TestTeardownWith_PatchesWAV(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	outfile, err := ioutil.TempFile("", "mix-teardown")
+	assert.Nil(t, err)
+	defer os.Remove(outfile.Name())
+	m.OutputStart(0, outfile)
+	m.StartAt(time.Now())
+	m.SetFireTone(441, 0, 50*time.Millisecond, 1, 0)
+	m.OutputContinueTo(10 * time.Millisecond)
+	assert.Nil(t, m.TeardownWith(TeardownOptions{Drain: true}))
+	outfile.Close()
+	samples, _, err := wav.Load(outfile.Name())
+	assert.Nil(t, err)
+	assert.True(t, len(samples) >= 2205, "%d samples", len(samples))
+}
+
+func TestTeardownWith_OutputInFlight(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	var out bytes.Buffer
+	m.OutputStart(0, &out)
+	m.StartAt(time.Now())
+	m.SetFireTone(441, 0, 0, 1, 0)
+	done := make(chan struct{})
+	go func() {
+		m.OutputContinueTo(10 * time.Second)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	m.Teardown() // waits for the output in flight, which is silent from then on
+	select {
+	case <-done:
+	default:
+		t.Error("output still in flight after Teardown")
+	}
+	assert.Equal(t, 0, m.FireCount())
 }
 
 func TestNextSample(t *testing.T) {
@@ -77,6 +689,23 @@ func TestOutputStart(t *testing.T) {
 	// TODO: Test
 }
 
+func TestOutputStartAuto(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	_, err := bind.Configure(*Spec())
+	assert.Nil(t, err)
+	bind.SetOutputCallback(NextSample)
+	SetFire(source.ToneKey(source.WaveSine, 441, 100*time.Millisecond), 50*time.Millisecond, 0, 1.0, 0) // to its natural end
+	var out bytes.Buffer
+	end := OutputStartAuto(&out, 20*time.Millisecond)
+	assert.Equal(t, 170*time.Millisecond, end)
+	StartAt(time.Now())
+	OutputContinueTo(end)
+	samples, _, err := wav.LoadBytes(out.Bytes()) // the header was written once, with the exact length
+	assert.Nil(t, err)
+	assert.Equal(t, 7497, len(samples))
+}
+
 func TestOutputContinueTo(t *testing.T) {
 	// TODO: Test
 }
@@ -111,7 +740,7 @@ func TestMixSetSpec(t *testing.T) {
 }
 
 func TestSetCycleDuration(t *testing.T) {
-	masterFreq = 0 // simulates never having set a mix frequency
+	mixDefault.masterFreq = 0 // simulates never having set a mix frequency
 	defer func() {
 		msg := recover()
 		assert.IsType(t, "", msg)
@@ -120,6 +749,13 @@ func TestSetCycleDuration(t *testing.T) {
 	SetCycleDuration(5 * time.Second)
 }
 
+func TestSetResampleQuality(t *testing.T) {
+	testMixSetup()
+	SetResampleQuality(source.ResampleHigh)
+	defer SetResampleQuality(source.ResampleMedium)
+	assert.Equal(t, source.ResampleHigh, source.GetResampleQuality())
+}
+
 func TestGetSource(t *testing.T) {
 	// TODO: Test Mixer getSource
 }
@@ -129,6 +765,173 @@ func TestMixCycle(t *testing.T) {
 	// TODO: Test garbage collection of unused fires
 }
 
+//
+// Private
+//
+
+// the cost of a mix cycle must not depend on the total # of fires scheduled, e.g. an entire song up front
+func BenchmarkMixCycle(b *testing.B) {
+	for _, scheduled := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("%d", scheduled), func(b *testing.B) {
+			testMixSetup()
+			src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+			for n := 0; n < scheduled; n++ {
+				SetFire(src, time.Hour+time.Duration(n)*time.Millisecond, 0, 1.0, 0)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				mixDefault.mixCycle()
+			}
+		})
+	}
+}
+
+// scheduling a whole song at once, of 50k fires, by SetFire of each, or by SetFires of the batch
+func BenchmarkSetFire_50k(b *testing.B) {
+	batch := testMixBatch(50000)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		testMixSetup()
+		b.StartTimer()
+		for _, entry := range batch {
+			SetFire(entry.Source, entry.Begin, entry.Sustain, entry.Volume, entry.Pan)
+		}
+	}
+}
+
+func BenchmarkSetFires_50k(b *testing.B) {
+	batch := testMixBatch(50000)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		testMixSetup()
+		b.StartTimer()
+		SetFires(batch)
+	}
+}
+
+func BenchmarkNextSample(b *testing.B) {
+	testMixSteadyState()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		NextSample()
+	}
+}
+
+// the output of an F32 driver, pulled by NextSample and converted, or filled directly by NextBlock
+func BenchmarkNextSample_F32(b *testing.B) {
+	testMixSteadyState()
+	out := make([]float32, 2*512)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < len(out); i += 2 {
+			smp := NextSample()
+			out[i], out[i+1] = float32(smp[0]), float32(smp[1])
+		}
+	}
+}
+
+func BenchmarkNextBlock_F32(b *testing.B) {
+	testMixSteadyState()
+	out := make([]float32, 2*512)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		NextBlock(out, 512)
+	}
+}
+
+func TestNextBlock(t *testing.T) {
+	testMixSteadyState()
+	bySample := make([]float32, 2*4096)
+	for i := 0; i < len(bySample); i += 2 {
+		smp := NextSample()
+		bySample[i], bySample[i+1] = float32(smp[0]), float32(smp[1])
+	}
+	testMixSteadyState()
+	byBlock := make([]float32, 2*4096)
+	for i := 0; i < len(byBlock); i += 2 * 512 {
+		NextBlock(byBlock[i:], 512)
+	}
+	assert.Equal(t, bySample, byBlock)
+	assert.NotEqual(t, make([]float32, len(byBlock)), byBlock)
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		NextBlock(byBlock, 512)
+	}))
+	Pause()
+	NextBlock(byBlock, 512)
+	assert.Equal(t, make([]float32, 2*512), byBlock[:2*512]) // silent while paused
+	Resume()
+}
+
+// the mix loop must make no allocations in its steady state, else the garbage collector may pause it long enough to drop out
+func TestNextSample_NoAllocs(t *testing.T) {
+	testMixSteadyState()
+	assert.Equal(t, 1, FireCountLive())
+	assert.Equal(t, float64(0), testing.AllocsPerRun(1000, func() {
+		NextSample()
+	}))
+	assert.Equal(t, float64(0), testing.AllocsPerRun(10, func() {
+		mixDefault.mixCycle()
+	}))
+}
+
+// testMixBatch of fires of a few sources, each a millisecond after the one before
+func testMixBatch(size int) []FireSpec {
+	batch := make([]FireSpec, size)
+	for n := range batch {
+		batch[n] = FireSpec{
+			Source: source.ToneKey(source.WaveSine, float64(441*(1+n%4)), 100*time.Millisecond),
+			Begin:  time.Duration(n) * time.Millisecond,
+			Volume: 1.0,
+		}
+	}
+	return batch
+}
+
+// testMixSteadyState of a stereo mix, with a looping fire at a rate on a bus, a master effect, and many fires scheduled later
+func testMixSteadyState() {
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 2,
+	})
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	loop := SetFireOnBus(NewBus("drums"), src, 0, 50*time.Millisecond, 0.5, -0.5)
+	loop.SetLoop(mixDefault.mixTzOf(100*time.Millisecond), -1)
+	loop.SetRate(1.5)
+	SetFireRate(src, 0, 0, 0.5, 0.5, 0.75)
+	AddMasterEffect(effect.NewLowPass(5000, 0.7))
+	for n := 0; n < 1000; n++ {
+		SetFire(src, time.Hour+time.Duration(n)*time.Millisecond, 0, 1.0, 0)
+	}
+	for n := 0; n < 3*44100; n++ { // past a few mix cycles, for the fires to go live and every buffer to grow
+		NextSample()
+	}
+}
+
+// testMixOutput selected, returning a func to restore the null output
+func testMixOutput(output opt.Output) func() {
+	bind.UseOutput(output)
+	return func() {
+		bind.UseOutput(opt.OutputNull)
+	}
+}
+
+func testMixSetup() {
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 1,
+	})
+}
+
 // TODO: test mix.GetSpec()
 
 // TODO: test mix.Debug(true) and mix.Debug(false)
@@ -0,0 +1,262 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/effect"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/level"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// Mixer of sources into an output audio stream, with its own schedule, source cache, clock and output. The package functions
+// use a default mixer, which is bound to the audio interface; New makes another, independent of the default and of each other,
+// e.g. to render offline alongside live playback. Teardown of one mixer does not affect any other.
+type Mixer struct {
+	/* accessed atomically, first so that they are 64-bit aligned */
+	mixCountReady        int64
+	mixCountLive         int64
+	mixNextFireAt        int64 // a time.Duration, or -1 if there is no ready fire
+	masterClipCount      uint64
+	mixStatCycles        int64 // durations are in nanoseconds
+	mixStatOverruns      int64
+	mixStatUnderrunsBase int64 // of the source stream underruns, when the stats were reset
+	mixStatWork          int64
+	mixStatMaxWork       int64
+	mixStatMaxLiveFires  int64
+	mixStatGCs           int64
+	mixStatGCPause       int64
+	mixStatLastWork      int64
+	mixStatLastBudget    int64
+	mixStatLastLiveFires int64
+	mixStatLastGCs       int64
+	mixStatLastGCPause   int64
+	mixStatClipped       int64
+	mixStatLastClipped   int64
+	mixStatTeeDropped    int64
+	mixStatOutUnderruns  int64        // frames of the output that the lookahead did not have in time
+	mixStatOutDropped    int64        // blocks of the output that its consumer did not take in time, see SetOutputPipe
+	mixNowFrames         int64        // the tz heard, published by the mix loop, or the output that takes it from the lookahead
+	mixFreqBits          uint64       // the masterFreq
+	mixSchedFreqBits     uint64       // the masterFreq divided by the mixTimeScale
+	mixStartAt           atomic.Value // the startAtTime
+	// outputMutex is held while the output is in flight, i.e. pulling and writing samples, or closing; never within the mixMutex
+	outputMutex   sync.Mutex
+	outputStarted bool
+	outputWriter  *mixOutWriter // of the output started, or nil
+	// mixLookaheadMutex guards the lookahead, which the output callback reads without the mixMutex; never within the mixMutex
+	mixLookaheadMutex sync.Mutex
+	mixLookahead      *mixLookahead // or nil to mix in the output callback, see SetLookahead
+	// mixHeardMutex guards the position heard, of the last frame the output callback took from the lookahead; it is held alone
+	mixHeardMutex sync.Mutex
+	mixHeard      mixHeard
+	// mixMutex guards all of the mixer state below, so that fires can be set from any goroutine while the mix loop is running
+	mixMutex         sync.Mutex
+	cache            *source.Cache
+	output           *bind.Output // or nil for the output bound to the audio interface, of the default mixer
+	teardown         teardownEnum
+	outputToDur      time.Duration
+	startAtTime      time.Time
+	pausedAtTime     time.Time
+	transport        transportEnum
+	nowTz            spec.Tz
+	nextCycleTz      spec.Tz
+	masterCycleDurTz spec.Tz
+	mixSourcePrefix  string
+	mixReadyFires    *fire.Queue
+	mixKeepSource    map[string]bool // reused by each mix cycle
+	mixFireBuffer    []sample.Value  // these buffers of the master channels are reused by each sample
+	mixFireScratch   []sample.Value
+	mixFireTail      []sample.Value // of a crossfade loop
+	mixSumBuffer     []sample.Value
+	mixOutBuffer     []sample.Value
+	mixLiveFires     []*fire.Fire
+	mixDoneFires     []*fire.Fire // kept for replay, see SetReplayLimit
+	mixReplayLimit   int
+	mixReplayHeld    int // while > 0, every done fire is kept, see mixHoldReplay
+	masterSpec       *spec.AudioSpec
+	mixRequestedSpec spec.AudioSpec // of Configure, which the output may not obtain
+	masterFreq       float64
+	masterVolume     float64
+	masterGain       float64 // ramps toward the master volume (or zero if muted) to avoid clicks
+	masterGainStep   float64
+	masterMuted      bool
+	masterMeter      *level.Meter
+	masterEffects    []effect.Effect
+	effectBuffer     []float64 // reused by the mix loop
+	mixClock         Clock     // or nil for the real clock
+	mixSubSample     bool      // precision of the begin of fires
+	mixPanLaw        PanLaw
+	mixChokeGroups   map[string]string // of each source key
+	mixChokeFade     time.Duration
+	mixChains        map[*fire.Fire][]mixChained // of each previous fire, see SetFireAfter
+	mixChainWaiting  map[*fire.Fire]*fire.Fire   // previous fire of each chained fire whose begin is not yet known
+	mixOutputLatency time.Duration
+	mixAheadCycles   int // of the lookahead, see SetLookahead
+	mixFireEvents    chan FireEvent
+	mixOutputMeta    *Metadata     // written in the header of a WAV, see SetOutputMetadata
+	mixIdle          chan struct{} // closed once there are no more fires, or nil while there are none
+	/* algorithm */
+	mixAlgorithm     MixAlgorithm
+	mixParams        MixParams
+	limiterDelay     [][]sample.Value // ring of frames, lookahead long
+	limiterAt        int
+	limiterGain      float64
+	limiterFreq      float64
+	limiterFrames    int
+	headroomGain     float64 // of the master, before compression
+	mixHeadroom      float64 // in dB
+	mixClipMode      sample.ClipMode
+	mixNormalizeGain float64 // of the output of the mix algorithm, while rendering normalized
+	mixDitherMode    sample.DitherMode
+	mixDither        *sample.Dither // of the master channels, or nil
+	/* buses */
+	mixMasterBus *Bus
+	mixBuses     map[string]*Bus
+	mixBusList   []*Bus
+	mixDuckers   []*mixDucker
+	/* capture */
+	mixCapturing    bool
+	mixCaptureStart time.Duration // mixer time of StartCapture
+	mixCaptured     []CapturedFire
+	/* parallel */
+	mixParallelFires int // # of live fires above which they are mixed in blocks
+	mixWorkers       int // 0 is one per CPU
+	mixBlockBeginTz  spec.Tz
+	mixBlockEndTz    spec.Tz // the block has the samples from its beginning until (not including) its end
+	mixChunks        []*mixChunk
+	mixChunkCount    int // of the current block
+	mixChunkJobs     chan *mixChunk
+	mixChunkWorkers  int            // # running, that receive from the jobs channel
+	mixChunkWait     sync.WaitGroup // of the chunks of the current block
+	mixChunkRunning  sync.WaitGroup // of the workers
+	/* schedule */
+	mixHorizonTz       spec.Tz // begin of the latest scheduled fire
+	mixLowWaterTz      spec.Tz
+	mixLowWaterFn      func(horizon time.Duration)
+	mixLowWaterPending bool    // from the signal until the callback returns
+	mixLowWaterLastTz  spec.Tz // horizon when the callback was last signaled
+	mixLowWaterRetryTz spec.Tz // after which to signal the callback again, even if the horizon has not moved
+	mixLowWaterSignal  chan struct{}
+	mixLowWaterStop    chan struct{} // closed to stop calling the callback, or nil if there is none
+	mixLowWaterDone    chan struct{} // closed once the loop that calls the callback has returned
+	/* ticks */
+	mixTickFn      func(tick int64, at time.Duration)
+	mixTickPPQN    int
+	mixTickNext    int64   // the next tick that is not yet due
+	mixTickNextTz  spec.Tz // the frame it begins on
+	mixTickPending []mixTick
+	mixTickSignal  chan struct{}
+	mixTickStop    chan struct{} // closed to stop calling the callback, or nil if there is none
+	mixTickDone    chan struct{} // closed once the loop that calls the callback has returned
+	/* watched sources */
+	mixWatchStop chan struct{} // closed to stop watching, or nil if not watching
+	/* failure */
+	mixErr   error // recovered from a panic of the mix loop, which is silent until the teardown, see OnError
+	mixErrFn func(err error, stack []byte)
+	/* output failure */
+	mixOutputPipe bool // see SetOutputPipe
+	mixOutErrFn   func(err error)
+	/* stats of the cycle in progress */
+	mixStatCycleBeginTz   spec.Tz
+	mixStatCycleWork      time.Duration
+	mixStatCycleLiveFires int
+	mixStatCycleClipped   int64
+	mixStatGC             debug.GCStats // reused by each cycle, to avoid allocation
+	mixOverrunFn          func(stats LoopStats)
+	mixOverrunSignal      chan struct{}
+	mixOverrunStop        chan struct{} // closed to stop calling the warning, or nil if there is none
+	mixOverrunDone        chan struct{} // closed once the loop that calls the warning has returned
+	/* multi-sample sources */
+	multiMutex      sync.RWMutex // guards the map, which is also read without the mixMutex, e.g. to prepare a source
+	mixMultiSources map[string]*multiSource
+	mixRandom       *rand.Rand
+	mixRandomSeed   int64
+	/* humanize */
+	mixHumanize       HumanizeSpec
+	mixHumanizeRandom *rand.Rand
+	/* tempo */
+	mixTempoMap     []mixTempo // sorted by step, always beginning at step zero
+	mixStepsPerBeat int
+	mixStepOffset   time.Duration
+	mixSwing        float64
+	mixGroove       []time.Duration
+	mixSustainTempo bool
+	mixSustains     map[*fire.Fire]mixSustain
+	mixTimeScale    float64 // of the schedule, see SetTimeScale
+	/* pre-roll */
+	mixPreRollBars   int
+	mixPreRollDur    time.Duration
+	mixPreRollClick  string // key of the click source, or empty for a silent pre-roll
+	mixPreRollOutput bool
+	mixPreRollTz     spec.Tz // of the pre-roll that is playing
+	mixPreRollLeftTz spec.Tz // until the pre-roll ends, or 0 if it is not playing
+	mixPreRollClicks []mixClick
+	mixTees          []*Tee // of the output, see TeeOutput
+}
+
+// New mixer, configured with a spec, independent of the default mixer and of any other, with a source cache of its own.
+// Its output is offline, in the format of the output selected for the process, e.g. a WAV writer via OutputStart, or pulled
+// by Render, a Reader or AdvanceBy; only the default mixer is bound to an audio interface. Returns an error if the spec is invalid.
+func New(s spec.AudioSpec) (*Mixer, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	m := newMixer(source.NewCache())
+	m.output = bind.NewOutput(m.NextSample)
+	m.Configure(s)
+	return m, nil
+}
+
+//
+// Private
+//
+
+// mixDefault is the mixer of the package functions, bound to the audio interface, whose sources are in the default cache
+var mixDefault = newMixer(source.DefaultCache())
+
+func newMixer(cache *source.Cache) *Mixer {
+	m := &Mixer{
+		mixNextFireAt:     -1,
+		cache:             cache,
+		startAtTime:       time.Now().Add(0xFFFF * time.Hour), // this gets reset by Start() or StartAt()
+		mixReadyFires:     fire.NewQueue(),
+		mixKeepSource:     make(map[string]bool),
+		masterVolume:      1,
+		masterGain:        1,
+		masterMeter:       &level.Meter{},
+		mixAlgorithm:      MixLogarithmic,
+		mixParams:         DefaultMixParams(),
+		limiterGain:       1,
+		headroomGain:      1,
+		mixNormalizeGain:  1,
+		mixParallelFires:  16,
+		mixLowWaterSignal: make(chan struct{}, 1),
+		mixOverrunSignal:  make(chan struct{}, 1),
+		mixTickSignal:     make(chan struct{}, 1),
+		mixTempoMap:       []mixTempo{{0, DefaultBPM, 0}},
+		mixStepsPerBeat:   DefaultStepsPerBeat,
+		mixMultiSources:   make(map[string]*multiSource),
+		mixRandomSeed:     DefaultRandomSeed,
+		mixChokeGroups:    make(map[string]string),
+		mixChokeFade:      DefaultChokeFade,
+		mixTimeScale:      1,
+		mixChains:         make(map[*fire.Fire][]mixChained),
+		mixChainWaiting:   make(map[*fire.Fire]*fire.Fire),
+		mixSustains:       make(map[*fire.Fire]mixSustain),
+	}
+	m.mixResetPicks()
+	m.mixResetHumanize()
+	m.mixClearBuses()
+	m.mixPublishClock()
+	debug.ReadGCStats(&m.mixStatGC) // such that the first cycle counts only its own garbage collections
+	return m
+}
@@ -0,0 +1,136 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/effect"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestNew(t *testing.T) {
+	testMixSetup()
+	m, err := New(spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 2})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	assert.Equal(t, float64(22050), m.Spec().Freq)
+	assert.Equal(t, float64(44100), Spec().Freq)
+	tone := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	f := m.SetFire(tone, 20*time.Millisecond, 0, 1, 0)
+	assert.Equal(t, spec.Tz(441), f.BeginTz) // at its own frequency
+	assert.Equal(t, 20*time.Millisecond, f.BeginAt())
+	assert.Equal(t, 1, m.FireCount())
+	assert.Equal(t, 0, FireCount())
+	assert.Equal(t, spec.Tz(2205), m.cache.GetLength(tone))
+	_, err = New(spec.AudioSpec{Freq: 0, Format: spec.AudioF32, Channels: 2})
+	assert.NotNil(t, err)
+}
+
+func TestNew_Render(t *testing.T) {
+	testMixSetup()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	for _, mixer := range []*Mixer{mixDefault, m} {
+		mixer.SetFireTone(441, 100*time.Millisecond, 100*time.Millisecond, 1, 0)
+	}
+	SetFireTone(882, 0, 50*time.Millisecond, 1, 0) // only on the default mixer
+	out, err := m.Render(300 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), out[441]) // the default mixer neither advances nor mixes into it
+	assert.NotEqual(t, float64(0), out[4410+220])
+	assert.Equal(t, time.Duration(0), GetNowAt())
+	reference, err := Render(300 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotEqual(t, float64(0), reference[441])
+	assert.Equal(t, out[4410+220], reference[4410+220])
+}
+
+func TestNew_NaturalLength(t *testing.T) {
+	testMixSetup()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	tone := source.ToneKey(source.WaveSine, 431, 100*time.Millisecond) // of no other test
+	f := m.SetFire(tone, 50*time.Millisecond, 0, 1, 0)                 // plays the full source, loaded in the cache of this mixer only
+	assert.Nil(t, source.Get(tone))
+	sustain, err := f.EffectiveSustain()
+	assert.Nil(t, err)
+	assert.Equal(t, 100*time.Millisecond, sustain)
+	assert.Equal(t, 150*time.Millisecond, m.ScheduleEnd())
+	out, err := m.Render(200 * time.Millisecond)
+	assert.Nil(t, err)
+	sounding := 0
+	for _, v := range out {
+		if v != 0 {
+			sounding++
+		}
+	}
+	assert.True(t, sounding > 4000, "only %d of %d samples sound", sounding, len(out))
+	assert.Equal(t, float64(0), out[2204])
+	assert.Equal(t, float64(0), out[6615+10]) // ended with its source
+	auto, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer auto.Teardown()
+	auto.SetFire(tone, 50*time.Millisecond, 0, 1, 0)
+	var wav bytes.Buffer
+	assert.Equal(t, 150*time.Millisecond, auto.OutputStartAuto(&wav, 0))
+}
+
+func TestNew_Teardown(t *testing.T) {
+	testMixSetup()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	SetFireTone(441, time.Second, 0, 1, 0)
+	m.SetFireTone(441, time.Second, 0, 1, 0)
+	SetMasterVolume(0.5)
+	m.Teardown()
+	assert.Equal(t, 0, m.FireCount())
+	assert.Equal(t, 0, m.cache.Count())
+	assert.Equal(t, 1, FireCount())
+	assert.Equal(t, 0.5, GetMasterVolume())
+	testMixSetup()
+	_, err = m.SetFireErr(source.ToneKey(source.WaveSine, 441, time.Second), 0, 0, 1, 0) // reused after Teardown
+	assert.Nil(t, err)
+	assert.Equal(t, 0, FireCount())
+}
+
+func TestNew_Effect(t *testing.T) {
+	testMixSetup()
+	m, err := New(spec.AudioSpec{Freq: 8000, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	d := effect.NewDelay(10*time.Millisecond, 0, 1)
+	m.AddMasterEffect(d)
+	m.SetFireTone(441, 0, 20*time.Millisecond, 1, 0)
+	out, err := m.Render(50 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), out[79])
+	assert.NotEqual(t, float64(0), out[81]) // delayed by 80 samples at its own frequency, not the default
+	assert.Equal(t, float64(44100), effect.Freq())
+}
+
+func TestNew_OutputWAV(t *testing.T) {
+	testMixSetup()
+	bind.UseOutput(opt.OutputWAV)
+	defer bind.UseOutput(opt.OutputNull)
+	m, err := New(spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 2})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	var out bytes.Buffer
+	m.OutputStart(100*time.Millisecond, &out)
+	header := out.Len()
+	m.StartAt(time.Now())
+	m.OutputContinueTo(100 * time.Millisecond)
+	assert.Nil(t, m.OutputClose())
+	assert.Equal(t, 2205*2*4, out.Len()-header) // of 32-bit float stereo samples
+	assert.Equal(t, 100*time.Millisecond, m.GetNowAt())
+	assert.Equal(t, time.Duration(0), GetNowAt())
+}
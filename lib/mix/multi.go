@@ -0,0 +1,161 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// DefaultRandomSeed of the picks of multi-sample sources, until SetRandomSeed
+const DefaultRandomSeed = 1
+
+// Layer of a multi-sample source: variants of one sound, e.g. recorded at one dynamic, for the fires with a volume in its velocity
+// range. The variants play in turn, round-robin, to avoid machine-gunning, or at random, never the one picked last.
+type Layer struct {
+	Sources     []string `json:"sources"`     // resolved like SetFire
+	MinVelocity float64  `json:"minVelocity"` // of the volume of a fire, from 0 to 1, inclusive
+	MaxVelocity float64  `json:"maxVelocity"`
+	Random      bool     `json:"random,omitempty"`
+}
+
+// RegisterMultiSource under a name, which SetFire will resolve before the sounds path, to a variant of the layer whose velocity
+// range has the volume of the fire, or else of the nearest layer. The variant is picked as the fire goes live, in order of playback,
+// round-robin from a variant drawn from the random seed, or at random; so a render is reproducible, given the seed, see SetRandomSeed.
+// Registering a name again replaces it. Returns an error if there is no layer, a layer has no source, or its velocity range is invalid.
+func (m *Mixer) RegisterMultiSource(name string, layers []Layer) error {
+	if len(layers) == 0 {
+		return errors.New("Multi-sample source must have at least one layer")
+	}
+	multi := &multiSource{layers: make([]*multiLayer, len(layers))}
+	for i, l := range layers {
+		if len(l.Sources) == 0 {
+			return fmt.Errorf("Layer %d of multi-sample source %s has no source", i, name)
+		}
+		if l.MinVelocity < 0 || l.MaxVelocity > 1 || l.MinVelocity > l.MaxVelocity {
+			return fmt.Errorf("Layer %d of multi-sample source %s has an invalid velocity range %v to %v", i, name, l.MinVelocity, l.MaxVelocity)
+		}
+		keys := make([]string, len(l.Sources))
+		for j, src := range l.Sources {
+			keys[j] = m.mixSourceKey(src)
+		}
+		multi.layers[i] = &multiLayer{keys: keys, min: l.MinVelocity, max: l.MaxVelocity, random: l.Random, last: -1}
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.multiMutex.Lock()
+	defer m.multiMutex.Unlock()
+	m.mixMultiSources[name] = multi
+	return nil
+}
+
+// SetRandomSeed of the picks of multi-sample sources, of the humanize, and of the noise of the dither, which begin again from it, e.g. to
+// render reproducibly.
+func (m *Mixer) SetRandomSeed(seed int64) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixRandomSeed = seed
+	m.mixResetPicks()
+	m.mixResetHumanize()
+	m.mixResetDither()
+}
+
+//
+// Private
+//
+
+type multiSource struct {
+	layers []*multiLayer
+}
+
+// multiLayer of resolved source keys; its picks are made by the mix loop, with the mixMutex held
+type multiLayer struct {
+	keys     []string
+	min, max float64
+	random   bool
+	last     int // or -1 before the first pick
+}
+
+// layerOf a velocity, else the layer with the nearest velocity range
+func (s *multiSource) layerOf(velocity float64) (nearest *multiLayer) {
+	distance := math.Inf(1)
+	for _, l := range s.layers {
+		var d float64
+		if velocity < l.min {
+			d = l.min - velocity
+		} else if velocity > l.max {
+			d = velocity - l.max
+		}
+		if d < distance {
+			nearest, distance = l, d
+		}
+	}
+	return
+}
+
+// pick the next variant of the layer
+func (l *multiLayer) pick(random *rand.Rand) string {
+	n := len(l.keys)
+	switch {
+	case n == 1:
+		l.last = 0
+	case l.last < 0:
+		l.last = random.Intn(n)
+	case l.random:
+		l.last = (l.last + 1 + random.Intn(n-1)) % n // never the same twice in a row
+	default:
+		l.last = (l.last + 1) % n
+	}
+	return l.keys[l.last]
+}
+
+// mixMultiSource by name, or nil if it is not one
+func (m *Mixer) mixMultiSource(name string) *multiSource {
+	m.multiMutex.RLock()
+	defer m.multiMutex.RUnlock()
+	return m.mixMultiSources[name]
+}
+
+// mixEachVariant of a source, if it is a multi-sample source
+func (m *Mixer) mixEachVariant(src string, fn func(key string)) {
+	if multi := m.mixMultiSource(src); multi != nil {
+		for _, l := range multi.layers {
+			for _, key := range l.keys {
+				fn(key)
+			}
+		}
+	}
+}
+
+// mixKeepSources of a fire, for Prune and Trim: its source, or all of the variants of its multi-sample source
+func (m *Mixer) mixKeepSources(keep map[string]bool, src string) {
+	keep[src] = true
+	m.mixEachVariant(src, func(key string) {
+		keep[key] = true
+	})
+}
+
+// mixResolve the source of a fire as it goes live, if it is a multi-sample source and not yet resolved, by its volume;
+// the caller must hold the mixMutex
+func (m *Mixer) mixResolve(f *fire.Fire) {
+	multi := m.mixMultiSource(f.Source)
+	if multi == nil || f.Resolved() != f.Source {
+		return
+	}
+	f.Resolve(multi.layerOf(f.Volume).pick(m.mixRandom))
+}
+
+// mixResetPicks of the multi-sample sources, from the random seed; the caller must hold the mixMutex
+func (m *Mixer) mixResetPicks() {
+	m.mixRandom = rand.New(rand.NewSource(m.mixRandomSeed))
+	m.multiMutex.RLock()
+	defer m.multiMutex.RUnlock()
+	for _, multi := range m.mixMultiSources {
+		for _, l := range multi.layers {
+			l.last = -1
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestRegisterMultiSource(t *testing.T) {
+	testMixSetup()
+	soft, hard := testMultiSource()
+	quiet := SetFire("kick", 0, 0, 0.2, 0)
+	loud := make([]*fire.Fire, 4)
+	for i := range loud {
+		loud[i] = SetFire("kick", time.Duration(i+1)*10*time.Millisecond, 0, 0.9, 0)
+	}
+	later := SetFire("kick", 10*time.Second, 0, 0.9, 0)
+	out, err := Render(100 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotEqual(t, float64(0), out[220])
+	assert.Equal(t, soft[0], quiet.Resolved())
+	assert.Equal(t, "kick", later.Resolved()) // not yet live
+	picked := testResolved(loud)
+	for i, key := range picked {
+		assert.Contains(t, hard, key)
+		assert.Equal(t, hard[(testIndexOf(hard, picked[0])+i)%len(hard)], key) // round-robin
+	}
+}
+
+func TestRegisterMultiSource_Nearest(t *testing.T) {
+	testMixSetup()
+	assert.Nil(t, RegisterMultiSource("snare", []Layer{
+		{Sources: []string{testMultiTone(330)}, MinVelocity: 0.2, MaxVelocity: 0.4},
+		{Sources: []string{testMultiTone(660)}, MinVelocity: 0.6, MaxVelocity: 0.8},
+	}))
+	low, high := SetFire("snare", 0, 0, 0, 0), SetFire("snare", 0, 0, 1, 0)
+	Render(10 * time.Millisecond)
+	assert.Equal(t, testMultiTone(330), low.Resolved())
+	assert.Equal(t, testMultiTone(660), high.Resolved())
+}
+
+func TestRegisterMultiSource_Random(t *testing.T) {
+	testMixSetup()
+	assert.Nil(t, RegisterMultiSource("hat", []Layer{
+		{Sources: []string{testMultiTone(2000), testMultiTone(3000), testMultiTone(4000)}, MaxVelocity: 1, Random: true},
+	}))
+	fires := make([]*fire.Fire, 20)
+	for i := range fires {
+		fires[i] = SetFire("hat", time.Duration(i)*5*time.Millisecond, 0, 1, 0)
+	}
+	Render(200 * time.Millisecond)
+	picked := testResolved(fires)
+	for i := 1; i < len(picked); i++ {
+		assert.NotEqual(t, picked[i-1], picked[i]) // never the same twice in a row
+	}
+}
+
+func TestRegisterMultiSource_Invalid(t *testing.T) {
+	testMixSetup()
+	assert.NotNil(t, RegisterMultiSource("kick", nil))
+	assert.NotNil(t, RegisterMultiSource("kick", []Layer{{MaxVelocity: 1}}))
+	assert.NotNil(t, RegisterMultiSource("kick", []Layer{{Sources: []string{testMultiTone(60)}, MinVelocity: 0.8, MaxVelocity: 0.2}}))
+	assert.NotNil(t, RegisterMultiSource("kick", []Layer{{Sources: []string{testMultiTone(60)}, MaxVelocity: 2}}))
+}
+
+func TestSetRandomSeed(t *testing.T) {
+	var picks [][]string
+	var renders [][]float64
+	for run := 0; run < 2; run++ {
+		testMixSetup()
+		testMultiSource()
+		SetRandomSeed(7)
+		fires := make([]*fire.Fire, 6)
+		for i := range fires {
+			fires[i] = SetFire("kick", time.Duration(i)*10*time.Millisecond, 0, 0.9, 0)
+		}
+		out, err := Render(100 * time.Millisecond)
+		assert.Nil(t, err)
+		picks = append(picks, testResolved(fires))
+		renders = append(renders, out)
+	}
+	assert.Equal(t, picks[0], picks[1])
+	assert.Equal(t, renders[0], renders[1]) // bit-identical
+}
+
+//
+// Private
+//
+
+// testMultiSource registers "kick", with one soft variant and three hard ones, returning the keys of each
+func testMultiSource() (soft []string, hard []string) {
+	soft = []string{testMultiTone(60)}
+	hard = []string{testMultiTone(80), testMultiTone(100), testMultiTone(120)}
+	RegisterMultiSource("kick", []Layer{
+		{Sources: soft, MinVelocity: 0, MaxVelocity: 0.5},
+		{Sources: hard, MinVelocity: 0.5, MaxVelocity: 1},
+	})
+	return
+}
+
+func testMultiTone(freq float64) string {
+	return source.ToneKey(source.WaveSine, freq, 50*time.Millisecond)
+}
+
+func testResolved(fires []*fire.Fire) (keys []string) {
+	for _, f := range fires {
+		keys = append(keys, f.Resolved())
+	}
+	return
+}
+
+func testIndexOf(keys []string, key string) int {
+	for i, k := range keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,206 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-mix/mix/bind/debug"
+)
+
+// OutputBlockFrames of each block that the output queues for its writer, and OutputQueueBlocks the most it queues, before it
+// waits for the writer, or drops blocks if the output is a pipe, see SetOutputPipe
+const (
+	OutputBlockFrames = 1024
+	OutputQueueBlocks = 16
+)
+
+// SetOutputPipe, if on, for each output started after, e.g. a WAV piped to aplay as it plays: if its consumer stalls, the output
+// never waits for it, but drops each block beyond OutputQueueBlocks, counting it in Stats, so that the live timing is kept. Else
+// (the default), e.g. an offline render to a file, the output waits for its writer, which is correct when nothing plays in real
+// time, and OutputContinueTo returns once all it mixed is written. Either way, the writer is called on a goroutine of its own.
+func (m *Mixer) SetOutputPipe(on bool) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixOutputPipe = on
+}
+
+// GetOutputPipe setting, see SetOutputPipe
+func (m *Mixer) GetOutputPipe() bool {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixOutputPipe
+}
+
+// OnOutputError to call fn with the first error of the writer of the output, e.g. a broken pipe after the process consuming it has
+// died; nil fn to only warn of it. The output stops cleanly: the rest of its samples are still mixed, to keep the time, but
+// discarded, and OutputClose returns an error of the failure. fn is called on its own goroutine, once per output, and is kept by
+// Teardown. A process writing to stdout must ignore SIGPIPE, e.g. by signal.Ignore, else Go exits it on a write to a broken pipe.
+func (m *Mixer) OnOutputError(fn func(err error)) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixOutErrFn = fn
+}
+
+//
+// Private
+//
+
+// mixOutWriter of the output, between its encoder and the writer of the caller, which it calls on a goroutine of its own; it is
+// filled and pushed by the output, which holds the outputMutex
+type mixOutWriter struct {
+	written int64 // accessed atomically, first so that it is 64-bit aligned
+	failed  int32 // accessed atomically, 1 after an error of the writer
+	mixer   *Mixer
+	out     io.Writer
+	pipe    bool        // drops blocks, instead of waiting for the writer
+	block   []byte      // filled by the output
+	blocks  chan []byte // queued for the writer
+	free    chan []byte // written, to be filled again
+	pending sync.WaitGroup
+	done    chan struct{}
+	errOnce sync.Once
+	err     error // of the writer, set once
+}
+
+// mixOutSeeker is a mixOutWriter of an io.WriteSeeker, e.g. an *os.File, so that the header of a streamed WAV can be patched
+type mixOutSeeker struct {
+	*mixOutWriter
+}
+
+// mixNewOutWriter to an io.Writer, in blocks of a # of bytes per frame
+func (m *Mixer) mixNewOutWriter(out io.Writer, frameSize int, pipe bool) *mixOutWriter {
+	size := OutputBlockFrames * frameSize
+	w := &mixOutWriter{
+		mixer:  m,
+		out:    out,
+		pipe:   pipe,
+		block:  make([]byte, 0, size),
+		blocks: make(chan []byte, OutputQueueBlocks),
+		free:   make(chan []byte, OutputQueueBlocks+2),
+		done:   make(chan struct{}),
+	}
+	for n := 0; n < OutputQueueBlocks+1; n++ { // enough that the output never waits for one
+		w.free <- make([]byte, 0, size)
+	}
+	go w.writeLoop()
+	return w
+}
+
+// writerOf the mixOutWriter for the output, which can seek if the writer of the caller can
+func (w *mixOutWriter) writerOf() io.Writer {
+	if _, ok := w.out.(io.WriteSeeker); ok {
+		return mixOutSeeker{w}
+	}
+	return w
+}
+
+// Write bytes of the output into the block, pushing it once it is full, or discard them after an error of the writer
+func (w *mixOutWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&w.failed) != 0 {
+		return len(p), nil
+	}
+	w.block = append(w.block, p...)
+	if len(w.block) >= cap(w.block) {
+		w.push(false)
+	}
+	return len(p), nil
+}
+
+// Flush all that was written to the writer of the caller, waiting for it, then flush it too if it is buffered, e.g. a *bufio.Writer
+func (w *mixOutWriter) Flush() error {
+	w.wait()
+	if flusher, ok := w.out.(interface{ Flush() error }); ok && atomic.LoadInt32(&w.failed) == 0 {
+		if err := flusher.Flush(); err != nil {
+			w.fail(err)
+		}
+	}
+	return w.error()
+}
+
+// Seek the writer of the caller, after all that was written to it
+func (s mixOutSeeker) Seek(offset int64, whence int) (int64, error) {
+	if err := s.Flush(); err != nil {
+		return 0, err
+	}
+	return s.out.(io.Seeker).Seek(offset, whence)
+}
+
+// push the block to the writer; if the output is a pipe, drop it if the queue is full, unless wait, e.g. of the header of a WAV
+func (w *mixOutWriter) push(wait bool) {
+	if len(w.block) == 0 {
+		return
+	}
+	w.pending.Add(1)
+	if w.pipe && !wait {
+		select {
+		case w.blocks <- w.block:
+		default:
+			w.pending.Done()
+			w.block = w.block[:0]
+			atomic.AddInt64(&w.mixer.mixStatOutDropped, 1)
+			return
+		}
+	} else {
+		w.blocks <- w.block // backpressure of the writer
+	}
+	w.block = <-w.free // never waits, as there is one more buffer than the queue and the writer can hold
+}
+
+// wait for the block, and all those queued, to be written
+func (w *mixOutWriter) wait() {
+	w.push(true)
+	w.pending.Wait()
+}
+
+// close the writer, after all that was written to it, but not the writer of the caller; returns an error of the failure, if any
+func (w *mixOutWriter) close() error {
+	w.push(true)
+	close(w.blocks)
+	<-w.done
+	if err := w.error(); err != nil {
+		return fmt.Errorf("Output failed after writing %d bytes, and discarded the rest: %s", atomic.LoadInt64(&w.written), err)
+	}
+	return nil
+}
+
+// writeLoop of the queued blocks, until the queue is closed; after an error, it only recycles them
+func (w *mixOutWriter) writeLoop() {
+	defer close(w.done)
+	for block := range w.blocks {
+		if atomic.LoadInt32(&w.failed) == 0 {
+			n, err := w.out.Write(block)
+			atomic.AddInt64(&w.written, int64(n))
+			if err != nil {
+				w.fail(err)
+			}
+		}
+		w.free <- block[:0]
+		w.pending.Done()
+	}
+}
+
+// fail on the first error of the writer: stop writing, and report it to the callback of OnOutputError on its own goroutine
+func (w *mixOutWriter) fail(err error) {
+	w.errOnce.Do(func() {
+		w.err = err
+		atomic.StoreInt32(&w.failed, 1)
+		debug.Warnf("mix output failed, discarding the rest: %s", err)
+		w.mixer.mixMutex.Lock()
+		fn := w.mixer.mixOutErrFn
+		w.mixer.mixMutex.Unlock()
+		if fn != nil {
+			go fn(err)
+		}
+	})
+}
+
+// error of the writer, if it failed
+func (w *mixOutWriter) error() error {
+	if atomic.LoadInt32(&w.failed) == 0 {
+		return nil
+	}
+	return w.err
+}
@@ -0,0 +1,118 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestOnOutputError(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	failed := make(chan error, 1)
+	m.OnOutputError(func(err error) {
+		failed <- err
+	})
+	out := &testOutWriter{limit: 10000}
+	m.OutputStart(0, out)
+	m.StartAt(time.Now())
+	m.SetFireTone(441, 0, 0, 1, 0)
+	m.OutputContinueTo(time.Second) // the rest is discarded, not written
+	assert.Equal(t, time.Second, m.GetNowAt())
+	select {
+	case err := <-failed:
+		assert.Equal(t, "broken pipe", err.Error())
+	case <-time.After(time.Second):
+		t.Error("output error not reported")
+	}
+	m.OutputContinueTo(2 * time.Second)
+	assert.Equal(t, 10000, out.Len())
+	err = m.OutputClose()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "after writing 10000 bytes")
+	assert.Contains(t, err.Error(), "broken pipe")
+	assert.Equal(t, 0, len(failed)) // once
+	m.OutputStart(0, &bytes.Buffer{})
+	assert.Nil(t, m.OutputClose()) // of a new output
+}
+
+func TestSetOutputPipe(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	m.SetOutputPipe(true)
+	assert.True(t, m.GetOutputPipe())
+	out := &testOutWriter{stall: make(chan struct{})}
+	m.OutputStart(0, out)
+	header := out.Len()
+	m.StartAt(time.Now())
+	m.SetFireTone(441, 0, 0, 1, 0)
+	done := make(chan struct{})
+	go func() {
+		m.OutputContinueTo(2 * time.Second) // of 86 blocks, most of which the stalled consumer never takes
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("output blocked by a stalled consumer")
+	}
+	dropped := m.Stats().OutDropped
+	assert.True(t, dropped >= 86-OutputQueueBlocks-1, "%d dropped", dropped)
+	close(out.stall)
+	assert.Nil(t, m.OutputClose())
+	frames := (out.Len() - header) / 4
+	assert.True(t, frames < 88200 && frames%OutputBlockFrames == 0, "%d frames", frames) // whole blocks, never a torn frame
+	m.Teardown()
+	assert.False(t, m.GetOutputPipe())
+	assert.Equal(t, int64(0), m.Stats().OutDropped)
+}
+
+//
+// Private
+//
+
+// testOutWriter that fails after a limit of bytes, if any, or stalls after the first write until stall is closed, if set
+type testOutWriter struct {
+	mutex  sync.Mutex
+	buf    bytes.Buffer
+	limit  int
+	stall  chan struct{}
+	writes int
+}
+
+func (w *testOutWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	w.writes++
+	stall := w.stall != nil && w.writes > 1
+	w.mutex.Unlock()
+	if stall {
+		<-w.stall
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.limit > 0 && w.buf.Len()+len(p) > w.limit {
+		n, _ := w.buf.Write(p[:w.limit-w.buf.Len()])
+		return n, errors.New("broken pipe")
+	}
+	return w.buf.Write(p)
+}
+
+// Len of all that was written
+func (w *testOutWriter) Len() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.Len()
+}
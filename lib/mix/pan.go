@@ -0,0 +1,44 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// PanLaw by which the pan of a fire or a bus is turned into the gain of each master channel, see SetPanLaw
+type PanLaw = source.PanLaw
+
+const (
+	PanLinear        = source.PanLinear        // the original curve, with the center at unity gain in every channel, and the default
+	PanConstantPower = source.PanConstantPower // sin/cos gains, with the same summed power at any pan
+	PanCompromise45  = source.PanCompromise45  // -4.5dB in each channel at the center
+	PanBalance       = source.PanBalance       // a balance, with the center at unity gain in every channel
+)
+
+// SetPanLaw of the pan of every fire and bus, e.g. PanConstantPower to avoid a jump in level while the pan is automated,
+// or PanBalance to silence the other side of a fire panned hard to one side. The default is PanLinear, the original curve,
+// which attenuates each channel by the pan in proportion to its index, whichever way it is panned.
+func (m *Mixer) SetPanLaw(law PanLaw) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixPanLaw = law
+}
+
+// GetPanLaw of the pan of every fire and bus, see SetPanLaw
+func (m *Mixer) GetPanLaw() PanLaw {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixPanLaw
+}
+
+//
+// Private
+//
+
+// mixPan a centered sample of all channels in place, by a pan law
+func mixPan(out []sample.Value, law PanLaw, pan float64) {
+	for c := range out {
+		out[c] *= sample.Value(source.PanGain(law, c, len(out), pan))
+	}
+}
@@ -0,0 +1,65 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetPanLaw(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, PanLinear, GetPanLaw())
+	SetPanLaw(PanConstantPower)
+	assert.Equal(t, PanConstantPower, GetPanLaw())
+	Teardown()
+	assert.Equal(t, PanLinear, GetPanLaw())
+}
+
+func TestSetPanLaw_Power(t *testing.T) {
+	for _, c := range []struct {
+		law    PanLaw
+		center float64 // summed power of both channels at the center, vs. hard to a side
+	}{
+		{PanConstantPower, 1},
+		{PanCompromise45, 0.70710678},
+		{PanBalance, 2},
+	} {
+		for _, onBus := range []bool{false, true} {
+			left, center, right := testPanPower(c.law, -1, onBus), testPanPower(c.law, 0, onBus), testPanPower(c.law, 1, onBus)
+			assert.InDelta(t, left, right, left*1e-6)
+			assert.InDelta(t, c.center, center/left, 1e-3)
+		}
+	}
+}
+
+//
+// Private
+//
+
+// testPanPower summed across both channels of a stereo render of a tone, panned on the fire or else on its bus, by a pan law
+func testPanPower(law PanLaw, pan float64, onBus bool) (power float64) {
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 2,
+	})
+	SetMixAlgorithm(MixHardClip)
+	SetPanLaw(law)
+	if onBus {
+		bus := NewBus("pan")
+		bus.SetPan(pan)
+		SetFireOnBus(bus, testMultiTone(441), 0, 0, 0.5, 0)
+	} else {
+		SetFireTone(441, 0, 50*time.Millisecond, 0.5, pan)
+	}
+	out, _ := Render(50 * time.Millisecond)
+	for _, v := range out {
+		power += v * v
+	}
+	return
+}
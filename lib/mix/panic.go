@@ -0,0 +1,71 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/sample"
+)
+
+// OnError to call fn with a panic recovered from the mix loop, as an error, and the stack of the goroutine where it happened, e.g. of
+// a corrupt source, or a source provider, that would otherwise kill the whole process; nil fn to only warn of it. The mixer fails:
+// its output is silent and no fire plays, until the host, having shown a dialog or saved its session, calls Teardown, after which it
+// can Configure it again. fn is called on its own goroutine, never the mix loop, once per failure, and is kept by Teardown.
+func (m *Mixer) OnError(fn func(err error, stack []byte)) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixErrFn = fn
+}
+
+// Err of the mix loop, recovered from a panic, or nil if it has not failed since the last Teardown, see OnError
+func (m *Mixer) Err() error {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixErr
+}
+
+//
+// Private
+//
+
+// mixPanicStackSize at most, of the stack reported of a panic
+const mixPanicStackSize = 64 << 10
+
+// mixPanic recovered on another goroutine, e.g. a worker mixing a chunk of the live fires, to panic again on the mix loop
+type mixPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// mixPanicStack of the calling goroutine, e.g. of a deferred call as it recovers, which is above the frames that panicked
+func mixPanicStack() []byte {
+	stack := make([]byte, mixPanicStackSize)
+	return stack[:runtime.Stack(stack, false)]
+}
+
+// mixFail on a panic recovered from the mix loop: silence the output until the teardown, and report the error, once, to the
+// callback of OnError on its own goroutine; the caller must hold the mixMutex
+func (m *Mixer) mixFail(r interface{}, stack []byte) {
+	if p, ok := r.(*mixPanic); ok {
+		r, stack = p.value, p.stack
+	}
+	if m.mixErr != nil {
+		return
+	}
+	m.mixErr = fmt.Errorf("Mix loop panicked: %v", r)
+	m.mixDropBlock()
+	debug.Warnf("mix failed, silent until it is torn down: %s\n%s", m.mixErr, stack)
+	if fn := m.mixErrFn; fn != nil {
+		go fn(m.mixErr, stack)
+	}
+}
+
+// mixSilence of the output buffer, for a sample that is not mixed; the caller must hold the mixMutex
+func (m *Mixer) mixSilence() []sample.Value {
+	for c := range m.mixOutBuffer {
+		m.mixOutBuffer[c] = 0
+	}
+	return m.mixOutBuffer
+}
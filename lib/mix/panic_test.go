@@ -0,0 +1,131 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestOnError(t *testing.T) {
+	testMixSetup()
+	failed, cleanup := testPanicProvider(t)
+	defer cleanup()
+	tone := source.ToneKey(source.WaveSine, 441, time.Second)
+	SetFire(tone, 0, 0, 1.0, 0)
+	SetFire("panics", 0, 0, 1.0, 0)
+	for n := 0; n < 100; n++ {
+		smp := NextSample()
+		if n > 50 {
+			assert.Equal(t, []sample.Value{0}, smp) // silent once failed
+		}
+	}
+	testPanicAwait(t, failed, "out of range")
+	assert.NotNil(t, Err())
+	assert.True(t, SetFire(tone, 0, 0, 1.0, 0).IsCanceled()) // no new fires
+	_, err := Render(10 * time.Millisecond)
+	assert.Equal(t, Err(), err)
+	testMixSetup()
+	assert.Nil(t, Err())
+	SetFire(tone, 0, 0, 1.0, 0)
+	var loud bool
+	for n := 0; n < 100; n++ {
+		loud = loud || NextSample()[0] != 0
+	}
+	assert.True(t, loud) // mixes again
+}
+
+func TestOnError_Parallel(t *testing.T) {
+	testMixSetup()
+	failed, cleanup := testPanicProvider(t)
+	defer cleanup()
+	SetWorkers(4)
+	defer SetWorkers(0)
+	for i := 0; i < 40; i++ {
+		SetFire(source.ToneKey(source.WaveSine, 441, time.Second), 0, 0, 0.01, 0)
+	}
+	SetFire("panics", 0, 0, 1.0, 0)
+	block := make([]float32, 100)
+	NextBlock(block, 100)
+	testPanicAwait(t, failed, "out of range")
+	assert.Equal(t, make([]float32, 100), block)
+}
+
+func TestOnError_Render(t *testing.T) {
+	testMixSetup()
+	failed, cleanup := testPanicProvider(t)
+	defer cleanup()
+	SetFire("panics", 0, 0, 1.0, 0)
+	_, err := Render(10 * time.Millisecond)
+	assert.NotNil(t, err)
+	testPanicAwait(t, failed, "out of range")
+}
+
+func TestOnError_Lookahead(t *testing.T) {
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	failed := make(chan error, 1)
+	m.OnError(func(err error, stack []byte) {
+		failed <- err
+	})
+	assert.Nil(t, source.RegisterProvider("panics", &testPanic{}))
+	defer source.RegisterProvider("panics", nil)
+	m.SetLookahead(1)
+	m.SetFire("panics", 0, 0, 1.0, 0)
+	select {
+	case err := <-failed:
+		assert.Contains(t, err.Error(), "out of range")
+	case <-time.After(5 * time.Second):
+		t.Fatal("mix loop did not fail")
+	}
+	assert.Equal(t, []sample.Value{0}, m.NextSample())
+}
+
+//
+// Private
+//
+
+// testPanic provider of a source, which panics after its values, like a bad index into a corrupt source
+type testPanic struct {
+	values []sample.Value
+}
+
+func (p *testPanic) At(frame spec.Tz, out []sample.Value) {
+	out[0] = p.values[frame] // out of range at the end of its values
+}
+
+func (p *testPanic) Length() spec.Tz {
+	return 44100
+}
+
+// testPanicProvider registered as "panics" on the default mixer, of 10 frames, and a channel of the errors reported to OnError
+func testPanicProvider(t *testing.T) (failed chan error, cleanup func()) {
+	assert.Nil(t, RegisterSourceProvider("panics", &testPanic{values: make([]sample.Value, 10)}))
+	failed = make(chan error, 1)
+	OnError(func(err error, stack []byte) {
+		assert.True(t, strings.Contains(string(stack), "testPanic"), "stack where it panicked:\n%s", stack)
+		failed <- err
+	})
+	return failed, func() {
+		OnError(nil)
+		RegisterSourceProvider("panics", nil)
+		testMixSetup()
+	}
+}
+
+// testPanicAwait the error reported to OnError
+func testPanicAwait(t *testing.T, failed chan error, contains string) {
+	select {
+	case err := <-failed:
+		assert.Contains(t, err.Error(), contains)
+	case <-time.After(5 * time.Second):
+		t.Fatal("mix loop did not fail")
+	}
+}
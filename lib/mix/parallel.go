@@ -0,0 +1,249 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"runtime"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// SetWorkers to load sources, and mix many live fires, concurrently across at most n goroutines, or 0 for one per CPU (GOMAXPROCS).
+//
+// While more than a few dozen fires are live, they are mixed ahead in blocks of a millisecond or so, in fixed chunks whose sums
+// are merged in order, so the output is the same for any # of workers, and an offline render is reproducible. A change to a
+// live fire, e.g. its volume or canceling it, takes effect at the next block.
+func (m *Mixer) SetWorkers(n int) {
+	if n < 0 {
+		n = 0
+	}
+	m.cache.SetWorkers(n)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixWorkers = n
+}
+
+//
+// Private
+//
+
+const (
+	mixChunkFires  = 8  // # of live fires in each chunk, whose sum is merged in order regardless of the # of workers
+	mixBlockFrames = 64 // most # of samples mixed ahead in a block
+)
+
+// mixChunk of the live fires, mixed by one worker into a sum of each bus, for each sample of a block
+type mixChunk struct {
+	mixer    *Mixer
+	fires    []*fire.Fire
+	sources  []*source.Source // of each fire, resolved once per block
+	buses    []int            // index of the bus of each fire
+	beginTz  spec.Tz
+	frames   int
+	stride   int            // # of values per sample: the # of buses times the # of channels
+	sums     []sample.Value // of each sample, of each bus, of each channel
+	events   []mixChunkEvent
+	buffer   []sample.Value
+	scratch  []sample.Value
+	tail     []sample.Value // of a crossfade loop
+	channels int
+	panicked *mixPanic // recovered by its worker, see renderOrRecover
+}
+
+// mixChunkEvent of a fire that started or finished playing, handled by the mix loop at the sample it happened, see mixFireTransition
+type mixChunkEvent struct {
+	frame int
+	fire  *fire.Fire
+	state fire.StateEnum
+}
+
+// mixBlockHas the sample at a Tz
+func (m *Mixer) mixBlockHas(tz spec.Tz) bool {
+	return tz >= m.mixBlockBeginTz && tz < m.mixBlockEndTz
+}
+
+// mixDropBlock of samples mixed ahead, e.g. when the playhead moves
+func (m *Mixer) mixDropBlock() {
+	m.mixBlockBeginTz = 0
+	m.mixBlockEndTz = 0
+}
+
+// mixRenderedTz is the last Tz for which the live fires have been mixed
+func (m *Mixer) mixRenderedTz() spec.Tz {
+	if m.mixBlockHas(m.nowTz) {
+		return m.mixBlockEndTz - 1
+	}
+	return m.nowTz
+}
+
+// mixRenderBlock of samples from now until the next mix cycle, at most, with the live fires split into chunks across the workers;
+// the caller must hold the mixMutex
+func (m *Mixer) mixRenderBlock() {
+	frames := 1
+	if m.nextCycleTz >= m.nowTz {
+		frames = int(m.nextCycleTz-m.nowTz) + 1
+	}
+	if frames > mixBlockFrames {
+		frames = mixBlockFrames
+	}
+	frames = m.mixChokeFrames(frames)
+	channels := m.masterSpec.Channels
+	stride := len(m.mixBusList) * channels
+	m.mixChunkCount = (len(m.mixLiveFires) + mixChunkFires - 1) / mixChunkFires
+	for len(m.mixChunks) < m.mixChunkCount {
+		m.mixChunks = append(m.mixChunks, &mixChunk{mixer: m})
+	}
+	for i, ch := range m.mixChunks[:m.mixChunkCount] {
+		end := (i + 1) * mixChunkFires
+		if end > len(m.mixLiveFires) {
+			end = len(m.mixLiveFires)
+		}
+		ch.prepare(m.mixLiveFires[i*mixChunkFires:end], m.nowTz, frames, stride, channels)
+	}
+	workers := m.mixWorkers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > m.mixChunkCount {
+		workers = m.mixChunkCount
+	}
+	if workers <= 1 {
+		for _, ch := range m.mixChunks[:m.mixChunkCount] {
+			ch.render()
+		}
+	} else {
+		m.mixStartWorkers(workers)
+		m.mixChunkWait.Add(m.mixChunkCount)
+		for _, ch := range m.mixChunks[:m.mixChunkCount] {
+			m.mixChunkJobs <- ch
+		}
+		m.mixChunkWait.Wait()
+		for _, ch := range m.mixChunks[:m.mixChunkCount] {
+			if p := ch.panicked; p != nil {
+				ch.panicked = nil
+				panic(p) // on the mix loop, which recovers it
+			}
+		}
+	}
+	m.mixBlockBeginTz = m.nowTz
+	m.mixBlockEndTz = m.nowTz + spec.Tz(frames)
+}
+
+// mixAddBlockFrame of each chunk, in order, to the sum of each bus, and handle the transitions of fires at the frame
+func (m *Mixer) mixAddBlockFrame(frame int) {
+	channels := m.masterSpec.Channels
+	for _, ch := range m.mixChunks[:m.mixChunkCount] {
+		for _, e := range ch.events {
+			if e.frame == frame {
+				m.mixFireTransition(e.fire, e.state)
+			}
+		}
+		sums := ch.sums[frame*ch.stride : (frame+1)*ch.stride]
+		for b := 0; b*channels < ch.stride; b++ {
+			m.mixBusList[b].add(sums[b*channels : (b+1)*channels])
+		}
+	}
+}
+
+// mixStartWorkers if there are not already as many, stopping any others; the caller must hold the mixMutex
+func (m *Mixer) mixStartWorkers(n int) {
+	if m.mixChunkWorkers == n {
+		return
+	}
+	m.mixStopWorkers()
+	m.mixChunkJobs = make(chan *mixChunk, n)
+	m.mixChunkWorkers = n
+	m.mixChunkRunning.Add(n)
+	for w := 0; w < n; w++ {
+		go m.mixChunkWorker(m.mixChunkJobs)
+	}
+}
+
+// mixStopWorkers if any, and wait for them to return, e.g. by the teardown, such that a mixer that is torn down leaks no goroutine;
+// they never take the mixMutex, which the caller must hold
+func (m *Mixer) mixStopWorkers() {
+	if m.mixChunkJobs == nil {
+		return
+	}
+	close(m.mixChunkJobs)
+	m.mixChunkJobs = nil
+	m.mixChunkWorkers = 0
+	m.mixChunkRunning.Wait()
+}
+
+func (m *Mixer) mixChunkWorker(jobs <-chan *mixChunk) {
+	defer m.mixChunkRunning.Done()
+	for ch := range jobs {
+		ch.renderOrRecover()
+		m.mixChunkWait.Done()
+	}
+}
+
+// renderOrRecover the chunk, on a worker, recovering a panic to raise again on the mix loop, see mixRenderBlock
+func (ch *mixChunk) renderOrRecover() {
+	defer func() {
+		if r := recover(); r != nil {
+			ch.panicked = &mixPanic{r, mixPanicStack()}
+		}
+	}()
+	ch.render()
+}
+
+// prepare the chunk to mix some fires, resolving their sources and buses; it makes no allocations once it has grown
+func (ch *mixChunk) prepare(fires []*fire.Fire, beginTz spec.Tz, frames int, stride int, channels int) {
+	ch.fires = fires
+	ch.sources = ch.sources[:0]
+	ch.buses = ch.buses[:0]
+	for _, f := range fires {
+		ch.sources = append(ch.sources, ch.mixer.mixGetSource(f.Resolved()))
+		ch.buses = append(ch.buses, ch.mixer.mixBusOf(f).index)
+	}
+	ch.beginTz = beginTz
+	ch.frames = frames
+	ch.stride = stride
+	if cap(ch.sums) < frames*stride {
+		ch.sums = make([]sample.Value, mixBlockFrames*stride)
+	}
+	ch.sums = ch.sums[:frames*stride]
+	if ch.channels != channels {
+		ch.buffer = make([]sample.Value, channels)
+		ch.scratch = make([]sample.Value, channels)
+		ch.tail = make([]sample.Value, channels)
+		ch.channels = channels
+	}
+}
+
+// render each sample of the block for the fires of the chunk, summed by bus in the order of the fires
+func (ch *mixChunk) render() {
+	for i := range ch.sums {
+		ch.sums[i] = 0
+	}
+	ch.events = ch.events[:0]
+	for frame := 0; frame < ch.frames; frame++ {
+		sums := ch.sums[frame*ch.stride : (frame+1)*ch.stride]
+		for i, f := range ch.fires {
+			fireTz, playing, transition := ch.mixer.mixFireAt(f, ch.beginTz+spec.Tz(frame))
+			if transition != 0 {
+				ch.events = append(ch.events, mixChunkEvent{frame, f, transition})
+			}
+			if !playing {
+				continue
+			}
+			volume, pan := f.VolumeAt(fireTz)*f.FadeAt(fireTz), f.PanAt(fireTz)
+			mixSourceAt(ch.buffer, ch.scratch, ch.sources[i], volume, pan, ch.mixer.mixPanLaw, f.PlayRate(), f.OffsetTz, fireTz, f.BeginFrac)
+			if f.XFadeTz > 0 {
+				mixLoopXFadeAt(ch.buffer, ch.tail, ch.scratch, ch.sources[i], f, volume, pan, ch.mixer.mixPanLaw)
+			}
+			if tone := f.ToneFilter(); tone != nil {
+				tone.Process(ch.buffer)
+			}
+			f.Meter().Add(ch.buffer)
+			offset := ch.buses[i] * ch.channels
+			for c, v := range ch.buffer {
+				sums[offset+c] += v
+			}
+		}
+	}
+}
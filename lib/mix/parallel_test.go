@@ -0,0 +1,119 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetWorkers(t *testing.T) {
+	defer SetWorkers(0)
+	SetWorkers(3)
+	assert.Equal(t, 3, source.Workers())
+	SetWorkers(-1)
+	assert.Equal(t, 0, mixDefault.mixWorkers)
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer m.Teardown()
+	m.SetWorkers(2)
+	assert.Equal(t, 2, m.cache.Workers())
+	assert.Equal(t, runtime.GOMAXPROCS(0), source.Workers()) // of the default mixer, as it was
+}
+
+func TestSetWorkers_Teardown(t *testing.T) {
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	m.SetWorkers(4)
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	for n := 0; n < 2*m.mixParallelFires; n++ {
+		m.SetFire(src, 0, 0, 0.1, 0)
+	}
+	m.OutputContinueTo(10 * time.Millisecond)
+	assert.Equal(t, 4, m.mixChunkWorkers)
+	before := runtime.NumGoroutine()
+	m.Teardown()
+	assert.Nil(t, m.mixChunkJobs)
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before-4 && time.Now().Before(deadline); {
+		runtime.Gosched()
+	}
+	assert.True(t, runtime.NumGoroutine() <= before-4, "chunk workers did not exit")
+}
+
+// the output of many live fires must not depend on the # of workers, so that an offline render is reproducible
+func TestSetWorkers_Deterministic(t *testing.T) {
+	defer SetWorkers(0)
+	SetWorkers(1)
+	single, singleEvents := testMixManyFires()
+	SetWorkers(4)
+	parallel, parallelEvents := testMixManyFires()
+	assert.True(t, mixDefault.mixChunkCount > 1)
+	assert.Equal(t, single, parallel)
+	assert.Equal(t, singleEvents, parallelEvents)
+	// and mixing in blocks sums the fires in a different order, but sounds the same
+	defer func(fires int) { mixDefault.mixParallelFires = fires }(mixDefault.mixParallelFires)
+	mixDefault.mixParallelFires = math.MaxInt32
+	serial, serialEvents := testMixManyFires()
+	assert.Equal(t, serialEvents, parallelEvents)
+	assert.Equal(t, len(serial), len(parallel))
+	for i := range serial {
+		assert.InDelta(t, float64(serial[i]), float64(parallel[i]), 1e-9)
+	}
+}
+
+func TestClearFiresAfter_Block(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	for n := 0; n < 2*mixDefault.mixParallelFires; n++ {
+		SetFire(src, 0, 0, 0.1, 0)
+	}
+	late := SetFire(src, 200*time.Millisecond, 0, 0.1, 0)
+	soon := SetFire(src, 20*time.Millisecond, 0, 0.1, 0)
+	for !(mixDefault.mixBlockHas(mixDefault.nowTz) && soon.BeginTz > mixDefault.nowTz && soon.BeginTz < mixDefault.mixBlockEndTz) {
+		NextSample()
+		assert.True(t, mixDefault.nowTz < soon.BeginTz)
+	}
+	assert.Equal(t, 1, ClearFiresAfter(0)) // only the late fire, because the soon fire has been mixed ahead in the block
+	assert.Equal(t, 1, FireCountReady())   // the soon fire, live but not yet begun
+	assert.False(t, late.IsAlive())
+	assert.False(t, soon.IsCanceled())
+}
+
+//
+// Test Components
+//
+
+// testMixManyFires on a few buses, dozens at once, as a render of a tenth of a second, and the events of the fires
+func testMixManyFires() (out []sample.Value, events []FireEvent) {
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 2,
+	})
+	buses := []*Bus{nil, NewBus("drums"), NewBus("pads")}
+	for n := 0; n < 60; n++ {
+		src := source.ToneKey(source.WaveSine, float64(110+n*7), 100*time.Millisecond)
+		SetFireOnBus(buses[n%len(buses)], src, time.Duration(n)*2*time.Millisecond, 0, 0.2, float64(n%5)/2-1)
+	}
+	ch := FireEvents()
+	for len(ch) > 0 { // of an earlier test
+		<-ch
+	}
+	for n := 0; n < 4410; n++ {
+		out = append(out, NextSample()...)
+		for len(ch) > 0 {
+			e := <-ch
+			e.Fire = nil // the fires differ by each render
+			events = append(events, e)
+		}
+	}
+	return
+}
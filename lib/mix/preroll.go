@@ -0,0 +1,144 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// PreRollBeatsPerBar of a count-in, see SetPreRoll
+const PreRollBeatsPerBar = 4
+
+// PreRollAccent is the volume of the click on the first beat of each bar of a count-in, and PreRollVolume of the others
+const (
+	PreRollAccent = 1.0
+	PreRollVolume = 0.5
+)
+
+// SetPreRoll of a # of bars of PreRollBeatsPerBar, at the tempo of step zero as of Start, e.g. a count-in of two bars of metronome
+// clicks for musicians; 0 bars for none (the default). Each Start or StartAt plays a click source, resolved like SetFire, on each
+// beat of the pre-roll, and then begins the mix: time zero of the schedule is the end of the pre-roll, so no fire need be offset by it,
+// and GetNowAt is negative until then. The clicks are not fires, so ClearAllFires does not remove them; an empty click source is a
+// silent pre-roll. The pre-roll is only written by OutputStart and OutputContinueTo if SetPreRollOutput.
+func (m *Mixer) SetPreRoll(bars int, clickSource string) {
+	if bars < 0 {
+		debug.Warnf("mix.SetPreRoll(%d) ignored: bars must not be negative", bars)
+		return
+	}
+	m.mixSetPreRoll(bars, 0, clickSource)
+}
+
+// SetPreRollDuration is SetPreRoll of a duration instead of a # of bars, with a click on each beat before the end of the pre-roll,
+// counting back from it; 0 for none.
+func (m *Mixer) SetPreRollDuration(d time.Duration, clickSource string) {
+	if d < 0 {
+		debug.Warnf("mix.SetPreRollDuration(%s) ignored: duration must not be negative", d)
+		return
+	}
+	m.mixSetPreRoll(0, d, clickSource)
+}
+
+// SetPreRollOutput to include the pre-roll in what OutputStart and OutputContinueTo write, e.g. a WAV of the count-in and the music:
+// OutputStart begins the pre-roll if it is not already playing, and lengthens the output by it. Else (the default) they skip any
+// pre-roll, such that the output begins at time zero.
+func (m *Mixer) SetPreRollOutput(include bool) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixPreRollOutput = include
+}
+
+//
+// Private
+//
+
+// mixClick of a pre-roll, at a Tz since it began
+type mixClick struct {
+	atTz   spec.Tz
+	volume float64
+}
+
+// mixSetPreRoll of a # of bars, or else a duration, loading its click source now, so it is ready for Start
+func (m *Mixer) mixSetPreRoll(bars int, d time.Duration, clickSource string) {
+	var key string
+	if clickSource != "" && (bars > 0 || d > 0) {
+		key = m.mixSourceKey(clickSource)
+		if err := m.mixPrepareSource(key); err != nil {
+			debug.Warnf("mix.SetPreRoll(%s) failed to load the click source: %s", clickSource, err)
+		}
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixPreRollBars = bars
+	m.mixPreRollDur = d
+	m.mixPreRollClick = key
+}
+
+// mixBeginPreRoll, if any, from its beginning, shifting the start time to its end; the caller must hold the mixMutex
+func (m *Mixer) mixBeginPreRoll() {
+	m.mixPreRollLeftTz = 0
+	m.mixPreRollClicks = m.mixPreRollClicks[:0]
+	beat := float64(time.Minute) / m.mixTempoMap[0].bpm
+	d := m.mixPreRollDur
+	if m.mixPreRollBars > 0 {
+		d = time.Duration(math.Round(float64(m.mixPreRollBars*PreRollBeatsPerBar) * beat))
+	}
+	m.mixPreRollTz = m.mixTzOf(d)
+	if m.mixPreRollTz == 0 {
+		return
+	}
+	for k := int(math.Floor(float64(d)/beat + 1e-9)); k >= 1; k-- { // counting back from the end, so the last click is a beat before zero
+		volume := PreRollVolume
+		if k%PreRollBeatsPerBar == 0 {
+			volume = PreRollAccent
+		}
+		at := m.mixTzOf(d - time.Duration(math.Round(float64(k)*beat)))
+		m.mixPreRollClicks = append(m.mixPreRollClicks, mixClick{at, volume})
+	}
+	m.mixPreRollLeftTz = m.mixPreRollTz
+	m.startAtTime = m.startAtTime.Add(d)
+}
+
+// mixSkipPreRoll that is playing, if any, moving the start time back to where the mix begins now; the caller must hold the mixMutex
+func (m *Mixer) mixSkipPreRoll() {
+	if m.mixPreRollLeftTz == 0 {
+		return
+	}
+	m.startAtTime = m.startAtTime.Add(-m.mixDurOf(m.mixPreRollLeftTz))
+	m.mixPreRollLeftTz = 0
+	m.mixPublishClock()
+}
+
+// mixNextPreRoll sample of the clicks, at the master volume, with the safety stage and dither of the output but none of the buses,
+// effects or mix algorithm; the caller must hold the mixMutex
+func (m *Mixer) mixNextPreRoll() []sample.Value {
+	at := m.mixPreRollTz - m.mixPreRollLeftTz
+	for c := range m.mixOutBuffer {
+		m.mixOutBuffer[c] = 0
+	}
+	if src := m.mixGetSource(m.mixPreRollClick); src != nil {
+		for _, click := range m.mixPreRollClicks {
+			if at < click.atTz || at >= click.atTz+src.Length() {
+				continue
+			}
+			mixSourceAt(m.mixFireBuffer, m.mixFireScratch, src, click.volume, 0, m.mixPanLaw, 1, 0, at-click.atTz, 0)
+			for c := range m.mixOutBuffer {
+				m.mixOutBuffer[c] += m.mixFireBuffer[c] * sample.Value(m.masterGain)
+			}
+		}
+	}
+	m.mixRampMasterGain()
+	sample.Clip(m.mixClipMode, m.mixOutBuffer)
+	m.mixDither.Apply(m.masterSpec.Format, m.mixOutBuffer)
+	m.mixPreRollLeftTz--
+	return m.mixOutBuffer
+}
+
+// mixPreRollAheadOfClock is true if the pre-roll has reached a clock that is not real; the caller must hold the mixMutex
+func (m *Mixer) mixPreRollAheadOfClock() bool {
+	since := m.mixNow().Sub(m.startAtTime.Add(-m.mixDurOf(m.mixPreRollTz)))
+	return since <= 0 || m.mixPreRollTz-m.mixPreRollLeftTz >= m.mixTzOf(since)
+}
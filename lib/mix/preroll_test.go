@@ -0,0 +1,80 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetPreRoll(t *testing.T) {
+	testMixSetup()
+	click := source.ToneKey(source.WaveSquare, 1000, 10*time.Millisecond)
+	SetPreRoll(1, click) // four beats at 120 bpm
+	SetFireTone(441, 0, 100*time.Millisecond, 1.0, 0)
+	StartAt(time.Now())
+	assert.Equal(t, -2*time.Second, GetNowAt())
+	out := make([]float64, 2*44100)
+	for n := range out {
+		out[n] = float64(NextSample()[0])
+		if n == 44100 {
+			assert.InDelta(t, float64(-time.Second), float64(GetNowAt()), float64(time.Millisecond))
+			ClearAllFires() // the clicks are not fires
+		}
+	}
+	assert.Equal(t, time.Duration(0), GetNowAt())
+	assert.NotEqual(t, float64(0), out[10])               // accent on the first beat
+	assert.Equal(t, float64(0), out[11025])               // between the clicks
+	assert.NotEqual(t, float64(0), out[3*22050+10])       // after ClearAllFires
+	assert.True(t, out[10] > 1.5*out[22050+10])           // louder than the other beats
+	assert.Equal(t, float64(0), float64(NextSample()[0])) // the fire was cleared
+	Teardown()
+	assert.Equal(t, time.Duration(0), GetNowAt())
+}
+
+func TestSetPreRoll_Schedule(t *testing.T) {
+	testMixSetup()
+	SetPreRollDuration(500*time.Millisecond, "") // silent, with no click source
+	f := SetFireTone(441, 0, 100*time.Millisecond, 1.0, 0)
+	StartAt(time.Now())
+	for n := 0; n < 22050; n++ {
+		assert.Equal(t, float64(0), float64(NextSample()[0]))
+	}
+	assert.Equal(t, time.Duration(0), GetNowAt())
+	for n := 0; n < 100; n++ {
+		NextSample()
+	}
+	assert.True(t, f.IsPlaying()) // time zero of the schedule is the end of the pre-roll
+}
+
+func TestSetPreRollOutput(t *testing.T) {
+	testMixSetup()
+	bind.UseOutput(opt.OutputWAV)
+	defer bind.UseOutput(opt.OutputNull)
+	for _, include := range []bool{false, true} {
+		m, err := New(spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 1})
+		assert.Nil(t, err)
+		m.SetPreRollDuration(100*time.Millisecond, source.ToneKey(source.WaveSquare, 1000, 10*time.Millisecond))
+		m.SetPreRollOutput(include)
+		var out bytes.Buffer
+		m.OutputStart(100*time.Millisecond, &out)
+		header := out.Len()
+		m.StartAt(time.Now())
+		m.OutputContinueTo(100 * time.Millisecond)
+		assert.Nil(t, m.OutputClose())
+		if include {
+			assert.Equal(t, 2*2205*4, out.Len()-header) // of the pre-roll and then the mix
+		} else {
+			assert.Equal(t, 2205*4, out.Len()-header)
+		}
+		assert.Equal(t, 100*time.Millisecond, m.GetNowAt())
+		m.Teardown()
+	}
+}
@@ -0,0 +1,45 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"io"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Reader pulls the mix output as bytes in a specific format, e.g. for libraries that expect an io.Reader of PCM.
+// The mix clock advances by exactly the number of frames read.
+type Reader struct {
+	Format spec.AudioFormat
+	Finite bool // if true, Read returns io.EOF once all fires have expired, else silence is produced indefinitely
+	// private
+	mixer   *Mixer
+	pending []byte // remainder of a frame that did not fit into the last Read
+	frame   []byte // reused to encode each frame
+}
+
+// NewReader of the mix output, encoded in a specific format
+func (m *Mixer) NewReader(format spec.AudioFormat) *Reader {
+	return &Reader{Format: format, mixer: m}
+}
+
+// Read the next frames of the mix output
+func (r *Reader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(r.pending) == 0 {
+			if r.Finite && r.mixer.FireCount() == 0 {
+				break
+			}
+			r.frame = sample.EncodeTo(r.frame[:0], r.Format, r.mixer.NextSample())
+			r.pending = r.frame
+		}
+		c := copy(p[n:], r.pending)
+		r.pending = r.pending[c:]
+		n += c
+	}
+	if n == 0 && len(p) > 0 {
+		err = io.EOF
+	}
+	return
+}
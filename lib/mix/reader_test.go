@@ -0,0 +1,38 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestReader(t *testing.T) {
+	testMixSetup()
+	r := NewReader(spec.AudioS16)
+	p := make([]byte, 101) // 50 frames of mono 16-bit, and half a frame
+	n, err := r.Read(p)
+	assert.Nil(t, err)
+	assert.Equal(t, 101, n)
+	assert.Equal(t, mixDefault.mixDurOf(51), GetNowAt())
+	n, err = r.Read(p[:1])
+	assert.Equal(t, 1, n)
+	assert.Equal(t, mixDefault.mixDurOf(51), GetNowAt())
+}
+
+func TestReader_Finite(t *testing.T) {
+	testMixSetup()
+	SetFire("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 0, 10*time.Millisecond, 1.0, 0)
+	r := NewReader(spec.AudioF32)
+	r.Finite = true
+	out, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.True(t, len(out) > 0)
+	_, err = r.Read(make([]byte, 4))
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,153 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+)
+
+// Render the mix offline, faster than realtime, for a length of time from the current playhead, as interleaved values of all channels.
+// The mix loop runs synchronously, regardless of the wall clock or transport, and holds off any other output while rendering,
+// so identical schedules render bit-identical output, e.g. after Teardown, or Stop to render from the top, see SetReplayLimit.
+func (m *Mixer) Render(length time.Duration) ([]float64, error) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if err := m.mixRenderable(); err != nil {
+		return nil, err
+	}
+	frames := m.mixRenderFrames(length)
+	out := make([]float64, 0, int(frames)*m.masterSpec.Channels)
+	err := m.mixRender(frames, func(smp []sample.Value) error {
+		for _, v := range smp {
+			out = append(out, float64(v))
+		}
+		return nil
+	})
+	return out, err
+}
+
+// RenderTo a writer, as Render, encoded in the format of the configured spec, e.g. to bounce to disk.
+func (m *Mixer) RenderTo(w io.Writer, length time.Duration) error {
+	return m.RenderCtx(context.Background(), length, w)
+}
+
+// RenderCtx is RenderTo until the context is done, e.g. when the client of a server disconnects; it is checked once per mix
+// cycle, after the cycle is flushed to the writer, so that a render canceled midway stops promptly, having written every complete
+// cycle, and returns the error of the context.
+func (m *Mixer) RenderCtx(ctx context.Context, length time.Duration, w io.Writer) error {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if err := m.mixRenderable(); err != nil {
+		return err
+	}
+	return m.mixRenderCtx(ctx, length, w, nil)
+}
+
+// RenderRange of the schedule to a writer, as a WAV in the format of the configured spec, of only the window from one time
+// to another, e.g. to punch in on one section of a long composition without rendering all of it; the WAV is exactly to - from long.
+// A fire that began before the window and is still sounding enters it at the right offset into its source, as if the mix had
+// played up to from, and a fire entirely outside the window is skipped. Afterward, the playhead is moved back to where it was.
+// The WAV has the metadata of SetOutputMetadata, if any.
+func (m *Mixer) RenderRange(from time.Duration, to time.Duration, w io.Writer) error {
+	if from < 0 || to <= from {
+		return fmt.Errorf("Cannot render from %s to %s (must end after it begins, at or after zero)", from, to)
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if err := m.mixRenderable(); err != nil {
+		return err
+	}
+	back := m.mixSchedDurOf(m.nowTz)
+	release := m.mixHoldReplay()
+	defer release()
+	m.mixSeekTo(from)
+	writer := wav.NewWriterWithMeta(w, wav.FormatFromSpec(m.masterSpec), m.mixDurOf(m.mixRenderFrames(to-from)), m.mixOutputMeta)
+	err := m.mixRenderCtx(context.Background(), to-from, writer, nil)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	m.mixSeekTo(back)
+	return err
+}
+
+//
+// Private
+//
+
+// mixRenderCtx to a writer, see RenderCtx, passing each sample to a function before it is encoded, unless it is nil;
+// the caller must hold the mixMutex
+func (m *Mixer) mixRenderCtx(ctx context.Context, length time.Duration, w io.Writer, each func(smp []sample.Value)) error {
+	buffer := bufio.NewWriter(w)
+	var frame []byte
+	encode := func(smp []sample.Value) error {
+		if each != nil {
+			each(smp)
+		}
+		frame = sample.EncodeTo(frame[:0], m.masterSpec.Format, smp)
+		_, err := buffer.Write(frame)
+		return err
+	}
+	for frames := m.mixRenderFrames(length); frames > 0; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cycle := m.masterCycleDurTz // until the end of the mix cycle in progress, if any
+		if m.nextCycleTz > m.nowTz {
+			cycle = m.nextCycleTz - m.nowTz
+		}
+		if cycle > frames {
+			cycle = frames
+		}
+		if err := m.mixRender(cycle, encode); err != nil {
+			return err
+		}
+		if err := buffer.Flush(); err != nil {
+			return err
+		}
+		frames -= cycle
+	}
+	return nil
+}
+
+func (m *Mixer) mixRenderable() error {
+	if m.masterSpec == nil || m.masterFreq <= 0 {
+		return errors.New("Must configure the mixer before rendering")
+	}
+	return nil
+}
+
+// mixRenderFrames of a duration of the schedule, to the nearest frame, exactly as many as OutputContinueTo outputs
+func (m *Mixer) mixRenderFrames(length time.Duration) spec.Tz {
+	return m.mixSchedTzOf(length)
+}
+
+// mixRender a # of frames as if playing, restoring the transport afterward, or returns the error of the mix loop if it has failed,
+// or fails now, see OnError; the caller must hold the mixMutex
+func (m *Mixer) mixRender(frames spec.Tz, each func(smp []sample.Value) error) (err error) {
+	if m.mixErr != nil {
+		return m.mixErr
+	}
+	previous := m.transport
+	m.transport = transportPlay
+	defer func() { m.transport = previous }()
+	defer func() {
+		if r := recover(); r != nil {
+			m.mixFail(r, mixPanicStack())
+			err = m.mixErr
+		}
+	}()
+	for n := spec.Tz(0); n < frames; n++ {
+		if err := each(m.mixNextSample()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
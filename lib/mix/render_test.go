@@ -0,0 +1,172 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/opt"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+)
+
+func TestRender(t *testing.T) {
+	testMixSetup()
+	testRenderSchedule()
+	first, err := Render(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 44100, len(first))
+	assert.Equal(t, float64(0), first[0])
+	assert.NotEqual(t, float64(0), first[44100/10+220])
+	assert.Equal(t, spec.Tz(44100), mixDefault.nowTz)
+	testMixSetup()
+	testRenderSchedule()
+	second, err := Render(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second) // bit-identical
+}
+
+func TestRender_Stopped(t *testing.T) {
+	testMixSetup()
+	testRenderSchedule()
+	Stop()
+	out, err := Render(time.Second)
+	assert.Nil(t, err)
+	assert.NotEqual(t, float64(0), out[44100/10+220]) // renders regardless of the transport
+	assert.False(t, IsPlaying())
+}
+
+func TestRender_NotConfigured(t *testing.T) {
+	testMixSetup()
+	mixDefault.masterFreq = 0 // simulates never having set a mix frequency
+	defer testMixSetup()
+	_, err := Render(time.Second)
+	assert.EqualError(t, err, "Must configure the mixer before rendering")
+}
+
+func TestRenderTo(t *testing.T) {
+	testMixSetup()
+	testRenderSchedule()
+	var buf bytes.Buffer
+	assert.Nil(t, RenderTo(&buf, 500*time.Millisecond))
+	assert.Equal(t, 22050*4, buf.Len()) // mono F32
+}
+
+func TestRenderRange(t *testing.T) {
+	testMixSetup()
+	testRenderSchedule()                              // a tone from 100ms to 200ms, entirely before the window, and a 1s source from 300ms, sounding into it
+	SetFireTone(441, 900*time.Millisecond, 0, 0.5, 0) // entirely after the window
+	var whole bytes.Buffer
+	assert.Nil(t, RenderTo(&whole, time.Second))
+	SeekTo(50 * time.Millisecond)
+	var punch bytes.Buffer
+	assert.Nil(t, RenderRange(400*time.Millisecond, 800*time.Millisecond, &punch))
+	assert.Equal(t, 50*time.Millisecond, GetNowAt()) // moved back
+	out, specs, err := wav.LoadBytes(punch.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, float64(44100), specs.Freq)
+	assert.Equal(t, 17640, len(out)) // exactly 400ms
+	assert.Equal(t, whole.Bytes()[17640*4:35280*4], punch.Bytes()[44:])
+	assert.EqualError(t, RenderRange(time.Second, time.Second, &punch), "Cannot render from 1s to 1s (must end after it begins, at or after zero)")
+}
+
+func TestRender_Frames(t *testing.T) {
+	testMixSetup()
+	defer testMixOutput(opt.OutputWAV)()
+	for _, d := range []time.Duration{350 * time.Millisecond, 10*time.Millisecond + 15*time.Microsecond} { // the latter is 441.66 frames
+		m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 1})
+		assert.Nil(t, err)
+		frames := int(m.mixSchedTzOf(d))
+		var out bytes.Buffer
+		m.OutputStart(d, &out)
+		m.OutputContinueTo(d)
+		assert.Nil(t, m.OutputClose())
+		assert.Equal(t, uint32(frames*2), binary.LittleEndian.Uint32(out.Bytes()[40:44]), "%s", d) // the size of the data, in the header
+		assert.Equal(t, frames*2, out.Len()-44, "%s", d)
+		rendered, err := m.Render(d)
+		assert.Nil(t, err)
+		assert.Equal(t, frames, len(rendered), "%s", d)
+		out.Reset()
+		assert.Nil(t, m.RenderRange(0, d, &out))
+		assert.Equal(t, uint32(frames*2), binary.LittleEndian.Uint32(out.Bytes()[40:44]), "%s", d)
+		assert.Equal(t, frames*2, out.Len()-44, "%s", d)
+		m.Teardown()
+	}
+}
+
+func TestRenderCtx(t *testing.T) {
+	testMixSetup()
+	SetCycleDuration(100 * time.Millisecond)
+	testRenderSchedule()
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &testCancelWriter{cancel: cancel}
+	assert.Equal(t, context.Canceled, RenderCtx(ctx, time.Second, w))
+	assert.Equal(t, 4410*4, w.buf.Len()) // flushed up to the last complete cycle
+	assert.Equal(t, spec.Tz(4410), mixDefault.nowTz)
+}
+
+func TestRenderCtx_Done(t *testing.T) {
+	testMixSetup()
+	testRenderSchedule()
+	var buf bytes.Buffer
+	assert.Nil(t, RenderCtx(context.Background(), 500*time.Millisecond, &buf))
+	assert.Equal(t, 22050*4, buf.Len())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, RenderCtx(ctx, 500*time.Millisecond, &buf))
+	assert.Equal(t, 22050*4, buf.Len())
+}
+
+//
+// Private
+//
+
+// testCancelWriter cancels a context on its first write
+type testCancelWriter struct {
+	buf    bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *testCancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.buf.Write(p)
+}
+
+func testRenderSchedule() {
+	SetFireTone(441, 100*time.Millisecond, 100*time.Millisecond, 0.5, 0)
+	SetFire("../source/testdata/Signed16bitLittleEndian44100HzMono.wav", 300*time.Millisecond, 0, 0.5, 0)
+}
+
+func TestRender_Channels(t *testing.T) {
+	for _, channels := range []int{1, 2, 4} {
+		Teardown()
+		Configure(spec.AudioSpec{
+			Freq:     44100,
+			Format:   spec.AudioF32,
+			Channels: channels,
+		})
+		SetFireTone(441, 0, 100*time.Millisecond, 0.5, 0)
+		SetFire("../source/testdata/Float32bitLittleEndian48000HzEstéreo.wav", 0, 0, 0.5, 0)
+		out, err := Render(100 * time.Millisecond)
+		assert.Nil(t, err)
+		assert.Equal(t, 4410*channels, len(out))
+	}
+	Teardown()
+	Configure(spec.AudioSpec{
+		Freq:     44100,
+		Format:   spec.AudioF32,
+		Channels: 4,
+	})
+	SetFireTone(441, 0, 100*time.Millisecond, 0.5, 0)
+	out, err := Render(100 * time.Millisecond)
+	assert.Nil(t, err)
+	for frame := 0; frame < len(out); frame += 4 {
+		assert.Equal(t, out[frame], out[frame+3]) // a mono source spreads across all outputs
+	}
+	testMixSetup()
+}
@@ -0,0 +1,63 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// SetReplayLimit of the fires that are done, to keep so that Stop, or SeekTo back before them, plays them again, e.g. in an editor
+// that auditions a song from the top; the fires that finished first are released first. 0 (the default) releases each fire as soon as
+// it is done, such that a long live session does not grow without bound, and -1 keeps every fire, e.g. a whole song set up front.
+// Render of a range, or normalized, always replays what it rendered to move the playhead back, whatever the limit.
+func (m *Mixer) SetReplayLimit(fires int) {
+	if fires < -1 {
+		debug.Warnf("mix.SetReplayLimit(%d) ignored: must be -1 for no limit, or not negative", fires)
+		return
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixReplayLimit = fires
+	m.mixTrimDoneFires()
+}
+
+// GetReplayLimit of the fires that are done, see SetReplayLimit
+func (m *Mixer) GetReplayLimit() int {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixReplayLimit
+}
+
+//
+// Private
+//
+
+// mixRetire a fire that is done, kept for replay unless the replay limit is 0, see SetReplayLimit; the caller must hold the mixMutex,
+// and trim the done fires after retiring a batch
+func (m *Mixer) mixRetire(f *fire.Fire) {
+	if m.mixReplayLimit != 0 || m.mixReplayHeld > 0 {
+		m.mixDoneFires = append(m.mixDoneFires, f)
+	}
+}
+
+// mixTrimDoneFires to the replay limit, releasing those that finished first; the caller must hold the mixMutex
+func (m *Mixer) mixTrimDoneFires() {
+	if m.mixReplayLimit < 0 || m.mixReplayHeld > 0 || len(m.mixDoneFires) <= m.mixReplayLimit {
+		return
+	}
+	kept := copy(m.mixDoneFires, m.mixDoneFires[len(m.mixDoneFires)-m.mixReplayLimit:])
+	for i := kept; i < len(m.mixDoneFires); i++ {
+		m.mixDoneFires[i] = nil // release the fire to the garbage collector
+	}
+	m.mixDoneFires = m.mixDoneFires[:kept]
+}
+
+// mixHoldReplay of every fire that is done, whatever the replay limit, until the func returned is called, e.g. to render ahead and
+// then move the playhead back; the caller must hold the mixMutex, also when it calls the func
+func (m *Mixer) mixHoldReplay() (release func()) {
+	m.mixReplayHeld++
+	return func() {
+		m.mixReplayHeld--
+		m.mixTrimDoneFires()
+	}
+}
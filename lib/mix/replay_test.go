@@ -0,0 +1,71 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetReplayLimit(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, 0, GetReplayLimit())
+	testReplaySchedule()
+	_, err := Render(1100 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mixDefault.mixDoneFires)) // each released as it ended
+	assert.Equal(t, 0, FireCount())
+	Stop()
+	assert.Equal(t, 0, FireCount())
+	// capped
+	testMixSetup()
+	SetReplayLimit(20)
+	testReplaySchedule()
+	for n := 0; n < 110; n++ {
+		_, err = Render(10 * time.Millisecond)
+		assert.Nil(t, err)
+		assert.True(t, len(mixDefault.mixDoneFires) <= 20, "%d fires retained", len(mixDefault.mixDoneFires))
+	}
+	assert.Equal(t, 20, len(mixDefault.mixDoneFires))
+	Stop()
+	assert.Equal(t, 20, FireCount()) // the last to finish replay
+	assert.Equal(t, 180*5*time.Millisecond, Fires()[0].BeginAt())
+	// unlimited
+	testMixSetup()
+	SetReplayLimit(-1)
+	testReplaySchedule()
+	_, err = Render(1100 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, len(mixDefault.mixDoneFires))
+	SetReplayLimit(10)
+	assert.Equal(t, 10, len(mixDefault.mixDoneFires))
+	SetReplayLimit(-2)
+	assert.Equal(t, 10, GetReplayLimit())
+}
+
+func TestSetReplayLimit_RenderRange(t *testing.T) {
+	testMixSetup()
+	testReplaySchedule()
+	var wav bytes.Buffer
+	assert.Nil(t, RenderRange(500*time.Millisecond, 600*time.Millisecond, &wav)) // renders ahead, and moves back
+	assert.Equal(t, 200, FireCount())
+	assert.Equal(t, 0, len(mixDefault.mixDoneFires))
+}
+
+//
+// Private
+//
+
+// testReplaySchedule of 200 fires, one every 5ms, each 2ms long
+func testReplaySchedule() {
+	src := source.ToneKey(source.WaveSine, 441, 2*time.Millisecond)
+	batch := make([]FireSpec, 200)
+	for i := range batch {
+		batch[i] = FireSpec{Source: src, Begin: time.Duration(i) * 5 * time.Millisecond, Volume: 1}
+	}
+	SetFires(batch)
+}
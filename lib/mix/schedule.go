@@ -0,0 +1,259 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// SetScheduleLowWater to call fn whenever the latest scheduled fire begins less than d ahead of the mix time,
+// e.g. to append the next bar of generated music just in time, or nil fn to stop.
+//
+// fn is called on its own goroutine, never the mix loop, and never concurrently with itself. It receives the schedule horizon:
+// the begin time of the latest scheduled fire, or the mix time if that is later, after which to append the next fires.
+// It is re-armed when it returns; if it scheduled nothing later, it is not called again until a mix cycle has passed.
+func (m *Mixer) SetScheduleLowWater(d time.Duration, fn func(horizon time.Duration)) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixLowWaterTz = m.mixSchedTzOf(d)
+	m.mixLowWaterFn = fn
+	m.mixLowWaterLastTz = 0
+	m.mixLowWaterRetryTz = 0
+	m.mixRunLowWater(fn != nil)
+}
+
+// ScheduleVersion of the format written by ExportSchedule; ImportSchedule reads any version up to this one.
+const ScheduleVersion = 2
+
+// ExportSchedule as JSON, of the fires that have not yet begun, nor been canceled, in order of their beginning:
+// an object with the "version" of the format, and the "fires", each a fire.Record of its source (resolved like SetFire)
+// and all of its settings, including a sustain that follows the tempo, see SetSustainFollowsTempo; every time is an integer #
+// of nanoseconds. A fire that waits on a previous fire, see SetFireAfter, follows the rest, with the index of that one and the gap.
+func (m *Mixer) ExportSchedule(w io.Writer) error {
+	m.mixMutex.Lock()
+	var records []fire.Record
+	var fires []*fire.Fire
+	add := func(f *fire.Fire) {
+		if !f.IsCanceled() {
+			records = append(records, m.mixRecordOf(f))
+			fires = append(fires, f)
+		}
+	}
+	for _, f := range m.mixLiveFires {
+		if f.BeginTz > m.nowTz {
+			add(f)
+		}
+	}
+	m.mixReadyFires.Each(add)
+	schedule := mixScheduleJSON{Version: ScheduleVersion, Fires: m.mixRecordChains(records, fires, 0)}
+	m.mixMutex.Unlock()
+	return json.NewEncoder(w).Encode(schedule)
+}
+
+// ImportSchedule from JSON written by ExportSchedule, setting each fire later by an offset, e.g. to append a saved pattern.
+// Returns an error, having set none of the fires, if the JSON cannot be read or any of the sources cannot be loaded.
+func (m *Mixer) ImportSchedule(r io.Reader, offset time.Duration) error {
+	var schedule mixScheduleJSON
+	if err := json.NewDecoder(r).Decode(&schedule); err != nil {
+		return err
+	}
+	if schedule.Version < 1 || schedule.Version > ScheduleVersion {
+		return fmt.Errorf("Unsupported schedule version: %d (must be 1 to %d)", schedule.Version, ScheduleVersion)
+	}
+	fires := make([]*fire.Fire, len(schedule.Fires))
+	for i, record := range schedule.Fires {
+		begin := record.Begin + offset
+		if record.After != nil {
+			begin = 0 // not yet known
+		}
+		f, err := m.mixFireOfRecord(record, begin)
+		if err != nil {
+			return err
+		}
+		fires[i] = f
+	}
+	m.mixMutex.Lock()
+	for i, record := range schedule.Fires {
+		m.mixRestoreSustain(fires[i], record)
+	}
+	m.mixMutex.Unlock()
+	m.mixScheduleChains(schedule.Fires, fires)
+	return nil
+}
+
+// ValidateSchedule of the fires that have not yet begun, nor been canceled, e.g. before a performance, checking that the source of
+// each can still be loaded before its moment arrives: a fire is only set if its source loads, but its file may since have been
+// removed or changed while it was out of memory. Each source is checked once, a file by its header, without decoding all of it.
+// Returns an error for each fire whose source cannot be loaded, with its begin time and source name, in order of their beginning.
+func (m *Mixer) ValidateSchedule() (errs []error) {
+	type pending struct {
+		key   string
+		name  string
+		begin time.Duration
+	}
+	var fires []pending
+	add := func(f *fire.Fire) {
+		if !f.IsCanceled() {
+			fires = append(fires, pending{f.Source, m.mixSourceName(f.Source), m.mixSchedDurOf(f.BeginTz)})
+		}
+	}
+	m.mixMutex.Lock()
+	for _, f := range m.mixLiveFires {
+		if f.BeginTz > m.nowTz {
+			add(f)
+		}
+	}
+	m.mixReadyFires.Each(add)
+	m.mixMutex.Unlock()
+	sort.SliceStable(fires, func(i, j int) bool {
+		return fires[i].begin < fires[j].begin
+	})
+	checked := make(map[string]error)
+	for _, f := range fires {
+		err, ok := checked[f.key]
+		if !ok {
+			err = m.mixCheckSource(f.key)
+			checked[f.key] = err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Cannot load %s for the fire at %s: %s", f.name, f.begin, err))
+		}
+	}
+	return
+}
+
+//
+// Private
+//
+
+// mixScheduleJSON is the versioned envelope of an exported schedule
+type mixScheduleJSON struct {
+	Version int           `json:"version"`
+	Fires   []fire.Record `json:"fires"`
+}
+
+// mixRecordOf a fire, with the name of its source as it would be set, and its sustain in steps if it follows the tempo;
+// the caller must hold the mixMutex
+func (m *Mixer) mixRecordOf(f *fire.Fire) fire.Record {
+	record := f.Record()
+	record.Source = m.mixSourceName(record.Source)
+	if s, ok := m.mixSustains[f]; ok {
+		record.TempoSustain = &fire.RecordSteps{Step: s.step, Steps: s.steps}
+	}
+	return record
+}
+
+// mixFireOfRecord at a begin time, restored from its record, at a fraction of a sample if it was, but not yet scheduled
+func (m *Mixer) mixFireOfRecord(record fire.Record, begin time.Duration) (*fire.Fire, error) {
+	f, err := m.mixNewFire(record.Source, begin, record.Sustain, record.Volume, record.Pan)
+	if err != nil {
+		return nil, err
+	}
+	f.Restore(record)
+	if record.SubSample {
+		m.mixMutex.Lock()
+		_, f.BeginFrac = m.mixBeginTzOf(begin)
+		m.mixMutex.Unlock()
+	}
+	return f, nil
+}
+
+// mixRestoreSustain of a fire from its record, to follow the tempo as it goes live if it did; the caller must hold the mixMutex
+func (m *Mixer) mixRestoreSustain(f *fire.Fire, record fire.Record) {
+	if record.TempoSustain != nil {
+		m.mixSustains[f] = mixSustain{record.TempoSustain.Step, record.TempoSustain.Steps}
+	}
+}
+
+// mixCheckLowWater once per sample, and signal the callback if the schedule is running low; it makes no allocations.
+// The caller must hold the mixMutex
+func (m *Mixer) mixCheckLowWater() {
+	if m.mixLowWaterFn == nil || m.mixLowWaterPending || m.mixHorizonTz >= m.nowTz+m.mixLowWaterTz {
+		return
+	}
+	if m.mixHorizonTz == m.mixLowWaterLastTz && m.nowTz < m.mixLowWaterRetryTz {
+		return
+	}
+	m.mixLowWaterPending = true
+	m.mixLowWaterLastTz = m.mixHorizonTz
+	m.mixLowWaterRetryTz = m.nowTz + m.masterCycleDurTz
+	select {
+	case m.mixLowWaterSignal <- struct{}{}:
+	default:
+	}
+}
+
+// mixRunLowWater loop on or off, e.g. off by the teardown, such that a mixer that is torn down leaks no goroutine;
+// the caller must hold the mixMutex
+func (m *Mixer) mixRunLowWater(on bool) {
+	if on == (m.mixLowWaterStop != nil) {
+		return
+	}
+	if !on {
+		close(m.mixLowWaterStop)
+		m.mixLowWaterStop = nil
+		m.mixLowWaterPending = false // if signaled, it is never received
+		return
+	}
+	m.mixLowWaterStop = make(chan struct{})
+	m.mixLowWaterDone = make(chan struct{})
+	go m.mixLowWaterLoop(m.mixLowWaterStop, m.mixLowWaterDone)
+}
+
+// mixLowWaterLoop calls the callback each time it is signaled, until stopped, on one goroutine, so never concurrently with itself
+func (m *Mixer) mixLowWaterLoop(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-m.mixLowWaterSignal:
+		}
+		m.mixMutex.Lock()
+		fn := m.mixLowWaterFn
+		horizon := m.mixSchedDurOf(m.mixHorizonTz)
+		if m.mixHorizonTz < m.nowTz {
+			horizon = m.mixSchedDurOf(m.nowTz)
+		}
+		m.mixMutex.Unlock()
+		if fn != nil {
+			fn(horizon)
+		}
+		m.mixMutex.Lock()
+		m.mixLowWaterPending = false
+		m.mixMutex.Unlock()
+	}
+}
+
+// mixRecountHorizon after fires are removed; the caller must hold the mixMutex
+func (m *Mixer) mixRecountHorizon() {
+	m.mixHorizonTz = 0
+	m.mixReadyFires.Each(func(f *fire.Fire) {
+		if f.BeginTz > m.mixHorizonTz {
+			m.mixHorizonTz = f.BeginTz
+		}
+	})
+	for _, f := range m.mixLiveFires {
+		if !f.IsCanceled() && f.BeginTz > m.mixHorizonTz {
+			m.mixHorizonTz = f.BeginTz
+		}
+	}
+}
+
+// mixCheckSource of a fire, or each of the variants of its multi-sample source, see source.Cache.Check
+func (m *Mixer) mixCheckSource(src string) (err error) {
+	if m.mixMultiSource(src) == nil {
+		return m.cache.Check(src)
+	}
+	m.mixEachVariant(src, func(key string) {
+		if checkErr := m.cache.Check(key); err == nil {
+			err = checkErr
+		}
+	})
+	return
+}
@@ -0,0 +1,253 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetScheduleLowWater(t *testing.T) {
+	testMixSetup()
+	defer SetScheduleLowWater(0, nil)
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	var inFlight int32
+	var horizons []time.Duration
+	SetScheduleLowWater(500*time.Millisecond, func(horizon time.Duration) {
+		assert.Equal(t, int32(1), atomic.AddInt32(&inFlight, 1))
+		defer atomic.AddInt32(&inFlight, -1)
+		horizons = append(horizons, horizon)
+		SetFire(src, horizon+100*time.Millisecond, 0, 1.0, 0)
+	})
+	for n := 0; n < 2*44100; n++ {
+		NextSample()
+		testWaitLowWater()
+	}
+	assert.True(t, len(horizons) > 20)
+	for i := 1; i < len(horizons); i++ {
+		assert.True(t, horizons[i] > horizons[i-1])
+	}
+	assert.True(t, mixDefault.mixHorizonTz+1 >= mixDefault.nowTz+mixDefault.mixTzOf(500*time.Millisecond))
+}
+
+func TestSetScheduleLowWater_Retry(t *testing.T) {
+	testMixSetup()
+	defer SetScheduleLowWater(0, nil)
+	var calls int32
+	SetScheduleLowWater(500*time.Millisecond, func(horizon time.Duration) {
+		atomic.AddInt32(&calls, 1) // schedules nothing, so it is called again only after each mix cycle
+	})
+	for n := 0; n < 2*44100+22050; n++ {
+		NextSample()
+		testWaitLowWater()
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestSetScheduleLowWater_Teardown(t *testing.T) {
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	m.SetScheduleLowWater(time.Second, func(horizon time.Duration) {})
+	m.mixMutex.Lock()
+	done := m.mixLowWaterDone
+	m.mixMutex.Unlock()
+	m.Teardown()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("low water loop did not exit")
+	}
+	m.SetScheduleLowWater(time.Second, func(horizon time.Duration) {}) // runs again after the teardown
+	m.mixMutex.Lock()
+	assert.NotNil(t, m.mixLowWaterStop)
+	m.mixMutex.Unlock()
+	m.SetScheduleLowWater(0, nil)
+}
+
+//
+// Test Components
+//
+
+// testWaitLowWater until the callback returns, as if it always kept up with the mix loop
+func testWaitLowWater() {
+	for {
+		mixDefault.mixMutex.Lock()
+		pending := mixDefault.mixLowWaterPending
+		mixDefault.mixMutex.Unlock()
+		if !pending {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestExportSchedule(t *testing.T) {
+	testMixSetup()
+	SetSoundsPath("../source/testdata/")
+	defer SetSoundsPath("")
+	tone := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	past := SetFire(tone, 0, 0, 1.0, 0)
+	SetFireOnBus(NewBus("drums"), "Signed16bitLittleEndian44100HzMono.wav", 2*time.Second, 0, 0.5, -0.5)
+	looped := SetFireLoop(tone, time.Second, 250*time.Millisecond, 4, 100*time.Millisecond, 0.8, 0.25)
+	looped.SetRate(2)
+	looped.SetFades(time.Millisecond, 5*time.Millisecond)
+	looped.SetVolumeEnvelope([]fire.EnvelopePoint{EnvelopePoint(0, 0), EnvelopePoint(50*time.Millisecond, 1)})
+	SetFire(tone, 3*time.Second, 0, 1.0, 0).Cancel()
+	for n := 0; n < 4410; n++ {
+		NextSample()
+	}
+	assert.True(t, past.IsPlaying())
+	var exported bytes.Buffer
+	assert.Nil(t, ExportSchedule(&exported))
+	// round trip
+	testMixSetup()
+	assert.Nil(t, ImportSchedule(bytes.NewReader(exported.Bytes()), 0))
+	assert.Equal(t, 2, FireCountReady())
+	var reexported bytes.Buffer
+	assert.Nil(t, ExportSchedule(&reexported))
+	assert.JSONEq(t, exported.String(), reexported.String())
+	var schedule mixScheduleJSON
+	assert.Nil(t, json.Unmarshal(exported.Bytes(), &schedule))
+	assert.Equal(t, ScheduleVersion, schedule.Version)
+	assert.Equal(t, 2, len(schedule.Fires))
+	assert.Equal(t, looped.Record(), schedule.Fires[0])
+	assert.Equal(t, "Signed16bitLittleEndian44100HzMono.wav", schedule.Fires[1].Source)
+	assert.Equal(t, "drums", schedule.Fires[1].Bus)
+	// offset
+	testMixSetup()
+	assert.Nil(t, ImportSchedule(bytes.NewReader(exported.Bytes()), time.Minute))
+	fires := Fires()
+	assert.Equal(t, time.Minute+time.Second, fires[0].BeginAt())
+	assert.Equal(t, time.Minute+2*time.Second, fires[1].BeginAt())
+}
+
+func TestExportSchedule_RoundTrip(t *testing.T) {
+	tone := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	for _, tc := range []struct {
+		name  string
+		set   func(m *Mixer) *fire.Fire
+		check func(t *testing.T, m *Mixer, f *fire.Fire)
+	}{
+		{"loopXFade", func(m *Mixer) *fire.Fire {
+			return m.SetFireLoopXFade(tone, time.Second, 20*time.Millisecond, 1.0, 0)
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			assert.True(t, f.LoopsForever())
+			assert.Equal(t, spec.Tz(882), f.XFadeTz)
+		}},
+		{"tone", func(m *Mixer) *fire.Fire {
+			f := m.SetFire(tone, time.Second, 0, 1.0, 0)
+			f.SetTone(-6, 4)
+			return f
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			low, high := f.Tone()
+			assert.Equal(t, float64(-6), low)
+			assert.Equal(t, float64(4), high)
+		}},
+		{"choke", func(m *Mixer) *fire.Fire {
+			f := m.SetFire(tone, time.Second, 0, 1.0, 0)
+			f.SetChoke("hats")
+			return f
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			assert.Equal(t, "hats", f.ChokeGroup())
+		}},
+		{"subSample", func(m *Mixer) *fire.Fire {
+			m.SetSubSamplePrecision(true)
+			return m.SetFire(tone, 10*time.Millisecond+11338*time.Nanosecond, 0, 1.0, 0) // and half a sample
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			assert.False(t, m.GetSubSamplePrecision()) // honored all the same
+			assert.Equal(t, spec.Tz(441), f.BeginTz)
+			assert.InDelta(t, 0.5, f.BeginFrac, 0.001)
+		}},
+		{"humanize", func(m *Mixer) *fire.Fire {
+			f := m.SetFire(tone, time.Second, 0, 1.0, 0)
+			f.SetHumanize(HumanizeSpec{VolumeJitter: 0.1, TimingJitter: 5 * time.Millisecond})
+			return f
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			h, ok := f.Humanize()
+			assert.True(t, ok)
+			assert.Equal(t, HumanizeSpec{VolumeJitter: 0.1, TimingJitter: 5 * time.Millisecond}, h)
+		}},
+		{"humanized", func(m *Mixer) *fire.Fire {
+			f := m.SetFire(tone, time.Second, 0, 0.5, 0)
+			f.ApplyHumanize(HumanizeSpec{}, nil, 0) // as it goes live
+			return f
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			m.SetHumanize(HumanizeSpec{VolumeJitter: 0.5})
+			m.OutputContinueTo(1100 * time.Millisecond)
+			assert.Equal(t, 0.5, f.Volume) // not varied again
+		}},
+		{"tempoSustain", func(m *Mixer) *fire.Fire {
+			m.SetSustainFollowsTempo(true)
+			return m.SetFireAtStep(tone, 4, 2, 1.0, 0)
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			assert.Equal(t, mixSustain{4, 2}, m.mixSustains[f])
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer a.Teardown()
+			assert.NotNil(t, tc.set(a))
+			var exported bytes.Buffer
+			assert.Nil(t, a.ExportSchedule(&exported))
+			b, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer b.Teardown()
+			assert.Nil(t, b.ImportSchedule(bytes.NewReader(exported.Bytes()), 0))
+			var reexported bytes.Buffer
+			assert.Nil(t, b.ExportSchedule(&reexported))
+			assert.JSONEq(t, exported.String(), reexported.String())
+			fires := b.Fires()
+			assert.Equal(t, 1, len(fires))
+			tc.check(t, b, fires[0])
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	testMixSetup()
+	dir, err := ioutil.TempDir("", "mix-validate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile("../source/testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "kick.wav"), data, 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "snare.wav"), data, 0644))
+	SetSoundsPath(dir + "/")
+	defer SetSoundsPath("")
+	SetFire("kick.wav", 2*time.Second, 0, 1.0, 0)
+	SetFire("kick.wav", time.Second, 0, 1.0, 0)
+	SetFire("snare.wav", time.Second, 0, 1.0, 0)
+	SetFire(source.ToneKey(source.WaveSine, 441, time.Second), time.Second, 0, 1.0, 0)
+	SetFire("snare.wav", 3*time.Second, 0, 1.0, 0).Cancel()
+	assert.Nil(t, ValidateSchedule())
+	assert.Nil(t, os.Remove(filepath.Join(dir, "kick.wav")))
+	assert.Nil(t, os.Remove(filepath.Join(dir, "snare.wav")))
+	assert.Nil(t, ValidateSchedule()) // still in memory
+	mixDefault.cache.Evict(filepath.Join(dir, "kick.wav"))
+	errs := ValidateSchedule()
+	assert.Equal(t, 2, len(errs))
+	assert.True(t, strings.HasPrefix(errs[0].Error(), "Cannot load kick.wav for the fire at 1s: "))
+	assert.True(t, strings.HasPrefix(errs[1].Error(), "Cannot load kick.wav for the fire at 2s: "))
+}
+
+func TestImportSchedule_FAIL(t *testing.T) {
+	testMixSetup()
+	assert.NotNil(t, ImportSchedule(strings.NewReader(`{"version":3,"fires":[]}`), 0))
+	assert.NotNil(t, ImportSchedule(strings.NewReader(`[]`), 0))
+	assert.NotNil(t, ImportSchedule(strings.NewReader(`{"version":1,"fires":[{"source":"ThisDoesNotExist.wav"},{"source":"`+source.ToneKey(source.WaveSine, 441, time.Second)+`"}]}`), 0))
+	assert.Equal(t, 0, FireCount())
+}
@@ -0,0 +1,299 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// SessionVersion of the format written by SaveSession; ReadSession reads any version up to this one.
+const SessionVersion = 2
+
+// Session of a mixer, as saved by SaveSession, with everything needed to resume it from where it was saved, e.g. after a crash,
+// or as a project file: its spec, the sources it refers to by name, but never their audio, its tempo, its master and bus levels,
+// and the fires that had not yet ended, with all of their settings, at times relative to the mix time at which it was saved; a fire
+// that was playing begins then, from where it was in its source, see fire.RecordFrom.
+type Session struct {
+	Version      int                `json:"version"`
+	Freq         float64            `json:"freq"` // of the spec requested of Configure
+	Format       spec.AudioFormat   `json:"format"`
+	Channels     int                `json:"channels"`
+	SoundsPath   string             `json:"soundsPath,omitempty"`
+	Sources      []string           `json:"sources"` // by name, of the fires, sorted
+	MultiSources map[string][]Layer `json:"multiSources,omitempty"`
+	Tempo        SessionTempo       `json:"tempo"`
+	MasterVolume float64            `json:"masterVolume"`
+	Muted        bool               `json:"muted,omitempty"`
+	Buses        []SessionBus       `json:"buses,omitempty"`
+	Fires        []fire.Record      `json:"fires"` // each begins relative to the mix time at which the session was saved
+}
+
+// SessionTempo of a session, see SetTempo; its step offset is relative to the mix time at which the session was saved
+type SessionTempo struct {
+	BPM          float64              `json:"bpm"`
+	StepsPerBeat int                  `json:"stepsPerBeat"`
+	Changes      []SessionTempoChange `json:"changes,omitempty"`
+	Swing        float64              `json:"swing,omitempty"`
+	Groove       []time.Duration      `json:"groove,omitempty"`
+	StepOffset   time.Duration        `json:"stepOffset"`
+	SustainTempo bool                 `json:"sustainTempo,omitempty"` // see SetSustainFollowsTempo
+}
+
+// SessionTempoChange of a session, see AddTempoChange
+type SessionTempoChange struct {
+	Step int     `json:"step"`
+	BPM  float64 `json:"bpm"`
+}
+
+// SessionBus of a session, by name, with its levels, but not its effects
+type SessionBus struct {
+	Name   string  `json:"name"`
+	Volume float64 `json:"volume"`
+	Pan    float64 `json:"pan"`
+	Muted  bool    `json:"muted,omitempty"`
+	Soloed bool    `json:"soloed,omitempty"`
+}
+
+// Spec of the session, as requested of Configure
+func (s *Session) Spec() spec.AudioSpec {
+	return spec.AudioSpec{Freq: s.Freq, Format: s.Format, Channels: s.Channels}
+}
+
+// SessionSourceErrors of the sources of a session that could not be loaded, by name; none of their fires were set
+type SessionSourceErrors map[string]error
+
+func (e SessionSourceErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	each := make([]string, len(names))
+	for i, name := range names {
+		each[i] = fmt.Sprintf("%s: %s", name, e[name])
+	}
+	return fmt.Sprintf("Cannot load %d sources of the session: %s", len(names), strings.Join(each, "; "))
+}
+
+// SaveSession as JSON, of the Session of the mixer now, e.g. for crash recovery, or a project file. The fires that are playing are
+// saved to resume from where they are, and the fires that wait on another, see SetFireAfter, to wait on it again; the fires that are
+// done, and the effects, are not saved.
+func (m *Mixer) SaveSession(w io.Writer) error {
+	m.mixMutex.Lock()
+	s := Session{
+		Version:      SessionVersion,
+		Freq:         m.mixRequestedSpec.Freq,
+		Format:       m.mixRequestedSpec.Format,
+		Channels:     m.mixRequestedSpec.Channels,
+		SoundsPath:   m.mixSourcePrefix,
+		Sources:      make([]string, 0),
+		MasterVolume: m.masterVolume,
+		Muted:        m.masterMuted,
+		Fires:        make([]fire.Record, 0),
+	}
+	now := m.mixSchedDurOf(m.nowTz)
+	s.Tempo = SessionTempo{
+		BPM:          m.mixTempoMap[0].bpm,
+		StepsPerBeat: m.mixStepsPerBeat,
+		Swing:        m.mixSwing,
+		Groove:       append([]time.Duration(nil), m.mixGroove...),
+		StepOffset:   m.mixStepOffset - now,
+		SustainTempo: m.mixSustainTempo,
+	}
+	for _, t := range m.mixTempoMap[1:] {
+		s.Tempo.Changes = append(s.Tempo.Changes, SessionTempoChange{Step: t.step, BPM: t.bpm})
+	}
+	for _, b := range m.mixBusList {
+		s.Buses = append(s.Buses, SessionBus{Name: b.name, Volume: b.volume, Pan: b.pan, Muted: b.muted, Soloed: b.soloed})
+	}
+	var fires []*fire.Fire
+	add := func(f *fire.Fire) {
+		if !f.IsCanceled() {
+			record := m.mixRecordOf(f)
+			record.Begin -= now
+			s.Fires = append(s.Fires, record)
+			fires = append(fires, f)
+		}
+	}
+	for _, f := range m.mixLiveFires {
+		if f.BeginTz > m.nowTz {
+			add(f)
+		} else if f.IsPlaying() {
+			for _, record := range f.RecordFrom(m.nowTz) {
+				record.Source = m.mixSourceName(record.Source)
+				if record.Begin -= now; record.Begin < 0 {
+					record.Begin = 0 // of rounding, it resumes now
+				}
+				s.Fires = append(s.Fires, record)
+				fires = append(fires, nil) // nothing waits on it, but on the fire that is playing
+			}
+		}
+	}
+	m.mixReadyFires.Each(add)
+	s.Fires = m.mixRecordChains(s.Fires, fires, now)
+	m.mixMutex.Unlock()
+	names := make(map[string]bool)
+	for _, record := range s.Fires {
+		names[record.Source] = true
+	}
+	for name := range names {
+		s.Sources = append(s.Sources, name)
+		if multi := m.mixMultiSource(name); multi != nil {
+			if s.MultiSources == nil {
+				s.MultiSources = make(map[string][]Layer)
+			}
+			s.MultiSources[name] = m.mixLayersOf(multi)
+		}
+	}
+	sort.Strings(s.Sources)
+	return json.NewEncoder(w).Encode(s)
+}
+
+// ReadSession from JSON written by SaveSession, e.g. to configure the output by its spec before RestoreSession
+func ReadSession(r io.Reader) (*Session, error) {
+	var s Session
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version < 1 || s.Version > SessionVersion {
+		return nil, fmt.Errorf("Unsupported session version: %d (must be 1 to %d)", s.Version, SessionVersion)
+	}
+	return &s, nil
+}
+
+// LoadSession from JSON written by SaveSession, configuring the mixer by its spec if it is not yet configured, see RestoreSession
+func (m *Mixer) LoadSession(r io.Reader) error {
+	s, err := ReadSession(r)
+	if err != nil {
+		return err
+	}
+	if m.Spec() == nil {
+		configure := s.Spec()
+		if err = configure.Validate(); err != nil {
+			return err
+		}
+		m.Configure(configure)
+	}
+	return m.RestoreSession(s)
+}
+
+// RestoreSession to the mixer, replacing its schedule by the fires of the session, each later by the mix time now, e.g. zero
+// before Start, so that it resumes from where it was saved; and setting its sounds path, multi-sample sources, tempo, and master and
+// bus levels. Each source is loaded by name, e.g. a registered source must be registered again beforehand; if any cannot be
+// loaded, the rest of the session is restored, and SessionSourceErrors are returned, of each source whose fires were not set.
+func (m *Mixer) RestoreSession(s *Session) error {
+	errs := make(SessionSourceErrors)
+	m.SetSoundsPath(s.SoundsPath)
+	for name, layers := range s.MultiSources {
+		if err := m.RegisterMultiSource(name, layers); err != nil {
+			errs[name] = err
+		}
+	}
+	for _, name := range s.Sources {
+		if _, failed := errs[name]; failed {
+			continue
+		}
+		if err := m.mixCheckSource(m.mixSourceKey(name)); err != nil {
+			errs[name] = err
+		}
+	}
+	m.mixMutex.Lock()
+	m.mixCancelAllFires()
+	m.mixClearAllFires()
+	now := m.mixSchedDurOf(m.nowTz)
+	m.masterVolume = s.MasterVolume
+	m.masterMuted = s.Muted
+	m.mixRestoreTempo(s.Tempo, now)
+	m.mixMutex.Unlock()
+	for _, b := range s.Buses {
+		m.mixRestoreBus(b)
+	}
+	fires := make([]*fire.Fire, len(s.Fires))
+	for i, record := range s.Fires {
+		if _, failed := errs[record.Source]; failed {
+			continue
+		}
+		begin := now + record.Begin
+		if record.After != nil {
+			begin = 0 // not yet known
+		}
+		f, err := m.mixFireOfRecord(record, begin)
+		if err != nil {
+			errs[record.Source] = err
+			continue
+		}
+		m.mixMutex.Lock()
+		m.mixRestoreSustain(f, record)
+		m.mixMutex.Unlock()
+		fires[i] = f
+	}
+	m.mixScheduleChains(s.Fires, fires)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//
+// Private
+//
+
+// mixLayersOf a multi-sample source, with the name of each of its variants as it would be set
+func (m *Mixer) mixLayersOf(multi *multiSource) (layers []Layer) {
+	for _, l := range multi.layers {
+		layer := Layer{MinVelocity: l.min, MaxVelocity: l.max, Random: l.random}
+		for _, key := range l.keys {
+			layer.Sources = append(layer.Sources, m.mixSourceName(key))
+		}
+		layers = append(layers, layer)
+	}
+	return
+}
+
+// mixRestoreBus of a session, by name, with its levels
+func (m *Mixer) mixRestoreBus(b SessionBus) {
+	bus := m.NewBus(b.Name)
+	bus.SetVolume(b.Volume)
+	bus.SetPan(b.Pan)
+	if b.Muted {
+		bus.Mute()
+	} else {
+		bus.Unmute()
+	}
+	if b.Soloed {
+		bus.Solo()
+	} else {
+		bus.Unsolo()
+	}
+}
+
+// mixRestoreTempo from a session, with its step offset later by the mix time now; the caller must hold the mixMutex
+func (m *Mixer) mixRestoreTempo(t SessionTempo, now time.Duration) {
+	m.mixTempoMap = []mixTempo{{0, DefaultBPM, 0}}
+	m.mixStepsPerBeat = DefaultStepsPerBeat
+	if t.BPM > 0 && t.StepsPerBeat > 0 {
+		m.mixTempoMap[0].bpm = t.BPM
+		m.mixStepsPerBeat = t.StepsPerBeat
+	}
+	for _, change := range t.Changes {
+		if change.Step > 0 && change.BPM > 0 {
+			m.mixTempoMap = append(m.mixTempoMap, mixTempo{step: change.Step, bpm: change.BPM})
+		}
+	}
+	sort.SliceStable(m.mixTempoMap, func(i, j int) bool {
+		return m.mixTempoMap[i].step < m.mixTempoMap[j].step
+	})
+	m.mixTempoMapUpdate()
+	m.mixSwing = t.Swing
+	m.mixGroove = append([]time.Duration(nil), t.Groove...)
+	m.mixStepOffset = t.StepOffset + now
+	m.mixSustainTempo = t.SustainTempo
+	m.mixTickSchedule(false)
+}
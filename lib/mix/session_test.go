@@ -0,0 +1,201 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSaveSession(t *testing.T) {
+	testMixSetup()
+	SetSoundsPath("../source/testdata/")
+	defer SetSoundsPath("")
+	SetTempo(100, 4)
+	AddTempoChange(16, 140)
+	SetSwing(0.5)
+	SetMasterVolume(0.8)
+	drums := NewBus("drums")
+	drums.SetVolume(0.5)
+	drums.Mute()
+	tone := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetFire(tone, 0, 0, 1.0, 0)
+	SetFireOnBus(drums, "Signed16bitLittleEndian44100HzMono.wav", 2*time.Second, 0, 0.5, -0.5)
+	SetFireLoop(tone, time.Second, 250*time.Millisecond, 4, 100*time.Millisecond, 0.8, 0.25)
+	for n := 0; n < 4410; n++ {
+		NextSample()
+	}
+	var saved bytes.Buffer
+	assert.Nil(t, SaveSession(&saved))
+	// a fresh mixer, as of another process, is configured by the session, and resumes from where it was saved
+	m := newMixer(source.NewCache())
+	m.output = bind.NewOutput(m.NextSample)
+	defer m.Teardown()
+	assert.Nil(t, m.LoadSession(bytes.NewReader(saved.Bytes())))
+	assert.Equal(t, spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1}, *m.Spec())
+	fires := m.Fires()
+	assert.Equal(t, 2, len(fires))
+	assert.Equal(t, 900*time.Millisecond, fires[0].BeginAt())
+	assert.Equal(t, 4, fires[0].Repeat)
+	assert.Equal(t, 1900*time.Millisecond, fires[1].BeginAt())
+	assert.Equal(t, "drums", fires[1].Bus)
+	assert.Equal(t, 0.8, m.GetMasterVolume())
+	assert.Equal(t, 150*time.Millisecond, m.StepDuration())
+	assert.Equal(t, 1, m.StepAt(50*time.Millisecond)) // step zero began before the mix time at which it was saved
+	assert.Equal(t, 0.5, m.NewBus("drums").GetVolume())
+	assert.True(t, m.NewBus("drums").IsMuted())
+	var resaved bytes.Buffer
+	assert.Nil(t, m.SaveSession(&resaved))
+	assert.JSONEq(t, saved.String(), resaved.String())
+}
+
+func TestSaveSession_Fires(t *testing.T) {
+	tone := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	for _, tc := range []struct {
+		name  string
+		set   func(m *Mixer) *fire.Fire
+		check func(t *testing.T, m *Mixer, f *fire.Fire)
+	}{
+		{"loopXFade", func(m *Mixer) *fire.Fire {
+			return m.SetFireLoopXFade(tone, time.Second, 20*time.Millisecond, 1.0, 0)
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			assert.True(t, f.LoopsForever())
+			assert.Equal(t, spec.Tz(882), f.XFadeTz)
+		}},
+		{"tone", func(m *Mixer) *fire.Fire {
+			f := m.SetFire(tone, time.Second, 0, 1.0, 0)
+			f.SetTone(-6, 4)
+			return f
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			low, high := f.Tone()
+			assert.Equal(t, float64(-6), low)
+			assert.Equal(t, float64(4), high)
+		}},
+		{"choke", func(m *Mixer) *fire.Fire {
+			f := m.SetFire(tone, time.Second, 0, 1.0, 0)
+			f.SetChoke("hats")
+			return f
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			assert.Equal(t, "hats", f.ChokeGroup())
+		}},
+		{"tempoSustain", func(m *Mixer) *fire.Fire {
+			m.SetSustainFollowsTempo(true)
+			return m.SetFireAtStep(tone, 4, 2, 1.0, 0)
+		}, func(t *testing.T, m *Mixer, f *fire.Fire) {
+			assert.True(t, m.GetSustainFollowsTempo())
+			assert.Equal(t, mixSustain{4, 2}, m.mixSustains[f])
+			m.AddTempoChange(2, 60) // after it was loaded, still follows
+			m.OutputContinueTo(time.Second)
+			assert.Equal(t, 500*time.Millisecond, f.Sustain())
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer a.Teardown()
+			assert.NotNil(t, tc.set(a))
+			var saved bytes.Buffer
+			assert.Nil(t, a.SaveSession(&saved))
+			b, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer b.Teardown()
+			assert.Nil(t, b.LoadSession(bytes.NewReader(saved.Bytes())))
+			var resaved bytes.Buffer
+			assert.Nil(t, b.SaveSession(&resaved))
+			assert.JSONEq(t, saved.String(), resaved.String())
+			fires := b.Fires()
+			assert.Equal(t, 1, len(fires))
+			tc.check(t, b, fires[0])
+		})
+	}
+}
+
+func TestSaveSession_Playing(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		at    time.Duration // of the save
+		set   func(m *Mixer)
+		fires int // saved
+	}{
+		{"sustain", 5 * time.Second, func(m *Mixer) {
+			m.SetFire(source.ToneKey(source.WaveSine, 441, 10*time.Second), time.Second, 8*time.Second, 1.0, 0)
+		}, 1},
+		{"loop", 1300 * time.Millisecond, func(m *Mixer) {
+			m.SetFireLoop(source.ToneKey(source.WaveSine, 441, 100*time.Millisecond), time.Second, 250*time.Millisecond, 4, 0, 0.8, 0.25)
+		}, 2},
+		{"sustainLoop", 2 * time.Second, func(m *Mixer) {
+			f := m.SetFire(source.ToneKey(source.WaveSine, 441, time.Second), 0, 3*time.Second, 1.0, 0)
+			f.SetSustainLoop(4410, 8820)
+		}, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer a.Teardown()
+			tc.set(a)
+			_, err = a.Render(tc.at)
+			assert.Nil(t, err)
+			var saved bytes.Buffer
+			assert.Nil(t, a.SaveSession(&saved))
+			session, err := ReadSession(bytes.NewReader(saved.Bytes()))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.fires, len(session.Fires))
+			assert.Equal(t, time.Duration(0), session.Fires[0].Begin) // resumes at once
+			b, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+			assert.Nil(t, err)
+			defer b.Teardown()
+			assert.Nil(t, b.LoadSession(bytes.NewReader(saved.Bytes())))
+			want, err := a.Render(time.Second)
+			assert.Nil(t, err)
+			got, err := b.Render(time.Second)
+			assert.Nil(t, err)
+			assert.NotEqual(t, float64(0), got[0]) // mid-sample
+			assert.InDeltaSlice(t, want, got, 1e-6)
+		})
+	}
+}
+
+func TestLoadSession_Missing(t *testing.T) {
+	testMixSetup()
+	dir, err := ioutil.TempDir("", "mix-session")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile("../source/testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "kick.wav"), data, 0644))
+	SetSoundsPath(dir + "/")
+	defer SetSoundsPath("")
+	SetFire("kick.wav", time.Second, 0, 1.0, 0)
+	SetFire("kick.wav", 2*time.Second, 0, 1.0, 0)
+	SetFireTone(441, 3*time.Second, 100*time.Millisecond, 1.0, 0)
+	var saved bytes.Buffer
+	assert.Nil(t, SaveSession(&saved))
+	assert.Nil(t, os.Remove(filepath.Join(dir, "kick.wav")))
+	EvictSource("kick.wav")
+	testMixSetup()
+	err = LoadSession(bytes.NewReader(saved.Bytes()))
+	missing, ok := err.(SessionSourceErrors)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(missing))
+	assert.NotNil(t, missing["kick.wav"])
+	assert.True(t, strings.HasPrefix(err.Error(), "Cannot load 1 sources of the session: kick.wav: "))
+	assert.Equal(t, 1, FireCountReady()) // the rest of the session is restored
+}
+
+func TestReadSession_FAIL(t *testing.T) {
+	_, err := ReadSession(strings.NewReader(`{"version":99}`))
+	assert.EqualError(t, err, "Unsupported session version: 99 (must be 1 to 2)")
+	_, err = ReadSession(strings.NewReader(`not json`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,222 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// SourceInfo describes a source in memory, e.g. for a sample browser.
+type SourceInfo struct {
+	Name         string           // as it would be set on a fire, without the sounds path prefix
+	Duration     time.Duration    // natural length, at the mix frequency
+	Channels     int              // of the original audio
+	OriginalFreq float64          // of the original audio, before it was resampled to the mix frequency
+	Samples      int              // in memory, at the mix frequency
+	Bytes        int64            // in memory
+	Meta         *spec.SourceMeta // embedded loop points and cues, in samples at the mix frequency, and tags, or nil
+	Tags         *Metadata        // of its file, e.g. the title, or the description and originator of a Broadcast Wave, or nil
+	Streaming    bool             // decoded from its file as it plays, see SetSourceStreaming
+	Normalized   float64          // gain in dB applied as it was loaded, see SetAutoNormalize
+	Trim         float64          // gain in dB applied as it plays, see SetSourceGain
+	TrimOffset   time.Duration    // of the leading audio skipped as it plays, see SetAutoTrim
+}
+
+// Sources in memory, in order of their name; this never loads a source.
+func (m *Mixer) Sources() []SourceInfo {
+	loaded := m.cache.Loaded()
+	infos := make([]SourceInfo, len(loaded))
+	for i, s := range loaded {
+		infos[i] = m.mixSourceInfo(s)
+	}
+	return infos
+}
+
+// GetSourceDuration of a source in memory, resolved like SetFire, e.g. to set a sustain that matches its natural length;
+// returns an error if the source is not in memory, because this never loads a source, see Prepare.
+func (m *Mixer) GetSourceDuration(name string) (time.Duration, error) {
+	s := m.cache.Get(m.mixSourceKey(name))
+	if s == nil {
+		return 0, fmt.Errorf("Source not in memory: %s (must be prepared or fired first)", name)
+	}
+	return m.mixDurOf(s.Length()), nil
+}
+
+// ReloadSource from its file, resolved like SetFire, e.g. after it was edited in another app while the sequence loops: it is
+// decoded and resampled again, and then swapped in at once, for the next fire of it and every fire already scheduled that has
+// not yet begun, whose natural length follows the new audio if it has a sustain of 0. A fire that is already playing it finishes
+// on the audio it began with, without a discontinuity. Every variant of a multi-sample source is reloaded. A source that is not
+// in memory is only prepared. Returns an error, keeping the source as it was, if it cannot be loaded.
+func (m *Mixer) ReloadSource(name string) error {
+	key := m.mixSourceKey(name)
+	if m.mixMultiSource(key) == nil {
+		return m.mixReloadSource(key)
+	}
+	var err error
+	m.mixEachVariant(key, func(variant string) {
+		if reloadErr := m.mixReloadSource(variant); err == nil {
+			err = reloadErr
+		}
+	})
+	return err
+}
+
+// WatchSourcesInterval at which the files of the sources in memory are checked for a change, see WatchSources
+const WatchSourcesInterval = 500 * time.Millisecond
+
+// WatchSources, if on, to reload each source in memory whose file changes, by its modification time or size, checked every
+// WatchSourcesInterval, see ReloadSource; a source that is registered, provided or synthesized is never reloaded. Off by default.
+func (m *Mixer) WatchSources(on bool) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixWatchSources(on)
+}
+
+// SetAutoTrim the leading silence of each source as it is loaded, up to its first sample above a threshold in dBFS, e.g. -60, such that
+// the fires of one-shots from different libraries begin at their transient, and feel equally tight; see SetAutoTrimTrailing. Only the
+// cursors of playback move: the samples in memory are not copied, and every source in memory is reloaded. A streamed source or a
+// synthesized tone is not trimmed. The offset of each source is in its SourceInfo, and can be overridden by SetSourceTrim.
+func (m *Mixer) SetAutoTrim(thresholdDB float64) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	_, _, trailing := source.GetAutoTrim()
+	source.SetAutoTrim(true, thresholdDB, trailing)
+}
+
+// SetAutoTrimTrailing silence too, if on, below the threshold of SetAutoTrim, which shortens the natural length of each source
+func (m *Mixer) SetAutoTrimTrailing(on bool) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	autoTrim, thresholdDB, _ := source.GetAutoTrim()
+	source.SetAutoTrim(autoTrim, thresholdDB, on)
+}
+
+// StopAutoTrim of sources, reloading every source in memory to play from its first sample to its last (the default)
+func (m *Mixer) StopAutoTrim() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	_, thresholdDB, trailing := source.GetAutoTrim()
+	source.SetAutoTrim(false, thresholdDB, trailing)
+}
+
+// SetSourceTrim offset of a source, resolved like SetFire, the leading audio skipped as it plays, instead of that detected by
+// SetAutoTrim, whether or not it is on, e.g. 0 to keep the breath before one vocal hit; a negative offset removes the override.
+// The source is reloaded if it is in memory.
+func (m *Mixer) SetSourceTrim(name string, offset time.Duration) {
+	key := m.mixSourceKey(name)
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	source.SetTrimOffset(key, offset)
+}
+
+//
+// Private
+//
+
+func (m *Mixer) mixSourceInfo(s *source.Source) SourceInfo {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	info := SourceInfo{
+		Name:       m.mixSourceName(s.URL),
+		Duration:   m.mixDurOf(s.Length()),
+		Samples:    int(s.Length()),
+		Bytes:      s.Size(),
+		Meta:       s.Meta(),
+		Streaming:  s.IsStreaming(),
+		Normalized: s.Normalization(),
+		Trim:       s.Trim(),
+		TrimOffset: m.mixDurOf(s.TrimOffset()),
+	}
+	if meta := s.Meta(); meta != nil {
+		info.Tags = meta.Tags
+	}
+	if audioSpec := s.Spec(); audioSpec != nil {
+		info.Channels = audioSpec.Channels
+		info.OriginalFreq = audioSpec.Freq
+	}
+	return info
+}
+
+// mixReloadSource by its key, decoded without holding the mixMutex, and then swapped in while holding it, such that the live fires
+// that have begun are moved onto the audio it replaces before another sample is mixed
+func (m *Mixer) mixReloadSource(key string) error {
+	if m.cache.Get(key) == nil {
+		return m.cache.Prepare(key)
+	}
+	s, err := m.cache.New(key)
+	if err != nil {
+		return err
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	previous := m.cache.Replace(s)
+	if previous == "" {
+		return nil // evicted while it loaded
+	}
+	begunTz := m.nowTz // fires that begin before it have played some of the audio, including any mixed ahead in a block
+	if m.mixBlockHas(m.nowTz) {
+		begunTz = m.mixBlockEndTz
+	}
+	for _, f := range m.mixLiveFires {
+		if f.BeginTz < begunTz && f.Resolved() == key && f.IsAlive() {
+			f.Resolve(previous)
+		}
+	}
+	debug.Infof("mix.ReloadSource(%s) kept the previous audio as %q for the fires playing it", key, previous)
+	return nil
+}
+
+// mixWatchSources on or off; the caller must hold the mixMutex
+func (m *Mixer) mixWatchSources(on bool) {
+	if on == (m.mixWatchStop != nil) {
+		return
+	}
+	if !on {
+		close(m.mixWatchStop)
+		m.mixWatchStop = nil
+		return
+	}
+	m.mixWatchStop = make(chan struct{})
+	go m.mixWatchLoop(m.mixWatchStop)
+}
+
+// mixWatchLoop checks the files of the sources in memory every interval, until stopped, and reloads each that has changed
+// since it was first seen
+func (m *Mixer) mixWatchLoop(stop chan struct{}) {
+	type seen struct {
+		modTime time.Time
+		size    int64
+	}
+	files := make(map[string]seen)
+	ticker := time.NewTicker(WatchSourcesInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		for _, s := range m.cache.Loaded() {
+			key := s.URL
+			if source.IsTone(key) || source.IsRegistered(key) || source.IsProvided(key) {
+				continue
+			}
+			info, err := os.Stat(key)
+			if err != nil {
+				continue
+			}
+			now := seen{info.ModTime(), info.Size()}
+			if was, ok := files[key]; ok && was != now {
+				if err := m.mixReloadSource(key); err != nil {
+					debug.Warnf("mix.WatchSources reload of %s failed: %s", key, err)
+				}
+			}
+			files[key] = now
+		}
+	}
+}
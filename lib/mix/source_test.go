@@ -0,0 +1,341 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSources(t *testing.T) {
+	testMixSetup()
+	SetSoundsPath("../source/testdata/")
+	defer SetSoundsPath("")
+	tone := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	assert.Nil(t, Prepare("Float32bitLittleEndian48000HzEstéreo.wav", tone))
+	defer EvictSource("Float32bitLittleEndian48000HzEstéreo.wav")
+	defer EvictSource(tone)
+	var stereo, sine SourceInfo
+	for _, info := range Sources() {
+		switch info.Name {
+		case "Float32bitLittleEndian48000HzEstéreo.wav":
+			stereo = info
+		case tone:
+			sine = info
+		}
+	}
+	assert.Equal(t, 2, stereo.Channels)
+	assert.Equal(t, float64(48000), stereo.OriginalFreq)
+	assert.True(t, stereo.Samples > 0)
+	assert.Equal(t, mixDefault.mixDurOf(spec.Tz(stereo.Samples)), stereo.Duration)
+	assert.Equal(t, int64(stereo.Samples)*2*8, stereo.Bytes)
+	assert.Equal(t, 100*time.Millisecond, sine.Duration)
+	assert.Equal(t, 4410, sine.Samples)
+}
+
+func TestSetAutoNormalize(t *testing.T) {
+	testMixSetup()
+	SetSoundsPath("../source/testdata/")
+	defer SetSoundsPath("")
+	name := "Signed16bitLittleEndian44100HzMono.wav"
+	assert.Nil(t, Prepare(name))
+	defer EvictSource(name)
+	defer StopAutoNormalize()
+	SetAutoNormalize(-6)
+	info := testSourceInfo(name)
+	assert.True(t, info.Normalized != 0)
+	SetMixAlgorithm(MixHardClip)
+	SetFire(name, 0, 0, 1, 0)
+	d, err := GetSourceDuration(name)
+	assert.Nil(t, err)
+	out, err := Render(d)
+	assert.Nil(t, err)
+	var peak float64
+	for _, v := range out {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	assert.InDelta(t, math.Pow(10, -6.0/20), peak, 1e-6)
+	StopAutoNormalize()
+	assert.Equal(t, float64(0), testSourceInfo(name).Normalized)
+}
+
+func TestSetAutoTrim(t *testing.T) {
+	testMixSetup()
+	var buf bytes.Buffer
+	w := wav.NewWriter(&buf, wav.FormatFromSpec(&spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1}), 10*time.Millisecond)
+	for n := 0; n < 441; n++ {
+		value := float32(0)
+		if n >= 100 {
+			value = 0.5 // the transient, after 100 samples of silence
+		}
+		assert.Nil(t, binary.Write(w, binary.LittleEndian, value))
+	}
+	assert.Nil(t, source.Register("hit.wav", buf.Bytes()))
+	assert.Nil(t, Prepare("hit.wav"))
+	defer EvictSource("hit.wav")
+	defer StopAutoTrim()
+	SetAutoTrim(-60)
+	assert.Equal(t, mixDefault.mixDurOf(100), testSourceInfo("hit.wav").TrimOffset)
+	SetMixAlgorithm(MixHardClip)
+	SetFire("hit.wav", 0, 0, 1, 0)
+	out, err := Render(10 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, out[0]) // begins at the transient
+	assert.Equal(t, float64(0), out[341])
+	SetSourceTrim("hit.wav", 0) // keeps its silence
+	defer SetSourceTrim("hit.wav", -1)
+	assert.Equal(t, time.Duration(0), testSourceInfo("hit.wav").TrimOffset)
+	StopAutoTrim()
+	SetSourceTrim("hit.wav", -1)
+	assert.Equal(t, time.Duration(0), testSourceInfo("hit.wav").TrimOffset)
+}
+
+func TestSetSourceGain(t *testing.T) {
+	testMixSetup()
+	SetMixAlgorithm(MixHardClip)
+	tone := source.ToneKey(source.WaveSine, 100, time.Second)
+	defer SetSourceGain(tone, 0)
+	SetFire(tone, 0, 0, 1, 0)
+	before, err := Render(100 * time.Millisecond)
+	assert.Nil(t, err)
+	SetSourceGain(tone, -6) // after the fire is scheduled
+	assert.Equal(t, float64(-6), GetSourceGain(tone))
+	assert.InDelta(t, -6, testSourceInfo(tone).Trim, 1e-9)
+	after, err := Render(100 * time.Millisecond)
+	assert.Nil(t, err)
+	for n := 441; n < len(after); n++ { // after the attack of the tone, each 100ms is 10 whole cycles
+		assert.InDelta(t, before[n]*math.Pow(10, -6.0/20), after[n], 1e-6)
+	}
+}
+
+func TestSetSourceGain_PerMixer(t *testing.T) {
+	tone := source.ToneKey(source.WaveSine, 100, time.Second)
+	peak := func(m *Mixer) float64 { // of the tone, from the top
+		m.Stop()
+		out, err := m.Render(100 * time.Millisecond)
+		assert.Nil(t, err)
+		var p float64
+		for _, v := range out {
+			p = math.Max(p, math.Abs(v))
+		}
+		return p
+	}
+	a, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	b, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	defer b.Teardown()
+	for _, m := range []*Mixer{a, b} {
+		m.SetMixAlgorithm(MixHardClip)
+		m.SetFire(tone, 0, 0, 1, 0)
+	}
+	before := peak(b)
+	a.SetSourceGain(tone, -20)
+	a.SetAutoNormalizeRMS(-40)
+	assert.Equal(t, float64(-20), a.GetSourceGain(tone))
+	assert.InDelta(t, before*math.Pow(10, -20.0/20), peak(a), 1e-6)
+	assert.Equal(t, float64(0), b.GetSourceGain(tone))
+	assert.Equal(t, before, peak(b)) // not the gain of the other mixer
+	assert.Equal(t, float64(0), GetSourceGain(tone))
+	a.Teardown()
+	assert.Equal(t, float64(0), a.GetSourceGain(tone)) // reset by the teardown
+	mode, _ := a.cache.GetNormalize()
+	assert.Equal(t, source.NormalizeOff, mode)
+}
+
+func TestSetResampleQuality_PerMixer(t *testing.T) {
+	testMixSetup()
+	a, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2})
+	assert.Nil(t, err)
+	b, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 2})
+	assert.Nil(t, err)
+	defer b.Teardown()
+	a.SetResampleQuality(source.ResampleHigh)
+	assert.Nil(t, a.SetDownmixMatrix(4, nil))
+	assert.Equal(t, source.ResampleHigh, a.GetResampleQuality())
+	assert.Nil(t, a.GetDownmixMatrix(4, 2))
+	assert.Equal(t, source.ResampleMedium, b.GetResampleQuality()) // not those of the other mixer
+	assert.NotNil(t, b.GetDownmixMatrix(4, 2))
+	assert.Equal(t, source.ResampleMedium, source.GetResampleQuality())
+	assert.NotNil(t, source.GetDownmixMatrix(4, 2))
+	a.Teardown()
+	assert.Equal(t, source.ResampleMedium, a.GetResampleQuality()) // reset by the teardown
+}
+
+func TestGetSourceDuration(t *testing.T) {
+	testMixSetup()
+	tone := source.ToneKey(source.WaveSine, 441, 250*time.Millisecond)
+	_, err := GetSourceDuration(tone)
+	assert.NotNil(t, err)
+	assert.Nil(t, Prepare(tone))
+	defer EvictSource(tone)
+	d, err := GetSourceDuration(tone)
+	assert.Nil(t, err)
+	assert.Equal(t, 250*time.Millisecond, d)
+	_, err = GetSourceDuration("ThisDoesNotExist.wav")
+	assert.NotNil(t, err)
+}
+
+func TestPrepareCtx(t *testing.T) {
+	testMixSetup()
+	tone := source.ToneKey(source.WaveSine, 441, 250*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, PrepareCtx(ctx, tone))
+	_, err := GetSourceDuration(tone)
+	assert.NotNil(t, err) // never began to load
+	assert.Nil(t, PrepareCtx(context.Background(), tone))
+	defer EvictSource(tone)
+	_, err = GetSourceDuration(tone)
+	assert.Nil(t, err)
+}
+
+func TestSetSourceStreaming(t *testing.T) {
+	name := "Signed16bitLittleEndian44100HzMono.wav"
+	var whole, streamed []float64
+	for _, on := range []bool{false, true} {
+		testMixSetup()
+		SetSoundsPath("../source/testdata/")
+		SetSourceStreaming(name, on)
+		assert.NotNil(t, FireNow(name, 0, 1, 0))
+		var out []float64
+		for i := 0; i < 4410; i++ {
+			out = append(out, float64(NextSample()[0]))
+		}
+		info := Sources()[0]
+		assert.Equal(t, on, info.Streaming)
+		if on {
+			streamed = out
+		} else {
+			whole = out
+		}
+		SetSourceStreaming(name, false)
+		SetSoundsPath("")
+	}
+	assert.NotEqual(t, make([]float64, len(whole)), whole)
+	assert.Equal(t, whole, streamed)
+}
+
+func TestSetSourceCacheLimit(t *testing.T) {
+	testMixSetup()
+	fired := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	idle := source.ToneKey(source.WaveSine, 882, 100*time.Millisecond)
+	assert.Nil(t, Prepare(idle, fired))
+	defer EvictSource(fired)
+	SetFire(fired, time.Second, 0, 1, 0)
+	before := SourceCacheStats()
+	SetSourceCacheLimit(1)
+	defer SetSourceCacheLimit(0)
+	assert.Nil(t, source.Get(idle))
+	assert.NotNil(t, source.Get(fired)) // pinned by its scheduled fire
+	assert.True(t, SourceCacheStats().Evictions > before.Evictions)
+	assert.NotNil(t, FireNow(idle, 0, 1, 0)) // loaded again
+	assert.Equal(t, before.Misses+1, SourceCacheStats().Misses)
+	assert.NotNil(t, source.Get(idle))
+}
+
+func TestReloadSource(t *testing.T) {
+	testMixSetup()
+	dir, err := ioutil.TempDir("", "mix-reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	testReloadWAV(t, filepath.Join(dir, "pad.wav"), 0.5, 10*time.Millisecond)
+	SetSoundsPath(dir + "/")
+	defer SetSoundsPath("")
+	SetFire("pad.wav", 0, 0, 1.0, 0)
+	SetFire("pad.wav", 100*time.Millisecond, 0, 1.0, 0)
+	out, err := Render(5 * time.Millisecond)
+	assert.Nil(t, err)
+	old := out[0]
+	assert.NotEqual(t, float64(0), old)
+	testReloadWAV(t, filepath.Join(dir, "pad.wav"), 0.25, 20*time.Millisecond)
+	assert.Nil(t, ReloadSource("pad.wav"))
+	out, err = Render(time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, old, out[0])              // the live fire finishes on the audio it began with
+	assert.Equal(t, float64(0), out[441-221]) // of its original length
+	replaced := out[4410-221]
+	assert.NotEqual(t, float64(0), replaced) // the scheduled fire plays the new audio, for its new length
+	assert.NotEqual(t, old, replaced)
+	assert.Equal(t, replaced, out[4410-221+881])
+	assert.Equal(t, float64(0), out[4410-221+882])
+	copies := 0
+	for _, info := range Sources() {
+		if info.Name == "pad.wav" {
+			copies++
+		}
+	}
+	assert.Equal(t, 0, copies) // the previous audio too is evicted once no fire plays it
+}
+
+func TestReloadSource_FAIL(t *testing.T) {
+	testMixSetup()
+	dir, err := ioutil.TempDir("", "mix-reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pad.wav")
+	testReloadWAV(t, path, 0.5, 10*time.Millisecond)
+	assert.Nil(t, Prepare(path))
+	assert.Nil(t, ioutil.WriteFile(path, []byte("this is not a WAV file"), 0644))
+	assert.NotNil(t, ReloadSource(path))
+	duration, err := GetSourceDuration(path) // kept as it was
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Millisecond, duration)
+	assert.NotNil(t, ReloadSource(filepath.Join(dir, "nonexistent.wav")))
+}
+
+func TestWatchSources(t *testing.T) {
+	testMixSetup()
+	dir, err := ioutil.TempDir("", "mix-watch")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pad.wav")
+	testReloadWAV(t, path, 0.5, 10*time.Millisecond)
+	assert.Nil(t, Prepare(path))
+	WatchSources(true)
+	defer WatchSources(false)
+	time.Sleep(2 * WatchSourcesInterval) // seen as it was
+	testReloadWAV(t, path, 0.5, 20*time.Millisecond)
+	deadline := time.Now().Add(5 * WatchSourcesInterval)
+	for duration, _ := GetSourceDuration(path); duration != 20*time.Millisecond; duration, _ = GetSourceDuration(path) {
+		if time.Now().After(deadline) {
+			t.Fatal("source was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//
+// Private
+//
+
+func testSourceInfo(name string) SourceInfo {
+	for _, info := range Sources() {
+		if info.Name == name {
+			return info
+		}
+	}
+	return SourceInfo{}
+}
+
+// testReloadWAV file of 32-bit float samples at 44.1kHz mono, of one value for a length
+func testReloadWAV(t *testing.T, path string, value float32, length time.Duration) {
+	var buf bytes.Buffer
+	w := wav.NewWriter(&buf, wav.FormatFromSpec(&spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1}), length)
+	for n := 0; n < int(length.Seconds()*44100); n++ {
+		assert.Nil(t, binary.Write(w, binary.LittleEndian, value))
+	}
+	assert.Nil(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+}
@@ -0,0 +1,189 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/lib/source"
+)
+
+// LoopStats of the mix loop, cumulative since ResetStats (or Teardown) and of the last mix cycle, e.g. to detect dropouts in production.
+// The budget of a cycle is the duration of the audio it mixed; computation beyond that cannot keep up with real-time playback.
+type LoopStats struct {
+	Cycles       int64         // mix cycles completed
+	Overruns     int64         // cycles whose computation took longer than their budget
+	Underruns    int64         // samples of streamed sources that played as silence, see StreamUnderruns
+	Work         time.Duration // of computation, in all cycles
+	MaxWork      time.Duration // of computation, in any one cycle
+	MaxLiveFires int           // at once, in any one cycle
+	GCs          int64         // garbage collections during cycles
+	GCPause      time.Duration // of garbage collection during cycles
+	Clipped      int64         // output samples beyond full scale in any channel, after compression, see SetClipMode
+	// of the last cycle
+	LastWork      time.Duration
+	LastBudget    time.Duration
+	LastLiveFires int
+	LastGCs       int64
+	LastGCPause   time.Duration
+	LastClipped   int64
+	// of each source provider, cumulative since it was registered, see RegisterSourceProvider
+	Providers map[string]ProviderStats
+	// blocks of any tee dropped because its writer could not keep up, see TeeOutput
+	TeeDropped int64
+	// frames of the output that played as silence, because the lookahead was exhausted, see SetLookahead
+	OutUnderruns int64
+	// blocks of the output dropped because its consumer could not keep up, see SetOutputPipe
+	OutDropped int64
+}
+
+// Stats returns a snapshot of the stats of the mix loop; it is cheap to poll from any goroutine.
+func (m *Mixer) Stats() LoopStats {
+	return LoopStats{
+		Cycles:        atomic.LoadInt64(&m.mixStatCycles),
+		Overruns:      atomic.LoadInt64(&m.mixStatOverruns),
+		Underruns:     source.StreamUnderruns() - atomic.LoadInt64(&m.mixStatUnderrunsBase),
+		Work:          time.Duration(atomic.LoadInt64(&m.mixStatWork)),
+		MaxWork:       time.Duration(atomic.LoadInt64(&m.mixStatMaxWork)),
+		MaxLiveFires:  int(atomic.LoadInt64(&m.mixStatMaxLiveFires)),
+		GCs:           atomic.LoadInt64(&m.mixStatGCs),
+		GCPause:       time.Duration(atomic.LoadInt64(&m.mixStatGCPause)),
+		LastWork:      time.Duration(atomic.LoadInt64(&m.mixStatLastWork)),
+		LastBudget:    time.Duration(atomic.LoadInt64(&m.mixStatLastBudget)),
+		LastLiveFires: int(atomic.LoadInt64(&m.mixStatLastLiveFires)),
+		LastGCs:       atomic.LoadInt64(&m.mixStatLastGCs),
+		LastGCPause:   time.Duration(atomic.LoadInt64(&m.mixStatLastGCPause)),
+		Clipped:       atomic.LoadInt64(&m.mixStatClipped),
+		LastClipped:   atomic.LoadInt64(&m.mixStatLastClipped),
+		Providers:     source.GetProviderStats(),
+		TeeDropped:    atomic.LoadInt64(&m.mixStatTeeDropped),
+		OutUnderruns:  atomic.LoadInt64(&m.mixStatOutUnderruns),
+		OutDropped:    atomic.LoadInt64(&m.mixStatOutDropped),
+	}
+}
+
+// ResetStats of the mix loop to zero, e.g. after warming up.
+func (m *Mixer) ResetStats() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixResetStats()
+}
+
+// SetOverrunWarning to call fn with the stats each time a mix cycle takes longer than its budget, or nil fn to stop.
+// fn is called on its own goroutine, never the mix loop, and never concurrently with itself; overruns while it runs are counted
+// in the stats, but do not call it again.
+func (m *Mixer) SetOverrunWarning(fn func(stats LoopStats)) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixOverrunFn = fn
+	m.mixRunOverrun(fn != nil)
+}
+
+//
+// Private
+//
+
+// mixStatSample of the computation of one sample, since it began; the caller must hold the mixMutex
+func (m *Mixer) mixStatSample(began time.Time) {
+	m.mixStatCycleWork += time.Since(began)
+	if live := int(atomic.LoadInt64(&m.mixCountLive)); live > m.mixStatCycleLiveFires {
+		m.mixStatCycleLiveFires = live
+	}
+}
+
+// mixStatCycle publishes the stats of the cycle that is ending, and signals the warning if it overran its budget;
+// the caller must hold the mixMutex
+func (m *Mixer) mixStatCycle() {
+	if m.nowTz <= m.mixStatCycleBeginTz { // e.g. after a seek backward
+		m.mixStatBeginCycle()
+		return
+	}
+	budget := m.mixDurOf(m.nowTz - m.mixStatCycleBeginTz)
+	gcs, gcPause := m.mixStatGC.NumGC, m.mixStatGC.PauseTotal
+	debug.ReadGCStats(&m.mixStatGC)
+	gcs, gcPause = m.mixStatGC.NumGC-gcs, m.mixStatGC.PauseTotal-gcPause
+	atomic.AddInt64(&m.mixStatCycles, 1)
+	atomic.AddInt64(&m.mixStatWork, int64(m.mixStatCycleWork))
+	if int64(m.mixStatCycleWork) > atomic.LoadInt64(&m.mixStatMaxWork) {
+		atomic.StoreInt64(&m.mixStatMaxWork, int64(m.mixStatCycleWork))
+	}
+	if int64(m.mixStatCycleLiveFires) > atomic.LoadInt64(&m.mixStatMaxLiveFires) {
+		atomic.StoreInt64(&m.mixStatMaxLiveFires, int64(m.mixStatCycleLiveFires))
+	}
+	atomic.AddInt64(&m.mixStatGCs, gcs)
+	atomic.AddInt64(&m.mixStatGCPause, int64(gcPause))
+	atomic.StoreInt64(&m.mixStatLastWork, int64(m.mixStatCycleWork))
+	atomic.StoreInt64(&m.mixStatLastBudget, int64(budget))
+	atomic.StoreInt64(&m.mixStatLastLiveFires, int64(m.mixStatCycleLiveFires))
+	atomic.StoreInt64(&m.mixStatLastGCs, gcs)
+	atomic.StoreInt64(&m.mixStatLastGCPause, int64(gcPause))
+	atomic.AddInt64(&m.mixStatClipped, m.mixStatCycleClipped)
+	atomic.StoreInt64(&m.mixStatLastClipped, m.mixStatCycleClipped)
+	if m.mixStatCycleWork > budget {
+		atomic.AddInt64(&m.mixStatOverruns, 1)
+		if m.mixOverrunFn != nil {
+			select {
+			case m.mixOverrunSignal <- struct{}{}:
+			default: // the warning is still running
+			}
+		}
+	}
+	m.mixStatBeginCycle()
+}
+
+// mixStatBeginCycle from now; the caller must hold the mixMutex
+func (m *Mixer) mixStatBeginCycle() {
+	m.mixStatCycleBeginTz = m.nowTz
+	m.mixStatCycleWork = 0
+	m.mixStatCycleLiveFires = 0
+	m.mixStatCycleClipped = 0
+}
+
+// mixResetStats to zero; the caller must hold the mixMutex
+func (m *Mixer) mixResetStats() {
+	for _, stat := range []*int64{
+		&m.mixStatCycles, &m.mixStatOverruns, &m.mixStatWork, &m.mixStatMaxWork, &m.mixStatMaxLiveFires, &m.mixStatGCs, &m.mixStatGCPause,
+		&m.mixStatLastWork, &m.mixStatLastBudget, &m.mixStatLastLiveFires, &m.mixStatLastGCs, &m.mixStatLastGCPause,
+		&m.mixStatClipped, &m.mixStatLastClipped, &m.mixStatTeeDropped, &m.mixStatOutUnderruns, &m.mixStatOutDropped,
+	} {
+		atomic.StoreInt64(stat, 0)
+	}
+	atomic.StoreInt64(&m.mixStatUnderrunsBase, source.StreamUnderruns())
+	debug.ReadGCStats(&m.mixStatGC)
+	m.mixStatBeginCycle()
+}
+
+// mixRunOverrun loop on or off, e.g. off by the teardown, such that a mixer that is torn down leaks no goroutine;
+// the caller must hold the mixMutex
+func (m *Mixer) mixRunOverrun(on bool) {
+	if on == (m.mixOverrunStop != nil) {
+		return
+	}
+	if !on {
+		close(m.mixOverrunStop)
+		m.mixOverrunStop = nil
+		return
+	}
+	m.mixOverrunStop = make(chan struct{})
+	m.mixOverrunDone = make(chan struct{})
+	go m.mixOverrunLoop(m.mixOverrunStop, m.mixOverrunDone)
+}
+
+// mixOverrunLoop calls the warning each time it is signaled, until stopped, on one goroutine, so never concurrently with itself
+func (m *Mixer) mixOverrunLoop(stop, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-m.mixOverrunSignal:
+		}
+		m.mixMutex.Lock()
+		fn := m.mixOverrunFn
+		m.mixMutex.Unlock()
+		if fn != nil {
+			fn(m.Stats())
+		}
+	}
+}
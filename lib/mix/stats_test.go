@@ -0,0 +1,86 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestStats(t *testing.T) {
+	testMixSetup()
+	SetCycleDuration(100 * time.Millisecond)
+	assert.Equal(t, LoopStats{}, Stats())
+	SetFire(source.ToneKey(source.WaveSine, 441, time.Second), 0, 0, 1, 0)
+	SetFire(source.ToneKey(source.WaveSine, 882, time.Second), 0, 0, 1, 0)
+	testMixFor(time.Second)
+	stats := Stats()
+	assert.True(t, stats.Cycles > 0)
+	assert.True(t, stats.Work > 0)
+	assert.True(t, stats.MaxWork >= stats.LastWork)
+	assert.Equal(t, mixDefault.mixDurOf(mixDefault.masterCycleDurTz+1), stats.LastBudget)
+	assert.Equal(t, 2, stats.MaxLiveFires)
+	assert.Equal(t, 2, stats.LastLiveFires)
+	assert.Equal(t, int64(0), stats.Underruns)
+}
+
+func TestResetStats(t *testing.T) {
+	testMixSetup()
+	testMixFor(time.Second)
+	assert.True(t, Stats().Cycles > 0)
+	ResetStats()
+	assert.Equal(t, LoopStats{}, Stats())
+}
+
+func TestSetOverrunWarning(t *testing.T) {
+	testMixSetup()
+	SetCycleDuration(100 * time.Millisecond)
+	warned := make(chan LoopStats, 1)
+	SetOverrunWarning(func(stats LoopStats) {
+		warned <- stats
+	})
+	defer SetOverrunWarning(nil)
+	testMixFor(200 * time.Millisecond)
+	overruns := Stats().Overruns // e.g. the first cycle, of only one sample
+	mixDefault.mixMutex.Lock()
+	mixDefault.mixStatCycleWork = time.Second // far over the budget of one cycle
+	mixDefault.mixMutex.Unlock()
+	testMixFor(mixDefault.mixDurOf(mixDefault.masterCycleDurTz + 1))
+	select {
+	case stats := <-warned:
+		assert.Equal(t, overruns+1, stats.Overruns)
+		assert.True(t, stats.LastWork >= time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("overrun warning was not called")
+	}
+}
+
+func TestSetOverrunWarning_Teardown(t *testing.T) {
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	before := runtime.NumGoroutine()
+	m.SetOverrunWarning(func(stats LoopStats) {})
+	m.mixMutex.Lock()
+	done := m.mixOverrunDone
+	m.mixMutex.Unlock()
+	m.Teardown()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("overrun loop did not exit")
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		runtime.Gosched()
+	}
+	assert.True(t, runtime.NumGoroutine() <= before, "overrun loop leaked")
+	m.SetOverrunWarning(func(stats LoopStats) {}) // runs again after the teardown
+	m.mixMutex.Lock()
+	assert.NotNil(t, m.mixOverrunStop)
+	m.mixMutex.Unlock()
+	m.SetOverrunWarning(nil)
+}
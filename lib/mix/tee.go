@@ -0,0 +1,158 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+)
+
+// TeeBlockFrames of each block that a tee queues for its writer, and TeeQueueBlocks the most it queues, e.g. about 1.5 seconds at
+// 44.1kHz, before it drops blocks, see TeeOutput
+const (
+	TeeBlockFrames = 1024
+	TeeQueueBlocks = 64
+)
+
+// Tee of the output of a mixer to a WAV writer, see TeeOutput
+type Tee struct {
+	dropped   int64 // accessed atomically, first so that it is 64-bit aligned
+	mixer     *Mixer
+	format    spec.AudioFormat
+	writer    *wav.Writer
+	block     []byte      // filled by the mix loop
+	blocks    chan []byte // queued for the writer
+	free      chan []byte // written, to be filled again
+	done      chan struct{}
+	err       error // of the writer
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// TeeOutput duplicates every sample of the output, as the output callback or NextSample produces it, into a streamed WAV of a format,
+// e.g. to record a live performance to disk while it plays to hardware; the WAV is at the frequency and channels of the mixer, and
+// the nearest format that WAV can encode, see wav.OutputFormat. The tee never blocks the mix loop: it queues blocks of
+// TeeBlockFrames for a writer on its own goroutine, and if the writer cannot keep up, e.g. a slow disk, it drops any block beyond
+// TeeQueueBlocks, counting it in Stats. Close the tee, at any time, or Teardown the mixer, to finalize a valid WAV.
+func (m *Mixer) TeeOutput(w io.Writer, format spec.AudioFormat) (*Tee, error) {
+	if format.Bits() == 0 {
+		return nil, fmt.Errorf("Cannot tee the output to format %v (must be a valid audio format)", format)
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.masterSpec == nil {
+		return nil, errors.New("Must configure the mixer before teeing its output")
+	}
+	teeSpec := *m.masterSpec
+	teeSpec.Format = wav.OutputFormat(format)
+	size := TeeBlockFrames * teeSpec.Channels * teeSpec.Format.Bits() / 8
+	t := &Tee{
+		mixer:  m,
+		format: teeSpec.Format,
+		writer: wav.NewWriter(w, wav.FormatFromSpec(&teeSpec), 0),
+		block:  make([]byte, 0, size),
+		blocks: make(chan []byte, TeeQueueBlocks),
+		free:   make(chan []byte, TeeQueueBlocks+2),
+		done:   make(chan struct{}),
+	}
+	for n := 0; n < TeeQueueBlocks+1; n++ { // enough that the mix loop never waits for one
+		t.free <- make([]byte, 0, size)
+	}
+	go t.writeLoop()
+	m.mixTees = append(m.mixTees, t)
+	return t, nil
+}
+
+// Dropped blocks of the tee, because its writer could not keep up
+func (t *Tee) Dropped() int64 {
+	return atomic.LoadInt64(&t.dropped)
+}
+
+// Close the tee, mid-playback or not: it stops duplicating the output, writes all it has queued, and closes the WAV writer,
+// first flushing it if it is buffered, then patching the header sizes if it is an io.WriteSeeker, e.g. an *os.File, but it
+// does not close w. Returns the first error of the writer; calling it again does nothing.
+func (t *Tee) Close() error {
+	t.closeOnce.Do(func() {
+		t.closeErr = t.mixer.mixCloseTee(t)
+	})
+	return t.closeErr
+}
+
+//
+// Private
+//
+
+// mixTeeSample of all channels to each tee; the caller must hold the mixMutex
+func (m *Mixer) mixTeeSample(smp []sample.Value) {
+	for _, t := range m.mixTees {
+		t.block = sample.EncodeTo(t.block, t.format, smp)
+		if len(t.block) >= cap(t.block) {
+			t.push()
+		}
+	}
+}
+
+// mixCloseTee, removing it from the mixer, then waiting for its writer to finish
+func (m *Mixer) mixCloseTee(t *Tee) error {
+	m.mixMutex.Lock()
+	for i, tee := range m.mixTees {
+		if tee == t {
+			m.mixTees = append(m.mixTees[:i], m.mixTees[i+1:]...)
+			break
+		}
+	}
+	block := t.block
+	t.block = nil
+	m.mixMutex.Unlock()
+	if len(block) > 0 {
+		t.blocks <- block // not the mix loop, so it may wait for the writer
+	}
+	close(t.blocks)
+	<-t.done
+	if err := t.writer.Close(); t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// mixCloseTees of the mixer, returning the first error of any
+func (m *Mixer) mixCloseTees() (err error) {
+	m.mixMutex.Lock()
+	tees := append([]*Tee(nil), m.mixTees...)
+	m.mixMutex.Unlock()
+	for _, t := range tees {
+		if closeErr := t.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return
+}
+
+// push the block that is full to the writer, or drop it if the queue is full; the caller must hold the mixMutex
+func (t *Tee) push() {
+	select {
+	case t.blocks <- t.block:
+		t.block = <-t.free // never waits, as there is one more buffer than the queue and the writer can hold
+	default:
+		atomic.AddInt64(&t.dropped, 1)
+		atomic.AddInt64(&t.mixer.mixStatTeeDropped, 1)
+		t.block = t.block[:0]
+	}
+}
+
+// writeLoop of the queued blocks, until the queue is closed; after an error, it only recycles them
+func (t *Tee) writeLoop() {
+	defer close(t.done)
+	for block := range t.blocks {
+		if t.err == nil {
+			_, t.err = t.writer.Write(block)
+		}
+		t.free <- block[:0]
+	}
+}
@@ -0,0 +1,108 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestTeeOutput(t *testing.T) {
+	testMixSetup()
+	dir, err := ioutil.TempDir("", "mix-tee")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file, err := os.Create(filepath.Join(dir, "tee.wav"))
+	assert.Nil(t, err)
+	defer file.Close()
+	tee, err := TeeOutput(file, spec.AudioS16)
+	assert.Nil(t, err)
+	SetFireTone(441, 0, time.Second, 1.0, 0)
+	var played []float32
+	for n := 0; n < 2000; n++ {
+		played = append(played, float32(NextSample()[0]))
+	}
+	block := make([]float32, 1000)
+	NextBlock(block, 1000)
+	played = append(played, block...)
+	assert.Nil(t, tee.Close()) // mid-playback
+	assert.Nil(t, tee.Close())
+	NextBlock(block, 1000)
+	out, recorded, err := wav.Load(file.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 1}, *recorded)
+	assert.Equal(t, 3000, len(out))
+	for n := range out {
+		assert.InDelta(t, played[n], float64(out[n].Values[0]), 1.0/0x7FFF)
+	}
+	assert.NotEqual(t, float64(0), float64(out[2525].Values[0])) // of NextBlock
+}
+
+func TestTeeOutput_Teardown(t *testing.T) {
+	testMixSetup()
+	var out bytes.Buffer
+	_, err := TeeOutput(&out, spec.AudioF32)
+	assert.Nil(t, err)
+	for n := 0; n < 100; n++ {
+		NextSample()
+	}
+	Teardown() // writes the partial block
+	assert.Equal(t, 44+100*4, out.Len())
+	for n := 0; n < 2*TeeBlockFrames; n++ {
+		NextSample()
+	}
+	assert.Equal(t, 44+100*4, out.Len())
+}
+
+func TestTeeOutput_FAIL(t *testing.T) {
+	m := newMixer(source.NewCache())
+	_, err := m.TeeOutput(ioutil.Discard, spec.AudioF32)
+	assert.EqualError(t, err, "Must configure the mixer before teeing its output")
+}
+
+func TestTeeOutput_Dropped(t *testing.T) {
+	testMixSetup()
+	slow := &testSlowWriter{release: make(chan bool)}
+	tee, err := TeeOutput(slow, spec.AudioF32)
+	assert.Nil(t, err)
+	frames := (TeeQueueBlocks + 4) * TeeBlockFrames
+	start := time.Now()
+	for n := 0; n < frames; n++ {
+		NextSample()
+	}
+	assert.True(t, time.Since(start) < 5*time.Second) // never waited for the writer
+	dropped := tee.Dropped()
+	assert.True(t, dropped >= 3)
+	assert.Equal(t, dropped, Stats().TeeDropped)
+	close(slow.release)
+	assert.Nil(t, tee.Close())
+	assert.Equal(t, 44+(int64(frames)-dropped*TeeBlockFrames)*4, int64(slow.out.Len()))
+	ResetStats()
+	assert.Equal(t, int64(0), Stats().TeeDropped)
+}
+
+//
+// Private
+//
+
+// testSlowWriter of a disk that cannot keep up: it writes the header, but waits for the release of each block
+type testSlowWriter struct {
+	release chan bool
+	out     bytes.Buffer
+}
+
+func (w *testSlowWriter) Write(p []byte) (int, error) {
+	if len(p) >= TeeBlockFrames {
+		<-w.release
+	}
+	return w.out.Write(p)
+}
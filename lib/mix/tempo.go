@@ -0,0 +1,273 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// DefaultBPM and DefaultStepsPerBeat of the tempo, until SetTempo, i.e. sixteenth notes at 120 beats per minute
+const (
+	DefaultBPM          = 120
+	DefaultStepsPerBeat = 4
+)
+
+// SetTempo in beats per minute from step zero, until the first tempo change, and the # of steps in each beat, for SetFireAtStep.
+// Changing the tempo only affects the fires set after it; those already set keep their time.
+func (m *Mixer) SetTempo(bpm float64, stepsPerBeat int) {
+	if bpm <= 0 || stepsPerBeat <= 0 {
+		debug.Warnf("mix.SetTempo(%v, %d) ignored: must be greater than zero", bpm, stepsPerBeat)
+		return
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixTempoMap[0].bpm = bpm
+	m.mixStepsPerBeat = stepsPerBeat
+	m.mixTempoMapUpdate()
+	m.mixTickSchedule(false)
+}
+
+// AddTempoChange to the tempo map, in beats per minute from a step onward, e.g. a series of them for a ritardando.
+// Changes may be added in any order; a change at step zero replaces the tempo of SetTempo, and a change at the same step as another
+// replaces it. Like SetTempo, it only affects the fires set after it.
+func (m *Mixer) AddTempoChange(atStep int, bpm float64) {
+	if bpm <= 0 || atStep < 0 {
+		debug.Warnf("mix.AddTempoChange(%d, %v) ignored: step must not be negative, and bpm must be greater than zero", atStep, bpm)
+		return
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	i := sort.Search(len(m.mixTempoMap), func(i int) bool {
+		return m.mixTempoMap[i].step >= atStep
+	})
+	if i < len(m.mixTempoMap) && m.mixTempoMap[i].step == atStep {
+		m.mixTempoMap[i].bpm = bpm
+	} else {
+		m.mixTempoMap = append(m.mixTempoMap, mixTempo{})
+		copy(m.mixTempoMap[i+1:], m.mixTempoMap[i:])
+		m.mixTempoMap[i] = mixTempo{step: atStep, bpm: bpm}
+	}
+	m.mixTempoMapUpdate()
+	m.mixTickSchedule(false)
+}
+
+// ClearTempoChanges from the tempo map, leaving only the tempo from step zero.
+func (m *Mixer) ClearTempoChanges() {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixTempoMap = m.mixTempoMap[:1]
+	m.mixTickSchedule(false)
+}
+
+// SetSwing from 0 (straight) to 1, which delays every other step by half a step: the second of each pair, i.e. steps 1, 3, 5...
+// counting from zero. It applies to the fires set at a step after it; those already set keep their time.
+func (m *Mixer) SetSwing(amount float64) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixSwing = math.Max(0, math.Min(1, amount))
+}
+
+// SetGroove of micro-timing offsets, positive (late) or negative (early), applied to each step in turn, repeating, e.g. from a
+// recorded performance; nil for none. Like swing, it applies to the fires set at a step after it, and never before time zero.
+func (m *Mixer) SetGroove(offsets []time.Duration) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixGroove = append([]time.Duration(nil), offsets...)
+}
+
+// SetStepOffset of step zero, since the start of mix playback, e.g. to leave some silence before the music.
+func (m *Mixer) SetStepOffset(offset time.Duration) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixStepOffset = offset
+	m.mixTickSchedule(false)
+}
+
+// StepDuration at the tempo from step zero, to the nearest nanosecond; the time of each step is computed exactly, never by
+// adding up this rounded duration.
+func (m *Mixer) StepDuration() time.Duration {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return time.Duration(math.Round(m.mixTempoMap[0].stepDur(m.mixStepsPerBeat)))
+}
+
+// StepAt a time since the start of mix playback, the step that it falls within, by the tempo map and step offset;
+// negative before the step offset.
+func (m *Mixer) StepAt(t time.Duration) int {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixStepAt(t)
+}
+
+// SetFireAtStep is SetFire at the beginning of a step, by the tempo map and step offset, with swing and groove,
+// sustained for a # of steps, or 0 for the natural length of the source.
+func (m *Mixer) SetFireAtStep(source string, step int, sustainSteps float64, volume float64, pan float64) *fire.Fire {
+	return m.mixSetFireAtStep("SetFireAtStep", source, float64(step), sustainSteps, volume, pan)
+}
+
+// SetFireAtBeat is SetFire at a beat, which may be fractional, by the tempo map and step offset, with swing and groove
+// if it falls on a step, sustained for a # of beats, or 0 for the natural length of the source.
+func (m *Mixer) SetFireAtBeat(source string, beat float64, sustainBeats float64, volume float64, pan float64) *fire.Fire {
+	m.mixMutex.Lock()
+	stepsPerBeat := float64(m.mixStepsPerBeat)
+	m.mixMutex.Unlock()
+	return m.mixSetFireAtStep("SetFireAtBeat", source, beat*stepsPerBeat, sustainBeats*stepsPerBeat, volume, pan)
+}
+
+// SetSustainFollowsTempo, if on, defers the sustain of each fire set at a step or beat after it until the fire goes live, shortly
+// before it begins, by the tempo map as it is then, such that tempo changes made after scheduling still affect the length of its
+// notes; its begin is kept. A sustain of 0 is still the natural length of the source. Until then, its sustain is as if it were off.
+func (m *Mixer) SetSustainFollowsTempo(on bool) {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixSustainTempo = on
+}
+
+// GetSustainFollowsTempo setting, see SetSustainFollowsTempo
+func (m *Mixer) GetSustainFollowsTempo() bool {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixSustainTempo
+}
+
+//
+// Private
+//
+
+// mixSustain of a fire, in steps from a step, whose end is resolved as it goes live, see SetSustainFollowsTempo
+type mixSustain struct {
+	step  float64
+	steps float64
+}
+
+// mixTempo from a step onward, beginning at a time in nanoseconds since step zero, exactly
+type mixTempo struct {
+	step int
+	bpm  float64
+	at   float64
+}
+
+// stepDur in nanoseconds, exactly, at a # of steps per beat
+func (t mixTempo) stepDur(stepsPerBeat int) float64 {
+	return float64(time.Minute) / (t.bpm * float64(stepsPerBeat))
+}
+
+// mixTempoMapUpdate the time that each tempo begins; the caller must hold the mixMutex
+func (m *Mixer) mixTempoMapUpdate() {
+	for i := 1; i < len(m.mixTempoMap); i++ {
+		prev := m.mixTempoMap[i-1]
+		m.mixTempoMap[i].at = prev.at + float64(m.mixTempoMap[i].step-prev.step)*prev.stepDur(m.mixStepsPerBeat)
+	}
+}
+
+// mixStepBegin since the start of mix playback, of a step that may be fractional, to the nearest nanosecond;
+// the caller must hold the mixMutex
+func (m *Mixer) mixStepBegin(step float64) time.Duration {
+	i := sort.Search(len(m.mixTempoMap), func(i int) bool {
+		return float64(m.mixTempoMap[i].step) > step
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+	tempo := m.mixTempoMap[i]
+	return m.mixStepOffset + time.Duration(math.Round(tempo.at+(step-float64(tempo.step))*tempo.stepDur(m.mixStepsPerBeat)))
+}
+
+// mixStepAt a time, the step that it falls within; the caller must hold the mixMutex
+func (m *Mixer) mixStepAt(t time.Duration) int {
+	at := float64(t - m.mixStepOffset)
+	i := sort.Search(len(m.mixTempoMap), func(i int) bool {
+		return m.mixTempoMap[i].at > at
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+	step := m.mixTempoMap[i].step + int(math.Floor((at-m.mixTempoMap[i].at)/m.mixTempoMap[i].stepDur(m.mixStepsPerBeat)))
+	if m.mixStepBegin(float64(step+1)) <= t { // the beginning of each step is rounded to the nearest nanosecond
+		step++
+	} else if m.mixStepBegin(float64(step)) > t {
+		step--
+	}
+	return step
+}
+
+// mixStepNearest to a time, by the tempo map; the caller must hold the mixMutex
+func (m *Mixer) mixStepNearest(t time.Duration) int {
+	step := m.mixStepAt(t)
+	if m.mixStepBegin(float64(step+1))-t < t-m.mixStepBegin(float64(step)) {
+		step++
+	}
+	return step
+}
+
+// mixStepFeel of a step, the offset of its beginning by swing and groove, if it is a whole step; the caller must hold the mixMutex
+func (m *Mixer) mixStepFeel(step float64) (offset time.Duration) {
+	if step != math.Floor(step) {
+		return 0
+	}
+	whole := int(step)
+	if m.mixSwing > 0 && whole%2 != 0 {
+		offset += time.Duration(math.Round(float64(m.mixStepBegin(step+1)-m.mixStepBegin(step)) * m.mixSwing / 2))
+	}
+	if len(m.mixGroove) > 0 {
+		offset += m.mixGroove[(whole%len(m.mixGroove)+len(m.mixGroove))%len(m.mixGroove)]
+	}
+	return
+}
+
+func (m *Mixer) mixSetFireAtStep(name string, source string, step float64, sustainSteps float64, volume float64, pan float64) *fire.Fire {
+	m.mixMutex.Lock()
+	begin := m.mixStepBegin(step)
+	sustain := m.mixStepSustain(step, sustainSteps)
+	if begin += m.mixStepFeel(step); begin < 0 {
+		begin = 0
+	}
+	deferred := m.mixSustainTempo && sustainSteps != 0
+	m.mixMutex.Unlock()
+	f, err := m.mixNewFire(source, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.%s(%s) failed: %s", name, source, err)
+		return nil
+	}
+	if deferred {
+		m.mixMutex.Lock()
+		m.mixSustains[f] = mixSustain{step, sustainSteps}
+		m.mixMutex.Unlock()
+	}
+	m.mixSchedule(f)
+	return f
+}
+
+// mixStepSustain of a # of steps from a step, by the tempo map, exactly as the steps begin, or 0 for none;
+// the caller must hold the mixMutex
+func (m *Mixer) mixStepSustain(step float64, steps float64) time.Duration {
+	if steps == 0 {
+		return 0
+	}
+	return m.mixStepBegin(step+steps) - m.mixStepBegin(step)
+}
+
+// mixResolveSustain of a fire as it goes live, by the tempo map as it is now, if its sustain follows the tempo;
+// the caller must hold the mixMutex
+func (m *Mixer) mixResolveSustain(f *fire.Fire) {
+	s, ok := m.mixSustains[f]
+	if !ok {
+		return
+	}
+	delete(m.mixSustains, f)
+	f.SetEnd(f.BeginTz + m.mixSchedTzOf(m.mixStepSustain(s.step, s.steps)))
+}
+
+func (m *Mixer) mixResetTempo() {
+	m.mixTempoMap = []mixTempo{{0, DefaultBPM, 0}}
+	m.mixStepsPerBeat = DefaultStepsPerBeat
+	m.mixStepOffset = 0
+	m.mixSwing = 0
+	m.mixGroove = nil
+	m.mixSustainTempo = false
+	m.mixSustains = make(map[*fire.Fire]mixSustain)
+}
@@ -0,0 +1,145 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetTempo(t *testing.T) {
+	testMixSetup()
+	assert.Equal(t, 125*time.Millisecond, StepDuration())
+	SetTempo(90, 3)
+	assert.Equal(t, time.Duration(222222222), StepDuration())
+	SetTempo(0, 4) // ignored
+	assert.Equal(t, time.Duration(222222222), StepDuration())
+}
+
+func TestStepAt(t *testing.T) {
+	testMixSetup()
+	SetTempo(140, 4)
+	SetStepOffset(time.Second)
+	for step := -3; step < 1000; step++ {
+		begin := time.Second + time.Duration(math.Round(float64(step)*float64(time.Minute)/560))
+		assert.Equal(t, step, StepAt(begin))
+		assert.Equal(t, step-1, StepAt(begin-1))
+	}
+}
+
+func TestSetFireAtStep(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetStepOffset(2 * time.Second)
+	first := SetFireAtStep(src, 16, 2, 1.0, 0)
+	SetTempo(60, 2) // only affects the fires set after it
+	second := SetFireAtStep(src, 1, 0, 1.0, 0)
+	assert.Equal(t, 4*time.Second, first.BeginAt())
+	assert.Equal(t, 250*time.Millisecond, first.Sustain())
+	assert.Equal(t, 2500*time.Millisecond, second.BeginAt())
+	assert.Equal(t, 100*time.Millisecond, second.Sustain())
+	assert.Nil(t, SetFireAtStep("ThisDoesNotExist.wav", 0, 1, 1.0, 0))
+}
+
+func TestAddTempoChange(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	AddTempoChange(16, 60)  // after four beats at 120 BPM, i.e. 2 seconds
+	AddTempoChange(8, 240)  // out of order: after two beats at 120 BPM, i.e. 1 second
+	AddTempoChange(0, 60)   // replaces the initial tempo: two beats at 60 BPM, i.e. 2 seconds
+	AddTempoChange(-1, 60)  // ignored
+	AddTempoChange(16, 120) // replaces the change at step 16
+	assert.Equal(t, []mixTempo{{0, 60, 0}, {8, 240, 2e9}, {16, 120, 2.5e9}}, mixDefault.mixTempoMap)
+	assert.Equal(t, 2*time.Second, SetFireAtStep(src, 8, 0, 1.0, 0).BeginAt())
+	assert.Equal(t, 2500*time.Millisecond, SetFireAtStep(src, 16, 0, 1.0, 0).BeginAt())
+	assert.Equal(t, 3*time.Second, SetFireAtStep(src, 20, 0, 1.0, 0).BeginAt())
+	spanning := SetFireAtStep(src, 6, 6, 1.0, 0) // two steps at 60 BPM, then four at 240 BPM
+	assert.Equal(t, 1500*time.Millisecond, spanning.BeginAt())
+	assert.Equal(t, 750*time.Millisecond, spanning.Sustain())
+	assert.Equal(t, 2750*time.Millisecond, SetFireAtBeat(src, 4.5, 0, 1.0, 0).BeginAt())
+	assert.Equal(t, 250*time.Millisecond, SetFireAtBeat(src, 0.25, 0.25, 1.0, 0).BeginAt())
+	ClearTempoChanges()
+	assert.Equal(t, []mixTempo{{0, 60, 0}}, mixDefault.mixTempoMap)
+}
+
+func TestStepAt_TempoMap(t *testing.T) {
+	testMixSetup()
+	SetStepOffset(500 * time.Millisecond)
+	AddTempoChange(7, 93)
+	AddTempoChange(31, 177.5)
+	AddTempoChange(64, 61)
+	for step := -5; step < 200; step++ {
+		mixDefault.mixMutex.Lock()
+		begin := mixDefault.mixStepBegin(float64(step))
+		mixDefault.mixMutex.Unlock()
+		assert.Equal(t, step, StepAt(begin))
+		assert.Equal(t, step-1, StepAt(begin-1))
+	}
+}
+
+func TestSetSwing(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	straight := SetFireAtStep(src, 1, 0, 1.0, 0)
+	SetSwing(1)
+	assert.Equal(t, time.Duration(0), SetFireAtStep(src, 0, 0, 1.0, 0).BeginAt())
+	assert.Equal(t, testAt(187500*time.Microsecond), SetFireAtStep(src, 1, 0, 1.0, 0).BeginAt())
+	assert.Equal(t, testAt(250*time.Millisecond), SetFireAtStep(src, 2, 0, 1.0, 0).BeginAt())
+	SetSwing(0.5)
+	assert.Equal(t, testAt(406250*time.Microsecond), SetFireAtStep(src, 3, 0, 1.0, 0).BeginAt())
+	assert.Equal(t, testAt(62500*time.Microsecond), SetFireAtBeat(src, 0.125, 0, 1.0, 0).BeginAt()) // half a step is not swung
+	assert.Equal(t, testAt(125*time.Millisecond), straight.BeginAt())                               // unaffected
+}
+
+func TestSetGroove(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 100*time.Millisecond)
+	SetGroove([]time.Duration{-10 * time.Millisecond, 20 * time.Millisecond})
+	assert.Equal(t, time.Duration(0), SetFireAtStep(src, 0, 0, 1.0, 0).BeginAt()) // never before time zero
+	assert.Equal(t, testAt(145*time.Millisecond), SetFireAtStep(src, 1, 0, 1.0, 0).BeginAt())
+	assert.Equal(t, testAt(240*time.Millisecond), SetFireAtStep(src, 2, 0, 1.0, 0).BeginAt())
+	early := SetFireAtStep(src, 4, 2, 1.0, 0)
+	SetGroove(nil)
+	assert.Equal(t, testAt(490*time.Millisecond), early.BeginAt()) // unaffected
+	assert.Equal(t, 250*time.Millisecond, early.Sustain())
+	assert.Equal(t, testAt(500*time.Millisecond), SetFireAtStep(src, 4, 0, 1.0, 0).BeginAt())
+}
+
+func TestSetSustainFollowsTempo(t *testing.T) {
+	testMixSetup()
+	src := source.ToneKey(source.WaveSine, 441, 2*time.Second)
+	scheduled := SetFireAtStep(src, 4, 2, 1.0, 0)
+	SetSustainFollowsTempo(true)
+	assert.True(t, GetSustainFollowsTempo())
+	deferred := SetFireAtStep(src, 4, 2, 1.0, 0)
+	natural := SetFireAtBeat(src, 1, 0, 1.0, 0)
+	assert.Equal(t, 250*time.Millisecond, deferred.Sustain()) // as if it were off, until it goes live
+	AddTempoChange(2, 93)
+	SetSustainFollowsTempo(false)
+	want := SetFireAtStep(src, 4, 2, 1.0, 0) // by the same tempo map, at schedule time
+	for n := 0; n < 44100; n++ {
+		NextSample()
+	}
+	assert.Equal(t, testAt(500*time.Millisecond), deferred.BeginAt()) // its begin is kept
+	assert.Equal(t, want.EndTz-want.BeginTz, deferred.EndTz-deferred.BeginTz)
+	assert.Equal(t, 250*time.Millisecond, scheduled.Sustain())
+	assert.Equal(t, 2*time.Second, natural.Sustain())
+	assert.Empty(t, mixDefault.mixSustains)
+	SetSustainFollowsTempo(true)
+	Teardown()
+	assert.False(t, GetSustainFollowsTempo())
+}
+
+//
+// Test Components
+//
+
+// testAt a time, on the sample that a fire would begin
+func testAt(d time.Duration) time.Duration {
+	tz, _ := mixDefault.mixBeginTzOf(d)
+	return mixDefault.mixDurOf(tz)
+}
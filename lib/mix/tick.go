@@ -0,0 +1,133 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// MIDIClockPPQN is the resolution of MIDI clock, in pulses (ticks) per quarter note, i.e. per beat
+const MIDIClockPPQN = 24
+
+// SetTickCallback to call fn on each tick of a clock at a # of ticks per beat, e.g. MIDIClockPPQN to send MIDI clock to
+// external hardware, or nil fn to stop. Tick 0 is at beat zero, by the step offset, and each tick after it is at its exact
+// time by the tempo map, without swing or groove.
+//
+// The ticks are derived from the sample clock: each is due as the mix loop mixes the frame that it begins on, which is ahead
+// of the speakers by the output latency, so fn is called slightly before the audio moment of its tick, and at is that exact
+// musical time since the start, for the receiver to compensate any jitter. fn is called on its own goroutine, never the mix
+// loop, and never concurrently with itself, with every tick in order. No tick is dropped or repeated as the mix plays,
+// pauses and resumes; a seek continues from the first tick at or after the new mix position.
+func (m *Mixer) SetTickCallback(ppqn int, fn func(tick int64, at time.Duration)) {
+	if fn != nil && ppqn <= 0 {
+		debug.Warnf("mix.SetTickCallback(%d) ignored: ticks per beat must be greater than zero", ppqn)
+		return
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	m.mixTickFn = fn
+	m.mixTickPPQN = ppqn
+	m.mixTickPending = m.mixTickPending[:0]
+	m.mixTickSchedule(true)
+	m.mixRunTicks(fn != nil)
+}
+
+//
+// Private
+//
+
+// mixTick that is due, and its exact time since the start
+type mixTick struct {
+	tick int64
+	at   time.Duration
+}
+
+// mixTickAt of a tick, the exact time since the start, by the tempo map; the caller must hold the mixMutex
+func (m *Mixer) mixTickAt(tick int64) time.Duration {
+	return m.mixStepBegin(float64(tick) * float64(m.mixStepsPerBeat) / float64(m.mixTickPPQN))
+}
+
+// mixTickSchedule the next tick after a seek, the first at or after the mix position; else, e.g. after a change of the tempo map,
+// only the frame of the next tick moves, so that none is repeated, and any that is now in the past is due on the next frame.
+// The caller must hold the mixMutex
+func (m *Mixer) mixTickSchedule(seek bool) {
+	if m.mixTickFn == nil || m.masterFreq == 0 {
+		return
+	}
+	m.mixTickNextTz = m.mixTickTzOf(m.mixTickNext)
+	if !seek {
+		return
+	}
+	m.mixTickNext = 0
+	if step := m.mixStepAt(m.mixSchedDurOf(m.nowTz)); step > 0 { // a tick at or before the mix position, to count on from
+		m.mixTickNext = int64(step) * int64(m.mixTickPPQN) / int64(m.mixStepsPerBeat)
+	}
+	for m.mixTickNextTz = m.mixTickTzOf(m.mixTickNext); m.mixTickNextTz < m.nowTz; m.mixTickNextTz = m.mixTickTzOf(m.mixTickNext) {
+		m.mixTickNext++
+	}
+}
+
+// mixTickTzOf a tick, the frame that it begins on, like a fire at its time; the caller must hold the mixMutex
+func (m *Mixer) mixTickTzOf(tick int64) spec.Tz {
+	tz, _ := m.mixBeginTzOf(m.mixTickAt(tick))
+	return tz
+}
+
+// mixCheckTicks once per sample, before the mix position moves on, and signal the callback of each tick due on this frame;
+// the caller must hold the mixMutex
+func (m *Mixer) mixCheckTicks() {
+	if m.mixTickFn == nil || m.nowTz < m.mixTickNextTz {
+		return
+	}
+	for m.nowTz >= m.mixTickNextTz { // more than one, at a tempo faster than the frequency
+		m.mixTickPending = append(m.mixTickPending, mixTick{m.mixTickNext, m.mixTickAt(m.mixTickNext)})
+		m.mixTickNext++
+		m.mixTickNextTz = m.mixTickTzOf(m.mixTickNext)
+	}
+	select {
+	case m.mixTickSignal <- struct{}{}:
+	default:
+	}
+}
+
+// mixRunTicks loop on or off, e.g. off by the teardown, such that a mixer that is torn down leaks no goroutine;
+// the caller must hold the mixMutex
+func (m *Mixer) mixRunTicks(on bool) {
+	if on == (m.mixTickStop != nil) {
+		return
+	}
+	if !on {
+		close(m.mixTickStop)
+		m.mixTickStop = nil
+		return
+	}
+	m.mixTickStop = make(chan struct{})
+	m.mixTickDone = make(chan struct{})
+	go m.mixTickLoop(m.mixTickStop, m.mixTickDone)
+}
+
+// mixTickLoop calls the callback with the ticks due each time it is signaled, until stopped, on one goroutine, so never concurrently
+// with itself
+func (m *Mixer) mixTickLoop(stop, done chan struct{}) {
+	defer close(done)
+	var ticks []mixTick
+	for {
+		select {
+		case <-stop:
+			return
+		case <-m.mixTickSignal:
+		}
+		m.mixMutex.Lock()
+		fn := m.mixTickFn
+		ticks, m.mixTickPending = m.mixTickPending, ticks[:0] // reused, so that the mix loop need not allocate
+		m.mixMutex.Unlock()
+		if fn == nil {
+			continue
+		}
+		for _, t := range ticks {
+			fn(t.tick, t.at)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetTickCallback(t *testing.T) {
+	testMixSetup()
+	SetTempo(120, 4) // a beat of 500ms, so 24 ticks of 20.833ms
+	ticks := testTicks(MIDIClockPPQN)
+	Render(time.Second)
+	got := testTicksAwait(t, ticks, 48) // tick 48 begins on the frame at 1s, which is not yet mixed
+	for i, tk := range got {
+		assert.Equal(t, int64(i), tk.tick)
+		assert.Equal(t, time.Duration(math.Round(float64(i)*float64(time.Second)/48)), tk.at)
+	}
+	testTicksNone(t, ticks)
+}
+
+func TestSetTickCallback_PauseSeek(t *testing.T) {
+	testMixSetup()
+	SetTempo(120, 4)
+	ticks := testTicks(MIDIClockPPQN)
+	for i := 0; i < 44100/4; i++ {
+		NextSample()
+	}
+	assert.Equal(t, int64(11), testTicksAwait(t, ticks, 12)[11].tick)
+	Pause()
+	for i := 0; i < 44100/4; i++ {
+		NextSample()
+	}
+	Resume()
+	for i := 0; i < 44100/4; i++ {
+		NextSample()
+	}
+	got := testTicksAwait(t, ticks, 12) // none dropped nor repeated
+	assert.Equal(t, int64(12), got[0].tick)
+	assert.Equal(t, int64(23), got[11].tick)
+	SeekTo(2*time.Second + time.Millisecond)
+	NextSample()
+	testTicksNone(t, ticks) // tick 96 began before the seek
+	for i := 0; i < 44100/48; i++ {
+		NextSample()
+	}
+	assert.Equal(t, int64(97), testTicksAwait(t, ticks, 1)[0].tick)
+}
+
+func TestSetTickCallback_TempoChange(t *testing.T) {
+	testMixSetup()
+	SetTempo(60, 4)
+	ticks := testTicks(4)
+	Render(time.Second + time.Millisecond) // ticks 0 to 4, at 250ms each
+	assert.Equal(t, int64(4), testTicksAwait(t, ticks, 5)[4].tick)
+	SetTempo(240, 4) // ticks 5 to 16 are now in the past, from 312.5ms to 1s, so they are due at once
+	NextSample()
+	got := testTicksAwait(t, ticks, 12)
+	assert.Equal(t, int64(5), got[0].tick)
+	assert.Equal(t, 312500*time.Microsecond, got[0].at)
+	assert.Equal(t, int64(16), got[11].tick)
+	testTicksNone(t, ticks)
+	SetTickCallback(0, nil)
+	Render(time.Second)
+	testTicksNone(t, ticks)
+}
+
+func TestSetTickCallback_Teardown(t *testing.T) {
+	m, err := New(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	assert.Nil(t, err)
+	m.SetTickCallback(MIDIClockPPQN, func(tick int64, at time.Duration) {})
+	m.mixMutex.Lock()
+	done := m.mixTickDone
+	m.mixMutex.Unlock()
+	m.Teardown()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("tick loop did not exit")
+	}
+	m.SetTickCallback(MIDIClockPPQN, func(tick int64, at time.Duration) {}) // runs again after the teardown
+	m.mixMutex.Lock()
+	assert.NotNil(t, m.mixTickStop)
+	m.mixMutex.Unlock()
+	m.SetTickCallback(0, nil)
+}
+
+//
+// Private
+//
+
+func testTicks(ppqn int) chan mixTick {
+	ticks := make(chan mixTick, 1000)
+	SetTickCallback(ppqn, func(tick int64, at time.Duration) {
+		ticks <- mixTick{tick, at}
+	})
+	return ticks
+}
+
+func testTicksAwait(t *testing.T, ticks chan mixTick, n int) (got []mixTick) {
+	for len(got) < n {
+		select {
+		case tk := <-ticks:
+			got = append(got, tk)
+		case <-time.After(time.Second):
+			t.Fatalf("%d of %d ticks", len(got), n)
+		}
+	}
+	return
+}
+
+func testTicksNone(t *testing.T, ticks chan mixTick) {
+	select {
+	case tk := <-ticks:
+		t.Fatalf("unexpected tick %d", tk.tick)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
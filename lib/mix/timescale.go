@@ -0,0 +1,98 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/fire"
+)
+
+// SetTimeScale of the schedule, e.g. 5 to audition a five-minute composition in one minute, without changing any time it was set
+// with; 1 (the default) plays it as is. Each time of the schedule maps to a sample of the output at the frequency divided by the scale,
+// and each source plays faster by the scale, so its pitch is higher too: the begin and sustain of each fire, its loop interval,
+// GetNowAt, SeekTo, ScheduleEnd, OutputContinueTo, Render, DurationToFrames and FramesToDuration are all times of the schedule.
+// The regions of sources, the pre-roll, the effects and AtFrame are not scaled. It must be set while stopped, i.e. before Start or
+// after Stop, else it is ignored; it also rescales the fires already scheduled, to the nearest sample, so set it before scheduling
+// for a render that is exact to the sample.
+func (m *Mixer) SetTimeScale(scale float64) {
+	if scale <= 0 || math.IsInf(scale, 0) || math.IsNaN(scale) {
+		debug.Warnf("mix.SetTimeScale(%v) ignored: scale must be positive", scale)
+		return
+	}
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	if m.nowTz != 0 {
+		debug.Warnf("mix.SetTimeScale(%v) ignored: must be set while stopped", scale)
+		return
+	}
+	m.mixRescale(scale)
+}
+
+// GetTimeScale of the schedule, see SetTimeScale
+func (m *Mixer) GetTimeScale() float64 {
+	m.mixMutex.Lock()
+	defer m.mixMutex.Unlock()
+	return m.mixTimeScale
+}
+
+//
+// Private
+//
+
+// mixSchedTzOf a time of the schedule, to the nearest sample at the master frequency divided by the time scale
+func (m *Mixer) mixSchedTzOf(d time.Duration) spec.Tz {
+	return spec.Tz(math.Round(d.Seconds() * m.masterFreq / m.mixTimeScale))
+}
+
+// mixSchedDurOf a # of samples, in time of the schedule, to the nearest nanosecond
+func (m *Mixer) mixSchedDurOf(tz spec.Tz) time.Duration {
+	return time.Duration(math.Round(float64(tz) * float64(time.Second) * m.mixTimeScale / m.masterFreq))
+}
+
+// mixRescale the schedule to a time scale, with every fire beginning and ending at the same time of the schedule as before, and
+// each fire waiting to be chained after another, and the low water; the caller must hold the mixMutex, while stopped
+func (m *Mixer) mixRescale(scale float64) {
+	ratio := m.mixTimeScale / scale
+	m.mixTimeScale = scale
+	m.mixPublishFreq()
+	fires := make([]*fire.Fire, 0, m.mixReadyFires.Len()+len(m.mixLiveFires))
+	fires = append(fires, m.mixReadyFires.Fires()...)
+	fires = append(fires, m.mixLiveFires...)
+	if len(fires) > 0 {
+		m.mixClearAllFires()
+		for _, f := range fires {
+			m.mixRescaleFire(f)
+		}
+		m.mixPushFires(fires)
+	}
+	for f := range m.mixChainWaiting {
+		m.mixRescaleFire(f)
+	}
+	for prev, chained := range m.mixChains {
+		for i := range chained {
+			m.mixChains[prev][i].gapTz = int64(math.Round(float64(chained[i].gapTz) * ratio))
+		}
+	}
+	m.mixLowWaterTz = spec.Tz(math.Round(float64(m.mixLowWaterTz) * ratio))
+	m.mixTickSchedule(true)
+}
+
+// mixRescaleFire to the time scale, by its record at the time scale it was set with; the caller must hold the mixMutex
+func (m *Mixer) mixRescaleFire(f *fire.Fire) {
+	record := f.Record()
+	beginTz, frac := m.mixBeginTzOf(record.Begin)
+	var endTz spec.Tz
+	if record.Sustain != 0 {
+		endTz = beginTz + m.mixSchedTzOf(record.Sustain)
+	}
+	f.SetTimeScale(m.mixTimeScale)
+	f.Restore(record)
+	f.BeginTz = beginTz
+	f.EndTz = endTz
+	if f.BeginFrac != 0 {
+		f.BeginFrac = frac
+	}
+}
@@ -0,0 +1,87 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetTimeScale(t *testing.T) {
+	testMixSetup()
+	SetTimeScale(5)
+	tone := SetFire(source.ToneKey(source.WaveSine, 441, time.Second), time.Second, 0, 1.0, 0)
+	short := SetFireTone(441, 2*time.Second, 500*time.Millisecond, 1.0, 0)
+	loop := SetFireLoop(source.ToneKey(source.WaveSine, 441, 100*time.Millisecond), 3*time.Second, 250*time.Millisecond, 4, 0, 1.0, 0)
+	assert.Equal(t, spec.Tz(8820), tone.BeginTz) // at 44100 / 5
+	assert.Equal(t, time.Second, tone.BeginAt())
+	assert.Equal(t, spec.Tz(4410), short.EndTz-short.BeginTz)
+	assert.Equal(t, spec.Tz(2205), loop.IntervalTz)
+	sustain, err := tone.EffectiveSustain()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, sustain) // the source of a second plays five times faster, in a second of the schedule
+	assert.Equal(t, 4*time.Second, ScheduleEnd())
+	for n := 0; n < 2*44100; n++ {
+		NextSample()
+	}
+	assert.Equal(t, 10*time.Second, GetNowAt())
+	assert.Equal(t, 0, FireCount()) // all ten seconds of the schedule are done
+	SetTimeScale(2)                 // ignored during playback
+	assert.Equal(t, 5.0, GetTimeScale())
+	Teardown()
+	assert.Equal(t, 1.0, GetTimeScale())
+}
+
+func TestSetTimeScale_Frames(t *testing.T) {
+	testMixSetup()
+	clock := NewFakeClock(time.Unix(1000, 0))
+	SetClock(clock)
+	defer SetClock(nil)
+	SetTimeScale(2)
+	f := SetFire(source.ToneKey(source.WaveSine, 441, time.Second), time.Second, 0, 1.0, 0)
+	assert.Equal(t, spec.Tz(22050), f.BeginTz)
+	assert.Equal(t, f.BeginTz, DurationToFrames(time.Second)) // the frame the fire begins on
+	assert.Equal(t, time.Second, FramesToDuration(22050))
+	assert.Equal(t, spec.Tz(22049), DurationToFrames(time.Second-time.Nanosecond))
+	for f := spec.Tz(0); f < 10000; f++ {
+		assert.Equal(t, f, DurationToFrames(FramesToDuration(f)))
+	}
+	StartAt(Now())
+	assert.Equal(t, time.Unix(1000, 0).Add(500*time.Millisecond), AtFrame(22050)) // heard in half a second
+	Teardown()
+	assert.Equal(t, spec.Tz(44100), DurationToFrames(time.Second))
+}
+
+func TestSetTimeScale_Rescale(t *testing.T) {
+	render := func(scale float64, before bool) []float64 {
+		testMixSetup()
+		if before {
+			SetTimeScale(scale)
+		}
+		SetFireTone(441, 100*time.Millisecond, 200*time.Millisecond, 1.0, 0)
+		SetFireLoop(source.ToneKey(source.WaveSquare, 882, 50*time.Millisecond), 400*time.Millisecond, 100*time.Millisecond, 3, 0, 0.5, 0)
+		if !before {
+			SetTimeScale(scale)
+		}
+		out, err := Render(time.Second)
+		assert.Nil(t, err)
+		return out
+	}
+	plain := render(1, false)
+	assert.Equal(t, plain, render(1, true))
+	fast := render(4, true)
+	assert.Equal(t, 11025, len(fast)) // a second of the schedule, in a quarter of a second
+	assert.Equal(t, fast, render(4, false))
+	testMixSetup()
+	SetTimeScale(4)
+	f := SetFireTone(441, 100*time.Millisecond, 200*time.Millisecond, 1.0, 0)
+	SetTimeScale(1) // back again, to within a sample of the coarser scale
+	assert.InDelta(t, 4410, float64(f.BeginTz), 4)
+	assert.InDelta(t, 8820, float64(f.EndTz-f.BeginTz), 4)
+	SetTimeScale(0) // ignored
+	assert.Equal(t, 1.0, GetTimeScale())
+}
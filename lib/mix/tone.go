@@ -0,0 +1,33 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// ToneDefaultSustain of a tone fired with no sustain, e.g. a metronome click
+const ToneDefaultSustain = 50 * time.Millisecond
+
+// SetFireTone is SetFire, playing a sine wave at a frequency in Hz, synthesized at the master frequency instead of loading a file.
+func (m *Mixer) SetFireTone(freq float64, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return m.SetFireWaveform(source.WaveSine, freq, begin, sustain, volume, pan)
+}
+
+// SetFireWaveform is SetFireTone, with a choice of waveform, e.g. source.WaveSquare or source.WaveNoise.
+func (m *Mixer) SetFireWaveform(wave source.Waveform, freq float64, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	if sustain <= 0 {
+		sustain = ToneDefaultSustain
+	}
+	src := source.ToneKey(wave, freq, sustain)
+	f, err := m.mixNewFire(src, begin, sustain, volume, pan)
+	if err != nil {
+		debug.Warnf("mix.SetFireWaveform(%s) failed: %s", src, err)
+		return nil
+	}
+	m.mixSchedule(f)
+	return f
+}
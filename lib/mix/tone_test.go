@@ -0,0 +1,81 @@
+// Package mix combines sources into an output audio stream
+package mix
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/lib/source"
+)
+
+func TestSetFireTone(t *testing.T) {
+	testMixSetup()
+	SetSoundsPath("sounds/") // a tone never loads from the sounds path
+	defer SetSoundsPath("")
+	f := SetFireTone(441, 10*time.Millisecond, 20*time.Millisecond, 1.0, 0)
+	assert.NotNil(t, f)
+	assert.Equal(t, source.ToneKey(source.WaveSine, 441, 20*time.Millisecond), f.Source)
+	out := make([]sample.Value, 44100/10)
+	for n := range out {
+		out[n] = NextSample()[0]
+	}
+	for n := 0; n < 441; n++ {
+		assert.Equal(t, sample.Value(0), out[n]) // sample-accurate begin
+	}
+	var peak sample.Value
+	for n := 441; n < 441+882; n++ {
+		if out[n].Abs() > peak {
+			peak = out[n].Abs()
+		}
+	}
+	assert.InDelta(t, 1/1.61803398875, float64(peak), 0.001)
+	for n := 441 + 882; n < len(out); n++ {
+		assert.Equal(t, sample.Value(0), out[n]) // sample-accurate end
+	}
+}
+
+func TestSetFireWaveform(t *testing.T) {
+	testMixSetup()
+	f := SetFireWaveform(source.WaveSquare, 1000, 0, 0, 1.0, 0)
+	assert.NotNil(t, f)
+	assert.Equal(t, source.ToneKey(source.WaveSquare, 1000, ToneDefaultSustain), f.Source)
+	assert.Nil(t, SetFireWaveform(source.Waveform("sawtooth"), 1000, 0, 0, 1.0, 0))
+}
+
+func TestFire_SetTone(t *testing.T) {
+	defer func(fires int) { mixDefault.mixParallelFires = fires }(mixDefault.mixParallelFires)
+	for _, parallel := range []bool{false, true} {
+		plain := testToneRMS(parallel, 0)
+		dull := testToneRMS(parallel, -12)
+		assert.InDelta(t, -12, 20*math.Log10(dull/plain), 1, "parallel %v", parallel)
+	}
+}
+
+//
+// Private
+//
+
+// testToneRMS of the output of a bright tone at a gain of its highs, see fire.SetTone, mixed in parallel or not
+func testToneRMS(parallel bool, highDB float64) float64 {
+	testMixSetup()
+	mixDefault.mixParallelFires = math.MaxInt32
+	if parallel {
+		mixDefault.mixParallelFires = 1 // mixed ahead in blocks
+	}
+	f := SetFireTone(8000, 10*time.Millisecond, 200*time.Millisecond, 0.5, 0)
+	g := SetFireTone(9000, 10*time.Millisecond, 200*time.Millisecond, 0.25, 0)
+	f.SetTone(0, highDB) // before it goes live
+	g.SetTone(0, highDB)
+	var sum float64
+	for n := 0; n < 44100/10; n++ {
+		v := float64(NextSample()[0])
+		if n >= 44100/20 {
+			sum += v * v
+		}
+	}
+	return math.Sqrt(sum)
+}
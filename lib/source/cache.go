@@ -0,0 +1,86 @@
+// Package source models a single audio source
+package source
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// CacheStats of the sources in memory, counted since the cache was made, e.g. for monitoring
+type CacheStats struct {
+	Hits      int64 // sources prepared that were already in memory
+	Misses    int64 // sources prepared that had to be loaded
+	Evictions int64 // sources removed from memory to keep it under the cache limit
+}
+
+// SetCacheLimit of the bytes of all sources in memory, above which Trim evicts the least recently prepared, or 0 for no limit (the default).
+func (c *Cache) SetCacheLimit(size int64) {
+	if size < 0 {
+		size = 0
+	}
+	atomic.StoreInt64(&c.limit, size)
+}
+
+// GetCacheLimit of the bytes of all sources in memory, or 0 for no limit.
+func (c *Cache) GetCacheLimit() int64 {
+	return atomic.LoadInt64(&c.limit)
+}
+
+// GetCacheStats of the sources in memory
+func (c *Cache) GetCacheStats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Evictions: atomic.LoadInt64(&c.evictions),
+	}
+}
+
+// Trim the sources in memory to the cache limit, evicting the least recently prepared first, except those in the keep list,
+// e.g. the sources of fires that are scheduled, even if that leaves it over the limit. Unlike Prune, this evicts preloaded sources too.
+func (c *Cache) Trim(keep map[string]bool) {
+	limit := c.GetCacheLimit()
+	if limit <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var size int64
+	var candidates []string
+	for key, s := range c.storage {
+		size += s.Size()
+		if !keep[key] {
+			candidates = append(candidates, key)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return c.used[candidates[i]] < c.used[candidates[j]]
+	})
+	for _, key := range candidates {
+		if size <= limit {
+			return
+		}
+		size -= c.storage[key].Size()
+		c.evict(key)
+		atomic.AddInt64(&c.evictions, 1)
+	}
+}
+
+// SetCacheLimit of the default cache, see Cache.SetCacheLimit
+func SetCacheLimit(size int64) {
+	defaultCache.SetCacheLimit(size)
+}
+
+// GetCacheLimit of the default cache, or 0 for no limit.
+func GetCacheLimit() int64 {
+	return defaultCache.GetCacheLimit()
+}
+
+// GetCacheStats of the default cache
+func GetCacheStats() CacheStats {
+	return defaultCache.GetCacheStats()
+}
+
+// Trim the default cache to its limit, see Cache.Trim
+func Trim(keep map[string]bool) {
+	defaultCache.Trim(keep)
+}
@@ -0,0 +1,101 @@
+// Package source models a single audio source
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetCacheLimit(t *testing.T) {
+	SetCacheLimit(-1)
+	assert.Equal(t, int64(0), GetCacheLimit())
+	SetCacheLimit(1000)
+	assert.Equal(t, int64(1000), GetCacheLimit())
+	SetCacheLimit(0)
+}
+
+func TestTrim(t *testing.T) {
+	testSourceSetup(44100, 1)
+	Prune(map[string]bool{})
+	first := ToneKey(WaveSine, 441, 100*time.Millisecond)
+	second := ToneKey(WaveSine, 882, 100*time.Millisecond)
+	third := ToneKey(WaveSine, 1323, 100*time.Millisecond)
+	assert.Nil(t, PreloadAll([]string{first, second, third}))
+	defer Evict(second)
+	assert.Nil(t, Prepare(first)) // now the most recently used
+	Trim(map[string]bool{})
+	assert.Equal(t, 3, Count()) // no limit
+	SetCacheLimit(4410 * 8 * 2)
+	defer SetCacheLimit(0)
+	before := GetCacheStats()
+	Trim(map[string]bool{second: true})
+	assert.Nil(t, Get(third))
+	assert.NotNil(t, Get(first))
+	assert.NotNil(t, Get(second))
+	SetCacheLimit(1)
+	Trim(map[string]bool{second: true})
+	assert.Nil(t, Get(first))
+	assert.NotNil(t, Get(second)) // kept, even over the limit
+	assert.Equal(t, before.Evictions+2, GetCacheStats().Evictions)
+}
+
+func TestGetCacheStats(t *testing.T) {
+	testSourceSetup(44100, 1)
+	tone := ToneKey(WaveSine, 441, 10*time.Millisecond)
+	Evict(tone)
+	before := GetCacheStats()
+	assert.Nil(t, Prepare(tone))
+	assert.Nil(t, Prepare(tone))
+	Evict(tone)
+	assert.Nil(t, Prepare(tone))
+	Evict(tone)
+	after := GetCacheStats()
+	assert.Equal(t, before.Hits+1, after.Hits)
+	assert.Equal(t, before.Misses+2, after.Misses)
+	assert.Equal(t, before.Evictions, after.Evictions)
+}
+
+func TestNewCache(t *testing.T) {
+	testSourceSetup(44100, 1)
+	c := NewCache()
+	c.Configure(spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 2})
+	tone := ToneKey(WaveSine, 441, 100*time.Millisecond)
+	Evict(tone)
+	assert.Nil(t, c.Prepare(tone))
+	assert.Equal(t, spec.Tz(2205), c.GetLength(tone))
+	assert.Equal(t, 2, len(c.Get(tone).SampleAt(0, 1, 0)))
+	assert.Nil(t, Get(tone)) // not in the default cache
+	assert.Nil(t, Prepare(tone))
+	assert.Equal(t, spec.Tz(4410), GetLength(tone))
+	c.Teardown()
+	assert.Equal(t, 0, c.Count())
+	assert.NotNil(t, Get(tone)) // the default cache is unaffected
+	Evict(tone)
+}
+
+func TestNewCache_Settings(t *testing.T) {
+	testSourceSetup(44100, 1)
+	src := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	c := NewCache()
+	c.Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1})
+	defer c.Teardown()
+	assert.Nil(t, Prepare(src))
+	defer Evict(src)
+	assert.Nil(t, c.Prepare(src))
+	c.SetStreaming(src, true)
+	c.SetResampleQuality(ResampleHigh)
+	assert.Nil(t, c.SetDownmixMatrix(4, nil))
+	assert.True(t, c.Get(src).IsStreaming())
+	assert.Equal(t, ResampleHigh, c.GetResampleQuality())
+	assert.Nil(t, c.GetDownmixMatrix(4, 2))
+	assert.False(t, Get(src).IsStreaming()) // the default cache is unaffected
+	assert.Equal(t, ResampleMedium, GetResampleQuality())
+	assert.NotNil(t, GetDownmixMatrix(4, 2))
+	c.Teardown()
+	assert.Equal(t, ResampleMedium, c.GetResampleQuality()) // reset by the teardown
+	assert.NotNil(t, c.GetDownmixMatrix(4, 2))
+}
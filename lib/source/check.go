@@ -0,0 +1,40 @@
+// Package source models a single audio source
+package source
+
+import (
+	"os"
+
+	"github.com/go-mix/mix/bind"
+)
+
+// Check that a source can be loaded for the spec of the cache, without keeping it in memory: a source already in memory, or a
+// registered, provided or synthesized one, always can. A file must exist, and its header must be readable, with a downmix of
+// its channels to the master; only if its loader cannot read the header alone is it decoded whole.
+func (c *Cache) Check(src string) error {
+	c.mutex.RLock()
+	_, loaded := c.storage[src]
+	c.mutex.RUnlock()
+	if loaded || IsTone(src) || IsRegistered(src) || IsProvided(src) {
+		return nil
+	}
+	if readFile != nil {
+		_, err := readFile(src)
+		return err
+	}
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	in, err := bind.OpenStream(src)
+	if err != nil {
+		_, err = c.New(src) // the loader cannot read the header alone
+		return err
+	}
+	defer in.Close()
+	_, err = c.downmixFor(in.Spec().Channels, c.master())
+	return err
+}
+
+// Check a source in the default cache, see Cache.Check
+func Check(src string) error {
+	return defaultCache.Check(src)
+}
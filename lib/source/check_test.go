@@ -0,0 +1,40 @@
+// Package source models a single audio source
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	testSourceSetup(44100, 2)
+	path := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	Evict(path)
+	assert.Nil(t, Check(path))
+	assert.Nil(t, Get(path)) // not kept in memory
+	assert.Nil(t, Check(ToneKey(WaveSine, 441, time.Second)))
+	assert.NotNil(t, Check("testdata/nonexistent.wav"))
+}
+
+func TestCheck_Header(t *testing.T) {
+	testSourceSetup(44100, 2)
+	dir, cleanup := testDownmixDir(t)
+	defer cleanup()
+	garbage := filepath.Join(dir, "garbage.wav")
+	assert.Nil(t, ioutil.WriteFile(garbage, []byte("this is not a WAV file"), 0644))
+	assert.NotNil(t, Check(garbage))
+	octo := testDownmixWAV(t, dir, "octo.wav", []float32{1, 1, 1, 1, 1, 1, 1, 1})
+	assert.EqualError(t, Check(octo), "No downmix matrix of 8 channels to 2, see SetDownmixMatrix")
+	quad := testDownmixWAV(t, dir, "quad.wav", []float32{0.1, 0.2, 0.3, 0.4})
+	assert.Nil(t, Prepare(quad))
+	defer Evict(quad)
+	assert.Nil(t, os.Remove(quad))
+	assert.Nil(t, Check(quad)) // still in memory
+	Evict(quad)
+	assert.NotNil(t, Check(quad))
+}
@@ -0,0 +1,140 @@
+// Package source models a single audio source
+package source
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// SetDownmixMatrix of a source of the cache with a # of channels onto fewer master channels: one row for each master channel, of the
+// weight of each source channel, e.g. {{1, 0, 0.7071, 0}, {0, 1, 0, 0.7071}} folds quad into stereo. It replaces any matrix of the
+// same shape, or with no rows, removes every matrix of the # of source channels; every source in memory of the cache of that # is
+// reloaded. The matrix is applied once, as a source is loaded, so it costs nothing in the mix loop. A source with more channels than
+// the master cannot be loaded without a matrix, unless the master is mono, to which any source downmixes at -3dB per channel pair.
+// There are defaults, by ITU-R BS.775, of quad (L, R, Ls, Rs) and 5.1 (L, R, C, LFE, Ls, Rs) to stereo.
+func (c *Cache) SetDownmixMatrix(srcChannels int, matrix [][]float64) error {
+	if srcChannels < 1 || srcChannels > spec.MaxChannels {
+		return fmt.Errorf("Unsupported downmix of %d channels (must be 1 to %d)", srcChannels, spec.MaxChannels)
+	}
+	if len(matrix) > spec.MaxChannels {
+		return fmt.Errorf("Unsupported downmix to %d channels (must be 1 to %d)", len(matrix), spec.MaxChannels)
+	}
+	for _, row := range matrix {
+		if len(row) != srcChannels {
+			return fmt.Errorf("Downmix matrix row has %d weights (must be one for each of %d channels)", len(row), srcChannels)
+		}
+	}
+	c.settingsMutex.Lock()
+	if len(matrix) == 0 {
+		for key := range c.downmixMatrices {
+			if key.src == srcChannels {
+				delete(c.downmixMatrices, key)
+			}
+		}
+	} else {
+		c.downmixMatrices[downmixKey{srcChannels, len(matrix)}] = copyMatrix(matrix)
+	}
+	c.settingsMutex.Unlock()
+	c.reloadWhere(func(s *Source) bool {
+		return s.audioSpec != nil && s.audioSpec.Channels == srcChannels
+	})
+	return nil
+}
+
+// GetDownmixMatrix of a source of the cache with a # of channels onto a # of master channels, or nil if there is none
+func (c *Cache) GetDownmixMatrix(srcChannels int, masterChannels int) [][]float64 {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+	return copyMatrix(c.downmixMatrices[downmixKey{srcChannels, masterChannels}])
+}
+
+// SetDownmixMatrix of a source of the default cache with a # of channels onto fewer master channels, see Cache.SetDownmixMatrix
+func SetDownmixMatrix(srcChannels int, matrix [][]float64) error {
+	return defaultCache.SetDownmixMatrix(srcChannels, matrix)
+}
+
+// GetDownmixMatrix of a source of the default cache with a # of channels onto a # of master channels, or nil if there is none
+func GetDownmixMatrix(srcChannels int, masterChannels int) [][]float64 {
+	return defaultCache.GetDownmixMatrix(srcChannels, masterChannels)
+}
+
+//
+// Private
+//
+
+// minus3dB of a channel folded into another, by ITU-R BS.775
+const minus3dB = math.Sqrt2 / 2
+
+type downmixKey struct {
+	src    int // channels of the source
+	master int // channels of the master
+}
+
+// downmixDefaults of each cache, until it sets its own
+var downmixDefaults = map[downmixKey][][]float64{
+	{4, 2}: {
+		{1, 0, minus3dB, 0},
+		{0, 1, 0, minus3dB},
+	},
+	{6, 2}: {
+		{1, 0, minus3dB, 0, minus3dB, 0},
+		{0, 1, minus3dB, 0, 0, minus3dB},
+	},
+}
+
+// downmixFor a master spec, the matrix of the cache of a source with a # of channels, or nil if it maps onto the master as it is;
+// an error if it has more channels than the master and there is no matrix.
+func (c *Cache) downmixFor(srcChannels int, masterSpec *spec.AudioSpec) ([][]float64, error) {
+	if masterSpec == nil || srcChannels <= masterSpec.Channels {
+		return nil, nil
+	}
+	if matrix := c.GetDownmixMatrix(srcChannels, masterSpec.Channels); matrix != nil {
+		return matrix, nil
+	}
+	if masterSpec.Channels == 1 {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("No downmix matrix of %d channels to %d, see SetDownmixMatrix", srcChannels, masterSpec.Channels)
+}
+
+// channelsFor a master spec, by the matrices of the cache, of the samples of a source with a # of channels, as it would be loaded, or 0 if it cannot be
+func (c *Cache) channelsFor(srcChannels int, masterSpec *spec.AudioSpec) int {
+	matrix, err := c.downmixFor(srcChannels, masterSpec)
+	if err != nil {
+		return 0
+	} else if matrix != nil {
+		return len(matrix)
+	}
+	return srcChannels
+}
+
+// downmix samples by a matrix, into new samples of one value for each row
+func downmix(samples []sample.Sample, matrix [][]float64) []sample.Sample {
+	out := make([]sample.Sample, len(samples))
+	for i, smp := range samples {
+		values := make([]sample.Value, len(matrix))
+		for c, row := range matrix {
+			for sc, weight := range row {
+				if sc < len(smp.Values) {
+					values[c] += sample.Value(weight) * smp.Values[sc]
+				}
+			}
+		}
+		out[i] = sample.New(values)
+	}
+	return out
+}
+
+func copyMatrix(matrix [][]float64) [][]float64 {
+	if matrix == nil {
+		return nil
+	}
+	out := make([][]float64, len(matrix))
+	for i, row := range matrix {
+		out[i] = append([]float64(nil), row...)
+	}
+	return out
+}
@@ -0,0 +1,134 @@
+// Package source models a single audio source
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestDownmix_Default(t *testing.T) {
+	testSourceSetup(44100, 2)
+	dir, cleanup := testDownmixDir(t)
+	defer cleanup()
+	surround, err := New(testDownmixWAV(t, dir, "surround.wav", []float32{0.1, 0.2, 0.3, 0.9, 0.4, 0.5})) // L, R, C, LFE, Ls, Rs
+	assert.Nil(t, err)
+	out := surround.SampleAt(0, 1, 0)
+	assert.InDelta(t, 0.1+(0.3+0.4)/math.Sqrt2, float64(out[0]), 1e-6)
+	assert.InDelta(t, 0.2+(0.3+0.5)/math.Sqrt2, float64(out[1]), 1e-6) // without the LFE
+	assert.Equal(t, int64(441*2*valueSize), surround.Size())           // downmixed once, in memory
+	assert.Equal(t, 6, surround.Spec().Channels)
+	quad, err := New(testDownmixWAV(t, dir, "quad.wav", []float32{0.1, 0.2, 0.3, 0.4})) // L, R, Ls, Rs
+	assert.Nil(t, err)
+	out = quad.SampleAt(0, 1, 0)
+	assert.InDelta(t, 0.1+0.3/math.Sqrt2, float64(out[0]), 1e-6)
+	assert.InDelta(t, 0.2+0.4/math.Sqrt2, float64(out[1]), 1e-6)
+}
+
+func TestDownmix_NoMatrix(t *testing.T) {
+	testSourceSetup(44100, 2)
+	dir, cleanup := testDownmixDir(t)
+	defer cleanup()
+	path := testDownmixWAV(t, dir, "octo.wav", []float32{1, 1, 1, 1, 1, 1, 1, 1})
+	_, err := New(path)
+	assert.EqualError(t, err, "No downmix matrix of 8 channels to 2, see SetDownmixMatrix")
+	testSourceSetup(44100, 1)
+	octo, err := New(path) // any source downmixes to a mono master
+	assert.Nil(t, err)
+	assert.InDelta(t, 8/math.Sqrt(8), float64(octo.SampleAt(0, 1, 0)[0]), 1e-6)
+}
+
+func TestSetDownmixMatrix(t *testing.T) {
+	testSourceSetup(44100, 2)
+	dir, cleanup := testDownmixDir(t)
+	defer cleanup()
+	path := testDownmixWAV(t, dir, "quad.wav", []float32{0.1, 0.2, 0.3, 0.4})
+	assert.Nil(t, Prepare(path))
+	defer Evict(path)
+	defaultMatrix := GetDownmixMatrix(4, 2)
+	defer SetDownmixMatrix(4, defaultMatrix)
+	assert.Nil(t, SetDownmixMatrix(4, [][]float64{{0.5, 0, 0.5, 0}, {0, 0.5, 0, 0.5}}))
+	out := Get(path).SampleAt(0, 1, 0) // reloaded
+	assert.InDelta(t, 0.2, float64(out[0]), 1e-6)
+	assert.InDelta(t, 0.3, float64(out[1]), 1e-6)
+	assert.Nil(t, SetDownmixMatrix(4, nil))
+	assert.Nil(t, GetDownmixMatrix(4, 2))
+	assert.Nil(t, Get(path)) // can no longer be loaded
+	assert.EqualError(t, SetDownmixMatrix(9, [][]float64{{1}}), "Unsupported downmix of 9 channels (must be 1 to 8)")
+	assert.EqualError(t, SetDownmixMatrix(4, [][]float64{{1, 0}}), "Downmix matrix row has 2 weights (must be one for each of 4 channels)")
+}
+
+func TestDownmix_Configure(t *testing.T) {
+	testSourceSetup(44100, 2)
+	dir, cleanup := testDownmixDir(t)
+	defer cleanup()
+	path := testDownmixWAV(t, dir, "quad.wav", []float32{0.1, 0.2, 0.3, 0.4})
+	assert.Nil(t, Prepare(path))
+	defer Evict(path)
+	assert.Equal(t, int64(441*2*valueSize), Get(path).Size())
+	testSourceSetup(44100, 4)
+	assert.Equal(t, int64(441*4*valueSize), Get(path).Size()) // reloaded, with all of its channels
+	assert.Equal(t, []sample.Value{sample.Value(float32(0.1)), sample.Value(float32(0.2)), sample.Value(float32(0.3)), sample.Value(float32(0.4))}, Get(path).SampleAt(0, 1, 0))
+}
+
+func TestDownmix_Stream(t *testing.T) {
+	testSourceSetup(44100, 2)
+	dir, cleanup := testDownmixDir(t)
+	defer cleanup()
+	path := testDownmixWAV(t, dir, "surround.wav", []float32{0.1, 0.2, 0.3, 0.9, 0.4, 0.5})
+	whole, err := New(path)
+	assert.Nil(t, err)
+	SetStreaming(path, true)
+	defer SetStreaming(path, false)
+	streamed, err := New(path)
+	assert.Nil(t, err)
+	defer streamed.Teardown()
+	assert.True(t, streamed.IsStreaming())
+	assert.Equal(t, whole.Length(), streamed.Length())
+	for tz := spec.Tz(0); tz < whole.Length(); tz++ {
+		assert.Equal(t, whole.SampleAt(tz, 1, 0), streamed.SampleAt(tz, 1, 0))
+	}
+}
+
+//
+// Private
+//
+
+func testDownmixDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "mix-downmix")
+	assert.Nil(t, err)
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// testDownmixWAV file of 32-bit float samples at 44.1kHz, of one frame repeated for 10ms, with a channel for each value
+func testDownmixWAV(t *testing.T, dir string, name string, frame []float32) string {
+	const frames = 441
+	channels := len(frame)
+	dataSize := frames * channels * 4
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{uint32(16), uint16(3), uint16(channels), uint32(44100), uint32(44100 * channels * 4), uint16(channels * 4), uint16(32)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	for i := 0; i < frames; i++ {
+		binary.Write(&buf, binary.LittleEndian, frame)
+	}
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+	return path
+}
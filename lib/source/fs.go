@@ -0,0 +1,17 @@
+// Package source models a single audio source
+package source
+
+import (
+	"io/fs"
+)
+
+// SetFS to load sources from a file system, e.g. an embed.FS, instead of the OS; nil to restore loading from the OS.
+func SetFS(fsys fs.FS) {
+	if fsys == nil {
+		readFile = nil
+		return
+	}
+	readFile = func(path string) ([]byte, error) {
+		return fs.ReadFile(fsys, path)
+	}
+}
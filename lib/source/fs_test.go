@@ -0,0 +1,23 @@
+// Package source models a single audio source
+package source
+
+import (
+	"io/ioutil"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFS(t *testing.T) {
+	testSourceSetup(44100, 1)
+	data, err := ioutil.ReadFile("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	SetFS(fstest.MapFS{"sounds/hihat.wav": {Data: data}})
+	defer SetFS(nil)
+	s, err := New("sounds/hihat.wav")
+	assert.Nil(t, err)
+	assert.True(t, s.Length() > 0)
+	_, err = New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,151 @@
+// Package source models a single audio source
+package source
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+// NormalizeMode of the level of each source as it is loaded
+type NormalizeMode uint
+
+const (
+	NormalizeOff  NormalizeMode = iota // the default
+	NormalizePeak                      // scale the peak of all channels to the target
+	NormalizeRMS                       // scale the RMS of all channels to the target
+)
+
+// SetNormalize of each source of the cache as it is loaded, to a target level in dBFS, such that sources recorded at different levels
+// enter the mix at a comparable level; every source in memory of the cache is reloaded. The samples are scaled once, in memory, so a
+// streamed source, which is never decoded whole, or a synthesized tone, is not normalized.
+func (c *Cache) SetNormalize(mode NormalizeMode, target float64) {
+	c.settingsMutex.Lock()
+	c.normalizeMode, c.normalizeTarget = mode, target
+	c.settingsMutex.Unlock()
+	c.reloadWhere(func(s *Source) bool {
+		return !IsTone(s.URL)
+	})
+}
+
+// GetNormalize mode and target level in dBFS of the cache, see SetNormalize
+func (c *Cache) GetNormalize() (mode NormalizeMode, target float64) {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+	return c.normalizeMode, c.normalizeTarget
+}
+
+// SetGain trim of a source of the cache in dB, a static gain applied as it plays, after any normalization; it takes effect at once,
+// on the source in memory of the cache, and on any fire of it that is already scheduled or playing, but not on any other cache.
+func (c *Cache) SetGain(src string, db float64) {
+	c.settingsMutex.Lock()
+	if db == 0 {
+		delete(c.gainTrims, src)
+	} else {
+		c.gainTrims[src] = db
+	}
+	c.settingsMutex.Unlock()
+	if s := c.Get(src); s != nil {
+		s.setTrim(db)
+	}
+}
+
+// GetGain trim of a source of the cache in dB, see SetGain
+func (c *Cache) GetGain(src string) float64 {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+	return c.gainTrims[src]
+}
+
+// SetNormalize of each source of the default cache, see Cache.SetNormalize
+func SetNormalize(mode NormalizeMode, target float64) {
+	defaultCache.SetNormalize(mode, target)
+}
+
+// GetNormalize mode and target level in dBFS of the default cache, see SetNormalize
+func GetNormalize() (mode NormalizeMode, target float64) {
+	return defaultCache.GetNormalize()
+}
+
+// SetGain trim of a source of the default cache in dB, see Cache.SetGain
+func SetGain(src string, db float64) {
+	defaultCache.SetGain(src, db)
+}
+
+// GetGain trim of a source of the default cache in dB, see SetGain
+func GetGain(src string) float64 {
+	return defaultCache.GetGain(src)
+}
+
+// Normalization gain in dB that was applied to the source as it was loaded, or 0 if it was not normalized
+func (s *Source) Normalization() float64 {
+	return s.normalization
+}
+
+// Trim gain in dB applied to the source as it plays, see SetGain
+func (s *Source) Trim() float64 {
+	return 20 * math.Log10(s.trim())
+}
+
+//
+// Private
+//
+
+// setTrim of the source in dB, which the mix loop reads atomically as a linear gain
+func (s *Source) setTrim(db float64) {
+	atomic.StoreUint64(&s.trimBits, math.Float64bits(math.Pow(10, db/20)))
+}
+
+// trim of the source, as a linear gain, which is unity until it is set
+func (s *Source) trim() float64 {
+	bits := atomic.LoadUint64(&s.trimBits)
+	if bits == 0 {
+		return 1
+	}
+	return math.Float64frombits(bits)
+}
+
+// loadGain of the source: normalize its samples in memory, unless it is a tone or streamed, and set its trim
+func (s *Source) loadGain() {
+	c := s.owner()
+	c.settingsMutex.Lock()
+	mode, target, trim := c.normalizeMode, c.normalizeTarget, c.gainTrims[s.URL]
+	c.settingsMutex.Unlock()
+	s.setTrim(trim)
+	s.normalization = 0
+	if mode == NormalizeOff || IsTone(s.URL) || s.stream != nil || s.provider != nil {
+		return
+	}
+	level := measure(mode, s.sample)
+	if level <= 0 {
+		return // silence cannot be normalized
+	}
+	gain := math.Pow(10, target/20) / level
+	for _, smp := range s.sample {
+		for c := range smp.Values {
+			smp.Values[c] *= sample.Value(gain)
+		}
+	}
+	s.normalization = 20 * math.Log10(gain)
+}
+
+// measure the peak or RMS level of all channels of some samples
+func measure(mode NormalizeMode, samples []sample.Sample) float64 {
+	var peak, sumSquares float64
+	var n int
+	for _, smp := range samples {
+		for _, v := range smp.Values {
+			peak = math.Max(peak, math.Abs(float64(v)))
+			sumSquares += float64(v * v)
+			n++
+		}
+	}
+	if mode == NormalizeRMS {
+		if n == 0 {
+			return 0
+		}
+		return math.Sqrt(sumSquares / float64(n))
+	}
+	return peak
+}
@@ -0,0 +1,54 @@
+// Package source models a single audio source
+package source
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetNormalize(t *testing.T) {
+	testSourceSetup(44100, 1)
+	src := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	tone := ToneKey(WaveSine, 441, 100*time.Millisecond)
+	assert.Nil(t, PreloadAll([]string{src, tone}))
+	defer Evict(src)
+	defer Evict(tone)
+	defer SetNormalize(NormalizeOff, 0)
+	peak := measure(NormalizePeak, Get(src).sample)
+	SetNormalize(NormalizePeak, -6)
+	mode, target := GetNormalize()
+	assert.Equal(t, NormalizePeak, mode)
+	assert.Equal(t, float64(-6), target)
+	assert.InDelta(t, math.Pow(10, -6.0/20), measure(NormalizePeak, Get(src).sample), 1e-9) // reloaded
+	assert.InDelta(t, -6-20*math.Log10(peak), Get(src).Normalization(), 1e-9)
+	assert.Equal(t, float64(0), Get(tone).Normalization()) // never a tone
+	SetNormalize(NormalizeRMS, -20)
+	assert.InDelta(t, 0.1, measure(NormalizeRMS, Get(src).sample), 1e-9)
+	SetNormalize(NormalizeOff, 0)
+	assert.Equal(t, float64(0), Get(src).Normalization())
+	assert.InDelta(t, peak, measure(NormalizePeak, Get(src).sample), 1e-9)
+}
+
+func TestSetGain(t *testing.T) {
+	testSourceSetup(44100, 1)
+	src := ToneKey(WaveSine, 441, 100*time.Millisecond)
+	assert.Nil(t, Prepare(src))
+	defer Evict(src)
+	defer SetGain(src, 0)
+	s := Get(src)
+	before := s.SampleAt(spec.Tz(25), 1, 0)[0]
+	SetGain(src, -6)
+	assert.Equal(t, float64(-6), GetGain(src))
+	assert.InDelta(t, -6, s.Trim(), 1e-9)
+	assert.InDelta(t, float64(before)*math.Pow(10, -6.0/20), float64(s.SampleAt(spec.Tz(25), 1, 0)[0]), 1e-9) // at once
+	Evict(src)
+	assert.Nil(t, Prepare(src))
+	assert.InDelta(t, -6, Get(src).Trim(), 1e-9) // as it is loaded again
+	SetGain(src, 0)
+	assert.Equal(t, before, Get(src).SampleAt(spec.Tz(25), 1, 0)[0])
+}
@@ -0,0 +1,75 @@
+// Package source models a single audio source
+package source
+
+import "math"
+
+// PanLaw by which a pan is turned into the gain of each master channel
+type PanLaw uint
+
+const (
+	// PanLinear is the original curve, and the default: the center is at unity gain in every channel, and each channel is
+	// attenuated in proportion to the pan, the more so the later it is, by 1 - |pan| × channel / # of channels.
+	PanLinear PanLaw = iota
+	// PanConstantPower sweeps a pair of adjacent channels with sin/cos gains, so the summed power is the same at any pan,
+	// e.g. -3dB in each channel of stereo at the center.
+	PanConstantPower
+	// PanCompromise45 is between a linear sweep (-6dB at the center) and constant power, at -4.5dB in each channel at the center.
+	PanCompromise45
+	// PanBalance is a balance that leaves the center at unity gain in every channel; so a sound panned center is about 3dB
+	// louder than one panned hard to a side. With more than two channels, it sweeps an equal-power pair across adjacent
+	// channels, blended with unity gain in all channels toward the center.
+	PanBalance
+)
+
+// PanGain of one of a number of master channels, for a pan from -1 (the first channel) to +1 (the last), by a pan law;
+// a mono master is not panned.
+func PanGain(law PanLaw, channel int, channels int, pan float64) float64 {
+	if channels < 2 {
+		return 1
+	}
+	switch law {
+	case PanConstantPower:
+		return panPairGain(channel, channels, pan, false)
+	case PanCompromise45:
+		return math.Sqrt(panPairGain(channel, channels, pan, false) * panPairGain(channel, channels, pan, true))
+	case PanBalance:
+		return panBalanceGain(channel, channels, pan)
+	}
+	return math.Max(0, 1-math.Abs(pan)*float64(channel)/float64(channels))
+}
+
+//
+// Private
+//
+
+// panBalanceGain of a channel, see PanBalance
+func panBalanceGain(channel int, channels int, pan float64) float64 {
+	if pan == 0 {
+		return 1
+	}
+	if channels == 2 {
+		return math.Min(1, 1+pan*float64(channel*2-1))
+	}
+	spread := math.Abs(pan)
+	return (1 - spread) + spread*panPairGain(channel, channels, pan, false)
+}
+
+// panPairGain of a channel, in the pair of adjacent channels at the position of the pan, by sin/cos gains, or else linear gains
+func panPairGain(channel int, channels int, pan float64, linear bool) float64 {
+	position := (pan + 1) / 2 * float64(channels-1)
+	pair := math.Floor(position)
+	frac := position - pair
+	switch float64(channel) {
+	case pair:
+		if linear {
+			return 1 - frac
+		}
+		return math.Cos(frac * math.Pi / 2)
+	case pair + 1:
+		if linear {
+			return frac
+		}
+		return math.Sin(frac * math.Pi / 2)
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+// Package source models a single audio source
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPanGain(t *testing.T) {
+	for _, c := range []struct {
+		law    PanLaw
+		center float64 // summed power of both channels at the center, vs. hard to a side
+	}{
+		{PanConstantPower, 1},
+		{PanCompromise45, 0.70710678},
+		{PanBalance, 2},
+	} {
+		for _, pan := range []float64{-1, 1} {
+			assert.InDelta(t, 1, testPanPower(c.law, pan), 1e-9)
+		}
+		assert.InDelta(t, c.center, testPanPower(c.law, 0), 1e-6)
+		assert.InDelta(t, 0, PanGain(c.law, 1, 2, -1), 1e-9)
+		assert.InDelta(t, 0, PanGain(c.law, 0, 2, 1), 1e-9)
+		assert.Equal(t, float64(1), PanGain(c.law, 0, 1, 0.5)) // mono is not panned
+	}
+	assert.InDelta(t, 0.59460356, PanGain(PanCompromise45, 0, 2, 0), 1e-6) // -4.5dB
+	assert.InDelta(t, 1, testPanPower(PanConstantPower, 0.3), 1e-9)
+	assert.Equal(t, float64(1), PanGain(PanLinear, 0, 2, -1)) // the original curve, the same whichever way it is panned
+	assert.Equal(t, 0.5, PanGain(PanLinear, 1, 2, -1))
+	assert.Equal(t, 0.5, PanGain(PanLinear, 1, 2, 1))
+	assert.Equal(t, 0.625, PanGain(PanLinear, 3, 4, -.5))
+	assert.Equal(t, float64(1), PanGain(PanLinear, 1, 2, 0))
+}
+
+func TestPanGain_Channels(t *testing.T) {
+	var power float64
+	for c := 0; c < 4; c++ {
+		g := PanGain(PanConstantPower, c, 4, 0.2)
+		power += g * g
+	}
+	assert.InDelta(t, 1, power, 1e-9)
+	assert.Equal(t, float64(0), PanGain(PanConstantPower, 0, 4, 0.2))
+}
+
+//
+// Private
+//
+
+// testPanPower summed across the channels of stereo, by a pan law
+func testPanPower(law PanLaw, pan float64) float64 {
+	left, right := PanGain(law, 0, 2, pan), PanGain(law, 1, 2, pan)
+	return left*left + right*right
+}
@@ -0,0 +1,162 @@
+// Package source models a single audio source
+package source
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Provider of the samples of a source, e.g. a synthesizer that generates its audio procedurally, see RegisterProvider.
+// A source loaded from a file is one too.
+//
+// At is called by the mix loop, for every frame of every fire that plays it, so it has the real-time constraints of the
+// mix loop: it must not block, e.g. on a mutex, a channel or I/O, nor allocate, and it must return in much less than the
+// duration of a frame. It may be called concurrently, for different fires, when the mix loop has more than one worker.
+// The time of each call is measured, see GetProviderStats, and a provider that overruns the budget of a frame is warned of.
+type Provider interface {
+	// At a frame since the beginning of the source, at the master frequency, into out, one value for each master channel;
+	// out is zeroed before each call, and must not be kept after it returns.
+	At(frame spec.Tz, out []sample.Value)
+	// Length of the source, in frames at the master frequency; a fire with a sustain plays until it ends, or until the
+	// end of the source, whichever is first.
+	Length() spec.Tz
+}
+
+// ProviderStats of the calls to a provider by the mix loop, cumulative since it was registered
+type ProviderStats struct {
+	Calls       int64         // of At, one per frame of each fire
+	Time        time.Duration // in all calls
+	MaxTime     time.Duration // in any one call
+	Slow        int64         // calls that took longer than a frame, the whole budget of the mix loop for that frame
+	Misbehaving bool          // if any call was slow, such that the mix may glitch
+}
+
+// RegisterProvider of the samples of a source under a name, to be played by fires of that name instead of a file, or nil to
+// remove it. Registering a name again replaces it, in every cache; its stats are kept.
+func RegisterProvider(name string, p Provider) error {
+	if name == "" {
+		return errors.New("Source provider requires a name")
+	}
+	providerMutex.Lock()
+	if p == nil {
+		delete(providers, name)
+	} else if previous, ok := providers[name]; ok {
+		providers[name] = &provided{Provider: p, stats: previous.stats}
+	} else {
+		providers[name] = &provided{Provider: p, stats: &providerStats{}}
+	}
+	providerMutex.Unlock()
+	eachCache(func(c *Cache) {
+		c.Evict(name) // loaded again from the provider if it is needed, for the spec of that cache
+	})
+	return nil
+}
+
+// IsProvided is true if a name has been registered with a provider
+func IsProvided(name string) bool {
+	_, ok := providerOf(name)
+	return ok
+}
+
+// GetProviderStats of each registered provider, by name; cheap to poll from any goroutine
+func GetProviderStats() map[string]ProviderStats {
+	providerMutex.Lock()
+	defer providerMutex.Unlock()
+	if len(providers) == 0 {
+		return nil
+	}
+	all := make(map[string]ProviderStats, len(providers))
+	for name, p := range providers {
+		all[name] = p.stats.snapshot()
+	}
+	return all
+}
+
+// At a frame since the beginning of the source, at unity volume and center pan, into out, one value for each master
+// channel, such that a source loaded from a file is a Provider too
+func (s *Source) At(frame spec.Tz, out []sample.Value) {
+	s.SampleAtInto(out, frame, 1, 0)
+}
+
+//
+// Private
+//
+
+var (
+	providers     = make(map[string]*provided)
+	providerMutex = &sync.Mutex{}
+)
+
+var _ Provider = (*Source)(nil)
+
+// provided source of a registered provider, and the stats of all of its calls
+type provided struct {
+	Provider
+	stats *providerStats
+}
+
+// providerStats accessed atomically, as the mix loop may call a provider from many goroutines
+type providerStats struct {
+	calls   int64
+	nanos   int64
+	maxNano int64
+	slow    int64
+}
+
+func providerOf(name string) (p *provided, ok bool) {
+	providerMutex.Lock()
+	defer providerMutex.Unlock()
+	p, ok = providers[name]
+	return
+}
+
+// loadProvider of the source, which is never resampled nor downmixed, as it provides frames at the master spec
+func (s *Source) loadProvider(p *provided, masterSpec *spec.AudioSpec) error {
+	if masterSpec == nil {
+		return errors.New("Cannot load a source provider before the mix is configured")
+	}
+	s.provider = p
+	s.audioSpec = &spec.AudioSpec{Freq: masterSpec.Freq, Format: spec.AudioF64, Channels: masterSpec.Channels}
+	s.freq = masterSpec.Freq
+	s.channels = masterSpec.Channels
+	s.maxTz = p.Length()
+	return nil
+}
+
+// provideAt a frame into values, measuring the time of the call against the budget of a frame
+func (s *Source) provideAt(at spec.Tz, values []sample.Value) {
+	began := time.Now()
+	s.provider.At(at, values)
+	s.provider.stats.add(s.URL, time.Since(began), time.Duration(float64(time.Second)/s.freq))
+}
+
+// add the time of one call, warning at the first that is slow
+func (ps *providerStats) add(src string, took time.Duration, budget time.Duration) {
+	atomic.AddInt64(&ps.calls, 1)
+	atomic.AddInt64(&ps.nanos, int64(took))
+	for max := atomic.LoadInt64(&ps.maxNano); int64(took) > max; max = atomic.LoadInt64(&ps.maxNano) {
+		if atomic.CompareAndSwapInt64(&ps.maxNano, max, int64(took)) {
+			break
+		}
+	}
+	if took > budget && atomic.AddInt64(&ps.slow, 1) == 1 {
+		debug.Warnf("source.provider(%s) took %s for one frame, longer than the %s budget of the mix loop", src, took, budget)
+	}
+}
+
+func (ps *providerStats) snapshot() ProviderStats {
+	slow := atomic.LoadInt64(&ps.slow)
+	return ProviderStats{
+		Calls:       atomic.LoadInt64(&ps.calls),
+		Time:        time.Duration(atomic.LoadInt64(&ps.nanos)),
+		MaxTime:     time.Duration(atomic.LoadInt64(&ps.maxNano)),
+		Slow:        slow,
+		Misbehaving: slow > 0,
+	}
+}
@@ -0,0 +1,93 @@
+// Package source models a single audio source
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestRegisterProvider(t *testing.T) {
+	testSourceSetup(44100, 2)
+	assert.Nil(t, RegisterProvider("ramp", testRamp{}))
+	defer RegisterProvider("ramp", nil)
+	assert.True(t, IsProvided("ramp"))
+	assert.Nil(t, Prepare("ramp"))
+	s := Get("ramp")
+	assert.NotNil(t, s)
+	assert.Equal(t, spec.Tz(100), s.Length())
+	assert.Equal(t, 2, s.Spec().Channels)
+	assert.Equal(t, []sample.Value{0.25, 0.5}, s.SampleAt(25, 1, 0))
+	assert.Equal(t, []sample.Value{0.125, 0.25}, s.SampleAt(25, 0.5, 0))
+	assert.Equal(t, []sample.Value{0, 0}, s.SampleAt(100, 1, 0)) // after its length
+	stats := GetProviderStats()["ramp"]
+	assert.Equal(t, int64(2), stats.Calls)
+	assert.True(t, stats.MaxTime <= stats.Time)
+	assert.False(t, stats.Misbehaving)
+	assert.Nil(t, RegisterProvider("ramp", testRamp{length: 10})) // replaced in every cache, with its stats kept
+	assert.Nil(t, Get("ramp"))
+	assert.Nil(t, Prepare("ramp"))
+	assert.Equal(t, spec.Tz(10), Get("ramp").Length())
+	assert.Equal(t, int64(2), GetProviderStats()["ramp"].Calls)
+	assert.Nil(t, RegisterProvider("ramp", nil))
+	assert.False(t, IsProvided("ramp"))
+	assert.Nil(t, GetProviderStats())
+}
+
+func TestRegisterProvider_Slow(t *testing.T) {
+	testSourceSetup(44100, 1)
+	assert.Nil(t, RegisterProvider("slow", testRamp{sleep: time.Millisecond}))
+	defer RegisterProvider("slow", nil)
+	assert.Nil(t, Prepare("slow"))
+	s := Get("slow")
+	s.SampleAt(0, 1, 0)
+	s.SampleAt(1, 1, 0)
+	stats := GetProviderStats()["slow"]
+	assert.Equal(t, int64(2), stats.Calls)
+	assert.Equal(t, int64(2), stats.Slow)
+	assert.True(t, stats.Misbehaving)
+	assert.True(t, stats.MaxTime >= time.Millisecond)
+}
+
+func TestRegisterProvider_FAIL(t *testing.T) {
+	assert.EqualError(t, RegisterProvider("", testRamp{}), "Source provider requires a name")
+}
+
+func TestSource_Provider(t *testing.T) {
+	testSourceSetup(44100, 2)
+	key := ToneKey(WaveSine, 441, 10*time.Millisecond)
+	assert.Nil(t, Prepare(key))
+	var p Provider = Get(key) // a source loaded from a file, or synthesized, is a provider too
+	assert.Equal(t, spec.Tz(441), p.Length())
+	out := make([]sample.Value, 2)
+	p.At(25, out)
+	assert.Equal(t, Get(key).SampleAt(25, 1, 0), out)
+}
+
+//
+// Private
+//
+
+// testRamp rises from 0 to 1 over its length, of 100 frames by default, in the first channel, and twice that in the second
+type testRamp struct {
+	length spec.Tz
+	sleep  time.Duration
+}
+
+func (r testRamp) At(frame spec.Tz, out []sample.Value) {
+	time.Sleep(r.sleep)
+	for c := range out {
+		out[c] = sample.Value(frame) / 100 * sample.Value(c+1)
+	}
+}
+
+func (r testRamp) Length() spec.Tz {
+	if r.length == 0 {
+		return 100
+	}
+	return r.length
+}
@@ -0,0 +1,57 @@
+// Package source models a single audio source
+package source
+
+import (
+	"sync"
+)
+
+// Register encoded audio data under a name, e.g. an embedded asset, to be loaded from memory instead of the filesystem.
+// The data is decoded immediately, and an error is returned if it cannot be. Registering a name again replaces it, in every cache.
+func Register(name string, data []byte) error {
+	registryMutex.Lock()
+	previous, existed := registry[name]
+	registry[name] = data
+	registryMutex.Unlock()
+	s, err := defaultCache.New(name)
+	if err != nil {
+		registryMutex.Lock()
+		if existed {
+			registry[name] = previous
+		} else {
+			delete(registry, name)
+		}
+		registryMutex.Unlock()
+		return err
+	}
+	eachCache(func(c *Cache) {
+		if c == defaultCache {
+			c.store(s)
+		} else {
+			c.Evict(name) // loaded again from the registry if it is needed, for the spec of that cache
+		}
+	})
+	return nil
+}
+
+// IsRegistered is true if a name has been registered with audio data
+func IsRegistered(name string) bool {
+	_, ok := registered(name)
+	return ok
+}
+
+//
+// Private
+//
+
+var (
+	registry      = make(map[string][]byte)
+	registryMutex = &sync.Mutex{}
+	readFile      func(path string) ([]byte, error)
+)
+
+func registered(name string) (data []byte, ok bool) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	data, ok = registry[name]
+	return
+}
@@ -0,0 +1,43 @@
+// Package source models a single audio source
+package source
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	testSourceSetup(44100, 1)
+	data, err := ioutil.ReadFile("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, Register("kick.wav", data))
+	assert.True(t, IsRegistered("kick.wav"))
+	s := Get("kick.wav")
+	assert.NotNil(t, s)
+	assert.True(t, s.Length() > 0)
+	// survives pruning, by decoding again from the registered data
+	Prune(map[string]bool{})
+	assert.Nil(t, Get("kick.wav"))
+	assert.Nil(t, Prepare("kick.wav"))
+	assert.Equal(t, s.Length(), Get("kick.wav").Length())
+}
+
+func TestRegister_Replace(t *testing.T) {
+	testSourceSetup(44100, 1)
+	data, err := ioutil.ReadFile("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, Register("snare.wav", data))
+	replacement, err := ioutil.ReadFile("testdata/Float32bitLittleEndian48000HzEstéreo.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, Register("snare.wav", replacement))
+	assert.Equal(t, 2, Get("snare.wav").Spec().Channels)
+}
+
+func TestRegister_FAIL(t *testing.T) {
+	testSourceSetup(44100, 1)
+	assert.NotNil(t, Register("garbage.wav", []byte("this is not a WAV file")))
+	assert.False(t, IsRegistered("garbage.wav"))
+	assert.Nil(t, Get("garbage.wav"))
+}
@@ -0,0 +1,206 @@
+// Package source models a single audio source
+package source
+
+import (
+	"math"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+// ResampleQuality of the conversion of source audio to the master frequency, once when it is loaded
+type ResampleQuality uint
+
+const (
+	// ResampleFast is linear interpolation, which is quick to load but aliases, e.g. on hats and cymbals
+	ResampleFast ResampleQuality = iota
+	// ResampleMedium is a windowed-sinc filter 8 zero-crossings wide, and the default
+	ResampleMedium
+	// ResampleHigh is a windowed-sinc filter 32 zero-crossings wide, with alias components below -60dB
+	ResampleHigh
+)
+
+// SetResampleQuality of source audio of the cache loaded from now on, and reload any source in memory of the cache that was resampled.
+func (c *Cache) SetResampleQuality(q ResampleQuality) {
+	c.settingsMutex.Lock()
+	c.resampleQuality = q
+	c.settingsMutex.Unlock()
+	c.reloadWhere(func(s *Source) bool {
+		return s.audioSpec != nil && s.freq != s.audioSpec.Freq
+	})
+}
+
+// GetResampleQuality of source audio of the cache
+func (c *Cache) GetResampleQuality() ResampleQuality {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+	return c.resampleQuality
+}
+
+// SetResampleQuality of source audio of the default cache, see Cache.SetResampleQuality
+func SetResampleQuality(q ResampleQuality) {
+	defaultCache.SetResampleQuality(q)
+}
+
+// GetResampleQuality of source audio of the default cache
+func GetResampleQuality() ResampleQuality {
+	return defaultCache.GetResampleQuality()
+}
+
+//
+// Private
+//
+
+const (
+	// resampleOversample is the # of points per zero-crossing in the table of the windowed-sinc kernel
+	resampleOversample = 512
+)
+
+// resample from the source frequency to the master frequency, at a quality
+func resample(q ResampleQuality, in []sample.Sample, fromFreq float64, toFreq float64) []sample.Sample {
+	return newResampler(q, fromFreq, toFreq).all(in)
+}
+
+// resampleLength of the output, such that the last output sample is no later than the last input sample
+func resampleLength(inLength int, ratio float64) int {
+	return int(math.Floor(float64(inLength-1)/ratio)) + 1
+}
+
+// resampleLinear from the source frequency to the master frequency
+func resampleLinear(in []sample.Sample, fromFreq float64, toFreq float64) []sample.Sample {
+	return (&resampler{ratio: fromFreq / toFreq, linear: true}).all(in)
+}
+
+// resampleSinc with a Blackman-Harris windowed-sinc filter, a # of zero-crossings wide on either side,
+// and cut off at a proportion (rolloff) of the lower of the two Nyquist frequencies to reject aliases.
+func resampleSinc(in []sample.Sample, fromFreq float64, toFreq float64, zeroCrossings int, rolloff float64) []sample.Sample {
+	return newResamplerSinc(fromFreq, toFreq, zeroCrossings, rolloff).all(in)
+}
+
+// resampler from one frequency to another, one output sample at a time, from a window of the input,
+// e.g. a block of a source that is streamed
+type resampler struct {
+	ratio  float64 // # of input samples per output sample
+	linear bool    // else windowed-sinc
+	kernel []float64
+	cutoff float64 // in proportion to the Nyquist frequency of the input
+	reach  float64 // in input samples, on either side of each output sample
+}
+
+// newResampler from one frequency to another, at a quality
+func newResampler(q ResampleQuality, fromFreq float64, toFreq float64) *resampler {
+	switch q {
+	case ResampleFast:
+		return &resampler{ratio: fromFreq / toFreq, linear: true}
+	case ResampleHigh:
+		return newResamplerSinc(fromFreq, toFreq, 32, 0.97)
+	default:
+		return newResamplerSinc(fromFreq, toFreq, 8, 0.9)
+	}
+}
+
+// newResamplerSinc a # of zero-crossings wide, cut off at a proportion (rolloff) of the lower of the two Nyquist frequencies
+func newResamplerSinc(fromFreq float64, toFreq float64, zeroCrossings int, rolloff float64) *resampler {
+	ratio := fromFreq / toFreq
+	cutoff := math.Min(1, 1/ratio) * rolloff
+	return &resampler{
+		ratio:  ratio,
+		kernel: resampleKernel(zeroCrossings),
+		cutoff: cutoff,
+		reach:  float64(zeroCrossings) / cutoff,
+	}
+}
+
+// all of the input, resampled
+func (r *resampler) all(in []sample.Sample) (out []sample.Sample) {
+	if len(in) == 0 {
+		return
+	}
+	out = make([]sample.Sample, resampleLength(len(in), r.ratio))
+	for i := range out {
+		out[i] = sample.New(make([]sample.Value, len(in[0].Values)))
+		r.at(i, in, 0, out[i].Values)
+	}
+	return
+}
+
+// span of the input, from its first to its last index, that an output sample depends on
+func (r *resampler) span(i int) (first int, last int) {
+	at := float64(i) * r.ratio
+	if r.linear {
+		return int(at), int(at) + 1
+	}
+	return int(math.Ceil(at - r.reach)), int(math.Floor(at + r.reach))
+}
+
+// at an output index, into values of each channel, from a window of the input that begins at an input index;
+// any input outside the window is silent, as it is beyond either end of the source
+func (r *resampler) at(i int, in []sample.Sample, begin int, values []sample.Value) {
+	for c := range values {
+		values[c] = 0
+	}
+	at := float64(i) * r.ratio
+	if r.linear {
+		tz := int(at)
+		if tz < begin || tz-begin >= len(in) {
+			return
+		}
+		frac := sample.Value(at - float64(tz))
+		cur := in[tz-begin].Values
+		for c := 0; c < len(values) && c < len(cur); c++ {
+			values[c] = cur[c]
+			if frac > 0 && tz+1-begin < len(in) {
+				values[c] += frac * (in[tz+1-begin].Values[c] - cur[c])
+			}
+		}
+		return
+	}
+	first, last := r.span(i)
+	for k := first; k <= last; k++ {
+		if k < begin || k-begin >= len(in) {
+			continue
+		}
+		weight := sample.Value(r.cutoff * resampleKernelAt(r.kernel, math.Abs(at-float64(k))*r.cutoff))
+		kin := in[k-begin].Values
+		for c := 0; c < len(values) && c < len(kin); c++ {
+			values[c] += weight * kin[c]
+		}
+	}
+}
+
+// resampleKernel table of a windowed sinc, from 0 to a # of zero-crossings, resampleOversample points per zero-crossing
+func resampleKernel(zeroCrossings int) []float64 {
+	size := zeroCrossings*resampleOversample + 1
+	kernel := make([]float64, size+1) // one extra point of zero, for interpolating at the very edge
+	for j := 0; j < size; j++ {
+		x := float64(j) / resampleOversample
+		kernel[j] = resampleSincOf(x) * resampleWindowOf(x/float64(zeroCrossings))
+	}
+	return kernel
+}
+
+// resampleKernelAt a distance in zero-crossings from the center, linearly interpolated from the table
+func resampleKernelAt(kernel []float64, x float64) float64 {
+	pos := x * resampleOversample
+	j := int(pos)
+	if j >= len(kernel)-1 {
+		return 0
+	}
+	frac := pos - float64(j)
+	return kernel[j] + frac*(kernel[j+1]-kernel[j])
+}
+
+func resampleSincOf(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// resampleWindowOf the Blackman-Harris window, from 1 at the center (0) to 0 at the edge (1)
+func resampleWindowOf(x float64) float64 {
+	if x >= 1 {
+		return 0
+	}
+	p := math.Pi * (x + 1) // the window spans -1 to +1, so x=0 is its center
+	return 0.35875 - 0.48829*math.Cos(p) + 0.14128*math.Cos(2*p) - 0.01168*math.Cos(3*p)
+}
@@ -0,0 +1,68 @@
+// Package source models a single audio source
+package source
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+)
+
+func TestSetResampleQuality(t *testing.T) {
+	assert.Equal(t, ResampleMedium, GetResampleQuality())
+	testSourceSetup(44100, 2)
+	assert.Nil(t, Prepare("testdata/Float32bitLittleEndian48000HzEstéreo.wav"))
+	before := Get("testdata/Float32bitLittleEndian48000HzEstéreo.wav").sample
+	SetResampleQuality(ResampleFast)
+	defer SetResampleQuality(ResampleMedium)
+	assert.Equal(t, ResampleFast, GetResampleQuality())
+	after := Get("testdata/Float32bitLittleEndian48000HzEstéreo.wav").sample
+	assert.Equal(t, len(before), len(after))
+	assert.NotEqual(t, before, after) // reloaded at the new quality
+}
+
+func TestResampleSinc_DC(t *testing.T) {
+	in := make([]sample.Sample, 1000)
+	for i := range in {
+		in[i] = sample.New([]sample.Value{0.5, -0.5})
+	}
+	out := resampleSinc(in, 44100, 48000, 32, 0.97)
+	assert.Equal(t, resampleLength(1000, 44100.0/48000), len(out))
+	for _, smp := range out[100 : len(out)-100] {
+		assert.InDelta(t, 0.5, float64(smp.Values[0]), 0.001)
+		assert.InDelta(t, -0.5, float64(smp.Values[1]), 0.001)
+	}
+}
+
+func TestResample_HighAliasesBelow60dB(t *testing.T) {
+	SetResampleQuality(ResampleHigh)
+	defer SetResampleQuality(ResampleMedium)
+	in := make([]sample.Sample, 44100/2)
+	for i := range in {
+		in[i] = sample.New([]sample.Value{sample.Value(0.5 * math.Sin(2*math.Pi*1000*float64(i)/44100))})
+	}
+	out := resample(ResampleMedium, in, 44100, 48000)
+	// spectrum of 4800 samples from the middle, i.e. 10Hz bins with 1kHz at bin 100
+	const size = 4800
+	window := make([]float64, size)
+	for n := range window {
+		window[n] = float64(out[4800+n].Values[0]) * resampleWindowOf(2*float64(n)/size-1)
+	}
+	var peak, alias float64
+	for bin := 1; bin < size/2; bin++ {
+		var sum complex128
+		for n, v := range window {
+			sum += complex(v, 0) * cmplx.Exp(complex(0, -2*math.Pi*float64(bin*n)/size))
+		}
+		magnitude := cmplx.Abs(sum)
+		if bin >= 96 && bin <= 104 { // the main lobe of the window around 1kHz
+			peak = math.Max(peak, magnitude)
+		} else {
+			alias = math.Max(alias, magnitude)
+		}
+	}
+	assert.True(t, 20*math.Log10(alias/peak) < -60, "alias components at %.1f dB", 20*math.Log10(alias/peak))
+}
@@ -10,20 +10,28 @@ import (
 	"github.com/go-mix/mix/bind/spec"
 )
 
+// Configure the spec of the mix, for the default cache
 func Configure(s spec.AudioSpec) {
-	masterChannelsFloat = float64(s.Channels)
-	masterSpec = &s
+	defaultCache.Configure(s)
 }
 
-// New Source from a "URL" (which is actually only a file path for now)
-func New(URL string) *Source {
+// New Source from a "URL" (which is actually only a file path for now), for the spec of the default cache
+func New(URL string) (*Source, error) {
+	return defaultCache.New(URL)
+}
+
+// New Source from a "URL", for the spec of the cache, without storing it
+func (c *Cache) New(URL string) (*Source, error) {
 	// TODO: implement true URL (for now, it's being used as a path)
 	s := &Source{
 		state: STAGED,
 		URL:   URL,
+		cache: c,
+	}
+	if err := s.loadFor(c.master()); err != nil {
+		return nil, err
 	}
-	s.load()
-	return s
+	return s, nil
 }
 
 // Source stores a series of Samples in Channels across Time, for audio playback.
@@ -31,32 +39,102 @@ type Source struct {
 	URL string
 	// private
 	sample    []sample.Sample
-	maxTz     spec.Tz
+	maxTz     spec.Tz // of playback, after any trim
+	lead      spec.Tz // of the samples in memory, that playback skips, see SetAutoTrim
 	audioSpec *spec.AudioSpec
+	freq      float64          // of the samples in memory, after resampling
+	channels  int              // of the samples in memory, after any downmix
+	meta      *spec.SourceMeta // in Tz of playback, after any trim
+	stream    *stream          // or nil if the samples are in memory
+	provider  *provided        // or nil if the samples are not provided, see RegisterProvider
+	cache     *Cache           // whose spec it is loaded for
 	state     stateEnum
+	/* gain */
+	normalization float64 // in dB, applied to the samples in memory as they were loaded
+	trimBits      uint64  // linear trim gain, accessed atomically; 0 is unity
 }
 
-// SampleAt at a specific Tz, volume (0 to 1), and pan (-1 to +1)
+// SampleAt at a specific Tz, volume (0 to 1), and pan (-1 to +1), mapped onto the master channels:
+// a mono source spreads across all of them, a multichannel source downmixes to mono at -3dB per channel pair,
+// and otherwise each master channel takes the source channel nearest its position. A source with more channels
+// than the master was already downmixed as it was loaded, see SetDownmixMatrix.
 func (s *Source) SampleAt(at spec.Tz, vol float64, pan float64) (out []sample.Value) {
-	out = make([]sample.Value, masterSpec.Channels)
-	if at < s.maxTz {
-		// if s.sample[at] != 0 {
-		// 	debug.Printf("*Source[%v].SampleAt(%v): %v\n", s.URL, at, s.sample[at])
-		// }
-		if masterSpec.Channels == s.audioSpec.Channels { // same # channels; easier maths
-			for c := int(0); c < masterSpec.Channels; c++ {
-				out[c] = volume(float64(c), vol, pan) * s.sample[at].Values[c]
-			}
-		} else { // need to map # source channels to # destination channels
-			tc := float64(s.audioSpec.Channels)
-			for c := int(0); c < masterSpec.Channels; c++ {
-				out[c] = volume(float64(c), vol, pan) * s.sample[at].Values[int(math.Floor(tc*float64(c)/masterChannelsFloat))]
-			}
+	out = make([]sample.Value, s.masterChannels())
+	s.SampleAtInto(out, at, vol, pan)
+	return
+}
+
+// SampleAtInto is SampleAt, into a slice of the master channels, e.g. a buffer reused by the mix loop to avoid allocation
+func (s *Source) SampleAtInto(out []sample.Value, at spec.Tz, vol float64, pan float64) {
+	for c := range out {
+		out[c] = 0
+	}
+	if at >= s.maxTz {
+		return
+	}
+	if s.provider != nil {
+		var buf [spec.MaxChannels]sample.Value
+		values := buf[:len(out)]
+		s.provideAt(at, values)
+		mapChannels(out, values, vol*s.trim(), pan)
+		return
+	}
+	if s.stream != nil {
+		var buf [spec.MaxChannels]sample.Value
+		values := buf[:s.stream.channels]
+		if ok, starved := s.stream.valuesAt(at, values); !ok {
+			streamUnderrun(s.URL, starved)
+			return
+		}
+		mapChannels(out, values, vol*s.trim(), pan)
+		return
+	}
+	mapChannels(out, s.sample[s.lead+at].Values, vol*s.trim(), pan)
+}
+
+// mapChannels of the values of a source sample onto the master channels, one per value out, at a volume and pan
+func mapChannels(out []sample.Value, values []sample.Value, vol float64, pan float64) {
+	channels := len(out)
+	if channels == len(values) { // same # channels; easier maths
+		for c := int(0); c < channels; c++ {
+			out[c] = volume(c, channels, vol, pan) * values[c]
+		}
+	} else if channels == 1 { // downmix to mono
+		var sum sample.Value
+		for _, v := range values {
+			sum += v
+		}
+		out[0] = volume(0, channels, vol, pan) * sum / sample.Value(math.Sqrt(float64(len(values))))
+	} else { // need to map # source channels to # destination channels
+		tc := float64(len(values))
+		for c := int(0); c < channels; c++ {
+			out[c] = volume(c, channels, vol, pan) * values[int(math.Floor(tc*float64(c)/float64(channels)))]
 		}
 	}
+}
+
+// SampleAtFrac at a fractional position in Tz, linearly interpolated, e.g. for playback at a rate other than 1
+func (s *Source) SampleAtFrac(at float64, vol float64, pan float64) (out []sample.Value) {
+	channels := s.masterChannels()
+	out = make([]sample.Value, channels)
+	s.SampleAtFracInto(out, make([]sample.Value, channels), at, vol, pan)
 	return
 }
 
+// SampleAtFracInto is SampleAtFrac, into a slice of the master channels, using another of the same size as scratch
+func (s *Source) SampleAtFracInto(out []sample.Value, scratch []sample.Value, at float64, vol float64, pan float64) {
+	tz := spec.Tz(at)
+	s.SampleAtInto(out, tz, vol, pan)
+	frac := sample.Value(at - float64(tz))
+	if frac == 0 {
+		return
+	}
+	s.SampleAtInto(scratch, tz+1, vol, pan)
+	for c := range out {
+		out[c] += frac * (scratch[c] - out[c])
+	}
+}
+
 // Length of the source audio in Tz
 func (s *Source) Length() spec.Tz {
 	return s.maxTz
@@ -67,19 +145,35 @@ func (s *Source) Spec() *spec.AudioSpec {
 	return s.audioSpec
 }
 
+// Meta embedded in the source audio, e.g. the loop points and cues of a sampler WAV, in Tz of playback, or nil if it has none
+func (s *Source) Meta() *spec.SourceMeta {
+	return s.meta
+}
+
+// Size in bytes of the source audio in memory, which for a streamed source is its ring buffer
+func (s *Source) Size() int64 {
+	if s.stream != nil {
+		return int64(len(s.stream.ring)) * valueSize
+	}
+	if len(s.sample) == 0 {
+		return 0
+	}
+	return int64(len(s.sample)) * int64(len(s.sample[0].Values)) * valueSize
+}
+
 // Teardown the source audio and release its memory.
 func (s *Source) Teardown() {
 	s.sample = nil
+	s.closeStream()
+	s.stream = nil
 }
 
 //
 // Private
 //
 
-var (
-	masterChannelsFloat float64
-	masterSpec          *spec.AudioSpec
-)
+// valueSize in bytes of a sample.Value, which is a float64
+const valueSize = 8
 
 type stateEnum uint
 
@@ -87,30 +181,100 @@ const (
 	STAGED stateEnum = iota
 	LOADING
 	READY
+	FAILED
 	// it is assumed that all alive states are < FINISHED
 	// FINISHED
-	// FAILED
 )
 
-func (s *Source) load() {
+// owner cache the source is loaded for, or else the default cache
+func (s *Source) owner() *Cache {
+	if s.cache == nil {
+		return defaultCache
+	}
+	return s.cache
+}
+
+// masterChannels of the cache the source is loaded for, or else of the default cache
+func (s *Source) masterChannels() int {
+	return s.owner().master().Channels
+}
+
+// loadFor the master spec of the mix, or nil if it is not yet configured, resampling it to the master frequency
+func (s *Source) loadFor(masterSpec *spec.AudioSpec) (err error) {
 	s.state = LOADING
-	s.sample, s.audioSpec = bind.LoadWAV(s.URL)
-	if s.audioSpec == nil {
-		// TODO: handle errors loading file
-		debug.Printf("could not load WAV %s\n", s.URL)
+	s.lead = 0
+	s.closeStream()
+	s.stream = nil
+	s.provider = nil
+	if p, ok := providerOf(s.URL); ok {
+		if err = s.loadProvider(p, masterSpec); err != nil {
+			debug.Warnf("source.load(%s) failed: %s", s.URL, err)
+			s.state = FAILED
+			return
+		}
+		s.loadGain()
+		s.state = READY
+		debug.Infof("source.load(%s) provided %d samples at %vHz, %d channels", s.URL, s.maxTz, s.freq, s.channels)
+		return
+	}
+	if !IsTone(s.URL) && !IsRegistered(s.URL) && readFile == nil && s.owner().isStreamed(s.URL) {
+		if err = s.openStream(masterSpec); err == nil {
+			s.loadGain()
+			s.state = READY
+			debug.Infof("source.load(%s) streaming %d samples at %vHz, %d channels", s.URL, s.maxTz, s.freq, s.audioSpec.Channels)
+			return
+		}
+		debug.Infof("source.load(%s) cannot stream, loading it whole: %s", s.URL, err)
+	}
+	if IsTone(s.URL) {
+		s.sample, s.audioSpec, err = tone(s.URL, masterSpec)
+	} else if data, ok := registered(s.URL); ok {
+		s.sample, s.audioSpec, err = bind.LoadBytes(s.URL, data)
+	} else if readFile != nil {
+		var data []byte
+		if data, err = readFile(s.URL); err == nil {
+			s.sample, s.audioSpec, err = bind.LoadBytes(s.URL, data)
+		}
+	} else {
+		s.sample, s.audioSpec, err = bind.LoadWAV(s.URL)
+	}
+	if err != nil {
+		debug.Warnf("source.load(%s) failed: %s", s.URL, err)
+		s.state = FAILED
+		return
+	}
+	matrix, err := s.owner().downmixFor(s.audioSpec.Channels, masterSpec)
+	if err != nil {
+		debug.Warnf("source.load(%s) failed: %s", s.URL, err)
+		s.sample = nil
+		s.state = FAILED
+		return
+	}
+	s.channels = s.audioSpec.Channels
+	if matrix != nil {
+		s.sample = downmix(s.sample, matrix) // before resampling, which is then of fewer channels
+		s.channels = len(matrix)
+	}
+	s.freq = s.audioSpec.Freq
+	s.meta = s.audioSpec.Meta
+	if masterSpec != nil && s.audioSpec.Freq > 0 && s.audioSpec.Freq != masterSpec.Freq {
+		s.sample = resample(s.owner().GetResampleQuality(), s.sample, s.audioSpec.Freq, masterSpec.Freq)
+		s.freq = masterSpec.Freq
+		s.meta = s.audioSpec.Meta.Scale(s.audioSpec.Freq, masterSpec.Freq)
 	}
 	s.maxTz = spec.Tz(len(s.sample))
+	s.loadGain()
+	s.loadTrim()
 	s.state = READY
+	debug.Infof("source.load(%s) %d samples at %vHz, %d channels", s.URL, s.maxTz, s.freq, s.audioSpec.Channels)
+	return
 }
 
-// volume (0 to 1), and pan (-1 to +1)
-// TODO: ensure implicit panning of source channels! e.g. 2 channels is full left, full right.
-func volume(channel float64, volume float64, pan float64) sample.Value {
+// volume (0 to 1), and pan (-1 to +1) of one of a number of master channels, by the linear pan law; see PanLinear.
+// A source that is panned is at the gain of the pan alone, as it always was; the mixer pans its fires after their volume.
+func volume(channel int, channels int, volume float64, pan float64) sample.Value {
 	if pan == 0 {
 		return sample.Value(volume)
-	} else if pan < 0 {
-		return sample.Value(math.Max(0, 1+pan*channel/masterChannelsFloat))
-	} else { // pan > 0
-		return sample.Value(math.Max(0, 1-pan*channel/masterChannelsFloat))
 	}
+	return sample.Value(PanGain(PanLinear, channel, channels, pan))
 }
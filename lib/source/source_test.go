@@ -2,6 +2,7 @@
 package source
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,31 +21,30 @@ func TestBase(t *testing.T) {
 func TestLoad_IntVsFloat(t *testing.T) {
 	debug.Configure(true)
 	testSourceSetup(44100, 1)
-	sourceFloat := New("testdata/Float32bitLittleEndian48000HzEstéreo.wav")
+	sourceFloat, err := New("testdata/Float32bitLittleEndian48000HzEstéreo.wav")
+	assert.Nil(t, err)
 	assert.NotNil(t, sourceFloat)
 	assert.Equal(t, spec.AudioF32, sourceFloat.Spec().Format)
-	sourceInt := New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	sourceInt, err := New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
 	assert.NotNil(t, sourceInt)
 	assert.Equal(t, spec.AudioS16, sourceInt.Spec().Format)
 }
 
 func TestLoad_FAIL(t *testing.T) {
 	pathFail := "testdata/ThisShouldFailBecauseItDoesNotExist.wav"
-	defer func() {
-		msg := recover()
-		assert.IsType(t, "", msg)
-		assert.Equal(t, "File not found: "+pathFail, msg)
-	}()
 	debug.Configure(true)
 	testSourceSetup(44100, 1)
-	source := New(pathFail)
-	assert.NotNil(t, source)
+	source, err := New(pathFail)
+	assert.Nil(t, source)
+	assert.EqualError(t, err, "File not found: "+pathFail)
 }
 
 func TestLoadSigned16bitLittleEndian44100HzMono(t *testing.T) {
 	debug.Configure(true)
 	testSourceSetup(44100, 1)
-	source := New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	source, err := New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
 	assert.NotNil(t, source)
 	totalSoundMovement := testSourceAssertSound(t, source, 1)
 	assert.True(t, totalSoundMovement > .001)
@@ -53,12 +53,43 @@ func TestLoadSigned16bitLittleEndian44100HzMono(t *testing.T) {
 func TestLoadFloat32bitLittleEndian48000HzEstéreo(t *testing.T) {
 	debug.Configure(true)
 	testSourceSetup(48000, 2)
-	source := New("testdata/Float32bitLittleEndian48000HzEstéreo.wav")
+	source, err := New("testdata/Float32bitLittleEndian48000HzEstéreo.wav")
+	assert.Nil(t, err)
 	assert.NotNil(t, source)
 	totalSoundMovement := testSourceAssertSound(t, source, 2)
 	assert.True(t, totalSoundMovement > .001)
 }
 
+func TestLoad_Resample(t *testing.T) {
+	SetResampleQuality(ResampleFast)
+	defer SetResampleQuality(ResampleMedium)
+	testSourceSetup(48000, 2)
+	native, err := New("testdata/Float32bitLittleEndian48000HzEstéreo.wav")
+	assert.Nil(t, err)
+	testSourceSetup(24000, 2)
+	resampled, err := New("testdata/Float32bitLittleEndian48000HzEstéreo.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(48000), resampled.Spec().Freq)
+	assert.Equal(t, (native.Length()+1)/2, resampled.Length())
+	for tz := spec.Tz(0); tz < resampled.Length(); tz += 97 {
+		assert.Equal(t, native.SampleAt(tz*2, 1, 0), resampled.SampleAt(tz, 1, 0))
+	}
+}
+
+func TestResample(t *testing.T) {
+	in := []sample.Sample{
+		sample.New([]sample.Value{0}),
+		sample.New([]sample.Value{1}),
+		sample.New([]sample.Value{0}),
+	}
+	out := resampleLinear(in, 1, 2)
+	assert.Equal(t, 5, len(out))
+	for i, expect := range []sample.Value{0, .5, 1, .5, 0} {
+		assert.Equal(t, expect, out[i].Values[0])
+	}
+	assert.Nil(t, resampleLinear(nil, 1, 2))
+}
+
 func TestOutput(t *testing.T) {
 	// TODO: Test Source plays audio
 }
@@ -67,6 +98,32 @@ func TestSampleAt(t *testing.T) {
 	// TODO: Test Source SampleAt
 }
 
+func TestSampleAtFrac(t *testing.T) {
+	testSourceSetup(44100, 1)
+	source, err := New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	for tz := spec.Tz(1000); tz < 1010; tz++ {
+		a := source.SampleAt(tz, 1, 0)[0]
+		b := source.SampleAt(tz+1, 1, 0)[0]
+		assert.Equal(t, a, source.SampleAtFrac(float64(tz), 1, 0)[0])
+		assert.InDelta(t, float64(a+b)/2, float64(source.SampleAtFrac(float64(tz)+0.5, 1, 0)[0]), 1e-9)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	testSourceSetup(44100, 1)
+	source, err := New("../../bind/wav/testdata/SustainLoop.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, []spec.Loop{{BeginTz: 1000, EndTz: 2000}}, source.Meta().Loops)
+	testSourceSetup(88200, 1)
+	source, err = New("../../bind/wav/testdata/SustainLoop.wav")
+	assert.Nil(t, err)
+	assert.Equal(t, []spec.Loop{{BeginTz: 2000, EndTz: 4000}}, source.Meta().Loops) // resampled
+	source, err = New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	assert.Nil(t, source.Meta())
+}
+
 func TestState(t *testing.T) {
 	// TODO: Test Source State
 }
@@ -84,24 +141,32 @@ func TestTeardown(t *testing.T) {
 }
 
 func TestMixer_mixVolume(t *testing.T) {
-	masterChannelsFloat = 1
-	assert.Equal(t, sample.Value(0), volume(0, 0, 0))
-	assert.Equal(t, sample.Value(1), volume(0, 1, .5))
-	masterChannelsFloat = 2
-	assert.Equal(t, sample.Value(1), volume(0, 1, -.5))
-	assert.Equal(t, sample.Value(.75), volume(1, 1, .5))
-	assert.Equal(t, sample.Value(.5), volume(0, .5, 0))
-	assert.Equal(t, sample.Value(.5), volume(1, .5, 1))
-	masterChannelsFloat = 3
-	assert.Equal(t, sample.Value(1), volume(0, 1, 0))
-	assert.Equal(t, sample.Value(0.6666666666666667), volume(1, 1, -1))
-	assert.Equal(t, sample.Value(0.6666666666666667), volume(2, .5, -.5))
-	assert.Equal(t, sample.Value(0.6666666666666667), volume(1, .5, 1))
-	masterChannelsFloat = 4
-	assert.Equal(t, sample.Value(1), volume(0, 1, -1))
-	assert.Equal(t, sample.Value(1), volume(1, 1, 0))
-	assert.Equal(t, sample.Value(.75), volume(2, .5, .5))
-	assert.Equal(t, sample.Value(.625), volume(3, .5, -.5))
+	assert.Equal(t, sample.Value(0), volume(0, 1, 0, 0))
+	assert.Equal(t, sample.Value(1), volume(0, 1, 1, .5))
+	assert.Equal(t, sample.Value(1), volume(0, 2, 1, -.5))
+	assert.Equal(t, sample.Value(.75), volume(1, 2, 1, .5))
+	assert.Equal(t, sample.Value(.5), volume(0, 2, .5, 0))
+	assert.Equal(t, sample.Value(.5), volume(1, 2, .5, 1))
+	assert.Equal(t, sample.Value(1), volume(0, 3, 1, 0))
+	assert.Equal(t, sample.Value(0.6666666666666667), volume(1, 3, 1, -1))
+	assert.Equal(t, sample.Value(0.6666666666666667), volume(2, 3, .5, -.5))
+	assert.Equal(t, sample.Value(0.6666666666666667), volume(1, 3, .5, 1))
+	assert.Equal(t, sample.Value(1), volume(0, 4, 1, -1))
+	assert.Equal(t, sample.Value(1), volume(1, 4, 1, 0))
+	assert.Equal(t, sample.Value(.75), volume(2, 4, .5, .5))
+	assert.Equal(t, sample.Value(.625), volume(3, 4, .5, -.5))
+}
+
+func TestSampleAt_Channels(t *testing.T) {
+	testSourceSetup(44100, 4)
+	mono := &Source{sample: []sample.Sample{sample.New([]sample.Value{0.5})}, maxTz: 1}
+	assert.Equal(t, []sample.Value{0.5, 0.5, 0.5, 0.5}, mono.SampleAt(0, 1, 0)) // spread across all outputs
+	stereo := &Source{sample: []sample.Sample{sample.New([]sample.Value{0.5, -0.25})}, maxTz: 1}
+	assert.Equal(t, []sample.Value{0.5, 0.5, -0.25, -0.25}, stereo.SampleAt(0, 1, 0))
+	testSourceSetup(44100, 1)
+	downmix := stereo.SampleAt(0, 1, 0)
+	assert.Equal(t, 1, len(downmix))
+	assert.InDelta(t, 0.25/math.Sqrt2, float64(downmix[0]), 1e-9) // -3dB
 }
 
 //
@@ -2,32 +2,175 @@
 package source
 
 import (
-	"github.com/go-mix/mix/bind/spec"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+
+	"github.com/go-mix/mix/bind/spec"
 )
 
-// Prepare a source by ensuring it is stored in memory.
-func Prepare(src string) {
-	storageMutex.Lock()
-	defer storageMutex.Unlock()
-	if _, exists := storage[src]; !exists {
-		storage[src] = New(src)
+// Cache of sources in memory, loaded and resampled for the spec of one mix. The package functions, e.g. Prepare and Get,
+// use a default cache; a mixer other than the default has a cache of its own, from NewCache.
+type Cache struct {
+	masterSpec *spec.AudioSpec
+	storage    map[string]*Source
+	mutex      sync.RWMutex // read-locked to get a source, which the mix loop may do from many goroutines
+	loading    map[string]*load
+	pinned     map[string]bool
+	used       map[string]uint64 // by the clock, when each source was last prepared
+	clock      uint64
+	limit      int64 // accessed atomically; 0 is no limit
+	hits       int64 // accessed atomically
+	misses     int64 // accessed atomically
+	evictions  int64 // accessed atomically
+	workers    int64 // accessed atomically; 0 is one per CPU
+	/* settings of the sources, guarded by the settingsMutex, which is never held while a source loads */
+	settingsMutex   sync.Mutex
+	normalizeMode   NormalizeMode
+	normalizeTarget float64            // in dBFS
+	gainTrims       map[string]float64 // in dB, of each source by its key
+	downmixMatrices map[downmixKey][][]float64
+	resampleQuality ResampleQuality
+	streamSources   map[string]bool
+}
+
+// NewCache of sources in memory, which must be configured before it loads any; Teardown when it is no longer needed.
+func NewCache() *Cache {
+	c := newCache()
+	cachesMutex.Lock()
+	defer cachesMutex.Unlock()
+	caches[c] = true
+	return c
+}
+
+// Configure the spec of the mix, and reload every source in the cache that was resampled for a different master frequency,
+// or downmixed for a different # of master channels
+func (c *Cache) Configure(s spec.AudioSpec) {
+	cachesMutex.Lock()
+	caches[c] = true // again, if it was torn down
+	cachesMutex.Unlock()
+	c.mutex.Lock()
+	c.masterSpec = &s
+	c.mutex.Unlock()
+	c.reloadWhere(func(src *Source) bool {
+		return src.freq != s.Freq || (src.audioSpec != nil && src.channels != c.channelsFor(src.audioSpec.Channels, &s))
+	})
+}
+
+// Teardown the cache, evicting every source, after which it is no longer reloaded by e.g. RegisterProvider, until it is configured
+// again; its settings, e.g. the gain of each source or its resample quality, are reset to the defaults.
+func (c *Cache) Teardown() {
+	cachesMutex.Lock()
+	delete(caches, c)
+	cachesMutex.Unlock()
+	c.mutex.Lock()
+	for key := range c.storage {
+		c.evict(key)
 	}
+	c.mutex.Unlock()
+	c.resetSettings()
 }
 
-// Get a source from storage
-func Get(src string) *Source {
-	storageMutex.Lock()
-	defer storageMutex.Unlock()
-	if _, ok := storage[src]; ok {
-		return storage[src]
-	} else {
+// Prepare a source by ensuring it is stored in memory, or return an error if it cannot be loaded.
+// Concurrent calls to prepare the same source share a single load. The source is then the most recently used, see Trim.
+func (c *Cache) Prepare(src string) error {
+	c.mutex.Lock()
+	c.clock++
+	c.used[src] = c.clock
+	if _, exists := c.storage[src]; exists {
+		c.mutex.Unlock()
+		atomic.AddInt64(&c.hits, 1)
 		return nil
 	}
+	atomic.AddInt64(&c.misses, 1)
+	if l, inFlight := c.loading[src]; inFlight {
+		c.mutex.Unlock()
+		<-l.done
+		return l.err
+	}
+	l := &load{done: make(chan struct{})}
+	c.loading[src] = l
+	c.mutex.Unlock()
+	s, err := c.New(src)
+	c.mutex.Lock()
+	if err == nil {
+		c.storage[src] = s
+	} else {
+		delete(c.used, src)
+	}
+	delete(c.loading, src)
+	c.mutex.Unlock()
+	l.err = err
+	close(l.done)
+	return err
 }
 
-func GetLength(src string) spec.Tz {
-	source := Get(src)
+// Preload is Prepare, and also keeps the source in memory, regardless of Prune, until it is evicted.
+func (c *Cache) Preload(src string) error {
+	if err := c.Prepare(src); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pinned[src] = true
+	return nil
+}
+
+// Evict a source from memory; it will be loaded again if it is needed.
+func (c *Cache) Evict(src string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evict(src)
+}
+
+// Replace a source in memory with another of the same URL, e.g. loaded again by New after its file was edited, keeping the one
+// it replaces under a new key, which is returned, for whatever is still playing it to finish on; that key is evicted by Prune like
+// any other. If none was in memory, it is simply stored, and the key is empty.
+func (c *Cache) Replace(s *Source) (previous string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if old, ok := c.storage[s.URL]; ok {
+		previous = s.URL + replacedSeparator + strconv.FormatUint(atomic.AddUint64(&replacedSeq, 1), 10)
+		c.storage[previous] = old
+		c.clock++
+		c.used[previous] = c.clock
+	}
+	c.storage[s.URL] = s
+	c.clock++
+	c.used[s.URL] = c.clock
+	return
+}
+
+// Replaced is the key of the source whose audio was kept under a key returned by Replace, else false
+func Replaced(key string) (src string, ok bool) {
+	if i := strings.LastIndex(key, replacedSeparator); i >= 0 {
+		return key[:i], true
+	}
+	return "", false
+}
+
+// Size in bytes of all sources in memory
+func (c *Cache) Size() (size int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, s := range c.storage {
+		size += s.Size()
+	}
+	return
+}
+
+// Get a source from storage
+func (c *Cache) Get(src string) *Source {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.storage[src]
+}
+
+// GetLength of a source in storage, or 0 if it is not
+func (c *Cache) GetLength(src string) spec.Tz {
+	source := c.Get(src)
 	if source != nil {
 		return source.Length()
 	} else {
@@ -36,21 +179,84 @@ func GetLength(src string) spec.Tz {
 }
 
 // Prune to keep only the sources in this list
-func Prune(keep map[string]bool) {
-	storageMutex.Lock()
-	defer storageMutex.Unlock()
-	for key, _ := range storage {
-		if _, exists := keep[key]; !exists {
-			delete(storage, key)
+func (c *Cache) Prune(keep map[string]bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, _ := range c.storage {
+		if _, exists := keep[key]; !exists && !c.pinned[key] {
+			c.evict(key)
 		}
 	}
 }
 
+// Loaded sources in memory, in order of their URL, without loading any
+func (c *Cache) Loaded() (sources []*Source) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, s := range c.storage {
+		sources = append(sources, s)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].URL < sources[j].URL
+	})
+	return
+}
+
 // Count the number of sources in memory
+func (c *Cache) Count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.storage)
+}
+
+// DefaultCache of sources, which the package functions use
+func DefaultCache() *Cache {
+	return defaultCache
+}
+
+// Prepare a source in the default cache, see Cache.Prepare
+func Prepare(src string) error {
+	return defaultCache.Prepare(src)
+}
+
+// Preload a source in the default cache, see Cache.Preload
+func Preload(src string) error {
+	return defaultCache.Preload(src)
+}
+
+// Evict a source from the default cache; it will be loaded again if it is needed.
+func Evict(src string) {
+	defaultCache.Evict(src)
+}
+
+// Size in bytes of all sources in the default cache
+func Size() int64 {
+	return defaultCache.Size()
+}
+
+// Get a source from the default cache
+func Get(src string) *Source {
+	return defaultCache.Get(src)
+}
+
+// GetLength of a source in the default cache, or 0 if it is not
+func GetLength(src string) spec.Tz {
+	return defaultCache.GetLength(src)
+}
+
+// Prune the default cache to keep only the sources in this list
+func Prune(keep map[string]bool) {
+	defaultCache.Prune(keep)
+}
+
+// Loaded sources in the default cache, in order of their URL, without loading any
+func Loaded() []*Source {
+	return defaultCache.Loaded()
+}
+
+// Count the number of sources in the default cache
 func Count() int {
-	storageMutex.Lock()
-	defer storageMutex.Unlock()
-	return len(storage)
+	return defaultCache.Count()
 }
 
 //
@@ -58,10 +264,109 @@ func Count() int {
 //
 
 var (
-	storage      map[string]*Source
-	storageMutex = &sync.Mutex{}
+	replacedSeq  uint64 // accessed atomically, such that each key of a replaced source is unique
+	defaultCache = newCache()
+	caches       = map[*Cache]bool{defaultCache: true} // which are reloaded by e.g. RegisterProvider
+	cachesMutex  = &sync.Mutex{}
 )
 
-func init() {
-	storage = make(map[string]*Source, 0)
+func newCache() *Cache {
+	c := &Cache{
+		storage: make(map[string]*Source),
+		loading: make(map[string]*load),
+		pinned:  make(map[string]bool),
+		used:    make(map[string]uint64),
+	}
+	c.resetSettings()
+	return c
+}
+
+// resetSettings of the sources of the cache to the defaults
+func (c *Cache) resetSettings() {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+	c.normalizeMode, c.normalizeTarget = NormalizeOff, 0
+	c.gainTrims = make(map[string]float64)
+	c.downmixMatrices = make(map[downmixKey][][]float64, len(downmixDefaults))
+	for key, matrix := range downmixDefaults {
+		c.downmixMatrices[key] = copyMatrix(matrix)
+	}
+	c.resampleQuality = ResampleMedium
+	c.streamSources = make(map[string]bool)
+}
+
+// replacedSeparator of the key of a replaced source from the # that makes it unique, which is never in a path
+const replacedSeparator = "\x00"
+
+// load in flight, shared by concurrent calls to Prepare the same source
+type load struct {
+	done chan struct{}
+	err  error
+}
+
+// master spec of the mix, or nil if it is not yet configured
+func (c *Cache) master() *spec.AudioSpec {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.masterSpec
+}
+
+// evict a source from memory; the caller must hold the mutex
+func (c *Cache) evict(src string) {
+	if s, ok := c.storage[src]; ok {
+		s.closeStream()
+	}
+	delete(c.storage, src)
+	delete(c.pinned, src)
+	delete(c.used, src)
+}
+
+// store a source, as the most recently used, replacing any of the same URL
+func (c *Cache) store(s *Source) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.storage[s.URL] = s
+	c.clock++
+	c.used[s.URL] = c.clock
+}
+
+// eachCache, e.g. to reload its sources; fn is called without holding the mutex of the list of caches
+func eachCache(fn func(c *Cache)) {
+	cachesMutex.Lock()
+	all := make([]*Cache, 0, len(caches))
+	for c := range caches {
+		all = append(all, c)
+	}
+	cachesMutex.Unlock()
+	for _, c := range all {
+		fn(c)
+	}
+}
+
+// reloadWhere the source matches, concurrently, removing any that can no longer be loaded
+func (c *Cache) reloadWhere(match func(s *Source) bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var keys []string
+	for key, s := range c.storage {
+		if match(s) {
+			keys = append(keys, key)
+		}
+	}
+	errs := make([]error, len(keys))
+	c.parallel(len(keys), func(i int) {
+		errs[i] = c.storage[keys[i]].loadFor(c.masterSpec)
+	})
+	for i, err := range errs {
+		if err != nil {
+			c.evict(keys[i])
+		}
+	}
+}
+
+// reloadWhere the source matches, in every cache
+func reloadWhere(match func(s *Source) bool) {
+	eachCache(func(c *Cache) {
+		c.reloadWhere(match)
+	})
 }
@@ -2,11 +2,95 @@
 package source
 
 import (
+	"sync"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestPrepare(t *testing.T) {
-	// TODO: test Prepare a source by ensuring it is stored in memory.
+	testSourceSetup(44100, 1)
+	assert.Nil(t, Prepare("testdata/Signed16bitLittleEndian44100HzMono.wav"))
+	assert.NotNil(t, Get("testdata/Signed16bitLittleEndian44100HzMono.wav"))
+}
+
+func TestPrepare_FAIL(t *testing.T) {
+	testSourceSetup(44100, 1)
+	assert.NotNil(t, Prepare("testdata/ThisShouldFailBecauseItDoesNotExist.wav"))
+	assert.Nil(t, Get("testdata/ThisShouldFailBecauseItDoesNotExist.wav"))
+}
+
+func TestPrepare_Concurrent(t *testing.T) {
+	testSourceSetup(44100, 1)
+	path := "testdata/Float32bitLittleEndian48000HzEstéreo.wav"
+	Evict(path)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, Prepare(path))
+		}()
+	}
+	wg.Wait()
+	assert.NotNil(t, Get(path))
+}
+
+func TestPreload(t *testing.T) {
+	testSourceSetup(44100, 1)
+	path := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	assert.Nil(t, Preload(path))
+	Prune(map[string]bool{})
+	assert.NotNil(t, Get(path))
+	Evict(path)
+	assert.Nil(t, Get(path))
+	Prune(map[string]bool{})
+}
+
+func TestSize(t *testing.T) {
+	testSourceSetup(44100, 1)
+	Prune(map[string]bool{})
+	assert.Equal(t, int64(0), Size())
+	path := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	assert.Nil(t, Prepare(path))
+	assert.Equal(t, int64(Get(path).Length())*8, Size())
+	Evict(path)
+	assert.Equal(t, int64(0), Size())
+}
+
+func TestConfigure_Reload(t *testing.T) {
+	testSourceSetup(44100, 1)
+	path := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	assert.Nil(t, Prepare(path))
+	length := Get(path).Length()
+	testSourceSetup(22050, 1)
+	assert.Equal(t, (length+1)/2, Get(path).Length())
+	testSourceSetup(44100, 1)
+	assert.Equal(t, length, Get(path).Length())
+	Evict(path)
+}
+
+func TestReplace(t *testing.T) {
+	testSourceSetup(44100, 1)
+	path := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	Evict(path)
+	c := DefaultCache()
+	s, err := c.New(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "", c.Replace(s)) // none was in memory
+	again, err := c.New(path)
+	assert.Nil(t, err)
+	previous := c.Replace(again)
+	assert.NotEqual(t, "", previous)
+	assert.True(t, again == Get(path))
+	assert.True(t, s == Get(previous))
+	src, ok := Replaced(previous)
+	assert.True(t, ok)
+	assert.Equal(t, path, src)
+	_, ok = Replaced(path)
+	assert.False(t, ok)
+	Prune(map[string]bool{path: true})
+	assert.Nil(t, Get(previous))
 }
 
 func TestGet(t *testing.T) {
@@ -24,3 +108,15 @@ func TestPrune(t *testing.T) {
 func TestCount(t *testing.T) {
 	// TODO: test Count the number of sources in memory
 }
+
+func TestLoaded(t *testing.T) {
+	testSourceSetup(44100, 1)
+	Prune(map[string]bool{})
+	assert.Nil(t, Prepare("testdata/Signed16bitLittleEndian44100HzMono.wav"))
+	assert.Nil(t, Prepare("testdata/Float32bitLittleEndian48000HzEstéreo.wav"))
+	loaded := Loaded()
+	assert.Equal(t, 2, len(loaded))
+	assert.Equal(t, "testdata/Float32bitLittleEndian48000HzEstéreo.wav", loaded[0].URL)
+	assert.Equal(t, "testdata/Signed16bitLittleEndian44100HzMono.wav", loaded[1].URL)
+	Prune(map[string]bool{})
+}
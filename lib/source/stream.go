@@ -0,0 +1,356 @@
+// Package source models a single audio source
+package source
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/debug"
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// SetStreaming of a source of the cache, to decode it from its file a block at a time, ahead of where it plays, instead of loading it
+// whole into memory, e.g. a backing track twenty minutes long; a source in memory of the cache is reloaded. Only a WAV file can stream,
+// and any other source is loaded whole, as usual. A streamed source follows a single playhead: fires of it at different positions at
+// once will underrun.
+func (c *Cache) SetStreaming(src string, on bool) {
+	c.settingsMutex.Lock()
+	if on {
+		c.streamSources[src] = true
+	} else {
+		delete(c.streamSources, src)
+	}
+	c.settingsMutex.Unlock()
+	c.reloadWhere(func(s *Source) bool {
+		return s.URL == src
+	})
+}
+
+// SetStreaming of a source of the default cache, see Cache.SetStreaming
+func SetStreaming(src string, on bool) {
+	defaultCache.SetStreaming(src, on)
+}
+
+// SetStreamingThreshold of the size of a file in bytes, above which its source streams automatically, or 0 never to (the default)
+func SetStreamingThreshold(size int64) {
+	atomic.StoreInt64(&streamThreshold, size)
+}
+
+// StreamUnderruns counts the samples of streamed sources that played as silence, because they had not yet been decoded
+func StreamUnderruns() int64 {
+	return atomic.LoadInt64(&streamUnderruns)
+}
+
+// IsStreaming is true if the source is decoded from its file as it plays
+func (s *Source) IsStreaming() bool {
+	return s.stream != nil
+}
+
+// Prefetch a streamed source from a Tz, such that it is decoded ahead of a fire that will begin playing there soon, e.g. at an offset;
+// nothing for a source in memory.
+func (s *Source) Prefetch(at spec.Tz) {
+	if s.stream != nil {
+		s.stream.prefetch(at)
+	}
+}
+
+//
+// Private
+//
+
+// streamBlockTz decoded at a time, at most
+const streamBlockTz = 4096
+
+var (
+	streamBufferDur = 2 * time.Second // of audio decoded ahead of the playhead, at most
+	streamThreshold int64             // accessed atomically
+	streamUnderruns int64             // accessed atomically
+)
+
+// stream of a source, decoded on a goroutine of its own into a ring buffer, from which the mix loop reads without waiting
+type stream struct {
+	in        bind.Stream
+	resampler *resampler  // or nil if the file is at the master frequency
+	downmix   [][]float64 // or nil if the file has no more channels than the master, see SetDownmixMatrix
+	channels  int         // after any downmix
+	sizeTz    spec.Tz     // of the ring buffer
+	blockTz   spec.Tz     // decoded at a time
+	lengthTz  spec.Tz     // of the source, at the master frequency
+	wake      chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+	/* guarded by the mutex */
+	mutex   sync.Mutex
+	ring    []sample.Value // of each channel of each Tz
+	beginTz spec.Tz        // of the samples in the ring buffer
+	endTz   spec.Tz        // of the samples in the ring buffer, and where the decoder continues
+	playTz  spec.Tz        // last played or prefetched
+	seekTz  spec.Tz
+	seeking bool
+	starved bool // since the last underrun
+	/* owned by the decoder */
+	cursor     int64           // frame of the file to read next
+	input      []sample.Sample // window of the file, to resample
+	inputBegin int64           // frame of the first sample in the input window
+}
+
+// isStreamed source, by request of the cache or by the size of its file
+func (c *Cache) isStreamed(src string) bool {
+	c.settingsMutex.Lock()
+	on := c.streamSources[src]
+	c.settingsMutex.Unlock()
+	if on {
+		return true
+	}
+	threshold := atomic.LoadInt64(&streamThreshold)
+	if threshold <= 0 {
+		return false
+	}
+	info, err := os.Stat(src)
+	return err == nil && info.Size() > threshold
+}
+
+// openStream of the source file for a master spec, or nil, decoded ahead from the beginning before it returns
+func (s *Source) openStream(masterSpec *spec.AudioSpec) error {
+	in, err := bind.OpenStream(s.URL)
+	if err != nil {
+		return err
+	}
+	if channels := in.Spec().Channels; channels < 1 || channels > spec.MaxChannels {
+		in.Close()
+		return fmt.Errorf("Cannot stream %d channels", channels)
+	}
+	matrix, err := s.owner().downmixFor(in.Spec().Channels, masterSpec)
+	if err != nil {
+		in.Close()
+		return err
+	}
+	s.audioSpec = in.Spec()
+	s.freq = s.audioSpec.Freq
+	s.meta = s.audioSpec.Meta
+	st := &stream{
+		in:       in,
+		downmix:  matrix,
+		channels: s.audioSpec.Channels,
+		lengthTz: spec.Tz(in.Frames()),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	if matrix != nil {
+		st.channels = len(matrix)
+	}
+	if masterSpec != nil && s.audioSpec.Freq > 0 && s.audioSpec.Freq != masterSpec.Freq {
+		st.resampler = newResampler(s.owner().GetResampleQuality(), s.audioSpec.Freq, masterSpec.Freq)
+		s.freq = masterSpec.Freq
+		s.meta = s.audioSpec.Meta.Scale(s.audioSpec.Freq, masterSpec.Freq)
+		if in.Frames() > 0 {
+			st.lengthTz = spec.Tz(resampleLength(int(in.Frames()), st.resampler.ratio))
+		}
+	}
+	st.sizeTz = spec.Tz(s.freq * streamBufferDur.Seconds())
+	st.blockTz = streamBlockTz
+	if st.blockTz > st.sizeTz/4 {
+		st.blockTz = st.sizeTz / 4
+	}
+	st.ring = make([]sample.Value, int(st.sizeTz)*st.channels)
+	for st.fill() {
+		// the first ring buffer, before any sample of it is played
+	}
+	go st.run()
+	s.stream = st
+	s.channels = st.channels
+	s.sample = nil
+	s.maxTz = st.lengthTz
+	return nil
+}
+
+// closeStream of the source, if it is streamed, which then plays silence, e.g. once it is evicted
+func (s *Source) closeStream() {
+	if s.stream != nil {
+		s.stream.stopOnce.Do(func() {
+			close(s.stream.stop)
+		})
+	}
+}
+
+// valuesAt a Tz, of each channel, or false if it has not yet been decoded, e.g. while the decoder catches up after a seek,
+// and true for starved if this begins an underrun
+func (st *stream) valuesAt(at spec.Tz, values []sample.Value) (ok bool, starved bool) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.playTz = at
+	if at >= st.beginTz && at < st.endTz {
+		i := int(at%st.sizeTz) * st.channels
+		copy(values, st.ring[i:i+st.channels])
+		st.starved = false
+		if st.endTz-at < st.sizeTz/2 && st.endTz < st.lengthTz {
+			st.signal()
+		}
+		return true, false
+	}
+	if at < st.beginTz || at >= st.endTz+st.sizeTz/2 {
+		st.seekTz, st.seeking = at, true // else, the decoder is only a little behind
+	}
+	st.signal()
+	starved = !st.starved
+	st.starved = true
+	return false, starved
+}
+
+// prefetch from a Tz, unless it is already decoded
+func (st *stream) prefetch(at spec.Tz) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.playTz = at
+	if at < st.beginTz || at >= st.endTz {
+		st.seekTz, st.seeking = at, true
+	}
+	st.signal()
+}
+
+// signal the decoder, without waiting; the caller must hold the mutex
+func (st *stream) signal() {
+	select {
+	case st.wake <- struct{}{}:
+	default: // already signaled
+	}
+}
+
+// run the decoder until the stream is closed, filling the ring buffer each time it is signaled
+func (st *stream) run() {
+	defer st.in.Close()
+	for {
+		select {
+		case <-st.stop:
+			return
+		case <-st.wake:
+		}
+		for st.fill() {
+			select {
+			case <-st.stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// fill the ring buffer with the next block ahead of the playhead, or return false if it is far enough ahead, or at the end
+func (st *stream) fill() bool {
+	st.mutex.Lock()
+	if st.seeking {
+		st.beginTz, st.endTz = st.seekTz, st.seekTz
+		st.seeking = false
+	}
+	fromTz := st.endTz
+	toTz := fromTz + st.blockTz
+	if toTz > st.lengthTz {
+		toTz = st.lengthTz
+	}
+	if fromTz >= toTz || toTz > st.playTz+st.sizeTz {
+		st.mutex.Unlock()
+		return false
+	}
+	st.mutex.Unlock()
+	block, err := st.decode(fromTz, toTz)
+	if err != nil {
+		debug.Warnf("source.stream failed to decode at %dz: %s", fromTz, err)
+		return false
+	}
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	if st.seeking || st.endTz != fromTz {
+		return true // superseded by a seek
+	}
+	for at := fromTz; at < toTz; at++ {
+		i := int(at%st.sizeTz) * st.channels
+		copy(st.ring[i:i+st.channels], block[int(at-fromTz)*st.channels:])
+	}
+	st.endTz = toTz
+	if st.endTz-st.beginTz > st.sizeTz {
+		st.beginTz = st.endTz - st.sizeTz
+	}
+	return true
+}
+
+// decode the samples from one Tz to another, at the master frequency, of each channel
+func (st *stream) decode(fromTz spec.Tz, toTz spec.Tz) ([]sample.Value, error) {
+	block := make([]sample.Value, int(toTz-fromTz)*st.channels)
+	if st.resampler == nil {
+		samples, err := st.read(int64(fromTz), int64(toTz))
+		if err != nil {
+			return nil, err
+		}
+		for i, smp := range samples {
+			copy(block[i*st.channels:(i+1)*st.channels], smp.Values)
+		}
+		return block, nil
+	}
+	first, _ := st.resampler.span(int(fromTz))
+	_, last := st.resampler.span(int(toTz) - 1)
+	if first < 0 {
+		first = 0
+	}
+	begin := int64(first)
+	if begin < st.inputBegin || begin > st.inputBegin+int64(len(st.input)) {
+		st.input, st.inputBegin = nil, begin
+	} else {
+		st.input = st.input[begin-st.inputBegin:]
+		st.inputBegin = begin
+	}
+	more, err := st.read(st.inputBegin+int64(len(st.input)), int64(last)+1)
+	if err != nil {
+		return nil, err
+	}
+	st.input = append(st.input, more...)
+	for at := fromTz; at < toTz; at++ {
+		i := int(at-fromTz) * st.channels
+		st.resampler.at(int(at), st.input, int(st.inputBegin), block[i:i+st.channels])
+	}
+	return block, nil
+}
+
+// read the frames of the file from one to another, seeking if need be, and fewer at the end of the file
+func (st *stream) read(from int64, to int64) (samples []sample.Sample, err error) {
+	if to > st.in.Frames() {
+		to = st.in.Frames()
+	}
+	if from >= to {
+		return
+	}
+	if from != st.cursor {
+		if err = st.in.SeekFrame(from); err != nil {
+			return
+		}
+		st.cursor = from
+	}
+	for st.cursor < to {
+		var more []sample.Sample
+		more, err = st.in.Read(int(to - st.cursor))
+		st.cursor += int64(len(more))
+		if st.downmix != nil {
+			more = downmix(more, st.downmix)
+		}
+		samples = append(samples, more...)
+		if err == io.EOF {
+			return samples, nil
+		} else if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// streamUnderrun of a sample that played as silence, warning once at the beginning of each underrun
+func streamUnderrun(src string, starved bool) {
+	atomic.AddInt64(&streamUnderruns, 1)
+	if starved {
+		debug.Warnf("source.stream(%s) underrun, playing silence until it catches up", src)
+	}
+}
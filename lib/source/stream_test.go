@@ -0,0 +1,140 @@
+// Package source models a single audio source
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetStreaming(t *testing.T) {
+	testSourceSetup(44100, 1)
+	src := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	whole, err := New(src)
+	assert.Nil(t, err)
+	assert.False(t, whole.IsStreaming())
+	SetStreaming(src, true)
+	defer SetStreaming(src, false)
+	streamed, err := New(src)
+	assert.Nil(t, err)
+	defer streamed.Teardown()
+	assert.True(t, streamed.IsStreaming())
+	assert.Equal(t, whole.Length(), streamed.Length())
+	assert.Equal(t, int64(44100*2*8), streamed.Size())
+	for tz := spec.Tz(0); tz < whole.Length(); tz++ {
+		assert.Equal(t, whole.SampleAt(tz, 1, 0), streamed.SampleAt(tz, 1, 0))
+	}
+}
+
+func TestSetStreaming_Resample(t *testing.T) {
+	testSourceSetup(44100, 2)
+	src := "testdata/Float32bitLittleEndian48000HzEstéreo.wav"
+	whole, err := New(src)
+	assert.Nil(t, err)
+	SetStreaming(src, true)
+	defer SetStreaming(src, false)
+	streamed, err := New(src)
+	assert.Nil(t, err)
+	defer streamed.Teardown()
+	assert.True(t, streamed.IsStreaming())
+	assert.Equal(t, float64(48000), streamed.Spec().Freq)
+	assert.Equal(t, whole.Length(), streamed.Length())
+	for tz := spec.Tz(0); tz < whole.Length(); tz++ {
+		assert.Equal(t, whole.SampleAt(tz, 1, 0), streamed.SampleAt(tz, 1, 0))
+	}
+}
+
+func TestSetStreaming_Reload(t *testing.T) {
+	testSourceSetup(44100, 1)
+	src := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	assert.Nil(t, Prepare(src))
+	defer Evict(src)
+	SetStreaming(src, true)
+	assert.True(t, Get(src).IsStreaming())
+	SetStreaming(src, false)
+	assert.False(t, Get(src).IsStreaming())
+}
+
+func TestSetStreamingThreshold(t *testing.T) {
+	testSourceSetup(44100, 1)
+	SetStreamingThreshold(1000)
+	defer SetStreamingThreshold(0)
+	streamed, err := New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	defer streamed.Teardown()
+	assert.True(t, streamed.IsStreaming())
+	SetStreamingThreshold(1000000)
+	whole, err := New("testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	assert.False(t, whole.IsStreaming())
+}
+
+func TestStream_Seek(t *testing.T) {
+	testSourceSetup(44100, 1)
+	defer testStreamBuffer(100 * time.Millisecond)() // shorter than the source
+	src := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	SetResampleQuality(ResampleFast)
+	defer SetResampleQuality(ResampleMedium)
+	whole, err := New(src)
+	assert.Nil(t, err)
+	SetStreaming(src, true)
+	defer SetStreaming(src, false)
+	streamed, err := New(src)
+	assert.Nil(t, err)
+	defer streamed.Teardown()
+	assert.True(t, whole.Length() > streamed.stream.sizeTz)
+	late := whole.Length() - 10
+	streamed.Prefetch(late)
+	testStreamAwait(t, streamed, late)
+	assert.Equal(t, whole.SampleAt(late, 1, 0), streamed.SampleAt(late, 1, 0))
+}
+
+func TestStream_Underrun(t *testing.T) {
+	testSourceSetup(44100, 1)
+	defer testStreamBuffer(100 * time.Millisecond)()
+	src := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	whole, err := New(src)
+	assert.Nil(t, err)
+	SetStreaming(src, true)
+	defer SetStreaming(src, false)
+	streamed, err := New(src)
+	assert.Nil(t, err)
+	defer streamed.Teardown()
+	middle := streamed.Length() / 2
+	assert.NotEqual(t, []sample.Value{0}, whole.SampleAt(middle, 1, 0))
+	before := StreamUnderruns()
+	assert.Equal(t, []sample.Value{0}, streamed.SampleAt(middle, 1, 0)) // not yet decoded, so silent instead of waiting
+	assert.Equal(t, before+1, StreamUnderruns())
+	testStreamAwait(t, streamed, middle)
+	assert.Equal(t, whole.SampleAt(middle, 1, 0), streamed.SampleAt(middle, 1, 0))
+	assert.Equal(t, before+1, StreamUnderruns())
+}
+
+//
+// Private
+//
+
+// testStreamBuffer of a duration, and return a func to restore the default
+func testStreamBuffer(d time.Duration) (restore func()) {
+	streamBufferDur = d
+	return func() {
+		streamBufferDur = 2 * time.Second
+	}
+}
+
+// testStreamAwait until the stream has decoded a Tz
+func testStreamAwait(t *testing.T, s *Source, at spec.Tz) {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		s.stream.mutex.Lock()
+		ready := at >= s.stream.beginTz && at < s.stream.endTz
+		s.stream.mutex.Unlock()
+		if ready {
+			return
+		}
+	}
+	t.Fatalf("stream did not decode %dz", at)
+}
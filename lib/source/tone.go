@@ -0,0 +1,144 @@
+// Package source models a single audio source
+package source
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// Waveform of a synthesized tone
+type Waveform string
+
+const (
+	WaveSine   Waveform = "sine"
+	WaveSquare Waveform = "square"
+	WaveNoise  Waveform = "noise" // white noise, the same burst every time, ignoring the frequency
+	WavePink   Waveform = "pink"  // pink noise, -3dB per octave, the same burst every time, ignoring the frequency
+	WaveSweep  Waveform = "sweep" // a logarithmic sine sweep from SweepLowHz up to the frequency, over the length of the tone
+)
+
+// SweepLowHz where a WaveSweep begins
+const SweepLowHz = 20
+
+// ToneRampDur is the linear attack and release of a synthesized tone, so that it doesn't click.
+const ToneRampDur = 3 * time.Millisecond
+
+// ToneKey of a tone source, synthesized at the master frequency instead of loading a file, e.g. for a metronome click.
+func ToneKey(wave Waveform, freq float64, length time.Duration) string {
+	return fmt.Sprintf("%s%s:%g:%d", tonePrefix, wave, freq, length.Nanoseconds())
+}
+
+// IsTone is true if the source is synthesized
+func IsTone(src string) bool {
+	return strings.HasPrefix(src, tonePrefix)
+}
+
+//
+// Private
+//
+
+const tonePrefix = "tone:"
+
+// parseTone of a key made by ToneKey
+func parseTone(src string) (wave Waveform, freq float64, length time.Duration, err error) {
+	var nanos int64
+	var waveName string
+	if _, err = fmt.Sscanf(strings.Replace(strings.TrimPrefix(src, tonePrefix), ":", " ", -1), "%s %g %d", &waveName, &freq, &nanos); err != nil {
+		err = errors.New("Invalid tone " + src)
+		return
+	}
+	wave, length = Waveform(waveName), time.Duration(nanos)
+	return
+}
+
+// tone synthesized in mono at the master frequency of a spec, or nil if it is not yet configured
+func tone(src string, masterSpec *spec.AudioSpec) (out []sample.Sample, audioSpec *spec.AudioSpec, err error) {
+	if masterSpec == nil || masterSpec.Freq <= 0 {
+		err = errors.New("Must configure the master frequency before synthesizing tone " + src)
+		return
+	}
+	wave, freq, length, err := parseTone(src)
+	if err != nil {
+		return
+	}
+	var value func(t float64) float64
+	switch wave {
+	case WaveSine:
+		value = func(t float64) float64 { return math.Sin(2 * math.Pi * freq * t) }
+	case WaveSquare:
+		value = func(t float64) float64 {
+			if math.Mod(freq*t, 1) < 0.5 {
+				return 1
+			}
+			return -1
+		}
+	case WaveNoise:
+		noise := rand.New(rand.NewSource(1))
+		value = func(t float64) float64 { return noise.Float64()*2 - 1 }
+	case WavePink:
+		value = pinkNoise()
+	case WaveSweep:
+		value = logSweep(math.Min(freq, masterSpec.Freq/2), length.Seconds())
+	default:
+		err = errors.New("Unsupported tone waveform: " + string(wave))
+		return
+	}
+	audioSpec = &spec.AudioSpec{
+		Freq:     masterSpec.Freq,
+		Format:   spec.AudioF64,
+		Channels: 1,
+	}
+	n := int(length.Seconds() * masterSpec.Freq)
+	values := make([]float64, n)
+	peak := 0.0
+	for i := range values {
+		values[i] = value(float64(i) / masterSpec.Freq)
+		peak = math.Max(peak, math.Abs(values[i]))
+	}
+	if wave == WavePink && peak > 0 {
+		for i := range values {
+			values[i] /= peak // filtered noise has no natural full scale
+		}
+	}
+	ramp := math.Min(ToneRampDur.Seconds()*masterSpec.Freq, float64(n)/2)
+	out = make([]sample.Sample, n)
+	for i := range out {
+		gain := 1.0
+		if fromEdge := math.Min(float64(i), float64(n-1-i)); fromEdge < ramp {
+			gain = fromEdge / ramp
+		}
+		out[i] = sample.New([]sample.Value{sample.Value(gain * values[i])})
+	}
+	return
+}
+
+// pinkNoise of white noise filtered by Paul Kellet's economy filter, the same burst every time; it must be called in order of time
+func pinkNoise() func(t float64) float64 {
+	noise := rand.New(rand.NewSource(1))
+	var b0, b1, b2 float64
+	return func(t float64) float64 {
+		white := noise.Float64()*2 - 1
+		b0 = 0.99765*b0 + white*0.0990460
+		b1 = 0.96300*b1 + white*0.2965164
+		b2 = 0.57000*b2 + white*1.0526913
+		return b0 + b1 + b2 + white*0.1848
+	}
+}
+
+// logSweep of a sine from SweepLowHz up to a frequency over a length in seconds, whose frequency doubles in equal times
+func logSweep(freq float64, length float64) func(t float64) float64 {
+	logRatio := math.Log(freq / SweepLowHz)
+	if logRatio <= 0 || length <= 0 {
+		return func(t float64) float64 { return math.Sin(2 * math.Pi * SweepLowHz * t) }
+	}
+	return func(t float64) float64 {
+		return math.Sin(2 * math.Pi * SweepLowHz * length / logRatio * (math.Exp(t/length*logRatio) - 1))
+	}
+}
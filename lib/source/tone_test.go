@@ -0,0 +1,96 @@
+// Package source models a single audio source
+package source
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToneKey(t *testing.T) {
+	key := ToneKey(WaveSine, 440, 50*time.Millisecond)
+	assert.Equal(t, "tone:sine:440:50000000", key)
+	assert.True(t, IsTone(key))
+	assert.False(t, IsTone("testdata/Signed16bitLittleEndian44100HzMono.wav"))
+	wave, freq, length, err := parseTone(key)
+	assert.Nil(t, err)
+	assert.Equal(t, WaveSine, wave)
+	assert.Equal(t, float64(440), freq)
+	assert.Equal(t, 50*time.Millisecond, length)
+}
+
+func TestTone_Sine(t *testing.T) {
+	testSourceSetup(44100, 1)
+	s, err := New(ToneKey(WaveSine, 441, 100*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, 4410, int(s.Length()))
+	assert.Equal(t, float64(44100), s.Spec().Freq)
+	assert.Equal(t, float64(0), float64(s.sample[0].Values[0]))       // attack ramp from silence
+	assert.InDelta(t, 0.0, float64(s.sample[4409].Values[0]), 0.0001) // release ramp to silence
+	assert.InDelta(t, 1.0, float64(s.sample[2025].Values[0]), 0.0001) // peak of a cycle, after the ramp
+	assert.InDelta(t, -1.0, float64(s.sample[2075].Values[0]), 0.0001)
+}
+
+func TestTone_Square(t *testing.T) {
+	testSourceSetup(48000, 1)
+	s, err := New(ToneKey(WaveSquare, 1000, 10*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, 480, int(s.Length()))
+	assert.Equal(t, float64(48000), s.Spec().Freq)
+	assert.Equal(t, float64(1), float64(s.sample[240].Values[0]))
+	assert.Equal(t, float64(-1), float64(s.sample[264].Values[0]))
+}
+
+func TestTone_Noise(t *testing.T) {
+	testSourceSetup(44100, 1)
+	a, err := New(ToneKey(WaveNoise, 0, 10*time.Millisecond))
+	assert.Nil(t, err)
+	b, err := New(ToneKey(WaveNoise, 0, 10*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, a.sample, b.sample) // deterministic
+	for _, smp := range a.sample {
+		assert.True(t, math.Abs(float64(smp.Values[0])) <= 1)
+	}
+}
+
+func TestTone_Pink(t *testing.T) {
+	testSourceSetup(44100, 1)
+	a, err := New(ToneKey(WavePink, 0, 100*time.Millisecond))
+	assert.Nil(t, err)
+	b, err := New(ToneKey(WavePink, 0, 100*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, a.sample, b.sample) // deterministic
+	peak := 0.0
+	for _, smp := range a.sample {
+		peak = math.Max(peak, math.Abs(float64(smp.Values[0])))
+	}
+	assert.InDelta(t, 1.0, peak, 0.0001) // normalized
+}
+
+func TestTone_Sweep(t *testing.T) {
+	testSourceSetup(44100, 1)
+	s, err := New(ToneKey(WaveSweep, 20000, time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, 44100, int(s.Length()))
+	// under 40Hz at the beginning, and over 10kHz at the end, by the zero crossings of a tenth of a second
+	crossings := func(from int) (n int) {
+		for i := from + 1; i < from+4410; i++ {
+			if (s.sample[i-1].Values[0] < 0) != (s.sample[i].Values[0] < 0) {
+				n++
+			}
+		}
+		return
+	}
+	assert.True(t, crossings(0) < 10)
+	assert.True(t, crossings(44100-4410-1) > 2*10000/10)
+}
+
+func TestTone_FAIL(t *testing.T) {
+	testSourceSetup(44100, 1)
+	_, err := New(ToneKey(Waveform("sawtooth"), 440, 10*time.Millisecond))
+	assert.EqualError(t, err, "Unsupported tone waveform: sawtooth")
+	_, err = New("tone:garbage")
+	assert.EqualError(t, err, "Invalid tone tone:garbage")
+}
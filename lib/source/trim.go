@@ -0,0 +1,133 @@
+// Package source models a single audio source
+package source
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+// SetAutoTrim, if on, of the leading silence of each source as it is loaded, up to its first sample above a threshold in dBFS, e.g.
+// -60, such that its fires begin at its transient; and of its trailing silence too, if trailing, which shortens its natural length.
+// The samples in memory are neither copied nor changed: playback only skips the silence. Every source in memory is reloaded.
+// A streamed source, which is never decoded whole, a provided source or a synthesized tone is not trimmed.
+func SetAutoTrim(on bool, thresholdDB float64, trailing bool) {
+	trimMutex.Lock()
+	autoTrimOn, autoTrimThreshold, autoTrimTrailing = on, thresholdDB, trailing
+	trimMutex.Unlock()
+	reloadWhere(isTrimmable)
+}
+
+// GetAutoTrim setting, see SetAutoTrim
+func GetAutoTrim() (on bool, thresholdDB float64, trailing bool) {
+	trimMutex.Lock()
+	defer trimMutex.Unlock()
+	return autoTrimOn, autoTrimThreshold, autoTrimTrailing
+}
+
+// SetTrimOffset of a source, the leading audio that playback skips, instead of the leading silence detected by SetAutoTrim, whether
+// or not it is on, e.g. 0 to keep the pickup of one sample; a negative offset removes it. The source is reloaded in every cache.
+func SetTrimOffset(src string, offset time.Duration) {
+	trimMutex.Lock()
+	if offset < 0 {
+		delete(trimOffsets, src)
+	} else {
+		trimOffsets[src] = offset
+	}
+	trimMutex.Unlock()
+	reloadWhere(func(s *Source) bool {
+		return s.URL == src && isTrimmable(s)
+	})
+}
+
+// GetTrimOffset of a source, and ok if it was set, see SetTrimOffset
+func GetTrimOffset(src string) (offset time.Duration, ok bool) {
+	trimMutex.Lock()
+	defer trimMutex.Unlock()
+	offset, ok = trimOffsets[src]
+	return
+}
+
+// TrimOffset of the source in Tz, the leading audio that playback skips, see SetAutoTrim
+func (s *Source) TrimOffset() spec.Tz {
+	return s.lead
+}
+
+//
+// Private
+//
+
+var (
+	autoTrimOn        bool
+	autoTrimThreshold float64 // in dBFS
+	autoTrimTrailing  bool
+	trimOffsets       = make(map[string]time.Duration)
+	trimMutex         = &sync.Mutex{}
+)
+
+// isTrimmable source, whose samples are all in memory
+func isTrimmable(s *Source) bool {
+	return !IsTone(s.URL) && s.stream == nil && s.provider == nil
+}
+
+// loadTrim of the source, by the cursors of playback into its samples in memory, after they are loaded and normalized
+func (s *Source) loadTrim() {
+	s.lead = 0
+	if !isTrimmable(s) || len(s.sample) == 0 {
+		return
+	}
+	trimMutex.Lock()
+	on, threshold, trailing := autoTrimOn, autoTrimThreshold, autoTrimTrailing
+	offset, override := trimOffsets[s.URL]
+	trimMutex.Unlock()
+	if !on && !override {
+		return
+	}
+	begin, end := 0, len(s.sample)
+	if level := math.Pow(10, threshold/20); on {
+		if first := s.firstAbove(level); first < len(s.sample) { // else it is all silence, left as is
+			begin = first
+			if trailing {
+				end = s.lastAbove(level) + 1
+			}
+		}
+	}
+	if override {
+		begin = int(math.Round(offset.Seconds() * s.freq))
+	}
+	if end < begin {
+		end = begin // all silence
+	}
+	if begin > len(s.sample) {
+		begin, end = len(s.sample), len(s.sample)
+	}
+	s.lead = spec.Tz(begin)
+	s.maxTz = spec.Tz(end - begin)
+	s.meta = s.meta.Offset(s.lead)
+}
+
+// firstAbove a linear level, the index of the first sample with a channel above it, or the length if there is none
+func (s *Source) firstAbove(level float64) int {
+	for i, smp := range s.sample {
+		for _, v := range smp.Values {
+			if math.Abs(float64(v)) > level {
+				return i
+			}
+		}
+	}
+	return len(s.sample)
+}
+
+// lastAbove a linear level, the index of the last sample with a channel above it, or -1 if there is none
+func (s *Source) lastAbove(level float64) int {
+	for i := len(s.sample) - 1; i >= 0; i-- {
+		for _, v := range s.sample[i].Values {
+			if math.Abs(float64(v)) > level {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,106 @@
+// Package source models a single audio source
+package source
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+)
+
+func TestSetAutoTrim(t *testing.T) {
+	testSourceSetup(44100, 1)
+	tone := ToneKey(WaveSine, 441, 100*time.Millisecond)
+	assert.Nil(t, Register("trim.wav", testTrimWAV(100, 200, 100)))
+	assert.Nil(t, PreloadAll([]string{"trim.wav", tone}))
+	defer Evict(tone)
+	defer SetAutoTrim(false, 0, false)
+	s := Get("trim.wav")
+	assert.Equal(t, spec.Tz(0), s.TrimOffset())
+	assert.Equal(t, spec.Tz(400), s.Length())
+	SetAutoTrim(true, -60, false)
+	on, threshold, trailing := GetAutoTrim()
+	assert.True(t, on)
+	assert.Equal(t, -60.0, threshold)
+	assert.False(t, trailing)
+	s = Get("trim.wav")
+	assert.Equal(t, spec.Tz(100), s.TrimOffset()) // reloaded
+	assert.Equal(t, spec.Tz(300), s.Length())
+	assert.Equal(t, 0.5, float64(s.SampleAt(0, 1, 0)[0])) // at its transient
+	assert.Equal(t, spec.Tz(0), Get(tone).TrimOffset())   // never a tone
+	SetAutoTrim(true, -60, true)
+	s = Get("trim.wav")
+	assert.Equal(t, spec.Tz(200), s.Length())
+	assert.Equal(t, 0.5, float64(s.SampleAt(199, 1, 0)[0]))
+	SetAutoTrim(false, -60, true)
+	s = Get("trim.wav")
+	assert.Equal(t, spec.Tz(0), s.TrimOffset())
+	assert.Equal(t, spec.Tz(400), s.Length())
+}
+
+func TestSetAutoTrim_Silence(t *testing.T) {
+	testSourceSetup(44100, 1)
+	assert.Nil(t, Register("silence.wav", testTrimWAV(300, 0, 0)))
+	SetAutoTrim(true, -60, true)
+	defer SetAutoTrim(false, 0, false)
+	assert.Nil(t, Prepare("silence.wav"))
+	s := Get("silence.wav")
+	assert.Equal(t, spec.Tz(0), s.TrimOffset()) // left as is
+	assert.Equal(t, spec.Tz(300), s.Length())
+}
+
+func TestSetTrimOffset(t *testing.T) {
+	testSourceSetup(44100, 1)
+	assert.Nil(t, Register("offset.wav", testTrimWAV(100, 200, 100)))
+	assert.Nil(t, Prepare("offset.wav"))
+	defer SetTrimOffset("offset.wav", -1)
+	SetTrimOffset("offset.wav", time.Second/441) // 100 samples
+	offset, ok := GetTrimOffset("offset.wav")
+	assert.True(t, ok)
+	assert.Equal(t, time.Second/441, offset)
+	s := Get("offset.wav")
+	assert.Equal(t, spec.Tz(100), s.TrimOffset())
+	assert.Equal(t, spec.Tz(300), s.Length())
+	SetAutoTrim(true, -60, false)
+	defer SetAutoTrim(false, 0, false)
+	SetTrimOffset("offset.wav", 0) // overrides the detected offset
+	assert.Equal(t, spec.Tz(0), Get("offset.wav").TrimOffset())
+	SetTrimOffset("offset.wav", time.Second) // beyond its end
+	assert.Equal(t, spec.Tz(0), Get("offset.wav").Length())
+	SetTrimOffset("offset.wav", -1)
+	_, ok = GetTrimOffset("offset.wav")
+	assert.False(t, ok)
+	assert.Equal(t, spec.Tz(100), Get("offset.wav").TrimOffset())
+}
+
+//
+// Private
+//
+
+// testTrimWAV of 32-bit float mono samples at 44.1kHz, a # of samples of faint noise below -60dBFS, loud, then faint again
+func testTrimWAV(lead int, loud int, tail int) []byte {
+	var values []float32
+	for i := 0; i < lead+loud+tail; i++ {
+		if i >= lead && i < lead+loud {
+			values = append(values, 0.5)
+		} else {
+			values = append(values, float32(0.0005*float64(i%2*2-1)))
+		}
+	}
+	dataSize := len(values) * 4
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{uint32(16), uint16(3), uint16(1), uint32(44100), uint32(44100 * 4), uint16(4), uint16(32)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	binary.Write(&buf, binary.LittleEndian, values)
+	return buf.Bytes()
+}
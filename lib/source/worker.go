@@ -0,0 +1,101 @@
+// Package source models a single audio source
+package source
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SetWorkers of the cache, to load and resample sources concurrently, at most n at a time, or 0 for one per CPU (GOMAXPROCS).
+func (c *Cache) SetWorkers(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&c.workers, int64(n))
+}
+
+// Workers of the cache that load and resample sources concurrently, resolving 0 to one per CPU (GOMAXPROCS).
+func (c *Cache) Workers() int {
+	if n := int(atomic.LoadInt64(&c.workers)); n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// SetWorkers of the default cache, see Cache.SetWorkers
+func SetWorkers(n int) {
+	defaultCache.SetWorkers(n)
+}
+
+// Workers of the default cache, see Cache.Workers
+func Workers() int {
+	return defaultCache.Workers()
+}
+
+// PreloadAll sources concurrently into the default cache, as Preload; the error returned is that of the first source, in order,
+// that cannot be loaded.
+func PreloadAll(srcs []string) error {
+	return defaultCache.PreloadAll(srcs)
+}
+
+// PreloadAll sources concurrently, as Preload; the error returned is that of the first source, in order, that cannot be loaded.
+func (c *Cache) PreloadAll(srcs []string) error {
+	return c.PreloadAllCtx(context.Background(), srcs)
+}
+
+// PreloadAllCtx sources concurrently into the default cache, as PreloadAll, until the context is done.
+func PreloadAllCtx(ctx context.Context, srcs []string) error {
+	return defaultCache.PreloadAllCtx(ctx, srcs)
+}
+
+// PreloadAllCtx sources concurrently, as PreloadAll, until the context is done; then no other source begins to load, though those
+// loading are finished, and the error of the context is returned.
+func (c *Cache) PreloadAllCtx(ctx context.Context, srcs []string) error {
+	errs := make([]error, len(srcs))
+	c.parallel(len(srcs), func(i int) {
+		if errs[i] = ctx.Err(); errs[i] == nil {
+			errs[i] = c.Preload(srcs[i])
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// Private
+//
+
+// parallel calls fn for each index from 0 to n, across at most the Workers of the cache, and returns when all calls are done
+func (c *Cache) parallel(n int, fn func(i int)) {
+	count := c.Workers()
+	if count > n {
+		count = n
+	}
+	if count <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for w := 0; w < count; w++ {
+		go func() {
+			defer wg.Done()
+			for i := int(atomic.AddInt64(&next, 1)); i < n; i = int(atomic.AddInt64(&next, 1)) {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
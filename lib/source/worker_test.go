@@ -0,0 +1,76 @@
+// Package source models a single audio source
+package source
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWorkers(t *testing.T) {
+	defer SetWorkers(0)
+	SetWorkers(3)
+	assert.Equal(t, 3, Workers())
+	SetWorkers(0)
+	assert.Equal(t, runtime.GOMAXPROCS(0), Workers())
+	c := NewCache()
+	defer c.Teardown()
+	c.SetWorkers(2)
+	assert.Equal(t, 2, c.Workers())
+	assert.Equal(t, runtime.GOMAXPROCS(0), Workers()) // of the default cache, as it was
+}
+
+func TestPreloadAll(t *testing.T) {
+	testSourceSetup(44100, 1)
+	paths := []string{
+		"testdata/Signed16bitLittleEndian44100HzMono.wav",
+		"testdata/Float32bitLittleEndian48000HzEstéreo.wav",
+	}
+	assert.Nil(t, PreloadAll(paths))
+	for _, path := range paths {
+		assert.NotNil(t, Get(path))
+		Evict(path)
+	}
+}
+
+func TestPreloadAll_FAIL(t *testing.T) {
+	testSourceSetup(44100, 1)
+	err := PreloadAll([]string{
+		"testdata/Signed16bitLittleEndian44100HzMono.wav",
+		"testdata/ThisShouldFailFirst.wav",
+		"testdata/ThisShouldFailSecond.wav",
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ThisShouldFailFirst")
+	Evict("testdata/Signed16bitLittleEndian44100HzMono.wav")
+}
+
+func TestPreloadAllCtx(t *testing.T) {
+	testSourceSetup(44100, 1)
+	path := "testdata/Signed16bitLittleEndian44100HzMono.wav"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, PreloadAllCtx(ctx, []string{path}))
+	assert.Nil(t, Get(path)) // never began to load
+	assert.Nil(t, PreloadAllCtx(context.Background(), []string{path}))
+	assert.NotNil(t, Get(path))
+	Evict(path)
+}
+
+func TestParallel(t *testing.T) {
+	defer SetWorkers(0)
+	SetWorkers(4)
+	var sum int64
+	done := make([]bool, 100)
+	defaultCache.parallel(len(done), func(i int) {
+		atomic.AddInt64(&sum, int64(i))
+		done[i] = true
+	})
+	assert.Equal(t, int64(4950), sum)
+	for _, d := range done {
+		assert.True(t, d)
+	}
+}
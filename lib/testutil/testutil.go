@@ -0,0 +1,174 @@
+// Package testutil tests the audio output of the mixer sample by sample, against golden WAV files
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-mix/mix/bind/sample"
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/bind/wav"
+	"github.com/go-mix/mix/lib/mix"
+	"github.com/go-mix/mix/lib/source"
+)
+
+// Update the golden files instead of comparing against them, e.g. `go test ./lib/mix/ -update` after a deliberate change to the
+// mixing algorithm; review the diff of the goldens before committing them.
+var Update = flag.Bool("update", false, "update the golden files instead of comparing against them")
+
+// Tolerance of a comparison to a golden: a sample differs if it is more than MaxDiff from the golden, and at most MaxDiffering
+// samples may differ, e.g. a MaxDiff of 1e-6 for the precision of a golden in F32.
+type Tolerance struct {
+	MaxDiff      float64 // absolute, of any sample
+	MaxDiffering int     // # of samples that may differ by more than MaxDiff
+}
+
+// EncodeWAV of interleaved values of all channels, as a WAV of a spec, in memory
+func EncodeWAV(s spec.AudioSpec, values []float64) ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if len(values)%s.Channels != 0 {
+		return nil, fmt.Errorf("Cannot encode %d values as a WAV (must be a multiple of %d channels)", len(values), s.Channels)
+	}
+	s.Format = wav.OutputFormat(s.Format)
+	var out bytes.Buffer
+	writer := wav.NewWriter(&out, wav.FormatFromSpec(&s), 0)
+	smp := make([]sample.Value, s.Channels)
+	var block []byte
+	for i := 0; i < len(values); i += s.Channels {
+		for c := range smp {
+			smp[c] = sample.Value(values[i+c])
+		}
+		block = sample.EncodeTo(block, s.Format, smp)
+	}
+	if _, err := writer.Write(block); err != nil {
+		return nil, err
+	}
+	data := out.Bytes() // streamed to a buffer, which cannot seek, so patch the sizes of its canonical header
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	binary.LittleEndian.PutUint32(data[40:], uint32(len(block)))
+	return data, nil
+}
+
+// DecodeWAV to interleaved values of all channels, and its spec
+func DecodeWAV(data []byte) ([]float64, *spec.AudioSpec, error) {
+	samples, s, err := wav.LoadBytes(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return interleave(samples, s.Channels), s, nil
+}
+
+// RegisterSource of interleaved values of all channels, in memory, under a name to set fires of, e.g. a ramp of mono F32 values at
+// 44.1kHz; no file is written to disk. See source.Register.
+func RegisterSource(name string, s spec.AudioSpec, values []float64) error {
+	data, err := EncodeWAV(s, values)
+	if err != nil {
+		return err
+	}
+	return source.Register(name, data)
+}
+
+// Render a schedule offline on a new mixer of a spec, for a length of time from zero, as interleaved values of all channels;
+// it is independent of the default mixer and of the wall clock, so the same schedule always renders the same output.
+func Render(s spec.AudioSpec, length time.Duration, schedule func(m *mix.Mixer) error) ([]float64, error) {
+	m, err := mix.New(s)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Teardown()
+	if err = schedule(m); err != nil {
+		return nil, err
+	}
+	return m.Render(length)
+}
+
+// Compare interleaved values to those of a golden, returning an error describing the first and largest differences if there
+// are more differing samples than the tolerance allows, or if their lengths differ
+func Compare(golden []float64, values []float64, tolerance Tolerance) error {
+	if len(values) != len(golden) {
+		return fmt.Errorf("Got %d values (golden has %d)", len(values), len(golden))
+	}
+	differing, first, largest, largestDiff := 0, -1, -1, 0.0
+	for i := range values {
+		diff := math.Abs(values[i] - golden[i])
+		if diff <= tolerance.MaxDiff {
+			continue
+		}
+		differing++
+		if first < 0 {
+			first = i
+		}
+		if largest < 0 || diff > largestDiff {
+			largest, largestDiff = i, diff
+		}
+	}
+	if differing <= tolerance.MaxDiffering {
+		return nil
+	}
+	return fmt.Errorf("Got %d values differing by more than %g (at most %d may), first at %d (%g, golden %g), largest at %d (%g, golden %g)",
+		differing, tolerance.MaxDiff, tolerance.MaxDiffering, first, values[first], golden[first], largest, values[largest], golden[largest])
+}
+
+// Golden compares interleaved values of a spec to those of a golden WAV file, failing the test if they differ beyond a tolerance,
+// or if its spec differs; with -update, see Update, it writes the golden file instead, in F32 for values exact to the float32.
+func Golden(t testing.TB, path string, s spec.AudioSpec, values []float64, tolerance Tolerance) {
+	t.Helper()
+	s.Format = spec.AudioF32
+	if *Update {
+		if err := writeGolden(path, s, values); err != nil {
+			t.Fatalf("Cannot update golden %s: %s", path, err)
+		}
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Cannot read golden %s (run with -update to create it): %s", path, err)
+	}
+	golden, goldenSpec, err := DecodeWAV(data)
+	if err != nil {
+		t.Fatalf("Cannot decode golden %s: %s", path, err)
+	}
+	if goldenSpec.Freq != s.Freq || goldenSpec.Channels != s.Channels {
+		t.Fatalf("Golden %s is %vHz %d channels (got %vHz %d channels)", path, goldenSpec.Freq, goldenSpec.Channels, s.Freq, s.Channels)
+	}
+	if err = Compare(golden, values, tolerance); err != nil {
+		t.Errorf("Golden %s: %s", path, err)
+	}
+}
+
+//
+// Private
+//
+
+// interleave the values of all channels of each sample
+func interleave(samples []sample.Sample, channels int) []float64 {
+	out := make([]float64, 0, len(samples)*channels)
+	for _, smp := range samples {
+		for c := 0; c < channels; c++ {
+			out = append(out, float64(smp.Values[c]))
+		}
+	}
+	return out
+}
+
+// writeGolden file, creating its directory if need be
+func writeGolden(path string, s spec.AudioSpec, values []float64) error {
+	data, err := EncodeWAV(s, values)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,78 @@
+// Package testutil tests the audio output of the mixer sample by sample, against golden WAV files
+package testutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-mix/mix/bind/spec"
+	"github.com/go-mix/mix/lib/mix"
+)
+
+func TestEncodeWAV(t *testing.T) {
+	s := spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 2}
+	values := []float64{0, 0.5, -0.25, 1, -1, 0.125}
+	data, err := EncodeWAV(s, values)
+	assert.Nil(t, err)
+	assert.Equal(t, 44+len(values)*4, len(data))
+	decoded, decodedSpec, err := DecodeWAV(data)
+	assert.Nil(t, err)
+	assert.Equal(t, s, *decodedSpec)
+	assert.Equal(t, values, decoded)
+}
+
+func TestEncodeWAV_FAIL(t *testing.T) {
+	_, err := EncodeWAV(spec.AudioSpec{Freq: 22050, Format: spec.AudioF32, Channels: 2}, []float64{0, 0.5, 1})
+	assert.EqualError(t, err, "Cannot encode 3 values as a WAV (must be a multiple of 2 channels)")
+}
+
+func TestRegisterSource_Render(t *testing.T) {
+	s := spec.AudioSpec{Freq: 1000, Format: spec.AudioF32, Channels: 1}
+	assert.Nil(t, RegisterSource("testutil-ramp", s, []float64{0.1, 0.2, 0.3, 0.4}))
+	render := func() []float64 {
+		out, err := Render(s, 10*time.Millisecond, func(m *mix.Mixer) error {
+			_, err := m.SetFireErr("testutil-ramp", 2*time.Millisecond, 0, 1.0, 0)
+			return err
+		})
+		assert.Nil(t, err)
+		return out
+	}
+	out := render()
+	assert.Equal(t, 10, len(out))
+	assert.Equal(t, float64(0), out[0])
+	assert.NotEqual(t, float64(0), out[3])
+	assert.Equal(t, out, render()) // deterministic
+}
+
+func TestCompare(t *testing.T) {
+	golden := []float64{0, 0.5, -0.5, 1}
+	assert.Nil(t, Compare(golden, []float64{0, 0.5, -0.5, 1}, Tolerance{}))
+	assert.Nil(t, Compare(golden, []float64{0.001, 0.5, -0.499, 1}, Tolerance{MaxDiff: 0.01}))
+	assert.Nil(t, Compare(golden, []float64{0.1, 0.5, -0.5, 1}, Tolerance{MaxDiff: 0.01, MaxDiffering: 1}))
+	assert.EqualError(t, Compare(golden, []float64{0.1, 0.5, -0.8, 1}, Tolerance{MaxDiff: 0.01, MaxDiffering: 1}),
+		"Got 2 values differing by more than 0.01 (at most 1 may), first at 0 (0.1, golden 0), largest at 2 (-0.8, golden -0.5)")
+	assert.EqualError(t, Compare(golden, []float64{0, 0.5}, Tolerance{}), "Got 2 values (golden has 4)")
+}
+
+func TestGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mix-testutil")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "golden", "test.wav")
+	s := spec.AudioSpec{Freq: 44100, Format: spec.AudioS16, Channels: 1}
+	values := []float64{0, 0.25, 0.5, 0.75}
+	*Update = true
+	Golden(t, path, s, values, Tolerance{})
+	*Update = false
+	Golden(t, path, s, values, Tolerance{})
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	_, goldenSpec, err := DecodeWAV(data)
+	assert.Nil(t, err)
+	assert.Equal(t, spec.AudioF32, goldenSpec.Format)
+}
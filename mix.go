@@ -7,6 +7,7 @@
 //     package main
 //
 //     import (
+//       "context"
 //       "fmt"
 //       "os"
 //       "time"
@@ -23,7 +24,6 @@
 //         Channels: 2,
 //         }
 //       bpm        = 120
-//       step       = time.Minute / time.Duration(bpm*4)
 //       loops      = 16
 //       prefix     = "sound/808/"
 //       kick1      = "kick1.wav"
@@ -60,18 +60,14 @@
 //       mix.SetSoundsPath(prefix)
 //       mix.StartAt(time.Now().Add(1 * time.Second))
 //
-//       t := 2 * time.Second // padding before music
-//       for n := 0; n < loops; n++ {
-//         for s := 0; s < len(pattern); s++ {
-//           mix.SetFire(pattern[s], t+time.Duration(s)*step, 0, 1.0, 0)
-//         }
-//         t += time.Duration(len(pattern)) * step
+//       mix.SetTempo(bpm, 4)
+//       mix.SetStepOffset(2 * time.Second) // padding before music
+//       for s := 0; s < loops*len(pattern); s++ {
+//         mix.SetFireAtStep(pattern[s%len(pattern)], s, 0, 1.0, 0)
 //       }
 //
 //       fmt.Printf("Mix, pid:%v, spec:%v\n", os.Getpid(), spec)
-//       for mix.FireCount() > 0 {
-//         time.Sleep(1 * time.Second)
-//       }
+//       mix.WaitDone(context.Background())
 //     }
 //
 // Play this Demo from the root of the project, with no actual audio playback
@@ -130,6 +126,10 @@
 //
 // Inspired by the theory paper "Mixing two digital audio streams with on the fly Loudness Normalization by Logarithmic Dynamic Range Compression" by Paul Vögler, 2012-04-20. This paper is published at http://www.voegler.eu/pub/audio/digital-audio-mixing-and-normalization.html.
 //
+// This logarithmic compression is the default. Alternatively, SetMixAlgorithm to plain summation with hard clipping, or with a lookahead limiter, and tune any of them with SetMixParams.
+//
+// Even so, a pathological stack of loud fires can overdrive the compression beyond full scale. SetHeadroom scales the master down before compression, and a final safety stage clips each output sample just before its conversion to the output format, see SetClipMode. Integer formats always saturate rather than wrap, and the clipped samples are counted in Stats. Converting to an integer format truncates each sample, unless SetDither adds noise to it first, e.g. mix.DitherTPDF to keep a fade-out or reverb tail smooth in a 16-bit render.
+//
 //
 // Usage
 //
@@ -152,31 +152,52 @@
 package mix
 
 import (
+	"context"
+	"errors"
 	"io"
+	"io/ioutil"
 	"time"
 
 	"github.com/go-mix/mix/bind"
 	"github.com/go-mix/mix/bind/debug"
 	"github.com/go-mix/mix/bind/spec"
 
+	"github.com/go-mix/mix/lib/effect"
 	"github.com/go-mix/mix/lib/fire"
+	"github.com/go-mix/mix/lib/level"
 	"github.com/go-mix/mix/lib/mix"
+	"github.com/go-mix/mix/lib/source"
 )
 
 // VERSION # of this mix source code
 // const VERSION = "0.0.3"
 
-// Debug ON/OFF (ripples down to all sub-modules)
+// Debug ON/OFF (ripples down to all sub-modules), as a convenience that logs to stderr, never stdout
 func Debug(isOn bool) {
 	debug.Configure(isOn)
 }
 
-// Configure the mixer frequency, format, channels & sample rate.
-func Configure(s spec.AudioSpec) {
-	s.Validate()
+// SetLogger to receive leveled messages from the mixer, e.g. source load events, mix cycle stats and fire lifecycle,
+// or nil to silence them (the default). It may be called from the mix loop, so it must be safe for concurrent use.
+func SetLogger(l debug.Logger) {
+	debug.SetLogger(l)
+}
+
+// Configure the mixer frequency, format, channels & sample rate, or return an error if the spec is invalid or the output cannot be opened.
+// The output may obtain a different spec than requested (e.g. SDL), in which case the mixer is configured to match it, with a warning, see ObtainedSpec.
+func Configure(s spec.AudioSpec) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	obtained, err := bind.Configure(s)
+	if err != nil {
+		return err
+	}
+	mix.ConfigureObtained(s, obtained)
+	mix.SetOutputLatency(bind.OutputLatency())
 	bind.SetOutputCallback(mix.NextSample)
-	bind.Configure(s)
-	mix.Configure(s)
+	bind.SetOutputBlockCallback(mix.NextBlock)
+	return bind.Start()
 }
 
 // Teardown everything and release all memory.
@@ -185,39 +206,586 @@ func Teardown() {
 	bind.Teardown()
 }
 
+// TeardownWith options, gracefully: stop accepting new fires, cut or drain the live audio, then flush and close the output
+// writer, e.g. patching the header of a streamed WAV, before releasing everything; calling it again does nothing.
+// Returns an error if the output could not be closed.
+func TeardownWith(opts mix.TeardownOptions) error {
+	err := mix.TeardownWith(opts)
+	bind.Teardown()
+	return err
+}
+
 // Spec for the mixer, which may include callback functions, e.g. portaudio
 func Spec() *spec.AudioSpec {
 	return mix.Spec()
 }
 
-// SetFire to represent a single audio source playing at a specific time in the future (in time.Duration from play start), with sustain time.Duration, volume from 0 to 1, and pan from -1 to +1
+// ObtainedSpec of the audio interface, which may differ from the spec requested of Configure, e.g. a device that only plays at 44.1kHz; the mix runs at it
+func ObtainedSpec() spec.AudioSpec {
+	return mix.ObtainedSpec()
+}
+
+// RequestedSpec of Configure, which the audio interface may not have obtained, see ObtainedSpec
+func RequestedSpec() spec.AudioSpec {
+	return mix.RequestedSpec()
+}
+
+// Mixer is an instance of the mixer, independent of the default one that the package functions use
+type Mixer = mix.Mixer
+
+// New mixer, with its own schedule, source cache, clock and offline output, e.g. to render alongside live playback; only the default mixer plays on the audio interface
+func New(s spec.AudioSpec) (*Mixer, error) {
+	return mix.New(s)
+}
+
+// SetFire to represent a single audio source playing at a specific time in the future (in time.Duration from play start), with sustain time.Duration, volume from 0 to 1, and pan from -1 to +1;
+// it begins on the exact sample floor(begin × frequency), see SetSubSamplePrecision. A sustain of 0 plays the full source, see fire.EffectiveSustain.
+// Returns nil, having scheduled nothing, if the source cannot be loaded, see SetFireErr for the error
 func SetFire(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
 	return mix.SetFire(source, begin, sustain, volume, pan)
 }
 
+// SetFireErr is SetFire, but returns an error (and schedules nothing) if the source cannot be resolved against the sounds path
+func SetFireErr(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) (*fire.Fire, error) {
+	return mix.SetFireErr(source, begin, sustain, volume, pan)
+}
+
+// SetFires of a batch, e.g. a whole song, much faster than SetFire of each; an entry whose source cannot be loaded is nil, with its error at the same index of errs
+func SetFires(batch []mix.FireSpec) (fires []*fire.Fire, errs []error) {
+	return mix.SetFires(batch)
+}
+
+// FireNow to play a source at the earliest sample the mixer can still include, e.g. triggered live from a MIDI controller
+func FireNow(source string, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.FireNow(source, sustain, volume, pan)
+}
+
+// SetFireRate is SetFire with a playback rate, e.g. 2.0 plays the source one octave up at double speed, and 0.5 one octave down
+func SetFireRate(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64, rate float64) *fire.Fire {
+	return mix.SetFireRate(source, begin, sustain, volume, pan, rate)
+}
+
+// SetFireRegion is SetFire, playing a region of the source from an offset, for a length or 0 to its natural end
+func SetFireRegion(source string, begin time.Duration, sustain time.Duration, offset time.Duration, length time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireRegion(source, begin, sustain, offset, length, volume, pan)
+}
+
+// SetFireLoop is SetFire re-triggered every interval, for a total # of repeats, or -1 to repeat until ClearAllFires or the fire is canceled
+func SetFireLoop(source string, begin time.Duration, interval time.Duration, repeat int, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireLoop(source, begin, interval, repeat, sustain, volume, pan)
+}
+
+// SetFireLoopXFade is SetFire looping until the fire is canceled, crossfading the tail of each iteration into the head of the next
+func SetFireLoopXFade(source string, begin time.Duration, xfade time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireLoopXFade(source, begin, xfade, volume, pan)
+}
+
+// SetFireSustainLoop is SetFire looping between the loop points embedded in the source, e.g. a sampler WAV, for the duration of the sustain
+func SetFireSustainLoop(source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireSustainLoop(source, begin, sustain, volume, pan)
+}
+
+// SetFireAfter is SetFire beginning a gap after a previous fire ends, once its end is known, e.g. the next line of dialogue; canceling the previous fire cancels this one.
+// Returns nil if the previous fire is nil, e.g. its source failed to load, or the source of this one cannot be loaded
+func SetFireAfter(prev *fire.Fire, gap time.Duration, source string, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireAfter(prev, gap, source, sustain, volume, pan)
+}
+
+// FireGroup of fires, e.g. a phrase, to shift, scale the volume of, or cancel as a unit, atomically, see NewFireGroup
+type FireGroup = mix.FireGroup
+
+// NewFireGroup to set fires by its SetFire, or Add them, and then ShiftBy, ScaleVolume or Cancel those that have not yet gone live
+func NewFireGroup() *FireGroup {
+	return mix.NewFireGroup()
+}
+
+// SetResampleQuality of sources converted to the mix frequency, e.g. source.ResampleHigh; the default is source.ResampleMedium
+func SetResampleQuality(q source.ResampleQuality) {
+	mix.SetResampleQuality(q)
+}
+
+// SetDownmixMatrix of sources with more channels than the output, e.g. 8 to 2; quad and 5.1 to stereo have defaults
+func SetDownmixMatrix(srcChannels int, matrix [][]float64) error {
+	return mix.SetDownmixMatrix(srcChannels, matrix)
+}
+
+// SetFireTone to play a synthesized sine wave at a frequency in Hz, e.g. a metronome click, without loading a file
+func SetFireTone(freq float64, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireTone(freq, begin, sustain, volume, pan)
+}
+
+// SetFireWaveform is SetFireTone, with a choice of waveform, e.g. source.WaveSquare or source.WaveNoise
+func SetFireWaveform(wave source.Waveform, freq float64, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireWaveform(wave, freq, begin, sustain, volume, pan)
+}
+
+// NewBus to group fires with their own volume, pan, mute and solo; if a bus already has the name, that bus is returned
+func NewBus(name string) *mix.Bus {
+	return mix.NewBus(name)
+}
+
+// SetDucking of a target bus by a trigger bus, e.g. to pump the "pads" bus by 6dB whenever the "kick" bus sounds, with attack and release times;
+// a depth of 0 removes it, and when several triggers duck the same bus, the deepest reduction wins
+func SetDucking(triggerBus string, targetBus string, depthDB float64, attack time.Duration, release time.Duration) {
+	mix.SetDucking(triggerBus, targetBus, depthDB, attack, release)
+}
+
+// SetFireOnBus is SetFire, played on a bus; a nil bus is the default master bus
+func SetFireOnBus(bus *mix.Bus, source string, begin time.Duration, sustain time.Duration, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireOnBus(bus, source, begin, sustain, volume, pan)
+}
+
+// AddMasterEffect to the chain of effects on the master output, e.g. an effect.NewLowPass filter
+func AddMasterEffect(e effect.Effect) {
+	mix.AddMasterEffect(e)
+}
+
+// RemoveMasterEffect from the chain of effects on the master output
+func RemoveMasterEffect(e effect.Effect) {
+	mix.RemoveMasterEffect(e)
+}
+
+// EnvelopePoint at an offset time.Duration from the beginning of a fire, e.g. for fire.SetVolumeEnvelope(...) or fire.SetPanEnvelope(...)
+func EnvelopePoint(offset time.Duration, value float64) fire.EnvelopePoint {
+	return mix.EnvelopePoint(offset, value)
+}
+
 // FireCount to check the number of fires currently scheduled for playback
 func FireCount() int {
 	return mix.FireCount()
 }
 
+// WaitDone blocks until there are no more fires, or the context is done and returns its error, e.g. instead of polling FireCount
+func WaitDone(ctx context.Context) error {
+	return mix.WaitDone(ctx)
+}
+
+// FireCountReady to check the number of fires scheduled to begin in the future
+func FireCountReady() int {
+	return mix.FireCountReady()
+}
+
+// FireCountLive to check the number of fires currently sounding
+func FireCountLive() int {
+	return mix.FireCountLive()
+}
+
+// NextFireAt the begin time of the earliest ready fire, since the start of mix playback, or false if there is none
+func NextFireAt() (time.Duration, bool) {
+	return mix.NextFireAt()
+}
+
+// ScheduleEnd the time since the start of mix playback that the last fire ends, including the natural length of a source played without a sustain,
+// e.g. to render exactly to the end
+func ScheduleEnd() time.Duration {
+	return mix.ScheduleEnd()
+}
+
+// Fires returns a snapshot of the ready and live fires, in order of their beginning, e.g. for a timeline UI
+func Fires() []*fire.Fire {
+	return mix.Fires()
+}
+
 // ClearAllFires to clear all fires currently ready, or live
 func ClearAllFires() {
 	mix.ClearAllFires()
 }
 
+// ClearFiresAfter to remove all fires that begin at or after a time.Duration-since-start, without disturbing any already begun; returns the # removed
+func ClearFiresAfter(t time.Duration) int {
+	return mix.ClearFiresAfter(t)
+}
+
+// ClearFiresBySource to remove all fires of a source that have not yet begun; returns the # removed
+func ClearFiresBySource(source string) int {
+	return mix.ClearFiresBySource(source)
+}
+
 // SetSoundsPath prefix
 func SetSoundsPath(prefix string) {
 	mix.SetSoundsPath(prefix)
 }
 
+// Prepare sources ahead of time, so that their first fire doesn't need to wait for loading, and keep them in memory until evicted; loads them concurrently, and blocks until done or returns the first error
+func Prepare(sources ...string) error {
+	return mix.Prepare(sources...)
+}
+
+// PrepareCtx is Prepare until the context is done, then returns its error; no other source begins to load
+func PrepareCtx(ctx context.Context, sources ...string) error {
+	return mix.PrepareCtx(ctx, sources...)
+}
+
+// SetWorkers to load sources, and mix many live fires, concurrently across at most n goroutines, or 0 for one per CPU (the default).
+// The output is the same for any # of workers, so an offline render is reproducible.
+func SetWorkers(n int) {
+	mix.SetWorkers(n)
+}
+
+// EvictSource from memory; it will be loaded again if it is fired
+func EvictSource(name string) {
+	mix.EvictSource(name)
+}
+
+// SourceCacheSize returns the bytes of audio resident in memory
+func SourceCacheSize() int64 {
+	return mix.SourceCacheSize()
+}
+
+// SetSourceCacheLimit evicts the least recently fired sources past a # of bytes in memory, except those with fires scheduled, or never if 0
+func SetSourceCacheLimit(size int64) {
+	mix.SetSourceCacheLimit(size)
+}
+
+// SourceCacheStats returns the hits, misses and evictions of sources in memory, for monitoring
+func SourceCacheStats() source.CacheStats {
+	return mix.SourceCacheStats()
+}
+
+// SetSourceStreaming decodes a long source from its file a little ahead of where it plays, instead of loading it whole, e.g. a backing track
+func SetSourceStreaming(name string, on bool) {
+	mix.SetSourceStreaming(name, on)
+}
+
+// SetSourceGain of a source, a static trim in dB, e.g. to match the levels of samples from different libraries; it takes effect at once
+func SetSourceGain(name string, db float64) {
+	mix.SetSourceGain(name, db)
+}
+
+// SetAutoNormalize the peak of each source as it is loaded to a target level in dBFS, once, in memory; see Sources for the gain applied
+func SetAutoNormalize(target float64) {
+	mix.SetAutoNormalize(target)
+}
+
+// SetAutoTrim the leading silence of each source as it is loaded, up to its first sample above a threshold in dBFS, e.g. -60
+func SetAutoTrim(thresholdDB float64) {
+	mix.SetAutoTrim(thresholdDB)
+}
+
+// SetSourceTrim offset of a source, the leading audio skipped as it plays, instead of that detected by SetAutoTrim
+func SetSourceTrim(name string, offset time.Duration) {
+	mix.SetSourceTrim(name, offset)
+}
+
+// SetStreamingThreshold streams every source file larger than a size in bytes, or never if 0
+func SetStreamingThreshold(size int64) {
+	mix.SetStreamingThreshold(size)
+}
+
+// StreamUnderruns returns the count of streamed samples that played as silence because they were not decoded in time
+func StreamUnderruns() int64 {
+	return mix.StreamUnderruns()
+}
+
+// ReloadSource decodes a source from its file again, e.g. after it was edited, for the next fires of it; a fire already playing it finishes on the audio it began with
+func ReloadSource(name string) error {
+	return mix.ReloadSource(name)
+}
+
+// WatchSources reloads each source in memory whose file changes, if on, see ReloadSource
+func WatchSources(on bool) {
+	mix.WatchSources(on)
+}
+
+// Sources returns the name, duration, channels, original frequency and memory footprint of each source in memory, without loading any
+func Sources() []mix.SourceInfo {
+	return mix.Sources()
+}
+
+// GetSourceDuration returns the natural length of a source in memory, e.g. to set a matching sustain, or an error if it has not been prepared
+func GetSourceDuration(name string) (time.Duration, error) {
+	return mix.GetSourceDuration(name)
+}
+
+// RegisterSource decodes audio from a reader, e.g. an embedded asset, and caches it under a name that SetFire resolves before the sounds path
+func RegisterSource(name string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return mix.RegisterSource(name, data)
+}
+
+// RegisterSourceBytes is RegisterSource from a []byte
+func RegisterSourceBytes(name string, data []byte) error {
+	return mix.RegisterSource(name, data)
+}
+
+// RegisterSourceProvider of procedurally generated audio under a name that SetFire resolves before the sounds path, see mix.SourceProvider
+func RegisterSourceProvider(name string, p mix.SourceProvider) error {
+	return mix.RegisterSourceProvider(name, p)
+}
+
+// SetSubSamplePrecision of the begin of fires: if on, the fraction of a sample after floor(begin × frequency) is honored too, by interpolation,
+// e.g. so that copies of a sample layered with tiny offsets do not flam; off by default
+func SetSubSamplePrecision(on bool) {
+	mix.SetSubSamplePrecision(on)
+}
+
+// RegisterMultiSource of velocity layers under one name, e.g. "kick" for soft, medium and hard samples, with round-robin variants of each;
+// SetFire resolves it to a variant by the volume of the fire, as it goes live, reproducibly given the seed of SetRandomSeed
+func RegisterMultiSource(name string, layers []mix.Layer) error {
+	return mix.RegisterMultiSource(name, layers)
+}
+
+// SetRandomSeed of the picks of multi-sample sources, of the humanize, and of the noise of the dither, which begin again from it, so that an offline render is reproducible
+func SetRandomSeed(seed int64) {
+	mix.SetRandomSeed(seed)
+}
+
+// SetScheduleLowWater calls fn, on its own goroutine, whenever the latest scheduled fire begins less than d ahead of the mix time,
+// with the schedule horizon after which to append more fires, e.g. to generate music bar-by-bar just in time; nil fn to stop
+func SetScheduleLowWater(d time.Duration, fn func(horizon time.Duration)) {
+	mix.SetScheduleLowWater(d, fn)
+}
+
+// ExportSchedule writes the fires that have not yet begun as versioned JSON, with all of their settings, e.g. to save a project
+func ExportSchedule(w io.Writer) error {
+	return mix.ExportSchedule(w)
+}
+
+// ImportSchedule reads JSON written by ExportSchedule and sets each of its fires later by an offset, or none if there is an error
+func ImportSchedule(r io.Reader, offset time.Duration) error {
+	return mix.ImportSchedule(r, offset)
+}
+
+// ValidateSchedule checks that the source of each fire not yet begun can still be loaded, e.g. before a performance; returns an error for each fire whose cannot
+func ValidateSchedule() []error {
+	return mix.ValidateSchedule()
+}
+
+// SaveSession writes everything needed to resume the mix as versioned JSON, e.g. for crash recovery or a project file: its spec, sounds path,
+// the sources by name but not their audio, the tempo, the master and bus levels, and the fires not yet begun, relative to the mix time now
+func SaveSession(w io.Writer) error {
+	return mix.SaveSession(w)
+}
+
+// LoadSession reads JSON written by SaveSession, configuring the output by its spec if it is not yet configured, and replaces the schedule by
+// its fires, such that Start resumes from where it was saved; returns mix.SessionSourceErrors of each source that cannot be loaded, whose fires are skipped
+func LoadSession(r io.Reader) error {
+	s, err := mix.ReadSession(r)
+	if err != nil {
+		return err
+	}
+	if Spec() == nil {
+		if err = Configure(s.Spec()); err != nil {
+			return err
+		}
+	}
+	return mix.RestoreSession(s)
+}
+
+// TeeOutput records the output to a streamed WAV of a format while it plays, e.g. a live performance to disk; it never blocks the audio,
+// and drops blocks if the writer cannot keep up, counted in Stats().TeeDropped. Close the tee, or Teardown, to finalize the WAV
+func TeeOutput(w io.Writer, format spec.AudioFormat) (*mix.Tee, error) {
+	return mix.TeeOutput(w, format)
+}
+
+// LoadMIDI sets a fire for each note of a Standard MIDI File that is mapped from its note number to a source, timed by the tempo map
+// of the file, with velocity as volume, sustained until the note-off or else the default sustain; returns the # of unmapped notes skipped
+func LoadMIDI(path string, mapping map[int]string, defaultSustain time.Duration) (skipped int, err error) {
+	return mix.LoadMIDI(path, mapping, defaultSustain)
+}
+
+// SetTempo in beats per minute from step zero, and steps per beat, for SetFireAtStep; only the fires set after a change of tempo are affected
+func SetTempo(bpm float64, stepsPerBeat int) {
+	mix.SetTempo(bpm, stepsPerBeat)
+}
+
+// AddTempoChange to the tempo map, in beats per minute from a step onward, e.g. for a tempo jump or a ritardando
+func AddTempoChange(atStep int, bpm float64) {
+	mix.AddTempoChange(atStep, bpm)
+}
+
+// ClearTempoChanges from the tempo map, leaving only the tempo from step zero
+func ClearTempoChanges() {
+	mix.ClearTempoChanges()
+}
+
+// SetSwing from 0 (straight) to 1, which delays every other step (1, 3, 5...) by half a step, for the fires set at a step after it
+func SetSwing(amount float64) {
+	mix.SetSwing(amount)
+}
+
+// SetGroove of micro-timing offsets, early or late, applied to each step in turn, repeating, for the fires set at a step after it
+func SetGroove(offsets []time.Duration) {
+	mix.SetGroove(offsets)
+}
+
+// SetStepOffset of step zero since the start of mix playback, e.g. to leave some silence before the music
+func SetStepOffset(offset time.Duration) {
+	mix.SetStepOffset(offset)
+}
+
+// StepDuration at the current tempo
+func StepDuration() time.Duration {
+	return mix.StepDuration()
+}
+
+// StepAt a time since the start of mix playback, the step it falls within, at the current tempo and step offset
+func StepAt(t time.Duration) int {
+	return mix.StepAt(t)
+}
+
+// SetFireAtStep to play a source at the beginning of a step, at the current tempo, sustained for a # of steps or 0 for its natural length
+func SetFireAtStep(source string, step int, sustainSteps float64, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireAtStep(source, step, sustainSteps, volume, pan)
+}
+
+// SetFireAtBeat to play a source at a beat, which may be fractional, by the tempo map, sustained for a # of beats or 0 for its natural length
+func SetFireAtBeat(source string, beat float64, sustainBeats float64, volume float64, pan float64) *fire.Fire {
+	return mix.SetFireAtBeat(source, beat, sustainBeats, volume, pan)
+}
+
+// SetSustainFollowsTempo, if on, to resolve the sustain in steps or beats of each fire set after it as the fire goes live, such that later tempo changes affect its length
+func SetSustainFollowsTempo(on bool) {
+	mix.SetSustainFollowsTempo(on)
+}
+
+// StartCapture of every fire set from now on, e.g. pads tapped live, at the mixer time each was intended to begin
+func StartCapture() {
+	mix.StartCapture()
+}
+
+// StopCapture and return the fires set since StartCapture, each with its time since the start of the capture, and its nearest step
+func StopCapture() []mix.CapturedFire {
+	return mix.StopCapture()
+}
+
+// ReplayCapture of fires at an offset, repeated every length of the capture for a total # of repeats; a replay is not itself captured
+func ReplayCapture(events []mix.CapturedFire, atOffset time.Duration, repeat int) {
+	mix.ReplayCapture(events, atOffset, repeat)
+}
+
+// FireEvents returns a channel of events as fires start and finish playing, timed by the mixer clock; the oldest events are dropped if it is not read
+func FireEvents() <-chan mix.FireEvent {
+	return mix.FireEvents()
+}
+
+// SetMixAlgorithm to combine all fires into the output, e.g. mix.MixLimiter; the default is mix.MixLogarithmic compression
+func SetMixAlgorithm(alg mix.MixAlgorithm) {
+	mix.SetMixAlgorithm(alg)
+}
+
+// SetMixParams to tune the mix algorithms, before or during playback
+func SetMixParams(p mix.MixParams) {
+	mix.SetMixParams(p)
+}
+
+// SetHeadroom in dB, scaling the master down before compression, e.g. 6 for half the level, leaving room for stacks of loud fires
+func SetHeadroom(db float64) {
+	mix.SetHeadroom(db)
+}
+
+// SetClipMode of the safety stage just before the conversion to the output format, e.g. mix.ClipSoft; the default is mix.ClipHard.
+// The output samples beyond full scale are counted in Stats, and integer formats always saturate rather than wrap
+func SetClipMode(mode mix.ClipMode) {
+	mix.SetClipMode(mode)
+}
+
+// SetDither of the conversion to an integer output format, e.g. mix.DitherTPDF for a 16-bit render; the default is mix.DitherNone.
+// The dither is reproducible given the random seed, see SetRandomSeed
+func SetDither(mode mix.DitherMode) {
+	mix.SetDither(mode)
+}
+
+// SetPanLaw of the pan of every fire and bus, e.g. mix.PanConstantPower to keep the level steady while the pan is automated,
+// or mix.PanBalance to pan a fire hard to one side; the default is mix.PanLinear, the original curve
+func SetPanLaw(law mix.PanLaw) {
+	mix.SetPanLaw(law)
+}
+
+// Calibrate by playing a calibration signal now for a duration, synthesized without any file, e.g. mix.ToneLeft1k to check the channel mapping
+// of an output binding; see package analyze to measure the output
+func Calibrate(signal mix.Calibration, d time.Duration) *fire.Fire {
+	return mix.Calibrate(signal, d)
+}
+
+// SetChokeGroup of sources, e.g. the open and closed hi-hats: when a fire of one of them starts playing, the other fires of the group
+// fade out over the choke fade, from that exact sample; with no sources, the group is removed. See fire.SetChoke for a single fire
+func SetChokeGroup(group string, sources ...string) {
+	mix.SetChokeGroup(group, sources...)
+}
+
+// SetChokeFade of a fire that is choked by another fire of its choke group; the default is mix.DefaultChokeFade
+func SetChokeFade(d time.Duration) {
+	mix.SetChokeFade(d)
+}
+
+// SetTimeScale of the schedule, e.g. 5 to audition a five-minute composition in one minute, its sources five times faster and higher;
+// the default is 1. It must be set while stopped, before Start or after Stop, and every time of the schedule, e.g. GetNowAt, is as set
+func SetTimeScale(scale float64) {
+	mix.SetTimeScale(scale)
+}
+
+// HumanizeSpec of the random variation of the volume, pan and timing of each fire, see SetHumanize
+type HumanizeSpec = mix.HumanizeSpec
+
+// SetHumanize varies the volume, pan and timing of each fire at random, by up to each jitter, e.g. HumanizeSpec{VolumeJitter: 0.1,
+// TimingJitter: 10 * time.Millisecond}, reproducibly given the seed of SetRandomSeed; a fire's own, see fire.SetHumanize, takes precedence
+func SetHumanize(h HumanizeSpec) {
+	mix.SetHumanize(h)
+}
+
+// GetOutputLevel returns the peak and RMS level of each output channel over the last mix cycle, from 0 to 1
+func GetOutputLevel() level.Level {
+	return mix.GetOutputLevel()
+}
+
+// GetClipCount returns the # of output samples that reached 1.0 or above, overdriving the compressor
+func GetClipCount() uint64 {
+	return mix.GetClipCount()
+}
+
+// Stats returns a snapshot of the mix loop stats, e.g. the computation of each mix cycle vs. its budget, and the overruns, to detect dropouts
+func Stats() mix.LoopStats {
+	return mix.Stats()
+}
+
+// ResetStats of the mix loop to zero
+func ResetStats() {
+	mix.ResetStats()
+}
+
+// SetOverrunWarning calls fn on its own goroutine whenever a mix cycle takes longer to compute than it lasts, or never if nil
+func SetOverrunWarning(fn func(stats mix.LoopStats)) {
+	mix.SetOverrunWarning(fn)
+}
+
+// OnError calls fn on its own goroutine if the mix loop panics, e.g. on a corrupt source, after which it is silent until Teardown
+func OnError(fn func(err error, stack []byte)) {
+	mix.OnError(fn)
+}
+
+// DBFS converts a linear level from 0 to 1 into decibels relative to full scale
+func DBFS(v float64) float64 {
+	return level.DBFS(v)
+}
+
 // Set the duration between "mix cycles", wherein garbage collection is performed.
 func SetMixCycleDuration(d time.Duration) {
 	mix.SetCycleDuration(d)
 }
 
+// SetPreRoll of a count-in, of a # of bars of four beats at the tempo, with a click source on each beat, played by each Start before time zero
+// of the schedule, so no fire need be offset by it; GetNowAt is negative until it ends. 0 bars for none (the default)
+func SetPreRoll(bars int, clickSource string) {
+	mix.SetPreRoll(bars, clickSource)
+}
+
+// SetPreRollDuration of a count-in, of a duration instead of a # of bars, with a click source on each beat before its end; 0 for none
+func SetPreRollDuration(d time.Duration, clickSource string) {
+	mix.SetPreRollDuration(d, clickSource)
+}
+
+// SetPreRollOutput to include the pre-roll in what OutputStart and OutputContinueTo write, e.g. a WAV with the count-in; by default it is skipped
+func SetPreRollOutput(include bool) {
+	mix.SetPreRollOutput(include)
+}
+
 // Start the mixer now
 func Start() {
-	mix.StartAt(time.Now())
+	mix.StartAt(mix.Now())
 }
 
 // StartAt a specific time in the future
@@ -225,6 +793,71 @@ func StartAt(t time.Time) {
 	mix.StartAt(t)
 }
 
+// SetClock of the mixer, e.g. a FakeClock for deterministic tests, or nil for the real clock; the mix never runs ahead of any other clock
+func SetClock(c mix.Clock) {
+	mix.SetClock(c)
+}
+
+// NewFakeClock at a time, which only moves when it is advanced, e.g. by AdvanceBy
+func NewFakeClock(now time.Time) *mix.FakeClock {
+	return mix.NewFakeClock(now)
+}
+
+// AdvanceBy a duration the fake clock, synchronously mixing that window to the configured output, e.g. for instant tests of scheduling
+func AdvanceBy(d time.Duration) error {
+	return mix.AdvanceBy(d)
+}
+
+// Pause the mixer clock; no further fires go live until Resume
+func Pause() {
+	mix.Pause()
+}
+
+// Resume playback from the paused position
+func Resume() {
+	mix.Resume()
+}
+
+// Stop playback and reset the playhead to zero, keeping loaded sources in cache; fires that are done play again only if kept, see SetReplayLimit
+func Stop() {
+	mix.Stop()
+}
+
+// SeekTo a specific time.Duration-since-epoch; fires spanning that position begin playing mid-sample
+func SeekTo(t time.Duration) {
+	mix.SeekTo(t)
+}
+
+// SetReplayLimit of the fires that are done, kept so that Stop, or SeekTo back, plays them again; 0 (the default) releases each as it ends, and -1 keeps all
+func SetReplayLimit(fires int) {
+	mix.SetReplayLimit(fires)
+}
+
+// IsPlaying returns true unless the mixer is paused or stopped
+func IsPlaying() bool {
+	return mix.IsPlaying()
+}
+
+// SetMasterVolume from 0 to 1, ramped over a few milliseconds to avoid clicks
+func SetMasterVolume(v float64) {
+	mix.SetMasterVolume(v)
+}
+
+// GetMasterVolume from 0 to 1
+func GetMasterVolume() float64 {
+	return mix.GetMasterVolume()
+}
+
+// Mute the output, without stopping the clock
+func Mute() {
+	mix.Mute()
+}
+
+// Unmute the output
+func Unmute() {
+	mix.Unmute()
+}
+
 // GetStartTime the mixer was started at
 func GetStartTime() time.Time {
 	return mix.GetStartTime()
@@ -235,17 +868,122 @@ func GetNowAt() time.Duration {
 	return mix.GetNowAt()
 }
 
-// OutputStart requires a known length
+// SetTickCallback to call fn on each tick at a # of ticks per beat, e.g. mix.MIDIClockPPQN, derived from the audio clock
+func SetTickCallback(ppqn int, fn func(tick int64, at time.Duration)) {
+	mix.SetTickCallback(ppqn, fn)
+}
+
+// GetNowFrames returns the current mix position in frames heard, exactly, readable from any goroutine without waiting for the mix
+func GetNowFrames() spec.Tz {
+	return mix.GetNowFrames()
+}
+
+// DurationToFrames of a time of the schedule since the start, to the frame that contains it, i.e. floor(d × frequency ÷ time scale)
+func DurationToFrames(d time.Duration) spec.Tz {
+	return mix.DurationToFrames(d)
+}
+
+// FramesToDuration since the start, of the beginning of a frame, rounded up to the nanosecond, the inverse of DurationToFrames
+func FramesToDuration(f spec.Tz) time.Duration {
+	return mix.FramesToDuration(f)
+}
+
+// AtFrame returns the predicted time by the mixer clock at which a frame is heard, e.g. to align video or lighting to the audio
+func AtFrame(f spec.Tz) time.Time {
+	return mix.AtFrame(f)
+}
+
+// SetOutputLatency between mixing a sample and hearing it; Configure sets it as reported by the bound hardware
+func SetOutputLatency(d time.Duration) {
+	mix.SetOutputLatency(d)
+}
+
+// GetOutputLatency between mixing a sample and hearing it, e.g. the buffer of the bound hardware, plus any lookahead
+func GetOutputLatency() time.Duration {
+	return mix.GetOutputLatency()
+}
+
+// SetLookahead of the output in mix cycles, e.g. 2, to mix ahead of the audio interface so that a mix cycle that runs long does not
+// glitch; it adds to GetOutputLatency, so keep the cycles short with SetCycleDuration. Set it after Configure; 0 (the default) for none.
+func SetLookahead(cycles int) {
+	mix.SetLookahead(cycles)
+}
+
+// NewReader of the mix output encoded in a specific format, e.g. for oto or beep; the mix clock advances by exactly the frames read.
+// This pull-based mode requires bind.UseOutput(opt.OutputReader) before Configure, because a streaming output would also advance the clock.
+func NewReader(format spec.AudioFormat) (*mix.Reader, error) {
+	if bind.IsStreamingOutput() {
+		return nil, errors.New("Cannot read from the mixer while it is streaming to an output")
+	}
+	return mix.NewReader(format), nil
+}
+
+// Render the mix offline for a length of time from the current playhead, as interleaved values of all channels, e.g. for golden-file tests
+func Render(length time.Duration) ([]float64, error) {
+	return mix.Render(length)
+}
+
+// RenderTo a writer, as Render, encoded in the format of the configured spec, e.g. to bounce to disk
+func RenderTo(w io.Writer, length time.Duration) error {
+	return mix.RenderTo(w, length)
+}
+
+// RenderRange of the schedule as a WAV of only the window [from, to), entering any fire still sounding from before it at the right offset
+func RenderRange(from time.Duration, to time.Duration, w io.Writer) error {
+	return mix.RenderRange(from, to, w)
+}
+
+// RenderCtx is RenderTo until the context is done, e.g. a client disconnects; it stops promptly, having flushed every complete mix cycle, and returns the error of the context
+func RenderCtx(ctx context.Context, length time.Duration, w io.Writer) error {
+	return mix.RenderCtx(ctx, length, w)
+}
+
+// MeasureLoudness of a render for a length of time from the current playhead, by ITU-R BS.1770 and EBU R128: the integrated loudness in LUFS,
+// the loudness range in LU, and the true peak in dBTP; afterward, the playhead is moved back to where it was
+func MeasureLoudness(length time.Duration) (mix.LoudnessReport, error) {
+	return mix.MeasureLoudness(length)
+}
+
+// RenderNormalized to a writer, as RenderTo, in two passes, such that its integrated loudness is a target in LUFS, e.g. -14 for streaming platforms;
+// the report is of the normalized render
+func RenderNormalized(length time.Duration, targetLUFS float64, w io.Writer) (mix.LoudnessReport, error) {
+	return mix.RenderNormalized(length, targetLUFS, w)
+}
+
+// OutputStart with a known length, or 0 to stream an unknown length, e.g. over a socket
 func OutputStart(length time.Duration, out io.Writer) {
 	mix.OutputStart(length, out)
 }
 
+// Metadata of a WAV, of its LIST/INFO and Broadcast Wave (bext) chunks, e.g. its title, artist, or description and originator
+type Metadata = mix.Metadata
+
+// SetOutputMetadata written in the header of each WAV output after, e.g. Metadata{Title: "Demo", Software: "mix"}, or empty for none
+func SetOutputMetadata(meta Metadata) {
+	mix.SetOutputMetadata(meta)
+}
+
+// OutputStartAuto with the exact length of the schedule plus a tail, loading the sources of all the fires first; returns the end to OutputContinueTo
+func OutputStartAuto(out io.Writer, extraTail time.Duration) time.Duration {
+	return mix.OutputStartAuto(out, extraTail)
+}
+
 // OutputContinueTo output as []byte via stdout, up to a specified duration-since-start
 func OutputContinueTo(t time.Duration) {
 	mix.OutputContinueTo(t)
 }
 
-// OutputBegin to output WAV closer as []byte via stdout
-func OutputClose() {
-	mix.OutputClose()
+// OutputClose to finish the output; a streamed WAV header is patched with the final sizes if the writer is an io.WriteSeeker
+func OutputClose() error {
+	return mix.OutputClose()
+}
+
+// SetOutputPipe, if on, to drop the blocks of each output started after that a stalled consumer does not take in time, e.g. aplay, instead of waiting for it
+func SetOutputPipe(on bool) {
+	mix.SetOutputPipe(on)
+}
+
+// OnOutputError calls fn on its own goroutine with the first error of the writer of the output, e.g. a broken pipe, after which the output is discarded
+func OnOutputError(fn func(err error)) {
+	mix.OnOutputError(fn)
 }
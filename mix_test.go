@@ -2,13 +2,18 @@
 package mix
 
 import (
+	"io"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/go-mix/mix/bind"
+	"github.com/go-mix/mix/bind/opt"
 	"github.com/go-mix/mix/bind/spec"
 	"github.com/go-mix/mix/lib/mix"
+	"github.com/go-mix/mix/lib/source"
 )
 
 func TestDebug(t *testing.T) {
@@ -20,16 +25,32 @@ func TestConfigure(t *testing.T) {
 }
 
 func TestConfigure_FailureFreqNotGreaterThanZero(t *testing.T) {
-	defer func() {
-		msg := recover()
-		assert.IsType(t, "", msg)
-		assert.Equal(t, "Must specify a mixing frequency greater than zero.", msg)
-	}()
-	Configure(spec.AudioSpec{
+	err := Configure(spec.AudioSpec{
 		Freq:     -100,
 		Format:   spec.AudioS16,
 		Channels: 2,
 	})
+	assert.EqualError(t, err, "Unsupported frequency: -100 (must be greater than zero)")
+}
+
+func TestConfigure_Obtained(t *testing.T) {
+	bind.RegisterOutput("test-22050", testOutput22050{})
+	bind.UseOutputString("test-22050")
+	defer bind.UseOutput(opt.OutputNull)
+	defer Teardown()
+	assert.Nil(t, Configure(spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1}))
+	assert.Equal(t, spec.AudioSpec{Freq: 44100, Format: spec.AudioF32, Channels: 1}, RequestedSpec())
+	assert.Equal(t, spec.AudioSpec{Freq: 22050, Format: spec.AudioS16, Channels: 1}, ObtainedSpec())
+	assert.Equal(t, float64(22050), Spec().Freq)
+	ClearAllFires()
+	SetFireWaveform(source.WaveSquare, 441, 0, time.Second, 1.0, 0)
+	last := 0
+	for n := 1; n <= 44100; n++ {
+		if mix.NextSample()[0] != 0 {
+			last = n
+		}
+	}
+	assert.InDelta(t, 22050, last, 1) // one second of output, at the frequency obtained, to its last sample that is not a zero crossing
 }
 
 func TestTeardown(t *testing.T) {
@@ -53,8 +74,50 @@ func TestSetFire(t *testing.T) {
 	assert.NotNil(t, fire)
 }
 
+func TestSetFireErr(t *testing.T) {
+	testAPISetup()
+	fire, err := SetFireErr("lib/source/testdata/ThisShouldFailBecauseItDoesNotExist.wav", time.Duration(0), 0, 1.0, 0)
+	assert.Nil(t, fire)
+	assert.EqualError(t, err, "File not found: lib/source/testdata/ThisShouldFailBecauseItDoesNotExist.wav")
+	assert.Equal(t, 0, FireCount())
+}
+
+func TestRegisterSource(t *testing.T) {
+	testAPISetup()
+	file, err := os.Open("lib/source/testdata/Signed16bitLittleEndian44100HzMono.wav")
+	assert.Nil(t, err)
+	defer file.Close()
+	assert.Nil(t, RegisterSource("embedded/kick.wav", file))
+	SetSoundsPath("this/path/does/not/exist/")
+	defer SetSoundsPath("")
+	fire, err := SetFireErr("embedded/kick.wav", time.Duration(0), 0, 1.0, 0)
+	assert.Nil(t, err)
+	assert.NotNil(t, fire)
+	assert.Equal(t, "embedded/kick.wav", fire.Source)
+	ClearAllFires()
+}
+
+func TestRegisterSourceBytes_Fail(t *testing.T) {
+	testAPISetup()
+	assert.NotNil(t, RegisterSourceBytes("garbage.wav", []byte("this is not a WAV file")))
+}
+
+func TestPrepare(t *testing.T) {
+	testAPISetup()
+	path := "lib/source/testdata/Signed16bitLittleEndian44100HzMono.wav"
+	assert.Nil(t, Prepare(path))
+	size := SourceCacheSize()
+	assert.True(t, size > 0)
+	EvictSource(path)
+	assert.True(t, SourceCacheSize() < size)
+	assert.EqualError(t, Prepare(path, "lib/source/testdata/ThisShouldFailBecauseItDoesNotExist.wav"), "File not found: lib/source/testdata/ThisShouldFailBecauseItDoesNotExist.wav")
+	EvictSource(path)
+}
+
 func TestFireCount(t *testing.T) {
 	testAPISetup()
+	Pause() // else the null output may play and expire the fires before they are counted
+	defer Resume()
 	assert.Equal(t, 0, FireCount())
 	SetFire("lib/source/testdata/Float32bitLittleEndian48000HzEstéreo.wav", time.Duration(0), 0, 1.0, 0)
 	assert.Equal(t, 1, FireCount())
@@ -99,6 +162,13 @@ func TestGetNowAt(t *testing.T) {
 	// TODO
 }
 
+func TestNewReader_WhileStreaming(t *testing.T) {
+	testAPISetup()
+	reader, err := NewReader(spec.AudioS16)
+	assert.Nil(t, reader)
+	assert.EqualError(t, err, "Cannot read from the mixer while it is streaming to an output")
+}
+
 func TestOutputStart(t *testing.T) {
 	// TODO: Test
 }
@@ -119,6 +189,22 @@ func TestAudioCallback(t *testing.T) {
 // Test Components
 //
 
+// testOutput22050 is a binding of a device that only plays at 22.05kHz in S16
+type testOutput22050 struct{}
+
+func (testOutput22050) ConfigureOutput(s spec.AudioSpec) (obtained spec.AudioSpec, err error) {
+	obtained = s
+	obtained.Freq = 22050
+	obtained.Format = spec.AudioS16
+	return
+}
+
+func (testOutput22050) OutputStart(length time.Duration, w io.Writer) {}
+
+func (testOutput22050) OutputNext(numSamples spec.Tz) error { return nil }
+
+func (testOutput22050) Teardown() {}
+
 func testAPISetup() {
 	ClearAllFires()
 	Configure(spec.AudioSpec{